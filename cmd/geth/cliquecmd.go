@@ -0,0 +1,765 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	cliqueSignersFlag = cli.StringFlag{
+		Name:  "signers",
+		Usage: "Comma separated list of the initial signer addresses",
+	}
+	cliqueSignerLimitFlag = cli.UintFlag{
+		Name:  "signerlimit",
+		Usage: "Signer limit, a percentage of the signers unless absolute",
+		Value: 50,
+	}
+	cliqueAbsoluteLimitFlag = cli.BoolFlag{
+		Name:  "absolutelimit",
+		Usage: "Interpret the signer limit as an absolute number of votes",
+	}
+	cliquePeriodFlag = cli.Uint64Flag{
+		Name:  "period",
+		Usage: "Number of seconds between blocks",
+		Value: 15,
+	}
+	cliqueEpochFlag = cli.Uint64Flag{
+		Name:  "epoch",
+		Usage: "Number of blocks after which to checkpoint and reset the pending votes",
+		Value: 30000,
+	}
+	cliqueChainIDFlag = cli.Uint64Flag{
+		Name:  "chainid",
+		Usage: "Chain identifier of the network",
+		Value: 1337,
+	}
+	cliqueGasLimitFlag = cli.Uint64Flag{
+		Name:  "gaslimit",
+		Usage: "Gas limit of the genesis block",
+		Value: 8000000,
+	}
+	cliqueFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to export or replay the governance history from",
+	}
+	cliqueToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to export or replay the governance history up to (0 = current head)",
+	}
+	cliqueFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format of the governance history (jsonl, csv)",
+		Value: "jsonl",
+	}
+	cliqueOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write the governance history to (default = stdout)",
+	}
+	cliqueDiffFlag = cli.BoolFlag{
+		Name:  "diff",
+		Usage: "Compare the replayed voting state against the persisted snapshots",
+	}
+	cliqueEnodesFlag = cli.StringFlag{
+		Name:  "enodes",
+		Usage: "Comma separated list of the signer nodes' enode URLs, in the order of the signers",
+	}
+	cliqueDirFlag = cli.StringFlag{
+		Name:  "dir",
+		Usage: "Directory to generate the network configuration into",
+		Value: "clique-network",
+	}
+	cliqueImageFlag = cli.StringFlag{
+		Name:  "image",
+		Usage: "Docker image the generated compose file runs the nodes with",
+		Value: "ethereum/client-go:stable",
+	}
+	cliqueEndpointFlag = cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "IPC endpoint of the running node (default = <datadir>/geth.ipc)",
+	}
+	cliqueDropFlag = cli.BoolFlag{
+		Name:  "drop",
+		Usage: "Vote to drop the account from the signer set instead of adding it",
+	}
+	cliqueMemoFlag = cli.StringFlag{
+		Name:  "memo",
+		Usage: "Memo justifying the proposal, recorded in the governance history",
+	}
+
+	cliqueCommand = cli.Command{
+		Name:        "clique",
+		Usage:       "Manage clique proof-of-authority networks",
+		ArgsUsage:   "",
+		Category:    "MISCELLANEOUS COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "init-genesis",
+				Usage:     "Generate the genesis of a new clique network",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(cliqueInitGenesis),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					cliqueSignersFlag,
+					cliqueSignerLimitFlag,
+					cliqueAbsoluteLimitFlag,
+					cliquePeriodFlag,
+					cliqueEpochFlag,
+					cliqueChainIDFlag,
+					cliqueGasLimitFlag,
+				},
+				Description: `
+geth clique init-genesis --signers <addr1,addr2,...> --signerlimit <limit>
+will print the genesis JSON of a new clique network, embedding the initial
+signers in the correct order into the extra-data and the signer limit into
+the clique configuration. The output can be passed to 'geth init'.
+`,
+			},
+			{
+				Name:      "init-network",
+				Usage:     "Generate the configuration of a new clique network",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(cliqueInitNetwork),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					cliqueSignersFlag,
+					cliqueSignerLimitFlag,
+					cliqueAbsoluteLimitFlag,
+					cliquePeriodFlag,
+					cliqueEpochFlag,
+					cliqueChainIDFlag,
+					cliqueGasLimitFlag,
+					cliqueEnodesFlag,
+					cliqueDirFlag,
+					cliqueImageFlag,
+				},
+				Description: `
+geth clique init-network --signers <addr1,addr2,...> --enodes <enode1,enode2,...> --dir <path>
+will generate everything needed to bring up a new clique network: the genesis
+JSON, the static node list connecting the signers, a systemd unit for every
+signer node and a docker compose file running all of them on a single host.
+The extra-data embedding the signers is parsed back by the consensus engine's
+rules before anything is written. The signer keystores and passwords are left
+for the operators to provide.
+`,
+			},
+			{
+				Name:      "export-governance",
+				Usage:     "Export the governance history of a clique chain",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(cliqueExportGovernance),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+					cliqueFromFlag,
+					cliqueToFlag,
+					cliqueFormatFlag,
+					cliqueOutputFlag,
+				},
+				Description: `
+geth clique export-governance --from <block> --to <block> --format <jsonl|csv>
+will replay the local chain between the given blocks and write a record of
+every vote counted, proposal passed and signer limit changed, along with the
+block number, hash and timestamp it happened in, for offline analysis.
+`,
+			},
+			{
+				Name:      "replay",
+				Usage:     "Replay the voting history of a clique chain",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(cliqueReplay),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+					cliqueFromFlag,
+					cliqueToFlag,
+					cliqueDiffFlag,
+				},
+				Description: `
+geth clique replay --from <block> --to <block> [--diff]
+will re-apply the local chain between the given blocks on top of the voting
+snapshot preceding them and print every governance state transition: the
+snapshot fields each block changed, the votes and proposals that caused it and
+the resulting signer set. With --diff, the replayed state is also compared
+against every snapshot persisted in the range, printing the fields they differ
+in, which helps finding out why two nodes disagree about the signer set.
+`,
+			},
+			{
+				Name:      "verify-snapshots",
+				Usage:     "Verify the persisted voting snapshots against the chain",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(cliqueVerifySnapshots),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+				},
+				Description: `
+geth clique verify-snapshots
+will replay the local chain from the last epoch checkpoint up to the head and
+compare the resulting voting state against every snapshot persisted in that
+range, reporting the first divergent snapshot along with the last consistent
+block and the fields the states differ in.
+`,
+			},
+			{
+				Name:      "repair-snapshots",
+				Usage:     "Drop and rebuild corrupted voting snapshots",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(cliqueRepairSnapshots),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+				},
+				Description: `
+geth clique repair-snapshots
+will check that every persisted voting snapshot can be decoded, dropping the
+corrupted ones from the database and rebuilding those of blocks still known
+to the local chain by replaying the headers.
+`,
+			},
+			{
+				Name:      "status",
+				Usage:     "Print the signing status of a running clique node",
+				ArgsUsage: "",
+				Action:    utils.MigrateFlags(cliqueStatus),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					cliqueEndpointFlag,
+				},
+				Description: `
+geth clique status [--endpoint <ipc path>]
+will connect to a running node over IPC and print the number of active
+signers, the in-turn ratio and the sealing activity of every signer over the
+last blocks of its chain.
+`,
+			},
+			{
+				Name:      "propose",
+				Usage:     "Propose adding or dropping a signer on a running clique node",
+				ArgsUsage: "<address>",
+				Action:    utils.MigrateFlags(cliquePropose),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					cliqueEndpointFlag,
+					cliqueDropFlag,
+					cliqueMemoFlag,
+				},
+				Description: `
+geth clique propose <address> [--drop] [--memo <text>]
+will connect to a running node over IPC and queue a proposal to add the given
+account to the signer set, or to drop it with --drop. The node keeps voting on
+the proposal in the blocks it seals until it passes or is discarded.
+`,
+			},
+			{
+				Name:      "propose-limit",
+				Usage:     "Propose a new signer limit on a running clique node",
+				ArgsUsage: "<limit>",
+				Action:    utils.MigrateFlags(cliqueProposeLimit),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					cliqueEndpointFlag,
+				},
+				Description: `
+geth clique propose-limit <limit>
+will connect to a running node over IPC and queue a proposal to change the
+signer limit of the network, replacing any signer limit proposal queued before.
+`,
+			},
+			{
+				Name:      "discard",
+				Usage:     "Discard a pending signer proposal of a running clique node",
+				ArgsUsage: "<address>",
+				Action:    utils.MigrateFlags(cliqueDiscard),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					cliqueEndpointFlag,
+				},
+				Description: `
+geth clique discard <address>
+will connect to a running node over IPC and drop the proposal it queued for
+the given account, stopping it from voting on it any further.
+`,
+			},
+			{
+				Name:      "snapshot",
+				Usage:     "Print the voting snapshot of a running clique node",
+				ArgsUsage: "[<block>]",
+				Action:    utils.MigrateFlags(cliqueSnapshot),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					cliqueEndpointFlag,
+				},
+				Description: `
+geth clique snapshot [<block>]
+will connect to a running node over IPC and print the voting snapshot at the
+given block, or at the head of its chain if none is given.
+`,
+			},
+			{
+				Name:      "history",
+				Usage:     "Print the governance history of a running clique node",
+				ArgsUsage: "[<address>]",
+				Action:    utils.MigrateFlags(cliqueHistory),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					cliqueEndpointFlag,
+					cliqueFromFlag,
+					cliqueToFlag,
+				},
+				Description: `
+geth clique history [<address>] [--from <block>] [--to <block>]
+will connect to a running node over IPC and print the governance proposals
+resolved on its chain within the given block range. If an account is given,
+the blocks in which it joined or left the signer set are printed instead.
+`,
+			},
+		},
+	}
+)
+
+// governanceColumns is the header row of the CSV governance history export.
+var governanceColumns = []string{"type", "block", "hash", "time", "kind", "signer", "address", "limit", "prevLimit", "signers", "votes", "passed", "memo"}
+
+// cliqueInitGenesis assembles and prints the genesis of a new clique network.
+func cliqueInitGenesis(ctx *cli.Context) error {
+	genesis, _ := makeCliqueGenesis(ctx)
+	out, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode genesis: %v", err)
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// makeCliqueGenesis assembles the genesis of a new clique network from the
+// command line flags, returning it along with its initial signers as embedded
+// in the extra-data. The extra-data is parsed back by the engine's own rules,
+// so a genesis the network wouldn't start from is never handed out.
+func makeCliqueGenesis(ctx *cli.Context) (*core.Genesis, []common.Address) {
+	var signers []common.Address
+	for _, signer := range utils.SplitAndTrim(ctx.String(cliqueSignersFlag.Name)) {
+		if !common.IsHexAddress(signer) {
+			utils.Fatalf("Invalid signer address: %s", signer)
+		}
+		signers = append(signers, common.HexToAddress(signer))
+	}
+	config := *params.AllCliqueProtocolChanges
+	config.ChainID = new(big.Int).SetUint64(ctx.Uint64(cliqueChainIDFlag.Name))
+	config.Clique = &params.CliqueConfig{
+		Period:              ctx.Uint64(cliquePeriodFlag.Name),
+		Epoch:               ctx.Uint64(cliqueEpochFlag.Name),
+		SignerLimit:         ctx.Uint(cliqueSignerLimitFlag.Name),
+		AbsoluteSignerLimit: ctx.Bool(cliqueAbsoluteLimitFlag.Name),
+	}
+	genesis, err := core.CliqueGenesisBlock(&config, signers, ctx.Uint64(cliqueGasLimitFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to assemble genesis: %v", err)
+	}
+	embedded, err := clique.GenesisSigners(config.Clique, genesis.ToBlock(nil).Header())
+	if err != nil {
+		utils.Fatalf("Invalid genesis extra-data: %v", err)
+	}
+	if len(embedded) != len(signers) {
+		utils.Fatalf("Genesis extra-data embeds %d signers, want %d", len(embedded), len(signers))
+	}
+	return genesis, embedded
+}
+
+// cliqueExportGovernance replays the local chain and writes its governance history.
+func cliqueExportGovernance(ctx *cli.Context) error {
+	format := ctx.String(cliqueFormatFlag.Name)
+	if format != "jsonl" && format != "csv" {
+		utils.Fatalf("Unsupported export format: %s", format)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, _ := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	engine, ok := chain.Engine().(*clique.Clique)
+	if !ok {
+		utils.Fatalf("Governance history is only available on clique networks")
+	}
+	from, to := ctx.Uint64(cliqueFromFlag.Name), ctx.Uint64(cliqueToFlag.Name)
+	if head := chain.CurrentHeader().Number.Uint64(); to == 0 || to > head {
+		to = head
+	}
+	if from > to {
+		utils.Fatalf("Invalid block range: %d > %d", from, to)
+	}
+	var out io.Writer = os.Stdout
+	if path := ctx.String(cliqueOutputFlag.Name); path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			utils.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	var (
+		write func(*clique.GovernanceRecord) error
+		flush = func() error { return nil }
+	)
+	switch format {
+	case "jsonl":
+		enc := json.NewEncoder(out)
+		write = func(record *clique.GovernanceRecord) error { return enc.Encode(record) }
+	case "csv":
+		w := csv.NewWriter(out)
+		if err := w.Write(governanceColumns); err != nil {
+			utils.Fatalf("Failed to write governance history: %v", err)
+		}
+		write = func(record *clique.GovernanceRecord) error { return w.Write(governanceRow(record)) }
+		flush = func() error { w.Flush(); return w.Error() }
+	}
+	if err := engine.GovernanceHistory(chain, from, to, write); err != nil {
+		utils.Fatalf("Failed to export governance history: %v", err)
+	}
+	if err := flush(); err != nil {
+		utils.Fatalf("Failed to write governance history: %v", err)
+	}
+	return nil
+}
+
+// cliqueReplay replays the local chain and prints its governance state transitions.
+func cliqueReplay(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, _ := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	engine, ok := chain.Engine().(*clique.Clique)
+	if !ok {
+		utils.Fatalf("Voting history replay is only available on clique networks")
+	}
+	from, to := ctx.Uint64(cliqueFromFlag.Name), ctx.Uint64(cliqueToFlag.Name)
+	if head := chain.CurrentHeader().Number.Uint64(); to == 0 || to > head {
+		to = head
+	}
+	if from > to {
+		utils.Fatalf("Invalid block range: %d > %d", from, to)
+	}
+	var compared, diverged int
+	err := engine.ReplayGovernance(chain, from, to, ctx.Bool(cliqueDiffFlag.Name), func(t *clique.Transition) error {
+		fmt.Printf("Block %d [%x] sealed by %s\n", t.Number, t.Hash, t.Signer.Hex())
+		for _, record := range t.Records {
+			fmt.Printf("  %s\n", describeGovernanceRecord(record))
+		}
+		if len(t.Fields) > 0 {
+			signers := make([]string, len(t.Signers))
+			for i, signer := range t.Signers {
+				signers[i] = signer.Hex()
+			}
+			fmt.Printf("  changed %s\n", strings.Join(t.Fields, ", "))
+			fmt.Printf("  signers %s, limit %d\n", strings.Join(signers, ", "), t.Limit)
+		}
+		if t.Stored {
+			compared++
+			if len(t.Mismatch) > 0 {
+				diverged++
+				fmt.Printf("  persisted snapshot differs in %s\n", strings.Join(t.Mismatch, ", "))
+			} else {
+				fmt.Printf("  persisted snapshot consistent\n")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.Fatalf("Failed to replay voting history: %v", err)
+	}
+	if diverged > 0 {
+		utils.Fatalf("%d of %d persisted snapshots diverge from the replayed chain", diverged, compared)
+	}
+	log.Info("Replayed voting history", "from", from, "to", to, "compared", compared)
+	return nil
+}
+
+// describeGovernanceRecord formats a governance history record into a single
+// human readable line.
+func describeGovernanceRecord(record *clique.GovernanceRecord) string {
+	var subject string
+	switch record.Kind {
+	case clique.ProposalSignerLimit:
+		subject = fmt.Sprintf("limit %d", record.Limit)
+	case clique.ProposalOverride:
+		subject = fmt.Sprintf("%d signers", len(record.Signers))
+	default:
+		subject = record.Address.Hex()
+	}
+	switch record.Type {
+	case clique.HistoryVote:
+		line := fmt.Sprintf("vote %s %s by %s (%d votes)", record.Kind, subject, record.Signer.Hex(), record.Votes)
+		if record.Passed {
+			line += ", passed"
+		}
+		return line
+	case clique.HistoryLimit:
+		return fmt.Sprintf("limit changed from %d to %d (%d votes)", record.PrevLimit, record.Limit, record.Votes)
+	default:
+		return fmt.Sprintf("passed %s %s (%d votes)", record.Kind, subject, record.Votes)
+	}
+}
+
+// cliqueVerifySnapshots checks the persisted voting snapshots of the last epoch
+// against the ones derived by replaying the local chain.
+func cliqueVerifySnapshots(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, _ := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	engine, ok := chain.Engine().(*clique.Clique)
+	if !ok {
+		utils.Fatalf("Snapshot verification is only available on clique networks")
+	}
+	defer engine.Close() // Flush the snapshots written in the background
+
+	checked, mismatch, err := engine.VerifySnapshots(chain)
+	if err != nil {
+		utils.Fatalf("Failed to verify snapshots: %v", err)
+	}
+	if mismatch != nil {
+		utils.Fatalf("Snapshot %d [%x] diverges from the chain in %s, last consistent block %d", mismatch.Number, mismatch.Hash, strings.Join(mismatch.Fields, ", "), mismatch.Verified)
+	}
+	log.Info("Verified voting snapshots", "checked", checked, "head", chain.CurrentHeader().Number)
+	return nil
+}
+
+// cliqueRepairSnapshots drops the corrupted voting snapshots and rebuilds them.
+func cliqueRepairSnapshots(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, _ := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	engine, ok := chain.Engine().(*clique.Clique)
+	if !ok {
+		utils.Fatalf("Snapshot repair is only available on clique networks")
+	}
+	defer engine.Close() // Flush the snapshots written in the background
+
+	repaired, err := engine.RepairSnapshots(chain)
+	if err != nil {
+		utils.Fatalf("Failed to repair snapshots: %v", err)
+	}
+	log.Info("Repaired voting snapshots", "count", len(repaired))
+	return nil
+}
+
+// governanceRow flattens a governance history record into a CSV row.
+func governanceRow(record *clique.GovernanceRecord) []string {
+	signers := make([]string, len(record.Signers))
+	for i, signer := range record.Signers {
+		signers[i] = signer.Hex()
+	}
+	return []string{
+		record.Type,
+		strconv.FormatUint(record.Block, 10),
+		record.Hash.Hex(),
+		strconv.FormatUint(record.Time, 10),
+		record.Kind.String(),
+		record.Signer.Hex(),
+		record.Address.Hex(),
+		strconv.FormatUint(uint64(record.Limit), 10),
+		strconv.FormatUint(uint64(record.PrevLimit), 10),
+		strings.Join(signers, ";"),
+		strconv.Itoa(record.Votes),
+		strconv.FormatBool(record.Passed),
+		record.Memo,
+	}
+}
+
+// dialClique connects to the IPC endpoint of the running node the clique
+// management commands operate on.
+func dialClique(ctx *cli.Context) *rpc.Client {
+	endpoint := ctx.String(cliqueEndpointFlag.Name)
+	if endpoint == "" && ctx.IsSet(utils.DataDirFlag.Name) {
+		endpoint = filepath.Join(ctx.String(utils.DataDirFlag.Name), "geth.ipc")
+	}
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		utils.Fatalf("Unable to attach to remote geth: %v", err)
+	}
+	return client
+}
+
+// cliqueCall invokes the given method on the running node and prints its
+// result as indented JSON.
+func cliqueCall(ctx *cli.Context, method string, args ...interface{}) {
+	client := dialClique(ctx)
+	defer client.Close()
+
+	var result json.RawMessage
+	if err := client.Call(&result, method, args...); err != nil {
+		utils.Fatalf("Failed to call %s: %v", method, err)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, result, "", "  "); err != nil {
+		utils.Fatalf("Failed to decode %s result: %v", method, err)
+	}
+	fmt.Println(out.String())
+}
+
+// cliqueAddressArg parses the single account argument of a clique command.
+func cliqueAddressArg(ctx *cli.Context) common.Address {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an account argument")
+	}
+	if !common.IsHexAddress(ctx.Args().First()) {
+		utils.Fatalf("Invalid account address: %s", ctx.Args().First())
+	}
+	return common.HexToAddress(ctx.Args().First())
+}
+
+// cliqueStatus prints the signing status of a running node.
+func cliqueStatus(ctx *cli.Context) error {
+	cliqueCall(ctx, "clique_status")
+	return nil
+}
+
+// cliquePropose queues a signer proposal on a running node.
+func cliquePropose(ctx *cli.Context) error {
+	address := cliqueAddressArg(ctx)
+	auth := !ctx.Bool(cliqueDropFlag.Name)
+
+	var memo *string
+	if ctx.IsSet(cliqueMemoFlag.Name) {
+		text := ctx.String(cliqueMemoFlag.Name)
+		memo = &text
+	}
+	client := dialClique(ctx)
+	defer client.Close()
+
+	if err := client.Call(nil, "clique_propose", address, auth, memo); err != nil {
+		utils.Fatalf("Failed to propose %s: %v", address.Hex(), err)
+	}
+	if auth {
+		fmt.Printf("Proposed adding %s to the signer set\n", address.Hex())
+	} else {
+		fmt.Printf("Proposed dropping %s from the signer set\n", address.Hex())
+	}
+	return nil
+}
+
+// cliqueProposeLimit queues a signer limit proposal on a running node.
+func cliqueProposeLimit(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires a signer limit argument")
+	}
+	limit, err := strconv.ParseUint(ctx.Args().First(), 10, 32)
+	if err != nil {
+		utils.Fatalf("Invalid signer limit: %v", err)
+	}
+	client := dialClique(ctx)
+	defer client.Close()
+
+	var accepted bool
+	if err := client.Call(&accepted, "clique_votingpercentage", 0, uint(limit), true); err != nil {
+		utils.Fatalf("Failed to propose signer limit: %v", err)
+	}
+	if !accepted {
+		utils.Fatalf("Signer limit %d rejected by the node", limit)
+	}
+	fmt.Printf("Proposed signer limit %d\n", limit)
+	return nil
+}
+
+// cliqueDiscard drops a pending signer proposal of a running node.
+func cliqueDiscard(ctx *cli.Context) error {
+	address := cliqueAddressArg(ctx)
+
+	client := dialClique(ctx)
+	defer client.Close()
+
+	if err := client.Call(nil, "clique_discard", address); err != nil {
+		utils.Fatalf("Failed to discard proposal for %s: %v", address.Hex(), err)
+	}
+	fmt.Printf("Discarded proposal for %s\n", address.Hex())
+	return nil
+}
+
+// cliqueSnapshot prints the voting snapshot of a running node.
+func cliqueSnapshot(ctx *cli.Context) error {
+	switch ctx.NArg() {
+	case 0:
+		cliqueCall(ctx, "clique_getSnapshot", nil)
+	case 1:
+		number, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+		if err != nil {
+			utils.Fatalf("Invalid block number: %v", err)
+		}
+		cliqueCall(ctx, "clique_getSnapshot", hexutil.Uint64(number))
+	default:
+		utils.Fatalf("This command accepts at most one block number argument")
+	}
+	return nil
+}
+
+// cliqueHistory prints the governance history of a running node.
+func cliqueHistory(ctx *cli.Context) error {
+	if ctx.NArg() > 0 {
+		cliqueCall(ctx, "clique_signerHistory", cliqueAddressArg(ctx))
+		return nil
+	}
+	var to *hexutil.Uint64
+	if number := ctx.Uint64(cliqueToFlag.Name); number > 0 {
+		to = (*hexutil.Uint64)(&number)
+	}
+	cliqueCall(ctx, "clique_getResolutions", hexutil.Uint64(ctx.Uint64(cliqueFromFlag.Name)), to)
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// cliqueNodeService is the systemd unit template running a single signer node of
+// a clique network generated by 'geth clique init-network'.
+var cliqueNodeService = template.Must(template.New("service").Parse(`[Unit]
+Description=Clique signer {{.Signer.Hex}} of network {{.NetworkID}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+User=geth
+ExecStartPre=/usr/local/bin/geth --datadir {{.DataDir}} init {{.Config}}/genesis.json
+ExecStartPre=/bin/cp {{.Config}}/static-nodes.json {{.DataDir}}/geth/static-nodes.json
+ExecStart=/usr/local/bin/geth --datadir {{.DataDir}} --networkid {{.NetworkID}} --syncmode full --port {{.Port}} --nodiscover --mine --miner.etherbase {{.Signer.Hex}} --unlock {{.Signer.Hex}} --password {{.DataDir}}/password.txt
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// cliqueCompose is the docker compose template running every signer node of a
+// clique network generated by 'geth clique init-network' on a single host.
+var cliqueCompose = template.Must(template.New("compose").Parse(`version: '2'
+services:{{range .Nodes}}
+  {{.Name}}:
+    image: {{$.Image}}
+    container_name: {{.Name}}
+    entrypoint: /bin/sh -c
+    command: >
+      "geth --datadir /root/.ethereum init /config/genesis.json &&
+      cp /config/static-nodes.json /root/.ethereum/geth/static-nodes.json &&
+      exec geth --datadir /root/.ethereum --networkid {{$.NetworkID}} --syncmode full --port {{.Port}} --nodiscover
+      --mine --miner.etherbase {{.Signer.Hex}} --unlock {{.Signer.Hex}} --password /root/.ethereum/password.txt"
+    ports:
+      - "{{.Port}}:{{.Port}}"
+      - "{{.Port}}:{{.Port}}/udp"
+    volumes:
+      - ./genesis.json:/config/genesis.json:ro
+      - ./static-nodes.json:/config/static-nodes.json:ro
+      - ./{{.Name}}:/root/.ethereum
+    restart: always{{end}}
+`))
+
+// cliqueNetworkNode is the deployment configuration of a single signer node.
+type cliqueNetworkNode struct {
+	Name      string         // Name of the node, used for its service and data directory
+	Signer    common.Address // Signer account the node seals with
+	Port      int            // Peer-to-peer listener port of the node
+	NetworkID uint64         // Network identifier of the chain
+	DataDir   string         // Data directory of the node on a systemd host
+	Config    string         // Directory of the shared network configuration on a systemd host
+}
+
+// cliqueInitNetwork generates the genesis, static node list and deployment
+// templates of a new clique network into an output directory.
+func cliqueInitNetwork(ctx *cli.Context) error {
+	genesis, signers := makeCliqueGenesis(ctx)
+
+	enodes := utils.SplitAndTrim(ctx.String(cliqueEnodesFlag.Name))
+	if len(enodes) > 0 && len(enodes) != len(signers) {
+		utils.Fatalf("Need one enode URL per signer, have %d for %d signers", len(enodes), len(signers))
+	}
+	for _, url := range enodes {
+		if _, err := enode.Parse(enode.ValidSchemes, url); err != nil {
+			utils.Fatalf("Invalid enode URL %s: %v", url, err)
+		}
+	}
+	dir := ctx.String(cliqueDirFlag.Name)
+	if err := os.MkdirAll(filepath.Join(dir, "systemd"), 0755); err != nil {
+		utils.Fatalf("Failed to create output directory: %v", err)
+	}
+	writeCliqueJSON(filepath.Join(dir, "genesis.json"), genesis)
+	if enodes == nil {
+		enodes = []string{}
+	}
+	writeCliqueJSON(filepath.Join(dir, "static-nodes.json"), enodes)
+
+	nodes := make([]*cliqueNetworkNode, len(signers))
+	for i, signer := range signers {
+		name := fmt.Sprintf("signer%d", i+1)
+		nodes[i] = &cliqueNetworkNode{
+			Name:      name,
+			Signer:    signer,
+			Port:      30303 + i,
+			NetworkID: genesis.Config.ChainID.Uint64(),
+			DataDir:   filepath.Join("/var/lib/geth", name),
+			Config:    "/etc/geth",
+		}
+		writeCliqueTemplate(filepath.Join(dir, "systemd", "geth-"+name+".service"), cliqueNodeService, nodes[i])
+	}
+	writeCliqueTemplate(filepath.Join(dir, "docker-compose.yml"), cliqueCompose, map[string]interface{}{
+		"Image":     ctx.String(cliqueImageFlag.Name),
+		"NetworkID": genesis.Config.ChainID.Uint64(),
+		"Nodes":     nodes,
+	})
+	fmt.Printf("Generated clique network of %d signers in %s\n", len(signers), dir)
+	if len(enodes) == 0 {
+		fmt.Println("No enode URLs given, fill in static-nodes.json before starting the nodes")
+	}
+	fmt.Println("Place each signer's keystore and password.txt into its data directory")
+	return nil
+}
+
+// writeCliqueJSON writes an indented JSON encoding of a value into a file.
+func writeCliqueJSON(path string, value interface{}) {
+	out, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode %s: %v", filepath.Base(path), err)
+	}
+	if err := ioutil.WriteFile(path, append(out, '\n'), 0644); err != nil {
+		utils.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// writeCliqueTemplate renders a deployment template into a file.
+func writeCliqueTemplate(path string, tmpl *template.Template, data interface{}) {
+	file, err := os.Create(path)
+	if err != nil {
+		utils.Fatalf("Failed to create %s: %v", path, err)
+	}
+	defer file.Close()
+
+	if err := tmpl.Execute(file, data); err != nil {
+		utils.Fatalf("Failed to render %s: %v", path, err)
+	}
+}
@@ -108,6 +108,18 @@ var (
 		utils.UltraLightOnlyAnnounceFlag,
 		utils.LightNoSyncServeFlag,
 		utils.EthPeerRequiredBlocksFlag,
+		utils.CliqueBootstrapFlag,
+		utils.CliquePeerBootstrapFlag,
+		utils.CliqueSigCacheFlag,
+		utils.CliqueSigCachePersistFlag,
+		utils.CliqueCheckpointFlag,
+		utils.CliqueAlertWebhookFlag,
+		utils.CliqueAlertExecFlag,
+		utils.CliqueRejectTxsDegradedFlag,
+		utils.CliqueStallRecoveryFlag,
+		utils.CliqueProposalStrategyFlag,
+		utils.CliqueVerifyWorkersFlag,
+		utils.CliqueSnapshotMemoryIntervalFlag,
 		utils.LegacyWhitelistFlag,
 		utils.BloomFilterSizeFlag,
 		utils.CacheFlag,
@@ -192,6 +204,8 @@ var (
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
 		utils.AllowUnprotectedTxs,
+		utils.RPCScopeSecretFlag,
+		utils.RPCAdminTokensFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -249,6 +263,8 @@ func init() {
 		// See snapshot.go
 		snapshotCommand,
 		validateCommand,
+		// See cliquecmd.go
+		cliqueCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
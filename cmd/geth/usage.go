@@ -55,6 +55,18 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.EthPeerRequiredBlocksFlag,
+			utils.CliqueBootstrapFlag,
+			utils.CliquePeerBootstrapFlag,
+			utils.CliqueSigCacheFlag,
+			utils.CliqueSigCachePersistFlag,
+			utils.CliqueCheckpointFlag,
+			utils.CliqueAlertWebhookFlag,
+			utils.CliqueAlertExecFlag,
+			utils.CliqueRejectTxsDegradedFlag,
+			utils.CliqueStallRecoveryFlag,
+			utils.CliqueProposalStrategyFlag,
+			utils.CliqueVerifyWorkersFlag,
+			utils.CliqueSnapshotMemoryIntervalFlag,
 		},
 	},
 	{
@@ -161,6 +173,8 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.RPCGlobalEVMTimeoutFlag,
 			utils.RPCGlobalTxFeeCapFlag,
 			utils.AllowUnprotectedTxs,
+			utils.RPCScopeSecretFlag,
+			utils.RPCAdminTokensFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -245,6 +246,55 @@ var (
 		Name:  "eth.requiredblocks",
 		Usage: "Comma separated block number-to-hash mappings to require for peering (<number>=<hash>)",
 	}
+	CliqueBootstrapFlag = cli.StringFlag{
+		Name:  "clique.bootstrap",
+		Usage: "RPC endpoint of a trusted node to bootstrap clique checkpoint snapshots from",
+	}
+	CliquePeerBootstrapFlag = cli.BoolFlag{
+		Name:  "clique.peerbootstrap",
+		Usage: "Bootstrap clique checkpoint snapshots from the connected peers",
+	}
+	CliqueCheckpointFlag = cli.StringFlag{
+		Name:  "clique.checkpoint",
+		Usage: "JSON file of a trusted clique checkpoint (number, hash, signers, signer limit) to start validating from",
+	}
+	CliqueAlertWebhookFlag = cli.StringFlag{
+		Name:  "clique.alert.webhook",
+		Usage: "URL to post clique consensus anomalies (missed slots, signer changes, slow rebuilds) to",
+	}
+	CliqueAlertExecFlag = cli.StringFlag{
+		Name:  "clique.alert.exec",
+		Usage: "Command to run for every clique consensus anomaly, fed the JSON encoded anomaly on stdin",
+	}
+	CliqueRejectTxsDegradedFlag = cli.BoolFlag{
+		Name:  "clique.degraded.rejecttxs",
+		Usage: "Reject new transactions while the clique chain is stalled due to a lost signer quorum",
+	}
+	CliqueStallRecoveryFlag = cli.BoolFlag{
+		Name:  "clique.stallrecovery",
+		Usage: "Propose dropping the offline clique signers once the chain resumes after a prolonged halt",
+	}
+	CliqueProposalStrategyFlag = cli.StringFlag{
+		Name:  "clique.proposalstrategy",
+		Usage: "Order the local signer votes on its queued clique proposals in (random, fifo, priority, roundrobin)",
+	}
+	CliqueVerifyWorkersFlag = cli.IntFlag{
+		Name:  "clique.verifyworkers",
+		Usage: "Maximum number of workers verifying clique header batches in parallel (0 = number of cores)",
+	}
+	CliqueSnapshotMemoryIntervalFlag = cli.Uint64Flag{
+		Name:  "clique.snapshots.memoryinterval",
+		Usage: "Block interval of the clique voting snapshots kept in memory, the ones in between recomputed on demand (0 = every block)",
+	}
+	CliqueSigCacheFlag = cli.IntFlag{
+		Name:  "clique.sigcache",
+		Usage: "Number of recovered clique block signers to keep in memory",
+		Value: ethconfig.Defaults.CliqueSigCache,
+	}
+	CliqueSigCachePersistFlag = cli.IntFlag{
+		Name:  "clique.sigcache.persist",
+		Usage: "Number of recovered clique block signers to persist across restarts (0 = disabled)",
+	}
 	LegacyWhitelistFlag = cli.StringFlag{
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>) (deprecated in favor of --peer.requiredblocks)",
@@ -661,6 +711,14 @@ var (
 		Name:  "rpc.allow-unprotected-txs",
 		Usage: "Allow for unprotected (non EIP155 signed) transactions to be submitted via RPC",
 	}
+	RPCScopeSecretFlag = cli.StringFlag{
+		Name:  "rpc.scopesecret",
+		Usage: "Path to a JWT secret verifying the scoped tokens accepted on the HTTP and WebSocket RPC endpoints",
+	}
+	RPCAdminTokensFlag = cli.StringFlag{
+		Name:  "rpc.admintokens",
+		Usage: "Comma separated list of API tokens granting the admin scope on the HTTP and WebSocket RPC endpoints",
+	}
 
 	// Network Settings
 	MaxPeersFlag = cli.IntFlag{
@@ -1303,6 +1361,17 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(JWTSecretFlag.Name) {
 		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCScopeSecretFlag.Name) {
+		cfg.RPCScopeSecret = ctx.GlobalString(RPCScopeSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAdminTokensFlag.Name) {
+		if cfg.RPCAPITokens == nil {
+			cfg.RPCAPITokens = make(map[string][]string)
+		}
+		for _, token := range SplitAndTrim(ctx.GlobalString(RPCAdminTokensFlag.Name)) {
+			cfg.RPCAPITokens[token] = []string{"admin"}
+		}
+	}
 
 	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
@@ -1642,6 +1711,49 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(CliqueBootstrapFlag.Name) {
+		cfg.CliqueBootstrap = ctx.GlobalString(CliqueBootstrapFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliquePeerBootstrapFlag.Name) {
+		cfg.CliquePeerBootstrap = ctx.GlobalBool(CliquePeerBootstrapFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueSigCacheFlag.Name) {
+		cfg.CliqueSigCache = ctx.GlobalInt(CliqueSigCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueSigCachePersistFlag.Name) {
+		cfg.CliqueSigCachePersist = ctx.GlobalInt(CliqueSigCachePersistFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueAlertWebhookFlag.Name) {
+		cfg.CliqueAlertWebhook = ctx.GlobalString(CliqueAlertWebhookFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueAlertExecFlag.Name) {
+		cfg.CliqueAlertExec = ctx.GlobalString(CliqueAlertExecFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueRejectTxsDegradedFlag.Name) {
+		cfg.CliqueRejectTxsDegraded = ctx.GlobalBool(CliqueRejectTxsDegradedFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueStallRecoveryFlag.Name) {
+		cfg.CliqueStallRecovery = ctx.GlobalBool(CliqueStallRecoveryFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueProposalStrategyFlag.Name) {
+		cfg.CliqueProposalStrategy = ctx.GlobalString(CliqueProposalStrategyFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueVerifyWorkersFlag.Name) {
+		cfg.CliqueVerifyWorkers = ctx.GlobalInt(CliqueVerifyWorkersFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueSnapshotMemoryIntervalFlag.Name) {
+		cfg.CliqueSnapshotMemoryInterval = ctx.GlobalUint64(CliqueSnapshotMemoryIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(CliqueCheckpointFlag.Name) {
+		blob, err := ioutil.ReadFile(ctx.GlobalString(CliqueCheckpointFlag.Name))
+		if err != nil {
+			Fatalf("Failed to read clique checkpoint: %v", err)
+		}
+		cfg.CliqueCheckpoint = new(params.CliqueCheckpoint)
+		if err := json.Unmarshal(blob, cfg.CliqueCheckpoint); err != nil {
+			Fatalf("Invalid clique checkpoint: %v", err)
+		}
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -0,0 +1,160 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package lru implements fixed size, thread safe caches with typed keys and values
+// and a pluggable eviction policy, optionally reporting their hit rates as metrics.
+package lru
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Policy is the strategy a cache uses to pick the item to evict when full.
+type Policy int
+
+const (
+	LRU      Policy = iota // Evict the least recently used item
+	TwoQueue               // Keep items seen once apart from the frequently used ones
+	ARC                    // Adaptively balance between recently and frequently used items
+)
+
+// String implements fmt.Stringer.
+func (p Policy) String() string {
+	switch p {
+	case LRU:
+		return "lru"
+	case TwoQueue:
+		return "2q"
+	case ARC:
+		return "arc"
+	default:
+		return "unknown"
+	}
+}
+
+// Cache is a fixed size, thread safe cache of typed items.
+type Cache[K comparable, V any] struct {
+	policy policy[K, V]
+	lock   sync.Mutex
+
+	hitMeter   metrics.Meter // Lookups finding the item
+	missMeter  metrics.Meter // Lookups not finding the item
+	evictMeter metrics.Meter // Items evicted to make room for new ones
+	sizeGauge  metrics.Gauge // Number of items held
+}
+
+// New creates a cache holding up to size items, evicting them according to the
+// given policy. If a name is given, the cache reports its hits, misses, evictions
+// and size as metrics under it, shared by all the caches of the same name.
+func New[K comparable, V any](size int, policy Policy, name string) *Cache[K, V] {
+	if size <= 0 {
+		size = 1
+	}
+	c := &Cache[K, V]{
+		hitMeter:   metrics.NilMeter{},
+		missMeter:  metrics.NilMeter{},
+		evictMeter: metrics.NilMeter{},
+		sizeGauge:  metrics.NilGauge{},
+	}
+	switch policy {
+	case TwoQueue:
+		c.policy = newTwoQueuePolicy[K, V](size)
+	case ARC:
+		c.policy = newARCPolicy[K, V](size)
+	default:
+		c.policy = lruPolicy[K, V]{newBasicLRU[K, V](size)}
+	}
+	if name != "" {
+		c.hitMeter = metrics.GetOrRegisterMeter(name+"/hit", nil)
+		c.missMeter = metrics.GetOrRegisterMeter(name+"/miss", nil)
+		c.evictMeter = metrics.GetOrRegisterMeter(name+"/evict", nil)
+		c.sizeGauge = metrics.GetOrRegisterGauge(name+"/size", nil)
+	}
+	return c
+}
+
+// Get retrieves an item, marking it as used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	value, ok := c.policy.get(key)
+	if ok {
+		c.hitMeter.Mark(1)
+	} else {
+		c.missMeter.Mark(1)
+	}
+	return value, ok
+}
+
+// Peek retrieves an item without marking it as used.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.policy.peek(key)
+}
+
+// Add inserts or updates an item, returning whether another one was evicted to
+// make room for it.
+func (c *Cache[K, V]) Add(key K, value V) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	evicted := c.policy.add(key, value)
+	if evicted {
+		c.evictMeter.Mark(1)
+	}
+	c.sizeGauge.Update(int64(c.policy.len()))
+	return evicted
+}
+
+// Contains checks whether an item is cached, without marking it as used.
+func (c *Cache[K, V]) Contains(key K) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.policy.contains(key)
+}
+
+// Remove drops an item from the cache, returning whether it was present.
+func (c *Cache[K, V]) Remove(key K) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	removed := c.policy.remove(key)
+	c.sizeGauge.Update(int64(c.policy.len()))
+	return removed
+}
+
+// Len returns the number of items cached.
+func (c *Cache[K, V]) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.policy.len()
+}
+
+// Purge drops all items from the cache.
+func (c *Cache[K, V]) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.policy.purge()
+	c.sizeGauge.Update(0)
+}
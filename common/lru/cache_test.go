@@ -0,0 +1,139 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package lru
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var policies = []Policy{LRU, TwoQueue, ARC}
+
+// Tests the basic operations of the caches of every policy, and that they never
+// hold more items than their size.
+func TestCacheOperations(t *testing.T) {
+	for _, policy := range policies {
+		cache := New[int, string](128, policy, "")
+
+		evictions := 0
+		for i := 0; i < 256; i++ {
+			if cache.Add(i, "value") {
+				evictions++
+			}
+		}
+		if cache.Len() != 128 {
+			t.Errorf("%v: cache length mismatch: have %d, want %d", policy, cache.Len(), 128)
+		}
+		if evictions != 128 {
+			t.Errorf("%v: eviction count mismatch: have %d, want %d", policy, evictions, 128)
+		}
+		// The most recent items must have been kept
+		for i := 128; i < 256; i++ {
+			if value, ok := cache.Get(i); !ok || value != "value" {
+				t.Errorf("%v: item %d missing", policy, i)
+			}
+		}
+		if _, ok := cache.Peek(0); ok {
+			t.Errorf("%v: evicted item retrievable", policy)
+		}
+		// Updates must not evict anything
+		if cache.Add(200, "updated") {
+			t.Errorf("%v: update evicted an item", policy)
+		}
+		if value, _ := cache.Peek(200); value != "updated" {
+			t.Errorf("%v: updated value mismatch: have %q, want %q", policy, value, "updated")
+		}
+		if !cache.Remove(200) || cache.Contains(200) || cache.Remove(200) {
+			t.Errorf("%v: item removal mismatch", policy)
+		}
+		cache.Purge()
+		if cache.Len() != 0 || cache.Contains(255) {
+			t.Errorf("%v: cache not purged", policy)
+		}
+	}
+}
+
+// Tests that the scan resistant policies keep frequently used items cached while
+// a stream of items used once passes through.
+func TestCacheScanResistance(t *testing.T) {
+	for _, policy := range []Policy{TwoQueue, ARC} {
+		cache := New[int, int](64, policy, "")
+		for i := 0; i < 16; i++ {
+			cache.Add(i, i)
+			cache.Get(i)
+		}
+		for i := 1000; i < 2000; i++ {
+			cache.Add(i, i)
+		}
+		for i := 0; i < 16; i++ {
+			if !cache.Contains(i) {
+				t.Errorf("%v: frequent item %d evicted by scan", policy, i)
+			}
+		}
+	}
+}
+
+// Tests that random workloads never break the size bound or the bookkeeping of
+// the cached items.
+func TestCacheRandomOperations(t *testing.T) {
+	for _, policy := range policies {
+		var (
+			cache = New[int, int](32, policy, "")
+			rng   = rand.New(rand.NewSource(1))
+		)
+		for i := 0; i < 10000; i++ {
+			key := rng.Intn(128)
+			switch rng.Intn(4) {
+			case 0:
+				cache.Remove(key)
+			case 1:
+				if value, ok := cache.Get(key); ok && value != key {
+					t.Fatalf("%v: value mismatch: have %d, want %d", policy, value, key)
+				}
+			default:
+				cache.Add(key, key)
+			}
+			if cache.Len() > 32 {
+				t.Fatalf("%v: cache overflown: have %d items, want at most %d", policy, cache.Len(), 32)
+			}
+		}
+	}
+}
+
+// Tests that named caches report their hits, misses and evictions.
+func TestCacheMetrics(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	cache := New[int, int](1, LRU, "lru/test")
+	cache.Add(1, 1)
+	cache.Add(2, 2)
+	cache.Get(2)
+	cache.Get(1)
+
+	for name, want := range map[string]int64{"lru/test/hit": 1, "lru/test/miss": 1, "lru/test/evict": 1} {
+		if have := metrics.DefaultRegistry.Get(name).(metrics.Meter).Count(); have != want {
+			t.Errorf("%s count mismatch: have %d, want %d", name, have, want)
+		}
+	}
+	if have := metrics.DefaultRegistry.Get("lru/test/size").(metrics.Gauge).Value(); have != 1 {
+		t.Errorf("size mismatch: have %d, want %d", have, 1)
+	}
+}
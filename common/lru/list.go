@@ -0,0 +1,148 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package lru
+
+// entry is an element of a recency list, holding a key and its value.
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+
+	prev, next *entry[K, V]
+}
+
+// basicLRU is a non thread safe, fixed size least recently used list. It's the
+// building block of all the cache policies, which layer several of them.
+type basicLRU[K comparable, V any] struct {
+	items map[K]*entry[K, V]
+	root  entry[K, V] // Sentinel of the ring, root.next is the most recent item
+	size  int
+}
+
+// newBasicLRU creates a least recently used list holding at most size items.
+func newBasicLRU[K comparable, V any](size int) *basicLRU[K, V] {
+	l := &basicLRU[K, V]{
+		items: make(map[K]*entry[K, V]),
+		size:  size,
+	}
+	l.root.prev, l.root.next = &l.root, &l.root
+	return l
+}
+
+// link inserts an entry at the front of the list.
+func (l *basicLRU[K, V]) link(e *entry[K, V]) {
+	e.prev, e.next = &l.root, l.root.next
+	l.root.next.prev = e
+	l.root.next = e
+}
+
+// unlink detaches an entry from the list.
+func (l *basicLRU[K, V]) unlink(e *entry[K, V]) {
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = nil, nil
+}
+
+// add inserts or updates an item, making it the most recent one. If the list is
+// full, the least recent item is evicted and returned.
+func (l *basicLRU[K, V]) add(key K, value V) (K, V, bool) {
+	if e, ok := l.items[key]; ok {
+		e.value = value
+		l.unlink(e)
+		l.link(e)
+
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	e := &entry[K, V]{key: key, value: value}
+	l.items[key] = e
+	l.link(e)
+
+	if len(l.items) > l.size {
+		return l.removeOldest()
+	}
+	var (
+		k K
+		v V
+	)
+	return k, v, false
+}
+
+// get retrieves an item, making it the most recent one.
+func (l *basicLRU[K, V]) get(key K) (V, bool) {
+	e, ok := l.items[key]
+	if !ok {
+		var v V
+		return v, false
+	}
+	l.unlink(e)
+	l.link(e)
+	return e.value, true
+}
+
+// peek retrieves an item without updating its recency.
+func (l *basicLRU[K, V]) peek(key K) (V, bool) {
+	if e, ok := l.items[key]; ok {
+		return e.value, true
+	}
+	var v V
+	return v, false
+}
+
+// contains checks whether an item is in the list, without updating its recency.
+func (l *basicLRU[K, V]) contains(key K) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+// remove drops an item from the list, returning whether it was present.
+func (l *basicLRU[K, V]) remove(key K) bool {
+	e, ok := l.items[key]
+	if !ok {
+		return false
+	}
+	l.unlink(e)
+	delete(l.items, key)
+	return true
+}
+
+// removeOldest drops the least recent item from the list and returns it.
+func (l *basicLRU[K, V]) removeOldest() (K, V, bool) {
+	e := l.root.prev
+	if e == &l.root {
+		var (
+			k K
+			v V
+		)
+		return k, v, false
+	}
+	l.unlink(e)
+	delete(l.items, e.key)
+	return e.key, e.value, true
+}
+
+// len returns the number of items in the list.
+func (l *basicLRU[K, V]) len() int {
+	return len(l.items)
+}
+
+// purge drops all items from the list.
+func (l *basicLRU[K, V]) purge() {
+	l.items = make(map[K]*entry[K, V])
+	l.root.prev, l.root.next = &l.root, &l.root
+}
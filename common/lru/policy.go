@@ -0,0 +1,298 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package lru
+
+const (
+	// twoQueueRecentRatio is the share of a 2Q cache reserved for items only seen
+	// once, the rest being reserved for the frequently used ones.
+	twoQueueRecentRatio = 0.25
+
+	// twoQueueGhostRatio is the size of the 2Q history of items evicted from the
+	// recent queue, relative to the size of the cache.
+	twoQueueGhostRatio = 0.5
+)
+
+// policy is a non thread safe eviction strategy over a fixed number of items.
+type policy[K comparable, V any] interface {
+	get(key K) (V, bool)
+	peek(key K) (V, bool)
+	add(key K, value V) bool // Returns whether an item was evicted to make room
+	contains(key K) bool
+	remove(key K) bool
+	len() int
+	purge()
+}
+
+// lruPolicy evicts the least recently used item.
+type lruPolicy[K comparable, V any] struct {
+	*basicLRU[K, V]
+}
+
+func (p lruPolicy[K, V]) add(key K, value V) bool {
+	_, _, evicted := p.basicLRU.add(key, value)
+	return evicted
+}
+
+// twoQueuePolicy keeps the items seen once apart from the frequently used ones,
+// so a scan over many items doesn't flush the frequently used ones. Items evicted
+// from the recent queue are remembered for a while to promote them on a re-add.
+type twoQueuePolicy[K comparable, V any] struct {
+	size       int
+	recentSize int
+
+	recent      *basicLRU[K, V]
+	frequent    *basicLRU[K, V]
+	recentEvict *basicLRU[K, struct{}]
+}
+
+func newTwoQueuePolicy[K comparable, V any](size int) *twoQueuePolicy[K, V] {
+	recentSize := int(float64(size) * twoQueueRecentRatio)
+	if recentSize < 1 {
+		recentSize = 1
+	}
+	ghostSize := int(float64(size) * twoQueueGhostRatio)
+	if ghostSize < 1 {
+		ghostSize = 1
+	}
+	return &twoQueuePolicy[K, V]{
+		size:        size,
+		recentSize:  recentSize,
+		recent:      newBasicLRU[K, V](size),
+		frequent:    newBasicLRU[K, V](size),
+		recentEvict: newBasicLRU[K, struct{}](ghostSize),
+	}
+}
+
+func (p *twoQueuePolicy[K, V]) get(key K) (V, bool) {
+	if value, ok := p.frequent.get(key); ok {
+		return value, true
+	}
+	// Items hit a second time are promoted to the frequently used ones
+	if value, ok := p.recent.peek(key); ok {
+		p.recent.remove(key)
+		p.frequent.add(key, value)
+		return value, true
+	}
+	var v V
+	return v, false
+}
+
+func (p *twoQueuePolicy[K, V]) peek(key K) (V, bool) {
+	if value, ok := p.frequent.peek(key); ok {
+		return value, true
+	}
+	return p.recent.peek(key)
+}
+
+func (p *twoQueuePolicy[K, V]) add(key K, value V) bool {
+	if p.frequent.contains(key) {
+		p.frequent.add(key, value)
+		return false
+	}
+	if p.recent.contains(key) {
+		p.recent.remove(key)
+		p.frequent.add(key, value)
+		return false
+	}
+	if p.recentEvict.contains(key) {
+		evicted := p.ensureSpace(true)
+		p.recentEvict.remove(key)
+		p.frequent.add(key, value)
+		return evicted
+	}
+	evicted := p.ensureSpace(false)
+	p.recent.add(key, value)
+	return evicted
+}
+
+// ensureSpace evicts an item if the cache is full, preferring the recent queue
+// if it's above its target size.
+func (p *twoQueuePolicy[K, V]) ensureSpace(recentEvict bool) bool {
+	recentLen, frequentLen := p.recent.len(), p.frequent.len()
+	if recentLen+frequentLen < p.size {
+		return false
+	}
+	if recentLen > 0 && (recentLen > p.recentSize || (recentLen == p.recentSize && !recentEvict)) {
+		key, _, _ := p.recent.removeOldest()
+		p.recentEvict.add(key, struct{}{})
+		return true
+	}
+	_, _, evicted := p.frequent.removeOldest()
+	return evicted
+}
+
+func (p *twoQueuePolicy[K, V]) contains(key K) bool {
+	return p.frequent.contains(key) || p.recent.contains(key)
+}
+
+func (p *twoQueuePolicy[K, V]) remove(key K) bool {
+	removed := p.frequent.remove(key)
+	removed = p.recent.remove(key) || removed
+	p.recentEvict.remove(key)
+	return removed
+}
+
+func (p *twoQueuePolicy[K, V]) len() int {
+	return p.recent.len() + p.frequent.len()
+}
+
+func (p *twoQueuePolicy[K, V]) purge() {
+	p.recent.purge()
+	p.frequent.purge()
+	p.recentEvict.purge()
+}
+
+// arcPolicy is an adaptive replacement cache, balancing between the recently and
+// the frequently used items based on the hits on the history of the evictions of
+// each.
+type arcPolicy[K comparable, V any] struct {
+	size int
+	p    int // Target size of the recent list, adapted on ghost hits
+
+	t1 *basicLRU[K, V]        // Items seen once recently
+	b1 *basicLRU[K, struct{}] // History of the items evicted from t1
+	t2 *basicLRU[K, V]        // Items seen at least twice recently
+	b2 *basicLRU[K, struct{}] // History of the items evicted from t2
+}
+
+func newARCPolicy[K comparable, V any](size int) *arcPolicy[K, V] {
+	return &arcPolicy[K, V]{
+		size: size,
+		t1:   newBasicLRU[K, V](size),
+		b1:   newBasicLRU[K, struct{}](size),
+		t2:   newBasicLRU[K, V](size),
+		b2:   newBasicLRU[K, struct{}](size),
+	}
+}
+
+func (p *arcPolicy[K, V]) get(key K) (V, bool) {
+	// Items hit a second time are promoted to the frequently used ones
+	if value, ok := p.t1.peek(key); ok {
+		p.t1.remove(key)
+		p.t2.add(key, value)
+		return value, true
+	}
+	return p.t2.get(key)
+}
+
+func (p *arcPolicy[K, V]) peek(key K) (V, bool) {
+	if value, ok := p.t1.peek(key); ok {
+		return value, true
+	}
+	return p.t2.peek(key)
+}
+
+func (p *arcPolicy[K, V]) add(key K, value V) bool {
+	if p.t1.contains(key) {
+		p.t1.remove(key)
+		p.t2.add(key, value)
+		return false
+	}
+	if p.t2.contains(key) {
+		p.t2.add(key, value)
+		return false
+	}
+	var evicted bool
+	if p.b1.contains(key) {
+		// Recently evicted item requested again, favour recency
+		delta := 1
+		if b1Len, b2Len := p.b1.len(), p.b2.len(); b2Len > b1Len {
+			delta = b2Len / b1Len
+		}
+		if p.p+delta >= p.size {
+			p.p = p.size
+		} else {
+			p.p += delta
+		}
+		if p.t1.len()+p.t2.len() >= p.size {
+			evicted = p.replace(false)
+		}
+		p.b1.remove(key)
+		p.t2.add(key, value)
+		return evicted
+	}
+	if p.b2.contains(key) {
+		// Frequently used item requested again, favour frequency
+		delta := 1
+		if b1Len, b2Len := p.b1.len(), p.b2.len(); b1Len > b2Len {
+			delta = b1Len / b2Len
+		}
+		if delta >= p.p {
+			p.p = 0
+		} else {
+			p.p -= delta
+		}
+		if p.t1.len()+p.t2.len() >= p.size {
+			evicted = p.replace(true)
+		}
+		p.b2.remove(key)
+		p.t2.add(key, value)
+		return evicted
+	}
+	if p.t1.len()+p.t2.len() >= p.size {
+		evicted = p.replace(false)
+	}
+	// Keep the histories bounded by the target sizes
+	if p.b1.len() > p.size-p.p {
+		p.b1.removeOldest()
+	}
+	if p.b2.len() > p.p {
+		p.b2.removeOldest()
+	}
+	p.t1.add(key, value)
+	return evicted
+}
+
+// replace evicts an item from either list into its history, based on the target
+// size of the recent list.
+func (p *arcPolicy[K, V]) replace(b2ContainsKey bool) bool {
+	if t1Len := p.t1.len(); t1Len > 0 && (t1Len > p.p || (t1Len == p.p && b2ContainsKey)) {
+		key, _, ok := p.t1.removeOldest()
+		if ok {
+			p.b1.add(key, struct{}{})
+		}
+		return ok
+	}
+	key, _, ok := p.t2.removeOldest()
+	if ok {
+		p.b2.add(key, struct{}{})
+	}
+	return ok
+}
+
+func (p *arcPolicy[K, V]) contains(key K) bool {
+	return p.t1.contains(key) || p.t2.contains(key)
+}
+
+func (p *arcPolicy[K, V]) remove(key K) bool {
+	removed := p.t1.remove(key)
+	removed = p.t2.remove(key) || removed
+	p.b1.remove(key)
+	p.b2.remove(key)
+	return removed
+}
+
+func (p *arcPolicy[K, V]) len() int {
+	return p.t1.len() + p.t2.len()
+}
+
+func (p *arcPolicy[K, V]) purge() {
+	p.t1.purge()
+	p.b1.purge()
+	p.t2.purge()
+	p.b2.purge()
+}
@@ -45,6 +45,7 @@ var (
 	errTooManyUncles    = errors.New("too many uncles")
 	errInvalidNonce     = errors.New("invalid nonce")
 	errInvalidUncleHash = errors.New("invalid uncle hash")
+	errNoSnapshots      = errors.New("voting snapshots not supported")
 )
 
 // Beacon is a consensus engine that combines the eth1 consensus and proof-of-stake
@@ -75,6 +76,18 @@ func (beacon *Beacon) Author(header *types.Header) (common.Address, error) {
 	return header.Coinbase, nil
 }
 
+// FeeRecipient implements consensus.FeeRecipientResolver, returning the account
+// the transaction fees of a block are credited to.
+func (beacon *Beacon) FeeRecipient(header *types.Header) (common.Address, error) {
+	if !beacon.IsPoSHeader(header) {
+		if resolver, ok := beacon.ethone.(consensus.FeeRecipientResolver); ok {
+			return resolver.FeeRecipient(header)
+		}
+		return beacon.ethone.Author(header)
+	}
+	return header.Coinbase, nil
+}
+
 // VerifyHeader checks whether a header conforms to the consensus rules of the
 // stock Ethereum consensus engine.
 func (beacon *Beacon) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
@@ -357,6 +370,44 @@ func (beacon *Beacon) SetThreads(threads int) {
 	}
 }
 
+// PrewarmSeal precomputes the seal verification of a header. Delegate the call
+// to the eth1 engine if it supports it.
+func (beacon *Beacon) PrewarmSeal(header *types.Header) {
+	if pw, ok := beacon.ethone.(consensus.SealPrewarmer); ok {
+		pw.PrewarmSeal(header)
+	}
+}
+
+// PermitsSender returns whether an account may send transactions in a child block
+// of the given parent. Delegate the call to the eth1 engine if it restricts them.
+func (beacon *Beacon) PermitsSender(chain consensus.ChainHeaderReader, parent *types.Header, sender common.Address) bool {
+	if permitter, ok := beacon.ethone.(consensus.SenderPermitter); ok {
+		return permitter.PermitsSender(chain, parent, sender)
+	}
+	return true
+}
+
+// VerifyState checks a block against the state of its parent. Delegate the call
+// to the eth1 engine for pre-merge blocks if it has state dependent rules.
+func (beacon *Beacon) VerifyState(chain consensus.ChainHeaderReader, block *types.Block, state *state.StateDB) error {
+	if beacon.IsPoSHeader(block.Header()) {
+		return nil
+	}
+	if verifier, ok := beacon.ethone.(consensus.StateVerifier); ok {
+		return verifier.VerifyState(chain, block, state)
+	}
+	return nil
+}
+
+// CheckpointSnapshot retrieves the encoded voting snapshot of a checkpoint. Delegate
+// the call to the eth1 engine if it can serve them.
+func (beacon *Beacon) CheckpointSnapshot(hash common.Hash) ([]byte, error) {
+	if server, ok := beacon.ethone.(consensus.SnapshotServer); ok {
+		return server.CheckpointSnapshot(hash)
+	}
+	return nil, errNoSnapshots
+}
+
 // IsTTDReached checks if the TotalTerminalDifficulty has been surpassed on the `parentHash` block.
 // It depends on the parentHash already being stored in the database.
 // If the parentHash is not stored in the database a UnknownAncestor error is returned.
@@ -0,0 +1,236 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// alertMissedSlots is the number of consecutive in-turn slots the local signer
+	// needs to miss for an alert to be raised.
+	alertMissedSlots = 3
+
+	// alertRebuildTime is the time a snapshot rebuild needs to exceed for an alert
+	// to be raised.
+	alertRebuildTime = time.Minute
+
+	// alertTimeout is the time an alert hook is given to deliver an alert.
+	alertTimeout = 10 * time.Second
+)
+
+// AlertKind is the type of a consensus anomaly reported to the alert hooks.
+type AlertKind string
+
+const (
+	AlertMissedSlots    AlertKind = "missedSlots"    // Local signer missed consecutive in-turn slots
+	AlertSignersChanged AlertKind = "signersChanged" // Signer set changed in a canonical block
+	AlertSlowRebuild    AlertKind = "slowRebuild"    // Snapshot rebuild took exceedingly long
+)
+
+// Alert is a consensus anomaly reported to the alert hooks.
+type Alert struct {
+	Kind    AlertKind        `json:"kind"`              // Type of the anomaly
+	Number  uint64           `json:"number"`            // Block number the anomaly was detected at
+	Hash    common.Hash      `json:"hash"`              // Block hash the anomaly was detected at
+	Message string           `json:"message"`           // Human readable description of the anomaly
+	Signers []common.Address `json:"signers,omitempty"` // Signer set after the change (signer set changes)
+	Time    time.Time        `json:"time"`              // Time the anomaly was detected at
+}
+
+// AlertHook is notified of the consensus anomalies detected by the engine, e.g.
+// to page the operators without scraping the logs. Alerts are delivered in the
+// background, an error is only logged.
+type AlertHook interface {
+	Alert(alert *Alert) error
+}
+
+// AlertHooks is a set of alert hooks all notified of every alert.
+type AlertHooks []AlertHook
+
+// Alert implements AlertHook, notifying every hook of the set and returning the
+// first error encountered.
+func (hooks AlertHooks) Alert(alert *Alert) error {
+	var first error
+	for _, hook := range hooks {
+		if err := hook.Alert(alert); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// webhookAlertHook posts the JSON encoded alerts to an HTTP endpoint.
+type webhookAlertHook struct {
+	url    string
+	client *http.Client
+}
+
+// WebhookAlertHook creates an alert hook posting the JSON encoded alerts to the
+// given URL.
+func WebhookAlertHook(url string) AlertHook {
+	return &webhookAlertHook{url: url, client: &http.Client{Timeout: alertTimeout}}
+}
+
+// Alert implements AlertHook.
+func (h *webhookAlertHook) Alert(alert *Alert) error {
+	blob, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	res, err := h.client.Post(h.url, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+// execAlertHook runs a command for every alert, feeding it the JSON encoded
+// alert on its standard input.
+type execAlertHook struct {
+	command string
+	args    []string
+}
+
+// ExecAlertHook creates an alert hook running the given command for every alert,
+// with the JSON encoded alert on its standard input.
+func ExecAlertHook(command string, args ...string) AlertHook {
+	return &execAlertHook{command: command, args: args}
+}
+
+// Alert implements AlertHook.
+func (h *execAlertHook) Alert(alert *Alert) error {
+	blob, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.command, h.args...)
+	cmd.Stdin = bytes.NewReader(blob)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// SetAlertHook sets the hook to notify of the consensus anomalies detected by the
+// engine, nil disabling alerting.
+func (c *Clique) SetAlertHook(hook AlertHook) {
+	c.alertLock.Lock()
+	defer c.alertLock.Unlock()
+
+	c.alerts = hook
+}
+
+// alert delivers an alert to the configured hook in the background, if any.
+func (c *Clique) alert(alert *Alert) {
+	c.alertLock.Lock()
+	hook := c.alerts
+	c.alertLock.Unlock()
+
+	if hook == nil {
+		return
+	}
+	alert.Time = time.Now()
+	log.Warn("Raising consensus alert", "kind", alert.Kind, "number", alert.Number, "message", alert.Message)
+
+	if !c.track() {
+		return
+	}
+	go func() {
+		defer c.wg.Done()
+		if err := hook.Alert(alert); err != nil {
+			log.Warn("Failed to deliver consensus alert", "kind", alert.Kind, "err", err)
+		}
+	}()
+}
+
+// checkAlerts inspects a new canonical head extending the previous one for the
+// anomalies alerted on: the local signer missing its in-turn slots and changes
+// of the signer set. The caller must hold the head lock.
+func (c *Clique) checkAlerts(chain consensus.ChainHeaderReader, head *types.Header) {
+	c.alertLock.Lock()
+	hook := c.alerts
+	c.alertLock.Unlock()
+
+	c.lock.RLock()
+	local := c.signer
+	c.lock.RUnlock()
+
+	if hook == nil {
+		return
+	}
+	number := head.Number.Uint64()
+	parent, err := c.snapshot(chain, number-1, head.ParentHash, nil)
+	if err != nil {
+		return
+	}
+	snap, err := c.snapshot(chain, number, head.Hash(), nil)
+	if err != nil {
+		return
+	}
+	if signer, err := ecrecover(head, c.signatures); err == nil && local != (common.Address{}) {
+		if rec := parent.seal(head, signer); rec.Turn == local {
+			if rec.missed() {
+				c.missedSlots++
+				if c.missedSlots == alertMissedSlots {
+					c.alert(&Alert{
+						Kind:    AlertMissedSlots,
+						Number:  number,
+						Hash:    head.Hash(),
+						Message: fmt.Sprintf("local signer %s missed %d consecutive in-turn slots", local.Hex(), c.missedSlots),
+					})
+				}
+			} else {
+				c.missedSlots = 0
+			}
+		}
+	}
+	changed := len(parent.Signers) != len(snap.Signers)
+	for signer := range snap.Signers {
+		if _, ok := parent.Signers[signer]; !ok {
+			changed = true
+			break
+		}
+	}
+	if changed {
+		c.alert(&Alert{
+			Kind:    AlertSignersChanged,
+			Number:  number,
+			Hash:    head.Hash(),
+			Message: fmt.Sprintf("signer set changed from %d to %d signers", len(parent.Signers), len(snap.Signers)),
+			Signers: snap.signers(),
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testerAlertHook records the alerts it's notified of.
+type testerAlertHook struct {
+	alerts []*Alert
+	lock   sync.Mutex
+}
+
+func (h *testerAlertHook) Alert(alert *Alert) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.alerts = append(h.alerts, alert)
+	return nil
+}
+
+// Tests that an alert is raised once the local signer misses the configured
+// number of consecutive in-turn slots.
+func TestMissedSlotsAlert(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		signers  = []string{"A", "B", "C", "D"}
+		engine   = New(config, rawdb.NewMemoryDatabase())
+		hook     = new(testerAlertHook)
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	engine.Authorize(accounts.address("A"), nil)
+	engine.SetAlertHook(hook)
+	engine.NewChainHead(chain, genesis)
+
+	// Seal every block in-turn, except the ones of the local signer, which are
+	// sealed by whichever signer didn't seal recently
+	var recents []string
+	for i := 1; i <= 3*len(signers); i++ {
+		parent := chain.headers[i-1]
+		snap, err := engine.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+		if err != nil {
+			t.Fatalf("failed to create snapshot %d: %v", i-1, err)
+		}
+		sealer := ""
+		for _, name := range signers {
+			if name == "A" || (len(recents) > 0 && recents[len(recents)-1] == name) || (len(recents) > 1 && recents[len(recents)-2] == name) {
+				continue
+			}
+			if sealer == "" || snap.inturn(uint64(i), accounts.address(name)) {
+				sealer = name
+			}
+		}
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Time:       parent.Time + 1,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, sealer)
+		chain.headers = append(chain.headers, header)
+		recents = append(recents, sealer)
+
+		engine.NewChainHead(chain, header)
+	}
+	engine.Close()
+
+	if len(hook.alerts) != 1 {
+		t.Fatalf("alert count mismatch: have %d, want %d", len(hook.alerts), 1)
+	}
+	if alert := hook.alerts[0]; alert.Kind != AlertMissedSlots {
+		t.Errorf("alert kind mismatch: have %s, want %s", alert.Kind, AlertMissedSlots)
+	}
+}
+
+// Tests that the webhook alert hook posts the JSON encoded alerts and reports the
+// failures of the endpoint.
+func TestWebhookAlertHook(t *testing.T) {
+	var received Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	alert := &Alert{Kind: AlertSignersChanged, Number: 1, Signers: []common.Address{{0x01}}}
+	if err := WebhookAlertHook(server.URL).Alert(alert); err != nil {
+		t.Fatalf("failed to deliver alert: %v", err)
+	}
+	if received.Kind != alert.Kind || received.Number != alert.Number || len(received.Signers) != 1 {
+		t.Errorf("delivered alert mismatch: have %+v, want %+v", received, alert)
+	}
+	if err := WebhookAlertHook(server.URL + "/fail").Alert(alert); err == nil {
+		t.Errorf("failing webhook reported success")
+	}
+}
@@ -17,8 +17,11 @@
 package clique
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -28,8 +31,27 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// adminScope is the authorization scope RPC clients need to be granted to change
+// the proposals and signing behaviour of the node, if the endpoint they connect
+// to restricts access by scope.
+const adminScope = "admin"
+
+// errAdminScope is returned if an RPC client not granted the admin scope tries
+// to change the proposals or signing behaviour of the node.
+var errAdminScope = errors.New("admin scope required")
+
+// requireAdmin ensures the RPC client issuing a call is granted the admin scope.
+func requireAdmin(ctx context.Context) error {
+	if !rpc.PeerInfoFromContext(ctx).HasScope(adminScope) {
+		return errAdminScope
+	}
+	return nil
+}
+
 // API is a user facing RPC API to allow controlling the signer and voting
-// mechanisms of the proof-of-authority scheme.
+// mechanisms of the proof-of-authority scheme. Methods changing the proposals
+// or signing behaviour of the node require the admin scope, while the ones only
+// reporting the state of the chain remain public.
 type API struct {
 	chain  consensus.ChainHeaderReader
 	clique *Clique
@@ -48,7 +70,7 @@ func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
 	if header == nil {
 		return nil, errUnknownBlock
 	}
-	return api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	return api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
 }
 
 // GetSnapshotAtHash retrieves the state snapshot at a given block.
@@ -57,7 +79,160 @@ func (api *API) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
 	if header == nil {
 		return nil, errUnknownBlock
 	}
-	return api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	return api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// SnapshotHash retrieves the hash of the canonical encoding of the state snapshot
+// at a given block, allowing operators to compare the consensus state of several
+// nodes without transferring the snapshots.
+func (api *API) SnapshotHash(number *rpc.BlockNumber) (common.Hash, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return snap.CanonicalHash()
+}
+
+// ImportSnapshot verifies a voting snapshot of a checkpoint block, retrieved from
+// a trusted node, against the local checkpoint header and persists it, allowing
+// the node to skip replaying the headers preceding the checkpoint.
+func (api *API) ImportSnapshot(ctx context.Context, blob json.RawMessage) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	var meta struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := json.Unmarshal(blob, &meta); err != nil {
+		return err
+	}
+	header := api.chain.GetHeaderByHash(meta.Hash)
+	if header == nil {
+		return errUnknownBlock
+	}
+	snap, err := api.clique.importSnapshot(api.chain, header, blob, true)
+	if err != nil {
+		return err
+	}
+	snap.frozen = true
+	api.clique.recents.Add(snap.Hash, snap)
+	return nil
+}
+
+// NewVote creates a subscription that fires for every vote counted in a block of
+// the canonical chain, along with the running tally of the proposal.
+func (api *API) NewVote(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		votes := make(chan *VoteEvent)
+		votesSub := api.clique.SubscribeVotes(votes)
+
+		for {
+			select {
+			case vote := <-votes:
+				notifier.Notify(rpcSub.ID, vote)
+			case <-rpcSub.Err():
+				votesSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				votesSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// SignerLimitChanged creates a subscription that fires whenever the signer limit
+// changes in a block of the canonical chain.
+func (api *API) SignerLimitChanged(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		changes := make(chan *LimitChangeEvent)
+		changesSub := api.clique.SubscribeLimitChanges(changes)
+
+		for {
+			select {
+			case change := <-changes:
+				notifier.Notify(rpcSub.ID, change)
+			case <-rpcSub.Err():
+				changesSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				changesSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// SealFailed creates a subscription that fires whenever the local signer misses
+// its in-turn slot because signing the block kept failing.
+func (api *API) SealFailed(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		failures := make(chan *SealFailureEvent)
+		failuresSub := api.clique.SubscribeSealFailures(failures)
+
+		for {
+			select {
+			case failure := <-failures:
+				notifier.Notify(rpcSub.ID, failure)
+			case <-rpcSub.Err():
+				failuresSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				failuresSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// NewState creates a subscription that fires with the full consensus state of
+// every new canonical chain head the engine is anchored on.
+func (api *API) NewState(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		states := make(chan *State)
+		statesSub := api.clique.SubscribeState(states)
+
+		for {
+			select {
+			case state := <-states:
+				notifier.Notify(rpcSub.ID, state)
+			case <-rpcSub.Err():
+				statesSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				statesSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
 }
 
 // GetSigners retrieves the list of authorized signers at the specified block.
@@ -73,11 +248,11 @@ func (api *API) GetSigners(number *rpc.BlockNumber) ([]common.Address, error) {
 	if header == nil {
 		return nil, errUnknownBlock
 	}
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
 	if err != nil {
 		return nil, err
 	}
-	return snap.signers(), nil
+	return snap.SignerList(), nil
 }
 
 // GetSignersAtHash retrieves the list of authorized signers at the specified block.
@@ -86,58 +261,228 @@ func (api *API) GetSignersAtHash(hash common.Hash) ([]common.Address, error) {
 	if header == nil {
 		return nil, errUnknownBlock
 	}
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
 	if err != nil {
 		return nil, err
 	}
-	return snap.signers(), nil
+	return snap.SignerList(), nil
+}
+
+// signersInfo is the signer set at a block along with the signer limit in force
+// and the number of votes it takes to pass a proposal.
+type signersInfo struct {
+	Number      uint64           `json:"number"`      // Number of the block the signers are reported at
+	Hash        common.Hash      `json:"hash"`        // Hash of the block the signers are reported at
+	Signers     []common.Address `json:"signers"`     // Authorized signers in ascending order
+	SignerLimit uint             `json:"signerLimit"` // Signer limit in force after the block
+	Absolute    bool             `json:"absolute"`    // Whether the signer limit is a vote count instead of a percentage
+	Threshold   uint             `json:"threshold"`   // Number of votes needed to pass a proposal after the block
 }
 
-// Proposals returns the current proposals the node tries to uphold and vote on.
-func (api *API) Proposals() map[common.Address]bool {
+// GetSignersInfoAtHash retrieves the list of authorized signers at the specified
+// block, along with the signer limit and effective voting threshold in force.
+func (api *API) GetSignersInfoAtHash(hash common.Hash) (*signersInfo, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &signersInfo{
+		Number:      snap.Number,
+		Hash:        snap.Hash,
+		Signers:     snap.SignerList(),
+		SignerLimit: snap.Limit(),
+		Absolute:    api.clique.config.AbsoluteSignerLimit,
+		Threshold:   snap.Threshold(),
+	}, nil
+}
+
+// GetGovernanceReceipt retrieves the governance effects of the block with the
+// given hash, derived from its header. The receipt embedded into the body isn't
+// trusted, as the header doesn't commit to it.
+func (api *API) GetGovernanceReceipt(hash common.Hash) (*GovernanceReceipt, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	if header.Number.Sign() == 0 {
+		return &GovernanceReceipt{Votes: []ReceiptVote{}, Resolutions: []ReceiptResolution{}}, nil
+	}
+	return api.clique.receipt(api.chain, header)
+}
+
+// queuedProposal is an authorization proposal the node tries to uphold.
+type queuedProposal struct {
+	Authorize bool   `json:"authorize"`      // Whether to authorize or deauthorize the account
+	Memo      string `json:"memo,omitempty"` // Justification the operator attached to the proposal
+}
+
+// Proposals returns the current proposals the node tries to uphold and vote on,
+// along with the memos attached to them.
+func (api *API) Proposals() map[common.Address]queuedProposal {
 	api.clique.lock.RLock()
 	defer api.clique.lock.RUnlock()
 
-	proposals := make(map[common.Address]bool)
+	proposals := make(map[common.Address]queuedProposal)
 	for address, auth := range api.clique.proposals {
-		proposals[address] = auth
+		proposals[address] = queuedProposal{Authorize: auth, Memo: api.clique.proposalInfo[address].Memo}
 	}
 	return proposals
 }
 
-// Propose injects a new authorization proposal that the signer will attempt to
-// push through.
-func (api *API) Propose(address common.Address, auth bool) {
+// proposalTarget is the target of clique_propose: either a single account whose
+// authorization is given separately, or a batch of proposals each carrying its
+// own authorization.
+type proposalTarget struct {
+	Address *common.Address
+	Batch   []ProposalEntry
+}
+
+func (t *proposalTarget) UnmarshalJSON(data []byte) error {
+	var batch []ProposalEntry
+	if err := json.Unmarshal(data, &batch); err == nil {
+		t.Address, t.Batch = nil, batch
+		return nil
+	}
+	address := new(common.Address)
+	if err := json.Unmarshal(data, address); err != nil {
+		return err
+	}
+	t.Address, t.Batch = address, nil
+	return nil
+}
+
+// Propose injects new authorization proposals that the signer will attempt to
+// push through. It accepts either a single account along with its authorization,
+// or a list of (address, authorize) pairs without one. A batch is validated as a
+// whole against the current signer set and rejected entirely if any proposal of
+// it doesn't make sense after the ones before it passed.
+//
+// An optional memo justifying the proposals is recorded along with them, and
+// reported in the governance history of the accounts voted on. Proposals of a
+// batch may carry their own memos instead.
+//
+// Proposals already queued are kept as they are, only their memos updated if
+// new ones are given. Accounts queued with the opposite authorization are refused
+// until their pending proposal is discarded.
+func (api *API) Propose(ctx context.Context, target proposalTarget, auth *bool, memo *string) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if target.Address != nil {
+		if auth == nil {
+			return errMissingAuthorization
+		}
+		api.clique.lock.Lock()
+		defer api.clique.lock.Unlock()
+
+		queued, err := api.clique.proposalQueued(*target.Address, *auth)
+		if err != nil {
+			return err
+		}
+		if !queued {
+			api.clique.queueProposal(*target.Address, *auth, 0)
+		}
+		if memo != nil && *memo != "" {
+			api.clique.setMemo(*target.Address, *auth, *memo, api.chain.CurrentHeader().Number.Uint64())
+		}
+		return nil
+	}
+	if auth != nil {
+		return errBatchAuthorization
+	}
+	if len(target.Batch) == 0 {
+		return errEmptyBatch
+	}
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	if err := validateProposals(snap, target.Batch); err != nil {
+		return err
+	}
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	queued := make([]bool, len(target.Batch))
+	for i, entry := range target.Batch {
+		if queued[i], err = api.clique.proposalQueued(entry.Address, entry.Authorize); err != nil {
+			return fmt.Errorf("proposal %d (%s): %w", i, entry.Address, err)
+		}
+	}
+	for i, entry := range target.Batch {
+		if !queued[i] {
+			api.clique.queueProposal(entry.Address, entry.Authorize, 0)
+		}
+		if entry.Memo == "" && memo != nil {
+			entry.Memo = *memo
+		}
+		if entry.Memo != "" {
+			api.clique.setMemo(entry.Address, entry.Authorize, entry.Memo, header.Number.Uint64())
+		}
+	}
+	return nil
+}
+
+// ProposeWithPriority injects a new authorization proposal with the given priority,
+// used for ordering the votes if the signer runs the priority proposal strategy.
+// Accounts queued with the opposite authorization are refused.
+func (api *API) ProposeWithPriority(ctx context.Context, address common.Address, auth bool, priority int) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
 	api.clique.lock.Lock()
 	defer api.clique.lock.Unlock()
 
-	api.clique.proposals[address] = auth
+	if _, err := api.clique.proposalQueued(address, auth); err != nil {
+		return err
+	}
+	api.clique.queueProposal(address, auth, priority)
+	return nil
 }
 
-func (api *API) Votingpercentage(votingType int, percentage uint, auth bool) bool {
+func (api *API) Votingpercentage(ctx context.Context, votingType int, percentage uint, auth bool) (bool, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return false, err
+	}
+
+	// Refuse limits still cooling down after a change, they'd be dropped unvoted
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return false, err
+	}
+	if wait := snap.limitWait(percentage, header.Number.Uint64()+1); wait != nil {
+		return false, wait
+	}
 	api.clique.lock.Lock()
 	defer api.clique.lock.Unlock()
 
-	if percentage > 0 && percentage < 100 {
+	if percentage > 0 && (api.clique.config.AbsoluteSignerLimit || percentage < 100) {
 		for k := range api.clique.signerLimitProposals {
 
 			delete(api.clique.signerLimitProposals, k)
 		}
 		api.clique.signerLimitProposals[percentage] = auth
-		return true
+		api.clique.storeProposals()
+		return true, nil
 	} else {
-		return false
+		return false, nil
 	}
 }
 
 func (api *API) Currentvotingpercentage() uint {
-	api.clique.lock.Lock()
-	defer api.clique.lock.Unlock()
-
 	header := api.chain.CurrentHeader()
-	snapshot, _ := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	snapshot, _ := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
 	if snapshot != nil {
-		currentVotingPercentage := snapshot.SignerLimit
+		currentVotingPercentage := snapshot.Limit()
 		return currentVotingPercentage
 	} else {
 		return 0
@@ -146,23 +491,602 @@ func (api *API) Currentvotingpercentage() uint {
 
 // Discard drops a currently running proposal, stopping the signer from casting
 // further votes (either for or against).
-func (api *API) Discard(address common.Address) {
+func (api *API) Discard(ctx context.Context, address common.Address) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.dropProposal(address)
+	return nil
+}
+
+// ProposePermit injects a new sender permission proposal that the signer will
+// attempt to push through, permitting or revoking an account to send transactions.
+func (api *API) ProposePermit(ctx context.Context, address common.Address, permit bool) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.permitProposals[address] = permit
+	api.clique.storeProposals()
+	return nil
+}
+
+// DiscardPermit drops a currently running sender permission proposal, stopping
+// the signer from casting further votes (either for or against).
+func (api *API) DiscardPermit(ctx context.Context, address common.Address) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	delete(api.clique.permitProposals, address)
+	api.clique.storeProposals()
+	return nil
+}
+
+// ProposePeriod injects a new signer period proposal that the signer will attempt
+// to push through, granting a signer a longer minimum block period (or reverting
+// it to the chain period if zero).
+func (api *API) ProposePeriod(ctx context.Context, signer common.Address, period uint64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if period != 0 && (period <= api.clique.config.Period || period > math.MaxUint32) {
+		return errInvalidSignerPeriod
+	}
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.periodProposals[signer] = period
+	api.clique.storeProposals()
+	return nil
+}
+
+// DiscardPeriod drops a currently running signer period proposal, stopping the
+// signer from casting further votes on it.
+func (api *API) DiscardPeriod(ctx context.Context, signer common.Address) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	delete(api.clique.periodProposals, signer)
+	api.clique.storeProposals()
+	return nil
+}
+
+// ProposeReplacement injects a new signer replacement proposal that the signer
+// will attempt to push through, swapping the old signer for the new account in
+// a single step, e.g. to rotate the key of a signer.
+func (api *API) ProposeReplacement(ctx context.Context, old, new common.Address) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if api.clique.config.ReplacementBlock == nil {
+		return errReplacementDisabled
+	}
+	if old == new {
+		return errInvalidReplacement
+	}
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.replaceProposals[old] = new
+	api.clique.storeProposals()
+	return nil
+}
+
+// DiscardReplacement drops a currently running signer replacement proposal,
+// stopping the signer from casting further votes on it.
+func (api *API) DiscardReplacement(ctx context.Context, old common.Address) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
 	api.clique.lock.Lock()
 	defer api.clique.lock.Unlock()
 
-	delete(api.clique.proposals, address)
+	delete(api.clique.replaceProposals, old)
+	api.clique.storeProposals()
+	return nil
+}
+
+// Resign makes the local signer leave the signer set in the next block it seals,
+// without waiting for the other signers to vote it out.
+func (api *API) Resign(ctx context.Context) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if api.clique.config.ResignationBlock == nil {
+		return errResignationDisabled
+	}
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.resigning = true
+	return nil
+}
+
+// DiscardResignation cancels a pending resignation of the local signer, if it
+// didn't seal its resignation block yet.
+func (api *API) DiscardResignation(ctx context.Context) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.resigning = false
+	return nil
+}
+
+// SetFeeRecipient sets the account the local signer declares as the recipient of
+// the transaction fees of its blocks. The zero address credits them to the signer.
+func (api *API) SetFeeRecipient(ctx context.Context, recipient common.Address) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	api.clique.SetFeeRecipient(recipient)
+	return nil
+}
+
+// DiscardAll drops every running proposal, the authorization, signer limit,
+// sender permission, signer period and signer replacement ones, stopping the
+// signer from casting any further votes.
+func (api *API) DiscardAll(ctx context.Context) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.proposals = make(map[common.Address]bool)
+	api.clique.proposalInfo = make(map[common.Address]proposalInfo)
+	api.clique.signerLimitProposals = make(map[uint]bool)
+	api.clique.permitProposals = make(map[common.Address]bool)
+	api.clique.periodProposals = make(map[common.Address]uint64)
+	api.clique.replaceProposals = make(map[common.Address]common.Address)
+	api.clique.storeProposals()
+	return nil
+}
+
+// OverrideHash returns the digest the current signers need to sign offline to
+// authorize replacing the signer set with the given one at a checkpoint block.
+func (api *API) OverrideHash(number uint64, signers []common.Address) (common.Hash, error) {
+	if err := api.checkOverride(number, signers); err != nil {
+		return common.Hash{}, err
+	}
+	return OverrideHash(number, sortedSigners(signers)), nil
+}
+
+// ProposeOverride schedules an emergency replacement of the signer set to be
+// embedded into the given checkpoint block if this node gets to seal it. The
+// signatures are those of the current signers over the override hash.
+func (api *API) ProposeOverride(ctx context.Context, number uint64, signers []common.Address, signatures []hexutil.Bytes) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := api.checkOverride(number, signers); err != nil {
+		return err
+	}
+	override := &signerOverride{Signers: sortedSigners(signers)}
+	for _, sig := range signatures {
+		override.Signatures = append(override.Signatures, sig)
+	}
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	api.clique.overrides[number] = override
+	return nil
+}
+
+// DiscardOverride drops a scheduled signer set override.
+func (api *API) DiscardOverride(ctx context.Context, number uint64) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	api.clique.lock.Lock()
+	defer api.clique.lock.Unlock()
+
+	delete(api.clique.overrides, number)
+	return nil
+}
+
+// checkOverride ensures that a signer set override is permitted and targets an
+// upcoming checkpoint block.
+func (api *API) checkOverride(number uint64, signers []common.Address) error {
+	if !api.clique.config.EmergencyOverride {
+		return errOverrideDisabled
+	}
+	if number%api.clique.config.Epoch != 0 || number <= api.chain.CurrentHeader().Number.Uint64() {
+		return fmt.Errorf("block %d is not an upcoming checkpoint", number)
+	}
+	if len(signers) == 0 {
+		return errInvalidOverride
+	}
+	return nil
+}
+
+// MetadataHash returns the digest the current signers need to sign offline to
+// approve associating the given metadata with a signer.
+func (api *API) MetadataHash(signer common.Address, metadata SignerMetadata) common.Hash {
+	return MetadataHash(signer, &metadata)
+}
+
+// RegisterMetadata stores the metadata of a signer in the local registry. The
+// signatures are those of the current signers over the metadata hash, a strict
+// majority of which is required. Registering empty metadata removes the entry.
+func (api *API) RegisterMetadata(ctx context.Context, signer common.Address, metadata SignerMetadata, signatures []hexutil.Bytes) error {
+	if err := requireAdmin(ctx); err != nil {
+		return err
+	}
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	sigs := make([][]byte, 0, len(signatures))
+	for _, sig := range signatures {
+		sigs = append(sigs, sig)
+	}
+	return api.clique.registerMetadata(snap, signer, &metadata, sigs)
+}
+
+// GetRegistry retrieves the operator metadata of all registered signers.
+func (api *API) GetRegistry() map[common.Address]SignerMetadata {
+	api.clique.lock.RLock()
+	defer api.clique.lock.RUnlock()
+
+	registry := make(map[common.Address]SignerMetadata, len(api.clique.metadata))
+	for signer, metadata := range api.clique.metadata {
+		registry[signer] = *metadata
+	}
+	return registry
+}
+
+// GetResolutions retrieves the governance proposals resolved on the canonical
+// chain within the given block range (defaulting to the entire chain).
+func (api *API) GetResolutions(from, to *rpc.BlockNumber) ([]*Resolution, error) {
+	return api.resolutions(auditPrefix, from, to)
+}
+
+// GetResolutionsBySigner retrieves the governance proposals resolved on the
+// canonical chain within the given block range, which the specified account
+// either voted on or was the target of.
+func (api *API) GetResolutionsBySigner(signer common.Address, from, to *rpc.BlockNumber) ([]*Resolution, error) {
+	return api.resolutions(append(append([]byte{}, auditSignerPrefix...), signer[:]...), from, to)
+}
+
+// GetResolutionsByKind retrieves the governance proposals of the given type
+// resolved on the canonical chain within the given block range.
+func (api *API) GetResolutionsByKind(kind ProposalKind, from, to *rpc.BlockNumber) ([]*Resolution, error) {
+	return api.resolutions(append(append([]byte{}, auditKindPrefix...), byte(kind)), from, to)
+}
+
+// SignerHistory retrieves every block of the canonical chain in which the given
+// account was added to, removed from or re-added to the signer set, along with
+// the voters involved, assembled from the audit index.
+func (api *API) SignerHistory(address common.Address) ([]*SignerEvent, error) {
+	genesis := api.chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return nil, errUnknownBlock
+	}
+	signers, err := checkpointSigners(api.clique.config, genesis)
+	if err != nil {
+		return nil, err
+	}
+	member := false
+	for _, signer := range signers {
+		member = member || signer == address
+	}
+	resolutions, err := api.resolutions(append(append([]byte{}, auditSignerPrefix...), address[:]...), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return signerHistory(address, member, resolutions), nil
+}
+
+// resolutions iterates the audit store under the given prefix, filtering out any
+// resolutions recorded on blocks which are not part of the canonical chain.
+func (api *API) resolutions(prefix []byte, from, to *rpc.BlockNumber) ([]*Resolution, error) {
+	head := api.chain.CurrentHeader().Number.Uint64()
+
+	start, end := uint64(0), head
+	if from != nil && *from > 0 {
+		start = uint64(from.Int64())
+	}
+	if to != nil && *to >= 0 && uint64(to.Int64()) < head {
+		end = uint64(to.Int64())
+	}
+	return api.resolutionsInRange(prefix, start, end)
+}
+
+// resolutionsInRange iterates the audit store under the given prefix within the
+// given block range, filtering out any resolutions recorded on blocks which are
+// not part of the canonical chain.
+func (api *API) resolutionsInRange(prefix []byte, start, end uint64) ([]*Resolution, error) {
+	results := []*Resolution{}
+	err := iterateResolutions(api.clique.db, prefix, start, end, func(res *Resolution) bool {
+		if header := api.chain.GetHeaderByNumber(res.Block); header != nil && header.Hash() == res.Hash {
+			results = append(results, res)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// epochValidators is the signer set and governance activity of a single epoch.
+type epochValidators struct {
+	Epoch       uint64           `json:"epoch"`       // Index of the epoch
+	Number      uint64           `json:"number"`      // Number of the checkpoint block starting the epoch
+	Hash        common.Hash      `json:"hash"`        // Hash of the checkpoint block starting the epoch
+	Signers     []common.Address `json:"signers"`     // Signer set checkpointed at the start of the epoch
+	SignerLimit uint             `json:"signerLimit"` // Signer limit in force at the start of the epoch
+	Threshold   uint             `json:"threshold"`   // Number of votes needed to pass a proposal at the start of the epoch
+	Changes     []*Resolution    `json:"changes"`     // Governance changes that occurred within the epoch
+}
+
+// GetValidatorsAtEpoch retrieves the signer set checkpointed at the start of the
+// given epoch, the signer limit in force and all the governance changes within
+// the epoch (up to the current head if the epoch is still in progress).
+func (api *API) GetValidatorsAtEpoch(epoch uint64) (*epochValidators, error) {
+	number := epoch * api.clique.config.Epoch
+	if number/api.clique.config.Epoch != epoch {
+		return nil, errUnknownBlock
+	}
+	header := api.chain.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, number, header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	end := number + api.clique.config.Epoch - 1
+	if head := api.chain.CurrentHeader().Number.Uint64(); head < end {
+		end = head
+	}
+	changes, err := api.resolutionsInRange(auditPrefix, number, end)
+	if err != nil {
+		return nil, err
+	}
+	return &epochValidators{
+		Epoch:       epoch,
+		Number:      number,
+		Hash:        header.Hash(),
+		Signers:     snap.SignerList(),
+		SignerLimit: snap.Limit(),
+		Threshold:   snap.Threshold(),
+		Changes:     changes,
+	}, nil
+}
+
+// recentSigners is the spam protection state of a block, detailing which signers
+// are barred from sealing its descendants and until when.
+type recentSigners struct {
+	Number   uint64                    `json:"number"`   // Number of the block the recents are reported at
+	Hash     common.Hash               `json:"hash"`     // Hash of the block the recents are reported at
+	Window   uint64                    `json:"window"`   // Number of blocks within which a signer may only seal once
+	Recents  map[uint64]common.Address `json:"recents"`  // Recent signers keyed by the block number they sealed
+	Eligible map[common.Address]uint64 `json:"eligible"` // First block each recent signer may seal again
+}
+
+// GetRecents retrieves the recent signers at the given block (or the current head
+// if none requested), along with the first block each of them may seal again.
+func (api *API) GetRecents(number *rpc.BlockNumber) (*recentSigners, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	return &recentSigners{
+		Number:   snap.Number,
+		Hash:     snap.Hash,
+		Window:   snap.recentsWindow(snap.Number + 1),
+		Recents:  snap.RecentSigners(),
+		Eligible: snap.EligibleBlocks(),
+	}, nil
+}
+
+// inturnStatus is the sealing turn of a signer at an upcoming block.
+type inturnStatus struct {
+	Signer   common.Address `json:"signer"`   // Signer the turn is reported for
+	Number   uint64         `json:"number"`   // Block number the turn is reported at
+	InTurn   bool           `json:"inturn"`   // Whether the signer is in-turn at the block
+	Next     uint64         `json:"next"`     // First block at or after the requested one the signer is in-turn for
+	Distance uint64         `json:"distance"` // Number of blocks from the current head until the next in-turn slot
+}
+
+// InTurn reports whether the signer is in-turn at the given block (or the next
+// one if none or a past one is requested) and how many blocks remain until its
+// next in-turn slot. The projection assumes the signer set of the current head
+// doesn't change in the meantime.
+func (api *API) InTurn(signer common.Address, number *rpc.BlockNumber) (*inturnStatus, error) {
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	target := snap.Number + 1
+	if number != nil && *number > 0 && uint64(*number) > snap.Number {
+		target = uint64(*number)
+	}
+	next, ok := snap.nextInturn(target, signer)
+	if !ok {
+		return nil, errUnauthorizedSigner
+	}
+	return &inturnStatus{
+		Signer:   signer,
+		Number:   target,
+		InTurn:   next == target,
+		Next:     next,
+		Distance: next - snap.Number,
+	}, nil
+}
+
+// checkpointAttestation is the aggregate BLS attestation of a checkpoint, along
+// with everything an external verifier needs to check it.
+type checkpointAttestation struct {
+	Number     uint64           `json:"number"`     // Number of the attested checkpoint
+	Hash       common.Hash      `json:"hash"`       // Hash of the attested checkpoint
+	Message    common.Hash      `json:"message"`    // Digest signed by the attesters
+	Signers    []common.Address `json:"signers"`    // Signers of the attested checkpoint, in ascending order
+	Attesters  []common.Address `json:"attesters"`  // Signers that attested the checkpoint
+	PublicKeys []hexutil.Bytes  `json:"publicKeys"` // BLS public keys of the attesters
+	Signature  hexutil.Bytes    `json:"signature"`  // Aggregated BLS signature of the attesters
+	Embedded   uint64           `json:"embedded"`   // Number of the checkpoint embedding the aggregate
+}
+
+// GetAttestation retrieves the aggregate attestation of the checkpoint with the
+// given number, embedded in the checkpoint following it.
+func (api *API) GetAttestation(number uint64) (*checkpointAttestation, error) {
+	epoch := api.clique.config.Epoch
+	if number%epoch != 0 {
+		return nil, errMissingAttestation
+	}
+	next := api.chain.GetHeaderByNumber(number + epoch)
+	if next == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, number+epoch-1, next.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if snap.AttestTarget != number || snap.AttestHash == (common.Hash{}) || len(snap.Attestations) == 0 || isOverride(next) {
+		return nil, errMissingAttestation
+	}
+	attestation := &checkpointAttestation{
+		Number:    number,
+		Hash:      snap.AttestHash,
+		Message:   AttestationHash(number, snap.AttestHash),
+		Signers:   append([]common.Address{}, snap.AttestSigners...),
+		Signature: common.CopyBytes(snap.AttestSignature),
+		Embedded:  next.Number.Uint64(),
+	}
+	for _, attester := range snap.Attestations {
+		attestation.Attesters = append(attestation.Attesters, attester.Signer)
+		attestation.PublicKeys = append(attestation.PublicKeys, common.CopyBytes(attester.PublicKey))
+	}
+	return attestation, nil
+}
+
+// provenSnapshot retrieves the snapshot of the last checkpoint at or before the
+// given block (or the current head if none requested), ensuring its header
+// commits to the snapshot root.
+func (api *API) provenSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
+	head := api.chain.CurrentHeader().Number.Uint64()
+	if number != nil && *number != rpc.LatestBlockNumber && uint64(number.Int64()) < head {
+		head = uint64(number.Int64())
+	}
+	header := api.chain.GetHeaderByNumber(head / api.clique.config.Epoch * api.clique.config.Epoch)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if root, ok := CheckpointRoot(api.clique.config, header); !ok || root != snap.Root() {
+		return nil, errMismatchingSnapshotRoot
+	}
+	return snap, nil
+}
+
+// GetSignerProof retrieves a Merkle proof that the signer is authorized at the
+// last checkpoint at or before the given block, verifiable against the snapshot
+// root committed to by the checkpoint header.
+func (api *API) GetSignerProof(signer common.Address, number *rpc.BlockNumber) (*SnapshotProof, error) {
+	snap, err := api.provenSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Prove(SignerLeaf(signer))
+}
+
+// GetSignerLimitProof retrieves a Merkle proof of the signer limit in force at
+// the last checkpoint at or before the given block.
+func (api *API) GetSignerLimitProof(number *rpc.BlockNumber) (*SnapshotProof, error) {
+	snap, err := api.provenSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Prove(SignerLimitLeaf(snap.SignerLimit, snap.SignerLimitAffirmed))
+}
+
+// GetSealStats retrieves the number of blocks each signer sealed in-turn and
+// out-of-turn within the most recent blocks.
+func (api *API) GetSealStats() map[common.Address]SealStats {
+	return api.clique.seals.Stats()
+}
+
+// GetOfflineSigners retrieves the signers that didn't seal any of the last rounds
+// of blocks up to the given one (or the current head if none requested), despite
+// being authorized throughout. These are the signers the stall recovery drops.
+func (api *API) GetOfflineSigners(number *rpc.BlockNumber) ([]common.Address, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.clique.OfflineSigners(api.chain, header)
+}
+
+// GetQuorum reports whether the chain stalled because too many signers went
+// offline for the remaining ones to seal blocks.
+func (api *API) GetQuorum() (*QuorumStatus, error) {
+	return api.clique.QuorumStatus(api.chain)
+}
+
+// GetRejections retrieves the number of times the seals of each signer were
+// rejected for being unauthorized or signed too recently, both when verifying
+// blocks and when sealing them locally.
+func (api *API) GetRejections() map[string]map[common.Address]RejectionStats {
+	return map[string]map[common.Address]RejectionStats{
+		stageVerify: api.clique.rejections.Stats(stageVerify),
+		stageSeal:   api.clique.rejections.Stats(stageSeal),
+	}
+}
+
+// GetMissedSlots retrieves the blocks within the most recent ones which the given
+// signer failed to seal in its turn, letting another signer seal them instead.
+func (api *API) GetMissedSlots(signer common.Address) []MissedSlot {
+	return api.clique.seals.Missed(signer)
 }
 
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
 	NumBlocks     uint64                 `json:"numBlocks"`
+
+	Metadata  map[common.Address]SignerMetadata `json:"metadata,omitempty"`
+	LimitWait *LimitWaitError                   `json:"limitWait,omitempty"` // Last signer limit proposal dropped during its cooldown
 }
 
 // Status returns the status of the last N blocks,
 // - the number of active signers,
 // - the number of signers,
 // - the percentage of in-turn blocks
+// - the registered operator metadata of the signers
 func (api *API) Status() (*status, error) {
 	var (
 		numBlocks = uint64(64)
@@ -170,7 +1094,7 @@ func (api *API) Status() (*status, error) {
 		diff      = uint64(0)
 		optimals  = 0
 	)
-	snap, err := api.clique.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -202,10 +1126,16 @@ func (api *API) Status() (*status, error) {
 		}
 		signStatus[sealer]++
 	}
+	api.clique.lock.RLock()
+	limitWait := api.clique.limitWait
+	api.clique.lock.RUnlock()
+
 	return &status{
 		InturnPercent: float64(100*optimals) / float64(numBlocks),
 		SigningStatus: signStatus,
 		NumBlocks:     numBlocks,
+		Metadata:      api.clique.signerMetadata(signers),
+		LimitWait:     limitWait,
 	}, nil
 }
 
@@ -0,0 +1,88 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the signers reported at a block are annotated with the signer limit
+// and voting threshold in force after it.
+func TestGetSignersInfoAtHash(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 2)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+		api      = &API{chain: chain, clique: engine}
+	)
+	// Pass a limit of 75% in the second block, resealing the chain
+	for i, header := range chain.headers[1:] {
+		header.ParentHash = chain.headers[i].Hash()
+		header.Coinbase = common.BigToAddress(big.NewInt(75))
+		header.Nonce = engine.voteNonces().limit
+		accounts.sign(header, []string{"A", "B", "C"}[(i+1)%3])
+	}
+	before, err := api.GetSignersInfoAtHash(chain.headers[0].Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve genesis signers: %v", err)
+	}
+	after, err := api.GetSignersInfoAtHash(chain.headers[2].Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve head signers: %v", err)
+	}
+	if len(before.Signers) != 3 || before.SignerLimit != 50 || before.Threshold != 2 {
+		t.Errorf("genesis signers mismatch: have %+v", before)
+	}
+	if len(after.Signers) != 3 || after.SignerLimit != 75 || after.Threshold != 3 || after.Number != 2 {
+		t.Errorf("head signers mismatch: have %+v", after)
+	}
+	if _, err := api.GetSignersInfoAtHash(common.Hash{0x01}); err != errUnknownBlock {
+		t.Errorf("unknown block error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+}
+
+// Tests that querying the current signer limit doesn't deadlock on the engine
+// lock, which the snapshot retrieval takes by itself.
+func TestCurrentVotingPercentage(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B"}, 1)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+		api      = &API{chain: chain, clique: engine}
+	)
+	done := make(chan uint, 1)
+	go func() { done <- api.Currentvotingpercentage() }()
+
+	select {
+	case limit := <-done:
+		if limit != 50 {
+			t.Errorf("signer limit mismatch: have %d, want %d", limit, 50)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("signer limit query deadlocked")
+	}
+	// The engine must remain usable afterwards
+	engine.Authorize(accounts.address("A"), nil)
+}
@@ -0,0 +1,415 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	blsPublicKeyLength = 192 // Length of an uncompressed BLS public key (G2 point)
+	blsSignatureLength = 96  // Length of an uncompressed BLS signature (G1 point)
+
+	// attestationLength is the length of an attestation in the extra-data of a
+	// non-checkpoint header: the public key of the signer, its proof of possession
+	// and its signature over the last checkpoint.
+	attestationLength = blsPublicKeyLength + 2*blsSignatureLength
+
+	// maxAttesters is the number of signers of a checkpoint that may attest it,
+	// bounded by the size of the participation bitmap.
+	maxAttesters = 256
+
+	// aggregateLength is the length of the aggregate attestation embedded after the
+	// signer list of a checkpoint: the participation bitmap over the signers of the
+	// previous checkpoint, followed by their aggregated signature.
+	aggregateLength = maxAttesters/8 + blsSignatureLength
+)
+
+var (
+	// errInvalidAttestation is returned if a block carries an attestation of a
+	// signer not entitled to attest the last checkpoint, or one already counted,
+	// or one whose key or signature doesn't verify.
+	errInvalidAttestation = errors.New("invalid checkpoint attestation")
+
+	// errMismatchingAttestation is returned if a checkpoint block embeds anything
+	// else than the aggregate of the attestations counted over the epoch.
+	errMismatchingAttestation = errors.New("mismatching aggregate attestation on checkpoint block")
+
+	// errInvalidAttestationKey is returned if a BLS secret key is not a valid
+	// scalar of the curve.
+	errInvalidAttestationKey = errors.New("invalid attestation key")
+
+	// errMissingAttestation is returned if an aggregate attestation is requested
+	// for a block that isn't a checkpoint, or a checkpoint nobody attested.
+	errMissingAttestation = errors.New("checkpoint not attested")
+)
+
+// Attestation is the BLS public key of a signer that attested the last checkpoint
+// within the current epoch. The signatures themselves are only kept aggregated.
+type Attestation struct {
+	Signer    common.Address `json:"signer"`    // Signer that attested the checkpoint
+	PublicKey hexutil.Bytes  `json:"publicKey"` // BLS public key the signer attested with
+}
+
+// AttestationHash returns the digest the signers of a checkpoint sign with their
+// BLS keys to attest it.
+func AttestationHash(number uint64, hash common.Hash) common.Hash {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], number)
+	return crypto.Keccak256Hash([]byte("clique-attestation"), blob[:], hash[:])
+}
+
+// possessionHash returns the digest a signer signs with its BLS key to prove its
+// possession, binding the key to the signer address against rogue key attacks.
+func possessionHash(signer common.Address, pubkey []byte) common.Hash {
+	return crypto.Keccak256Hash([]byte("clique-bls-possession"), signer[:], pubkey)
+}
+
+// hashToG1 maps a digest onto the G1 curve. Since a digest is shorter than the
+// field modulus, it's always a valid field element to map.
+func hashToG1(digest common.Hash) (*bls12381.PointG1, error) {
+	in := make([]byte, 48)
+	copy(in[48-common.HashLength:], digest[:])
+	return bls12381.NewG1().MapToCurve(in)
+}
+
+// blsSecretKey parses a BLS secret key, a big endian scalar of the curve.
+func blsSecretKey(secret []byte) (*big.Int, error) {
+	key := new(big.Int).SetBytes(secret)
+	if key.Sign() == 0 || key.Cmp(bls12381.NewG1().Q()) >= 0 {
+		return nil, errInvalidAttestationKey
+	}
+	return key, nil
+}
+
+// GenerateAttestationKey creates a new random BLS secret key to attest checkpoints.
+func GenerateAttestationKey() ([]byte, error) {
+	for {
+		key, err := crand.Int(crand.Reader, bls12381.NewG1().Q())
+		if err != nil {
+			return nil, err
+		}
+		if key.Sign() != 0 {
+			return common.LeftPadBytes(key.Bytes(), 32), nil
+		}
+	}
+}
+
+// AttestationPublicKey derives the BLS public key of a secret attestation key.
+func AttestationPublicKey(secret []byte) ([]byte, error) {
+	key, err := blsSecretKey(secret)
+	if err != nil {
+		return nil, err
+	}
+	g2 := bls12381.NewG2()
+	return g2.ToBytes(g2.MulScalar(g2.New(), g2.One(), key)), nil
+}
+
+// blsSign signs a digest with a BLS secret key.
+func blsSign(key *big.Int, digest common.Hash) ([]byte, error) {
+	point, err := hashToG1(digest)
+	if err != nil {
+		return nil, err
+	}
+	g1 := bls12381.NewG1()
+	return g1.ToBytes(g1.MulScalar(g1.New(), point, key)), nil
+}
+
+// decodeBLSPublicKey deserializes a BLS public key, ensuring it's a valid, non
+// trivial point of the G2 subgroup.
+func decodeBLSPublicKey(pubkey []byte) (*bls12381.PointG2, error) {
+	g2 := bls12381.NewG2()
+	point, err := g2.FromBytes(pubkey)
+	if err != nil {
+		return nil, err
+	}
+	if g2.IsZero(point) || !g2.InCorrectSubgroup(point) {
+		return nil, errInvalidAttestationKey
+	}
+	return point, nil
+}
+
+// decodeBLSSignature deserializes a BLS signature, ensuring it's a valid point of
+// the G1 subgroup.
+func decodeBLSSignature(signature []byte) (*bls12381.PointG1, error) {
+	g1 := bls12381.NewG1()
+	point, err := g1.FromBytes(signature)
+	if err != nil {
+		return nil, err
+	}
+	if !g1.InCorrectSubgroup(point) {
+		return nil, errInvalidAttestation
+	}
+	return point, nil
+}
+
+// blsVerify checks a BLS signature over a digest against a public key.
+func blsVerify(pubkey *bls12381.PointG2, digest common.Hash, signature *bls12381.PointG1) bool {
+	point, err := hashToG1(digest)
+	if err != nil {
+		return false
+	}
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(signature, bls12381.NewG2().One())
+	engine.AddPairInv(point, pubkey)
+	return engine.Check()
+}
+
+// VerifyAttestation checks an aggregate attestation of the checkpoint with the
+// given number and hash against the BLS public keys of the signers that took
+// part in it. External verifiers holding the keys of a checkpoint's signers can
+// use it to verify the validator set the signers attested to.
+func VerifyAttestation(number uint64, hash common.Hash, pubkeys [][]byte, signature []byte) error {
+	if len(pubkeys) == 0 {
+		return errInvalidAttestation
+	}
+	g2 := bls12381.NewG2()
+	aggregate := g2.Zero()
+	for _, pubkey := range pubkeys {
+		point, err := decodeBLSPublicKey(pubkey)
+		if err != nil {
+			return err
+		}
+		g2.Add(aggregate, aggregate, point)
+	}
+	sig, err := decodeBLSSignature(signature)
+	if err != nil {
+		return err
+	}
+	if !blsVerify(aggregate, AttestationHash(number, hash), sig) {
+		return errInvalidAttestation
+	}
+	return nil
+}
+
+// splitPayload separates the extra-data payload of a non-checkpoint header into
+// its governance votes, checkpoint attestation and finality justification.
+func splitPayload(config *params.CliqueConfig, header *types.Header) ([]byte, []byte, *Justification) {
+	votes, attestation, blob := splitRegularPayload(config, header.Number, headerPayload(config, header))
+
+	var justification *Justification
+	if blob != nil {
+		justification = &Justification{
+			Number: binary.BigEndian.Uint64(blob),
+			Hash:   common.BytesToHash(blob[8:]),
+		}
+	}
+	return votes, attestation, justification
+}
+
+// splitRegularPayload separates the positional extra-data payload of the non-
+// checkpoint header with the given number into its governance votes, checkpoint
+// attestation and encoded finality justification, which are embedded in this
+// order. The sections are told apart by the payload length modulo the length of
+// a governance vote, which differs for every combination.
+func splitRegularPayload(config *params.CliqueConfig, number *big.Int, payload []byte) ([]byte, []byte, []byte) {
+	var (
+		attesting     = config.IsAttestation(number)
+		justification []byte
+		attestation   []byte
+	)
+	if config.IsFinality(number) {
+		rem := len(payload) % governanceVoteLength
+		if rem == justificationLength || (attesting && len(payload) >= attestationLength+justificationLength && rem == (attestationLength+justificationLength)%governanceVoteLength) {
+			justification = payload[len(payload)-justificationLength:]
+			payload = payload[:len(payload)-justificationLength]
+		}
+	}
+	if attesting && len(payload) >= attestationLength && len(payload)%governanceVoteLength == attestationLength%governanceVoteLength {
+		attestation = payload[len(payload)-attestationLength:]
+		payload = payload[:len(payload)-attestationLength]
+	}
+	return payload, attestation, justification
+}
+
+// attesting returns whether the signer may still attest the current target.
+func (s *Snapshot) attesting(signer common.Address) bool {
+	if s.AttestHash == (common.Hash{}) {
+		return false
+	}
+	for _, attestation := range s.Attestations {
+		if attestation.Signer == signer {
+			return false
+		}
+	}
+	return s.attesterIndex(signer) >= 0
+}
+
+// attesterIndex returns the position of the signer in the signer list of the
+// attested checkpoint, or -1 if it may not attest it.
+func (s *Snapshot) attesterIndex(signer common.Address) int {
+	for i, attester := range s.AttestSigners {
+		if attester == signer && i < maxAttesters {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyAttestation verifies and counts the attestation embedded into a header,
+// folding its signature into the running aggregate of the epoch.
+func (s *Snapshot) applyAttestation(header *types.Header, signer common.Address) error {
+	if !s.config.IsAttestation(header.Number) || header.Number.Uint64()%s.config.Epoch == 0 {
+		return nil
+	}
+	_, blob, _ := splitPayload(s.config, header)
+	if blob == nil {
+		return nil
+	}
+	if !s.attesting(signer) {
+		return errInvalidAttestation
+	}
+	var (
+		pubkey    = blob[:blsPublicKeyLength]
+		possesion = blob[blsPublicKeyLength : blsPublicKeyLength+blsSignatureLength]
+		signature = blob[blsPublicKeyLength+blsSignatureLength:]
+	)
+	key, err := decodeBLSPublicKey(pubkey)
+	if err != nil {
+		return errInvalidAttestation
+	}
+	proof, err := decodeBLSSignature(possesion)
+	if err != nil || !blsVerify(key, possessionHash(signer, pubkey), proof) {
+		return errInvalidAttestation
+	}
+	sig, err := decodeBLSSignature(signature)
+	if err != nil || !blsVerify(key, AttestationHash(s.AttestTarget, s.AttestHash), sig) {
+		return errInvalidAttestation
+	}
+	g1 := bls12381.NewG1()
+	if s.AttestSignature != nil {
+		aggregate, err := g1.FromBytes(s.AttestSignature)
+		if err != nil {
+			return err
+		}
+		g1.Add(sig, sig, aggregate)
+	}
+	s.AttestSignature = g1.ToBytes(sig)
+
+	// Attestations are shared between snapshot copies, never modify in place
+	s.Attestations = append(append(make([]*Attestation, 0, len(s.Attestations)+1), s.Attestations...), &Attestation{
+		Signer:    signer,
+		PublicKey: common.CopyBytes(pubkey),
+	})
+	return nil
+}
+
+// aggregate returns the aggregate attestation the next checkpoint must embed, or
+// nil if none of the signers attested the current target.
+func (s *Snapshot) aggregate() []byte {
+	if len(s.Attestations) == 0 {
+		return nil
+	}
+	blob := make([]byte, aggregateLength)
+	for _, attestation := range s.Attestations {
+		index := s.attesterIndex(attestation.Signer)
+		blob[index/8] |= 1 << (7 - uint(index%8))
+	}
+	copy(blob[maxAttesters/8:], s.AttestSignature)
+	return blob
+}
+
+// resetAttestations makes the checkpoint with the given header the target of the
+// attestations of the epoch starting at it.
+func (s *Snapshot) resetAttestations(header *types.Header) {
+	s.AttestTarget, s.AttestHash = header.Number.Uint64(), header.Hash()
+	s.AttestSigners = s.signers()
+	s.Attestations, s.AttestSignature = nil, nil
+}
+
+// verifyAggregate checks that a checkpoint embeds exactly the aggregate of the
+// attestations counted in the snapshot of its parent. Signer set overrides don't
+// carry any, the attestations of their epoch are lost.
+func verifyAggregate(snap *Snapshot, header *types.Header) error {
+	if isOverride(header) {
+		return nil
+	}
+	if _, blob, _ := splitCheckpoint(snap.config, header); !bytes.Equal(blob, snap.aggregate()) {
+		return errMismatchingAttestation
+	}
+	return nil
+}
+
+// attestationKey is the local BLS key of the engine along with the attestation it
+// produced for the current target, cached as signing is relatively expensive.
+type attestationKey struct {
+	secret *big.Int
+	pubkey []byte
+
+	prover common.Address // Signer the proof of possession was made for
+	proof  []byte         // Proof of possession of the key by the signer
+
+	target common.Hash // Hash of the checkpoint last attested
+	signed []byte      // Signature over the checkpoint last attested
+}
+
+// SetAttestationKey sets the BLS secret key the local signer attests checkpoints
+// with. Attestations are only produced once the attestation fork is active.
+func (c *Clique) SetAttestationKey(secret []byte) error {
+	key, err := blsSecretKey(secret)
+	if err != nil {
+		return err
+	}
+	pubkey, err := AttestationPublicKey(secret)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.attestKey = &attestationKey{secret: key, pubkey: pubkey}
+	return nil
+}
+
+// attestation returns the attestation the local signer should embed into the
+// header being prepared, or nil if it has nothing to attest. The caller must hold
+// the engine lock.
+func (c *Clique) attestation(snap *Snapshot, signer common.Address) []byte {
+	key := c.attestKey
+	if key == nil || !snap.attesting(signer) {
+		return nil
+	}
+	if key.proof == nil || key.prover != signer {
+		proof, err := blsSign(key.secret, possessionHash(signer, key.pubkey))
+		if err != nil {
+			return nil
+		}
+		key.prover, key.proof = signer, proof
+	}
+	if key.target != snap.AttestHash {
+		signed, err := blsSign(key.secret, AttestationHash(snap.AttestTarget, snap.AttestHash))
+		if err != nil {
+			return nil
+		}
+		key.target, key.signed = snap.AttestHash, signed
+	}
+	blob := make([]byte, 0, attestationLength)
+	blob = append(blob, key.pubkey...)
+	blob = append(blob, key.proof...)
+	return append(blob, key.signed...)
+}
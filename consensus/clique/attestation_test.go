@@ -0,0 +1,128 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the BLS attestations of a checkpoint are counted once per signer,
+// aggregated into a signature verifiable against the attesters' keys, and that
+// the next checkpoint must embed exactly the aggregate.
+func TestCheckpointAttestation(t *testing.T) {
+	pool := newTesterAccountPool()
+	signers := []string{"A", "B", "C"}
+
+	addrs := make([]common.Address, len(signers))
+	for i, signer := range signers {
+		addrs[i] = pool.address(signer)
+	}
+	config := &params.CliqueConfig{Epoch: 4, AttestationBlock: big.NewInt(0)}
+	snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, addrs)
+
+	// Create an engine attesting with a fresh BLS key for every signer
+	engines := make(map[string]*Clique)
+	pubkeys := make(map[string][]byte)
+	for _, signer := range signers {
+		secret, err := GenerateAttestationKey()
+		if err != nil {
+			t.Fatalf("failed to generate attestation key: %v", err)
+		}
+		engine := New(config, rawdb.NewMemoryDatabase())
+		engine.Authorize(pool.address(signer), nil)
+		if err := engine.SetAttestationKey(secret); err != nil {
+			t.Fatalf("failed to set attestation key: %v", err)
+		}
+		engines[signer] = engine
+		pubkeys[signer], _ = AttestationPublicKey(secret)
+	}
+	// Run through the first epoch, making block 4 the attested checkpoint
+	headers := make([]*types.Header, 4)
+	for i := range headers {
+		headers[i] = &types.Header{Number: big.NewInt(int64(i + 1)), Extra: make([]byte, extraVanity+extraSeal)}
+		if i == 3 {
+			headers[i].Extra = make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal)
+			pool.checkpoint(headers[i], signers)
+		}
+		pool.sign(headers[i], signers[i%len(signers)])
+	}
+	snap, err := snap.apply(headers)
+	if err != nil {
+		t.Fatalf("failed to apply first epoch: %v", err)
+	}
+	if snap.AttestTarget != 4 || snap.AttestHash != headers[3].Hash() || len(snap.AttestSigners) != len(signers) {
+		t.Fatalf("attestation target mismatch: have %d/%x with %d signers", snap.AttestTarget, snap.AttestHash, len(snap.AttestSigners))
+	}
+	attest := func(number int64, signer string) *types.Header {
+		header := &types.Header{Number: big.NewInt(number)}
+		header.Extra = append(make([]byte, extraVanity), engines[signer].attestation(snap, pool.address(signer))...)
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+		pool.sign(header, signer)
+		return header
+	}
+	// Attestations of two signers should be aggregated
+	first := attest(5, "B")
+	if snap, err = snap.apply([]*types.Header{first}); err != nil {
+		t.Fatalf("failed to apply first attestation: %v", err)
+	}
+	if engines["B"].attestation(snap, pool.address("B")) != nil {
+		t.Errorf("signer attesting twice")
+	}
+	if snap, err = snap.apply([]*types.Header{attest(6, "C")}); err != nil {
+		t.Fatalf("failed to apply second attestation: %v", err)
+	}
+	if len(snap.Attestations) != 2 {
+		t.Fatalf("attestation count mismatch: have %d, want %d", len(snap.Attestations), 2)
+	}
+	if err := VerifyAttestation(4, headers[3].Hash(), [][]byte{pubkeys["B"], pubkeys["C"]}, snap.AttestSignature); err != nil {
+		t.Errorf("failed to verify aggregate attestation: %v", err)
+	}
+	if err := VerifyAttestation(4, headers[3].Hash(), [][]byte{pubkeys["A"], pubkeys["B"]}, snap.AttestSignature); err != errInvalidAttestation {
+		t.Errorf("wrong keys error mismatch: have %v, want %v", err, errInvalidAttestation)
+	}
+	// A repeated attestation must be rejected
+	replay := &types.Header{Number: big.NewInt(7), Extra: common.CopyBytes(first.Extra)}
+	pool.sign(replay, "B")
+	if _, err := snap.apply([]*types.Header{replay}); err != errInvalidAttestation {
+		t.Errorf("repeated attestation error mismatch: have %v, want %v", err, errInvalidAttestation)
+	}
+	// The next checkpoint must embed exactly the aggregate of the epoch
+	checkpoint := &types.Header{Number: big.NewInt(8)}
+	checkpoint.Extra = make([]byte, extraVanity+len(signers)*common.AddressLength)
+	pool.checkpoint(&types.Header{Extra: checkpoint.Extra}, signers)
+	bare := &types.Header{Number: checkpoint.Number, Extra: append(common.CopyBytes(checkpoint.Extra), make([]byte, extraSeal)...)}
+	checkpoint.Extra = append(append(checkpoint.Extra, snap.aggregate()...), make([]byte, extraSeal)...)
+
+	if err := verifyCheckpoint(snap, checkpoint); err != nil {
+		t.Errorf("failed to verify attested checkpoint: %v", err)
+	}
+	if err := verifyCheckpoint(snap, bare); err != errMismatchingAttestation {
+		t.Errorf("unattested checkpoint error mismatch: have %v, want %v", err, errMismatchingAttestation)
+	}
+	if list, err := checkpointSigners(snap.config, checkpoint); err != nil || len(list) != len(signers) {
+		t.Errorf("checkpoint signers mismatch: have %d (%v), want %d", len(list), err, len(signers))
+	}
+	if bitmap := snap.aggregate()[0]; bitmap != 0xc0 && bitmap != 0xa0 && bitmap != 0x60 {
+		t.Errorf("participation bitmap mismatch: have %08b", bitmap)
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+var (
+	auditPrefix       = []byte("clique-audit-r-") // auditPrefix + num (uint64 big endian) + hash -> resolution
+	auditSignerPrefix = []byte("clique-audit-s-") // auditSignerPrefix + address + num (uint64 big endian) + hash -> nil
+	auditKindPrefix   = []byte("clique-audit-k-") // auditKindPrefix + kind + num (uint64 big endian) + hash -> nil
+)
+
+// ProposalKind is the type of a governance proposal tracked by the audit store.
+type ProposalKind uint8
+
+const (
+	ProposalAuthorize   ProposalKind = iota // Vote to add an account to the signer set
+	ProposalDeauthorize                     // Vote to remove an account from the signer set
+	ProposalSignerLimit                     // Vote to change the signer limit percentage
+	ProposalOverride                        // Emergency replacement of the whole signer set
+	ProposalPermit                          // Vote to permit an account to send transactions
+	ProposalRevoke                          // Vote to revoke the transaction permission of an account
+	ProposalPeriod                          // Vote to grant a signer a specific minimum block period
+	ProposalResignation                     // Voluntary removal of a signer from the signer set
+	ProposalReplacement                     // Vote to swap a signer for another account in a single step
+)
+
+// String implements the stringer interface.
+func (k ProposalKind) String() string {
+	switch k {
+	case ProposalAuthorize:
+		return "authorize"
+	case ProposalDeauthorize:
+		return "deauthorize"
+	case ProposalSignerLimit:
+		return "signerLimit"
+	case ProposalOverride:
+		return "override"
+	case ProposalPermit:
+		return "permit"
+	case ProposalRevoke:
+		return "revoke"
+	case ProposalPeriod:
+		return "period"
+	case ProposalResignation:
+		return "resignation"
+	case ProposalReplacement:
+		return "replacement"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(k))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k ProposalKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *ProposalKind) UnmarshalText(input []byte) error {
+	switch string(input) {
+	case "authorize":
+		*k = ProposalAuthorize
+	case "deauthorize":
+		*k = ProposalDeauthorize
+	case "signerLimit":
+		*k = ProposalSignerLimit
+	case "override":
+		*k = ProposalOverride
+	case "permit":
+		*k = ProposalPermit
+	case "revoke":
+		*k = ProposalRevoke
+	case "period":
+		*k = ProposalPeriod
+	case "resignation":
+		*k = ProposalResignation
+	case "replacement":
+		*k = ProposalReplacement
+	default:
+		return fmt.Errorf("unknown proposal kind %q", input)
+	}
+	return nil
+}
+
+// AuditVote is a single vote in the trail that led to a proposal resolving.
+type AuditVote struct {
+	Signer common.Address `json:"signer"` // Authorized signer that cast this vote
+	Block  uint64         `json:"block"`  // Block number the vote was cast in
+}
+
+// Resolution is the audit record of a governance proposal that passed.
+type Resolution struct {
+	Kind      ProposalKind     `json:"kind"`                // Type of the proposal that passed
+	Block     uint64           `json:"block"`               // Block number in which the proposal passed
+	Hash      common.Hash      `json:"hash"`                // Block hash in which the proposal passed
+	Address   common.Address   `json:"address"`             // Account whose authorization changed (membership votes)
+	Limit     uint             `json:"limit,omitempty"`     // New signer limit percentage (limit votes)
+	PrevLimit uint             `json:"prevLimit,omitempty"` // Signer limit percentage before the change (limit votes)
+	Period    uint64           `json:"period,omitempty"`    // Minimum block period granted to the signer (period votes)
+	Replaced  common.Address   `json:"replaced,omitempty"`  // Signer swapped for the account (replacement votes)
+	Signers   []common.Address `json:"signers,omitempty"`   // Signer set installed by the proposal (overrides)
+	Votes     []AuditVote      `json:"votes"`               // Trail of votes that made the proposal pass
+}
+
+// Membership changes of a signer in its history.
+const (
+	SignerAdded   = "added"    // Account authorized for the first time
+	SignerRemoved = "removed"  // Account deauthorized, resigned, replaced or overridden out
+	SignerReadded = "re-added" // Account authorized again after being removed
+)
+
+// SignerEvent is a membership change of a single account in the signer set.
+type SignerEvent struct {
+	Action string       `json:"action"` // Membership change of the account (added, removed or re-added)
+	Kind   ProposalKind `json:"kind"`   // Type of the proposal that changed the membership
+	Block  uint64       `json:"block"`  // Block number in which the membership changed
+	Hash   common.Hash  `json:"hash"`   // Block hash in which the membership changed
+	Votes  []AuditVote  `json:"votes"`  // Trail of votes that changed the membership
+}
+
+// signerHistory assembles the membership changes of an account from the audit
+// records involving it, in ascending block order, starting from whether it was
+// a signer in the genesis.
+func signerHistory(address common.Address, genesis bool, resolutions []*Resolution) []*SignerEvent {
+	var (
+		member  = genesis
+		removed bool
+		events  = []*SignerEvent{}
+	)
+	for _, res := range resolutions {
+		var add, drop bool
+		switch res.Kind {
+		case ProposalAuthorize:
+			add = res.Address == address
+		case ProposalDeauthorize, ProposalResignation:
+			drop = res.Address == address
+		case ProposalReplacement:
+			add, drop = res.Address == address, res.Replaced == address
+		case ProposalOverride:
+			listed := false
+			for _, signer := range res.Signers {
+				listed = listed || signer == address
+			}
+			add, drop = listed && !member, !listed && member
+		}
+		event := &SignerEvent{Kind: res.Kind, Block: res.Block, Hash: res.Hash, Votes: res.Votes}
+		switch {
+		case add && !member:
+			event.Action, member = SignerAdded, true
+			if removed {
+				event.Action = SignerReadded
+			}
+		case drop && member:
+			event.Action, member, removed = SignerRemoved, false, true
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// signers returns every account involved in the resolution, the voters as well
+// as the target of a membership change or the members of an overridden set.
+func (r *Resolution) signers() []common.Address {
+	seen := make(map[common.Address]struct{})
+	addrs := make([]common.Address, 0, len(r.Votes)+len(r.Signers)+1)
+	switch r.Kind {
+	case ProposalAuthorize, ProposalDeauthorize, ProposalResignation:
+		seen[r.Address] = struct{}{}
+		addrs = append(addrs, r.Address)
+	case ProposalReplacement:
+		seen[r.Address], seen[r.Replaced] = struct{}{}, struct{}{}
+		addrs = append(addrs, r.Address, r.Replaced)
+	case ProposalOverride:
+		for _, signer := range r.Signers {
+			if _, ok := seen[signer]; !ok {
+				seen[signer] = struct{}{}
+				addrs = append(addrs, signer)
+			}
+		}
+	}
+	for _, vote := range r.Votes {
+		if _, ok := seen[vote.Signer]; !ok {
+			seen[vote.Signer] = struct{}{}
+			addrs = append(addrs, vote.Signer)
+		}
+	}
+	return addrs
+}
+
+// auditSuffix returns the number + hash key suffix shared by all audit entries
+// of a resolution.
+func auditSuffix(number uint64, hash common.Hash) []byte {
+	suffix := make([]byte, 8+common.HashLength)
+	binary.BigEndian.PutUint64(suffix, number)
+	copy(suffix[8:], hash[:])
+	return suffix
+}
+
+// auditKey = auditPrefix + num (uint64 big endian) + hash
+func auditKey(number uint64, hash common.Hash) []byte {
+	return append(append([]byte{}, auditPrefix...), auditSuffix(number, hash)...)
+}
+
+// auditSignerKey = auditSignerPrefix + address + num (uint64 big endian) + hash
+func auditSignerKey(signer common.Address, number uint64, hash common.Hash) []byte {
+	key := append(append([]byte{}, auditSignerPrefix...), signer[:]...)
+	return append(key, auditSuffix(number, hash)...)
+}
+
+// auditKindKey = auditKindPrefix + kind + num (uint64 big endian) + hash
+func auditKindKey(kind ProposalKind, number uint64, hash common.Hash) []byte {
+	key := append(append([]byte{}, auditKindPrefix...), byte(kind))
+	return append(key, auditSuffix(number, hash)...)
+}
+
+// storeResolutions writes a batch of resolved proposals and their indices into
+// the database. Resolutions are keyed by block number and hash, so rewriting the
+// same records after a snapshot regeneration is harmless.
+func storeResolutions(db ethdb.Database, resolutions []*Resolution) error {
+	if len(resolutions) == 0 {
+		return nil
+	}
+	batch := db.NewBatch()
+	for _, res := range resolutions {
+		blob, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(auditKey(res.Block, res.Hash), blob); err != nil {
+			return err
+		}
+		for _, signer := range res.signers() {
+			if err := batch.Put(auditSignerKey(signer, res.Block, res.Hash), nil); err != nil {
+				return err
+			}
+		}
+		if err := batch.Put(auditKindKey(res.Kind, res.Block, res.Hash), nil); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// deleteResolutions removes the resolutions recorded on the given blocks, along
+// with their indices, e.g. after the blocks were reorged out of the chain.
+func deleteResolutions(db ethdb.Database, headers []*types.Header) error {
+	batch := db.NewBatch()
+	for _, header := range headers {
+		res, err := loadResolution(db, header.Number.Uint64(), header.Hash())
+		if err != nil {
+			continue // No proposal resolved in the block
+		}
+		if err := deleteResolution(batch, res); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// pruneResolutions removes all the resolutions recorded from the given block on
+// which are not part of the canonical chain, along with their indices.
+func pruneResolutions(db ethdb.Database, chain consensus.ChainHeaderReader, from uint64) error {
+	start := make([]byte, 8)
+	binary.BigEndian.PutUint64(start, from)
+
+	it := db.NewIterator(auditPrefix, start)
+	defer it.Release()
+
+	batch := db.NewBatch()
+	for it.Next() {
+		res := new(Resolution)
+		if err := json.Unmarshal(it.Value(), res); err != nil {
+			return err
+		}
+		if header := chain.GetHeaderByNumber(res.Block); header != nil && header.Hash() == res.Hash {
+			continue
+		}
+		if err := deleteResolution(batch, res); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// deleteResolution queues the removal of a resolution record and its indices.
+func deleteResolution(batch ethdb.Batch, res *Resolution) error {
+	if err := batch.Delete(auditKey(res.Block, res.Hash)); err != nil {
+		return err
+	}
+	for _, signer := range res.signers() {
+		if err := batch.Delete(auditSignerKey(signer, res.Block, res.Hash)); err != nil {
+			return err
+		}
+	}
+	return batch.Delete(auditKindKey(res.Kind, res.Block, res.Hash))
+}
+
+// loadResolution retrieves a single resolution record from the database.
+func loadResolution(db ethdb.Database, number uint64, hash common.Hash) (*Resolution, error) {
+	blob, err := db.Get(auditKey(number, hash))
+	if err != nil {
+		return nil, err
+	}
+	res := new(Resolution)
+	if err := json.Unmarshal(blob, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// iterateResolutions walks all the audit entries under the given prefix within
+// the [from, to] block range in ascending order, resolving them to the actual
+// records. Iteration stops early if the callback returns false.
+func iterateResolutions(db ethdb.Database, prefix []byte, from, to uint64, fn func(*Resolution) bool) error {
+	start := make([]byte, 8)
+	binary.BigEndian.PutUint64(start, from)
+
+	it := db.NewIterator(prefix, start)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+8+common.HashLength {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		hash := common.BytesToHash(key[len(prefix)+8:])
+
+		res, err := loadResolution(db, number, hash)
+		if err != nil {
+			// Skip any stale indices of records pruned after a reorg
+			if has, _ := db.Has(auditKey(number, hash)); !has {
+				continue
+			}
+			return err
+		}
+		if !fn(res) {
+			break
+		}
+	}
+	return it.Error()
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+)
+
+// Tests that resolutions written to the audit store can be queried back via all
+// the supported indices, respecting the requested block ranges.
+func TestAuditStoreIndices(t *testing.T) {
+	var (
+		db = rawdb.NewMemoryDatabase()
+		a  = common.Address{0x0a}
+		b  = common.Address{0x0b}
+		c  = common.Address{0x0c}
+	)
+	resolutions := []*Resolution{
+		{Kind: ProposalAuthorize, Block: 5, Hash: common.Hash{0x05}, Address: c, Votes: []AuditVote{{Signer: a, Block: 4}, {Signer: b, Block: 5}}},
+		{Kind: ProposalSignerLimit, Block: 9, Hash: common.Hash{0x09}, Limit: 66, PrevLimit: 50, Votes: []AuditVote{{Signer: a, Block: 8}, {Signer: b, Block: 9}}},
+		{Kind: ProposalDeauthorize, Block: 300, Hash: common.Hash{0x01, 0x2c}, Address: b, Votes: []AuditVote{{Signer: a, Block: 299}, {Signer: c, Block: 300}}},
+	}
+	if err := storeResolutions(db, resolutions); err != nil {
+		t.Fatalf("failed to store resolutions: %v", err)
+	}
+	collect := func(prefix []byte, from, to uint64) []uint64 {
+		var blocks []uint64
+		if err := iterateResolutions(db, prefix, from, to, func(res *Resolution) bool {
+			blocks = append(blocks, res.Block)
+			return true
+		}); err != nil {
+			t.Fatalf("failed to iterate resolutions: %v", err)
+		}
+		return blocks
+	}
+	tests := []struct {
+		prefix   []byte
+		from, to uint64
+		want     []uint64
+	}{
+		{auditPrefix, 0, 1000, []uint64{5, 9, 300}},
+		{auditPrefix, 6, 299, []uint64{9}},
+		{append(append([]byte{}, auditSignerPrefix...), a[:]...), 0, 1000, []uint64{5, 9, 300}},
+		{append(append([]byte{}, auditSignerPrefix...), b[:]...), 0, 1000, []uint64{5, 9, 300}},
+		{append(append([]byte{}, auditSignerPrefix...), c[:]...), 0, 1000, []uint64{5, 300}},
+		{append(append([]byte{}, auditSignerPrefix...), c[:]...), 6, 1000, []uint64{300}},
+		{append(append([]byte{}, auditKindPrefix...), byte(ProposalSignerLimit)), 0, 1000, []uint64{9}},
+		{append(append([]byte{}, auditKindPrefix...), byte(ProposalDeauthorize)), 0, 100, nil},
+	}
+	for i, tt := range tests {
+		have := collect(tt.prefix, tt.from, tt.to)
+		if len(have) != len(tt.want) {
+			t.Errorf("test %d: result mismatch: have %v, want %v", i, have, tt.want)
+			continue
+		}
+		for j := range have {
+			if have[j] != tt.want[j] {
+				t.Errorf("test %d: result mismatch: have %v, want %v", i, have, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// Tests that the membership history of an account is assembled from the audit
+// records involving it, telling first authorizations and re-authorizations apart.
+func TestSignerHistory(t *testing.T) {
+	var (
+		target = common.HexToAddress("0x01")
+		other  = common.HexToAddress("0x02")
+		voter  = common.HexToAddress("0x03")
+	)
+	resolutions := []*Resolution{
+		{Kind: ProposalAuthorize, Block: 2, Address: target, Votes: []AuditVote{{Signer: voter, Block: 2}}},
+		{Kind: ProposalAuthorize, Block: 3, Address: other, Votes: []AuditVote{{Signer: target, Block: 3}}},
+		{Kind: ProposalDeauthorize, Block: 5, Address: target},
+		{Kind: ProposalReplacement, Block: 7, Address: target, Replaced: other},
+		{Kind: ProposalOverride, Block: 10, Signers: []common.Address{other, voter}},
+		{Kind: ProposalOverride, Block: 20, Signers: []common.Address{target, voter}},
+	}
+	var actions []string
+	for _, event := range signerHistory(target, false, resolutions) {
+		actions = append(actions, event.Action)
+	}
+	want := []string{SignerAdded, SignerRemoved, SignerReadded, SignerRemoved, SignerReadded}
+	if len(actions) != len(want) {
+		t.Fatalf("event count mismatch: have %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("event %d: action mismatch: have %s, want %s", i, actions[i], want[i])
+		}
+	}
+	// A genesis signer isn't added again, but re-added after its removal
+	events := signerHistory(other, true, resolutions)
+	if len(events) != 3 || events[0].Action != SignerRemoved || events[0].Block != 7 || events[1].Action != SignerReadded || events[2].Block != 20 {
+		t.Errorf("genesis signer history mismatch: have %d events", len(events))
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// errInvalidBootstrapSnapshot is returned if a snapshot retrieved from a remote
+// source doesn't match the checkpoint header it was requested for.
+var errInvalidBootstrapSnapshot = errors.New("bootstrap snapshot mismatches checkpoint")
+
+// errUncommittedSnapshot is returned if a snapshot retrieved from an untrusted
+// source carries state its checkpoint header doesn't commit to.
+var errUncommittedSnapshot = errors.New("bootstrap snapshot not committed to by checkpoint")
+
+// errInvalidTrustedCheckpoint is returned if a trusted checkpoint is malformed or
+// contradicts the checkpoint header it's anchored at.
+var errInvalidTrustedCheckpoint = errors.New("invalid trusted checkpoint")
+
+// SnapshotSource retrieves the JSON encoded voting snapshot of a checkpoint block
+// from a remote party, allowing freshly syncing nodes to skip replaying all the
+// headers since genesis.
+type SnapshotSource func(number uint64, hash common.Hash) ([]byte, error)
+
+// RPCSnapshotSource creates a snapshot source retrieving snapshots from a remote
+// node via the clique RPC API.
+func RPCSnapshotSource(client *rpc.Client) SnapshotSource {
+	return func(number uint64, hash common.Hash) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var blob json.RawMessage
+		if err := client.CallContext(ctx, &blob, "clique_getSnapshotAtHash", hash); err != nil {
+			return nil, err
+		}
+		return blob, nil
+	}
+}
+
+// SetSnapshotSource sets the remote source to bootstrap checkpoint snapshots from
+// when they are not available locally. Snapshots from untrusted sources are only
+// accepted if their checkpoint commits to the whole of their state.
+func (c *Clique) SetSnapshotSource(source SnapshotSource, trusted bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.source, c.sourceTrusted = source, trusted
+}
+
+// CheckpointSnapshot retrieves the JSON encoded voting snapshot of a checkpoint
+// block, if available locally, to serve to remote nodes bootstrapping from it.
+func (c *Clique) CheckpointSnapshot(hash common.Hash) ([]byte, error) {
+	var snap *Snapshot
+	if s, ok := c.recents.Get(hash); ok {
+		snap = s
+	} else {
+		db, err := c.snapshotDB(nil)
+		if err != nil {
+			return nil, err
+		}
+		s, err := loadSnapshot(c.config, c.signatures, db, hash)
+		if err != nil {
+			return nil, err
+		}
+		snap = s
+	}
+	if snap.Number == 0 || snap.Number%c.config.Epoch != 0 {
+		return nil, errUnknownBlock
+	}
+	return snap.MarshalCanonical()
+}
+
+// bootstrapSnapshot attempts to retrieve the snapshot of a checkpoint block from
+// the configured remote source, verifying it against the checkpoint header.
+func (c *Clique) bootstrapSnapshot(chain consensus.ChainHeaderReader, checkpoint *types.Header) *Snapshot {
+	c.lock.RLock()
+	source, trusted := c.source, c.sourceTrusted
+	c.lock.RUnlock()
+
+	hash := checkpoint.Hash()
+	if source == nil || c.bootstrapFailures.Contains(hash) {
+		return nil
+	}
+	blob, err := source(checkpoint.Number.Uint64(), hash)
+	if err == nil {
+		var snap *Snapshot
+		if snap, err = c.importSnapshot(chain, checkpoint, blob, trusted); err == nil {
+			log.Info("Bootstrapped voting snapshot", "number", snap.Number, "hash", snap.Hash)
+			return snap
+		}
+	}
+	log.Warn("Failed to bootstrap voting snapshot", "number", checkpoint.Number, "hash", hash, "err", err)
+	c.bootstrapFailures.Add(hash, struct{}{})
+	return nil
+}
+
+// importSnapshot decodes a JSON encoded snapshot of a checkpoint block, verifies
+// it against the checkpoint header commitments and persists it.
+//
+// A checkpoint resets all pending votes and embeds the signer set (and optionally
+// the signer limit state), so only the recent signers are not committed to by the
+// header; those are checked against any locally available headers. Snapshots of
+// untrusted origin must moreover be fully covered by the checkpoint commitments.
+func (c *Clique) importSnapshot(chain consensus.ChainHeaderReader, checkpoint *types.Header, blob []byte, trusted bool) (*Snapshot, error) {
+	snap := new(Snapshot)
+	if err := json.Unmarshal(blob, snap); err != nil {
+		return nil, err
+	}
+	snap.config = c.config
+	snap.sigcache = c.signatures
+	snap.owned = cowAll
+	snap.sortSigners()
+	snap.ensureContainers()
+
+	if err := c.verifyCheckpointSnapshot(chain, checkpoint, snap); err != nil {
+		return nil, err
+	}
+	if !trusted {
+		if err := verifyCommittedSnapshot(c.config, checkpoint, snap); err != nil {
+			return nil, err
+		}
+	}
+	db, err := c.snapshotDB(chain)
+	if err != nil {
+		return nil, err
+	}
+	if err := snap.persist(db); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// verifyCheckpointSnapshot checks the snapshot of a checkpoint block against the
+// commitments of the checkpoint header and any locally available headers.
+func (c *Clique) verifyCheckpointSnapshot(chain consensus.ChainHeaderReader, checkpoint *types.Header, snap *Snapshot) error {
+	number := checkpoint.Number.Uint64()
+	if number%c.config.Epoch != 0 || snap.Number != number || snap.Hash != checkpoint.Hash() {
+		return errInvalidBootstrapSnapshot
+	}
+	if len(snap.Votes) != 0 || len(snap.Tally) != 0 || len(snap.SignerLimitVotes) != 0 || len(snap.SignerLimitTally) != 0 || snap.EpochChanges != 0 {
+		return errInvalidBootstrapSnapshot
+	}
+	signers, err := checkpointSigners(c.config, checkpoint)
+	if err != nil {
+		return err
+	}
+	if len(signers) != len(snap.Signers) {
+		return errInvalidBootstrapSnapshot
+	}
+	for _, signer := range signers {
+		if _, ok := snap.Signers[signer]; !ok {
+			return errInvalidBootstrapSnapshot
+		}
+	}
+	if c.config.CheckpointLimit && checkpoint.MixDigest != limitCommitment(snap.SignerLimit, snap.SignerLimitAffirmed) {
+		return errInvalidBootstrapSnapshot
+	}
+	// Without any votes pending, the committed root covers the rest of the state
+	if root, ok := CheckpointRoot(c.config, checkpoint); ok && root != snap.Root() {
+		return errInvalidBootstrapSnapshot
+	}
+	window := snap.recentsWindow(number)
+	for seen, signer := range snap.Recents {
+		if seen > number || seen+window <= number {
+			return errInvalidBootstrapSnapshot
+		}
+		if header := chain.GetHeaderByNumber(seen); header != nil {
+			if sealer, err := ecrecover(header, c.signatures); err != nil || sealer != signer {
+				return errInvalidBootstrapSnapshot
+			}
+		}
+	}
+	return nil
+}
+
+// verifyCommittedSnapshot checks that the checkpoint header commits to the whole
+// state of a snapshot already verified against it. The snapshot root covers the
+// signer set, limit and cooldowns, but not the rest of the governance state, so
+// snapshots carrying any of it are refused; their state is replayed instead.
+func verifyCommittedSnapshot(config *params.CliqueConfig, checkpoint *types.Header, snap *Snapshot) error {
+	if _, ok := CheckpointRoot(config, checkpoint); !ok {
+		return errUncommittedSnapshot
+	}
+	if len(snap.Permitted) != 0 || len(snap.PermitVotes) != 0 || len(snap.PermitTally) != 0 ||
+		len(snap.Openings) != 0 || len(snap.Commits) != 0 || len(snap.Probations) != 0 ||
+		len(snap.Periods) != 0 || len(snap.PeriodVotes) != 0 || len(snap.Replacements) != 0 {
+		return errUncommittedSnapshot
+	}
+	if snap.JustifyTarget != 0 || len(snap.Justifiers) != 0 || snap.FinalizedNumber != 0 {
+		return errUncommittedSnapshot
+	}
+	if snap.AttestTarget != 0 || len(snap.AttestSigners) != 0 || len(snap.Attestations) != 0 || len(snap.AttestSignature) != 0 {
+		return errUncommittedSnapshot
+	}
+	return nil
+}
+
+// SetTrustedCheckpoint sets the checkpoint to anchor the voting snapshots at,
+// overriding the one published in the chain configuration. Chains through the
+// checkpoint are validated from its signer set and limit onward, without needing
+// the headers preceding it. A nil checkpoint reverts to replaying from genesis.
+func (c *Clique) SetTrustedCheckpoint(checkpoint *params.CliqueCheckpoint) error {
+	if checkpoint != nil {
+		if err := validateTrustedCheckpoint(c.config, checkpoint); err != nil {
+			return err
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.trusted = checkpoint
+	return nil
+}
+
+// validateTrustedCheckpoint checks that a trusted checkpoint is self-consistent.
+// Checkpoints must be at epoch boundaries, where all pending votes are discarded,
+// so the signer set and limit fully describe the voting state.
+func validateTrustedCheckpoint(config *params.CliqueConfig, checkpoint *params.CliqueCheckpoint) error {
+	if checkpoint.Number == 0 || checkpoint.Number%config.Epoch != 0 || checkpoint.Hash == (common.Hash{}) {
+		return errInvalidTrustedCheckpoint
+	}
+	if len(checkpoint.Signers) == 0 || checkpoint.SignerLimitAffirmed > checkpoint.Number {
+		return errInvalidTrustedCheckpoint
+	}
+	seen := make(map[common.Address]struct{}, len(checkpoint.Signers))
+	for _, signer := range checkpoint.Signers {
+		if _, ok := seen[signer]; ok {
+			return errInvalidTrustedCheckpoint
+		}
+		seen[signer] = struct{}{}
+	}
+	return nil
+}
+
+// trustedSnapshot creates and persists the snapshot of the trusted checkpoint. If
+// the checkpoint header is available locally, it's cross-checked against the
+// signers and limit it commits to, refusing to anchor at a wrong checkpoint.
+func (c *Clique) trustedSnapshot(chain consensus.ChainHeaderReader, checkpoint *params.CliqueCheckpoint) (*Snapshot, error) {
+	snap := newSnapshot(c.config, c.signatures, checkpoint.Number, checkpoint.Hash, checkpoint.Signers)
+	if checkpoint.SignerLimit != 0 {
+		snap.SignerLimit = checkpoint.SignerLimit
+	}
+	snap.SignerLimitAffirmed = checkpoint.SignerLimitAffirmed
+
+	if header := chain.GetHeader(checkpoint.Hash, checkpoint.Number); header != nil {
+		signers, err := checkpointSigners(c.config, header)
+		if err != nil {
+			return nil, err
+		}
+		if len(signers) != len(snap.Signers) {
+			return nil, errInvalidTrustedCheckpoint
+		}
+		for _, signer := range signers {
+			if _, ok := snap.Signers[signer]; !ok {
+				return nil, errInvalidTrustedCheckpoint
+			}
+		}
+		if c.config.CheckpointLimit && header.MixDigest != limitCommitment(snap.SignerLimit, snap.SignerLimitAffirmed) {
+			return nil, errInvalidTrustedCheckpoint
+		}
+	}
+	db, err := c.snapshotDB(chain)
+	if err != nil {
+		return nil, err
+	}
+	if err := snap.persist(db); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
@@ -0,0 +1,281 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testerHeaderChain is a minimal header chain reader over a single branch.
+type testerHeaderChain struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+}
+
+func (c *testerHeaderChain) Config() *params.ChainConfig  { return c.config }
+func (c *testerHeaderChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+func (c *testerHeaderChain) GetTd(common.Hash, uint64) *big.Int {
+	return big.NewInt(int64(len(c.headers)))
+}
+
+func (c *testerHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+
+func (c *testerHeaderChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+func (c *testerHeaderChain) GetBlock(common.Hash, uint64) *types.Block { return nil }
+
+func (c *testerHeaderChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+// Tests that checkpoint snapshots can be bootstrapped from a remote source, and
+// that snapshots not matching the checkpoint header commitments are rejected.
+func TestSnapshotBootstrap(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 3, SignerLimit: 66, CheckpointLimit: true}
+		signers  = []string{"A", "B"}
+	)
+	// Create a two signer chain up to and including a checkpoint
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	source := New(config, rawdb.NewMemoryDatabase())
+	for i := 1; i <= 3; i++ {
+		parent := chain.headers[i-1]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Time:       parent.Time + 1,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i == 3 {
+			header.Extra = make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal)
+			accounts.checkpoint(header, signers)
+
+			snap, err := source.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+			if err != nil {
+				t.Fatalf("failed to create parent snapshot: %v", err)
+			}
+			header.MixDigest = limitCommitment(snap.epochLimit(3))
+		}
+		accounts.sign(header, signers[i%2])
+		chain.headers = append(chain.headers, header)
+	}
+	checkpoint := chain.headers[3]
+	want, err := source.snapshot(chain, 3, checkpoint.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create checkpoint snapshot: %v", err)
+	}
+	blob, _ := want.MarshalCanonical()
+
+	// Ensure the source serves the checkpoint snapshot, but nothing else
+	if served, err := source.CheckpointSnapshot(checkpoint.Hash()); err != nil || !bytes.Equal(served, blob) {
+		t.Fatalf("served snapshot mismatch: have %s, want %s, err %v", served, blob, err)
+	}
+	if _, err := source.CheckpointSnapshot(chain.headers[2].Hash()); err != errUnknownBlock {
+		t.Errorf("non-checkpoint snapshot error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+
+	// Bootstrap a fresh engine and ensure the snapshot is imported as is
+	db := rawdb.NewMemoryDatabase()
+	engine := New(config, db)
+
+	var requests int
+	engine.SetSnapshotSource(func(number uint64, hash common.Hash) ([]byte, error) {
+		requests++
+		return blob, nil
+	}, true)
+	have, err := engine.snapshot(chain, 3, checkpoint.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to bootstrap snapshot: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("snapshot request count mismatch: have %d, want %d", requests, 1)
+	}
+	if len(have.Recents) != len(want.Recents) || have.Limit() != want.Limit() || len(have.Signers) != len(want.Signers) {
+		t.Errorf("bootstrapped snapshot mismatch: have %+v, want %+v", have, want)
+	}
+	snapdb, _ := engine.snapshotDB(nil)
+	if _, err := loadSnapshot(config, engine.signatures, snapdb, checkpoint.Hash()); err != nil {
+		t.Errorf("bootstrapped snapshot not persisted: %v", err)
+	}
+	// Ensure snapshots mismatching the checkpoint commitments are rejected
+	tamper := func(edit func(snap map[string]interface{})) []byte {
+		var snap map[string]interface{}
+		json.Unmarshal(blob, &snap)
+		edit(snap)
+		tampered, _ := json.Marshal(snap)
+		return tampered
+	}
+	tests := []struct {
+		name string
+		blob []byte
+	}{
+		{"wrong signers", tamper(func(snap map[string]interface{}) {
+			snap["signers"] = map[string]interface{}{accounts.address("C").Hex(): struct{}{}}
+		})},
+		{"wrong limit", tamper(func(snap map[string]interface{}) { snap["limit"] = 50 })},
+		{"pending votes", tamper(func(snap map[string]interface{}) {
+			snap["votes"] = []interface{}{map[string]interface{}{"signer": accounts.address("A"), "block": 2, "address": accounts.address("C"), "authorize": true}}
+		})},
+		{"forged recents", tamper(func(snap map[string]interface{}) {
+			snap["recents"] = map[string]interface{}{"3": accounts.address("A")}
+		})},
+		{"stale recents", tamper(func(snap map[string]interface{}) {
+			snap["recents"] = map[string]interface{}{"1": accounts.address("B")}
+		})},
+	}
+	for _, tt := range tests {
+		if _, err := New(config, rawdb.NewMemoryDatabase()).importSnapshot(chain, checkpoint, tt.blob, true); err != errInvalidBootstrapSnapshot {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, errInvalidBootstrapSnapshot)
+		}
+	}
+	// Ensure untrusted sources are refused, the checkpoint not committing to a root
+	if _, err := New(config, rawdb.NewMemoryDatabase()).importSnapshot(chain, checkpoint, blob, false); err != errUncommittedSnapshot {
+		t.Errorf("untrusted snapshot error mismatch: have %v, want %v", err, errUncommittedSnapshot)
+	}
+	untrusted := New(config, rawdb.NewMemoryDatabase())
+	untrusted.SetSnapshotSource(func(number uint64, hash common.Hash) ([]byte, error) {
+		return blob, nil
+	}, false)
+	if _, err := untrusted.snapshot(chain, 3, checkpoint.Hash(), nil); err != nil {
+		t.Fatalf("failed to replay snapshot: %v", err)
+	}
+	if !untrusted.bootstrapFailures.Contains(checkpoint.Hash()) {
+		t.Errorf("untrusted snapshot bootstrapped")
+	}
+}
+
+// Tests that snapshots are only considered committed to if the checkpoint header
+// carries a snapshot root and the snapshot has no state beyond what it covers.
+func TestCommittedSnapshot(t *testing.T) {
+	addrs := []common.Address{{0x01}, {0x02}}
+	config := &params.CliqueConfig{Epoch: 4, SnapshotRootBlock: big.NewInt(4)}
+	snap := newSnapshot(config, nil, 4, common.Hash{}, addrs)
+
+	extra := make([]byte, extraVanity)
+	for _, addr := range addrs {
+		extra = append(extra, addr[:]...)
+	}
+	bare := &types.Header{Number: big.NewInt(4), Extra: append(common.CopyBytes(extra), make([]byte, extraSeal)...)}
+	rooted := &types.Header{Number: big.NewInt(4), Extra: append(append(extra, encodeSnapshotRoot(snapshotRootVersion, snap.Root())...), make([]byte, extraSeal)...)}
+
+	if err := verifyCommittedSnapshot(config, bare, snap); err != errUncommittedSnapshot {
+		t.Errorf("rootless checkpoint error mismatch: have %v, want %v", err, errUncommittedSnapshot)
+	}
+	if err := verifyCommittedSnapshot(config, rooted, snap); err != nil {
+		t.Errorf("failed to verify committed snapshot: %v", err)
+	}
+	snap.Permitted = map[common.Address]struct{}{{0x03}: {}}
+	if err := verifyCommittedSnapshot(config, rooted, snap); err != errUncommittedSnapshot {
+		t.Errorf("uncovered state error mismatch: have %v, want %v", err, errUncommittedSnapshot)
+	}
+}
+
+// Tests that snapshots are anchored at a trusted checkpoint without needing the
+// headers before it, and that checkpoints contradicting the header are rejected.
+func TestTrustedCheckpoint(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 3, SignerLimit: 50}
+		signers  = []string{"A", "B"}
+	)
+	// Create a chain fragment starting at a checkpoint, missing all its ancestors
+	checkpoint := &types.Header{
+		ParentHash: common.HexToHash("0xdeadbeef"),
+		Number:     big.NewInt(3),
+		Difficulty: diffInTurn,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(checkpoint, signers)
+	accounts.sign(checkpoint, "B")
+
+	header := &types.Header{
+		ParentHash: checkpoint.Hash(),
+		Number:     big.NewInt(4),
+		Difficulty: diffInTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	accounts.sign(header, "A")
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{nil, nil, nil, checkpoint, header}}
+
+	// Anchor an engine at the checkpoint and ensure its state is adopted
+	trusted := &params.CliqueCheckpoint{
+		Number:      3,
+		Hash:        checkpoint.Hash(),
+		Signers:     []common.Address{accounts.address("A"), accounts.address("B")},
+		SignerLimit: 66,
+	}
+	engine := New(config, rawdb.NewMemoryDatabase())
+	if err := engine.SetTrustedCheckpoint(trusted); err != nil {
+		t.Fatalf("failed to set trusted checkpoint: %v", err)
+	}
+	snap, err := engine.snapshot(chain, 4, header.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if snap.SignerLimit != 66 || len(snap.Signers) != 2 || snap.Recents[4] != accounts.address("A") {
+		t.Errorf("anchored snapshot mismatch: limit %d, signers %d, recents %v", snap.SignerLimit, len(snap.Signers), snap.Recents)
+	}
+	// Ensure a checkpoint contradicting the header is refused
+	forged := *trusted
+	forged.Signers = []common.Address{accounts.address("A"), accounts.address("C")}
+
+	engine = New(config, rawdb.NewMemoryDatabase())
+	if err := engine.SetTrustedCheckpoint(&forged); err != nil {
+		t.Fatalf("failed to set forged checkpoint: %v", err)
+	}
+	if _, err := engine.snapshot(chain, 4, header.Hash(), nil); err != errInvalidTrustedCheckpoint {
+		t.Errorf("forged checkpoint error mismatch: have %v, want %v", err, errInvalidTrustedCheckpoint)
+	}
+	// Ensure checkpoints off the epoch boundaries are refused upfront
+	forged = *trusted
+	forged.Number = 4
+	if err := engine.SetTrustedCheckpoint(&forged); err != errInvalidTrustedCheckpoint {
+		t.Errorf("unaligned checkpoint error mismatch: have %v, want %v", err, errInvalidTrustedCheckpoint)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// MarshalCanonical encodes the snapshot into its canonical JSON form: object keys
+// in ascending order, no insignificant whitespace, empty maps always present and
+// empty lists always absent. The canonical form is thus identical across nodes
+// regardless of whether their containers were allocated or left nil, and can be
+// compared byte by byte.
+func (s *Snapshot) MarshalCanonical() ([]byte, error) {
+	// Allocate the missing maps on a shallow copy, the snapshot may be shared
+	normal := *s
+	normal.ensureContainers()
+
+	blob, err := json.Marshal(&normal)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(blob)
+}
+
+// CanonicalHash returns the hash of the canonical encoding of the snapshot, which
+// nodes agreeing on the consensus state at the snapshot's block share.
+func (s *Snapshot) CanonicalHash() (common.Hash, error) {
+	blob, err := s.MarshalCanonical()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(blob), nil
+}
+
+// canonicalJSON re-encodes a JSON document into its canonical form.
+func canonicalJSON(blob []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(blob))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := writeCanonical(buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCanonical encodes a decoded JSON value canonically into the buffer. Object
+// members holding null or an empty list are omitted, array elements are always
+// retained as their position is significant.
+func writeCanonical(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key, member := range v {
+			if !isEmptyJSON(member) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case string, bool, nil:
+		blob, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(blob)
+
+	case json.Number:
+		buf.WriteString(v.String())
+
+	default:
+		return fmt.Errorf("unexpected JSON value %T", value)
+	}
+	return nil
+}
+
+// isEmptyJSON reports whether a decoded JSON value is null or an empty list, both
+// of which decode into a nil slice.
+func isEmptyJSON(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
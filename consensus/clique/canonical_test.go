@@ -0,0 +1,91 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the canonical encoding of a snapshot is independent of how its
+// containers are populated, survives a database round trip and tells different
+// consensus states apart.
+func TestCanonicalSnapshot(t *testing.T) {
+	pool := newTesterAccountPool()
+	addrs := []common.Address{pool.address("A"), pool.address("B"), pool.address("C")}
+
+	config := &params.CliqueConfig{Epoch: 30000}
+	snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, addrs)
+
+	header := &types.Header{Number: big.NewInt(1), Coinbase: pool.address("D"), Extra: make([]byte, extraVanity+extraSeal)}
+	copy(header.Nonce[:], nonceAuthVote)
+	pool.sign(header, "A")
+
+	snap, err := snap.apply([]*types.Header{header})
+	if err != nil {
+		t.Fatalf("failed to apply header: %v", err)
+	}
+	want, err := snap.MarshalCanonical()
+	if err != nil {
+		t.Fatalf("failed to encode snapshot: %v", err)
+	}
+	// The canonical encoding must be a fixed point of the canonicalization
+	if again, err := canonicalJSON(want); err != nil || !bytes.Equal(again, want) {
+		t.Errorf("canonical encoding not stable: have %s, want %s", again, want)
+	}
+	// Nil and empty containers must encode identically
+	plain, _ := json.Marshal(snap)
+	decoded := new(Snapshot)
+	if err := json.Unmarshal(plain, decoded); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	decoded.Permitted, decoded.PermitTally, decoded.SignerLimitWait = nil, nil, nil
+	decoded.SignerLimitVotes = []*LimitVote{}
+	decoded.PermitVotes = []*Vote{}
+	if have, _ := decoded.MarshalCanonical(); !bytes.Equal(have, want) {
+		t.Errorf("canonical encoding mismatch:\nhave %s\nwant %s", have, want)
+	}
+	// The encoding stored in the database must be the canonical one
+	db := rawdb.NewMemoryDatabase()
+	if err := snap.store(db); err != nil {
+		t.Fatalf("failed to store snapshot: %v", err)
+	}
+	if blob, _ := db.Get(append(append([]byte{}, snapshotPrefix...), snap.Hash[:]...)); !bytes.Equal(blob, want) {
+		t.Errorf("stored encoding mismatch:\nhave %s\nwant %s", blob, want)
+	}
+	loaded, err := loadSnapshot(config, nil, db, snap.Hash)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	hash, _ := snap.CanonicalHash()
+	if have, _ := loaded.CanonicalHash(); have != hash {
+		t.Errorf("loaded snapshot hash mismatch: have %x, want %x", have, hash)
+	}
+	// A different consensus state must hash differently
+	other := snap.copy()
+	other.removeSigner(pool.address("C"))
+	if have, _ := other.CanonicalHash(); have == hash {
+		t.Errorf("differing snapshots hash identically")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// applyChunked applies a run of headers on top of a snapshot in chunks ending on
+// the checkpoint interval boundaries, loading the headers not yet in memory one
+// chunk at a time. The checkpoint snapshots are persisted after every chunk, so
+// the memory use stays the same however long the run, and an interrupted replay
+// resumes from the last chunk instead of the start. The signer recoveries are
+// tallied into stats, and the chunks labelled as replays in CPU profiles.
+//
+// Replays also cover side chains and historical blocks, so the proposals resolved
+// and blocks sealed aren't recorded here, but by adoptHeaders for the canonical
+// chain only.
+func (c *Clique) applyChunked(chain consensus.ChainHeaderReader, db ethdb.Database, snap *Snapshot, hashes []common.Hash, headers []*types.Header, stats *applyStats) (*Snapshot, error) {
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		interval = c.snapshotMemoryInterval()
+	)
+	for done := 0; done < len(hashes); {
+		size := int(checkpointInterval - snap.Number%checkpointInterval)
+		if interval > 1 {
+			// Stop on the memory interval too, caching the snapshots on it, so
+			// later lookups don't recompute them from further back
+			if gap := int(interval - snap.Number%interval); gap < size {
+				size = gap
+			}
+		}
+		if size > len(hashes)-done {
+			size = len(hashes) - done
+		}
+		chunk := make([]*types.Header, size)
+		for i := range chunk {
+			if chunk[i] = headers[done+i]; chunk[i] == nil {
+				chunk[i] = chain.GetHeader(hashes[done+i], snap.Number+uint64(i)+1)
+				if chunk[i] == nil {
+					return nil, consensus.ErrUnknownAncestor
+				}
+			}
+		}
+		var (
+			next *Snapshot
+			err  error
+		)
+		profileDo(profileReplay, snap.Number+1, snap.Number+uint64(size), func() {
+			next, err = snap.applyWith(chunk, stats)
+		})
+		if err != nil {
+			return nil, err
+		}
+		// If we've generated a new checkpoint snapshot, save to disk. This needs to
+		// happen before the snapshot is shared, as it becomes the base of later deltas.
+		if next.Number%checkpointInterval == 0 {
+			if err := next.persist(db); err != nil {
+				return nil, err
+			}
+			log.Trace("Stored voting snapshot to disk", "number", next.Number, "hash", next.Hash, "deltas", next.deltas)
+		}
+		snap, done = next, done+size
+		if interval > 1 && done < len(hashes) {
+			c.cacheSnapshot(snap)
+		}
+
+		// If we're taking too much time, notify the user once a while
+		if done < len(hashes) && time.Since(logged) > 8*time.Second {
+			log.Info("Reconstructing voting history", "processed", done, "total", len(hashes), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if time.Since(start) > 8*time.Second {
+		log.Info("Reconstructed voting history", "processed", len(hashes), "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	return snap, nil
+}
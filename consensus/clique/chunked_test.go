@@ -0,0 +1,79 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that replaying a run of headers longer than the checkpoint interval
+// yields the same snapshot as applying them in one go, persisting the checkpoint
+// snapshots crossed on the way.
+func TestChunkedApplication(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, []string{"A"})
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	for i := 1; i <= checkpointInterval+100; i++ {
+		header := &types.Header{
+			ParentHash: chain.headers[i-1].Hash(),
+			Number:     big.NewInt(int64(i)),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, "A")
+		chain.headers = append(chain.headers, header)
+	}
+	head := chain.CurrentHeader()
+
+	engine := New(config, rawdb.NewMemoryDatabase())
+	snap, err := engine.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to replay headers: %v", err)
+	}
+	if snap.Number != head.Number.Uint64() || snap.Hash != head.Hash() {
+		t.Errorf("snapshot head mismatch: have #%d [%x], want #%d [%x]", snap.Number, snap.Hash, head.Number, head.Hash())
+	}
+	// Applying all the headers at once must produce the same state
+	want, err := newSnapshot(config, NewSigCache(16, nil), 0, genesis.Hash(), []common.Address{accounts.address("A")}).apply(chain.headers[1:])
+	if err != nil {
+		t.Fatalf("failed to apply headers: %v", err)
+	}
+	if snap.Root() != want.Root() || len(snap.Recents) != len(want.Recents) {
+		t.Errorf("chunked snapshot mismatch")
+	}
+	// The checkpoint snapshot crossed midway must have been persisted
+	db, _ := engine.snapshotDB(chain)
+	checkpoint := chain.headers[checkpointInterval]
+	if _, err := loadSnapshot(config, nil, db, checkpoint.Hash()); err != nil {
+		t.Errorf("intermediate checkpoint snapshot not persisted: %v", err)
+	}
+}
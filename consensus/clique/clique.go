@@ -19,29 +19,32 @@ package clique
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"math/rand"
+	"runtime/pprof"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
-	lru "github.com/hashicorp/golang-lru"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -51,6 +54,8 @@ const (
 	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
 
 	wiggleTime = 500 * time.Millisecond // Random delay (per signer) to allow concurrent signers
+
+	defaultSignerLimit = 50 // Default percentage of signers needed to pass a proposal
 )
 
 // Clique proof-of-authority protocol constants.
@@ -60,10 +65,10 @@ var (
 	extraVanity = 32                     // Fixed number of extra-data prefix bytes reserved for signer vanity
 	extraSeal   = crypto.SignatureLength // Fixed number of extra-data suffix bytes reserved for signer seal
 
-	nonceAuthVote = hexutil.MustDecode("0xffffffffffffffff") // Magic nonce number to vote on adding a new signer
-	nonceDropVote = hexutil.MustDecode("0x0000000000000000") // Magic nonce number to vote on removing a signer.
+	nonceAuthVote = hexutil.MustDecode("0xffffffffffffffff") // Default magic nonce number to vote on adding a new signer
+	nonceDropVote = hexutil.MustDecode("0x0000000000000000") // Default magic nonce number to vote on removing a signer.
 
-	nonceSignerLimitAuthVote = hexutil.MustDecode("0xfffffff100000000") // Magic nonce number to vote on adding a new signer
+	nonceSignerLimitAuthVote = hexutil.MustDecode("0xfffffff100000000") // Default magic nonce number to vote on the signer limit
 
 	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
 
@@ -129,6 +134,10 @@ var (
 	// the previous block's timestamp + the minimum block period.
 	errInvalidTimestamp = errors.New("invalid timestamp")
 
+	// errEarlyOutOfTurn is returned if an out-of-turn block is timestamped before
+	// the configured out-of-turn sealing delay elapsed.
+	errEarlyOutOfTurn = errors.New("out-of-turn block sealed too early")
+
 	// errInvalidVotingChain is returned if an authorization list is attempted to
 	// be modified via out-of-range or non-contiguous headers.
 	errInvalidVotingChain = errors.New("invalid voting chain")
@@ -139,18 +148,33 @@ var (
 	// errRecentlySigned is returned if a header is signed by an authorized entity
 	// that already signed a header recently, thus is temporarily not allowed to.
 	errRecentlySigned = errors.New("recently signed")
+
+	// errInvalidSignerLimit is returned if a signer limit vote carries a limit out
+	// of the permitted range (1-100 percent, or 1-signer count if absolute).
+	errInvalidSignerLimit = errors.New("invalid signer limit vote")
 )
 
 // SignerFn hashes and signs the data to be signed by a backing account.
 type SignerFn func(signer accounts.Account, mimeType string, message []byte) ([]byte, error)
 
 // ecrecover extracts the Ethereum account address from a signed header.
-func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, error) {
+func ecrecover(header *types.Header, sigcache *SigCache) (common.Address, error) {
 	// If the signature's already cached, return that
 	hash := header.Hash()
 	if address, known := sigcache.Get(hash); known {
-		return address.(common.Address), nil
+		return address, nil
 	}
+	signer, err := recoverSeal(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sigcache.Add(hash, signer)
+	return signer, nil
+}
+
+// recoverSeal extracts the Ethereum account address from the seal of a header,
+// bypassing the signature cache.
+func recoverSeal(header *types.Header) (common.Address, error) {
 	// Retrieve the signature from the header extra-data
 	if len(header.Extra) < extraSeal {
 		return common.Address{}, errMissingSignature
@@ -164,8 +188,6 @@ func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, er
 	}
 	var signer common.Address
 	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
-
-	sigcache.Add(hash, signer)
 	return signer, nil
 }
 
@@ -175,15 +197,68 @@ type Clique struct {
 	config *params.CliqueConfig // Consensus engine configuration parameters
 	db     ethdb.Database       // Database to store and retrieve snapshot checkpoints
 
-	recents    *lru.ARCCache // Snapshots for recent block to speed up reorgs
-	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
-
-	proposals            map[common.Address]bool // Current list of proposals we are pushing
-	signerLimitProposals map[uint]bool           // Current list of signer limit percentage we are pushing
-
-	signer common.Address // Ethereum address of the signing key
-	signFn SignerFn       // Signer function to authorize hashes with
-	lock   sync.RWMutex   // Protects the signer fields
+	snapdb        ethdb.Database // Database namespace of the chain's snapshots, set up on first use and flushed in the background
+	namespaceLock sync.Mutex     // Protects the snapshot namespace setup
+
+	recents    *lru.Cache[common.Hash, *Snapshot] // Snapshots for recent block to speed up reorgs
+	forks      *forkTree                          // Snapshots of the blocks abandoned by recent micro reorgs
+	signatures *SigCache                          // Signatures of recent blocks to speed up mining
+
+	proposals            map[common.Address]bool           // Current list of proposals we are pushing
+	signerLimitProposals map[uint]bool                     // Current list of signer limit percentage we are pushing
+	limitWait            *LimitWaitError                   // Last signer limit proposal dropped during its cooldown
+	overrides            map[uint64]*signerOverride        // Signer set overrides to embed at upcoming checkpoints
+	permitProposals      map[common.Address]bool           // Current list of sender permissions we are pushing
+	periodProposals      map[common.Address]uint64         // Current list of signer periods we are pushing
+	replaceProposals     map[common.Address]common.Address // Current list of signer replacements we are pushing (old -> new)
+	resigning            bool                              // Whether the local signer is leaving the signer set
+	recovery             bool                              // Whether to drop offline signers after a chain halt
+
+	strategy       string                           // Order the local signer votes on its queued proposals in
+	proposalInfo   map[common.Address]proposalInfo  // Queueing metadata of the authorization proposals
+	proposalSeq    uint64                           // Last sequence number handed out to a proposal
+	proposalCursor uint64                           // Sequence number of the last proposal voted on (round-robin)
+	reveals        map[common.Address]pendingReveal // Votes committed to by the local signer, pending their reveal
+
+	seals      *sealTracker                       // Participation of the signers within the recent blocks
+	rejections *rejectionTracker                  // Consensus rejections of the signers' seals
+	replays    reconstructionTracker              // Lengthy voting history replays in progress
+	deposits   *depositReader                     // Access to the chain state to check candidate deposits with
+	metadata   map[common.Address]*SignerMetadata // Operator metadata registry approved by the signers
+
+	head        *types.Header // Last canonical chain head the engine was anchored on
+	missedSlots int           // Consecutive in-turn slots the local signer missed up to the head
+	headLock    sync.Mutex    // Protects the chain head across reorg handling
+
+	stateFeed   event.Feed              // Feed of the consensus state of new chain heads
+	voteFeed    event.Feed              // Feed of the votes counted in new chain heads
+	limitFeed   event.Feed              // Feed of the signer limit changes in new chain heads
+	failureFeed event.Feed              // Feed of the in-turn slots missed due to signing failures
+	scope       event.SubscriptionScope // Subscription scope tracking the feed subscribers
+
+	calculator DifficultyCalculator // Custom fork-choice weight of the blocks (nil = in-turn/out-of-turn)
+	attestKey  *attestationKey      // BLS key the local signer attests checkpoints with (nil = no attestations)
+
+	source            SnapshotSource                    // Remote source to bootstrap checkpoint snapshots from
+	sourceTrusted     bool                              // Whether the snapshot source is trusted with state the checkpoints don't commit to
+	bootstrapFailures *lru.Cache[common.Hash, struct{}] // Checkpoints that recently failed to bootstrap
+	trusted           *params.CliqueCheckpoint          // Checkpoint to anchor the snapshots at instead of replaying history
+	flushed           common.Hash                       // Snapshot flushed to disk on the last shutdown
+
+	signer         common.Address  // Ethereum address of the signing key
+	signFn         SignerFn        // Signer function to authorize hashes with
+	feeRecipient   common.Address  // Account the local signer declares to credit its fees to
+	retry          SealRetryPolicy // Policy to retry failed block signatures with
+	verifyWorkers  int             // Hard cap of the header verification workers (0 = number of cores)
+	memoryInterval uint64          // Block interval of the snapshots kept in memory (0 = every block)
+	lock           sync.RWMutex    // Protects the signer fields
+
+	alerts    AlertHook  // Hook to notify of consensus anomalies (nil = disabled)
+	alertLock sync.Mutex // Protects the alert hook, separately as alerts may be raised under the signer lock
+
+	quit      chan struct{}  // Quit channel to cancel the in-flight work on shutdown
+	wg        sync.WaitGroup // Background tasks to wait for on shutdown
+	closeLock sync.Mutex     // Serializes task registration with shutdown
 
 	// The fields below are for testing only
 	fakeDiff bool // Skip difficulty verifications
@@ -192,23 +267,59 @@ type Clique struct {
 // New creates a Clique proof-of-authority consensus engine with the initial
 // signers set to the ones provided by the user.
 func New(config *params.CliqueConfig, db ethdb.Database) *Clique {
+	return NewWithSigCache(config, db, NewSigCache(inmemorySignatures, nil))
+}
+
+// NewWithSigCache creates a Clique proof-of-authority consensus engine recovering
+// block signers through the given signature cache, which may be shared with other
+// engine instances.
+func NewWithSigCache(config *params.CliqueConfig, db ethdb.Database, signatures *SigCache) *Clique {
 	// Set any missing consensus parameters to their defaults
 	conf := *config
 	if conf.Epoch == 0 {
 		conf.Epoch = epochLength
 	}
+	if conf.AbsoluteSignerLimit && conf.SignerLimit == 0 {
+		log.Warn("Absolute signer limit not configured, falling back to percentage", "limit", defaultSignerLimit)
+		conf.AbsoluteSignerLimit = false
+	}
+	if conf.SignerLimit == 0 {
+		conf.SignerLimit = defaultSignerLimit
+	}
+	if conf.TrustedCheckpoint != nil && validateTrustedCheckpoint(&conf, conf.TrustedCheckpoint) != nil {
+		log.Warn("Invalid trusted checkpoint, replaying from genesis", "number", conf.TrustedCheckpoint.Number, "hash", conf.TrustedCheckpoint.Hash)
+		conf.TrustedCheckpoint = nil
+	}
 	// Allocate the snapshot caches and create the engine
-	recents, _ := lru.NewARC(inmemorySnapshots)
-	signatures, _ := lru.NewARC(inmemorySignatures)
+	recents := lru.New[common.Hash, *Snapshot](inmemorySnapshots, lru.ARC, "clique/snapshots")
+	failures := lru.New[common.Hash, struct{}](inmemorySnapshots, lru.LRU, "")
 
-	return &Clique{
+	c := &Clique{
 		config:               &conf,
 		db:                   db,
 		recents:              recents,
+		forks:                newForkTree(),
 		signatures:           signatures,
 		proposals:            make(map[common.Address]bool),
 		signerLimitProposals: make(map[uint]bool),
+		overrides:            make(map[uint64]*signerOverride),
+		permitProposals:      make(map[common.Address]bool),
+		periodProposals:      make(map[common.Address]uint64),
+		replaceProposals:     make(map[common.Address]common.Address),
+		deposits:             new(depositReader),
+		proposalInfo:         make(map[common.Address]proposalInfo),
+		reveals:              make(map[common.Address]pendingReveal),
+		seals:                newSealTracker(sealWindow),
+		rejections:           newRejectionTracker(),
+		metadata:             make(map[common.Address]*SignerMetadata),
+		bootstrapFailures:    failures,
+		trusted:              conf.TrustedCheckpoint,
+		retry:                DefaultSealRetryPolicy,
+		quit:                 make(chan struct{}),
 	}
+	c.loadProposals()
+	c.loadMetadata()
+	return c
 }
 
 // Author implements consensus.Engine, returning the Ethereum address recovered
@@ -217,6 +328,12 @@ func (c *Clique) Author(header *types.Header) (common.Address, error) {
 	return ecrecover(header, c.signatures)
 }
 
+// PrewarmSeal implements consensus.SealPrewarmer, recovering the signer of an
+// announced header into the signature cache so its import doesn't redo it.
+func (c *Clique) PrewarmSeal(header *types.Header) {
+	ecrecover(header, c.signatures)
+}
+
 // VerifyHeader checks whether a header conforms to the consensus rules.
 func (c *Clique) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
 	return c.verifyHeader(chain, header, nil)
@@ -229,17 +346,36 @@ func (c *Clique) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*typ
 	abort := make(chan struct{})
 	results := make(chan error, len(headers))
 
-	go func() {
+	if !c.track() {
+		for range headers {
+			results <- errEngineClosed
+		}
+		return abort, results
+	}
+	first, last := headerSpan(headers)
+	profileGo(profileVerify, first, last, func(ctx context.Context) {
+		defer c.wg.Done()
+
+		// Recover the signers of large batches in parallel ahead of verifying
+		// them, the snapshots themselves can only be built sequentially
+		stop := c.prefetchSigners(headers, c.verifyWorkerCount(headers, time.Now()))
+		defer stop()
+
 		for i, header := range headers {
 			err := c.verifyHeader(chain, header, headers[:i])
 
+			// Reattribute the goroutine to the batch if a replay relabelled it
+			pprof.SetGoroutineLabels(ctx)
+
 			select {
 			case <-abort:
 				return
+			case <-c.quit:
+				return
 			case results <- err:
 			}
 		}
-	}()
+	})
 	return abort, results
 }
 
@@ -262,11 +398,34 @@ func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 	if checkpoint && header.Coinbase != (common.Address{}) {
 		return errInvalidCheckpointBeneficiary
 	}
-	// Nonces must be 0x00..0 or 0xff..f, zeroes enforced on checkpoints
-	if !bytes.Equal(header.Nonce[:], nonceAuthVote) && !bytes.Equal(header.Nonce[:], nonceDropVote) && !bytes.Equal(header.Nonce[:], nonceSignerLimitAuthVote) {
+	// Nonces must be one of the vote nonces of the chain (0x00..0 or 0xff..f by
+	// default), the drop nonce enforced on checkpoints unless the signer set is
+	// being overridden
+	override := checkpoint && isOverride(header)
+	sealed := isCommitVote(header) || isRevealVote(header)
+	nonces := c.voteNonces()
+	if header.Nonce != nonces.auth && header.Nonce != nonces.drop && header.Nonce != nonces.limit && !isPermitVote(header) && !isPeriodVote(header) && !isResignation(header) && !isReplaceVote(header) && !sealed && !override {
+		return errInvalidVote
+	}
+	if sealed && c.config.RevealWindow == 0 {
 		return errInvalidVote
 	}
-	if checkpoint && !bytes.Equal(header.Nonce[:], nonceDropVote) {
+	if isPermitVote(header) && !c.config.IsPermissioned(header.Number) {
+		return errPermissionDisabled
+	}
+	if isPeriodVote(header) && !c.config.IsSignerPeriod(header.Number) {
+		return errPeriodDisabled
+	}
+	if isResignation(header) && !c.config.IsResignation(header.Number) {
+		return errResignationDisabled
+	}
+	if isReplaceVote(header) && !c.config.IsReplacement(header.Number) {
+		return errReplacementDisabled
+	}
+	if override && !c.config.EmergencyOverride {
+		return errOverrideDisabled
+	}
+	if checkpoint && !override && header.Nonce != nonces.drop {
 		return errInvalidCheckpointVote
 	}
 	// Check that the extra-data contains both the vanity and signature
@@ -277,15 +436,24 @@ func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 		return errMissingSignature
 	}
 	// Ensure that the extra-data contains a signer list on checkpoint, but none otherwise
-	signersBytes := len(header.Extra) - extraVanity - extraSeal
-	if !checkpoint && signersBytes != 0 {
-		return errExtraSigners
+	if err := verifyExtraPayload(c.config, header); err != nil {
+		return err
+	}
+	if !checkpoint && len(headerPayload(c.config, header)) != 0 {
+		votes, _, _ := splitPayload(c.config, header)
+		if len(votes) != 0 && !(c.config.IsGovernance(header.Number) && len(votes)%governanceVoteLength == 0) {
+			return errExtraSigners
+		}
 	}
-	if checkpoint && signersBytes%common.AddressLength != 0 {
-		return errInvalidCheckpointSigners
+	if checkpoint && !override {
+		signers, aggregate, root := splitCheckpoint(c.config, header)
+		if len(signers)%common.AddressLength != 0 || (aggregate != nil && !c.config.IsAttestation(header.Number)) || (root != nil) != c.config.IsSnapshotRoot(header.Number) {
+			return errInvalidCheckpointSigners
+		}
 	}
-	// Ensure that the mix digest is zero as we don't have fork protection currently
-	if header.MixDigest != (common.Hash{}) {
+	// Ensure that the mix digest is zero as we don't have fork protection currently,
+	// unless it's a checkpoint committing to the signer limit
+	if header.MixDigest != (common.Hash{}) && !(checkpoint && c.config.CheckpointLimit) && !isReplaceVote(header) {
 		return errInvalidMixDigest
 	}
 	// Ensure that the block doesn't contain any uncles which are meaningless in PoA
@@ -294,7 +462,10 @@ func (c *Clique) verifyHeader(chain consensus.ChainHeaderReader, header *types.H
 	}
 	// Ensure that the block's difficulty is meaningful (may not be correct at this point)
 	if number > 0 {
-		if header.Difficulty == nil || (header.Difficulty.Cmp(diffInTurn) != 0 && header.Difficulty.Cmp(diffNoTurn) != 0) {
+		if header.Difficulty == nil || header.Difficulty.Sign() <= 0 {
+			return errInvalidDifficulty
+		}
+		if c.calculator == nil && header.Difficulty.Cmp(diffInTurn) != 0 && header.Difficulty.Cmp(diffNoTurn) != 0 {
 			return errInvalidDifficulty
 		}
 	}
@@ -354,15 +525,87 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 	if err != nil {
 		return err
 	}
-	// If the block is a checkpoint block, verify the signer list
+	// If the block is a signer limit or period vote, ensure the value is sane
+	if header.Nonce == c.voteNonces().limit {
+		if _, ok := snap.decodeSignerLimit(header.Coinbase); !ok {
+			return errInvalidSignerLimit
+		}
+	}
+	if err := snap.verifyPayloadLimit(header); err != nil {
+		return err
+	}
+	if isPeriodVote(header) {
+		if _, ok := snap.decodePeriodVote(header); !ok {
+			return errInvalidSignerPeriod
+		}
+	}
+	if isReplaceVote(header) {
+		if err := snap.verifyReplaceVote(header); err != nil {
+			return err
+		}
+	}
+	// Membership votes may not take the signer set out of its size bounds
+	if err := snap.verifySignerCount(header); err != nil {
+		return err
+	}
+	// Signers may only resign themselves
+	if isResignation(header) {
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return err
+		}
+		if err := verifyResignation(header, signer); err != nil {
+			return err
+		}
+	}
+	// If the block is a checkpoint block, verify the signer list and limit, and if
+	// the signer set is overridden, check the seal against the new signers
 	if number%c.config.Epoch == 0 {
-		signers := make([]byte, len(snap.Signers)*common.AddressLength)
-		for i, signer := range snap.signers() {
-			copy(signers[i*common.AddressLength:], signer[:])
+		if err := verifyCheckpoint(snap, header); err != nil {
+			return err
 		}
-		extraSuffix := len(header.Extra) - extraSeal
-		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], signers) {
-			return errMismatchingCheckpointSigners
+		if snap, err = snap.overridden(header); err != nil {
+			return err
+		}
+	}
+	// Signers granted a longer period must leave at least that much after the parent
+	if len(snap.Periods) > 0 && c.config.IsSignerPeriod(header.Number) {
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return err
+		}
+		if parent.Time+snap.signerPeriod(signer) > header.Time {
+			return errInvalidTimestamp
+		}
+	}
+	// Signers still on probation may seal, but not vote
+	if len(snap.Probations) > 0 && snap.castsVote(header) {
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return err
+		}
+		if err := snap.verifyProbation(header, signer); err != nil {
+			return err
+		}
+	}
+	// Out-of-turn blocks may be required to leave the in-turn signer some slack
+	if c.config.OutOfTurnSealDelay > 0 && parent.Time+c.config.Period+c.config.OutOfTurnSealDelay > header.Time {
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return err
+		}
+		if !snap.inturn(number, signer) {
+			return errEarlyOutOfTurn
+		}
+	}
+	// Any finality justification must be for the current target
+	if c.config.IsFinality(header.Number) {
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return err
+		}
+		if _, err := snap.verifyJustification(header, signer); err != nil {
+			return err
 		}
 	}
 	// All basic checks passed, verify the seal and return
@@ -371,50 +614,124 @@ func (c *Clique) verifyCascadingFields(chain consensus.ChainHeaderReader, header
 
 // snapshot retrieves the authorization snapshot at a given point in time.
 func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
+	return c.snapshotFor(triggerSync, chain, number, hash, parents)
+}
+
+// cachedSnapshot retrieves a snapshot from the in-memory cache, or from the ones
+// abandoned by a recent micro reorg (caching it again), without reconstructing
+// it if missing.
+func (c *Clique) cachedSnapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash) *Snapshot {
+	if s, ok := c.recents.Get(hash); ok {
+		return s
+	}
+	if c.forks.size() > 0 {
+		if header := chain.GetHeader(hash, number); header != nil {
+			if s := c.forks.get(header.ParentHash, hash); s != nil {
+				c.cacheSnapshot(s)
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotFor is snapshot, attributing the timing of any reconstruction needed to
+// the given trigger.
+func (c *Clique) snapshotFor(trigger applyTrigger, chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
+	c.lock.RLock()
+	trusted := c.trusted
+	c.lock.RUnlock()
+
 	var (
-		headers []*types.Header
+		hashes  []common.Hash   // Hashes of the headers to apply, newest first
+		headers []*types.Header // Headers to apply already in memory (explicit parents), nil otherwise
 		snap    *Snapshot
 	)
+	db, err := c.snapshotDB(chain)
+	if err != nil {
+		return nil, err
+	}
 	for snap == nil {
-		// If an in-memory snapshot was found, use that
-		if s, ok := c.recents.Get(hash); ok {
-			snap = s.(*Snapshot)
+		// If an in-memory or retained side fork snapshot was found, use that
+		if s := c.cachedSnapshot(chain, number, hash); s != nil {
+			snap = s
 			break
 		}
-		// If an on-disk checkpoint snapshot can be found, use that
-		if number%checkpointInterval == 0 {
-			if s, err := loadSnapshot(c.config, c.signatures, c.db, hash); err == nil {
+		// If an on-disk snapshot can be found, use that. Besides the checkpoints,
+		// the nearest ancestor found in the snapshot index is used, wherever it is
+		known := indexedSnapshot(db, number, hash)
+		if known || number%checkpointInterval == 0 || (number > 0 && number%c.config.Epoch == 0) || hash == c.flushed {
+			s, err := loadSnapshot(c.config, c.signatures, db, hash)
+			if err != nil && known && err != errCorruptSnapshot {
+				// Pruned or repaired since it was indexed, drop the stale entry
+				if err := unindexSnapshot(db, number, hash); err != nil {
+					return nil, err
+				}
+			}
+			if err == nil {
 				log.Trace("Loaded voting snapshot from disk", "number", number, "hash", hash)
 				snap = s
 				if number == 0 || snap.SignerLimit == 0 {
-					snap.SignerLimit = 50
+					snap.SignerLimit = c.config.SignerLimit
 				}
 				break
 			}
+			// If the snapshot is corrupted, drop it and rebuild it from the chain
+			if err == errCorruptSnapshot {
+				if err := deleteSnapshot(db, hash); err != nil {
+					return nil, err
+				}
+				if err := unindexSnapshot(db, number, hash); err != nil {
+					return nil, err
+				}
+				log.Warn("Deleted corrupt voting snapshot, rebuilding", "number", number, "hash", hash)
+			}
+		}
+		// If we're at the trusted checkpoint, start from its published state instead
+		// of replaying all the headers before it
+		if trusted != nil && number == trusted.Number && hash == trusted.Hash {
+			s, err := c.trustedSnapshot(chain, trusted)
+			if err != nil {
+				return nil, err
+			}
+			log.Info("Anchored voting snapshot at trusted checkpoint", "number", number, "hash", hash)
+			snap = s
+			break
+		}
+		// If we're at a checkpoint not available locally, try to bootstrap it from a
+		// remote source instead of replaying all the headers before it
+		if number > 0 && number%c.config.Epoch == 0 {
+			if checkpoint := chain.GetHeader(hash, number); checkpoint != nil {
+				if s := c.bootstrapSnapshot(chain, checkpoint); s != nil {
+					snap = s
+					break
+				}
+			}
 		}
 		// If we're at the genesis, snapshot the initial state. Alternatively if we're
 		// at a checkpoint block without a parent (light client CHT), or we have piled
 		// up more headers than allowed to be reorged (chain reinit from a freezer),
 		// consider the checkpoint trusted and snapshot it.
-		if number == 0 || (number%c.config.Epoch == 0 && (len(headers) > params.FullImmutabilityThreshold || chain.GetHeaderByNumber(number-1) == nil)) {
+		if number == 0 || (number%c.config.Epoch == 0 && (len(hashes) > params.FullImmutabilityThreshold || chain.GetHeaderByNumber(number-1) == nil)) {
 			checkpoint := chain.GetHeaderByNumber(number)
 			if checkpoint != nil {
 				hash := checkpoint.Hash()
 
-				signers := make([]common.Address, (len(checkpoint.Extra)-extraVanity-extraSeal)/common.AddressLength)
-				for i := 0; i < len(signers); i++ {
-					copy(signers[i][:], checkpoint.Extra[extraVanity+i*common.AddressLength:])
+				var err error
+				if snap, err = checkpointSnapshot(c.config, c.signatures, checkpoint); err != nil {
+					return nil, err
 				}
-				snap = newSnapshot(c.config, c.signatures, number, hash, signers)
-				if err := snap.store(c.db); err != nil {
+				if err := snap.persist(db); err != nil {
 					return nil, err
 				}
 				log.Info("Stored checkpoint snapshot to disk", "number", number, "hash", hash)
 				break
 			}
 		}
-		// No snapshot for this header, gather the header and move backward
+		// No snapshot for this header, gather the header and move backward. Only
+		// the hashes of the headers from the database are kept, they are reloaded
+		// chunk by chunk when applied to keep the memory use bounded.
 		var header *types.Header
 		if len(parents) > 0 {
 			// If we have explicit parents, pick from there (enforced)
@@ -423,43 +740,63 @@ func (c *Clique) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 				return nil, consensus.ErrUnknownAncestor
 			}
 			parents = parents[:len(parents)-1]
+			headers = append(headers, header)
 		} else {
 			// No explicit parents (or no more left), reach out to the database
 			header = chain.GetHeader(hash, number)
 			if header == nil {
 				return nil, consensus.ErrUnknownAncestor
 			}
+			headers = append(headers, nil)
 		}
-		headers = append(headers, header)
+		hashes = append(hashes, hash)
 		number, hash = number-1, header.ParentHash
 	}
 	// Previous snapshot found, apply any pending headers on top of it
-	for i := 0; i < len(headers)/2; i++ {
+	for i := 0; i < len(hashes)/2; i++ {
+		hashes[i], hashes[len(hashes)-1-i] = hashes[len(hashes)-1-i], hashes[i]
 		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
 	}
-	snap, err := snap.apply(headers)
+	var (
+		start = time.Now()
+		done  = c.replays.start(snap.Number, snap.Number+uint64(len(hashes)))
+		stats = newApplyStats(trigger)
+	)
+	snap, err = c.applyChunked(chain, db, snap, hashes, headers, stats)
+	done()
 	if err != nil {
 		return nil, err
 	}
-	c.recents.Add(snap.Hash, snap)
-
-	// If we've generated a new checkpoint snapshot, save to disk
-	if snap.Number%checkpointInterval == 0 && len(headers) > 0 {
-		if err = snap.store(c.db); err != nil {
-			return nil, err
-		}
-		log.Trace("Stored voting snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+	if len(hashes) > 0 {
+		stats.done(start, len(hashes))
+	}
+	if elapsed := time.Since(start); elapsed > alertRebuildTime {
+		c.alert(&Alert{
+			Kind:    AlertSlowRebuild,
+			Number:  snap.Number,
+			Hash:    snap.Hash,
+			Message: fmt.Sprintf("rebuilding the snapshot from %d headers took %v", len(hashes), common.PrettyDuration(elapsed)),
+		})
 	}
+	// Publish the snapshot, caching it unless the memory interval skips it
+	c.cacheSnapshot(snap)
+
 	return snap, err
 }
 
 // VerifyUncles implements consensus.Engine, always returning an error for any
-// uncles as this consensus mechanism doesn't permit uncles.
+// uncles as this consensus mechanism doesn't permit uncles. Being the only hook
+// verifying block bodies, it also checks the governance votes embedded into the
+// header against the governance transactions of the block, and that all of its
+// transactions were sent by permitted accounts.
 func (c *Clique) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
 	if len(block.Uncles()) > 0 {
 		return errors.New("uncles not allowed")
 	}
-	return nil
+	if err := verifyGovernanceVotes(chain, c.config, block); err != nil {
+		return err
+	}
+	return c.verifyPermittedSenders(chain, block)
 }
 
 // verifySeal checks whether the signature contained in the header satisfies the
@@ -478,23 +815,21 @@ func (c *Clique) verifySeal(snap *Snapshot, header *types.Header, parents []*typ
 		return err
 	}
 	if _, ok := snap.Signers[signer]; !ok {
+		c.rejections.mark(stageVerify, signer, number, errUnauthorizedSigner)
 		return errUnauthorizedSigner
 	}
 	for seen, recent := range snap.Recents {
-		if recent == signer {
+		if recent == signer && !snap.bootstrapping() {
 			// Signer is among recents, only fail if the current block doesn't shift it out
-			if limit := uint64(snap.signerLimit()); seen > number-limit {
+			if limit := snap.recentsWindow(number); seen > number-limit {
+				c.rejections.mark(stageVerify, signer, number, errRecentlySigned)
 				return errRecentlySigned
 			}
 		}
 	}
 	// Ensure that the difficulty corresponds to the turn-ness of the signer
 	if !c.fakeDiff {
-		inturn := snap.inturn(header.Number.Uint64(), signer)
-		if inturn && header.Difficulty.Cmp(diffInTurn) != 0 {
-			return errWrongDifficulty
-		}
-		if !inturn && header.Difficulty.Cmp(diffNoTurn) != 0 {
+		if want := c.difficulty(snap, signer); want == nil || header.Difficulty.Cmp(want) != 0 {
 			return errWrongDifficulty
 		}
 	}
@@ -506,70 +841,203 @@ func (c *Clique) verifySeal(snap *Snapshot, header *types.Header, parents []*typ
 func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
 	// If the block isn't a checkpoint, cast a random vote (good enough for now)
 	header.Coinbase = common.Address{}
-	header.Nonce = types.BlockNonce{}
+	header.Nonce = c.voteNonces().drop
 
 	number := header.Number.Uint64()
 	// Assemble the voting snapshot to check which votes make sense
-	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+	snap, err := c.snapshotFor(triggerSealing, chain, number-1, header.ParentHash, nil)
 
 	if err != nil {
 		return err
 	}
-	if number%c.config.Epoch != 0 {
-		c.lock.RLock()
+	// Signers still on probation may seal, but not vote yet. Resigning signers
+	// don't vote either, but leave the signer set in the first block they seal.
+	c.lock.RLock()
+	signer := c.signer
+	probation := snap.onProbation(signer, number)
+	resign := c.resigning && c.config.IsResignation(header.Number) && snap.canResign(signer, number)
+	c.lock.RUnlock()
+
+	var limitVote uint64 // Signer limit voted on in the payload, alongside the header vote
+
+	if resign {
+		header.Coinbase = signer
+		copy(header.Nonce[:], nonceResignation)
+	} else if number%c.config.Epoch != 0 && !probation {
+		// Candidates must have locked a deposit as of the parent state to be voted in
+		var statedb *state.StateDB
+		if c.config.IsDeposit(header.Number) {
+			statedb = c.deposits.state(header.ParentHash)
+		}
+		c.lock.Lock()
 
 		// Gather all the proposals that make sense voting on
 		addresses := make([]common.Address, 0, len(c.proposals))
 		for address, authorize := range c.proposals {
-			if snap.validVote(address, authorize) {
+			if snap.validVote(address, authorize) && !snap.changesCapped() {
+				if authorize && !snap.eligible(header.Number, statedb, address) {
+					continue
+				}
 				addresses = append(addresses, address)
 			}
 		}
 
-		limits := make([]uint, 0, len(c.signerLimitProposals))
+		var (
+			limits  = make([]uint, 0, len(c.signerLimitProposals))
+			expired bool
+		)
+		if c.limitWait != nil && c.limitWait.Reopen <= number {
+			c.limitWait = nil
+		}
 		for limit, authorize := range c.signerLimitProposals {
-			if snap.SignerLimitWait[uint64(limit)].Block >= number {
+			if wait := snap.limitWait(limit, number); wait != nil {
+				log.Warn("Dropped signer limit proposal during cooldown", "limit", limit, "reopen", wait.Reopen)
 				delete(c.signerLimitProposals, limit)
+				c.limitWait = wait
+				expired = true
 			}
 
-			if snap.validSignerLimitVote(limit, authorize) {
+			if snap.validSignerLimitVote(limit, authorize, number) {
 				limits = append(limits, limit)
 			}
 		}
+		if expired {
+			c.storeProposals()
+		}
+		var permits []common.Address
+		if c.config.IsPermissioned(header.Number) {
+			for address, permit := range c.permitProposals {
+				if snap.validPermitVote(address, permit) {
+					permits = append(permits, address)
+				}
+			}
+		}
+		var replacements []common.Address
+		if c.config.IsReplacement(header.Number) {
+			for old, new := range c.replaceProposals {
+				if snap.validReplaceVote(old, new) {
+					if snap.eligible(header.Number, statedb, new) {
+						replacements = append(replacements, old)
+					}
+				}
+			}
+		}
+		var periods []common.Address
+		if c.config.IsSignerPeriod(header.Number) {
+			for address, period := range c.periodProposals {
+				if snap.validPeriodVote(address, period) {
+					periods = append(periods, address)
+				}
+			}
+		}
 
 		// If there's pending proposals, cast a vote on them
-		if len(addresses) > 0 {
-			header.Coinbase = addresses[rand.Intn(len(addresses))]
-			if c.proposals[header.Coinbase] {
-				copy(header.Nonce[:], nonceAuthVote)
-			} else {
-				copy(header.Nonce[:], nonceDropVote)
+		if len(replacements) > 0 {
+			old := replacements[rand.Intn(len(replacements))]
+			encodeReplaceVote(header, old, c.replaceProposals[old])
+		} else if len(addresses) > 0 {
+			address, ok := c.committedProposal(snap, c.signer, addresses)
+			if !ok {
+				address = c.selectProposal(addresses)
 			}
+			c.castSealed(snap, header, c.signer, address, c.proposals[address])
 		} else if len(limits) > 0 {
 			limit := limits[rand.Intn(len(limits))]
 			header.Coinbase = common.BigToAddress(new(big.Int).SetUint64(uint64(limit)))
-			copy(header.Nonce[:], nonceSignerLimitAuthVote)
+			header.Nonce = c.voteNonces().limit
+		} else if len(permits) > 0 {
+			header.Coinbase = permits[rand.Intn(len(permits))]
+			if c.permitProposals[header.Coinbase] {
+				copy(header.Nonce[:], noncePermitVote)
+			} else {
+				copy(header.Nonce[:], nonceRevokeVote)
+			}
+		} else if len(periods) > 0 {
+			header.Coinbase = periods[rand.Intn(len(periods))]
+			encodePeriodVote(header, c.periodProposals[header.Coinbase])
 		}
-		c.lock.RUnlock()
+		// Past the structured extra-data fork, blocks voting on anything else may
+		// vote on the signer limit in their payload too
+		if len(limits) > 0 && header.Nonce != c.voteNonces().limit && structuredExtra(c.config, header) {
+			limitVote = uint64(limits[rand.Intn(len(limits))])
+		}
+		c.lock.Unlock()
 	}
-	// Set the correct difficulty
-	header.Difficulty = calcDifficulty(snap, c.signer)
-
 	// Ensure the extra data has all its components
 	if len(header.Extra) < extraVanity {
 		header.Extra = append(header.Extra, bytes.Repeat([]byte{0x00}, extraVanity-len(header.Extra))...)
 	}
 	header.Extra = header.Extra[:extraVanity]
 
+	// Declare the fee recipient of the local signer, if it's set
+	c.lock.RLock()
+	recipient := c.feeRecipient
+	c.lock.RUnlock()
+
+	if recipient != (common.Address{}) && c.config.IsFeeRecipient(header.Number) {
+		header.Extra = append([]byte{}, header.Extra...) // Don't overwrite the miner's extra
+		declareFeeRecipient(header, recipient)
+	}
 	if number%c.config.Epoch == 0 {
+		payload := make([]byte, 0, len(snap.Signers)*common.AddressLength)
 		for _, signer := range snap.signers() {
-			header.Extra = append(header.Extra, signer[:]...)
+			payload = append(payload, signer[:]...)
+		}
+		// Embed any pending signer set override instead, unless it's not acceptable (anymore)
+		c.lock.RLock()
+		override := c.overrides[number]
+		c.lock.RUnlock()
+
+		if override != nil {
+			candidate := types.CopyHeader(header)
+			copy(candidate.Nonce[:], nonceSignerOverride)
+			candidate.Extra = append(append(candidate.Extra, encodeOverride(override)...), make([]byte, extraSeal)...)
+			structureExtra(c.config, candidate, 0)
+
+			if overridden, err := snap.overridden(candidate); err != nil {
+				log.Warn("Discarding invalid signer override", "number", number, "err", err)
+			} else {
+				header.Nonce, payload, snap = candidate.Nonce, encodeOverride(override), overridden
+			}
+		}
+		// Embed the aggregate attestation of the epoch and the root of the state
+		// after the checkpoint following a plain signer list
+		if !isOverride(header) {
+			payload = append(payload, snap.aggregate()...)
+			if c.config.IsSnapshotRoot(header.Number) {
+				payload = append(payload, snap.checkpointRoot(number)...)
+			}
+		}
+		header.Extra = append(header.Extra, payload...)
+	}
+	c.lock.Lock()
+	signer = c.signer
+	attestation := c.attestation(snap, signer)
+	c.lock.Unlock()
+
+	// Attest the last checkpoint, unless already done
+	if number%c.config.Epoch != 0 && c.config.IsAttestation(header.Number) && attestation != nil {
+		header.Extra = append(append([]byte{}, header.Extra...), attestation...)
+	}
+	// Justify the current finality target, unless already done
+	if number%c.config.Epoch != 0 && c.config.IsFinality(header.Number) {
+		if justification := snap.justification(number, signer); justification != nil {
+			header.Extra = append([]byte{}, header.Extra...) // Don't overwrite the miner's extra
+			embedJustification(header, justification)
 		}
 	}
 	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+	structureExtra(c.config, header, limitVote)
+
+	// Set the correct difficulty
 
-	// Mix digest is reserved for now, set to empty
+	header.Difficulty = c.difficulty(snap, signer)
+
+	// Mix digest is reserved for now, set to empty unless committing to the limit
 	header.MixDigest = common.Hash{}
+	if number%c.config.Epoch == 0 && c.config.CheckpointLimit {
+		header.MixDigest = limitCommitment(snap.epochLimit(number))
+	}
 
 	// Ensure the timestamp has the correct delay
 	parent := chain.GetHeader(header.ParentHash, number-1)
@@ -577,6 +1045,12 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 		return consensus.ErrUnknownAncestor
 	}
 	header.Time = parent.Time + c.config.Period
+	if c.config.IsSignerPeriod(header.Number) {
+		header.Time = parent.Time + snap.signerPeriod(signer)
+	}
+	if !snap.inturn(number, signer) {
+		header.Time += c.config.OutOfTurnSealDelay
+	}
 	if header.Time < uint64(time.Now().Unix()) {
 		header.Time = uint64(time.Now().Unix())
 	}
@@ -586,21 +1060,59 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given.
 func (c *Clique) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
-	// No block rewards in PoA, so the state remains as is and uncles are dropped
+	// Reward the sealer of the block, recovered from the seal as the coinbase
+	// carries the votes
+	if c.config.IsReward(header.Number) {
+		sealer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			log.Error("Failed to recover block sealer for reward", "number", header.Number, "err", err)
+		} else {
+			accumulateRewards(c.config, state, sealer)
+		}
+	}
+	// Commit the signer set to state at checkpoints
+	if err := c.commitSignerState(chain, header, state); err != nil {
+		log.Error("Failed to commit signer state", "number", header.Number, "err", err)
+	}
+	// Uncles are meaningless in PoA, drop them
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
 }
 
 // FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
-// nor block rewards given, and returns the final block.
+// rewarding the local signer if enabled, and returns the final block.
 func (c *Clique) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	// Reward the local signer, as the block isn't sealed yet
+	if c.config.IsReward(header.Number) {
+		c.lock.RLock()
+		signer := c.signer
+		c.lock.RUnlock()
+
+		accumulateRewards(c.config, state, signer)
+	}
+	// Embed the votes cast by governance transactions, as the block isn't sealed yet
+	if err := c.embedGovernanceVotes(chain, header, txs); err != nil {
+		return nil, err
+	}
+	// Commit the signer set to state at checkpoints
+	if err := c.commitSignerState(chain, header, state); err != nil {
+		return nil, err
+	}
 	// Finalize block
-	c.Finalize(chain, header, state, txs, uncles)
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	header.UncleHash = types.CalcUncleHash(nil)
 
 	// Assemble and return the final block for sealing
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
 }
 
+// accumulateRewards credits the sealer of a block with the configured reward.
+func accumulateRewards(config *params.CliqueConfig, state *state.StateDB, sealer common.Address) {
+	if config.BlockReward != nil && config.BlockReward.Sign() > 0 {
+		state.AddBalance(sealer, config.BlockReward)
+	}
+}
+
 // Authorize injects a private key into the consensus engine to mint new blocks
 // with.
 func (c *Clique) Authorize(signer common.Address, signFn SignerFn) {
@@ -631,66 +1143,101 @@ func (c *Clique) Seal(chain consensus.ChainHeaderReader, block *types.Block, res
 	c.lock.RUnlock()
 
 	// Bail out if we're unauthorized to sign a block
-	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+	snap, err := c.snapshotFor(triggerSealing, chain, number-1, header.ParentHash, nil)
 	if err != nil {
 		return err
 	}
+	if snap, err = snap.overridden(header); err != nil {
+		return err
+	}
 	if _, authorized := snap.Signers[signer]; !authorized {
+		c.rejections.mark(stageSeal, signer, number, errUnauthorizedSigner)
 		return errUnauthorizedSigner
 	}
 	// If we're amongst the recent signers, wait for the next block
 	for seen, recent := range snap.Recents {
-		if recent == signer {
+		if recent == signer && !snap.bootstrapping() {
 			// Signer is among recents, only wait if the current block doesn't shift it out
-			if limit := uint64(snap.signerLimit()); number < limit || seen > number-limit {
+			if limit := snap.recentsWindow(number); number < limit || seen > number-limit {
+				c.rejections.mark(stageSeal, signer, number, errRecentlySigned)
 				return errors.New("signed recently, must wait for others")
 			}
 		}
 	}
 	// Sweet, the protocol permits us to sign the block, wait for our time
-	delay := time.Unix(int64(header.Time), 0).Sub(time.Now()) // nolint: gosimple
-	if header.Difficulty.Cmp(diffNoTurn) == 0 {
-		// It's not our turn explicitly to sign, delay it a bit
-		wiggle := time.Duration(snap.signerLimit()) * wiggleTime
-		delay += time.Duration(rand.Int63n(int64(wiggle)))
+	due := time.Unix(int64(header.Time), 0)
+	inturn := snap.inturn(number, signer)
+	if !inturn {
+		// It's not our turn explicitly to sign, delay broadcasting it a bit
+		wiggle := time.Duration(snap.recentsWindow(number)) * c.wiggleTime()
+		due = due.Add(time.Duration(rand.Int63n(int64(wiggle))))
 
 		log.Trace("Out-of-turn signing requested", "wiggle", common.PrettyDuration(wiggle))
 	}
-	// Sign all the things!
+	// Sign all the things, retrying in the background if the signer fails
 	sighash, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeClique, CliqueRLP(header))
 	if err != nil {
-		return err
+		sealSignFailMeter.Mark(1)
+		if !c.track() {
+			return errEngineClosed
+		}
+		profileGo(profileSeal, number, number, func(context.Context) {
+			c.retrySeal(chain, block, header, signer, signFn, inturn, due, results, stop, err)
+		})
+		return nil
 	}
 	copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
 	// Wait until sealing is terminated or delay timeout.
+	delay := time.Until(due)
 	log.Trace("Waiting for slot to sign and propagate", "delay", common.PrettyDuration(delay))
-	go func() {
+	if !c.track() {
+		return errEngineClosed
+	}
+	profileGo(profileSeal, number, number, func(context.Context) {
+		defer c.wg.Done()
+
 		select {
 		case <-stop:
 			return
+		case <-c.quit:
+			return
 		case <-time.After(delay):
 		}
 
 		select {
-		case results <- block.WithSeal(header):
+		case results <- c.withReceipt(chain, block.WithSeal(header)):
 		default:
 			log.Warn("Sealing result is not read by miner", "sealhash", SealHash(header))
 		}
-	}()
+	})
 
 	return nil
 }
 
+// wiggleTime returns the maximum random broadcast delay of out-of-turn blocks
+// per recent signer.
+func (c *Clique) wiggleTime() time.Duration {
+	if c.config.OutOfTurnWiggle != 0 {
+		return time.Duration(c.config.OutOfTurnWiggle) * time.Millisecond
+	}
+	return wiggleTime
+}
+
 // CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
 // that a new block should have:
 // * DIFF_NOTURN(2) if BLOCK_NUMBER % SIGNER_COUNT != SIGNER_INDEX
 // * DIFF_INTURN(1) if BLOCK_NUMBER % SIGNER_COUNT == SIGNER_INDEX
+// unless a custom DifficultyCalculator is configured.
 func (c *Clique) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
-	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	snap, err := c.snapshotFor(triggerSealing, chain, parent.Number.Uint64(), parent.Hash(), nil)
 	if err != nil {
 		return nil
 	}
-	return calcDifficulty(snap, c.signer)
+	c.lock.RLock()
+	signer := c.signer
+	c.lock.RUnlock()
+
+	return c.difficulty(snap, signer)
 }
 
 func calcDifficulty(snap *Snapshot, signer common.Address) *big.Int {
@@ -705,8 +1252,26 @@ func (c *Clique) SealHash(header *types.Header) common.Hash {
 	return SealHash(header)
 }
 
-// Close implements consensus.Engine. It's a noop for clique as there are no background threads.
+// Close implements consensus.Engine, cancelling any in-flight sealing and header
+// verification, and flushing the voting snapshot of the chain head to disk.
 func (c *Clique) Close() error {
+	c.closeLock.Lock()
+	select {
+	case <-c.quit:
+		c.closeLock.Unlock()
+		return nil
+	default:
+		close(c.quit)
+	}
+	c.closeLock.Unlock()
+
+	c.wg.Wait()
+	c.flushSnapshot()
+
+	// Wait for all the snapshots written so far to reach the disk
+	if db, _ := c.snapshotDB(nil); db != c.db {
+		db.Close()
+	}
 	return nil
 }
 
@@ -19,10 +19,12 @@ package clique
 import (
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -125,3 +127,97 @@ func TestSealHash(t *testing.T) {
 		t.Errorf("have %x, want %x", have, want)
 	}
 }
+
+// Tests that out-of-turn blocks are stamped and verified against the configured
+// out-of-turn sealing delay, while in-turn blocks are unaffected.
+func TestOutOfTurnSealDelay(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Period: 5, Epoch: 30000, OutOfTurnSealDelay: 3}
+		signers  = []string{"A", "B"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Time:       uint64(time.Now().Unix()) + 100,
+		GasLimit:   8000000,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+	engine := New(config, rawdb.NewMemoryDatabase())
+
+	snap, err := engine.snapshot(chain, 0, genesis.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create genesis snapshot: %v", err)
+	}
+	inturn, noturn := "A", "B"
+	if !snap.inturn(1, accounts.address(inturn)) {
+		inturn, noturn = noturn, inturn
+	}
+	tests := []struct {
+		signer string
+		delay  uint64
+		err    error
+	}{
+		{signer: inturn, delay: 5},
+		{signer: noturn, delay: 5, err: errEarlyOutOfTurn},
+		{signer: noturn, delay: 7, err: errEarlyOutOfTurn},
+		{signer: noturn, delay: 8},
+	}
+	for i, tt := range tests {
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			Number:     common.Big1,
+			Time:       genesis.Time + tt.delay,
+			GasLimit:   genesis.GasLimit,
+			Difficulty: diffNoTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if tt.signer == inturn {
+			header.Difficulty = diffInTurn
+		}
+		accounts.sign(header, tt.signer)
+		if err := engine.verifyCascadingFields(chain, header, nil); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Ensure the out-of-turn signer stamps its blocks with the delay
+	engine.signer = accounts.address(noturn)
+	header := &types.Header{ParentHash: genesis.Hash(), Number: common.Big1}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	if want := genesis.Time + 8; header.Time != want {
+		t.Errorf("out-of-turn timestamp mismatch: have %d, want %d", header.Time, want)
+	}
+}
+
+// Tests that from the reward fork on, the block reward is credited to the sealer
+// of the block instead of the vote carrying coinbase.
+func TestSealerRewards(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, RewardBlock: big.NewInt(2), BlockReward: big.NewInt(1000)}
+		chain    = &testerHeaderChain{config: &params.ChainConfig{Clique: config}}
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	for number, want := range []int64{0, 0, 1000, 1000} {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		header := &types.Header{
+			Number:   big.NewInt(int64(number)),
+			Coinbase: accounts.address("B"),
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], nonceAuthVote)
+		accounts.sign(header, "A")
+
+		engine.Finalize(chain, header, statedb, nil, nil)
+		if have := statedb.GetBalance(accounts.address("A")); have.Cmp(big.NewInt(want)) != 0 {
+			t.Errorf("block %d: sealer balance mismatch: have %v, want %v", number, have, want)
+		}
+		if have := statedb.GetBalance(accounts.address("B")); have.Sign() != 0 {
+			t.Errorf("block %d: coinbase rewarded: %v", number, have)
+		}
+	}
+}
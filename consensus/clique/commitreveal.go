@@ -0,0 +1,226 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// nonceCommitVote is the magic nonce of a block committing to a membership vote
+// without disclosing it, the coinbase carrying the commitment digest.
+var nonceCommitVote = hexutil.MustDecode("0xfffffff500000000")
+
+const (
+	revealDropMarker = 0xc0 // First nonce byte of a block revealing a deauthorization vote
+	revealAuthMarker = 0xc1 // First nonce byte of a block revealing an authorization vote
+
+	revealSaltLength = 7 // Length of the salt following the reveal marker in the nonce
+)
+
+// errInvalidReveal is returned if a block reveals a membership vote that doesn't
+// match a commitment of its signer within the reveal window.
+var errInvalidReveal = errors.New("vote reveal without matching commitment")
+
+// Commitment is a membership vote committed to by a signer under commit-reveal
+// voting, awaiting the reveal of its contents.
+type Commitment struct {
+	Signer common.Address `json:"signer"` // Signer that committed to the vote
+	Block  uint64         `json:"block"`  // Block number the commitment was made in
+	Digest common.Address `json:"digest"` // Truncated hash of the committed vote
+}
+
+// revealSalt is the random salt blinding a committed vote.
+type revealSalt [revealSaltLength]byte
+
+// pendingReveal is a vote committed to by the local signer, retained until the
+// engine reveals it in a later block.
+type pendingReveal struct {
+	salt      revealSalt
+	authorize bool
+}
+
+// commitDigest calculates the commitment of a signer to a membership vote.
+func commitDigest(signer, address common.Address, authorize bool, salt revealSalt) common.Address {
+	var direction byte
+	if authorize {
+		direction = 1
+	}
+	return common.BytesToAddress(crypto.Keccak256(signer[:], address[:], []byte{direction}, salt[:])[:common.AddressLength])
+}
+
+// isCommitVote returns whether the header commits to a membership vote.
+func isCommitVote(header *types.Header) bool {
+	return bytes.Equal(header.Nonce[:], nonceCommitVote)
+}
+
+// isRevealVote returns whether the header reveals a committed membership vote.
+func isRevealVote(header *types.Header) bool {
+	return header.Nonce[0] == revealDropMarker || header.Nonce[0] == revealAuthMarker
+}
+
+// encodeReveal assembles the nonce revealing a committed membership vote.
+func encodeReveal(authorize bool, salt revealSalt) types.BlockNonce {
+	var nonce types.BlockNonce
+	if nonce[0] = revealDropMarker; authorize {
+		nonce[0] = revealAuthMarker
+	}
+	copy(nonce[1:], salt[:])
+	return nonce
+}
+
+// decodeReveal extracts the direction and salt of a revealed membership vote.
+func decodeReveal(header *types.Header) (bool, revealSalt) {
+	var salt revealSalt
+	copy(salt[:], header.Nonce[1:])
+	return header.Nonce[0] == revealAuthMarker, salt
+}
+
+// commitReveal returns whether deauthorization votes must be committed to before
+// they are revealed and counted.
+func (s *Snapshot) commitReveal() bool {
+	return s.config.RevealWindow > 0
+}
+
+// commitment retrieves the outstanding commitment of the given signer, if any.
+func (s *Snapshot) commitment(signer common.Address) *Commitment {
+	for _, commit := range s.Commits {
+		if commit.Signer == signer {
+			return commit
+		}
+	}
+	return nil
+}
+
+// commitVote records the commitment of a signer to a vote, replacing any earlier
+// commitment it didn't reveal.
+func (s *Snapshot) commitVote(number uint64, signer, digest common.Address) {
+	s.dropCommitment(signer)
+
+	// Commitments are shared between snapshot copies, never modify in place
+	s.Commits = append(append(make([]*Commitment, 0, len(s.Commits)+1), s.Commits...), &Commitment{
+		Signer: signer,
+		Block:  number,
+		Digest: digest,
+	})
+}
+
+// dropCommitment removes the commitment of the given signer, if any.
+func (s *Snapshot) dropCommitment(signer common.Address) {
+	for i, commit := range s.Commits {
+		if commit.Signer == signer {
+			commits := make([]*Commitment, 0, len(s.Commits)-1)
+			s.Commits = append(append(commits, s.Commits[:i]...), s.Commits[i+1:]...)
+			return
+		}
+	}
+}
+
+// revealVote checks a vote revealed by a signer in the given block against its
+// commitment, consuming the commitment if it matches.
+func (s *Snapshot) revealVote(number uint64, signer, address common.Address, authorize bool, salt revealSalt) error {
+	commit := s.commitment(signer)
+	if commit == nil || commit.Block >= number || commit.Block+s.config.RevealWindow < number {
+		return errInvalidReveal
+	}
+	if commit.Digest != commitDigest(signer, address, authorize, salt) {
+		return errInvalidReveal
+	}
+	s.dropCommitment(signer)
+	return nil
+}
+
+// expireCommits discards the commitments not revealed within the reveal window
+// ending at the given block.
+func (s *Snapshot) expireCommits(number uint64) {
+	for i := 0; i < len(s.Commits); i++ {
+		if commit := s.Commits[i]; commit.Block+s.config.RevealWindow < number {
+			s.dropCommitment(commit.Signer)
+			i--
+		}
+	}
+}
+
+// Commitments retrieves the votes committed to but not yet revealed by the
+// signers under commit-reveal voting.
+func (s *Snapshot) Commitments() []Commitment {
+	commits := make([]Commitment, len(s.Commits))
+	for i, commit := range s.Commits {
+		commits[i] = *commit
+	}
+	return commits
+}
+
+// castSealed sets the vote of the local signer on a membership proposal into the
+// header, committing to it first if the vote must not be disclosed right away.
+// The caller must hold the engine lock.
+func (c *Clique) castSealed(snap *Snapshot, header *types.Header, signer, address common.Address, authorize bool) {
+	if !snap.commitReveal() || authorize {
+		header.Coinbase = address
+		if authorize {
+			header.Nonce = snap.voteNonces().auth
+		} else {
+			header.Nonce = snap.voteNonces().drop
+		}
+		return
+	}
+	number := header.Number.Uint64()
+
+	// Reveal the vote if it was committed to in an earlier block and the reveal
+	// window is still open
+	pending, ok := c.reveals[address]
+	if ok && pending.authorize == authorize {
+		commit := snap.commitment(signer)
+		if commit != nil && commit.Block < number && commit.Block+snap.config.RevealWindow >= number && commit.Digest == commitDigest(signer, address, authorize, pending.salt) {
+			header.Coinbase, header.Nonce = address, encodeReveal(authorize, pending.salt)
+			return
+		}
+	} else {
+		// Otherwise commit to it, reusing the salt across the repeated preparations
+		// of the same block, so whichever gets sealed can be revealed
+		pending = pendingReveal{authorize: authorize}
+		if _, err := crand.Read(pending.salt[:]); err != nil {
+			return
+		}
+		c.reveals[address] = pending
+	}
+	header.Coinbase = commitDigest(signer, address, authorize, pending.salt)
+	copy(header.Nonce[:], nonceCommitVote)
+}
+
+// committedProposal returns the proposal the local signer already committed to
+// in the snapshot, if it is still among the ones worth voting on. Committed votes
+// are followed through to their reveal instead of switching proposals. The caller
+// must hold the engine lock.
+func (c *Clique) committedProposal(snap *Snapshot, signer common.Address, addresses []common.Address) (common.Address, bool) {
+	commit := snap.commitment(signer)
+	if commit == nil {
+		return common.Address{}, false
+	}
+	for _, address := range addresses {
+		if pending, ok := c.reveals[address]; ok && commit.Digest == commitDigest(signer, address, pending.authorize, pending.salt) {
+			return address, true
+		}
+	}
+	return common.Address{}, false
+}
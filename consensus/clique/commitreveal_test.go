@@ -0,0 +1,146 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that under commit-reveal voting deauthorization votes are only counted
+// once revealed, and that reveals not matching a live commitment are rejected.
+func TestCommitRevealVoting(t *testing.T) {
+	pool := newTesterAccountPool()
+	signers := []string{"A", "B", "C"}
+
+	addrs := make([]common.Address, len(signers))
+	for i, signer := range signers {
+		addrs[i] = pool.address(signer)
+	}
+	config := &params.CliqueConfig{Epoch: 30000, RevealWindow: 2}
+	genesis := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, addrs)
+
+	var (
+		target = pool.address("C")
+		salts  = map[string]revealSalt{"A": {0x0a}, "B": {0x0b}}
+	)
+	commit := func(number int64, signer string) *types.Header {
+		header := &types.Header{
+			Number:   big.NewInt(number),
+			Coinbase: commitDigest(pool.address(signer), target, false, salts[signer]),
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], nonceCommitVote)
+		pool.sign(header, signer)
+		return header
+	}
+	reveal := func(number int64, signer string, salt revealSalt) *types.Header {
+		header := &types.Header{
+			Number:   big.NewInt(number),
+			Coinbase: target,
+			Nonce:    encodeReveal(false, salt),
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		pool.sign(header, signer)
+		return header
+	}
+	// Committed votes should be recorded without being tallied
+	snap, err := genesis.apply([]*types.Header{commit(1, "A"), commit(2, "B")})
+	if err != nil {
+		t.Fatalf("failed to apply commitments: %v", err)
+	}
+	if len(snap.Commits) != 2 || len(snap.Votes) != 0 {
+		t.Fatalf("commit state mismatch: commitments %d, votes %d", len(snap.Commits), len(snap.Votes))
+	}
+	// Revealed votes should be tallied, dropping the signer once passing
+	revealed, err := snap.apply([]*types.Header{reveal(3, "A", salts["A"])})
+	if err != nil {
+		t.Fatalf("failed to apply first reveal: %v", err)
+	}
+	if tally := revealed.Tally[target]; tally.Votes != 1 || len(revealed.Commits) != 1 {
+		t.Errorf("reveal state mismatch: tally %d, commitments %d", tally.Votes, len(revealed.Commits))
+	}
+	if revealed, err = revealed.apply([]*types.Header{reveal(4, "B", salts["B"])}); err != nil {
+		t.Fatalf("failed to apply second reveal: %v", err)
+	}
+	if _, ok := revealed.Signers[target]; ok || len(revealed.Commits) != 0 {
+		t.Errorf("deauthorization not passed: signers %d, commitments %d", len(revealed.Signers), len(revealed.Commits))
+	}
+	// Reveals with a wrong salt or after the reveal window must be rejected
+	if _, err := snap.apply([]*types.Header{reveal(3, "A", salts["B"])}); err != errInvalidReveal {
+		t.Errorf("mismatching reveal error mismatch: have %v, want %v", err, errInvalidReveal)
+	}
+	late := &types.Header{Number: big.NewInt(3), Extra: make([]byte, extraVanity+extraSeal)}
+	pool.sign(late, "C")
+	expired, err := snap.apply([]*types.Header{late})
+	if err != nil {
+		t.Fatalf("failed to apply filler block: %v", err)
+	}
+	if _, err := expired.apply([]*types.Header{reveal(4, "A", salts["A"])}); err != errInvalidReveal {
+		t.Errorf("expired reveal error mismatch: have %v, want %v", err, errInvalidReveal)
+	}
+	// Plain deauthorization votes must not be counted
+	plain := &types.Header{Number: big.NewInt(1), Coinbase: target, Extra: make([]byte, extraVanity+extraSeal)}
+	pool.sign(plain, "A")
+	if snap, err = genesis.apply([]*types.Header{plain}); err != nil {
+		t.Fatalf("failed to apply plain vote: %v", err)
+	}
+	if len(snap.Votes) != 0 {
+		t.Errorf("plain deauthorization vote counted")
+	}
+}
+
+// Tests that the local signer commits to its deauthorization votes first and
+// reveals them in a later block.
+func TestCommitRevealSealing(t *testing.T) {
+	var (
+		signer = common.Address{0x0a}
+		target = common.Address{0x0c}
+	)
+	config := &params.CliqueConfig{Epoch: 30000, RevealWindow: 2}
+	engine := New(config, rawdb.NewMemoryDatabase())
+	snap := newSnapshot(config, nil, 0, common.Hash{}, []common.Address{signer, {0x0b}, target})
+
+	// The first block should carry the commitment, consistently across preparations
+	first := &types.Header{Number: big.NewInt(1)}
+	engine.castSealed(snap, first, signer, target, false)
+	if !isCommitVote(first) {
+		t.Fatalf("first vote not committed: nonce %x", first.Nonce)
+	}
+	again := &types.Header{Number: big.NewInt(1)}
+	engine.castSealed(snap, again, signer, target, false)
+	if again.Coinbase != first.Coinbase {
+		t.Errorf("commitment changed across preparations: have %x, want %x", again.Coinbase, first.Coinbase)
+	}
+	snap.commitVote(1, signer, first.Coinbase)
+
+	// A later block should reveal the vote matching the commitment
+	second := &types.Header{Number: big.NewInt(2)}
+	engine.castSealed(snap, second, signer, target, false)
+	if !isRevealVote(second) || second.Coinbase != target {
+		t.Fatalf("second vote not revealed: nonce %x, coinbase %x", second.Nonce, second.Coinbase)
+	}
+	authorize, salt := decodeReveal(second)
+	if err := snap.revealVote(2, signer, target, authorize, salt); err != nil {
+		t.Errorf("failed to reveal sealed vote: %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+// trackedReplay is the number of headers a voting history replay must span to
+// be reported as a reconstruction in progress.
+const trackedReplay = 1024
+
+// reconstructionTracker keeps track of the lengthy voting history replays the
+// engine is running, so monitoring tools can follow snapshot regenerations.
+type reconstructionTracker struct {
+	replays map[uint64]*Reconstruction // Replays in flight, keyed by an identifier
+	nonce   uint64                     // Last identifier handed out to a replay
+	lock    sync.Mutex
+}
+
+// Reconstruction is a voting history replay in progress.
+type Reconstruction struct {
+	Origin  uint64 `json:"origin"`  // Number of the snapshot the replay starts from
+	Target  uint64 `json:"target"`  // Number of the block the snapshot is reconstructed at
+	Elapsed uint64 `json:"elapsed"` // Seconds since the replay started
+
+	started mclock.AbsTime
+}
+
+// start registers a replay of the voting history between the given blocks if it
+// is long enough to be tracked, returning the function to call once it's done.
+func (t *reconstructionTracker) start(origin, target uint64) func() {
+	if target-origin < trackedReplay {
+		return func() {}
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.replays == nil {
+		t.replays = make(map[uint64]*Reconstruction)
+	}
+	t.nonce++
+	id := t.nonce
+	t.replays[id] = &Reconstruction{Origin: origin, Target: target, started: mclock.Now()}
+
+	return func() {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+
+		delete(t.replays, id)
+	}
+}
+
+// running returns the replays currently in flight.
+func (t *reconstructionTracker) running() []Reconstruction {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	replays := make([]Reconstruction, 0, len(t.replays))
+	for _, replay := range t.replays {
+		entry := *replay
+		entry.Elapsed = uint64(time.Duration(mclock.Now() - replay.started).Seconds())
+		replays = append(replays, entry)
+	}
+	return replays
+}
+
+// dashboardSigner is the state and recent activity of a single signer.
+type dashboardSigner struct {
+	Address  common.Address  `json:"address"`            // Address of the signer
+	Stats    SealStats       `json:"stats"`              // Blocks sealed and slots missed within the recent blocks
+	Recent   bool            `json:"recent"`             // Whether the signer is barred from sealing the next block
+	Eligible uint64          `json:"eligible"`           // First block the signer may seal (next block unless recent)
+	Metadata *SignerMetadata `json:"metadata,omitempty"` // Registered operator metadata of the signer
+}
+
+// dashboardProposals is the governance state pending in the snapshot, along with
+// the proposals the local signer is pushing.
+type dashboardProposals struct {
+	Tally       map[common.Address]Tally `json:"tally"`       // Running authorization vote tallies
+	LimitTally  map[uint]LimitTally      `json:"limitTally"`  // Running signer limit vote tallies
+	PermitTally map[common.Address]Tally `json:"permitTally"` // Running sender permission vote tallies
+
+	Local       map[common.Address]bool `json:"local"`       // Authorization proposals the local signer votes on
+	LocalLimits map[uint]bool           `json:"localLimits"` // Signer limit proposals the local signer votes on
+	LocalPermit map[common.Address]bool `json:"localPermit"` // Sender permission proposals the local signer votes on
+}
+
+// dashboardLimit is the signer limit state of the snapshot.
+type dashboardLimit struct {
+	Limit     uint                 `json:"limit"`     // Signer limit currently in force
+	Absolute  bool                 `json:"absolute"`  // Whether the limit is a signer count instead of a percentage
+	Threshold uint                 `json:"threshold"` // Number of votes needed for a proposal to pass
+	Affirmed  uint64               `json:"affirmed"`  // Block number the limit was last set or reaffirmed in
+	Waits     map[uint64]WaitTally `json:"waits"`     // Cooldowns of the recently passed signer limits
+}
+
+// dashboardSync is the progress of the local chain towards the network head.
+type dashboardSync struct {
+	Lag             uint64           `json:"lag"`             // Seconds the local head is behind the wall clock
+	Behind          uint64           `json:"behind"`          // Estimated number of blocks the local head is behind
+	Reconstructions []Reconstruction `json:"reconstructions"` // Voting history replays in progress
+	Reconstructing  bool             `json:"reconstructing"`  // Whether any voting history is being replayed
+}
+
+// dashboard is everything a monitoring interface needs to render the state of a
+// clique network, gathered in a single call.
+type dashboard struct {
+	Number    uint64             `json:"number"`    // Number of the head the dashboard is reported at
+	Hash      common.Hash        `json:"hash"`      // Hash of the head the dashboard is reported at
+	Time      uint64             `json:"time"`      // Timestamp of the head the dashboard is reported at
+	Signers   []dashboardSigner  `json:"signers"`   // Authorized signers in ascending order
+	Proposals dashboardProposals `json:"proposals"` // Pending governance proposals
+	Limit     dashboardLimit     `json:"limit"`     // Signer limit state
+	Recents   *recentSigners     `json:"recents"`   // Spam protection state
+	Sync      dashboardSync      `json:"sync"`      // Sync and reconstruction progress
+}
+
+// Dashboard retrieves the signer set, per-signer activity, pending proposals,
+// signer limit state, recent signers and sync progress at the current head, to
+// spare monitoring interfaces a dozen round trips per refresh.
+func (api *API) Dashboard() (*dashboard, error) {
+	header := api.chain.CurrentHeader()
+	snap, err := api.clique.snapshotFor(triggerRPC, api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signers  = snap.signers()
+		stats    = api.clique.seals.Stats()
+		metadata = api.clique.signerMetadata(signers)
+		eligible = snap.EligibleBlocks()
+	)
+	board := &dashboard{
+		Number:  snap.Number,
+		Hash:    snap.Hash,
+		Time:    header.Time,
+		Signers: make([]dashboardSigner, 0, len(signers)),
+		Proposals: dashboardProposals{
+			Tally:       make(map[common.Address]Tally, len(snap.Tally)),
+			LimitTally:  snap.LimitTallies(),
+			PermitTally: make(map[common.Address]Tally, len(snap.PermitTally)),
+		},
+		Limit: dashboardLimit{
+			Limit:     snap.Limit(),
+			Absolute:  snap.config.AbsoluteSignerLimit,
+			Threshold: snap.Threshold(),
+			Affirmed:  snap.SignerLimitAffirmed,
+			Waits:     snap.LimitWaits(),
+		},
+		Recents: &recentSigners{
+			Number:   snap.Number,
+			Hash:     snap.Hash,
+			Window:   snap.recentsWindow(snap.Number + 1),
+			Recents:  snap.RecentSigners(),
+			Eligible: eligible,
+		},
+	}
+	for _, signer := range signers {
+		entry := dashboardSigner{
+			Address:  signer,
+			Stats:    stats[signer],
+			Eligible: snap.Number + 1,
+		}
+		if next, ok := eligible[signer]; ok && next > entry.Eligible {
+			entry.Recent, entry.Eligible = true, next
+		}
+		if meta, ok := metadata[signer]; ok {
+			entry.Metadata = &meta
+		}
+		board.Signers = append(board.Signers, entry)
+	}
+	for address, tally := range snap.Tally {
+		board.Proposals.Tally[address] = tally
+	}
+	for address, tally := range snap.PermitTally {
+		board.Proposals.PermitTally[address] = tally
+	}
+	api.clique.lock.RLock()
+	board.Proposals.Local = make(map[common.Address]bool, len(api.clique.proposals))
+	for address, auth := range api.clique.proposals {
+		board.Proposals.Local[address] = auth
+	}
+	board.Proposals.LocalLimits = make(map[uint]bool, len(api.clique.signerLimitProposals))
+	for limit, auth := range api.clique.signerLimitProposals {
+		board.Proposals.LocalLimits[limit] = auth
+	}
+	board.Proposals.LocalPermit = make(map[common.Address]bool, len(api.clique.permitProposals))
+	for address, permit := range api.clique.permitProposals {
+		board.Proposals.LocalPermit[address] = permit
+	}
+	api.clique.lock.RUnlock()
+
+	// Estimate how far the local head is behind the network
+	if now := uint64(time.Now().Unix()); now > header.Time {
+		board.Sync.Lag = now - header.Time
+		if period := api.clique.config.Period; period > 0 {
+			board.Sync.Behind = board.Sync.Lag / period
+		}
+	}
+	board.Sync.Reconstructions = api.clique.replays.running()
+	board.Sync.Reconstructing = len(board.Sync.Reconstructions) > 0
+
+	return board, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the dashboard gathers the signer set, their activity, the pending
+// proposals and the recent signers of the current head.
+func TestDashboard(t *testing.T) {
+	accounts := newTesterAccountPool()
+	signers := []string{"A", "B", "C"}
+	config := &params.CliqueConfig{Period: 1, Epoch: 30000}
+
+	genesis := &types.Header{
+		Number: big.NewInt(0),
+		Extra:  make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	// Have A seal a block voting D in
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     big.NewInt(1),
+		Time:       1,
+		Coinbase:   accounts.address("D"),
+		Difficulty: diffNoTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	copy(header.Nonce[:], nonceAuthVote)
+	accounts.sign(header, "A")
+	chain.headers = append(chain.headers, header)
+
+	api := &API{chain: chain, clique: New(config, rawdb.NewMemoryDatabase())}
+	proposeAccount(api, accounts.address("E"), true)
+
+	board, err := api.Dashboard()
+	if err != nil {
+		t.Fatalf("failed to retrieve dashboard: %v", err)
+	}
+	if board.Number != 1 || board.Hash != header.Hash() || len(board.Signers) != len(signers) {
+		t.Fatalf("dashboard head mismatch: have #%d [%x] with %d signers", board.Number, board.Hash, len(board.Signers))
+	}
+	for _, signer := range board.Signers {
+		if recent := signer.Address == accounts.address("A"); signer.Recent != recent {
+			t.Errorf("signer %x recency mismatch: have %v, want %v", signer.Address, signer.Recent, recent)
+		}
+	}
+	if tally, ok := board.Proposals.Tally[accounts.address("D")]; !ok || tally.Votes != 1 {
+		t.Errorf("pending tally mismatch: have %+v", board.Proposals.Tally)
+	}
+	if auth, ok := board.Proposals.Local[accounts.address("E")]; !ok || !auth {
+		t.Errorf("local proposals mismatch: have %v", board.Proposals.Local)
+	}
+	if board.Recents.Recents[1] != accounts.address("A") {
+		t.Errorf("recents mismatch: have %v", board.Recents.Recents)
+	}
+	if board.Sync.Reconstructing {
+		t.Errorf("reconstruction reported without a replay running")
+	}
+}
+
+// Tests that only lengthy voting history replays are reported as in progress,
+// and only while they are running.
+func TestReconstructionTracking(t *testing.T) {
+	var tracker reconstructionTracker
+
+	short := tracker.start(0, trackedReplay-1)
+	if replays := tracker.running(); len(replays) != 0 {
+		t.Errorf("short replay tracked: %v", replays)
+	}
+	long := tracker.start(10, 10+trackedReplay)
+	if replays := tracker.running(); len(replays) != 1 || replays[0].Origin != 10 || replays[0].Target != 10+trackedReplay {
+		t.Errorf("long replay mismatch: %v", replays)
+	}
+	short()
+	long()
+	if replays := tracker.running(); len(replays) != 0 {
+		t.Errorf("finished replay still tracked: %v", replays)
+	}
+}
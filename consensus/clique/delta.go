@@ -0,0 +1,314 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// snapshotCompaction is the maximum number of consecutive deltas persisted on
+// top of a full snapshot before the next checkpoint is compacted into a full one.
+const snapshotCompaction = 16
+
+var deltaPrefix = []byte("clique-delta-") // deltaPrefix + hash -> snapshot delta
+
+// snapshotDelta is the difference between a persisted snapshot and the previous
+// persisted snapshot it was derived from. Large, slowly changing containers (the
+// signer set, recents, tally and permitted senders) are recorded as changes,
+// whereas the vote lists and the signer limit state, which are small and reset
+// every epoch, are stored in full.
+type snapshotDelta struct {
+	Parent common.Hash `json:"parent"` // Hash of the persisted snapshot this delta applies on top of
+	Depth  int         `json:"depth"`  // Number of deltas between this one and the last full snapshot
+
+	Number uint64      `json:"number"` // Block number where the snapshot was created
+	Hash   common.Hash `json:"hash"`   // Block hash where the snapshot was created
+
+	SignersAdded   []common.Address          `json:"signersAdded,omitempty"`
+	SignersRemoved []common.Address          `json:"signersRemoved,omitempty"`
+	RecentsSet     map[uint64]common.Address `json:"recentsSet,omitempty"`
+	RecentsDropped []uint64                  `json:"recentsDropped,omitempty"`
+	TallySet       map[common.Address]Tally  `json:"tallySet,omitempty"`
+	TallyDropped   []common.Address          `json:"tallyDropped,omitempty"`
+	Votes          []*Vote                   `json:"votes"`
+
+	SignerLimit      uint                 `json:"limit"`
+	SignerLimitVotes []*LimitVote         `json:"signerLimitVotes"`
+	SignerLimitTally map[uint]LimitTally  `json:"signerLimitTally"`
+	SignerLimitWait  map[uint64]WaitTally `json:"waitTally"`
+
+	SignerLimitAffirmed uint64 `json:"limitAffirmed,omitempty"`
+	EpochChanges        uint   `json:"epochChanges,omitempty"`
+
+	PermittedAdded   []common.Address         `json:"permittedAdded,omitempty"`
+	PermittedRemoved []common.Address         `json:"permittedRemoved,omitempty"`
+	PermitVotes      []*Vote                  `json:"permitVotes,omitempty"`
+	PermitTally      map[common.Address]Tally `json:"permitTally,omitempty"`
+
+	JustifyTarget   uint64           `json:"justifyTarget,omitempty"`
+	JustifyHash     common.Hash      `json:"justifyHash,omitempty"`
+	Justifiers      []common.Address `json:"justifiers,omitempty"`
+	FinalizedNumber uint64           `json:"finalizedNumber,omitempty"`
+	FinalizedHash   common.Hash      `json:"finalizedHash,omitempty"`
+
+	Openings []*Opening    `json:"openings,omitempty"`
+	Commits  []*Commitment `json:"commits,omitempty"`
+
+	Periods     []*SignerPeriod `json:"periods,omitempty"`
+	PeriodVotes []*PeriodVote   `json:"periodVotes,omitempty"`
+
+	Probations []*Probation `json:"probations,omitempty"`
+
+	Replacements []*ReplaceVote `json:"replacements,omitempty"`
+
+	AttestTarget    uint64           `json:"attestTarget,omitempty"`
+	AttestHash      common.Hash      `json:"attestHash,omitempty"`
+	AttestSigners   []common.Address `json:"attestSigners,omitempty"`
+	Attestations    []*Attestation   `json:"attestations,omitempty"`
+	AttestSignature hexutil.Bytes    `json:"attestSignature,omitempty"`
+}
+
+// diff calculates the delta needed to get from the base snapshot to this one.
+func (s *Snapshot) diff(base *Snapshot) *snapshotDelta {
+	delta := &snapshotDelta{
+		Parent:           base.Hash,
+		Depth:            base.deltas + 1,
+		Number:           s.Number,
+		Hash:             s.Hash,
+		RecentsSet:       make(map[uint64]common.Address),
+		TallySet:         make(map[common.Address]Tally),
+		Votes:            s.Votes,
+		SignerLimit:      s.SignerLimit,
+		SignerLimitVotes: s.SignerLimitVotes,
+		SignerLimitTally: s.SignerLimitTally,
+		SignerLimitWait:  s.SignerLimitWait,
+
+		SignerLimitAffirmed: s.SignerLimitAffirmed,
+		EpochChanges:        s.EpochChanges,
+
+		PermitVotes: s.PermitVotes,
+		PermitTally: s.PermitTally,
+
+		JustifyTarget:   s.JustifyTarget,
+		JustifyHash:     s.JustifyHash,
+		Justifiers:      s.Justifiers,
+		FinalizedNumber: s.FinalizedNumber,
+		FinalizedHash:   s.FinalizedHash,
+
+		Openings: s.Openings,
+		Commits:  s.Commits,
+
+		Periods:     s.Periods,
+		PeriodVotes: s.PeriodVotes,
+
+		Probations: s.Probations,
+
+		Replacements: s.Replacements,
+
+		AttestTarget:    s.AttestTarget,
+		AttestHash:      s.AttestHash,
+		AttestSigners:   s.AttestSigners,
+		Attestations:    s.Attestations,
+		AttestSignature: s.AttestSignature,
+	}
+	for signer := range s.Signers {
+		if _, ok := base.Signers[signer]; !ok {
+			delta.SignersAdded = append(delta.SignersAdded, signer)
+		}
+	}
+	for signer := range base.Signers {
+		if _, ok := s.Signers[signer]; !ok {
+			delta.SignersRemoved = append(delta.SignersRemoved, signer)
+		}
+	}
+	for number, signer := range s.Recents {
+		if old, ok := base.Recents[number]; !ok || old != signer {
+			delta.RecentsSet[number] = signer
+		}
+	}
+	for number := range base.Recents {
+		if _, ok := s.Recents[number]; !ok {
+			delta.RecentsDropped = append(delta.RecentsDropped, number)
+		}
+	}
+	for address := range s.Permitted {
+		if _, ok := base.Permitted[address]; !ok {
+			delta.PermittedAdded = append(delta.PermittedAdded, address)
+		}
+	}
+	for address := range base.Permitted {
+		if _, ok := s.Permitted[address]; !ok {
+			delta.PermittedRemoved = append(delta.PermittedRemoved, address)
+		}
+	}
+	for address, tally := range s.Tally {
+		if old, ok := base.Tally[address]; !ok || old != tally {
+			delta.TallySet[address] = tally
+		}
+	}
+	for address := range base.Tally {
+		if _, ok := s.Tally[address]; !ok {
+			delta.TallyDropped = append(delta.TallyDropped, address)
+		}
+	}
+	// Order the changes gathered from the maps, so the delta encodes canonically
+	sort.Sort(signersAscending(delta.SignersAdded))
+	sort.Sort(signersAscending(delta.SignersRemoved))
+	sort.Slice(delta.RecentsDropped, func(i, j int) bool { return delta.RecentsDropped[i] < delta.RecentsDropped[j] })
+	sort.Sort(signersAscending(delta.TallyDropped))
+	sort.Sort(signersAscending(delta.PermittedAdded))
+	sort.Sort(signersAscending(delta.PermittedRemoved))
+	return delta
+}
+
+// MarshalCanonical encodes the delta into the same canonical JSON form as full
+// snapshots, so persisted deltas are identical across nodes too.
+func (d *snapshotDelta) MarshalCanonical() ([]byte, error) {
+	// Allocate the missing maps on a shallow copy, the delta shares the snapshot's
+	normal := *d
+	if normal.SignerLimitTally == nil {
+		normal.SignerLimitTally = make(map[uint]LimitTally)
+	}
+	if normal.SignerLimitWait == nil {
+		normal.SignerLimitWait = make(map[uint64]WaitTally)
+	}
+	blob, err := json.Marshal(&normal)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalJSON(blob)
+}
+
+// patch creates a new snapshot by applying the delta on top of the base one.
+func (s *Snapshot) patch(delta *snapshotDelta) *Snapshot {
+	snap := s.copy()
+
+	snap.Number, snap.Hash = delta.Number, delta.Hash
+	for _, signer := range delta.SignersAdded {
+		snap.addSigner(signer)
+	}
+	for _, signer := range delta.SignersRemoved {
+		snap.removeSigner(signer)
+	}
+	if len(delta.RecentsSet) > 0 || len(delta.RecentsDropped) > 0 {
+		snap.writable(cowRecents)
+		for number, signer := range delta.RecentsSet {
+			snap.Recents[number] = signer
+		}
+		for _, number := range delta.RecentsDropped {
+			delete(snap.Recents, number)
+		}
+	}
+	if len(delta.TallySet) > 0 || len(delta.TallyDropped) > 0 {
+		snap.writable(cowTally)
+		for address, tally := range delta.TallySet {
+			snap.Tally[address] = tally
+		}
+		for _, address := range delta.TallyDropped {
+			delete(snap.Tally, address)
+		}
+	}
+	if len(delta.PermittedAdded) > 0 || len(delta.PermittedRemoved) > 0 {
+		snap.writable(cowPermitted)
+		for _, address := range delta.PermittedAdded {
+			snap.Permitted[address] = struct{}{}
+		}
+		for _, address := range delta.PermittedRemoved {
+			delete(snap.Permitted, address)
+		}
+	}
+	snap.Votes = delta.Votes
+	snap.SignerLimit = delta.SignerLimit
+	snap.SignerLimitAffirmed = delta.SignerLimitAffirmed
+	snap.EpochChanges = delta.EpochChanges
+	snap.SignerLimitVotes = delta.SignerLimitVotes
+	snap.SignerLimitTally = delta.SignerLimitTally
+	if snap.SignerLimitTally == nil {
+		snap.SignerLimitTally = make(map[uint]LimitTally)
+	}
+	snap.SignerLimitWait = delta.SignerLimitWait
+	if snap.SignerLimitWait == nil {
+		snap.SignerLimitWait = make(map[uint64]WaitTally)
+	}
+	snap.JustifyTarget, snap.JustifyHash, snap.Justifiers = delta.JustifyTarget, delta.JustifyHash, delta.Justifiers
+	snap.FinalizedNumber, snap.FinalizedHash = delta.FinalizedNumber, delta.FinalizedHash
+	snap.Openings = delta.Openings
+	snap.Commits = delta.Commits
+	snap.Periods, snap.PeriodVotes = delta.Periods, delta.PeriodVotes
+	snap.Probations = delta.Probations
+	snap.Replacements = delta.Replacements
+	snap.AttestTarget, snap.AttestHash = delta.AttestTarget, delta.AttestHash
+	snap.AttestSigners, snap.Attestations, snap.AttestSignature = delta.AttestSigners, delta.Attestations, delta.AttestSignature
+	snap.PermitVotes = delta.PermitVotes
+	snap.PermitTally = delta.PermitTally
+	if snap.PermitTally == nil {
+		snap.PermitTally = make(map[common.Address]Tally)
+	}
+	snap.owned |= cowVotes | cowLimitVotes | cowLimitTally | cowLimitWait | cowPermitVotes | cowPermitTally
+
+	snap.base, snap.deltas = snap, delta.Depth
+	return snap
+}
+
+// persist writes the snapshot into the database. If the snapshot descends from a
+// previously persisted one, only the delta between the two is written, unless
+// too many deltas piled up already, in which case a full snapshot is written to
+// compact them.
+//
+// Persisting marks the snapshot as the base of its future descendants, so it
+// must be done before the snapshot is shared.
+func (s *Snapshot) persist(db ethdb.Database) error {
+	if s.base == nil || s.base.deltas+1 >= snapshotCompaction {
+		if err := s.store(db); err != nil {
+			return err
+		}
+		s.base, s.deltas = s, 0
+		return nil
+	}
+	blob, err := s.diff(s.base).MarshalCanonical()
+	if err != nil {
+		return err
+	}
+	if err := db.Put(append(append([]byte{}, deltaPrefix...), s.Hash[:]...), blob); err != nil {
+		return err
+	}
+	if err := indexSnapshot(db, s.Number, s.Hash); err != nil {
+		return err
+	}
+	s.base, s.deltas = s, s.base.deltas+1
+	return nil
+}
+
+// loadDelta retrieves a snapshot delta from the database.
+func loadDelta(db ethdb.Database, hash common.Hash) (*snapshotDelta, error) {
+	blob, err := db.Get(append(append([]byte{}, deltaPrefix...), hash[:]...))
+	if err != nil {
+		return nil, err
+	}
+	delta := new(snapshotDelta)
+	if err := json.Unmarshal(blob, delta); err != nil {
+		log.Warn("Failed to decode voting snapshot delta", "hash", hash, "err", err)
+		return nil, errCorruptSnapshot
+	}
+	return delta, nil
+}
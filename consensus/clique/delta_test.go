@@ -0,0 +1,132 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that snapshots persisted as deltas on top of each other can be loaded
+// back exactly, across compaction boundaries.
+func TestSnapshotDeltaPersistence(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{Epoch: 30000}
+		snap   = newSnapshot(config, nil, 0, common.Hash{}, []common.Address{{0x01}, {0x02}, {0x03}})
+	)
+	if err := snap.persist(db); err != nil {
+		t.Fatalf("failed to persist genesis snapshot: %v", err)
+	}
+	var persisted []*Snapshot
+	for i := 1; i <= 2*snapshotCompaction+3; i++ {
+		next := snap.copy()
+		next.Number, next.Hash = uint64(i), common.Hash{byte(i), 0xff}
+
+		switch i % 4 {
+		case 0:
+			next.addSigner(common.Address{byte(i), 0xaa})
+		case 1:
+			next.removeSigner(next.signers()[0])
+		case 2:
+			next.cast(common.Address{byte(i), 0xbb}, true)
+			next.writable(cowVotes)
+			next.Votes = append(next.Votes, &Vote{Signer: next.signers()[0], Block: uint64(i), Address: common.Address{byte(i), 0xbb}, Authorize: true})
+		case 3:
+			next.SignerLimit = uint(40 + i)
+			next.writable(cowLimitWait)
+			next.SignerLimitWait[uint64(i)] = WaitTally{Block: uint64(i + 3)}
+		}
+		next.writable(cowRecents)
+		next.Recents[uint64(i)] = next.signers()[0]
+		delete(next.Recents, uint64(i-2))
+
+		if err := next.persist(db); err != nil {
+			t.Fatalf("snapshot %d: failed to persist: %v", i, err)
+		}
+		if want := i % snapshotCompaction; next.deltas != want {
+			t.Errorf("snapshot %d: delta depth mismatch: have %d, want %d", i, next.deltas, want)
+		}
+		persisted = append(persisted, next)
+		snap = next
+	}
+	for _, want := range persisted {
+		have, err := loadSnapshot(config, nil, db, want.Hash)
+		if err != nil {
+			t.Fatalf("snapshot %d: failed to load: %v", want.Number, err)
+		}
+		haveBlob, _ := json.Marshal(have)
+		wantBlob, _ := json.Marshal(want)
+		if !bytes.Equal(haveBlob, wantBlob) {
+			t.Errorf("snapshot %d: content mismatch:\nhave %s\nwant %s", want.Number, haveBlob, wantBlob)
+		}
+		if have.deltas != want.deltas {
+			t.Errorf("snapshot %d: delta depth mismatch: have %d, want %d", want.Number, have.deltas, want.deltas)
+		}
+	}
+}
+
+// Tests that snapshot deltas are persisted in their canonical encoding, which is
+// reproduced byte for byte after loading them back.
+func TestSnapshotDeltaCanonical(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{Epoch: 30000}
+		snap   = newSnapshot(config, nil, 0, common.Hash{}, []common.Address{{0x01}, {0x02}, {0x03}})
+	)
+	if err := snap.persist(db); err != nil {
+		t.Fatalf("failed to persist genesis snapshot: %v", err)
+	}
+	next := snap.copy()
+	next.Number, next.Hash = 1, common.Hash{0x01, 0xff}
+	for i := 0; i < 32; i++ {
+		next.addSigner(common.Address{0xaa, byte(i)})
+	}
+	next.removeSigner(common.Address{0x02})
+	next.writable(cowRecents)
+	next.Recents[1] = common.Address{0x01}
+
+	if err := next.persist(db); err != nil {
+		t.Fatalf("failed to persist delta: %v", err)
+	}
+	stored, err := db.Get(append(append([]byte{}, deltaPrefix...), next.Hash[:]...))
+	if err != nil {
+		t.Fatalf("failed to retrieve delta: %v", err)
+	}
+	delta, err := loadDelta(db, next.Hash)
+	if err != nil {
+		t.Fatalf("failed to load delta: %v", err)
+	}
+	blob, err := delta.MarshalCanonical()
+	if err != nil {
+		t.Fatalf("failed to encode delta: %v", err)
+	}
+	if !bytes.Equal(blob, stored) {
+		t.Errorf("delta encoding mismatch:\nhave %s\nwant %s", blob, stored)
+	}
+	// Recalculating the delta must yield the same bytes regardless of map order
+	for i := 0; i < 8; i++ {
+		if blob, _ := next.diff(snap).MarshalCanonical(); !bytes.Equal(blob, stored) {
+			t.Fatalf("delta encoding not deterministic:\nhave %s\nwant %s", blob, stored)
+		}
+	}
+}
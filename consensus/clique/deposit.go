@@ -0,0 +1,167 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// depositsSlot is the storage slot of the deposit mapping of the deposit contract.
+// Its storage must follow the Solidity layout of a contract declaring:
+//
+//	mapping(address => uint256) deposits; // slot 0, wei locked per candidate
+var depositsSlot = common.BigToHash(big.NewInt(0))
+
+// errUndepositedCandidate is returned if a block votes on authorizing a candidate
+// that didn't lock the minimum deposit in the deposit contract.
+var errUndepositedCandidate = errors.New("candidate without deposit")
+
+// StateReader retrieves the state of the chain after the block with the given hash.
+type StateReader func(hash common.Hash) (*state.StateDB, error)
+
+// depositReader gives the sealer access to the chain state to skip the votes on
+// candidates without deposits. Votes of remote blocks are checked against the
+// state they are processed on instead, so snapshots never need the state.
+type depositReader struct {
+	fn   StateReader
+	lock sync.RWMutex
+}
+
+// depositSlot returns the storage slot of a candidate in the deposit mapping.
+func depositSlot(candidate common.Address) common.Hash {
+	return crypto.Keccak256Hash(common.LeftPadBytes(candidate[:], 32), depositsSlot[:])
+}
+
+// SetStateReader sets the function to retrieve the chain state with, needed to
+// skip voting on candidates without deposits once the deposit fork is active.
+func (c *Clique) SetStateReader(fn func(hash common.Hash) (*state.StateDB, error)) {
+	c.deposits.lock.Lock()
+	defer c.deposits.lock.Unlock()
+
+	c.deposits.fn = fn
+}
+
+// state retrieves the chain state after the block with the given hash, or nil if
+// it's not accessible (e.g. pruned).
+func (r *depositReader) state(hash common.Hash) *state.StateDB {
+	r.lock.RLock()
+	fn := r.fn
+	r.lock.RUnlock()
+
+	if fn == nil {
+		return nil
+	}
+	statedb, err := fn(hash)
+	if err != nil {
+		return nil
+	}
+	return statedb
+}
+
+// deposited returns whether an account locked at least the minimum deposit in the
+// deposit contract as of the given state.
+func deposited(config *params.CliqueConfig, statedb *state.StateDB, candidate common.Address) bool {
+	deposit := statedb.GetState(config.DepositContract, depositSlot(candidate)).Big()
+	return config.MinDeposit == nil || deposit.Cmp(config.MinDeposit) >= 0
+}
+
+// eligible returns whether an authorization vote on a candidate may be cast in the
+// block with the given number on top of the snapshot, requiring the candidate to
+// have locked the configured deposit as of the parent state once the deposit fork
+// is active. Without the state, candidates are deemed ineligible.
+func (s *Snapshot) eligible(number *big.Int, statedb *state.StateDB, candidate common.Address) bool {
+	if !s.config.IsDeposit(number) {
+		return true
+	}
+	if _, ok := s.Signers[candidate]; ok {
+		return true
+	}
+	return statedb != nil && deposited(s.config, statedb, candidate)
+}
+
+// authorizedCandidate returns the candidate a header votes on authorizing, be it
+// through a plain or revealed vote or a replacement, if any.
+func (s *Snapshot) authorizedCandidate(header *types.Header) (common.Address, bool) {
+	switch {
+	case header.Nonce == s.voteNonces().auth:
+		return header.Coinbase, true
+	case isRevealVote(header) && s.commitReveal():
+		if authorize, _ := decodeReveal(header); authorize {
+			return header.Coinbase, true
+		}
+	case isReplaceVote(header):
+		return header.Coinbase, true
+	}
+	return common.Address{}, false
+}
+
+// eligibleGovernanceVotes drops the governance votes cast in the block with the
+// given number that authorize candidates without deposits.
+func (s *Snapshot) eligibleGovernanceVotes(number *big.Int, statedb *state.StateDB, votes []*GovernanceVote) []*GovernanceVote {
+	if !s.config.IsDeposit(number) {
+		return votes
+	}
+	eligible := make([]*GovernanceVote, 0, len(votes))
+	for _, vote := range votes {
+		if vote.Kind == ProposalAuthorize && !s.eligible(number, statedb, vote.Target) {
+			continue
+		}
+		eligible = append(eligible, vote)
+	}
+	return eligible
+}
+
+// VerifyState implements consensus.StateVerifier, checking the candidates a block
+// votes on authorizing against the deposits locked as of its parent state. Header
+// votes on candidates without deposits are rejected, whereas governance votes on
+// them must have been left out of the votes embedded into the header. Snapshots
+// thus tally the header alone, without ever needing the state.
+func (c *Clique) VerifyState(chain consensus.ChainHeaderReader, block *types.Block, statedb *state.StateDB) error {
+	header := block.Header()
+	if !c.config.IsDeposit(header.Number) {
+		return nil
+	}
+	snap, err := c.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if candidate, ok := snap.authorizedCandidate(header); ok && !snap.eligible(header.Number, statedb, candidate) {
+		return errUndepositedCandidate
+	}
+	if !governanceEnabled(c.config, header.Number) {
+		return nil
+	}
+	votes, err := governanceVotes(chain.Config(), header.Number, block.Transactions())
+	if err != nil {
+		return err
+	}
+	votes = snap.eligibleGovernanceVotes(header.Number, statedb, votes)
+	if payload, _ := splitJustification(c.config, header); !bytes.Equal(payload, encodeGovernanceVotes(votes)) {
+		return errInvalidGovernanceVotes
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that authorization votes on candidates that didn't lock the minimum
+// deposit are rejected against the parent state, while snapshots tally the
+// headers without ever needing the state.
+func TestSignerDeposits(t *testing.T) {
+	accounts := newTesterAccountPool()
+	contract := common.Address{0xde, 0x90}
+	config := &params.CliqueConfig{Epoch: 30000, DepositBlock: big.NewInt(3), DepositContract: contract, MinDeposit: big.NewInt(100)}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetState(contract, depositSlot(accounts.address("C")), common.BigToHash(big.NewInt(100)))
+	statedb.SetState(contract, depositSlot(accounts.address("D")), common.BigToHash(big.NewInt(99)))
+
+	var (
+		chain  = newTesterSignedChain(accounts, config, []string{"A", "B"}, 2)
+		engine = New(config, rawdb.NewMemoryDatabase())
+	)
+	vote := func(parent *types.Header, signer, add string) *types.Block {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Coinbase:   accounts.address(add),
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], nonceAuthVote)
+		accounts.sign(header, signer)
+		return types.NewBlockWithHeader(header)
+	}
+	// Votes before the deposit fork aren't checked
+	if err := engine.VerifyState(chain, vote(chain.headers[1], "A", "D"), statedb); err != nil {
+		t.Fatalf("pre-fork vote rejected: %v", err)
+	}
+	// A candidate short of the minimum deposit may not be voted on
+	if err := engine.VerifyState(chain, vote(chain.CurrentHeader(), "B", "D"), statedb); err != errUndepositedCandidate {
+		t.Fatalf("underfunded vote error mismatch: have %v, want %v", err, errUndepositedCandidate)
+	}
+	// A candidate with enough locked may be voted in, tallied from the headers alone
+	for _, signer := range []string{"B", "A"} {
+		block := vote(chain.CurrentHeader(), signer, "C")
+		if err := engine.VerifyState(chain, block, statedb); err != nil {
+			t.Fatalf("deposited vote rejected: %v", err)
+		}
+		chain.headers = append(chain.headers, block.Header())
+	}
+	head := chain.CurrentHeader()
+	snap, err := engine.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to tally votes without state: %v", err)
+	}
+	if _, ok := snap.Signers[accounts.address("C")]; !ok {
+		t.Fatalf("deposited candidate not authorized")
+	}
+	// Sealers skip the candidates without deposits, or all if the state is missing
+	if snap.eligible(big.NewInt(5), statedb, accounts.address("D")) {
+		t.Errorf("underfunded candidate deemed eligible")
+	}
+	if snap.eligible(big.NewInt(5), nil, accounts.address("E")) {
+		t.Errorf("candidate deemed eligible without state")
+	}
+	if !snap.eligible(big.NewInt(5), nil, accounts.address("C")) {
+		t.Errorf("authorized signer deemed ineligible")
+	}
+}
+
+// Tests that governance votes authorizing candidates without deposits are left
+// out of the header by the sealer, and that verification insists on it.
+func TestGovernanceDeposits(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		contract = common.Address{0xde, 0x90}
+		config   = &params.CliqueConfig{Epoch: 30000, GovernanceBlock: big.NewInt(1), DepositBlock: big.NewInt(1), DepositContract: contract, MinDeposit: big.NewInt(100)}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B"}, 0)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetState(contract, depositSlot(accounts.address("C")), common.BigToHash(big.NewInt(100)))
+
+	engine.SetStateReader(func(hash common.Hash) (*state.StateDB, error) {
+		if hash != chain.headers[0].Hash() {
+			return nil, errors.New("unknown block")
+		}
+		return statedb, nil
+	})
+	txSigner := types.MakeSigner(chain.config, common.Big1)
+
+	var txs []*types.Transaction
+	for i, target := range []string{"C", "D"} {
+		tx := types.NewTransaction(uint64(i), GovernanceAddress, new(big.Int), 50000, big.NewInt(1), GovernanceTxData(ProposalAuthorize, accounts.address(target)))
+		tx, _ = types.SignTx(tx, txSigner, accounts.accounts["B"])
+		txs = append(txs, tx)
+	}
+	header := &types.Header{
+		ParentHash: chain.headers[0].Hash(),
+		Number:     common.Big1,
+		Difficulty: diffNoTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	if err := engine.embedGovernanceVotes(chain, header, txs); err != nil {
+		t.Fatalf("failed to embed governance votes: %v", err)
+	}
+	if have, want := len(header.Extra), extraVanity+governanceVoteLength+extraSeal; have != want {
+		t.Fatalf("extra-data length mismatch: have %d, want %d", have, want)
+	}
+	accounts.sign(header, "A")
+	if err := engine.VerifyState(chain, types.NewBlock(header, txs, nil, nil, trie.NewStackTrie(nil)), statedb); err != nil {
+		t.Fatalf("filtered governance votes rejected: %v", err)
+	}
+	// Embedding the vote on the candidate without deposit must be rejected
+	unfiltered := types.CopyHeader(header)
+	unfiltered.Extra = make([]byte, extraVanity, extraVanity+2*governanceVoteLength+extraSeal)
+	unfiltered.Extra = append(unfiltered.Extra, encodeGovernanceVotes([]*GovernanceVote{
+		{Signer: accounts.address("B"), Kind: ProposalAuthorize, Target: accounts.address("C")},
+		{Signer: accounts.address("B"), Kind: ProposalAuthorize, Target: accounts.address("D")},
+	})...)
+	unfiltered.Extra = append(unfiltered.Extra, make([]byte, extraSeal)...)
+	accounts.sign(unfiltered, "A")
+
+	if err := engine.VerifyState(chain, types.NewBlock(unfiltered, txs, nil, nil, trie.NewStackTrie(nil)), statedb); err != errInvalidGovernanceVotes {
+		t.Fatalf("unfiltered votes error mismatch: have %v, want %v", err, errInvalidGovernanceVotes)
+	}
+}
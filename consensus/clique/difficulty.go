@@ -0,0 +1,48 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DifficultyCalculator computes the fork-choice weight of clique blocks. Since
+// every node recomputes the difficulty when verifying a block, implementations
+// must be deterministic functions of the parent snapshot and the block signer,
+// and all nodes of a network must run the same one.
+type DifficultyCalculator interface {
+	// CalcDifficulty returns the difficulty a block sealed by the given signer on
+	// top of the snapshot must carry. It must be positive.
+	CalcDifficulty(snap *Snapshot, signer common.Address) *big.Int
+}
+
+// SetDifficultyCalculator replaces the default in-turn/out-of-turn difficulty
+// rule with a custom one. It must be called before the engine is used.
+func (c *Clique) SetDifficultyCalculator(calc DifficultyCalculator) {
+	c.calculator = calc
+}
+
+// difficulty returns the difficulty of a block sealed by signer on top of the
+// snapshot, as computed by the configured calculator.
+func (c *Clique) difficulty(snap *Snapshot, signer common.Address) *big.Int {
+	if c.calculator != nil {
+		return c.calculator.CalcDifficulty(snap, signer)
+	}
+	return calcDifficulty(snap, signer)
+}
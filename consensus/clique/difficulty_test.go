@@ -0,0 +1,87 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testerDifficulty weighs in-turn blocks by ten and out-of-turn ones by the
+// number of signers.
+type testerDifficulty struct{}
+
+func (testerDifficulty) CalcDifficulty(snap *Snapshot, signer common.Address) *big.Int {
+	if snap.inturn(snap.Number+1, signer) {
+		return big.NewInt(10)
+	}
+	return big.NewInt(int64(len(snap.Signers)))
+}
+
+// Tests that a custom difficulty calculator is used both to prepare and to verify
+// headers, rejecting the default difficulties.
+func TestDifficultyCalculator(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000}
+		signers  = []string{"A", "B", "C"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		GasLimit:   8000000,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.SetDifficultyCalculator(testerDifficulty{})
+
+	snap, err := engine.snapshot(chain, 0, genesis.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create genesis snapshot: %v", err)
+	}
+	for _, signer := range signers {
+		engine.signer = accounts.address(signer)
+		header := &types.Header{ParentHash: genesis.Hash(), Number: common.Big1, GasLimit: genesis.GasLimit}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("signer %s: failed to prepare header: %v", signer, err)
+		}
+		header.Time, header.UncleHash = genesis.Time, types.EmptyUncleHash
+		accounts.sign(header, signer)
+
+		want := testerDifficulty{}.CalcDifficulty(snap, accounts.address(signer))
+		if header.Difficulty.Cmp(want) != 0 {
+			t.Fatalf("signer %s: difficulty mismatch: have %v, want %v", signer, header.Difficulty, want)
+		}
+		if err := engine.verifyHeader(chain, header, nil); err != nil {
+			t.Errorf("signer %s: custom difficulty rejected: %v", signer, err)
+		}
+		// Ensure the default difficulty is rejected
+		header.Difficulty = calcDifficulty(snap, accounts.address(signer))
+		accounts.sign(header, signer)
+		if err := engine.verifyHeader(chain, header, nil); err != errWrongDifficulty {
+			t.Errorf("signer %s: default difficulty error mismatch: have %v, want %v", signer, err, errWrongDifficulty)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// VoteEvent is posted for every vote counted in a block of the canonical chain.
+type VoteEvent struct {
+	Block    uint64         `json:"block"`              // Block number the vote was cast in
+	Hash     common.Hash    `json:"hash"`               // Block hash the vote was cast in
+	Signer   common.Address `json:"signer"`             // Authorized signer that cast the vote
+	Kind     ProposalKind   `json:"kind"`               // Type of the proposal voted on
+	Address  common.Address `json:"address"`            // Account voted on (membership votes)
+	Limit    uint           `json:"limit,omitempty"`    // Signer limit percentage voted on (limit votes)
+	Period   uint64         `json:"period,omitempty"`   // Minimum block period voted on (period votes)
+	Replaced common.Address `json:"replaced,omitempty"` // Signer voted on swapping for the account (replacement votes)
+	Votes    int            `json:"votes"`              // Running tally of the proposal, including this vote
+	Passed   bool           `json:"passed"`             // Whether the vote made the proposal pass
+}
+
+// LimitChangeEvent is posted whenever the signer limit changes in a block of the
+// canonical chain, either by a passing vote or by reverting at an epoch boundary.
+type LimitChangeEvent struct {
+	Block    uint64      `json:"block"`    // Block number the limit changed in
+	Hash     common.Hash `json:"hash"`     // Block hash the limit changed in
+	OldLimit uint        `json:"oldLimit"` // Signer limit percentage before the change
+	NewLimit uint        `json:"newLimit"` // Signer limit percentage after the change
+	Votes    int         `json:"votes"`    // Number of votes that passed the change (zero on epoch reverts)
+	Wait     uint64      `json:"wait"`     // Block until which the new limit can't be voted on again
+}
+
+// SubscribeVotes registers a subscription for the votes counted in the blocks of
+// the canonical chain.
+func (c *Clique) SubscribeVotes(ch chan<- *VoteEvent) event.Subscription {
+	return c.scope.Track(c.voteFeed.Subscribe(ch))
+}
+
+// SubscribeLimitChanges registers a subscription for the signer limit changes in
+// the blocks of the canonical chain.
+func (c *Clique) SubscribeLimitChanges(ch chan<- *LimitChangeEvent) event.Subscription {
+	return c.scope.Track(c.limitFeed.Subscribe(ch))
+}
+
+// publishEvents sends the votes counted and signer limit changes made in a newly
+// adopted canonical header to all subscribers, given the snapshot resulting from
+// applying the header alone.
+func (c *Clique) publishEvents(snap *Snapshot) {
+	if c.scope.Count() == 0 {
+		return
+	}
+	for _, vote := range snap.observed {
+		c.voteFeed.Send(vote)
+	}
+	for _, res := range snap.resolutions {
+		if res.Kind != ProposalSignerLimit {
+			continue
+		}
+		c.limitFeed.Send(&LimitChangeEvent{
+			Block:    res.Block,
+			Hash:     res.Hash,
+			OldLimit: res.PrevLimit,
+			NewLimit: res.Limit,
+			Votes:    len(res.Votes),
+			Wait:     snap.SignerLimitWait[uint64(res.Limit)].Block,
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the votes counted in new canonical blocks are posted along with the
+// running tally, both for single head updates and batch imports.
+func TestVoteEvents(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		signers  = []string{"A", "B", "C"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	votes := []struct {
+		signer   string
+		coinbase common.Address
+		nonce    []byte
+	}{
+		{"A", accounts.address("D"), nonceAuthVote},
+		{"B", accounts.address("D"), nonceAuthVote},
+		{"C", common.BigToAddress(big.NewInt(60)), nonceSignerLimitAuthVote},
+	}
+	for i, vote := range votes {
+		header := &types.Header{
+			ParentHash: chain.headers[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Difficulty: diffNoTurn,
+			Coinbase:   vote.coinbase,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], vote.nonce)
+		accounts.sign(header, vote.signer)
+		chain.headers = append(chain.headers, header)
+	}
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.NewChainHead(chain, genesis)
+
+	events := make(chan *VoteEvent, len(votes))
+	sub := engine.SubscribeVotes(events)
+	defer sub.Unsubscribe()
+
+	// Import the first block as a head update and the rest as a batch
+	engine.NewChainHead(chain, chain.headers[1])
+	engine.NewChainHead(chain, chain.headers[3])
+
+	want := []VoteEvent{
+		{Block: 1, Signer: accounts.address("A"), Kind: ProposalAuthorize, Address: accounts.address("D"), Votes: 1},
+		{Block: 2, Signer: accounts.address("B"), Kind: ProposalAuthorize, Address: accounts.address("D"), Votes: 2, Passed: true},
+		{Block: 3, Signer: accounts.address("C"), Kind: ProposalSignerLimit, Limit: 60, Votes: 1},
+	}
+	for i, w := range want {
+		if len(events) == 0 {
+			t.Fatalf("vote %d: event missing", i)
+		}
+		have := <-events
+		w.Hash = chain.headers[w.Block].Hash()
+		if *have != w {
+			t.Errorf("vote %d: event mismatch: have %+v, want %+v", i, *have, w)
+		}
+	}
+	if len(events) != 0 {
+		t.Errorf("unexpected vote event: %+v", <-events)
+	}
+}
+
+// Tests that passing signer limit votes are posted with the previous limit and
+// the cooldown of the new one.
+func TestLimitChangeEvents(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		signers  = []string{"A", "B", "C"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	for i, signer := range []string{"A", "B", "C"} {
+		header := &types.Header{
+			ParentHash: chain.headers[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Difficulty: diffNoTurn,
+			Coinbase:   common.BigToAddress(big.NewInt(60)),
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], nonceSignerLimitAuthVote)
+		accounts.sign(header, signer)
+		chain.headers = append(chain.headers, header)
+	}
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.NewChainHead(chain, genesis)
+
+	changes := make(chan *LimitChangeEvent, 2)
+	sub := engine.SubscribeLimitChanges(changes)
+	defer sub.Unsubscribe()
+
+	engine.NewChainHead(chain, chain.headers[3])
+	if len(changes) != 1 {
+		t.Fatalf("limit change count mismatch: have %d, want %d", len(changes), 1)
+	}
+	want := LimitChangeEvent{Block: 2, Hash: chain.headers[2].Hash(), OldLimit: 50, NewLimit: 60, Votes: 2, Wait: 5}
+	if have := <-changes; *have != want {
+		t.Errorf("limit change mismatch: have %+v, want %+v", *have, want)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// errInvalidExtraPayload is returned if a header from the structured extra-data
+// fork on carries a payload that isn't the canonical RLP encoding of its sections.
+var errInvalidExtraPayload = errors.New("invalid structured extra-data payload")
+
+// extraPayload is the structured extra-data payload of the headers from the
+// structured extra-data fork on. It's embedded RLP encoded between the vanity and
+// the seal, in place of the positional sections told apart by their lengths, so
+// third parties can parse it without replicating the length rules, and further
+// sections can be added without making the existing ones ambiguous.
+type extraPayload struct {
+	Signers   []common.Address // Signer list of a checkpoint
+	Aggregate []byte           // Aggregate attestation of the epoch closed by a checkpoint
+	Root      []byte           // Snapshot root committed to by a checkpoint
+	Override  []byte           // Signer set override of a checkpoint, replacing all the above
+
+	Votes         []byte // Governance votes of a non-checkpoint block
+	Attestation   []byte // Checkpoint attestation of a non-checkpoint block
+	Justification []byte // Finality justification of a non-checkpoint block
+
+	Limit uint64 `rlp:"optional"` // Signer limit voted on alongside the header vote of a non-checkpoint block
+}
+
+// structuredExtra returns whether a header carries a structured extra-data
+// payload. The genesis is always positional, as it's assembled outside of the
+// engine.
+func structuredExtra(config *params.CliqueConfig, header *types.Header) bool {
+	return config.IsStructuredExtra(header.Number) && header.Number.Sign() > 0
+}
+
+// decodeExtra decodes the extra-data payload of a header into its sections,
+// selecting the parser by the layout the header carries at its height: headers
+// before the structured extra-data fork are split by the positional length rules,
+// the ones after are RLP decoded. Legacy headers carry no payload votes, theirs
+// are all encoded in the nonce and coinbase.
+func decodeExtra(config *params.CliqueConfig, header *types.Header) (*extraPayload, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	payload := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	if !structuredExtra(config, header) {
+		return structurePayload(config, header, payload), nil
+	}
+	structured := new(extraPayload)
+	if err := rlp.DecodeBytes(payload, structured); err != nil {
+		return nil, errInvalidExtraPayload
+	}
+	return structured, nil
+}
+
+// headerPayload returns the extra-data payload of a header between the vanity and
+// the seal in the positional layout, converting it from the structured one if the
+// header carries that. Malformed structured payloads, rejected by the header
+// verification, yield an empty payload.
+func headerPayload(config *params.CliqueConfig, header *types.Header) []byte {
+	if !structuredExtra(config, header) {
+		return header.Extra[extraVanity : len(header.Extra)-extraSeal]
+	}
+	structured, err := decodeExtra(config, header)
+	if err != nil {
+		return nil
+	}
+	return structured.positional()
+}
+
+// positional assembles the structured payload sections in their positional layout.
+func (p *extraPayload) positional() []byte {
+	if len(p.Override) > 0 {
+		return p.Override
+	}
+	var payload []byte
+	for _, signer := range p.Signers {
+		payload = append(payload, signer[:]...)
+	}
+	for _, section := range [][]byte{p.Aggregate, p.Root, p.Votes, p.Attestation, p.Justification} {
+		payload = append(payload, section...)
+	}
+	return payload
+}
+
+// structurePayload separates the positional extra-data payload of a header into
+// the sections of a structured one.
+func structurePayload(config *params.CliqueConfig, header *types.Header, payload []byte) *extraPayload {
+	if header.Number.Uint64()%config.Epoch != 0 {
+		votes, attestation, justification := splitRegularPayload(config, header.Number, payload)
+		return &extraPayload{Votes: votes, Attestation: attestation, Justification: justification}
+	}
+	if isOverride(header) {
+		return &extraPayload{Override: payload}
+	}
+	list, aggregate, root := splitCheckpointPayload(payload)
+
+	signers := make([]common.Address, len(list)/common.AddressLength)
+	for i := range signers {
+		copy(signers[i][:], list[i*common.AddressLength:])
+	}
+	return &extraPayload{Signers: signers, Aggregate: aggregate, Root: root}
+}
+
+// structureExtra converts the positional extra-data of a header being assembled
+// into the structured layout, if the header must carry that, embedding the given
+// signer limit vote (0 = none) into the payload. The vanity and the seal are
+// retained as they are.
+func structureExtra(config *params.CliqueConfig, header *types.Header, limit uint64) {
+	if !structuredExtra(config, header) {
+		return
+	}
+	payload := header.Extra[extraVanity : len(header.Extra)-extraSeal]
+
+	structured := structurePayload(config, header, payload)
+	structured.Limit = limit
+
+	blob, err := rlp.EncodeToBytes(structured)
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	extra := make([]byte, 0, extraVanity+len(blob)+extraSeal)
+	extra = append(extra, header.Extra[:extraVanity]...)
+	extra = append(extra, blob...)
+	header.Extra = append(extra, header.Extra[len(header.Extra)-extraSeal:]...)
+}
+
+// verifyExtraPayload checks that a header carrying a structured extra-data payload
+// embeds exactly the canonical encoding of its sections, each of them permitted
+// in the header, so every payload has a single interpretation.
+func verifyExtraPayload(config *params.CliqueConfig, header *types.Header) error {
+	if !structuredExtra(config, header) {
+		return nil
+	}
+	structured, err := decodeExtra(config, header)
+	if err != nil {
+		return err
+	}
+	canonical := structurePayload(config, header, structured.positional())
+	if header.Number.Uint64()%config.Epoch != 0 {
+		canonical.Limit = structured.Limit
+	}
+	blob, err := rlp.EncodeToBytes(canonical)
+	if err != nil || !bytes.Equal(blob, header.Extra[extraVanity:len(header.Extra)-extraSeal]) {
+		return errInvalidExtraPayload
+	}
+	return nil
+}
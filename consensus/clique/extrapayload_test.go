@@ -0,0 +1,241 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that positional extra-data payloads are converted into the structured
+// layout and back without loss, and that only canonical encodings are accepted.
+func TestStructuredExtraRoundTrip(t *testing.T) {
+	config := &params.CliqueConfig{Epoch: 4, StructuredExtraBlock: big.NewInt(1), AttestationBlock: big.NewInt(0), SnapshotRootBlock: big.NewInt(0)}
+
+	signers := append(common.Address{0x01}.Bytes(), common.Address{0x02}.Bytes()...)
+	root := encodeSnapshotRoot(snapshotRootVersion, common.Hash{0x03})
+	aggregate := bytes.Repeat([]byte{0xaa}, aggregateLength)
+
+	tests := []struct {
+		number  int64
+		payload []byte
+	}{
+		{1, nil},
+		{4, signers},
+		{4, append(append(common.CopyBytes(signers), aggregate...), root...)},
+		{4, append(common.CopyBytes(signers), root...)},
+	}
+	for i, tt := range tests {
+		positional := append(append(make([]byte, extraVanity), tt.payload...), bytes.Repeat([]byte{0xff}, extraSeal)...)
+		header := &types.Header{Number: big.NewInt(tt.number), Extra: common.CopyBytes(positional)}
+
+		structureExtra(config, header, 0)
+		if bytes.Equal(header.Extra, positional) {
+			t.Errorf("test %d: extra-data not structured", i)
+		}
+		if !bytes.Equal(header.Extra[len(header.Extra)-extraSeal:], positional[len(positional)-extraSeal:]) {
+			t.Errorf("test %d: seal not retained", i)
+		}
+		if err := verifyExtraPayload(config, header); err != nil {
+			t.Errorf("test %d: failed to verify structured payload: %v", i, err)
+		}
+		if have := headerPayload(config, header); !bytes.Equal(have, tt.payload) {
+			t.Errorf("test %d: payload mismatch: have %x, want %x", i, have, tt.payload)
+		}
+	}
+	// Positional payloads past the fork, and sections misplaced into a header that
+	// doesn't permit them, must be rejected
+	header := &types.Header{Number: big.NewInt(4), Extra: append(append(make([]byte, extraVanity), signers...), make([]byte, extraSeal)...)}
+	if err := verifyExtraPayload(config, header); err != errInvalidExtraPayload {
+		t.Errorf("positional payload error mismatch: have %v, want %v", err, errInvalidExtraPayload)
+	}
+	structureExtra(config, header, 0)
+	header.Number = big.NewInt(5)
+	if err := verifyExtraPayload(config, header); err != errInvalidExtraPayload {
+		t.Errorf("misplaced signer list error mismatch: have %v, want %v", err, errInvalidExtraPayload)
+	}
+	// Headers before the fork remain positional
+	header = &types.Header{Number: big.NewInt(0), Extra: append(append(make([]byte, extraVanity), signers...), make([]byte, extraSeal)...)}
+	if have := headerPayload(config, header); !bytes.Equal(have, signers) {
+		t.Errorf("genesis payload mismatch: have %x, want %x", have, signers)
+	}
+}
+
+// Tests that the light verifier follows a chain switching to the structured
+// extra-data layout, checking its checkpoint signer lists.
+func TestStructuredExtraLightVerifier(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = &params.CliqueConfig{Epoch: 3, StructuredExtraBlock: big.NewInt(2)}
+	)
+	makeHeader := func(parent *types.Header, structured bool) *types.Header {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       parent.Time + 1,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if header.Number.Uint64()%config.Epoch == 0 {
+			header.Extra = append(append(make([]byte, extraVanity), addr[:]...), make([]byte, extraSeal)...)
+		}
+		if structured {
+			structureExtra(config, header, 0)
+		}
+		sig, _ := crypto.Sign(SealHash(header).Bytes(), key)
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		return header
+	}
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      append(append(make([]byte, extraVanity), addr[:]...), make([]byte, extraSeal)...),
+	}
+	verifier, err := NewLightVerifier(config, genesis)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	headers := []*types.Header{makeHeader(genesis, false)}
+	headers = append(headers, makeHeader(headers[0], true))
+
+	for _, header := range headers {
+		if err := verifier.Verify(header); err != nil {
+			t.Fatalf("header %d: failed to verify: %v", header.Number, err)
+		}
+	}
+	if err := verifier.Verify(makeHeader(headers[1], false)); err != errInvalidExtraPayload {
+		t.Fatalf("positional checkpoint error mismatch: have %v, want %v", err, errInvalidExtraPayload)
+	}
+	if err := verifier.Verify(makeHeader(headers[1], true)); err != nil {
+		t.Fatalf("failed to verify structured checkpoint: %v", err)
+	}
+}
+
+// Tests that a chain straddling the structured extra-data fork is decoded with
+// the legacy parser before the fork and the structured one after, carrying votes
+// across, and that neither layout is accepted on the other side of the fork.
+func TestStructuredExtraFork(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		names    = []string{"A", "B", "C"}
+		config   = &params.CliqueConfig{Epoch: 4, SignerLimit: 100, StructuredExtraBlock: big.NewInt(6), RecentsWindowBlock: big.NewInt(0)}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(names)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, names)
+
+	verifier, err := NewLightVerifier(config, genesis)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	// makeHeader creates the next header sealed in turn, voting on the given
+	// account through the nonce and on the given limit through the payload
+	makeHeader := func(vote common.Address, limit uint64, structured bool) *types.Header {
+		var (
+			parent = verifier.head
+			snap   = verifier.Snapshot()
+			number = parent.Number.Uint64() + 1
+		)
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).SetUint64(number),
+			Time:       parent.Time + 1,
+			Coinbase:   vote,
+			Nonce:      snap.voteNonces().drop,
+			Extra:      make([]byte, extraVanity),
+		}
+		if vote != (common.Address{}) {
+			header.Nonce = snap.voteNonces().auth
+		}
+		if number%config.Epoch == 0 {
+			for _, signer := range snap.signers() {
+				header.Extra = append(header.Extra, signer[:]...)
+			}
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+		if structured {
+			layout := *config
+			layout.StructuredExtraBlock = common.Big1
+			structureExtra(&layout, header, limit)
+		}
+		for _, name := range names {
+			if snap.inturn(number, accounts.address(name)) {
+				header.Difficulty = calcDifficulty(snap, accounts.address(name))
+				accounts.sign(header, name)
+			}
+		}
+		return header
+	}
+	// Vote on D with the legacy layout before the fork and both layouts after it
+	for number := 1; number <= 9; number++ {
+		var (
+			vote  common.Address
+			limit uint64
+		)
+		if number == 5 || number == 7 {
+			vote = accounts.address("D")
+		}
+		if number == 7 {
+			limit = 66
+		}
+		structured := uint64(number) >= config.StructuredExtraBlock.Uint64()
+		if number == 5 || number == 8 {
+			// Headers in the layout of the other side of the fork must be rejected
+			want := errExtraSigners
+			if structured {
+				want = errInvalidExtraPayload
+			}
+			if err := verifier.Verify(makeHeader(vote, 0, !structured)); err != want {
+				t.Errorf("block %d: cross-layout error mismatch: have %v, want %v", number, err, want)
+			}
+		}
+		header := makeHeader(vote, limit, structured)
+		if err := verifier.Verify(header); err != nil {
+			t.Fatalf("block %d: failed to verify: %v", number, err)
+		}
+		decoded, err := decodeExtra(config, header)
+		if err != nil {
+			t.Fatalf("block %d: failed to decode extra-data: %v", number, err)
+		}
+		if number%4 == 0 && len(decoded.Signers) != len(names) {
+			t.Errorf("block %d: checkpoint signers mismatch: have %d, want %d", number, len(decoded.Signers), len(names))
+		}
+		if decoded.Limit != limit {
+			t.Errorf("block %d: payload limit mismatch: have %d, want %d", number, decoded.Limit, limit)
+		}
+		// Votes cast in both layouts must be tallied up together until the checkpoint
+		if number == 7 {
+			snap := verifier.Snapshot()
+			if tally := snap.Tally[accounts.address("D")]; tally.Votes != 2 {
+				t.Errorf("authorization tally mismatch across the fork: have %d votes, want 2", tally.Votes)
+			}
+			if votes := snap.PendingLimitVotes(); len(votes) != 1 || votes[0].Limit != 66 {
+				t.Errorf("payload limit votes mismatch: have %+v", votes)
+			}
+		}
+	}
+}
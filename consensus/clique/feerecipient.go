@@ -0,0 +1,74 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// feeRecipientMagic marks a fee recipient declared at the end of the vanity.
+	feeRecipientMagic = []byte("fee:")
+
+	// feeRecipientOffset is the vanity offset the fee recipient declaration starts at.
+	feeRecipientOffset = extraVanity - len(feeRecipientMagic) - common.AddressLength
+)
+
+// FeeRecipient implements consensus.FeeRecipientResolver, returning the account
+// the transaction fees of a block are credited to. From the fee recipient fork on,
+// a sealer may declare it in the vanity of its blocks, since the coinbase carries
+// the votes. Otherwise the fees go to the sealer itself.
+func (c *Clique) FeeRecipient(header *types.Header) (common.Address, error) {
+	if c.config.IsFeeRecipient(header.Number) {
+		if recipient, ok := declaredFeeRecipient(header); ok {
+			return recipient, nil
+		}
+	}
+	return ecrecover(header, c.signatures)
+}
+
+// SetFeeRecipient sets the account the local signer declares as the recipient of
+// the fees of its blocks. The zero address credits them to the signer.
+func (c *Clique) SetFeeRecipient(recipient common.Address) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.feeRecipient = recipient
+}
+
+// declaredFeeRecipient extracts the fee recipient declared in the vanity of a
+// header, if any.
+func declaredFeeRecipient(header *types.Header) (common.Address, bool) {
+	if len(header.Extra) < extraVanity {
+		return common.Address{}, false
+	}
+	declaration := header.Extra[feeRecipientOffset:extraVanity]
+	if !bytes.HasPrefix(declaration, feeRecipientMagic) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(declaration[len(feeRecipientMagic):]), true
+}
+
+// declareFeeRecipient embeds the fee recipient declaration into the vanity of a
+// header being prepared.
+func declareFeeRecipient(header *types.Header, recipient common.Address) {
+	copy(header.Extra[feeRecipientOffset:], feeRecipientMagic)
+	copy(header.Extra[feeRecipientOffset+len(feeRecipientMagic):], recipient[:])
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that signers can declare the recipient of their fees from the fork on,
+// and that the fees go to the sealer otherwise.
+func TestFeeRecipient(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, FeeRecipientBlock: big.NewInt(2)}
+		signers  = []string{"A"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.signer = accounts.address("A")
+	engine.SetFeeRecipient(accounts.address("F"))
+
+	for i := 1; i <= 2; i++ {
+		extra := bytes.Repeat([]byte{0xff}, extraVanity)
+		header := &types.Header{
+			ParentHash: chain.headers[i-1].Hash(),
+			Number:     big.NewInt(int64(i)),
+			Extra:      extra,
+		}
+		if err := engine.Prepare(chain, header); err != nil {
+			t.Fatalf("block %d: failed to prepare header: %v", i, err)
+		}
+		if !bytes.Equal(extra, bytes.Repeat([]byte{0xff}, extraVanity)) {
+			t.Fatalf("block %d: miner extra data overwritten", i)
+		}
+		accounts.sign(header, "A")
+		chain.headers = append(chain.headers, header)
+
+		want := accounts.address("A")
+		if config.IsFeeRecipient(header.Number) {
+			want = accounts.address("F")
+		}
+		if recipient, err := engine.FeeRecipient(header); err != nil || recipient != want {
+			t.Errorf("block %d: fee recipient mismatch: have %x (%v), want %x", i, recipient, err, want)
+		}
+	}
+}
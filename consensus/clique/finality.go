@@ -0,0 +1,139 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	finalityInterval    = 64                    // Default number of blocks between the ancestors justified for finality
+	justificationLength = 8 + common.HashLength // Length of a justification in the header extra-data
+)
+
+// errInvalidJustification is returned if a block justifies anything else than the
+// current finality target, or its signer already justified it.
+var errInvalidJustification = errors.New("invalid finality justification")
+
+// Justification is a signer's vote for the finality of an ancestor block, embedded
+// right before the seal of the blocks it seals. Once a supermajority of distinct
+// signers justified a block, it's final and the chain refuses to reorg past it.
+type Justification struct {
+	Number uint64      // Number of the justified ancestor
+	Hash   common.Hash // Hash of the justified ancestor
+}
+
+// justificationTarget returns the number of the ancestor the block with the given
+// number may justify: the last multiple of the finality interval before it.
+func justificationTarget(config *params.CliqueConfig, number uint64) uint64 {
+	interval := config.FinalityInterval
+	if interval == 0 {
+		interval = finalityInterval
+	}
+	return (number - 1) / interval * interval
+}
+
+// splitJustification separates the justification embedded into the extra-data of
+// a non-checkpoint header from the governance votes preceding it, skipping any
+// checkpoint attestation in between.
+func splitJustification(config *params.CliqueConfig, header *types.Header) ([]byte, *Justification) {
+	votes, _, justification := splitPayload(config, header)
+	return votes, justification
+}
+
+// embedJustification appends a justification to the payload of the header being
+// prepared, which must not contain the seal yet.
+func embedJustification(header *types.Header, justification *Justification) {
+	blob := make([]byte, justificationLength)
+	binary.BigEndian.PutUint64(blob, justification.Number)
+	copy(blob[8:], justification.Hash[:])
+
+	header.Extra = append(header.Extra, blob...)
+}
+
+// justification returns the justification the given signer may embed into the
+// block with the given number, or nil if it has nothing left to justify.
+func (s *Snapshot) justification(number uint64, signer common.Address) *Justification {
+	if s.JustifyHash == (common.Hash{}) || s.JustifyTarget != justificationTarget(s.config, number) {
+		return nil
+	}
+	if s.FinalizedHash != (common.Hash{}) && s.JustifyTarget <= s.FinalizedNumber {
+		return nil
+	}
+	for _, justifier := range s.Justifiers {
+		if justifier == signer {
+			return nil
+		}
+	}
+	return &Justification{Number: s.JustifyTarget, Hash: s.JustifyHash}
+}
+
+// verifyJustification checks that the justification embedded into a header, if
+// any, is one its signer may cast in the context of the snapshot of its parent.
+func (s *Snapshot) verifyJustification(header *types.Header, signer common.Address) (*Justification, error) {
+	if header.Number.Uint64()%s.config.Epoch == 0 {
+		return nil, nil
+	}
+	_, justification := splitJustification(s.config, header)
+	if justification == nil {
+		return nil, nil
+	}
+	if want := s.justification(header.Number.Uint64(), signer); want == nil || *want != *justification {
+		return nil, errInvalidJustification
+	}
+	return justification, nil
+}
+
+// applyJustification counts the justification embedded into a header, finalizing
+// the target once justified by a supermajority of the signers, and moves on to
+// the next target if the header is one.
+func (s *Snapshot) applyJustification(header *types.Header, signer common.Address) error {
+	if !s.config.IsFinality(header.Number) {
+		return nil
+	}
+	justification, err := s.verifyJustification(header, signer)
+	if err != nil {
+		return err
+	}
+	if justification != nil {
+		// Justifiers are shared between snapshot copies, never modify in place
+		s.Justifiers = append(append(make([]common.Address, 0, len(s.Justifiers)+1), s.Justifiers...), signer)
+		if 3*len(s.Justifiers) > 2*len(s.Signers) {
+			s.FinalizedNumber, s.FinalizedHash = justification.Number, justification.Hash
+		}
+	}
+	if number := header.Number.Uint64(); justificationTarget(s.config, number+1) == number {
+		s.JustifyTarget, s.JustifyHash, s.Justifiers = number, header.Hash(), nil
+	}
+	return nil
+}
+
+// Finalized returns the latest block finalized on the chain ending in the given
+// head, or nil if none was finalized yet.
+func (c *Clique) Finalized(chain consensus.ChainHeaderReader, head *types.Header) *types.Header {
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil || snap.FinalizedHash == (common.Hash{}) {
+		return nil
+	}
+	return chain.GetHeader(snap.FinalizedHash, snap.FinalizedNumber)
+}
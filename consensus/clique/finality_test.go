@@ -0,0 +1,101 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that signers justify the finality targets in the blocks they seal, and
+// that a target is finalized once a supermajority of the signers justified it.
+func TestFinalityJustifications(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, FinalityBlock: big.NewInt(1), FinalityInterval: 4}
+		signers  = []string{"A", "B", "C"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		GasLimit:   params.GenesisGasLimit,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+	engine := New(config, rawdb.NewMemoryDatabase())
+
+	// Seal a few blocks round robin, justifying whenever possible
+	var justified []uint64
+	for i := 1; i <= 8; i++ {
+		parent := chain.headers[i-1]
+		snap, err := engine.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to create snapshot: %v", i, err)
+		}
+		signer := signers[i%len(signers)]
+
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Difficulty: calcDifficulty(snap, accounts.address(signer)),
+			GasLimit:   params.GenesisGasLimit,
+			Extra:      make([]byte, extraVanity),
+		}
+		if justification := snap.justification(uint64(i), accounts.address(signer)); justification != nil {
+			embedJustification(header, justification)
+			justified = append(justified, uint64(i))
+		}
+		header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+		accounts.sign(header, signer)
+
+		if err := engine.verifyCascadingFields(chain, header, nil); err != nil {
+			t.Fatalf("block %d: failed to verify: %v", i, err)
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	// Block 4 is the first target, justified by all three signers in 5-7
+	if want := []uint64{5, 6, 7}; len(justified) != len(want) || justified[0] != want[0] || justified[2] != want[2] {
+		t.Fatalf("justifying blocks mismatch: have %v, want %v", justified, want)
+	}
+	if final := engine.Finalized(chain, chain.headers[6]); final != nil {
+		t.Errorf("block finalized without supermajority: %d", final.Number)
+	}
+	if final := engine.Finalized(chain, chain.headers[7]); final == nil || final.Hash() != chain.headers[4].Hash() {
+		t.Errorf("finalized block mismatch: have %v, want 4", final)
+	}
+	// Justifications of anything but the current target must be rejected
+	header := &types.Header{
+		ParentHash: chain.headers[8].Hash(),
+		Number:     big.NewInt(9),
+		Difficulty: diffInTurn,
+		GasLimit:   params.GenesisGasLimit,
+		Extra:      make([]byte, extraVanity),
+	}
+	embedJustification(header, &Justification{Number: 8, Hash: chain.headers[7].Hash()})
+	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+	accounts.sign(header, "C")
+
+	if err := engine.verifyCascadingFields(chain, header, nil); err != errInvalidJustification {
+		t.Errorf("invalid justification error mismatch: have %v, want %v", err, errInvalidJustification)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// flushQueueLimit is the maximum number of snapshot writes waiting to be flushed
+// to disk. Further writes block until the flusher catches up.
+const flushQueueLimit = 128
+
+// errFlushDeleted is returned when reading an entry whose deletion is queued.
+var errFlushDeleted = errors.New("snapshot entry deleted")
+
+// flushOp is a single write queued for flushing.
+type flushOp struct {
+	key   []byte
+	value []byte // Nil for deletions
+}
+
+// snapshotFlusher wraps the snapshot database, writing to disk in the background
+// so header verification doesn't stall on slow disks. Writes are flushed one by
+// one in the order they were made, so a crash never leaves a delta on disk with
+// its base missing, nor the flushed snapshot marker without its snapshot. Reads
+// are served from the queued writes until they reach the disk.
+type snapshotFlusher struct {
+	ethdb.Database // Database flushed to
+
+	queue   []*flushOp          // Writes waiting to be flushed, in order
+	pending map[string]*flushOp // Latest queued write of every key
+	closed  bool                // Whether writes go straight to disk
+	cond    *sync.Cond          // Signals changes of the queue
+	lock    sync.Mutex
+	done    chan struct{} // Closed when the flusher terminates
+}
+
+// newSnapshotFlusher wraps a database into a background flusher.
+func newSnapshotFlusher(db ethdb.Database) *snapshotFlusher {
+	f := &snapshotFlusher{
+		Database: db,
+		pending:  make(map[string]*flushOp),
+		done:     make(chan struct{}),
+	}
+	f.cond = sync.NewCond(&f.lock)
+	go f.loop()
+	return f
+}
+
+// loop flushes the queued writes until the flusher is closed and drained.
+func (f *snapshotFlusher) loop() {
+	defer close(f.done)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for {
+		for len(f.queue) == 0 && !f.closed {
+			f.cond.Wait()
+		}
+		if len(f.queue) == 0 {
+			return
+		}
+		op := f.queue[0]
+		f.queue = f.queue[1:]
+		f.lock.Unlock()
+
+		var err error
+		if op.value == nil {
+			err = f.Database.Delete(op.key)
+		} else {
+			err = f.Database.Put(op.key, op.value)
+		}
+		if err != nil {
+			log.Error("Failed to flush voting snapshot", "key", hexutil.Bytes(op.key), "err", err)
+		}
+		f.lock.Lock()
+		if f.pending[string(op.key)] == op {
+			delete(f.pending, string(op.key))
+		}
+		f.cond.Broadcast()
+	}
+}
+
+// enqueue queues a write for flushing, waiting for room in the queue if needed.
+// Once the flusher is closed, writes go straight to disk.
+func (f *snapshotFlusher) enqueue(key []byte, value []byte) error {
+	f.lock.Lock()
+	for len(f.queue) >= flushQueueLimit && !f.closed {
+		f.cond.Wait()
+	}
+	if f.closed {
+		f.lock.Unlock()
+		if value == nil {
+			return f.Database.Delete(key)
+		}
+		return f.Database.Put(key, value)
+	}
+	defer f.lock.Unlock()
+
+	op := &flushOp{key: append([]byte{}, key...)}
+	if value != nil {
+		op.value = append([]byte{}, value...)
+	}
+	f.queue = append(f.queue, op)
+	f.pending[string(key)] = op
+	f.cond.Broadcast()
+	return nil
+}
+
+// Put queues the given key and value for writing.
+func (f *snapshotFlusher) Put(key []byte, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	return f.enqueue(key, value)
+}
+
+// Delete queues the given key for removal.
+func (f *snapshotFlusher) Delete(key []byte) error {
+	return f.enqueue(key, nil)
+}
+
+// Get retrieves the given key, either from the queued writes or from disk.
+func (f *snapshotFlusher) Get(key []byte) ([]byte, error) {
+	f.lock.Lock()
+	op, ok := f.pending[string(key)]
+	f.lock.Unlock()
+
+	if !ok {
+		return f.Database.Get(key)
+	}
+	if op.value == nil {
+		return nil, errFlushDeleted
+	}
+	return append([]byte{}, op.value...), nil
+}
+
+// Has retrieves whether the given key is present, either among the queued writes
+// or on disk.
+func (f *snapshotFlusher) Has(key []byte) (bool, error) {
+	f.lock.Lock()
+	op, ok := f.pending[string(key)]
+	f.lock.Unlock()
+
+	if !ok {
+		return f.Database.Has(key)
+	}
+	return op.value != nil, nil
+}
+
+// NewIterator creates an iterator over the given prefix of the database, waiting
+// for the queued writes to reach the disk first so none of them are missed.
+func (f *snapshotFlusher) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	f.lock.Lock()
+	for len(f.pending) > 0 {
+		f.cond.Wait()
+	}
+	f.lock.Unlock()
+
+	return f.Database.NewIterator(prefix, start)
+}
+
+// NewBatch creates a batch whose writes are queued for flushing on commit.
+func (f *snapshotFlusher) NewBatch() ethdb.Batch {
+	return &flusherBatch{flusher: f}
+}
+
+// NewBatchWithSize creates a batch whose writes are queued for flushing on commit,
+// with a pre-allocated buffer.
+func (f *snapshotFlusher) NewBatchWithSize(size int) ethdb.Batch {
+	return &flusherBatch{flusher: f}
+}
+
+// Close flushes all queued writes to disk and terminates the flusher, writing
+// any later ones synchronously. The wrapped database is left open.
+func (f *snapshotFlusher) Close() error {
+	f.lock.Lock()
+	f.closed = true
+	f.cond.Broadcast()
+	f.lock.Unlock()
+
+	<-f.done
+	return nil
+}
+
+// flusherBatch collects writes to the snapshot database, queueing them into the
+// flusher in order once written, so they are ordered with the direct writes.
+type flusherBatch struct {
+	flusher *snapshotFlusher
+	ops     []*flushOp
+	size    int
+}
+
+// Put inserts the given value into the batch.
+func (b *flusherBatch) Put(key []byte, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	b.ops = append(b.ops, &flushOp{key: append([]byte{}, key...), value: append([]byte{}, value...)})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete inserts the given key removal into the batch.
+func (b *flusherBatch) Delete(key []byte) error {
+	b.ops = append(b.ops, &flushOp{key: append([]byte{}, key...)})
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *flusherBatch) ValueSize() int {
+	return b.size
+}
+
+// Write queues the batched writes for flushing.
+func (b *flusherBatch) Write() error {
+	for _, op := range b.ops {
+		if err := b.flusher.enqueue(op.key, op.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset resets the batch for reuse.
+func (b *flusherBatch) Reset() {
+	b.ops = b.ops[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *flusherBatch) Replay(w ethdb.KeyValueWriter) error {
+	for _, op := range b.ops {
+		var err error
+		if op.value == nil {
+			err = w.Delete(op.key)
+		} else {
+			err = w.Put(op.key, op.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// gatedDatabase is a database whose writes block until released, recording the
+// order they reach it in.
+type gatedDatabase struct {
+	ethdb.Database
+	gate    chan struct{}
+	written [][]byte
+}
+
+func (db *gatedDatabase) Put(key []byte, value []byte) error {
+	<-db.gate
+	db.written = append(db.written, append([]byte{}, key...))
+	return db.Database.Put(key, value)
+}
+
+func (db *gatedDatabase) Delete(key []byte) error {
+	<-db.gate
+	db.written = append(db.written, append([]byte{}, key...))
+	return db.Database.Delete(key)
+}
+
+// Tests that writes queued in the snapshot flusher are readable before reaching
+// the disk, and that they reach it in order once the flusher is closed.
+func TestSnapshotFlusher(t *testing.T) {
+	db := &gatedDatabase{Database: rawdb.NewMemoryDatabase(), gate: make(chan struct{})}
+	flusher := newSnapshotFlusher(db)
+
+	keys := [][]byte{[]byte("base"), []byte("delta"), []byte("marker")}
+	for _, key := range keys {
+		if err := flusher.Put(key, key); err != nil {
+			t.Fatalf("failed to queue %s: %v", key, err)
+		}
+	}
+	if err := flusher.Delete([]byte("base")); err != nil {
+		t.Fatalf("failed to queue deletion: %v", err)
+	}
+	// Queued writes must be visible while the disk is stalled
+	if blob, err := flusher.Get([]byte("delta")); err != nil || !bytes.Equal(blob, []byte("delta")) {
+		t.Errorf("queued write mismatch: have %s (%v), want %s", blob, err, "delta")
+	}
+	if has, _ := flusher.Has([]byte("base")); has {
+		t.Errorf("queued deletion not visible")
+	}
+	// Release the disk and ensure everything was written in order
+	close(db.gate)
+	flusher.Close()
+
+	want := append(keys, []byte("base"))
+	if len(db.written) != len(want) {
+		t.Fatalf("write count mismatch: have %d, want %d", len(db.written), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(db.written[i], want[i]) {
+			t.Errorf("write %d mismatch: have %s, want %s", i, db.written[i], want[i])
+		}
+	}
+	if has, _ := db.Database.Has([]byte("base")); has {
+		t.Errorf("deleted entry left on disk")
+	}
+	if blob, _ := db.Database.Get([]byte("marker")); !bytes.Equal(blob, []byte("marker")) {
+		t.Errorf("flushed entry mismatch: have %s, want %s", blob, "marker")
+	}
+	// Writes after closing must go straight to disk
+	if err := flusher.Put([]byte("late"), []byte("late")); err != nil {
+		t.Fatalf("failed to write after close: %v", err)
+	}
+	if has, _ := db.Database.Has([]byte("late")); !has {
+		t.Errorf("write after close not on disk")
+	}
+}
+
+// Tests that batches written through the snapshot flusher are queued behind the
+// direct writes, and that iterators see every queued write.
+func TestSnapshotFlusherBatchIterator(t *testing.T) {
+	db := &gatedDatabase{Database: rawdb.NewMemoryDatabase(), gate: make(chan struct{})}
+	flusher := newSnapshotFlusher(db)
+	defer flusher.Close()
+
+	if err := flusher.Put([]byte("a-base"), []byte("base")); err != nil {
+		t.Fatalf("failed to queue write: %v", err)
+	}
+	batch := flusher.NewBatch()
+	batch.Put([]byte("a-delta"), []byte("delta"))
+	batch.Delete([]byte("a-base"))
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	// Batched writes must be visible while the disk is stalled, without bypassing it
+	if blob, err := flusher.Get([]byte("a-delta")); err != nil || !bytes.Equal(blob, []byte("delta")) {
+		t.Errorf("batched write mismatch: have %s (%v), want %s", blob, err, "delta")
+	}
+	if has, _ := flusher.Has([]byte("a-base")); has {
+		t.Errorf("batched deletion not visible")
+	}
+	if has, _ := db.Database.Has([]byte("a-delta")); has {
+		t.Errorf("batched write bypassed the queue")
+	}
+	// Iterating must wait for the queued writes to reach the disk
+	close(db.gate)
+
+	it := flusher.NewIterator([]byte("a-"), nil)
+	defer it.Release()
+
+	var keys []string
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	if len(keys) != 1 || keys[0] != "a-delta" {
+		t.Errorf("iterated keys mismatch: have %v, want %v", keys, []string{"a-delta"})
+	}
+	want := []string{"a-base", "a-delta", "a-base"}
+	if len(db.written) != len(want) {
+		t.Fatalf("write count mismatch: have %d, want %d", len(db.written), len(want))
+	}
+	for i := range want {
+		if string(db.written[i]) != want[i] {
+			t.Errorf("write %d mismatch: have %s, want %s", i, db.written[i], want[i])
+		}
+	}
+}
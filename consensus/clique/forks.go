@@ -0,0 +1,159 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// forkRetainDepth is the maximum number of blocks a reorg may abandon for
+	// their snapshots to be retained, in case the chain flips back to them.
+	forkRetainDepth = 2
+
+	// forkRetainWindow is the number of blocks below the chain head after which
+	// retained side fork snapshots are dropped, as no reorg would return to them.
+	forkRetainWindow = 16
+
+	// forkRetainLimit is the maximum number of side fork snapshots retained.
+	forkRetainLimit = 64
+)
+
+// forkTree retains the snapshots of the blocks abandoned by recent micro reorgs,
+// keyed by the hash of their parent. Competing signers sealing the same height
+// make the chain flip between sibling blocks, and the snapshots of the branch
+// flipped back to are picked up here instead of being derived afresh.
+type forkTree struct {
+	children map[common.Hash][]*Snapshot // Retained snapshots by the hash of their parent
+	count    int                         // Number of snapshots retained
+	lock     sync.Mutex
+}
+
+// newForkTree creates an empty side fork snapshot tree.
+func newForkTree() *forkTree {
+	return &forkTree{children: make(map[common.Hash][]*Snapshot)}
+}
+
+// add retains the snapshot of an abandoned block, dropping the snapshots that
+// fell out of the retention window of the given chain head, and the lowest ones
+// if still too many are retained.
+func (t *forkTree) add(parent common.Hash, snap *Snapshot, head uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, child := range t.children[parent] {
+		if child.Hash == snap.Hash {
+			return
+		}
+	}
+	t.children[parent] = append(t.children[parent], snap)
+	t.count++
+
+	floor := uint64(0)
+	if head > forkRetainWindow {
+		floor = head - forkRetainWindow
+	}
+	t.pruneBelow(floor)
+	for t.count > forkRetainLimit {
+		t.dropLowest()
+	}
+}
+
+// get retrieves and releases the retained snapshot of the block with the given
+// hash and parent, as the block is adopted into the chain again.
+func (t *forkTree) get(parent common.Hash, hash common.Hash) *Snapshot {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	children := t.children[parent]
+	for i, child := range children {
+		if child.Hash != hash {
+			continue
+		}
+		if len(children) == 1 {
+			delete(t.children, parent)
+		} else {
+			t.children[parent] = append(children[:i:i], children[i+1:]...)
+		}
+		t.count--
+		return child
+	}
+	return nil
+}
+
+// size returns the number of retained snapshots.
+func (t *forkTree) size() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.count
+}
+
+// purge drops all the retained snapshots.
+func (t *forkTree) purge() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.children, t.count = make(map[common.Hash][]*Snapshot), 0
+}
+
+// pruneBelow drops the retained snapshots of blocks below the given number. The
+// caller must hold the tree lock.
+func (t *forkTree) pruneBelow(number uint64) {
+	for parent, children := range t.children {
+		kept := children[:0]
+		for _, child := range children {
+			if child.Number >= number {
+				kept = append(kept, child)
+			}
+		}
+		t.count -= len(children) - len(kept)
+		if len(kept) == 0 {
+			delete(t.children, parent)
+		} else {
+			t.children[parent] = kept
+		}
+	}
+}
+
+// dropLowest drops the retained snapshot of the lowest block. The caller must
+// hold the tree lock.
+func (t *forkTree) dropLowest() {
+	var (
+		parent common.Hash
+		index  = -1
+	)
+	for hash, children := range t.children {
+		for i, child := range children {
+			if index < 0 || child.Number < t.children[parent][index].Number {
+				parent, index = hash, i
+			}
+		}
+	}
+	if index < 0 {
+		return
+	}
+	children := t.children[parent]
+	if len(children) == 1 {
+		delete(t.children, parent)
+	} else {
+		t.children[parent] = append(children[:index:index], children[index+1:]...)
+	}
+	t.count--
+}
@@ -0,0 +1,150 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the snapshots abandoned by a micro reorg are retained and reused
+// once the chain flips back to their blocks.
+func TestMicroReorgRetention(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		engine = New(params.AllCliqueProtocolChanges.Clique, db)
+	)
+	genspec := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	copy(genspec.ExtraData[extraVanity:], addr[:])
+	genesis := genspec.MustCommit(db)
+
+	// Generate competing branches, differing in their vanity
+	makeBranch := func(n int, vanity byte) []*types.Block {
+		blocks, _ := core.GenerateChain(params.AllCliqueProtocolChanges, genesis, engine, db, n, func(i int, block *core.BlockGen) {
+			block.SetDifficulty(diffInTurn)
+		})
+		for i, block := range blocks {
+			header := block.Header()
+			if i > 0 {
+				header.ParentHash = blocks[i-1].Hash()
+			}
+			header.Extra = make([]byte, extraVanity+extraSeal)
+			header.Extra[0] = vanity
+			header.Difficulty = diffInTurn
+
+			sig, _ := crypto.Sign(SealHash(header).Bytes(), key)
+			copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+			blocks[i] = block.WithSeal(header)
+		}
+		return blocks
+	}
+	first, second := makeBranch(4, 0x01), makeBranch(3, 0x02)
+
+	chain, _ := core.NewBlockChain(db, nil, params.AllCliqueProtocolChanges, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	engine.NewChainHead(chain, chain.CurrentHeader())
+	if _, err := chain.InsertChain(first[:2]); err != nil {
+		t.Fatalf("failed to insert initial branch: %v", err)
+	}
+	engine.NewChainHead(chain, chain.CurrentHeader())
+
+	retained := make(map[common.Hash]*Snapshot)
+	for _, block := range first[:2] {
+		snap, ok := engine.recents.Peek(block.Hash())
+		if !ok {
+			t.Fatalf("snapshot %d missing before reorg", block.NumberU64())
+		}
+		retained[block.Hash()] = snap
+	}
+	// Reorg two blocks deep onto the competing branch, retaining the snapshots
+	if _, err := chain.InsertChain(second); err != nil {
+		t.Fatalf("failed to insert competing branch: %v", err)
+	}
+	engine.NewChainHead(chain, chain.CurrentHeader())
+	if size := engine.forks.size(); size != 2 {
+		t.Fatalf("retained snapshot count mismatch: have %d, want 2", size)
+	}
+	for _, block := range first[:2] {
+		if engine.recents.Contains(block.Hash()) {
+			t.Errorf("snapshot %d of abandoned branch still cached", block.NumberU64())
+		}
+	}
+	// Flip back three blocks deep, reusing the retained snapshots but not keeping
+	// the ones abandoned now
+	if _, err := chain.InsertChain(first[2:]); err != nil {
+		t.Fatalf("failed to extend initial branch: %v", err)
+	}
+	engine.NewChainHead(chain, chain.CurrentHeader())
+	if head := chain.CurrentBlock().Hash(); head != first[len(first)-1].Hash() {
+		t.Fatalf("chain head mismatch: have %x, want %x", head, first[len(first)-1].Hash())
+	}
+	for hash, want := range retained {
+		if have, _ := engine.recents.Peek(hash); have != want {
+			t.Errorf("snapshot %x not reused from the retained side fork", hash)
+		}
+	}
+	if size := engine.forks.size(); size != 0 {
+		t.Errorf("retained snapshot count mismatch: have %d, want 0", size)
+	}
+}
+
+// Tests that the retained side fork snapshots are bounded by the window below
+// the chain head and by their number.
+func TestForkTreePruning(t *testing.T) {
+	tree := newForkTree()
+	for i := uint64(1); i <= 10; i++ {
+		tree.add(common.Hash{byte(i)}, &Snapshot{Number: i, Hash: common.Hash{0xff, byte(i)}}, 10)
+	}
+	if size := tree.size(); size != 10 {
+		t.Fatalf("retained snapshot count mismatch: have %d, want 10", size)
+	}
+	// Advancing the head drops the snapshots falling out of the window
+	tree.add(common.Hash{20}, &Snapshot{Number: 20, Hash: common.Hash{0xff, 20}}, 20)
+	if size := tree.size(); size != 8 {
+		t.Fatalf("retained snapshot count mismatch: have %d, want 8", size)
+	}
+	if tree.get(common.Hash{3}, common.Hash{0xff, 3}) != nil {
+		t.Errorf("snapshot below the window retained")
+	}
+	if tree.get(common.Hash{4}, common.Hash{0xff, 4}) == nil {
+		t.Errorf("snapshot within the window dropped")
+	}
+	// Exceeding the limit drops the lowest snapshots
+	for i := uint64(0); i < forkRetainLimit; i++ {
+		tree.add(common.Hash{0xee, byte(i)}, &Snapshot{Number: 20, Hash: common.Hash{0xee, byte(i)}}, 20)
+	}
+	if size := tree.size(); size != forkRetainLimit {
+		t.Errorf("retained snapshot count mismatch: have %d, want %d", size, forkRetainLimit)
+	}
+	if tree.get(common.Hash{5}, common.Hash{0xff, 5}) != nil {
+		t.Errorf("lowest snapshot retained above the limit")
+	}
+}
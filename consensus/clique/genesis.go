@@ -0,0 +1,56 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// GenesisSigners validates the extra-data of a clique genesis header and returns
+// the initial signers embedded in it. The genesis must carry the vanity, a non
+// empty list of signers in strictly ascending order and an empty seal, without
+// any of the optional checkpoint sections, which the engine can't assemble for a
+// block it didn't create.
+func GenesisSigners(config *params.CliqueConfig, genesis *types.Header) ([]common.Address, error) {
+	if len(genesis.Extra) < extraVanity {
+		return nil, errMissingVanity
+	}
+	if len(genesis.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	if !bytes.Equal(genesis.Extra[len(genesis.Extra)-extraSeal:], make([]byte, extraSeal)) {
+		return nil, errInvalidCheckpointSigners
+	}
+	list, aggregate, root := splitCheckpoint(config, genesis)
+	if len(list) == 0 || len(list)%common.AddressLength != 0 || aggregate != nil || root != nil {
+		return nil, errInvalidCheckpointSigners
+	}
+	signers, err := checkpointSigners(config, genesis)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(signers); i++ {
+		if bytes.Compare(signers[i-1][:], signers[i][:]) >= 0 {
+			return nil, errInvalidCheckpointSigners
+		}
+	}
+	return signers, nil
+}
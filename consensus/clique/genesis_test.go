@@ -0,0 +1,71 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that only well formed genesis extra-data is accepted, yielding the
+// initial signers in the order they are embedded.
+func TestGenesisSigners(t *testing.T) {
+	config := &params.CliqueConfig{Epoch: 30000, SnapshotRootBlock: big.NewInt(0)}
+
+	extra := func(payload []byte, seal byte) []byte {
+		return append(append(make([]byte, extraVanity), payload...), bytes.Repeat([]byte{seal}, extraSeal)...)
+	}
+	ordered := append(common.Address{0x01}.Bytes(), common.Address{0x02}.Bytes()...)
+	reversed := append(common.Address{0x02}.Bytes(), common.Address{0x01}.Bytes()...)
+	duplicate := append(common.Address{0x01}.Bytes(), common.Address{0x01}.Bytes()...)
+	rooted := append(common.CopyBytes(ordered), encodeSnapshotRoot(snapshotRootVersion, common.Hash{0x03})...)
+
+	tests := []struct {
+		extra []byte
+		fail  bool
+	}{
+		{extra(ordered, 0x00), false},
+		{extra(nil, 0x00), true},
+		{extra(reversed, 0x00), true},
+		{extra(duplicate, 0x00), true},
+		{extra(ordered, 0xff), true},
+		{extra(rooted, 0x00), true},
+		{extra(ordered[:30], 0x00), true},
+		{make([]byte, extraVanity), true},
+	}
+	for i, tt := range tests {
+		signers, err := GenesisSigners(config, &types.Header{Number: big.NewInt(0), Extra: tt.extra})
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: malformed genesis accepted", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: valid genesis rejected: %v", i, err)
+			continue
+		}
+		if len(signers) != 2 || signers[0] != (common.Address{0x01}) || signers[1] != (common.Address{0x02}) {
+			t.Errorf("test %d: signers mismatch: have %x", i, signers)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// GovernanceAddress is the reserved account governance transactions are sent to.
+// Their data is a proposal kind byte followed by the account voted on, or by the
+// signer limit encoded the same way as in the coinbase of limit votes.
+var GovernanceAddress = common.HexToAddress("0x000000000000000000000000000000000000c119")
+
+const (
+	governanceTxLength   = 1 + common.AddressLength                  // Length of the governance transaction data
+	governanceVoteLength = common.AddressLength + governanceTxLength // Length of a vote in the header extra-data
+)
+
+// errInvalidGovernanceVotes is returned if the governance votes embedded into a
+// block header don't match the governance transactions of the block.
+var errInvalidGovernanceVotes = errors.New("governance votes don't match transactions")
+
+// GovernanceVote is a vote cast by a signer through a governance transaction.
+type GovernanceVote struct {
+	Signer common.Address // Sender of the governance transaction
+	Kind   ProposalKind   // Type of the proposal voted on
+	Target common.Address // Account voted on, or the encoded signer limit
+}
+
+// GovernanceTxData returns the data of a governance transaction voting on the
+// given proposal.
+func GovernanceTxData(kind ProposalKind, target common.Address) []byte {
+	return append([]byte{byte(kind)}, target[:]...)
+}
+
+// decodeGovernanceTx decodes the proposal voted on by a governance transaction,
+// returning false if the data isn't a valid vote.
+func decodeGovernanceTx(data []byte) (ProposalKind, common.Address, bool) {
+	if len(data) != governanceTxLength {
+		return 0, common.Address{}, false
+	}
+	switch kind := ProposalKind(data[0]); kind {
+	case ProposalAuthorize, ProposalDeauthorize, ProposalSignerLimit, ProposalPermit, ProposalRevoke:
+		return kind, common.BytesToAddress(data[1:]), true
+	}
+	return 0, common.Address{}, false
+}
+
+// governanceVotes extracts the votes cast by the governance transactions of a
+// block, in transaction order. Transactions not carrying a valid vote are plain
+// transfers to the governance address.
+func governanceVotes(config *params.ChainConfig, number *big.Int, txs []*types.Transaction) ([]*GovernanceVote, error) {
+	var (
+		signer = types.MakeSigner(config, number)
+		votes  []*GovernanceVote
+	)
+	for _, tx := range txs {
+		if tx.To() == nil || *tx.To() != GovernanceAddress {
+			continue
+		}
+		kind, target, ok := decodeGovernanceTx(tx.Data())
+		if !ok {
+			continue
+		}
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		votes = append(votes, &GovernanceVote{Signer: sender, Kind: kind, Target: target})
+	}
+	return votes, nil
+}
+
+// encodeGovernanceVotes serializes governance votes into the header extra-data
+// section between the vanity and the seal.
+func encodeGovernanceVotes(votes []*GovernanceVote) []byte {
+	payload := make([]byte, 0, len(votes)*governanceVoteLength)
+	for _, vote := range votes {
+		payload = append(payload, vote.Signer[:]...)
+		payload = append(payload, GovernanceTxData(vote.Kind, vote.Target)...)
+	}
+	return payload
+}
+
+// decodeGovernanceVotes deserializes the governance votes embedded into a header.
+func decodeGovernanceVotes(config *params.CliqueConfig, header *types.Header) ([]*GovernanceVote, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	payload, _ := splitJustification(config, header)
+	if len(payload)%governanceVoteLength != 0 {
+		return nil, errInvalidGovernanceVotes
+	}
+	votes := make([]*GovernanceVote, 0, len(payload)/governanceVoteLength)
+	for i := 0; i < len(payload); i += governanceVoteLength {
+		kind, target, ok := decodeGovernanceTx(payload[i+common.AddressLength : i+governanceVoteLength])
+		if !ok {
+			return nil, errInvalidGovernanceVotes
+		}
+		votes = append(votes, &GovernanceVote{
+			Signer: common.BytesToAddress(payload[i : i+common.AddressLength]),
+			Kind:   kind,
+			Target: target,
+		})
+	}
+	return votes, nil
+}
+
+// governanceEnabled returns whether a header may carry governance votes.
+func governanceEnabled(config *params.CliqueConfig, number *big.Int) bool {
+	return config.IsGovernance(number) && number.Uint64()%config.Epoch != 0
+}
+
+// verifyGovernanceVotes checks that the governance votes embedded into the header
+// of a block are exactly the ones cast by its governance transactions.
+func verifyGovernanceVotes(chain consensus.ChainReader, config *params.CliqueConfig, block *types.Block) error {
+	if !governanceEnabled(config, block.Number()) {
+		return nil
+	}
+	// Votes on candidates without deposits are left out, which can only be checked
+	// against the state in VerifyState
+	if config.IsDeposit(block.Number()) {
+		return nil
+	}
+	votes, err := governanceVotes(chain.Config(), block.Number(), block.Transactions())
+	if err != nil {
+		return err
+	}
+	if payload, _ := splitJustification(config, block.Header()); !bytes.Equal(payload, encodeGovernanceVotes(votes)) {
+		return errInvalidGovernanceVotes
+	}
+	return nil
+}
+
+// embedGovernanceVotes embeds the governance votes cast by the transactions of a
+// block being assembled into its header, ahead of any finality justification.
+// Votes authorizing candidates without deposits are left out.
+func (c *Clique) embedGovernanceVotes(chain consensus.ChainHeaderReader, header *types.Header, txs []*types.Transaction) error {
+	config := c.config
+	if !governanceEnabled(config, header.Number) {
+		return nil
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errMissingSignature
+	}
+	votes, err := governanceVotes(chain.Config(), header.Number, txs)
+	if err != nil {
+		return err
+	}
+	if config.IsDeposit(header.Number) {
+		snap, err := c.snapshotFor(triggerSealing, chain, header.Number.Uint64()-1, header.ParentHash, nil)
+		if err != nil {
+			return err
+		}
+		votes = snap.eligibleGovernanceVotes(header.Number, c.deposits.state(header.ParentHash), votes)
+	}
+	var (
+		payload = encodeGovernanceVotes(votes)
+		rest, _ = splitJustification(config, header)
+		trailer = headerPayload(config, header)[len(rest):]
+		limit   = payloadLimit(config, header)
+	)
+	extra := make([]byte, 0, extraVanity+len(payload)+len(trailer)+extraSeal)
+	extra = append(extra, header.Extra[:extraVanity]...)
+	extra = append(extra, payload...)
+	extra = append(extra, trailer...)
+	header.Extra = append(extra, make([]byte, extraSeal)...)
+
+	structureExtra(config, header, limit)
+	return nil
+}
+
+// applyGovernanceVotes tallies up the votes embedded into a header that were cast
+// by authorized signers through governance transactions.
+func (s *Snapshot) applyGovernanceVotes(header *types.Header) error {
+	if !governanceEnabled(s.config, header.Number) {
+		return nil
+	}
+	votes, err := decodeGovernanceVotes(s.config, header)
+	if err != nil {
+		return err
+	}
+	number, hash := header.Number.Uint64(), header.Hash()
+	for _, vote := range votes {
+		if _, ok := s.Signers[vote.Signer]; !ok || s.onProbation(vote.Signer, number) {
+			continue
+		}
+		switch vote.Kind {
+		case ProposalSignerLimit:
+			if _, ok := s.decodeSignerLimit(vote.Target); !ok {
+				continue
+			}
+			s.uncastLimitVote(vote.Signer, vote.Target)
+			s.applyLimitVote(number, hash, vote.Signer, vote.Target)
+		case ProposalPermit, ProposalRevoke:
+			if s.config.IsPermissioned(header.Number) {
+				s.applyPermitVote(number, hash, vote.Signer, vote.Target, vote.Kind == ProposalPermit)
+			}
+		default:
+			s.applyVote(number, hash, vote.Signer, vote.Target, vote.Kind == ProposalAuthorize)
+		}
+	}
+	return nil
+}
+
+// ExemptsFee implements consensus.FeeExempter, exempting the governance votes of
+// the authorized signers from the gas price rules, so governance never competes
+// with user traffic on busy chains. Votes transferring value aren't exempt.
+func (c *Clique) ExemptsFee(chain consensus.ChainHeaderReader, parent *types.Header, sender common.Address, tx *types.Transaction) bool {
+	if !c.config.IsGovernance(new(big.Int).Add(parent.Number, common.Big1)) {
+		return false
+	}
+	if tx.To() == nil || *tx.To() != GovernanceAddress || tx.Value().Sign() != 0 {
+		return false
+	}
+	if _, _, ok := decodeGovernanceTx(tx.Data()); !ok {
+		return false
+	}
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return false
+	}
+	_, ok := snap.Signers[sender]
+	return ok && !snap.onProbation(sender, parent.Number.Uint64()+1)
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that votes cast by signers through governance transactions are embedded
+// into the header, verified against the block body and tallied up along with the
+// header votes.
+func TestGovernanceVotes(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50, GovernanceBlock: big.NewInt(1)}
+		signers  = []string{"A", "B", "C"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+	engine := New(config, rawdb.NewMemoryDatabase())
+
+	// Vote on D through transactions of two signers and an outsider
+	var (
+		txSigner = types.MakeSigner(chain.config, common.Big1)
+		txs      []*types.Transaction
+	)
+	for _, sender := range []string{"B", "E", "C"} {
+		accounts.address(sender)
+		tx := types.NewTransaction(0, GovernanceAddress, new(big.Int), 50000, big.NewInt(1), GovernanceTxData(ProposalAuthorize, accounts.address("D")))
+		tx, _ = types.SignTx(tx, txSigner, accounts.accounts[sender])
+		txs = append(txs, tx)
+	}
+	// Include a plain transfer and a malformed vote, neither of which may count
+	txs = append(txs, types.NewTransaction(1, GovernanceAddress, big.NewInt(1), 50000, big.NewInt(1), nil))
+	txs = append(txs, types.NewTransaction(2, GovernanceAddress, big.NewInt(1), 50000, big.NewInt(1), []byte{0xff}))
+
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     common.Big1,
+		Difficulty: diffNoTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	copy(header.Nonce[:], nonceDropVote)
+	if err := engine.embedGovernanceVotes(chain, header, txs); err != nil {
+		t.Fatalf("failed to embed governance votes: %v", err)
+	}
+	if have, want := len(header.Extra), extraVanity+3*governanceVoteLength+extraSeal; have != want {
+		t.Fatalf("extra-data length mismatch: have %d, want %d", have, want)
+	}
+	accounts.sign(header, "A")
+	chain.headers = append(chain.headers, header)
+
+	block := types.NewBlock(header, txs, nil, nil, trie.NewStackTrie(nil))
+	if err := engine.VerifyUncles(chain, block); err != nil {
+		t.Fatalf("failed to verify governance votes: %v", err)
+	}
+	if err := engine.VerifyUncles(chain, types.NewBlock(header, txs[1:], nil, nil, trie.NewStackTrie(nil))); err != errInvalidGovernanceVotes {
+		t.Fatalf("tampered votes error mismatch: have %v, want %v", err, errInvalidGovernanceVotes)
+	}
+	// Ensure only the signers' votes were counted, authorizing D
+	snap, err := engine.snapshot(chain, 1, header.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if !snap.IsSigner(accounts.address("D")) {
+		t.Errorf("signer not authorized by governance votes")
+	}
+	if len(snap.resolutions) != 1 || len(snap.resolutions[0].Votes) != 2 {
+		t.Errorf("resolution mismatch: %+v", snap.resolutions)
+	}
+}
+
+// Tests that only the value-less governance votes of authorized signers are
+// exempt from the gas price rules.
+func TestGovernanceFeeExemption(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50, GovernanceBlock: big.NewInt(1)}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B"}, 0)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+		txSigner = types.MakeSigner(chain.config, common.Big1)
+	)
+	vote := GovernanceTxData(ProposalAuthorize, accounts.address("C"))
+	tests := []struct {
+		sender string
+		to     common.Address
+		value  int64
+		data   []byte
+		exempt bool
+	}{
+		{"A", GovernanceAddress, 0, vote, true},
+		{"C", GovernanceAddress, 0, vote, false},         // Outsider
+		{"A", GovernanceAddress, 1, vote, false},         // Value transfer
+		{"A", GovernanceAddress, 0, []byte{0xff}, false}, // Malformed vote
+		{"A", accounts.address("C"), 0, vote, false},     // Not a governance transaction
+	}
+	for i, tt := range tests {
+		tx, _ := types.SignTx(types.NewTransaction(0, tt.to, big.NewInt(tt.value), 50000, new(big.Int), tt.data), txSigner, accounts.accounts[tt.sender])
+		if exempt := engine.ExemptsFee(chain, chain.headers[0], accounts.address(tt.sender), tx); exempt != tt.exempt {
+			t.Errorf("test %d: exemption mismatch: have %v, want %v", i, exempt, tt.exempt)
+		}
+	}
+	// Nothing is exempt before governance transactions are enabled
+	engine = New(&params.CliqueConfig{Epoch: 30000, SignerLimit: 50, GovernanceBlock: big.NewInt(2)}, rawdb.NewMemoryDatabase())
+	tx, _ := types.SignTx(types.NewTransaction(0, GovernanceAddress, new(big.Int), 50000, new(big.Int), vote), txSigner, accounts.accounts["A"])
+	if engine.ExemptsFee(chain, chain.headers[0], accounts.address("A"), tx) {
+		t.Errorf("governance vote exempt before the fork")
+	}
+}
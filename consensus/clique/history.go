@@ -0,0 +1,128 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Types of the entries in the governance history of a chain.
+const (
+	HistoryVote       = "vote"       // Vote counted in a block
+	HistoryResolution = "resolution" // Proposal that passed in a block
+	HistoryLimit      = "limit"      // Signer limit change, by a passing vote or an epoch revert
+)
+
+// GovernanceRecord is a single entry in the governance history of a chain, flat
+// so it can be exported both as JSON and as CSV for offline analysis.
+type GovernanceRecord struct {
+	Type      string           `json:"type"`                // Type of the entry (vote, resolution or limit)
+	Block     uint64           `json:"block"`               // Block number the entry happened in
+	Hash      common.Hash      `json:"hash"`                // Block hash the entry happened in
+	Time      uint64           `json:"time"`                // Timestamp of the block the entry happened in
+	Kind      ProposalKind     `json:"kind"`                // Type of the proposal concerned
+	Signer    common.Address   `json:"signer"`              // Signer that cast the vote (votes)
+	Address   common.Address   `json:"address"`             // Account voted on or whose authorization changed
+	Limit     uint             `json:"limit,omitempty"`     // Signer limit voted on or installed
+	PrevLimit uint             `json:"prevLimit,omitempty"` // Signer limit before the change (limit changes)
+	Period    uint64           `json:"period,omitempty"`    // Minimum block period voted on or granted (period votes)
+	Replaced  common.Address   `json:"replaced,omitempty"`  // Signer swapped for the account (replacement votes)
+	Signers   []common.Address `json:"signers,omitempty"`   // Signer set installed by an override
+	Votes     int              `json:"votes"`               // Running tally of a vote, or number of votes passing a proposal
+	Passed    bool             `json:"passed,omitempty"`    // Whether the vote made the proposal pass (votes)
+	Memo      string           `json:"memo,omitempty"`      // Justification attached to the proposal when queued locally
+}
+
+// GovernanceHistory replays the canonical headers between the given blocks (both
+// inclusive) and reports every vote counted, proposal passed and signer limit
+// changed in them, in chain order. The callback may abort the walk by returning
+// an error, which is passed on to the caller.
+func (c *Clique) GovernanceHistory(chain consensus.ChainHeaderReader, from, to uint64, fn func(*GovernanceRecord) error) error {
+	if from == 0 {
+		from = 1 // The genesis doesn't carry any votes
+	}
+	parent := chain.GetHeaderByNumber(from - 1)
+	if parent == nil {
+		return errUnknownBlock
+	}
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	for number := from; number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil || header.ParentHash != parent.Hash() {
+			return errUnknownBlock
+		}
+		if snap, err = snap.apply([]*types.Header{header}); err != nil {
+			return err
+		}
+		for _, record := range governanceRecords(snap, header) {
+			if record.Kind == ProposalAuthorize || record.Kind == ProposalDeauthorize {
+				record.Memo = findMemo(c.db, record.Address, record.Kind == ProposalAuthorize, record.Block)
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		parent = header
+	}
+	return nil
+}
+
+// governanceRecords converts the votes counted and proposals resolved by applying
+// a header into governance history entries.
+func governanceRecords(snap *Snapshot, header *types.Header) []*GovernanceRecord {
+	records := make([]*GovernanceRecord, 0, len(snap.observed)+len(snap.resolutions))
+	for _, vote := range snap.observed {
+		records = append(records, &GovernanceRecord{
+			Type:     HistoryVote,
+			Block:    vote.Block,
+			Hash:     vote.Hash,
+			Time:     header.Time,
+			Kind:     vote.Kind,
+			Signer:   vote.Signer,
+			Address:  vote.Address,
+			Limit:    vote.Limit,
+			Period:   vote.Period,
+			Replaced: vote.Replaced,
+			Votes:    vote.Votes,
+			Passed:   vote.Passed,
+		})
+	}
+	for _, res := range snap.resolutions {
+		record := &GovernanceRecord{
+			Type:     HistoryResolution,
+			Block:    res.Block,
+			Hash:     res.Hash,
+			Time:     header.Time,
+			Kind:     res.Kind,
+			Address:  res.Address,
+			Signers:  res.Signers,
+			Period:   res.Period,
+			Replaced: res.Replaced,
+			Votes:    len(res.Votes),
+		}
+		if res.Kind == ProposalSignerLimit {
+			record.Type, record.Limit, record.PrevLimit = HistoryLimit, res.Limit, res.PrevLimit
+		}
+		records = append(records, record)
+	}
+	return records
+}
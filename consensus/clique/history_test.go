@@ -0,0 +1,98 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the governance history replays the votes and resolutions of the
+// requested block range in chain order.
+func TestGovernanceHistory(t *testing.T) {
+	accounts := newTesterAccountPool()
+	signers := []string{"A", "B"}
+	config := &params.CliqueConfig{Period: 1, Epoch: 30000}
+
+	genesis := &types.Header{
+		Number: big.NewInt(0),
+		Extra:  make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	// Have both signers vote in C, the second vote passing the proposal
+	for i, signer := range []string{"A", "B", "A"} {
+		parent := chain.headers[i]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Time:       parent.Time + 1,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i < 2 {
+			header.Coinbase = accounts.address("C")
+			copy(header.Nonce[:], nonceAuthVote)
+		}
+		accounts.sign(header, signer)
+		chain.headers = append(chain.headers, header)
+	}
+	engine := New(config, rawdb.NewMemoryDatabase())
+
+	var records []*GovernanceRecord
+	if err := engine.GovernanceHistory(chain, 0, 3, func(record *GovernanceRecord) error {
+		records = append(records, record)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to export governance history: %v", err)
+	}
+	want := []struct {
+		kind   string
+		block  uint64
+		signer common.Address
+		passed bool
+	}{
+		{HistoryVote, 1, accounts.address("A"), false},
+		{HistoryVote, 2, accounts.address("B"), true},
+		{HistoryResolution, 2, common.Address{}, false},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), len(want))
+	}
+	for i, record := range records {
+		if record.Type != want[i].kind || record.Block != want[i].block || record.Signer != want[i].signer || record.Passed != want[i].passed {
+			t.Errorf("record %d mismatch: have %s/%d/%x/%v, want %s/%d/%x/%v", i, record.Type, record.Block, record.Signer, record.Passed, want[i].kind, want[i].block, want[i].signer, want[i].passed)
+		}
+		if record.Address != accounts.address("C") || record.Time != chain.headers[record.Block].Time {
+			t.Errorf("record %d subject mismatch: have %x at %d", i, record.Address, record.Time)
+		}
+	}
+	// Walks starting mid-range must only report the later entries
+	records = records[:0]
+	engine.GovernanceHistory(chain, 2, 2, func(record *GovernanceRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if len(records) != 2 {
+		t.Errorf("partial record count mismatch: have %d, want %d", len(records), 2)
+	}
+}
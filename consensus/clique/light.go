@@ -0,0 +1,189 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errMismatchingCheckpointLimit is returned if a checkpoint block commits to a
+// signer limit different than the one the local node calculated.
+var errMismatchingCheckpointLimit = errors.New("mismatching signer limit on checkpoint block")
+
+// limitCommitment encodes the signer limit state in force after a checkpoint into
+// the mix digest of the checkpoint header, so that nodes starting from a trusted
+// checkpoint (e.g. light clients) can resume voting without replaying history.
+//
+//	limit (8 bytes, big endian) | affirmed (8 bytes, big endian) | zeroes
+func limitCommitment(limit uint, affirmed uint64) common.Hash {
+	var commitment common.Hash
+	binary.BigEndian.PutUint64(commitment[:8], uint64(limit))
+	binary.BigEndian.PutUint64(commitment[8:16], affirmed)
+	return commitment
+}
+
+// decodeLimitCommitment extracts the signer limit state committed into the mix
+// digest of a checkpoint header.
+func decodeLimitCommitment(commitment common.Hash) (uint, uint64) {
+	return uint(binary.BigEndian.Uint64(commitment[:8])), binary.BigEndian.Uint64(commitment[8:16])
+}
+
+// checkpointSnapshot creates a snapshot from a trusted checkpoint header, taking
+// the signer set from the extra-data and, if committed, the signer limit state
+// from the mix digest.
+func checkpointSnapshot(config *params.CliqueConfig, sigcache *SigCache, checkpoint *types.Header) (*Snapshot, error) {
+	signers, err := checkpointSigners(config, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	snap := newSnapshot(config, sigcache, checkpoint.Number.Uint64(), checkpoint.Hash(), signers)
+	if config.CheckpointLimit && checkpoint.Number.Uint64() > 0 {
+		snap.SignerLimit, snap.SignerLimitAffirmed = decodeLimitCommitment(checkpoint.MixDigest)
+	}
+	return snap, nil
+}
+
+// LightVerifier tracks the clique voting state from a trusted checkpoint onward,
+// verifying the consensus rules of a contiguous run of headers without needing
+// access to a header chain. It's meant for light clients and bridges following a
+// chain header by header.
+type LightVerifier struct {
+	config *params.CliqueConfig
+	snap   *Snapshot
+	head   *types.Header
+}
+
+// NewLightVerifier creates a header verifier anchored at a trusted checkpoint.
+func NewLightVerifier(config *params.CliqueConfig, checkpoint *types.Header) (*LightVerifier, error) {
+	conf := *config
+	if conf.Epoch == 0 {
+		conf.Epoch = epochLength
+	}
+	if conf.SignerLimit == 0 {
+		conf.SignerLimit = defaultSignerLimit
+	}
+	if checkpoint.Number.Uint64()%conf.Epoch != 0 {
+		return nil, errUnknownBlock
+	}
+	sigcache := NewSigCache(inmemorySignatures, nil)
+	snap, err := checkpointSnapshot(&conf, sigcache, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &LightVerifier{config: &conf, snap: snap, head: checkpoint}, nil
+}
+
+// Verify checks whether a header extending the last verified one conforms to the
+// consensus rules, and if so, applies it to the tracked voting state.
+func (v *LightVerifier) Verify(header *types.Header) error {
+	number := header.Number.Uint64()
+	if number != v.head.Number.Uint64()+1 || header.ParentHash != v.head.Hash() {
+		return consensus.ErrUnknownAncestor
+	}
+	if v.head.Time+v.config.Period > header.Time {
+		return errInvalidTimestamp
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errMissingSignature
+	}
+	if err := verifyExtraPayload(v.config, header); err != nil {
+		return err
+	}
+	checkpoint := number%v.config.Epoch == 0
+	if !checkpoint && len(headerPayload(v.config, header)) != 0 {
+		return errExtraSigners
+	}
+	if header.MixDigest != (common.Hash{}) && !(checkpoint && v.config.CheckpointLimit) {
+		return errInvalidMixDigest
+	}
+	if checkpoint && header.Coinbase != (common.Address{}) {
+		return errInvalidCheckpointBeneficiary
+	}
+	nonces := v.snap.voteNonces()
+	if checkpoint && !isOverride(header) && header.Nonce != nonces.drop {
+		return errInvalidCheckpointVote
+	}
+	if header.Nonce == nonces.limit {
+		if _, ok := v.snap.decodeSignerLimit(header.Coinbase); !ok {
+			return errInvalidSignerLimit
+		}
+	}
+	// Verify the checkpoint commitments against the parent state
+	if checkpoint {
+		if err := verifyCheckpoint(v.snap, header); err != nil {
+			return err
+		}
+	}
+	// Ensure the difficulty matches the turn-ness of the signer, then apply
+	sealing, err := v.snap.overridden(header)
+	if err != nil {
+		return err
+	}
+	signer, err := ecrecover(header, v.snap.sigcache)
+	if err != nil {
+		return err
+	}
+	if want := calcDifficulty(sealing, signer); header.Difficulty == nil || header.Difficulty.Cmp(want) != 0 {
+		return errWrongDifficulty
+	}
+	snap, err := v.snap.apply([]*types.Header{header})
+	if err != nil {
+		return err
+	}
+	snap.frozen = true
+	v.snap, v.head = snap, header
+	return nil
+}
+
+// Snapshot returns the voting state after the last verified header.
+func (v *LightVerifier) Snapshot() *Snapshot {
+	return v.snap
+}
+
+// verifyCheckpoint checks the signer list, signer limit, aggregate attestation and
+// snapshot root commitments of a checkpoint header against the state of its parent.
+func verifyCheckpoint(snap *Snapshot, header *types.Header) error {
+	number := header.Number.Uint64()
+	if !isOverride(header) {
+		signers := make([]byte, len(snap.Signers)*common.AddressLength)
+		for i, signer := range snap.signers() {
+			copy(signers[i*common.AddressLength:], signer[:])
+		}
+		if list, _, _ := splitCheckpoint(snap.config, header); !bytes.Equal(list, signers) {
+			return errMismatchingCheckpointSigners
+		}
+	}
+	if err := verifyAggregate(snap, header); err != nil {
+		return err
+	}
+	if err := verifySnapshotRoot(snap, header); err != nil {
+		return err
+	}
+	if snap.config.CheckpointLimit {
+		if header.MixDigest != limitCommitment(snap.epochLimit(number)) {
+			return errMismatchingCheckpointLimit
+		}
+	}
+	return nil
+}
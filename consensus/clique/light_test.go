@@ -0,0 +1,101 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the light verifier tracks signer limit changes across checkpoints
+// and that checkpoints commit to the signer limit in force.
+func TestLightVerifier(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		config = &params.CliqueConfig{Epoch: 3, CheckpointLimit: true}
+	)
+	// Create a header builder for a single signer chain
+	makeHeader := func(parent *types.Header, limit uint, mix common.Hash) *types.Header {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       parent.Time + 1,
+			Difficulty: diffInTurn,
+			MixDigest:  mix,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if limit != 0 {
+			header.Coinbase = common.BigToAddress(new(big.Int).SetUint64(uint64(limit)))
+			copy(header.Nonce[:], nonceSignerLimitAuthVote)
+		}
+		if header.Number.Uint64()%config.Epoch == 0 {
+			header.Extra = append(append(make([]byte, extraVanity), addr[:]...), make([]byte, extraSeal)...)
+		}
+		sig, _ := crypto.Sign(SealHash(header).Bytes(), key)
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		return header
+	}
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      append(append(make([]byte, extraVanity), addr[:]...), make([]byte, extraSeal)...),
+	}
+	verifier, err := NewLightVerifier(config, genesis)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	// Change the signer limit and ensure a checkpoint must commit to it
+	headers := []*types.Header{makeHeader(genesis, 66, common.Hash{})}
+	headers = append(headers, makeHeader(headers[0], 0, common.Hash{}))
+
+	for _, header := range headers {
+		if err := verifier.Verify(header); err != nil {
+			t.Fatalf("header %d: failed to verify: %v", header.Number, err)
+		}
+	}
+	if limit := verifier.Snapshot().Limit(); limit != 66 {
+		t.Fatalf("signer limit mismatch: have %d, want %d", limit, 66)
+	}
+	if err := verifier.Verify(makeHeader(headers[1], 0, limitCommitment(50, 0))); err != errMismatchingCheckpointLimit {
+		t.Fatalf("stale checkpoint commitment error mismatch: have %v, want %v", err, errMismatchingCheckpointLimit)
+	}
+	checkpoint := makeHeader(headers[1], 0, limitCommitment(66, 1))
+	if err := verifier.Verify(checkpoint); err != nil {
+		t.Fatalf("failed to verify checkpoint: %v", err)
+	}
+	// Anchor a new verifier at the checkpoint and ensure it resumes the limit
+	resumed, err := NewLightVerifier(config, checkpoint)
+	if err != nil {
+		t.Fatalf("failed to create resumed verifier: %v", err)
+	}
+	if limit := resumed.Snapshot().Limit(); limit != 66 {
+		t.Errorf("resumed signer limit mismatch: have %d, want %d", limit, 66)
+	}
+	next := makeHeader(checkpoint, 0, common.Hash{})
+	if err := resumed.Verify(next); err != nil {
+		t.Errorf("resumed verifier failed to verify: %v", err)
+	}
+	if err := verifier.Verify(next); err != nil {
+		t.Errorf("original verifier failed to verify: %v", err)
+	}
+}
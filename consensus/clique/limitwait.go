@@ -0,0 +1,48 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import "fmt"
+
+// LimitWaitError is returned when a signer limit vote is refused because the
+// same limit passed recently, and voting on it is barred until every signer had
+// a chance to seal.
+type LimitWaitError struct {
+	Limit  uint   `json:"limit"`  // Signer limit voted on
+	Reopen uint64 `json:"reopen"` // First block in which the limit may be voted on again
+}
+
+// Error implements the error interface.
+func (e *LimitWaitError) Error() string {
+	return fmt.Sprintf("signer limit %d changed recently, voting reopens at block %d", e.Limit, e.Reopen)
+}
+
+// ErrorData implements rpc.DataError, exposing the block at which voting on the
+// limit reopens to RPC callers.
+func (e *LimitWaitError) ErrorData() interface{} {
+	return e
+}
+
+// limitWait returns the cooldown barring a vote on the given signer limit in the
+// given block after a recent change of the limit, or nil if it may be voted on.
+func (s *Snapshot) limitWait(limit uint, number uint64) *LimitWaitError {
+	wait, ok := s.SignerLimitWait[uint64(limit)]
+	if !ok || wait.Block < number {
+		return nil
+	}
+	return &LimitWaitError{Limit: limit, Reopen: wait.Block + 1}
+}
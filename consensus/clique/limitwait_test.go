@@ -0,0 +1,71 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that voting on a signer limit during the cooldown following its change
+// is refused with an error telling the block at which voting reopens.
+func TestSignerLimitWait(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B"}, 2)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+		api      = &API{chain: chain, clique: engine}
+	)
+	// Pass a limit of 75% in the first two blocks, resealing the chain
+	for i, header := range chain.headers[1:] {
+		header.ParentHash = chain.headers[i].Hash()
+		header.Coinbase = common.BigToAddress(big.NewInt(75))
+		copy(header.Nonce[:], nonceSignerLimitAuthVote)
+		accounts.sign(header, []string{"A", "B"}[(i+1)%2])
+	}
+	_, err := api.Votingpercentage(context.Background(), 0, 75, true)
+
+	var wait *LimitWaitError
+	if !errors.As(err, &wait) {
+		t.Fatalf("cooldown vote error mismatch: have %v, want %T", err, wait)
+	}
+	if wait.Limit != 75 || wait.Reopen != 5 {
+		t.Errorf("cooldown mismatch: have limit %d reopening at %d, want limit 75 reopening at 5", wait.Limit, wait.Reopen)
+	}
+	if data, ok := err.(interface{ ErrorData() interface{} }); !ok || data.ErrorData() != wait {
+		t.Errorf("cooldown error data missing")
+	}
+	// Other limits may still be voted on
+	if ok, err := api.Votingpercentage(context.Background(), 0, 60, true); !ok || err != nil {
+		t.Errorf("unrelated limit vote refused: %v", err)
+	}
+	// Voting on the changed limit reopens once every signer had a chance to seal
+	snap, err := engine.snapshot(chain, 2, chain.headers[2].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if snap.limitWait(75, 4) == nil || snap.limitWait(75, 5) != nil {
+		t.Errorf("cooldown window mismatch")
+	}
+}
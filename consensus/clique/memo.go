@@ -0,0 +1,93 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var memoPrefix = []byte("clique-memo-") // memoPrefix + address + num (uint64 big endian) -> memo
+
+// proposalMemo is the justification an operator attached to an authorization
+// proposal queued locally. Memos are kept after their proposals resolve or are
+// discarded, so the governance history can tell why an account was voted on.
+type proposalMemo struct {
+	Authorize bool   `json:"authorize"` // Whether the proposal authorized or deauthorized the account
+	Memo      string `json:"memo"`      // Free-form justification of the proposal
+}
+
+// memoKey = memoPrefix + address + num (uint64 big endian)
+func memoKey(address common.Address, number uint64) []byte {
+	key := append(append(append([]byte{}, memoPrefix...), address[:]...), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(key)-8:], number)
+	return key
+}
+
+// setMemo attaches a memo to a queued authorization proposal, recording it along
+// with the head block it was queued at. The caller must hold the engine lock.
+func (c *Clique) setMemo(address common.Address, auth bool, memo string, number uint64) {
+	info, ok := c.proposalInfo[address]
+	if !ok {
+		return
+	}
+	info.Memo = memo
+	c.proposalInfo[address] = info
+	c.storeProposals()
+
+	if c.db == nil {
+		return
+	}
+	blob, err := json.Marshal(&proposalMemo{Authorize: auth, Memo: memo})
+	if err != nil {
+		log.Warn("Failed to encode clique proposal memo", "err", err)
+		return
+	}
+	if err := c.db.Put(memoKey(address, number), blob); err != nil {
+		log.Warn("Failed to store clique proposal memo", "address", address, "err", err)
+	}
+}
+
+// findMemo retrieves the memo of the latest proposal on the account in the given
+// direction queued before the given block, if any.
+func findMemo(db ethdb.Iteratee, address common.Address, auth bool, number uint64) string {
+	if db == nil {
+		return ""
+	}
+	prefix := append(append([]byte{}, memoPrefix...), address[:]...)
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var found string
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+8 {
+			continue
+		}
+		if binary.BigEndian.Uint64(it.Key()[len(prefix):]) >= number {
+			break // Memos are ordered by the block they were queued at
+		}
+		var memo proposalMemo
+		if err := json.Unmarshal(it.Value(), &memo); err == nil && memo.Authorize == auth {
+			found = memo.Memo
+		}
+	}
+	return found
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+// SetSnapshotMemoryInterval sets the block interval of the voting snapshots kept
+// in the in-memory cache. Snapshots of the blocks in between are recomputed on
+// demand from the nearest cached one, letting memory-constrained nodes trade the
+// memory of the cache for the processing of up to interval-1 headers per lookup.
+// An interval of 0 or 1 keeps the snapshots of all the blocks.
+func (c *Clique) SetSnapshotMemoryInterval(interval uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.memoryInterval = interval
+}
+
+// snapshotMemoryInterval returns the block interval of the voting snapshots kept
+// in the in-memory cache, 1 if all of them are kept.
+func (c *Clique) snapshotMemoryInterval() uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.memoryInterval < 1 {
+		return 1
+	}
+	return c.memoryInterval
+}
+
+// cacheSnapshot publishes a snapshot, preventing any further modifications to
+// it, and keeps it in the in-memory cache if it's on the memory interval.
+func (c *Clique) cacheSnapshot(snap *Snapshot) {
+	if !snap.frozen {
+		snap.frozen = true
+	}
+	if snap.Number%c.snapshotMemoryInterval() == 0 {
+		c.recents.Add(snap.Hash, snap)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that only the snapshots on the memory interval are kept in memory, and
+// that the ones in between are still recomputed correctly.
+func TestSnapshotMemoryInterval(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 10)
+	)
+	full := New(config, rawdb.NewMemoryDatabase())
+	defer full.Close()
+
+	sparse := New(config, rawdb.NewMemoryDatabase())
+	defer sparse.Close()
+	sparse.SetSnapshotMemoryInterval(4)
+
+	if _, err := sparse.snapshot(chain, 10, chain.headers[10].Hash(), nil); err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	for number := 1; number < len(chain.headers); number++ {
+		if have, want := sparse.recents.Contains(chain.headers[number].Hash()), number%4 == 0; have != want {
+			t.Errorf("block %d: cached mismatch: have %v, want %v", number, have, want)
+		}
+	}
+	for _, number := range []uint64{10, 9, 6, 3} {
+		have, err := sparse.snapshot(chain, number, chain.headers[number].Hash(), nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to create sparse snapshot: %v", number, err)
+		}
+		want, err := full.snapshot(chain, number, chain.headers[number].Hash(), nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to create full snapshot: %v", number, err)
+		}
+		if !reflect.DeepEqual(have.Signers, want.Signers) || !reflect.DeepEqual(have.Recents, want.Recents) {
+			t.Errorf("block %d: snapshot mismatch: have %v/%v, want %v/%v", number, have.Signers, have.Recents, want.Signers, want.Recents)
+		}
+		if sparse.recents.Contains(have.Hash) != (number%4 == 0) {
+			t.Errorf("block %d: off-interval snapshot cached", number)
+		}
+	}
+}
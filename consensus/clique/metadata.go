@@ -0,0 +1,135 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// metadataPrefix is the database prefix the signer metadata registry is stored
+// under, followed by the signer address.
+var metadataPrefix = []byte("clique-metadata-")
+
+var (
+	// errInvalidMetadata is returned if a metadata registration carries a
+	// malformed signature.
+	errInvalidMetadata = errors.New("invalid metadata signature")
+
+	// errMetadataQuorum is returned if a metadata registration isn't co-signed by
+	// a strict majority of the current signers.
+	errMetadataQuorum = errors.New("metadata lacks signer majority")
+)
+
+// SignerMetadata is the operator information the signers agreed to associate with
+// a signer address, so consortium members can identify who is behind it.
+type SignerMetadata struct {
+	Name     string `json:"name"`     // Name of the operator running the signer
+	Contact  string `json:"contact"`  // Contact details of the operator
+	Endpoint string `json:"endpoint"` // Public RPC endpoint of the operator
+	Region   string `json:"region"`   // Region the signer is operated from
+}
+
+// MetadataHash returns the digest that the current signers need to sign offline
+// to approve associating the given metadata with a signer. Approving empty
+// metadata removes the signer from the registry.
+func MetadataHash(signer common.Address, metadata *SignerMetadata) common.Hash {
+	blob, err := rlp.EncodeToBytes([]interface{}{"clique-metadata", signer, metadata.Name, metadata.Contact, metadata.Endpoint, metadata.Region})
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return crypto.Keccak256Hash(blob)
+}
+
+// metadataKey = metadataPrefix + signer
+func metadataKey(signer common.Address) []byte {
+	return append(append([]byte{}, metadataPrefix...), signer[:]...)
+}
+
+// loadMetadata reloads the signer metadata registry from the database.
+func (c *Clique) loadMetadata() {
+	if c.db == nil {
+		return
+	}
+	it := c.db.NewIterator(metadataPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) != len(metadataPrefix)+common.AddressLength {
+			continue
+		}
+		metadata := new(SignerMetadata)
+		if err := json.Unmarshal(it.Value(), metadata); err != nil {
+			log.Warn("Failed to decode clique signer metadata", "err", err)
+			continue
+		}
+		c.metadata[common.BytesToAddress(it.Key()[len(metadataPrefix):])] = metadata
+	}
+}
+
+// registerMetadata verifies that the metadata of a signer was approved by a
+// strict majority of the signers in the snapshot and if so, stores it in the
+// registry, replacing any previous entry.
+func (c *Clique) registerMetadata(snap *Snapshot, signer common.Address, metadata *SignerMetadata, signatures [][]byte) error {
+	approved, ok := snap.approvals(MetadataHash(signer, metadata), signatures)
+	if !ok {
+		return errInvalidMetadata
+	}
+	if len(approved) <= len(snap.Signers)/2 {
+		return errMetadataQuorum
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if *metadata == (SignerMetadata{}) {
+		delete(c.metadata, signer)
+		if c.db != nil {
+			if err := c.db.Delete(metadataKey(signer)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	c.metadata[signer] = metadata
+	if c.db == nil {
+		return nil
+	}
+	blob, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return c.db.Put(metadataKey(signer), blob)
+}
+
+// signerMetadata returns a copy of the registry entries of the given signers.
+func (c *Clique) signerMetadata(signers []common.Address) map[common.Address]SignerMetadata {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	entries := make(map[common.Address]SignerMetadata)
+	for _, signer := range signers {
+		if metadata, ok := c.metadata[signer]; ok {
+			entries[signer] = *metadata
+		}
+	}
+	return entries
+}
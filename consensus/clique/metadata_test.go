@@ -0,0 +1,74 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that signer metadata is only registered if approved by a majority of the
+// signers, and that the registry survives an engine restart.
+func TestMetadataRegistry(t *testing.T) {
+	var (
+		db       = rawdb.NewMemoryDatabase()
+		config   = &params.CliqueConfig{Epoch: 30000}
+		accounts = newTesterAccountPool()
+	)
+	signers := []common.Address{accounts.address("A"), accounts.address("B"), accounts.address("C")}
+	snap := newSnapshot(config, nil, 0, common.Hash{}, signers)
+
+	metadata := &SignerMetadata{Name: "Operator A", Contact: "ops@a.example", Endpoint: "https://rpc.a.example", Region: "eu-west"}
+	sign := func(metadata *SignerMetadata, approvers ...string) [][]byte {
+		hash := MetadataHash(accounts.address("A"), metadata)
+
+		var sigs [][]byte
+		for _, approver := range approvers {
+			accounts.address(approver) // ensure the key exists
+			sig, _ := crypto.Sign(hash[:], accounts.accounts[approver])
+			sigs = append(sigs, sig)
+		}
+		return sigs
+	}
+	engine := New(config, db)
+	if err := engine.registerMetadata(snap, accounts.address("A"), metadata, sign(metadata, "A", "D")); err != errMetadataQuorum {
+		t.Fatalf("minority registration error mismatch: have %v, want %v", err, errMetadataQuorum)
+	}
+	if err := engine.registerMetadata(snap, accounts.address("A"), metadata, [][]byte{{0x01}}); err != errInvalidMetadata {
+		t.Fatalf("malformed registration error mismatch: have %v, want %v", err, errInvalidMetadata)
+	}
+	if err := engine.registerMetadata(snap, accounts.address("A"), metadata, sign(metadata, "A", "B")); err != nil {
+		t.Fatalf("failed to register metadata: %v", err)
+	}
+	// Restart the engine and ensure the registry is reloaded
+	engine = New(config, db)
+	entries := engine.signerMetadata(signers)
+	if len(entries) != 1 || entries[accounts.address("A")] != *metadata {
+		t.Fatalf("reloaded registry mismatch: have %v", entries)
+	}
+	// Remove the entry via empty metadata and ensure it's gone after a restart
+	if err := engine.registerMetadata(snap, accounts.address("A"), new(SignerMetadata), sign(new(SignerMetadata), "B", "C")); err != nil {
+		t.Fatalf("failed to remove metadata: %v", err)
+	}
+	if entries := New(config, db).signerMetadata(signers); len(entries) != 0 {
+		t.Errorf("removed metadata reloaded: %v", entries)
+	}
+}
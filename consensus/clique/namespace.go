@@ -0,0 +1,134 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// namespacePrefix is the key prefix of the voting snapshots of a chain, followed
+	// by the first bytes of its genesis hash, so several clique chains may share a
+	// database (e.g. test harnesses) without their snapshots colliding.
+	namespacePrefix = []byte("clique-ns-") // namespacePrefix + genesis[:8] + snapshot key -> snapshot data
+
+	// snapshotPrefix is the key prefix of the full voting snapshots, within the
+	// namespace of their chain or at the top level before namespacing.
+	snapshotPrefix = []byte("clique-") // snapshotPrefix + hash -> snapshot
+)
+
+// namespaceLength is the number of genesis hash bytes identifying a chain.
+const namespaceLength = 8
+
+// snapshotNamespace returns the key prefix of the voting snapshots of the chain
+// with the given genesis hash.
+func snapshotNamespace(genesis common.Hash) string {
+	return string(append(append([]byte{}, namespacePrefix...), genesis[:namespaceLength]...))
+}
+
+// snapshotDB returns the database the voting snapshots of the chain are stored in,
+// namespaced by the chain's genesis hash. The namespace is set up on first use,
+// upgrading the database of the chain to the current layout. Until then, or if
+// the genesis is unknown, the plain database is returned.
+func (c *Clique) snapshotDB(chain consensus.ChainHeaderReader) (ethdb.Database, error) {
+	c.namespaceLock.Lock()
+	defer c.namespaceLock.Unlock()
+
+	if c.snapdb != nil {
+		return c.snapdb, nil
+	}
+	if c.db == nil || chain == nil {
+		return c.db, nil
+	}
+	genesis := chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return c.db, nil
+	}
+	namespace := snapshotNamespace(genesis.Hash())
+	if err := migrateSchema(chain, c.db, namespace); err != nil {
+		return nil, err
+	}
+	c.snapdb = newSnapshotFlusher(rawdb.NewTable(c.db, namespace))
+	c.loadFlushed(c.snapdb)
+	return c.snapdb, nil
+}
+
+// migrateSnapshots moves the full and delta voting snapshots of the chain stored
+// before namespacing, along with the flushed snapshot marker, into the namespaced
+// database. Snapshots of blocks unknown to the chain belong to other chains, and
+// are left for them to migrate.
+func migrateSnapshots(chain consensus.ChainHeaderReader, db ethdb.Database, namespace string) error {
+	rekey := func(key []byte) []byte {
+		return append([]byte(namespace), key...)
+	}
+	var (
+		batch = db.NewBatch()
+		moved int
+	)
+	it := db.NewIterator(snapshotPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		var (
+			key  = it.Key()
+			hash common.Hash
+		)
+		switch {
+		case bytes.HasPrefix(key, namespacePrefix):
+			continue
+		case len(key) == len(snapshotPrefix)+common.HashLength:
+			hash = common.BytesToHash(key[len(snapshotPrefix):])
+		case bytes.HasPrefix(key, deltaPrefix) && len(key) == len(deltaPrefix)+common.HashLength:
+			hash = common.BytesToHash(key[len(deltaPrefix):])
+		default:
+			continue
+		}
+		if chain.GetHeaderByHash(hash) == nil {
+			continue
+		}
+		batch.Put(rekey(key), it.Value())
+		batch.Delete(common.CopyBytes(key))
+		moved++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if blob, err := db.Get(flushedSnapshotKey); err == nil && chain.GetHeaderByHash(common.BytesToHash(blob)) != nil {
+		batch.Put(rekey(flushedSnapshotKey), blob)
+		batch.Delete(flushedSnapshotKey)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if moved > 0 {
+		log.Info("Migrated voting snapshots into chain namespace", "snapshots", moved)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the snapshots of chains sharing a database are stored apart, and
+// that the snapshots stored before namespacing are moved into the namespace of
+// the chain they belong to.
+func TestSnapshotNamespace(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000}
+		db       = rawdb.NewMemoryDatabase()
+	)
+	newChain := func(vanity byte) *testerHeaderChain {
+		genesis := &types.Header{
+			Number:     common.Big0,
+			Difficulty: common.Big1,
+			Extra:      make([]byte, extraVanity+common.AddressLength+extraSeal),
+		}
+		genesis.Extra[0] = vanity
+		accounts.checkpoint(genesis, []string{"A"})
+		return &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+	}
+	first, second := newChain(1), newChain(2)
+
+	// Store a snapshot of each chain in the legacy layout, and flag the first one
+	// as flushed on shutdown
+	legacy := func(chain *testerHeaderChain) *Snapshot {
+		snap := newSnapshot(config, nil, 0, chain.headers[0].Hash(), []common.Address{accounts.address("A")})
+		if err := snap.store(db); err != nil {
+			t.Fatalf("failed to store legacy snapshot: %v", err)
+		}
+		return snap
+	}
+	firstSnap, secondSnap := legacy(first), legacy(second)
+	if err := db.Put(flushedSnapshotKey, firstSnap.Hash[:]); err != nil {
+		t.Fatalf("failed to store flushed marker: %v", err)
+	}
+	// Setting up the namespace of the first chain should only move its snapshot
+	engine := New(config, db)
+	namespaced, err := engine.snapshotDB(first)
+	if err != nil {
+		t.Fatalf("failed to set up namespace: %v", err)
+	}
+
+	if _, err := loadSnapshot(config, nil, namespaced, firstSnap.Hash); err != nil {
+		t.Errorf("failed to load migrated snapshot: %v", err)
+	}
+	if has, _ := db.Has(append(append([]byte{}, snapshotPrefix...), firstSnap.Hash[:]...)); has {
+		t.Errorf("legacy snapshot kept after migration")
+	}
+	if engine.flushed != firstSnap.Hash {
+		t.Errorf("flushed snapshot mismatch: have %x, want %x", engine.flushed, firstSnap.Hash)
+	}
+	if _, err := loadSnapshot(config, nil, namespaced, secondSnap.Hash); err == nil {
+		t.Errorf("foreign snapshot migrated into namespace")
+	}
+	if has, _ := db.Has(append(append([]byte{}, snapshotPrefix...), secondSnap.Hash[:]...)); !has {
+		t.Errorf("foreign legacy snapshot dropped")
+	}
+	// The second chain should pick up its own snapshot in its own namespace
+	other, err := New(config, db).snapshotDB(second)
+	if err != nil {
+		t.Fatalf("failed to set up foreign namespace: %v", err)
+	}
+	if _, err := loadSnapshot(config, nil, other, secondSnap.Hash); err != nil {
+		t.Errorf("failed to load migrated foreign snapshot: %v", err)
+	}
+	if _, err := loadSnapshot(config, nil, other, firstSnap.Hash); err == nil {
+		t.Errorf("snapshot visible across namespaces")
+	}
+}
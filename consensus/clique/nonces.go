@@ -0,0 +1,110 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// voteNonces are the magic nonces a chain casts its membership and signer limit
+// votes with. Chains started before these were configurable may use their own
+// values instead of the stock ones.
+type voteNonces struct {
+	auth  types.BlockNonce // Nonce of the votes authorizing a signer
+	drop  types.BlockNonce // Nonce of the votes deauthorizing a signer and of the blocks not voting
+	limit types.BlockNonce // Nonce of the votes on the signer limit
+}
+
+// voteNoncesOf returns the vote nonces configured for a chain, defaulting to the
+// stock ones.
+func voteNoncesOf(config *params.CliqueConfig) voteNonces {
+	var nonces voteNonces
+	copy(nonces.auth[:], nonceAuthVote)
+	copy(nonces.drop[:], nonceDropVote)
+	copy(nonces.limit[:], nonceSignerLimitAuthVote)
+
+	if config.AuthVoteNonce != nil {
+		nonces.auth = types.EncodeNonce(uint64(*config.AuthVoteNonce))
+	}
+	if config.DropVoteNonce != nil {
+		nonces.drop = types.EncodeNonce(uint64(*config.DropVoteNonce))
+	}
+	if config.LimitVoteNonce != nil {
+		nonces.limit = types.EncodeNonce(uint64(*config.LimitVoteNonce))
+	}
+	return nonces
+}
+
+// voteNonces returns the vote nonces of the chain the engine runs.
+func (c *Clique) voteNonces() voteNonces {
+	return voteNoncesOf(c.config)
+}
+
+// voteNonces returns the vote nonces of the chain the snapshot belongs to.
+func (s *Snapshot) voteNonces() voteNonces {
+	return voteNoncesOf(s.config)
+}
+
+// CheckVoteNonces verifies that the vote nonces configured for a chain are unique,
+// both among themselves and against the nonces of the other vote types, as a
+// header carrying an ambiguous nonce would be counted differently across nodes.
+func CheckVoteNonces(config *params.CliqueConfig) error {
+	nonces := voteNoncesOf(config)
+	votes := []struct {
+		name  string
+		nonce types.BlockNonce
+	}{
+		{"authorization", nonces.auth},
+		{"deauthorization", nonces.drop},
+		{"signer limit", nonces.limit},
+	}
+	reserved := []struct {
+		name  string
+		nonce []byte
+	}{
+		{"signer override", nonceSignerOverride},
+		{"sender permit", noncePermitVote},
+		{"sender revoke", nonceRevokeVote},
+		{"vote commitment", nonceCommitVote},
+		{"resignation", nonceResignation},
+		{"signer replacement", nonceReplaceVote},
+	}
+	for i, vote := range votes {
+		for _, other := range votes[:i] {
+			if vote.nonce == other.nonce {
+				return fmt.Errorf("clique %s vote nonce %#x clashes with the %s vote nonce", vote.name, vote.nonce[:], other.name)
+			}
+		}
+		header := &types.Header{Nonce: vote.nonce}
+		for _, other := range reserved {
+			if bytes.Equal(vote.nonce[:], other.nonce) {
+				return fmt.Errorf("clique %s vote nonce %#x clashes with the %s nonce", vote.name, vote.nonce[:], other.name)
+			}
+		}
+		if isPeriodVote(header) {
+			return fmt.Errorf("clique %s vote nonce %#x clashes with the signer period vote nonces", vote.name, vote.nonce[:])
+		}
+		if isRevealVote(header) {
+			return fmt.Errorf("clique %s vote nonce %#x clashes with the vote reveal nonces", vote.name, vote.nonce[:])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that configured vote nonces clashing with each other or with the nonces
+// of the other vote types are rejected.
+func TestCheckVoteNonces(t *testing.T) {
+	nonce := func(n uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&n) }
+
+	tests := []struct {
+		config *params.CliqueConfig
+		valid  bool
+	}{
+		{&params.CliqueConfig{}, true},
+		{&params.CliqueConfig{AuthVoteNonce: nonce(1), DropVoteNonce: nonce(2), LimitVoteNonce: nonce(3)}, true},
+		{&params.CliqueConfig{AuthVoteNonce: nonce(0)}, false},                   // Clashes with the default drop nonce
+		{&params.CliqueConfig{DropVoteNonce: nonce(0xfffffff100000000)}, false},  // Clashes with the default limit nonce
+		{&params.CliqueConfig{LimitVoteNonce: nonce(0xfffffff300000000)}, false}, // Clashes with the permit nonce
+		{&params.CliqueConfig{LimitVoteNonce: nonce(0xfffffff60000000f)}, false}, // Clashes with the period nonces
+		{&params.CliqueConfig{AuthVoteNonce: nonce(0xc1000000000000ff)}, false},  // Clashes with the reveal nonces
+	}
+	for i, tt := range tests {
+		if err := CheckVoteNonces(tt.config); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+// Tests that votes are tallied by the nonces configured for the chain, and that
+// the stock nonces aren't accepted in their stead.
+func TestCustomVoteNonces(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		auth     = hexutil.Uint64(0x1)
+		drop     = hexutil.Uint64(0x2)
+		config   = &params.CliqueConfig{Epoch: 30000, AuthVoteNonce: &auth, DropVoteNonce: &drop}
+		signers  = []common.Address{accounts.address("A")}
+	)
+	header := &types.Header{
+		Number:   big.NewInt(1),
+		Coinbase: accounts.address("B"),
+		Nonce:    types.EncodeNonce(uint64(auth)),
+		Extra:    make([]byte, extraVanity+extraSeal),
+	}
+	accounts.sign(header, "A")
+
+	snap, err := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, signers).apply([]*types.Header{header})
+	if err != nil {
+		t.Fatalf("failed to apply custom nonce vote: %v", err)
+	}
+	if _, ok := snap.Signers[accounts.address("B")]; !ok {
+		t.Errorf("custom nonce vote not counted")
+	}
+	copy(header.Nonce[:], nonceAuthVote)
+	accounts.sign(header, "A")
+
+	if _, err := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, signers).apply([]*types.Header{header}); err != errInvalidVote {
+		t.Errorf("stock nonce vote error mismatch: have %v, want %v", err, errInvalidVote)
+	}
+}
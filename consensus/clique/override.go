@@ -0,0 +1,243 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// nonceSignerOverride is the magic nonce marking a checkpoint block which replaces
+// the signer set with one co-signed by a supermajority of the current signers.
+var nonceSignerOverride = hexutil.MustDecode("0xfffffff200000000")
+
+var (
+	// errOverrideDisabled is returned if a checkpoint block carries a signer set
+	// override but the chain doesn't permit emergency overrides.
+	errOverrideDisabled = errors.New("signer override not enabled")
+
+	// errInvalidOverride is returned if a signer set override is malformed, i.e.
+	// it's not decodable, or its signer list is empty or not strictly ascending.
+	errInvalidOverride = errors.New("invalid signer override")
+
+	// errOverrideQuorum is returned if a signer set override isn't co-signed by a
+	// strict supermajority of the current signers.
+	errOverrideQuorum = errors.New("signer override lacks supermajority")
+)
+
+// signerOverride is the payload embedded in the extra-data of an override
+// checkpoint (between the vanity and the seal) instead of the signer list.
+type signerOverride struct {
+	Signers    []common.Address // New signer set, in ascending order
+	Signatures [][]byte         // Signatures of current signers over the override hash
+}
+
+// OverrideHash returns the digest that the current signers need to sign offline
+// to authorize replacing the signer set with the given one at the checkpoint
+// block with the given number.
+func OverrideHash(number uint64, signers []common.Address) common.Hash {
+	blob, err := rlp.EncodeToBytes([]interface{}{"clique-override", number, signers})
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return crypto.Keccak256Hash(blob)
+}
+
+// isOverride returns whether the header is a signer set override checkpoint.
+func isOverride(header *types.Header) bool {
+	return bytes.Equal(header.Nonce[:], nonceSignerOverride)
+}
+
+// decodeOverride extracts the signer set override from a checkpoint header.
+func decodeOverride(config *params.CliqueConfig, header *types.Header) (*signerOverride, error) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	override := new(signerOverride)
+	if err := rlp.DecodeBytes(headerPayload(config, header), override); err != nil {
+		return nil, errInvalidOverride
+	}
+	if len(override.Signers) == 0 {
+		return nil, errInvalidOverride
+	}
+	for i := 1; i < len(override.Signers); i++ {
+		if bytes.Compare(override.Signers[i-1][:], override.Signers[i][:]) >= 0 {
+			return nil, errInvalidOverride
+		}
+	}
+	return override, nil
+}
+
+// encodeOverride creates the extra-data section of a signer override checkpoint,
+// excluding the vanity and the seal.
+func encodeOverride(override *signerOverride) []byte {
+	blob, err := rlp.EncodeToBytes(override)
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return blob
+}
+
+// checkpointSigners extracts the signer set embedded in a checkpoint header, be
+// that a plain signer list or an override.
+func checkpointSigners(config *params.CliqueConfig, header *types.Header) ([]common.Address, error) {
+	if isOverride(header) {
+		override, err := decodeOverride(config, header)
+		if err != nil {
+			return nil, err
+		}
+		return override.Signers, nil
+	}
+	list, _, _ := splitCheckpoint(config, header)
+	signers := make([]common.Address, len(list)/common.AddressLength)
+	for i := 0; i < len(signers); i++ {
+		copy(signers[i][:], list[i*common.AddressLength:])
+	}
+	return signers, nil
+}
+
+// verifyOverride checks that a signer set override for the given checkpoint was
+// co-signed by a strict supermajority (more than two thirds) of the signers in
+// the snapshot and keeps the signer set within its size bounds, returning the
+// approving signers in ascending order.
+func (s *Snapshot) verifyOverride(number uint64, override *signerOverride) ([]common.Address, error) {
+	if uint(len(override.Signers)) < s.config.MinSigners {
+		return nil, errSignerFloor
+	}
+	if s.config.MaxSigners > 0 && uint(len(override.Signers)) > s.config.MaxSigners {
+		return nil, errSignerCeiling
+	}
+	approved, ok := s.approvals(OverrideHash(number, override.Signers), override.Signatures)
+	if !ok {
+		return nil, errInvalidOverride
+	}
+	if 3*len(approved) <= 2*len(s.Signers) {
+		return nil, errOverrideQuorum
+	}
+	approvals := make([]common.Address, 0, len(approved))
+	for _, signer := range s.signers() {
+		if _, ok := approved[signer]; ok {
+			approvals = append(approvals, signer)
+		}
+	}
+	return approvals, nil
+}
+
+// approvals recovers the signers of the given signatures over a digest, returning
+// the set of those authorized in the snapshot, or false if a signature is invalid.
+func (s *Snapshot) approvals(hash common.Hash, signatures [][]byte) (map[common.Address]struct{}, bool) {
+	approved := make(map[common.Address]struct{})
+	for _, sig := range signatures {
+		pubkey, err := crypto.Ecrecover(hash[:], sig)
+		if err != nil {
+			return nil, false
+		}
+		var signer common.Address
+		copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+
+		if _, ok := s.Signers[signer]; ok {
+			approved[signer] = struct{}{}
+		}
+	}
+	return approved, true
+}
+
+// applyOverride verifies the signer set override carried by a checkpoint header
+// and if valid, replaces the signer set of the snapshot with the overridden one,
+// discarding the spam protection state of the replaced signers.
+func (s *Snapshot) applyOverride(header *types.Header) error {
+	if !s.config.EmergencyOverride {
+		return errOverrideDisabled
+	}
+	override, err := decodeOverride(s.config, header)
+	if err != nil {
+		return err
+	}
+	number := header.Number.Uint64()
+	approvals, err := s.verifyOverride(number, override)
+	if err != nil {
+		return err
+	}
+	res := &Resolution{
+		Kind:    ProposalOverride,
+		Block:   number,
+		Hash:    header.Hash(),
+		Signers: override.Signers,
+	}
+	for _, signer := range approvals {
+		res.Votes = append(res.Votes, AuditVote{Signer: signer, Block: number})
+	}
+	s.resolutions = append(s.resolutions, res)
+
+	s.Signers = make(map[common.Address]struct{}, len(override.Signers))
+	for _, signer := range override.Signers {
+		s.Signers[signer] = struct{}{}
+	}
+	s.Recents = make(map[uint64]common.Address)
+	s.owned |= cowSigners | cowRecents
+	s.sortSigners()
+
+	// Discard the periods granted to the replaced signers and their probations
+	for _, period := range s.Periods {
+		if _, ok := s.Signers[period.Signer]; !ok {
+			s.dropPeriod(period.Signer, true)
+		}
+	}
+	for _, probation := range s.Probations {
+		if _, ok := s.Signers[probation.Signer]; !ok {
+			s.endProbation(probation.Signer)
+		}
+	}
+	return nil
+}
+
+// overridden returns the snapshot a checkpoint header needs to be sealed against:
+// a copy with the new signer set if the header overrides it, the snapshot itself
+// otherwise.
+func (s *Snapshot) overridden(header *types.Header) (*Snapshot, error) {
+	if header.Number.Uint64()%s.config.Epoch != 0 || !isOverride(header) {
+		return s, nil
+	}
+	snap := s.copy()
+	if err := snap.applyOverride(header); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// sortedSigners returns a deduplicated copy of the signers in ascending order,
+// as required by the override format.
+func sortedSigners(signers []common.Address) []common.Address {
+	sorted := append([]common.Address{}, signers...)
+	sort.Sort(signersAscending(sorted))
+
+	unique := sorted[:0]
+	for _, signer := range sorted {
+		if len(unique) == 0 || signer != unique[len(unique)-1] {
+			unique = append(unique, signer)
+		}
+	}
+	return unique
+}
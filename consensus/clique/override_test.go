@@ -0,0 +1,99 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a checkpoint carrying a signer set override replaces the signers if
+// and only if it's co-signed by a strict supermajority of the current signers.
+func TestSignerOverride(t *testing.T) {
+	tests := []struct {
+		enabled  bool
+		approve  []string // Current signers co-signing the override
+		sealer   string   // Signer sealing the override checkpoint
+		corrupt  bool     // Whether to corrupt the override payload
+		err      error
+		approved int
+	}{
+		{enabled: true, approve: []string{"A", "B", "C"}, sealer: "E", approved: 3},
+		{enabled: true, approve: []string{"A", "B", "C", "D"}, sealer: "F", approved: 4},
+		{enabled: true, approve: []string{"A", "B"}, sealer: "E", err: errOverrideQuorum},
+		{enabled: true, approve: []string{"A", "A", "B"}, sealer: "E", err: errOverrideQuorum},
+		{enabled: true, approve: []string{"A", "B", "E"}, sealer: "E", err: errOverrideQuorum},
+		{enabled: true, approve: []string{"A", "B", "C"}, sealer: "A", err: errUnauthorizedSigner},
+		{enabled: true, approve: []string{"A", "B", "C"}, sealer: "E", corrupt: true, err: errInvalidOverride},
+		{enabled: false, approve: []string{"A", "B", "C"}, sealer: "E", err: errOverrideDisabled},
+	}
+	for i, tt := range tests {
+		accounts := newTesterAccountPool()
+
+		current := []common.Address{accounts.address("A"), accounts.address("B"), accounts.address("C"), accounts.address("D")}
+		replaced := sortedSigners([]common.Address{accounts.address("E"), accounts.address("F")})
+
+		config := &params.CliqueConfig{Epoch: 4, EmergencyOverride: tt.enabled}
+		sigcache := NewSigCache(inmemorySignatures, nil)
+		snap := newSnapshot(config, sigcache, 3, common.Hash{}, current)
+
+		hash := OverrideHash(4, replaced)
+		override := &signerOverride{Signers: replaced}
+		for _, signer := range tt.approve {
+			accounts.address(signer)
+			sig, _ := crypto.Sign(hash[:], accounts.accounts[signer])
+			override.Signatures = append(override.Signatures, sig)
+		}
+		header := &types.Header{
+			Number:     big.NewInt(4),
+			Difficulty: diffNoTurn,
+			Extra:      append(append(make([]byte, extraVanity), encodeOverride(override)...), make([]byte, extraSeal)...),
+		}
+		copy(header.Nonce[:], nonceSignerOverride)
+		if tt.corrupt {
+			header.Extra[extraVanity] = 0x00
+		}
+		accounts.sign(header, tt.sealer)
+
+		result, err := snap.apply([]*types.Header{header})
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if have := result.SignerList(); !reflect.DeepEqual(have, replaced) {
+			t.Errorf("test %d: signers mismatch: have %x, want %x", i, have, replaced)
+		}
+		if recents := result.RecentSigners(); len(recents) != 1 || recents[4] != accounts.address(tt.sealer) {
+			t.Errorf("test %d: recents mismatch: have %v", i, recents)
+		}
+		if len(result.resolutions) != 1 || result.resolutions[0].Kind != ProposalOverride || len(result.resolutions[0].Votes) != tt.approved {
+			t.Errorf("test %d: resolution mismatch: have %v", i, result.resolutions)
+		}
+		if have := snap.SignerList(); len(have) != len(current) {
+			t.Errorf("test %d: parent snapshot modified: have %x", i, have)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// errDoubleLimitVote is returned if a block votes on the signer limit both in its
+// header and in its extra-data payload.
+var errDoubleLimitVote = errors.New("block carries two signer limit votes")
+
+// payloadLimit returns the signer limit voted on in the structured extra-data
+// payload of a header, alongside whatever its nonce and coinbase vote on, or 0
+// if the header carries no such vote.
+func payloadLimit(config *params.CliqueConfig, header *types.Header) uint64 {
+	if !structuredExtra(config, header) {
+		return 0
+	}
+	structured, err := decodeExtra(config, header)
+	if err != nil {
+		return 0
+	}
+	return structured.Limit
+}
+
+// verifyPayloadLimit checks that the signer limit voted on in the payload of a
+// header is within the permitted bounds, and that the header doesn't vote on the
+// signer limit through its nonce too.
+func (s *Snapshot) verifyPayloadLimit(header *types.Header) error {
+	limit := payloadLimit(s.config, header)
+	if limit == 0 {
+		return nil
+	}
+	if header.Nonce == s.voteNonces().limit {
+		return errDoubleLimitVote
+	}
+	if _, ok := s.decodeSignerLimit(common.BigToAddress(new(big.Int).SetUint64(limit))); !ok {
+		return errInvalidSignerLimit
+	}
+	return nil
+}
+
+// applyPayloadLimit tallies up the signer limit vote carried in the payload of a
+// header sealed by an authorized signer, which must already be verified.
+func (s *Snapshot) applyPayloadLimit(header *types.Header, signer common.Address) {
+	limit := payloadLimit(s.config, header)
+	if limit == 0 {
+		return
+	}
+	address := common.BigToAddress(new(big.Int).SetUint64(limit))
+
+	s.uncastLimitVote(signer, address)
+	s.applyLimitVote(header.Number.Uint64(), header.Hash(), signer, address)
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that past the structured extra-data fork, a block may vote on a signer
+// and on the signer limit at the same time, but not on two signer limits.
+func TestPayloadLimitVote(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50, StructuredExtraBlock: big.NewInt(1)}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 1)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	// Reseal the first block with an authorization and a signer limit vote
+	header := chain.headers[1]
+	header.Coinbase = accounts.address("D")
+	header.Nonce = engine.voteNonces().auth
+	structureExtra(config, header, 66)
+	accounts.sign(header, "B")
+
+	snap, err := engine.snapshot(chain, 1, header.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if tally := snap.Tally[accounts.address("D")]; tally.Votes != 1 || !tally.Authorize {
+		t.Errorf("authorization tally mismatch: have %+v", tally)
+	}
+	if votes := snap.PendingLimitVotes(); len(votes) != 1 || votes[0].Limit != 66 || votes[0].Signer != accounts.address("B") {
+		t.Errorf("signer limit votes mismatch: have %+v", votes)
+	}
+	// Limit votes in both the header and the payload, or out of bounds, are rejected
+	parent, err := engine.snapshot(chain, 0, chain.headers[0].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create genesis snapshot: %v", err)
+	}
+	tests := []struct {
+		coinbase common.Address
+		nonce    types.BlockNonce
+		limit    uint64
+		err      error
+	}{
+		{common.BigToAddress(big.NewInt(60)), engine.voteNonces().limit, 66, errDoubleLimitVote},
+		{common.Address{}, engine.voteNonces().drop, 101, errInvalidSignerLimit},
+		{common.Address{}, engine.voteNonces().drop, 66, nil},
+	}
+	for i, tt := range tests {
+		header := &types.Header{
+			ParentHash: chain.headers[0].Hash(),
+			Number:     big.NewInt(1),
+			Difficulty: diffInTurn,
+			Coinbase:   tt.coinbase,
+			Nonce:      tt.nonce,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		structureExtra(config, header, tt.limit)
+		accounts.sign(header, "B")
+
+		if _, err := parent.apply([]*types.Header{header}); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// noncePeriodVote is the magic nonce prefix to vote on the minimum block period
+// of the signer in the coinbase. The last four bytes of the nonce carry the period
+// in seconds, zero reverting the signer to the chain period.
+var noncePeriodVote = hexutil.MustDecode("0xfffffff600000000")
+
+var (
+	// errPeriodDisabled is returned if a block votes on a signer period before the
+	// signer period fork.
+	errPeriodDisabled = errors.New("signer periods not enabled")
+
+	// errInvalidSignerPeriod is returned if a block votes on a signer period not
+	// longer than the chain period, other than the zero reverting to it.
+	errInvalidSignerPeriod = errors.New("invalid signer period")
+)
+
+// SignerPeriod is a minimum block period granted to a specific signer, e.g. to
+// give a slow HSM-backed signer extra time to seal its blocks.
+type SignerPeriod struct {
+	Signer common.Address `json:"signer"` // Signer the period is granted to
+	Period uint64         `json:"period"` // Minimum number of seconds between the parent and the signer's blocks
+}
+
+// PeriodVote is a vote of an authorized signer to grant an account a minimum
+// block period.
+type PeriodVote struct {
+	Signer  common.Address `json:"signer"`  // Authorized signer that cast this vote
+	Block   uint64         `json:"block"`   // Block number the vote was cast in
+	Address common.Address `json:"address"` // Signer the period is voted on for
+	Period  uint64         `json:"period"`  // Period voted on (0 = revert to the chain period)
+}
+
+// isPeriodVote returns whether the header votes on a signer period.
+func isPeriodVote(header *types.Header) bool {
+	return bytes.Equal(header.Nonce[:4], noncePeriodVote[:4])
+}
+
+// encodePeriodVote sets the nonce of a header to vote on the given period.
+func encodePeriodVote(header *types.Header, period uint64) {
+	copy(header.Nonce[:], noncePeriodVote)
+	binary.BigEndian.PutUint32(header.Nonce[4:], uint32(period))
+}
+
+// decodePeriodVote extracts the period voted on by a header, returning false if
+// it doesn't lengthen the chain period (nor reverts to it).
+func (s *Snapshot) decodePeriodVote(header *types.Header) (uint64, bool) {
+	period := uint64(binary.BigEndian.Uint32(header.Nonce[4:]))
+	return period, period == 0 || period > s.config.Period
+}
+
+// signerPeriod returns the minimum number of seconds between a block sealed by
+// the signer and its parent.
+func (s *Snapshot) signerPeriod(signer common.Address) uint64 {
+	for _, period := range s.Periods {
+		if period.Signer == signer {
+			return period.Period
+		}
+	}
+	return s.config.Period
+}
+
+// validPeriodVote returns whether it makes sense to cast the specified signer
+// period vote in the given snapshot context.
+func (s *Snapshot) validPeriodVote(address common.Address, period uint64) bool {
+	if _, ok := s.Signers[address]; !ok {
+		return false
+	}
+	if period == 0 {
+		period = s.config.Period
+	}
+	return s.signerPeriod(address) != period
+}
+
+// applyPeriodVote tallies a signer period vote of an authorized signer, granting
+// the period if the vote made the proposal pass.
+func (s *Snapshot) applyPeriodVote(number uint64, hash common.Hash, signer, address common.Address, period uint64) {
+	// Discard any previous vote from the signer on the account. The votes are
+	// shared between snapshot copies, never modify in place
+	votes := make([]*PeriodVote, 0, len(s.PeriodVotes)+1)
+	for _, vote := range s.PeriodVotes {
+		if vote.Signer != signer || vote.Address != address {
+			votes = append(votes, vote)
+		}
+	}
+	s.PeriodVotes = votes
+
+	if !s.validPeriodVote(address, period) {
+		return
+	}
+	s.PeriodVotes = append(s.PeriodVotes, &PeriodVote{
+		Signer:  signer,
+		Block:   number,
+		Address: address,
+		Period:  period,
+	})
+	var trail []AuditVote
+	for _, vote := range s.PeriodVotes {
+		if vote.Address == address && vote.Period == period {
+			trail = append(trail, AuditVote{Signer: vote.Signer, Block: vote.Block})
+		}
+	}
+	passed := len(trail) >= int(s.signerLimit())
+
+	s.observed = append(s.observed, &VoteEvent{
+		Block:   number,
+		Hash:    hash,
+		Signer:  signer,
+		Kind:    ProposalPeriod,
+		Address: address,
+		Period:  period,
+		Votes:   len(trail),
+		Passed:  passed,
+	})
+	if !passed {
+		return
+	}
+	s.resolutions = append(s.resolutions, &Resolution{
+		Kind:    ProposalPeriod,
+		Block:   number,
+		Hash:    hash,
+		Address: address,
+		Period:  period,
+		Votes:   trail,
+	})
+	s.dropPeriod(address, false)
+	if period != 0 {
+		s.Periods = append(s.Periods, &SignerPeriod{Signer: address, Period: period})
+	}
+}
+
+// dropPeriod discards the period granted to an account along with any votes on
+// it, and if the account was deauthorized, any period votes it cast too.
+func (s *Snapshot) dropPeriod(address common.Address, deauthorized bool) {
+	periods := make([]*SignerPeriod, 0, len(s.Periods))
+	for _, period := range s.Periods {
+		if period.Signer != address {
+			periods = append(periods, period)
+		}
+	}
+	votes := make([]*PeriodVote, 0, len(s.PeriodVotes))
+	for _, vote := range s.PeriodVotes {
+		if vote.Address != address && !(deauthorized && vote.Signer == address) {
+			votes = append(votes, vote)
+		}
+	}
+	s.Periods, s.PeriodVotes = periods, votes
+	if len(s.Periods) == 0 {
+		s.Periods = nil
+	}
+	if len(s.PeriodVotes) == 0 {
+		s.PeriodVotes = nil
+	}
+}
+
+// SignerPeriods retrieves the minimum block periods granted to specific signers.
+func (s *Snapshot) SignerPeriods() map[common.Address]uint64 {
+	periods := make(map[common.Address]uint64, len(s.Periods))
+	for _, period := range s.Periods {
+		periods[period.Signer] = period.Period
+	}
+	return periods
+}
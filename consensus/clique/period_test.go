@@ -0,0 +1,110 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that signer periods are granted and reverted by the signer limit, that
+// they are enforced on the timestamps of the signer's blocks and that they are
+// dropped along with the signer.
+func TestSignerPeriodVoting(t *testing.T) {
+	accounts := newTesterAccountPool()
+	signers := []string{"A", "B", "C"}
+	config := &params.CliqueConfig{Period: 5, Epoch: 30000, SignerPeriodBlock: big.NewInt(0)}
+
+	genesis := &types.Header{
+		Number:   big.NewInt(0),
+		GasLimit: params.GenesisGasLimit,
+		Extra:    make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.fakeDiff = true
+
+	// seal creates the next block of the chain, optionally voting on a period
+	seal := func(signer string, delay uint64, vote string, period uint64) *types.Header {
+		parent := chain.headers[len(chain.headers)-1]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       parent.Time + delay,
+			GasLimit:   parent.GasLimit,
+			Difficulty: diffNoTurn,
+			UncleHash:  uncleHash,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if vote != "" {
+			header.Coinbase = accounts.address(vote)
+			encodePeriodVote(header, period)
+		}
+		accounts.sign(header, signer)
+		return header
+	}
+	// Grant C a longer period, which should only pass on the second vote
+	for _, signer := range []string{"A", "B"} {
+		header := seal(signer, 5, "C", 15)
+		if err := engine.verifyHeader(chain, header, nil); err != nil {
+			t.Fatalf("failed to verify period vote of %s: %v", signer, err)
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	snap, err := engine.snapshot(chain, 2, chain.headers[2].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if period := snap.signerPeriod(accounts.address("C")); period != 15 {
+		t.Fatalf("granted period mismatch: have %d, want %d", period, 15)
+	}
+	if period := snap.signerPeriod(accounts.address("A")); period != 5 {
+		t.Errorf("chain period mismatch: have %d, want %d", period, 5)
+	}
+	// C may only seal after its own period, others after the chain one
+	if err := engine.verifyHeader(chain, seal("C", 5, "", 0), nil); err != errInvalidTimestamp {
+		t.Errorf("early block error mismatch: have %v, want %v", err, errInvalidTimestamp)
+	}
+	if err := engine.verifyHeader(chain, seal("C", 15, "", 0), nil); err != nil {
+		t.Errorf("failed to verify block after the granted period: %v", err)
+	}
+	// Periods not longer than the chain one are rejected
+	if err := engine.verifyHeader(chain, seal("C", 5, "A", 3), nil); err != errInvalidSignerPeriod {
+		t.Errorf("short period error mismatch: have %v, want %v", err, errInvalidSignerPeriod)
+	}
+	// Reverting to the chain period should pass the same way
+	revert := snap.copy()
+	for i, signer := range []string{"A", "B"} {
+		revert.applyPeriodVote(uint64(3+i), common.Hash{}, accounts.address(signer), accounts.address("C"), 0)
+	}
+	if period := revert.signerPeriod(accounts.address("C")); period != 5 || len(revert.Periods) != 0 {
+		t.Errorf("reverted period mismatch: have %d (%d granted)", period, len(revert.Periods))
+	}
+	// Deauthorizing the signer should drop its period
+	drop := snap.copy()
+	drop.dropPeriod(accounts.address("C"), true)
+	if len(drop.Periods) != 0 || len(snap.Periods) != 1 {
+		t.Errorf("dropped periods mismatch: have %d, origin %d", len(drop.Periods), len(snap.Periods))
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	noncePermitVote = hexutil.MustDecode("0xfffffff300000000") // Magic nonce number to vote on permitting a sender
+	nonceRevokeVote = hexutil.MustDecode("0xfffffff400000000") // Magic nonce number to vote on revoking a sender
+)
+
+var (
+	// errPermissionDisabled is returned if a block votes on a sender permission
+	// before the permission fork.
+	errPermissionDisabled = errors.New("sender permissions not enabled")
+
+	// errUnpermittedSender is returned if a block contains a transaction sent by
+	// an account that is neither a signer, nor permitted by the signers.
+	errUnpermittedSender = errors.New("unpermitted transaction sender")
+)
+
+// isPermitVote returns whether the header votes on a sender permission.
+func isPermitVote(header *types.Header) bool {
+	return bytes.Equal(header.Nonce[:], noncePermitVote) || bytes.Equal(header.Nonce[:], nonceRevokeVote)
+}
+
+// permits returns whether an account may send transactions in the context of the
+// snapshot. Signers are always permitted, so they can keep governing the chain.
+func (s *Snapshot) permits(address common.Address) bool {
+	if _, ok := s.Signers[address]; ok {
+		return true
+	}
+	_, ok := s.Permitted[address]
+	return ok
+}
+
+// validPermitVote returns whether it makes sense to cast the specified sender
+// permission vote in the given snapshot context.
+func (s *Snapshot) validPermitVote(address common.Address, permit bool) bool {
+	_, permitted := s.Permitted[address]
+	return permitted != permit
+}
+
+// applyPermitVote tallies a sender permission vote of an authorized signer on an
+// account, updating the permitted senders if the vote made the proposal pass.
+func (s *Snapshot) applyPermitVote(number uint64, hash common.Hash, signer, address common.Address, permit bool) {
+	// Discard any previous vote from the signer on the account
+	for i, vote := range s.PermitVotes {
+		if vote.Signer == signer && vote.Address == address {
+			s.uncastPermit(vote.Address, vote.Authorize)
+
+			s.writable(cowPermitVotes)
+			s.PermitVotes = append(s.PermitVotes[:i], s.PermitVotes[i+1:]...)
+			break // only one vote allowed
+		}
+	}
+	kind := ProposalRevoke
+	if permit {
+		kind = ProposalPermit
+	}
+	if !s.castPermit(address, permit) {
+		return
+	}
+	s.writable(cowPermitVotes)
+	s.PermitVotes = append(s.PermitVotes, &Vote{
+		Signer:    signer,
+		Block:     number,
+		Address:   address,
+		Authorize: permit,
+	})
+	votes := s.PermitTally[address].Votes
+	passed := votes >= int(s.signerLimit())
+
+	s.observed = append(s.observed, &VoteEvent{
+		Block:   number,
+		Hash:    hash,
+		Signer:  signer,
+		Kind:    kind,
+		Address: address,
+		Votes:   votes,
+		Passed:  passed,
+	})
+	if !passed {
+		return
+	}
+	res := &Resolution{
+		Kind:    kind,
+		Block:   number,
+		Hash:    hash,
+		Address: address,
+	}
+	s.writable(cowPermitVotes)
+	for i := 0; i < len(s.PermitVotes); i++ {
+		if vote := s.PermitVotes[i]; vote.Address == address {
+			if vote.Authorize == permit {
+				res.Votes = append(res.Votes, AuditVote{Signer: vote.Signer, Block: vote.Block})
+			}
+			s.PermitVotes = append(s.PermitVotes[:i], s.PermitVotes[i+1:]...)
+			i--
+		}
+	}
+	s.resolutions = append(s.resolutions, res)
+
+	s.writable(cowPermitTally)
+	delete(s.PermitTally, address)
+
+	s.writable(cowPermitted)
+	if permit {
+		s.Permitted[address] = struct{}{}
+	} else {
+		delete(s.Permitted, address)
+	}
+}
+
+// castPermit adds a new sender permission vote into the tally.
+func (s *Snapshot) castPermit(address common.Address, permit bool) bool {
+	if !s.validPermitVote(address, permit) {
+		return false
+	}
+	s.writable(cowPermitTally)
+	if old, ok := s.PermitTally[address]; ok {
+		old.Votes++
+		s.PermitTally[address] = old
+	} else {
+		s.PermitTally[address] = Tally{Authorize: permit, Votes: 1}
+	}
+	return true
+}
+
+// uncastPermit removes a previously cast sender permission vote from the tally.
+func (s *Snapshot) uncastPermit(address common.Address, permit bool) {
+	tally, ok := s.PermitTally[address]
+	if !ok || tally.Authorize != permit {
+		return
+	}
+	s.writable(cowPermitTally)
+	if tally.Votes > 1 {
+		tally.Votes--
+		s.PermitTally[address] = tally
+	} else {
+		delete(s.PermitTally, address)
+	}
+}
+
+// PermitsSender implements consensus.SenderPermitter, returning whether an account
+// may send transactions included in a child block of the given parent.
+func (c *Clique) PermitsSender(chain consensus.ChainHeaderReader, parent *types.Header, sender common.Address) bool {
+	if !c.config.IsPermissioned(new(big.Int).Add(parent.Number, common.Big1)) {
+		return true
+	}
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		log.Debug("Failed to retrieve clique sender permissions", "number", parent.Number, "hash", parent.Hash(), "err", err)
+		return false
+	}
+	return snap.permits(sender)
+}
+
+// verifyPermittedSenders checks that all the transactions of a block were sent
+// by accounts permitted in the context of its parent.
+func (c *Clique) verifyPermittedSenders(chain consensus.ChainReader, block *types.Block) error {
+	if !c.config.IsPermissioned(block.Number()) || len(block.Transactions()) == 0 {
+		return nil
+	}
+	number := block.NumberU64()
+	snap, err := c.snapshot(chain, number-1, block.ParentHash(), nil)
+	if err != nil {
+		return err
+	}
+	signer := types.MakeSigner(chain.Config(), block.Number())
+	for _, tx := range block.Transactions() {
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		if !snap.permits(sender) {
+			return errUnpermittedSender
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that signers vote senders into the permitted set, and that blocks with
+// transactions of unpermitted senders are rejected.
+func TestSenderPermissions(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50, PermissionBlock: big.NewInt(1)}
+		signers  = []string{"A", "B", "C"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{ChainID: common.Big1, Clique: config}, headers: []*types.Header{genesis}}
+	engine := New(config, rawdb.NewMemoryDatabase())
+
+	// Have two of the signers permit X, which is enough to pass
+	for i, signer := range []string{"A", "B"} {
+		header := &types.Header{
+			ParentHash: chain.headers[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Difficulty: diffNoTurn,
+			Coinbase:   accounts.address("X"),
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], noncePermitVote)
+		accounts.sign(header, signer)
+		chain.headers = append(chain.headers, header)
+	}
+	for i, want := range []bool{false, false, true} {
+		if have := engine.PermitsSender(chain, chain.headers[i], accounts.address("X")); have != want {
+			t.Errorf("block %d: permission mismatch: have %v, want %v", i+1, have, want)
+		}
+	}
+	if !engine.PermitsSender(chain, genesis, accounts.address("C")) {
+		t.Errorf("signer not permitted")
+	}
+	// Ensure only transactions of permitted senders may be included
+	txSigner := types.MakeSigner(chain.config, big.NewInt(3))
+	sign := func(sender string) *types.Transaction {
+		accounts.address(sender)
+		tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, new(big.Int), 21000, big.NewInt(1), nil), txSigner, accounts.accounts[sender])
+		return tx
+	}
+	header := &types.Header{
+		ParentHash: chain.headers[2].Hash(),
+		Number:     big.NewInt(3),
+		Difficulty: diffNoTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	for _, tt := range []struct {
+		senders []string
+		err     error
+	}{
+		{[]string{"X", "C"}, nil},
+		{[]string{"X", "Y"}, errUnpermittedSender},
+	} {
+		var txs []*types.Transaction
+		for _, sender := range tt.senders {
+			txs = append(txs, sign(sender))
+		}
+		if err := engine.VerifyUncles(chain, types.NewBlock(header, txs, nil, nil, trie.NewStackTrie(nil))); err != tt.err {
+			t.Errorf("senders %v: error mismatch: have %v, want %v", tt.senders, err, tt.err)
+		}
+	}
+	// Permission votes must be rejected before the fork
+	config.PermissionBlock = big.NewInt(10)
+	if err := New(config, rawdb.NewMemoryDatabase()).verifyHeader(chain, chain.headers[1], nil); err != errPermissionDisabled {
+		t.Errorf("early permission vote error mismatch: have %v, want %v", err, errPermissionDisabled)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// errProbationVote is returned if a block sealed by a signer still on probation
+// carries a vote.
+var errProbationVote = errors.New("vote cast by signer on probation")
+
+// Probation is a newly authorized signer that may seal blocks, but not vote yet.
+type Probation struct {
+	Signer common.Address `json:"signer"` // Signer on probation
+	Block  uint64         `json:"block"`  // Block number the signer was authorized in
+}
+
+// castsVote returns whether a header carries a vote of its sealer. Checkpoints,
+// resignations and blocks voting on nothing (zero coinbase with a drop nonce)
+// don't.
+func (s *Snapshot) castsVote(header *types.Header) bool {
+	if header.Number.Uint64()%s.config.Epoch == 0 || isResignation(header) {
+		return false
+	}
+	return header.Coinbase != (common.Address{}) || header.Nonce != s.voteNonces().drop || payloadLimit(s.config, header) != 0
+}
+
+// onProbation returns whether the signer may not vote in the given block yet.
+func (s *Snapshot) onProbation(signer common.Address, number uint64) bool {
+	for _, probation := range s.Probations {
+		if probation.Signer == signer {
+			return probation.Block+s.config.ProbationPeriod >= number
+		}
+	}
+	return false
+}
+
+// startProbation puts a newly authorized signer on probation, if enabled.
+func (s *Snapshot) startProbation(signer common.Address, number uint64) {
+	if s.config.ProbationPeriod == 0 {
+		return
+	}
+	// Probations are shared between snapshot copies, never modify in place
+	s.Probations = append(append(make([]*Probation, 0, len(s.Probations)+1), s.Probations...), &Probation{
+		Signer: signer,
+		Block:  number,
+	})
+}
+
+// endProbation takes a signer off probation, either because its probation period
+// elapsed or because it was deauthorized.
+func (s *Snapshot) endProbation(signer common.Address) {
+	probations := make([]*Probation, 0, len(s.Probations))
+	for _, probation := range s.Probations {
+		if probation.Signer != signer {
+			probations = append(probations, probation)
+		}
+	}
+	if len(probations) == 0 {
+		probations = nil
+	}
+	s.Probations = probations
+}
+
+// expireProbations takes the signers whose probation period elapsed before the
+// given block off probation.
+func (s *Snapshot) expireProbations(number uint64) {
+	for i := 0; i < len(s.Probations); i++ {
+		if probation := s.Probations[i]; probation.Block+s.config.ProbationPeriod < number {
+			s.endProbation(probation.Signer)
+			i--
+		}
+	}
+}
+
+// verifyProbation checks that a header sealed by the given signer doesn't carry
+// a vote while the signer is on probation.
+func (s *Snapshot) verifyProbation(header *types.Header, signer common.Address) error {
+	if s.castsVote(header) && s.onProbation(signer, header.Number.Uint64()) {
+		return errProbationVote
+	}
+	return nil
+}
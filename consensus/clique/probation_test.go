@@ -0,0 +1,72 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that newly authorized signers may seal right away, but may only vote once
+// their probation period elapsed.
+func TestSignerProbation(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.CliqueConfig{Epoch: 30000, ProbationPeriod: 2}
+	snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, []common.Address{
+		accounts.address("A"), accounts.address("B"),
+	})
+	block := func(number int64, signer, auth string) *types.Header {
+		header := &types.Header{
+			Number: big.NewInt(number),
+			Extra:  make([]byte, extraVanity+extraSeal),
+		}
+		if auth != "" {
+			header.Coinbase = accounts.address(auth)
+			copy(header.Nonce[:], nonceAuthVote)
+		}
+		accounts.sign(header, signer)
+		return header
+	}
+	snap, err := snap.apply([]*types.Header{block(1, "A", "C"), block(2, "B", "C")})
+	if err != nil {
+		t.Fatalf("failed to authorize signer: %v", err)
+	}
+	if !snap.onProbation(accounts.address("C"), 3) || snap.onProbation(accounts.address("C"), 5) {
+		t.Fatalf("probation period mismatch: %v", snap.Probations)
+	}
+	// The new signer may seal, but not vote during its probation
+	if _, err := snap.apply([]*types.Header{block(3, "C", "D")}); err != errProbationVote {
+		t.Errorf("probation vote error mismatch: have %v, want %v", err, errProbationVote)
+	}
+	if snap, err = snap.apply([]*types.Header{block(3, "C", ""), block(4, "A", "")}); err != nil {
+		t.Fatalf("failed to seal during probation: %v", err)
+	}
+	// Once the probation elapsed, the signer may vote
+	if snap, err = snap.apply([]*types.Header{block(5, "C", "D")}); err != nil {
+		t.Fatalf("failed to vote after probation: %v", err)
+	}
+	if len(snap.Probations) != 0 {
+		t.Errorf("probation not expired: %v", snap.Probations)
+	}
+	if snap.Tally[accounts.address("D")].Votes != 1 {
+		t.Errorf("vote after probation not counted")
+	}
+}
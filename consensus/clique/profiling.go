@@ -0,0 +1,65 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Operations the consensus work is attributed to in CPU profiles.
+const (
+	profileSeal   = "seal"   // Signing and broadcasting a block
+	profileVerify = "verify" // Verifying a batch of headers
+	profileReplay = "replay" // Reconstructing a snapshot from the headers
+)
+
+// profileLabels returns the pprof labels of a consensus operation on a range of
+// blocks.
+func profileLabels(op string, first, last uint64) pprof.LabelSet {
+	blocks := strconv.FormatUint(first, 10)
+	if last != first {
+		blocks += "-" + strconv.FormatUint(last, 10)
+	}
+	return pprof.Labels("engine", "clique", "operation", op, "blocks", blocks)
+}
+
+// profileGo runs a consensus operation on a range of blocks on a new goroutine,
+// tagged with the pprof labels of the operation. The goroutines it starts inherit
+// the labels, and the labelled context is passed in to restore them if nested
+// operations relabel the goroutine.
+func profileGo(op string, first, last uint64, fn func(ctx context.Context)) {
+	go pprof.Do(context.Background(), profileLabels(op, first, last), fn)
+}
+
+// profileDo runs a consensus operation on a range of blocks on the calling
+// goroutine, tagged with the pprof labels of the operation. As the runtime has
+// no way to retrieve the labels of a goroutine, any it had are dropped after.
+func profileDo(op string, first, last uint64, fn func()) {
+	pprof.Do(context.Background(), profileLabels(op, first, last), func(context.Context) { fn() })
+}
+
+// headerSpan returns the numbers of the first and last headers of a batch.
+func headerSpan(headers []*types.Header) (uint64, uint64) {
+	if len(headers) == 0 {
+		return 0, 0
+	}
+	return headers[0].Number.Uint64(), headers[len(headers)-1].Number.Uint64()
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+)
+
+// Tests that consensus goroutines are labelled with their operation and block
+// range.
+func TestProfileLabels(t *testing.T) {
+	tests := []struct {
+		op          string
+		first, last uint64
+		blocks      string
+	}{
+		{profileSeal, 7, 7, "7"},
+		{profileVerify, 10, 42, "10-42"},
+	}
+	for i, tt := range tests {
+		labels := make(chan map[string]string)
+		profileGo(tt.op, tt.first, tt.last, func(ctx context.Context) {
+			have := make(map[string]string)
+			pprof.ForLabels(ctx, func(key, value string) bool {
+				have[key] = value
+				return true
+			})
+			labels <- have
+		})
+		have := <-labels
+		if have["engine"] != "clique" || have["operation"] != tt.op || have["blocks"] != tt.blocks {
+			t.Errorf("test %d: labels mismatch: have %v, want operation %s on blocks %s", i, have, tt.op, tt.blocks)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// proposalsKey is the database key the locally queued proposals are stored under.
+var proposalsKey = []byte("clique-proposals")
+
+// Strategies for choosing which of the queued authorization proposals the local
+// signer votes on in the next sealed block.
+const (
+	ProposalRandom     = "random"     // Pick a random proposal (default)
+	ProposalFIFO       = "fifo"       // Push the oldest proposal until it resolves
+	ProposalPriority   = "priority"   // Push the highest priority proposal, oldest first on ties
+	ProposalRoundRobin = "roundrobin" // Cycle through the proposals in queueing order
+)
+
+var (
+	// errDuplicateProposal is returned if a batch of authorization proposals
+	// contains several proposals on the same account.
+	errDuplicateProposal = errors.New("account proposed more than once")
+
+	// errInvalidProposal is returned if an authorization proposal of a batch
+	// authorizes a signer, deauthorizes a non-signer or resizes the signer set
+	// beyond its bounds, given the proposals before it passed.
+	errInvalidProposal = errors.New("proposal not applicable to the signer set")
+
+	// errMissingAuthorization is returned if a single account is proposed without
+	// stating whether to authorize or deauthorize it.
+	errMissingAuthorization = errors.New("missing authorization of proposed account")
+
+	// errBatchAuthorization is returned if a batch of proposals is given along with
+	// a single authorization instead of one per proposal.
+	errBatchAuthorization = errors.New("authorization must be given per proposal in a batch")
+
+	// errEmptyBatch is returned if a batch of proposals contains none.
+	errEmptyBatch = errors.New("empty proposal batch")
+
+	// errUnknownStrategy is returned if the proposal selection strategy to use is
+	// not one of the supported ones.
+	errUnknownStrategy = errors.New("unknown proposal strategy")
+
+	// errConflictingProposal is returned if an account is proposed while already
+	// queued with the opposite authorization, which must be discarded first.
+	errConflictingProposal = errors.New("account already proposed with the opposite authorization")
+)
+
+// ProposalEntry is a single authorization proposal of a batch.
+type ProposalEntry struct {
+	Address   common.Address `json:"address"`        // Account to change the authorization of
+	Authorize bool           `json:"authorize"`      // Whether to authorize or deauthorize the account
+	Memo      string         `json:"memo,omitempty"` // Justification to record along with the proposal
+}
+
+// validateProposals checks that every proposal of a batch makes sense on the
+// given snapshot, assuming the proposals before it all passed.
+func validateProposals(snap *Snapshot, entries []ProposalEntry) error {
+	snap = snap.copy()
+
+	seen := make(map[common.Address]struct{}, len(entries))
+	for i, entry := range entries {
+		if _, ok := seen[entry.Address]; ok {
+			return fmt.Errorf("proposal %d (%s): %w", i, entry.Address, errDuplicateProposal)
+		}
+		seen[entry.Address] = struct{}{}
+
+		if !snap.validVote(entry.Address, entry.Authorize) {
+			return fmt.Errorf("proposal %d (%s): %w", i, entry.Address, errInvalidProposal)
+		}
+		if entry.Authorize {
+			snap.addSigner(entry.Address)
+		} else {
+			snap.removeSigner(entry.Address)
+		}
+	}
+	return nil
+}
+
+// proposalInfo is the queueing metadata of an authorization proposal.
+type proposalInfo struct {
+	Seq      uint64 `json:"seq"`            // Sequence number the proposal was queued with
+	Priority int    `json:"priority"`       // Priority of the proposal for the priority strategy
+	Memo     string `json:"memo,omitempty"` // Justification the operator attached to the proposal
+}
+
+// storedProposals is the database representation of the proposals the local
+// signer is pushing.
+type storedProposals struct {
+	Addresses    map[common.Address]bool           `json:"addresses"`              // Authorization proposals
+	Limits       map[uint]bool                     `json:"limits"`                 // Signer limit proposals
+	Info         map[common.Address]proposalInfo   `json:"info,omitempty"`         // Queueing metadata of the authorization proposals
+	Seq          uint64                            `json:"seq,omitempty"`          // Last sequence number handed out
+	Permits      map[common.Address]bool           `json:"permits,omitempty"`      // Sender permission proposals
+	Periods      map[common.Address]uint64         `json:"periods,omitempty"`      // Signer period proposals
+	Replacements map[common.Address]common.Address `json:"replacements,omitempty"` // Signer replacement proposals
+}
+
+// loadProposals reloads the proposals queued before a restart, so a signer keeps
+// voting on its pending campaigns.
+func (c *Clique) loadProposals() {
+	if c.db == nil {
+		return
+	}
+	blob, err := c.db.Get(proposalsKey)
+	if err != nil {
+		return
+	}
+	var stored storedProposals
+	if err := json.Unmarshal(blob, &stored); err != nil {
+		log.Warn("Failed to decode clique proposals", "err", err)
+		return
+	}
+	for address, auth := range stored.Addresses {
+		c.proposals[address] = auth
+	}
+	for limit, auth := range stored.Limits {
+		c.signerLimitProposals[limit] = auth
+	}
+	for address, info := range stored.Info {
+		c.proposalInfo[address] = info
+	}
+	for address, permit := range stored.Permits {
+		c.permitProposals[address] = permit
+	}
+	for address, period := range stored.Periods {
+		c.periodProposals[address] = period
+	}
+	for old, new := range stored.Replacements {
+		c.replaceProposals[old] = new
+	}
+	c.proposalSeq = stored.Seq
+	log.Info("Loaded clique proposals", "addresses", len(stored.Addresses), "limits", len(stored.Limits), "permits", len(stored.Permits))
+}
+
+// storeProposals persists the currently queued proposals. The caller must hold
+// the engine lock.
+func (c *Clique) storeProposals() {
+	if c.db == nil {
+		return
+	}
+	blob, err := json.Marshal(&storedProposals{
+		Addresses:    c.proposals,
+		Limits:       c.signerLimitProposals,
+		Info:         c.proposalInfo,
+		Seq:          c.proposalSeq,
+		Permits:      c.permitProposals,
+		Periods:      c.periodProposals,
+		Replacements: c.replaceProposals,
+	})
+	if err != nil {
+		log.Warn("Failed to encode clique proposals", "err", err)
+		return
+	}
+	if err := c.db.Put(proposalsKey, blob); err != nil {
+		log.Warn("Failed to store clique proposals", "err", err)
+	}
+}
+
+// validProposalStrategy reports whether the proposal selection strategy is known.
+func validProposalStrategy(strategy string) bool {
+	switch strategy {
+	case ProposalRandom, ProposalFIFO, ProposalPriority, ProposalRoundRobin:
+		return true
+	}
+	return false
+}
+
+// SetProposalStrategy sets the order the local signer votes on its queued
+// authorization proposals in. An empty strategy votes in random order.
+func (c *Clique) SetProposalStrategy(strategy string) error {
+	if strategy != "" && !validProposalStrategy(strategy) {
+		return fmt.Errorf("%w: %q", errUnknownStrategy, strategy)
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.strategy = strategy
+	return nil
+}
+
+// queueProposal queues an authorization proposal, keeping the queue position of
+// an already queued one. The caller must hold the engine lock.
+func (c *Clique) queueProposal(address common.Address, auth bool, priority int) {
+	info, ok := c.proposalInfo[address]
+	if !ok {
+		c.proposalSeq++
+		info.Seq = c.proposalSeq
+	}
+	info.Priority, info.Memo = priority, ""
+
+	c.proposals[address] = auth
+	c.proposalInfo[address] = info
+	c.storeProposals()
+}
+
+// proposalQueued reports whether an authorization proposal is already queued,
+// failing if the account is queued with the opposite authorization. The caller
+// must hold the engine lock.
+func (c *Clique) proposalQueued(address common.Address, auth bool) (bool, error) {
+	queued, ok := c.proposals[address]
+	if !ok {
+		return false, nil
+	}
+	if queued != auth {
+		return false, errConflictingProposal
+	}
+	return true, nil
+}
+
+// dropProposal removes an authorization proposal from the queue. The caller must
+// hold the engine lock.
+func (c *Clique) dropProposal(address common.Address) {
+	delete(c.proposals, address)
+	delete(c.proposalInfo, address)
+	delete(c.reveals, address)
+	c.storeProposals()
+}
+
+// selectProposal picks the proposal to vote on from the currently valid ones,
+// according to the configured strategy. The caller must hold the engine lock.
+func (c *Clique) selectProposal(addresses []common.Address) common.Address {
+	if c.strategy == "" || c.strategy == ProposalRandom {
+		return addresses[rand.Intn(len(addresses))]
+	}
+	// Order the candidates by queueing order, breaking ties (e.g. proposals not
+	// queued via the API) by address to stay deterministic
+	before := func(a, b common.Address) bool {
+		if ia, ib := c.proposalInfo[a], c.proposalInfo[b]; ia.Seq != ib.Seq {
+			return ia.Seq < ib.Seq
+		}
+		return bytes.Compare(a[:], b[:]) < 0
+	}
+	best := addresses[0]
+	switch c.strategy {
+	case ProposalFIFO:
+		for _, address := range addresses[1:] {
+			if before(address, best) {
+				best = address
+			}
+		}
+	case ProposalPriority:
+		for _, address := range addresses[1:] {
+			have, want := c.proposalInfo[best].Priority, c.proposalInfo[address].Priority
+			if want > have || (want == have && before(address, best)) {
+				best = address
+			}
+		}
+	case ProposalRoundRobin:
+		// Pick the first proposal queued after the last one voted on, wrapping
+		// around to the oldest one
+		var next *common.Address
+		for i, address := range addresses {
+			if before(address, best) {
+				best = address
+			}
+			if c.proposalInfo[address].Seq > c.proposalCursor && (next == nil || before(address, *next)) {
+				next = &addresses[i]
+			}
+		}
+		if next != nil {
+			best = *next
+		}
+		c.proposalCursor = c.proposalInfo[best].Seq
+	}
+	return best
+}
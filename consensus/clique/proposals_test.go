@@ -0,0 +1,394 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// proposeAccount queues a single authorization proposal through the API.
+func proposeAccount(api *API, address common.Address, auth bool) error {
+	return api.Propose(context.Background(), proposalTarget{Address: &address}, &auth, nil)
+}
+
+// Tests that locally queued proposals survive an engine restart.
+func TestProposalPersistence(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		config = &params.CliqueConfig{Epoch: 30000}
+		added  = common.HexToAddress("0x01")
+		kicked = common.HexToAddress("0x02")
+	)
+	chain := newTesterSignedChain(newTesterAccountPool(), config, []string{"A"}, 0)
+
+	api := &API{chain: chain, clique: New(config, db)}
+	proposeAccount(api, added, true)
+	proposeAccount(api, kicked, false)
+	proposeAccount(api, common.HexToAddress("0x03"), true)
+	api.Discard(context.Background(), common.HexToAddress("0x03"))
+	api.Votingpercentage(context.Background(), 0, 75, true)
+
+	// Restart the engine and ensure the proposals are reloaded
+	api = &API{clique: New(config, db)}
+	if proposals := api.Proposals(); len(proposals) != 2 || !proposals[added].Authorize || proposals[kicked].Authorize {
+		t.Errorf("reloaded proposals mismatch: have %v", proposals)
+	}
+	if limits := api.clique.signerLimitProposals; len(limits) != 1 || !limits[75] {
+		t.Errorf("reloaded limit proposals mismatch: have %v", limits)
+	}
+	// Discard everything and ensure nothing is reloaded
+	api.DiscardAll(context.Background())
+
+	api = &API{clique: New(config, db)}
+	if proposals := api.Proposals(); len(proposals) != 0 {
+		t.Errorf("discarded proposals reloaded: %v", proposals)
+	}
+	if limits := api.clique.signerLimitProposals; len(limits) != 0 {
+		t.Errorf("discarded limit proposals reloaded: %v", limits)
+	}
+}
+
+// Tests that the configured strategy decides the order the queued proposals are
+// voted on.
+func TestProposalSelection(t *testing.T) {
+	var (
+		first  = common.HexToAddress("0x03")
+		second = common.HexToAddress("0x01")
+		third  = common.HexToAddress("0x02")
+	)
+	tests := []struct {
+		strategy string
+		want     []common.Address
+	}{
+		{ProposalFIFO, []common.Address{first, first, first, first}},
+		{ProposalPriority, []common.Address{second, second, second, second}},
+		{ProposalRoundRobin, []common.Address{first, second, third, first}},
+	}
+	for _, tt := range tests {
+		engine := New(&params.CliqueConfig{Epoch: 30000}, rawdb.NewMemoryDatabase())
+		if err := engine.SetProposalStrategy(tt.strategy); err != nil {
+			t.Fatalf("%s: failed to set strategy: %v", tt.strategy, err)
+		}
+		engine.queueProposal(first, true, 0)
+		engine.queueProposal(second, true, 2)
+		engine.queueProposal(third, false, 1)
+
+		candidates := []common.Address{third, second, first}
+		for i, want := range tt.want {
+			if have := engine.selectProposal(candidates); have != want {
+				t.Errorf("%s: vote %d: proposal mismatch: have %x, want %x", tt.strategy, i, have, want)
+			}
+		}
+	}
+}
+
+// Tests that batches of proposals are validated as a whole against the signer
+// set, and queued entirely or not at all.
+func TestProposalBatch(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 3)
+	)
+	api := &API{chain: chain, clique: New(config, rawdb.NewMemoryDatabase())}
+
+	// Both a single account and a batch must be accepted as the target
+	var target proposalTarget
+	if err := json.Unmarshal([]byte(`"0x0000000000000000000000000000000000000001"`), &target); err != nil || target.Address == nil || target.Batch != nil {
+		t.Fatalf("single account target mismatch: have %+v, err %v", target, err)
+	}
+	if err := json.Unmarshal([]byte(`[{"address":"0x0000000000000000000000000000000000000001","authorize":true}]`), &target); err != nil || target.Address != nil || len(target.Batch) != 1 {
+		t.Fatalf("batch target mismatch: have %+v, err %v", target, err)
+	}
+	// Invalid batches must be rejected without queueing any of their proposals
+	auth := true
+	tests := []struct {
+		batch []ProposalEntry
+		auth  *bool
+		err   error
+	}{
+		{batch: nil, err: errEmptyBatch},
+		{batch: []ProposalEntry{{Address: accounts.address("D"), Authorize: true}}, auth: &auth, err: errBatchAuthorization},
+		{batch: []ProposalEntry{{Address: accounts.address("D"), Authorize: true}, {Address: accounts.address("A"), Authorize: true}}, err: errInvalidProposal},
+		{batch: []ProposalEntry{{Address: accounts.address("D"), Authorize: true}, {Address: accounts.address("E"), Authorize: false}}, err: errInvalidProposal},
+		{batch: []ProposalEntry{{Address: accounts.address("D"), Authorize: true}, {Address: accounts.address("D"), Authorize: false}}, err: errDuplicateProposal},
+	}
+	for i, tt := range tests {
+		if err := api.Propose(context.Background(), proposalTarget{Batch: tt.batch}, tt.auth, nil); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if proposals := api.Proposals(); len(proposals) != 0 {
+			t.Errorf("test %d: rejected batch queued: %v", i, proposals)
+		}
+	}
+	// A valid batch must be queued entirely
+	batch := []ProposalEntry{
+		{Address: accounts.address("D"), Authorize: true},
+		{Address: accounts.address("C"), Authorize: false},
+		{Address: accounts.address("E"), Authorize: true},
+	}
+	if err := api.Propose(context.Background(), proposalTarget{Batch: batch}, nil, nil); err != nil {
+		t.Fatalf("failed to propose batch: %v", err)
+	}
+	proposals := api.Proposals()
+	if len(proposals) != len(batch) {
+		t.Fatalf("queued proposal count mismatch: have %d, want %d", len(proposals), len(batch))
+	}
+	for _, entry := range batch {
+		if proposal, ok := proposals[entry.Address]; !ok || proposal.Authorize != entry.Authorize {
+			t.Errorf("proposal %s mismatch: have %v (queued %v), want %v", entry.Address, proposal.Authorize, ok, entry.Authorize)
+		}
+	}
+	if err := api.Propose(context.Background(), proposalTarget{Address: &batch[0].Address}, nil, nil); err != errMissingAuthorization {
+		t.Errorf("missing authorization error mismatch: have %v, want %v", err, errMissingAuthorization)
+	}
+}
+
+// Tests that proposing an already queued account keeps the original proposal,
+// and proposing it with the opposite authorization is refused.
+func TestProposalDeduplication(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 3)
+		memo     = "onboarding the new validator"
+	)
+	api := &API{chain: chain, clique: New(config, rawdb.NewMemoryDatabase())}
+
+	auth, added := true, accounts.address("D")
+	if err := api.Propose(context.Background(), proposalTarget{Address: &added}, &auth, &memo); err != nil {
+		t.Fatalf("failed to propose account: %v", err)
+	}
+	seq := api.clique.proposalInfo[added].Seq
+
+	// Proposing the same account again must keep the queued proposal untouched
+	if err := proposeAccount(api, added, true); err != nil {
+		t.Fatalf("failed to repropose account: %v", err)
+	}
+	if info := api.clique.proposalInfo[added]; info.Seq != seq || info.Memo != memo {
+		t.Errorf("reproposed account requeued: have %+v, want seq %d, memo %q", info, seq, memo)
+	}
+	batch := []ProposalEntry{
+		{Address: added, Authorize: true},
+		{Address: accounts.address("E"), Authorize: true},
+	}
+	if err := api.Propose(context.Background(), proposalTarget{Batch: batch}, nil, nil); err != nil {
+		t.Fatalf("failed to propose overlapping batch: %v", err)
+	}
+	if proposals := api.Proposals(); len(proposals) != 2 || api.clique.proposalInfo[added].Seq != seq {
+		t.Errorf("overlapping batch mismatch: have %v", proposals)
+	}
+	// Proposing the opposite authorization must be refused in all forms
+	if err := proposeAccount(api, added, false); err != errConflictingProposal {
+		t.Errorf("conflicting proposal error mismatch: have %v, want %v", err, errConflictingProposal)
+	}
+	if err := api.ProposeWithPriority(context.Background(), added, false, 1); err != errConflictingProposal {
+		t.Errorf("conflicting priority proposal error mismatch: have %v, want %v", err, errConflictingProposal)
+	}
+	if err := proposeAccount(api, accounts.address("F"), false); err != nil {
+		t.Fatalf("failed to propose account: %v", err)
+	}
+	batch = []ProposalEntry{
+		{Address: accounts.address("G"), Authorize: true},
+		{Address: accounts.address("F"), Authorize: true},
+	}
+	if err := api.Propose(context.Background(), proposalTarget{Batch: batch}, nil, nil); !errors.Is(err, errConflictingProposal) {
+		t.Errorf("conflicting batch error mismatch: have %v, want %v", err, errConflictingProposal)
+	}
+	if proposals := api.Proposals(); len(proposals) != 3 || !proposals[added].Authorize || proposals[accounts.address("F")].Authorize {
+		t.Errorf("conflicting proposals queued: %v", proposals)
+	}
+	// Once discarded, the opposite authorization may be proposed
+	api.Discard(context.Background(), added)
+	if err := proposeAccount(api, added, false); err != nil {
+		t.Errorf("failed to propose discarded account: %v", err)
+	}
+}
+
+// Tests that memos attached to proposals are reported along with the queued
+// proposals, survive restarts and show up in the governance history.
+func TestProposalMemos(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 3)
+		db       = rawdb.NewMemoryDatabase()
+		memo     = "onboarding the new validator"
+	)
+	api := &API{chain: chain, clique: New(config, db)}
+
+	auth, added := true, accounts.address("D")
+	if err := api.Propose(context.Background(), proposalTarget{Address: &added}, &auth, &memo); err != nil {
+		t.Fatalf("failed to propose account: %v", err)
+	}
+	if proposal := api.Proposals()[accounts.address("D")]; !proposal.Authorize || proposal.Memo != memo {
+		t.Errorf("queued proposal mismatch: have %+v", proposal)
+	}
+	api = &API{chain: chain, clique: New(config, db)}
+	if proposal := api.Proposals()[accounts.address("D")]; proposal.Memo != memo {
+		t.Errorf("reloaded memo mismatch: have %q, want %q", proposal.Memo, memo)
+	}
+	// Cast a vote on the proposal and ensure the history carries the memo
+	parent := chain.headers[3]
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(4),
+		Time:       parent.Time + 1,
+		Difficulty: diffInTurn,
+		Coinbase:   accounts.address("D"),
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	copy(header.Nonce[:], nonceAuthVote)
+	accounts.sign(header, "B")
+	chain.headers = append(chain.headers, header)
+
+	var records []*GovernanceRecord
+	if err := api.clique.GovernanceHistory(chain, 0, 4, func(record *GovernanceRecord) error {
+		records = append(records, record)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to replay governance history: %v", err)
+	}
+	if len(records) != 1 || records[0].Address != accounts.address("D") || records[0].Memo != memo {
+		t.Fatalf("governance history mismatch: have %d records", len(records))
+	}
+	// A proposal in the opposite direction must not inherit the memo
+	if found := findMemo(db, accounts.address("D"), false, 4); found != "" {
+		t.Errorf("memo attributed to opposite proposal: %q", found)
+	}
+}
+
+// Tests that proposals can only be changed by RPC clients granted the admin scope
+// if the endpoint restricts access by scope, while reporting them remains public.
+func TestProposalAdminScope(t *testing.T) {
+	server := rpc.NewServer()
+	defer server.Stop()
+
+	api := &API{clique: New(&params.CliqueConfig{Epoch: 30000}, rawdb.NewMemoryDatabase())}
+	if err := server.RegisterName("clique", api); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	// Grant the scopes listed in a header, none if the header is missing
+	httpsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var scopes []string
+		if scope := r.Header.Get("Scope"); scope != "" {
+			scopes = []string{scope}
+		}
+		server.ServeHTTP(w, r.WithContext(rpc.WithScopes(r.Context(), scopes)))
+	}))
+	defer httpsrv.Close()
+
+	address := common.HexToAddress("0x01")
+	for i, tt := range []struct {
+		scope string
+		err   bool
+	}{
+		{scope: "", err: true},
+		{scope: "other", err: true},
+		{scope: adminScope, err: false},
+	} {
+		client, err := rpc.DialHTTP(httpsrv.URL)
+		if err != nil {
+			t.Fatalf("test %d: failed to dial endpoint: %v", i, err)
+		}
+		if tt.scope != "" {
+			client.SetHeader("Scope", tt.scope)
+		}
+		if err := client.Call(nil, "clique_propose", address, true); (err != nil) != tt.err {
+			t.Errorf("test %d: propose error mismatch: have %v, want error %v", i, err, tt.err)
+		}
+		if err := client.Call(nil, "clique_discard", address); (err != nil) != tt.err {
+			t.Errorf("test %d: discard error mismatch: have %v, want error %v", i, err, tt.err)
+		}
+		var proposals map[common.Address]queuedProposal
+		if err := client.Call(&proposals, "clique_proposals"); err != nil {
+			t.Errorf("test %d: failed to retrieve proposals: %v", i, err)
+		}
+		client.Close()
+	}
+	// In-process callers are granted every scope
+	if err := proposeAccount(api, address, true); err != nil {
+		t.Errorf("in-process proposal rejected: %v", err)
+	}
+}
+
+// Tests that every method changing the state of the node refuses RPC clients not
+// granted the admin scope.
+func TestAdminScopeMethods(t *testing.T) {
+	server := rpc.NewServer()
+	defer server.Stop()
+
+	api := &API{clique: New(&params.CliqueConfig{Epoch: 30000}, rawdb.NewMemoryDatabase())}
+	if err := server.RegisterName("clique", api); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	httpsrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.ServeHTTP(w, r.WithContext(rpc.WithScopes(r.Context(), []string{})))
+	}))
+	defer httpsrv.Close()
+
+	client, err := rpc.DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatalf("failed to dial endpoint: %v", err)
+	}
+	defer client.Close()
+
+	var (
+		address = common.HexToAddress("0x01")
+		other   = common.HexToAddress("0x02")
+	)
+	tests := []struct {
+		method string
+		args   []interface{}
+	}{
+		{"clique_importSnapshot", []interface{}{map[string]interface{}{}}},
+		{"clique_propose", []interface{}{address, true}},
+		{"clique_proposeWithPriority", []interface{}{address, true, 1}},
+		{"clique_votingpercentage", []interface{}{0, 75, true}},
+		{"clique_discard", []interface{}{address}},
+		{"clique_proposePermit", []interface{}{address, true}},
+		{"clique_discardPermit", []interface{}{address}},
+		{"clique_proposePeriod", []interface{}{address, 10}},
+		{"clique_discardPeriod", []interface{}{address}},
+		{"clique_proposeReplacement", []interface{}{address, other}},
+		{"clique_discardReplacement", []interface{}{address}},
+		{"clique_resign", nil},
+		{"clique_discardResignation", nil},
+		{"clique_setFeeRecipient", []interface{}{address}},
+		{"clique_discardAll", nil},
+		{"clique_proposeOverride", []interface{}{30000, []common.Address{address}, []string{}}},
+		{"clique_discardOverride", []interface{}{30000}},
+		{"clique_registerMetadata", []interface{}{address, SignerMetadata{}, []string{}}},
+	}
+	for _, tt := range tests {
+		if err := client.Call(nil, tt.method, tt.args...); err == nil || err.Error() != errAdminScope.Error() {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.method, err, errAdminScope)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+)
+
+// stallPeriods is the number of block periods without a new block after which the
+// chain is considered stalled.
+const stallPeriods = 5
+
+// ErrQuorumLost is returned if the chain stalled because too many signers went
+// offline for the remaining ones to seal blocks.
+var ErrQuorumLost = errors.New("clique quorum lost, chain stalled")
+
+// QuorumStatus reports whether enough signers are online for the chain to make
+// progress. A chain is degraded if it stalled while signers were eligible to seal
+// the next block, meaning they went offline and too few of the signers that are
+// still online are permitted to seal by the recent signer limit.
+type QuorumStatus struct {
+	Degraded bool             `json:"degraded"` // Whether the chain stalled due to a lost quorum
+	Stalled  bool             `json:"stalled"`  // Whether no block was sealed within the stall timeout
+	Number   uint64           `json:"number"`   // Number of the chain head
+	Age      uint64           `json:"age"`      // Seconds elapsed since the chain head was sealed
+	Online   int              `json:"online"`   // Number of signers that sealed within the recent signer window
+	Required int              `json:"required"` // Number of online signers needed to keep sealing blocks
+	Missing  []common.Address `json:"missing"`  // Signers eligible to seal the next block (offline if stalled)
+}
+
+// QuorumStatus checks whether the chain stalled while signers were eligible to
+// seal the next block, in which case they are deemed offline.
+func (c *Clique) QuorumStatus(chain consensus.ChainHeaderReader) (*QuorumStatus, error) {
+	return c.quorumStatus(chain, time.Now())
+}
+
+func (c *Clique) quorumStatus(chain consensus.ChainHeaderReader, now time.Time) (*QuorumStatus, error) {
+	head := chain.CurrentHeader()
+	if head == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	status := &QuorumStatus{
+		Number:   head.Number.Uint64(),
+		Required: int(snap.recentsWindow(head.Number.Uint64() + 1)),
+	}
+	if sealed := time.Unix(int64(head.Time), 0); now.After(sealed) {
+		status.Age = uint64(now.Sub(sealed) / time.Second)
+	}
+	// Zero period chains only seal blocks on demand, they never stall
+	if period := c.config.Period; period > 0 && status.Age > stallPeriods*period {
+		status.Stalled = true
+	}
+	// Signers among the recent ones are online but not permitted to seal the next
+	// block, any other signer is
+	var (
+		next   = status.Number + 1
+		limit  = snap.recentsWindow(next)
+		recent = make(map[common.Address]struct{})
+	)
+	for seen, signer := range snap.Recents {
+		if next < limit || seen > next-limit {
+			recent[signer] = struct{}{}
+		}
+	}
+	for signer := range snap.Signers {
+		if _, ok := recent[signer]; ok {
+			status.Online++
+		} else {
+			status.Missing = append(status.Missing, signer)
+		}
+	}
+	sort.Sort(signersAscending(status.Missing))
+
+	status.Degraded = status.Stalled && len(status.Missing) > 0
+	return status, nil
+}
+
+// CheckQuorum returns ErrQuorumLost if the chain is degraded due to a lost quorum,
+// e.g. to reject transactions that can't be included until it recovers.
+func (c *Clique) CheckQuorum(chain consensus.ChainHeaderReader) error {
+	status, err := c.QuorumStatus(chain)
+	if err != nil {
+		return err
+	}
+	if status.Degraded {
+		return ErrQuorumLost
+	}
+	return nil
+}
+
+// HealthHandler returns an HTTP handler reporting the quorum status of the chain,
+// responding with 503 Service Unavailable while it's degraded.
+func (c *Clique) HealthHandler(chain consensus.ChainHeaderReader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, err := c.QuorumStatus(chain)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if status.Degraded {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
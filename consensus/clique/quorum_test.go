@@ -0,0 +1,97 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a chain stalling while signers are eligible to seal is reported as
+// degraded, along with the signers deemed offline.
+func TestQuorumStatus(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Period: 1, Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C", "D"}, 0)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	// Seal a few blocks without D, after which only A is permitted to seal
+	for i, signer := range []string{"A", "B", "C", "A", "B", "C"} {
+		parent := chain.headers[i]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Time:       parent.Time + 1,
+			Difficulty: diffNoTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, signer)
+		chain.headers = append(chain.headers, header)
+	}
+	sealed := time.Unix(int64(chain.CurrentHeader().Time), 0)
+
+	status, err := engine.quorumStatus(chain, sealed.Add(time.Second))
+	if err != nil {
+		t.Fatalf("failed to retrieve quorum status: %v", err)
+	}
+	if status.Stalled || status.Degraded {
+		t.Errorf("progressing chain reported stalled (%v) or degraded (%v)", status.Stalled, status.Degraded)
+	}
+	status, err = engine.quorumStatus(chain, sealed.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to retrieve quorum status: %v", err)
+	}
+	missing := []common.Address{accounts.address("A"), accounts.address("D")}
+	sort.Sort(signersAscending(missing))
+
+	want := &QuorumStatus{Degraded: true, Stalled: true, Number: 6, Age: 60, Online: 2, Required: 3, Missing: missing}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("stalled quorum status mismatch: have %+v, want %+v", status, want)
+	}
+	if err := engine.CheckQuorum(chain); err != ErrQuorumLost {
+		t.Errorf("quorum check error mismatch: have %v, want %v", err, ErrQuorumLost)
+	}
+	// The health endpoint reports the degradation, unless blocks are sealed on demand
+	for _, tt := range []struct {
+		period uint64
+		code   int
+	}{
+		{period: 1, code: http.StatusServiceUnavailable},
+		{period: 0, code: http.StatusOK},
+	} {
+		conf := *config
+		conf.Period = tt.period
+		chain.config.Clique = &conf
+
+		res := httptest.NewRecorder()
+		New(&conf, rawdb.NewMemoryDatabase()).HealthHandler(chain).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/clique/health", nil))
+		if res.Code != tt.code {
+			t.Errorf("period %d: health status mismatch: have %d, want %d", tt.period, res.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+)
+
+// readinessSlots is the default number of recent in-turn slots of the local
+// signer the readiness probe expects at least one sealed block in.
+const readinessSlots = 3
+
+// ReadinessStatus reports whether a node is fit to take part in sealing, meant
+// for orchestrators to restart or alert on unhealthy sealers. A node without a
+// local signer is ready as soon as it's synced.
+type ReadinessStatus struct {
+	Ready      bool           `json:"ready"`      // Whether the node is synced and, if sealing, healthy
+	Synced     bool           `json:"synced"`     // Whether the node finished its initial sync
+	Number     uint64         `json:"number"`     // Number of the chain head
+	Signer     common.Address `json:"signer"`     // Local signer, zero if the node doesn't seal
+	Authorized bool           `json:"authorized"` // Whether the local signer is in the signer set at the head
+	Slots      int            `json:"slots"`      // Recent in-turn slots of the local signer inspected
+	Sealed     int            `json:"sealed"`     // Inspected in-turn slots the local signer sealed
+}
+
+// ReadinessStatus inspects the last in-turn slots of the local signer, up to the
+// given number of them, and reports whether it sealed in any of them.
+func (c *Clique) ReadinessStatus(chain consensus.ChainHeaderReader, synced bool, slots int) (*ReadinessStatus, error) {
+	head := chain.CurrentHeader()
+	if head == nil {
+		return nil, errUnknownBlock
+	}
+	c.lock.RLock()
+	signer := c.signer
+	c.lock.RUnlock()
+
+	status := &ReadinessStatus{
+		Synced: synced,
+		Number: head.Number.Uint64(),
+		Signer: signer,
+	}
+	if signer == (common.Address{}) {
+		status.Ready = synced
+		return status, nil
+	}
+	snap, err := c.snapshotFor(triggerRPC, chain, status.Number, head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	_, status.Authorized = snap.Signers[signer]
+	if !status.Authorized {
+		return status, nil
+	}
+	// Walk back over a bounded number of rounds looking for the local signer's
+	// turns, a signer that only just joined may not have had any yet
+	var (
+		header = head
+		rounds = uint64(slots) * uint64(len(snap.Signers))
+	)
+	for i := uint64(0); i < rounds && status.Slots < slots && header.Number.Uint64() > 0; i++ {
+		number := header.Number.Uint64()
+		parent, err := c.snapshotFor(triggerRPC, chain, number-1, header.ParentHash, nil)
+		if err != nil {
+			return nil, err
+		}
+		sealer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return nil, err
+		}
+		if rec := parent.seal(header, sealer); rec.Turn == signer {
+			status.Slots++
+			if rec.Signer == signer {
+				status.Sealed++
+			}
+		}
+		if header = chain.GetHeader(header.ParentHash, number-1); header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+	}
+	status.Ready = synced && (status.Slots == 0 || status.Sealed > 0)
+	return status, nil
+}
+
+// ReadinessHandler returns an HTTP handler reporting the readiness status of the
+// node, responding with 503 Service Unavailable unless it's ready. The number of
+// in-turn slots inspected can be set by the slots query parameter. The synced
+// callback reports whether the node finished its initial sync.
+func (c *Clique) ReadinessHandler(chain consensus.ChainHeaderReader, synced func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slots := readinessSlots
+		if param := r.URL.Query().Get("slots"); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil || n <= 0 {
+				http.Error(w, "invalid slots parameter", http.StatusBadRequest)
+				return
+			}
+			slots = n
+		}
+		status, err := c.ReadinessStatus(chain, synced(), slots)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
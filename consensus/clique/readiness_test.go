@@ -0,0 +1,123 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the readiness status reports sealers missing all their recent
+// in-turn slots, unauthorized sealers and unsynced nodes as not ready.
+func TestReadinessStatus(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.CliqueConfig{Period: 1, Epoch: 30000, SignerLimit: 50}
+
+	names := map[common.Address]string{}
+	sorted := make([]common.Address, 0, 4)
+	for _, name := range []string{"A", "B", "C", "D"} {
+		names[accounts.address(name)] = name
+		sorted = append(sorted, accounts.address(name))
+	}
+	sort.Sort(signersAscending(sorted))
+
+	// makeChain seals 12 blocks, each by its in-turn signer unless A is skipped,
+	// in which case the other signers take turns sealing all blocks
+	makeChain := func(skipA bool) *testerHeaderChain {
+		chain := newTesterSignedChain(accounts, config, []string{"A", "B", "C", "D"}, 0)
+		var others []string
+		for _, signer := range sorted {
+			if names[signer] != "A" {
+				others = append(others, names[signer])
+			}
+		}
+		for i := 1; i <= 12; i++ {
+			parent := chain.headers[i-1]
+			header := &types.Header{
+				ParentHash: parent.Hash(),
+				Number:     big.NewInt(int64(i)),
+				Time:       parent.Time + 1,
+				Difficulty: diffNoTurn,
+				Extra:      make([]byte, extraVanity+extraSeal),
+			}
+			if skipA {
+				accounts.sign(header, others[i%len(others)])
+			} else {
+				accounts.sign(header, names[sorted[i%len(sorted)]])
+			}
+			chain.headers = append(chain.headers, header)
+		}
+		return chain
+	}
+	healthy, missing := makeChain(false), makeChain(true)
+
+	tests := []struct {
+		chain  *testerHeaderChain
+		signer string
+		synced bool
+		want   ReadinessStatus
+	}{
+		{healthy, "", true, ReadinessStatus{Ready: true, Synced: true}},
+		{healthy, "", false, ReadinessStatus{}},
+		{healthy, "A", true, ReadinessStatus{Ready: true, Synced: true, Authorized: true, Slots: 3, Sealed: 3}},
+		{healthy, "A", false, ReadinessStatus{Synced: false, Authorized: true, Slots: 3, Sealed: 3}},
+		{missing, "A", true, ReadinessStatus{Synced: true, Authorized: true, Slots: 3}},
+		{healthy, "E", true, ReadinessStatus{Synced: true}},
+	}
+	for i, tt := range tests {
+		engine := New(config, rawdb.NewMemoryDatabase())
+		if tt.signer != "" {
+			engine.Authorize(accounts.address(tt.signer), nil)
+			tt.want.Signer = accounts.address(tt.signer)
+		}
+		tt.want.Number = 12
+
+		status, err := engine.ReadinessStatus(tt.chain, tt.synced, readinessSlots)
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve readiness status: %v", i, err)
+		}
+		if *status != tt.want {
+			t.Errorf("test %d: readiness status mismatch: have %+v, want %+v", i, status, tt.want)
+		}
+		code := http.StatusOK
+		if !tt.want.Ready {
+			code = http.StatusServiceUnavailable
+		}
+		res := httptest.NewRecorder()
+		engine.ReadinessHandler(tt.chain, func() bool { return tt.synced }).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/health/consensus", nil))
+		if res.Code != code {
+			t.Errorf("test %d: readiness code mismatch: have %d, want %d", i, res.Code, code)
+		}
+	}
+	// Malformed slot counts are rejected
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.Authorize(accounts.address("A"), nil)
+
+	res := httptest.NewRecorder()
+	engine.ReadinessHandler(missing, func() bool { return true }).ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/health/consensus?slots=x", nil))
+	if res.Code != http.StatusBadRequest {
+		t.Errorf("invalid slots parameter code mismatch: have %d, want %d", res.Code, http.StatusBadRequest)
+	}
+}
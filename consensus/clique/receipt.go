@@ -0,0 +1,127 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// GovernanceReceipt records the governance effects of a block, so explorers and
+// light clients don't need to rederive them from the headers. It's embedded RLP
+// encoded into the consensus data section of the block body. The header doesn't
+// commit to it and relaying peers may strip or alter it, so it's advisory only:
+// blocks are never rejected over it and the node itself rederives it.
+type GovernanceReceipt struct {
+	Votes       []ReceiptVote       `json:"votes"`       // Votes tallied up in the block
+	Resolutions []ReceiptResolution `json:"resolutions"` // Proposals resolved in the block
+}
+
+// ReceiptVote is a vote tallied up in a block.
+type ReceiptVote struct {
+	Signer   common.Address `json:"signer"`             // Authorized signer that cast the vote
+	Kind     ProposalKind   `json:"kind"`               // Type of the proposal voted on
+	Address  common.Address `json:"address"`            // Account voted on (membership votes)
+	Limit    uint           `json:"limit,omitempty"`    // Signer limit voted on (limit votes)
+	Period   uint64         `json:"period,omitempty"`   // Minimum block period voted on (period votes)
+	Replaced common.Address `json:"replaced,omitempty"` // Signer voted on swapping for the account (replacement votes)
+	Tally    uint64         `json:"tally"`              // Running tally of the proposal, including this vote
+	Passed   bool           `json:"passed"`             // Whether the vote made the proposal pass
+}
+
+// ReceiptResolution is a proposal resolved in a block.
+type ReceiptResolution struct {
+	Kind      ProposalKind     `json:"kind"`                // Type of the proposal that passed
+	Address   common.Address   `json:"address"`             // Account whose authorization changed (membership votes)
+	Limit     uint             `json:"limit,omitempty"`     // New signer limit (limit votes)
+	PrevLimit uint             `json:"prevLimit,omitempty"` // Signer limit before the change (limit votes)
+	Period    uint64           `json:"period,omitempty"`    // Minimum block period granted to the signer (period votes)
+	Replaced  common.Address   `json:"replaced,omitempty"`  // Signer swapped for the account (replacement votes)
+	Signers   []common.Address `json:"signers,omitempty"`   // Signer set installed by the proposal (overrides)
+	Votes     []AuditVote      `json:"votes"`               // Trail of votes that made the proposal pass
+}
+
+// governanceReceipt assembles the receipt of the votes counted and proposals
+// resolved by applying a single header.
+func governanceReceipt(snap *Snapshot) *GovernanceReceipt {
+	receipt := &GovernanceReceipt{
+		Votes:       make([]ReceiptVote, 0, len(snap.observed)),
+		Resolutions: make([]ReceiptResolution, 0, len(snap.resolutions)),
+	}
+	for _, vote := range snap.observed {
+		receipt.Votes = append(receipt.Votes, ReceiptVote{
+			Signer:   vote.Signer,
+			Kind:     vote.Kind,
+			Address:  vote.Address,
+			Limit:    vote.Limit,
+			Period:   vote.Period,
+			Replaced: vote.Replaced,
+			Tally:    uint64(vote.Votes),
+			Passed:   vote.Passed,
+		})
+	}
+	for _, res := range snap.resolutions {
+		receipt.Resolutions = append(receipt.Resolutions, ReceiptResolution{
+			Kind:      res.Kind,
+			Address:   res.Address,
+			Limit:     res.Limit,
+			PrevLimit: res.PrevLimit,
+			Period:    res.Period,
+			Replaced:  res.Replaced,
+			Signers:   res.Signers,
+			Votes:     res.Votes,
+		})
+	}
+	return receipt
+}
+
+// receipt derives the governance receipt of a sealed header by applying it onto
+// the snapshot of its parent.
+func (c *Clique) receipt(chain consensus.ChainHeaderReader, header *types.Header) (*GovernanceReceipt, error) {
+	parent, err := c.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := parent.apply([]*types.Header{header})
+	if err != nil {
+		return nil, err
+	}
+	return governanceReceipt(snap), nil
+}
+
+// withReceipt embeds the governance receipt of a sealed block into its consensus
+// data past the governance receipt fork. Being optional, the block is left as is
+// if the receipt can't be derived.
+func (c *Clique) withReceipt(chain consensus.ChainHeaderReader, block *types.Block) *types.Block {
+	if !c.config.IsReceipt(block.Number()) {
+		return block
+	}
+	receipt, err := c.receipt(chain, block.Header())
+	if err != nil {
+		log.Warn("Failed to derive governance receipt", "number", block.Number(), "err", err)
+		return block
+	}
+	data, err := rlp.EncodeToBytes(receipt)
+	if err != nil {
+		log.Warn("Failed to encode governance receipt", "number", block.Number(), "err", err)
+		return block
+	}
+	return block.WithConsensusData(data)
+}
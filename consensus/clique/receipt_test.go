@@ -0,0 +1,90 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// decodeReceipt extracts the governance receipt embedded into a block, or returns
+// nil if it doesn't carry one.
+func decodeReceipt(block *types.Block) (*GovernanceReceipt, error) {
+	data := block.ConsensusData()
+	if len(data) == 0 {
+		return nil, nil
+	}
+	receipt := new(GovernanceReceipt)
+	if err := rlp.DecodeBytes(data, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// Tests that sealed blocks past the receipt fork carry a receipt of the votes
+// tallied up in them, and that receipts being advisory, blocks are never rejected
+// over them.
+func TestGovernanceReceipt(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50, ReceiptBlock: common.Big1}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 1)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	// Reseal the first block with an authorization vote
+	header := chain.headers[1]
+	header.Coinbase = accounts.address("D")
+	header.Nonce = engine.voteNonces().auth
+	accounts.sign(header, "B")
+
+	block := engine.withReceipt(chain, types.NewBlockWithHeader(header))
+	if len(block.ConsensusData()) == 0 {
+		t.Fatalf("sealed block carries no receipt")
+	}
+	receipt, err := decodeReceipt(block)
+	if err != nil {
+		t.Fatalf("failed to decode receipt: %v", err)
+	}
+	if len(receipt.Votes) != 1 || receipt.Votes[0].Signer != accounts.address("B") || receipt.Votes[0].Address != accounts.address("D") || receipt.Votes[0].Tally != 1 {
+		t.Errorf("receipt votes mismatch: have %+v", receipt.Votes)
+	}
+	if len(receipt.Resolutions) != 0 {
+		t.Errorf("receipt resolutions mismatch: have %+v, want none", receipt.Resolutions)
+	}
+	// Stripped or tampered receipts don't invalidate the block, nor are they served
+	api := &API{chain: chain, clique: engine}
+	tampered := append(common.CopyBytes(block.ConsensusData()[:len(block.ConsensusData())-1]), 0x02)
+	for _, relayed := range []*types.Block{types.NewBlockWithHeader(header), block.WithConsensusData(tampered)} {
+		if err := engine.VerifyUncles(chain, relayed); err != nil {
+			t.Errorf("block rejected over its receipt: %v", err)
+		}
+	}
+	if have, err := api.GetGovernanceReceipt(header.Hash()); err != nil || !reflect.DeepEqual(have, receipt) {
+		t.Errorf("served receipt mismatch: have %+v, want %+v (err %v)", have, receipt, err)
+	}
+	// Receipts aren't embedded before the fork
+	legacy := New(&params.CliqueConfig{Epoch: 30000, SignerLimit: 50, ReceiptBlock: common.Big2}, rawdb.NewMemoryDatabase())
+	if block := legacy.withReceipt(chain, types.NewBlockWithHeader(header)); len(block.ConsensusData()) != 0 {
+		t.Errorf("pre-fork block carries a receipt")
+	}
+}
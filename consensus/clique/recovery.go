@@ -0,0 +1,129 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// recoveryRounds is the number of rounds of blocks a signer needs to skip sealing
+// entirely, while being authorized all along, to be considered offline.
+const recoveryRounds = 2
+
+// recoveryPriority is the priority of the drop proposals queued by the stall
+// recovery, voted on before any operator proposal by the priority strategy.
+const recoveryPriority = math.MaxInt32
+
+// SetStallRecovery enables or disables the stall recovery of the local signer. If
+// enabled, the first block sealed after a prolonged chain halt makes the signer
+// queue proposals dropping every signer provably offline, speeding up the
+// recovery from a mass outage.
+//
+// As the offline signers are derived from the chain alone, every online signer
+// running the recovery queues the same proposals, without any coordination.
+func (c *Clique) SetStallRecovery(enabled bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.recovery = enabled
+}
+
+// OfflineSigners retrieves the signers that didn't seal any of the last rounds of
+// blocks up to the given header, despite being authorized throughout.
+func (c *Clique) OfflineSigners(chain consensus.ChainHeaderReader, head *types.Header) ([]common.Address, error) {
+	number := head.Number.Uint64()
+	snap, err := c.snapshot(chain, number, head.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	window := uint64(recoveryRounds * len(snap.Signers))
+	if number < window {
+		return nil, nil // Not enough history to prove anything
+	}
+	// Collect the signers of the window, reaching back to its first block
+	var (
+		sealers = make(map[common.Address]struct{})
+		header  = head
+	)
+	for n := number; n > number-window; n-- {
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return nil, err
+		}
+		sealers[signer] = struct{}{}
+		header = chain.GetHeader(header.ParentHash, n-1)
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	start, err := c.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var offline []common.Address
+	for _, signer := range snap.signers() {
+		if _, ok := sealers[signer]; ok {
+			continue
+		}
+		if _, ok := start.Signers[signer]; ok {
+			offline = append(offline, signer)
+		}
+	}
+	return offline, nil
+}
+
+// recoverStall queues drop proposals for the offline signers if the new head
+// ended a prolonged halt of the chain and the stall recovery is enabled.
+func (c *Clique) recoverStall(chain consensus.ChainHeaderReader, parent, head *types.Header) {
+	c.lock.RLock()
+	enabled, local := c.recovery, c.signer
+	c.lock.RUnlock()
+
+	period := c.config.Period
+	if !enabled || period == 0 || head.Time-parent.Time <= stallPeriods*period {
+		return
+	}
+	offline, err := c.OfflineSigners(chain, head)
+	if err != nil {
+		log.Warn("Failed to detect offline clique signers", "number", head.Number, "err", err)
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, signer := range offline {
+		if signer == local {
+			continue
+		}
+		if auth, ok := c.proposals[signer]; ok && !auth {
+			continue
+		}
+		c.queueProposal(signer, false, recoveryPriority)
+		c.setMemo(signer, false, fmt.Sprintf("stall recovery: no seals within %d rounds before block %d", recoveryRounds, head.Number), head.Number.Uint64())
+
+		log.Warn("Proposing to drop offline clique signer", "signer", signer, "number", head.Number, "halt", head.Time-parent.Time)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the block ending a prolonged chain halt makes the local signer queue
+// drop proposals for the signers that didn't seal throughout the last rounds.
+func TestStallRecovery(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Period: 1, Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C", "D"}, 0)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	engine.Authorize(accounts.address("B"), nil)
+	engine.SetStallRecovery(true)
+	engine.NewChainHead(chain, chain.headers[0])
+
+	// Seal a few rounds without D, the last block after a lengthy halt
+	signers := []string{"A", "B", "C", "A", "B", "C", "A", "B", "C", "A"}
+	for i, signer := range signers {
+		parent := chain.headers[i]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Time:       parent.Time + 1,
+			Difficulty: diffNoTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i == len(signers)-1 {
+			header.Time += 60
+		}
+		accounts.sign(header, signer)
+		chain.headers = append(chain.headers, header)
+
+		if i < len(signers)-1 && len(engine.proposals) != 0 {
+			t.Fatalf("block %d: drop proposed without a halt: %v", i+1, engine.proposals)
+		}
+		engine.NewChainHead(chain, header)
+	}
+	offline, err := engine.OfflineSigners(chain, chain.CurrentHeader())
+	if err != nil {
+		t.Fatalf("failed to retrieve offline signers: %v", err)
+	}
+	if want := []common.Address{accounts.address("D")}; !reflect.DeepEqual(offline, want) {
+		t.Errorf("offline signers mismatch: have %v, want %v", offline, want)
+	}
+	if auth, ok := engine.proposals[accounts.address("D")]; !ok || auth || len(engine.proposals) != 1 {
+		t.Errorf("recovery proposals mismatch: have %v", engine.proposals)
+	}
+	if info := engine.proposalInfo[accounts.address("D")]; info.Priority != recoveryPriority || info.Memo == "" {
+		t.Errorf("recovery proposal info mismatch: have %+v", info)
+	}
+}
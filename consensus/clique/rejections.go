@@ -0,0 +1,135 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// rejectionAlertInterval is the minimum time between two warnings about the same
+// signer being rejected at the same stage.
+const rejectionAlertInterval = time.Minute
+
+// Consensus stages the rejections of signers are tracked at.
+const (
+	stageVerify = "verify" // Verifying the seal of a remote or local block
+	stageSeal   = "seal"   // Sealing a block with the local signer
+)
+
+var (
+	verifyUnauthorizedMeter   = metrics.NewRegisteredMeter("clique/reject/verify/unauthorized", nil)
+	verifyRecentlySignedMeter = metrics.NewRegisteredMeter("clique/reject/verify/recentlysigned", nil)
+	sealUnauthorizedMeter     = metrics.NewRegisteredMeter("clique/reject/seal/unauthorized", nil)
+	sealRecentlySignedMeter   = metrics.NewRegisteredMeter("clique/reject/seal/recentlysigned", nil)
+)
+
+// RejectionStats is the number of times the seal of a signer was rejected for
+// not being authorized or for having signed too recently.
+type RejectionStats struct {
+	Unauthorized   uint64 `json:"unauthorized"`   // Number of rejections for not being a signer
+	RecentlySigned uint64 `json:"recentlySigned"` // Number of rejections for signing too recently
+	LastNumber     uint64 `json:"lastNumber"`     // Number of the block last rejected
+}
+
+// rejectionKey identifies the rejections of a signer at a consensus stage.
+type rejectionKey struct {
+	stage  string
+	signer common.Address
+}
+
+// rejectionTracker counts the consensus rejections of the individual signers, so
+// a misconfigured peer or a deauthorized signer still trying to seal stands out.
+type rejectionTracker struct {
+	stats  map[rejectionKey]*RejectionStats // Rejections of the signers per stage
+	warned map[rejectionKey]time.Time       // Last time a rejection was warned about
+
+	lock sync.Mutex
+}
+
+// newRejectionTracker creates an empty consensus rejection tracker.
+func newRejectionTracker() *rejectionTracker {
+	return &rejectionTracker{
+		stats:  make(map[rejectionKey]*RejectionStats),
+		warned: make(map[rejectionKey]time.Time),
+	}
+}
+
+// mark accounts the rejection of a block of the signer at the given stage, if
+// the error is a signer rejection at all. Other errors are ignored.
+func (t *rejectionTracker) mark(stage string, signer common.Address, number uint64, err error) {
+	var name string
+	switch err {
+	case errUnauthorizedSigner:
+		name = "unauthorized"
+	case errRecentlySigned:
+		name = "recentlysigned"
+	default:
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	key := rejectionKey{stage: stage, signer: signer}
+	stats := t.stats[key]
+	if stats == nil {
+		stats = new(RejectionStats)
+		t.stats[key] = stats
+	}
+	switch err {
+	case errUnauthorizedSigner:
+		stats.Unauthorized++
+		if stage == stageSeal {
+			sealUnauthorizedMeter.Mark(1)
+		} else {
+			verifyUnauthorizedMeter.Mark(1)
+		}
+	case errRecentlySigned:
+		stats.RecentlySigned++
+		if stage == stageSeal {
+			sealRecentlySignedMeter.Mark(1)
+		} else {
+			verifyRecentlySignedMeter.Mark(1)
+		}
+	}
+	stats.LastNumber = number
+	metrics.GetOrRegisterCounter("clique/reject/"+stage+"/"+name+"/"+signer.Hex(), nil).Inc(1)
+
+	// Unauthorized seals hint at a misconfiguration, warn about them periodically
+	if err == errUnauthorizedSigner && time.Since(t.warned[key]) > rejectionAlertInterval {
+		log.Warn("Rejected seal of unauthorized signer", "stage", stage, "signer", signer, "number", number, "rejections", stats.Unauthorized)
+		t.warned[key] = time.Now()
+	}
+}
+
+// Stats returns a copy of the rejections of every signer at the given stage.
+func (t *rejectionTracker) Stats(stage string) map[common.Address]RejectionStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := make(map[common.Address]RejectionStats)
+	for key, s := range t.stats {
+		if key.stage == stage {
+			stats[key.signer] = *s
+		}
+	}
+	return stats
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that seals rejected for being unauthorized or signed too recently are
+// counted per signer, while other verification failures are ignored.
+func TestSealRejections(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 4)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	engine.fakeDiff = true
+
+	parent := chain.headers[4]
+	snap, err := engine.snapshot(chain, 4, parent.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	seal := func(signer string) *types.Header {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(5),
+			Time:       parent.Time + 1,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, signer)
+		return header
+	}
+	// Block 4 was sealed by B, which may not seal block 5 again
+	for _, tt := range []struct {
+		signer string
+		err    error
+	}{
+		{"D", errUnauthorizedSigner},
+		{"D", errUnauthorizedSigner},
+		{"B", errRecentlySigned},
+		{"C", nil},
+	} {
+		if err := engine.verifySeal(snap, seal(tt.signer), nil); err != tt.err {
+			t.Fatalf("signer %s: seal verification error mismatch: have %v, want %v", tt.signer, err, tt.err)
+		}
+	}
+	stats := engine.rejections.Stats(stageVerify)
+	if len(stats) != 2 {
+		t.Fatalf("rejected signer count mismatch: have %d, want %d", len(stats), 2)
+	}
+	if have := stats[accounts.address("D")]; have != (RejectionStats{Unauthorized: 2, LastNumber: 5}) {
+		t.Errorf("unauthorized signer rejections mismatch: have %+v", have)
+	}
+	if have := stats[accounts.address("B")]; have != (RejectionStats{RecentlySigned: 1, LastNumber: 5}) {
+		t.Errorf("recent signer rejections mismatch: have %+v", have)
+	}
+	if stats := engine.rejections.Stats(stageSeal); len(stats) != 0 {
+		t.Errorf("sealing rejections reported: %v", stats)
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// NewChainHead re-anchors the engine on a new canonical chain head. If the head
+// doesn't extend the previous one, the snapshots cached for the abandoned branch
+// are invalidated down to the common ancestor (retaining them aside if only a few
+// blocks were abandoned), along with any proposals recorded as resolved there.
+// The blocks adopted into the canonical chain are recorded in the audit store and
+// the participation of the signers, which only ever track the canonical chain.
+// The consensus state of the head is published afterwards, outside the head lock
+// so slow subscribers can't stall head processing.
+func (c *Clique) NewChainHead(chain consensus.ChainHeaderReader, head *types.Header) {
+	c.setChainHead(chain, head)
+	c.publishState(chain, head)
+}
+
+// setChainHead re-anchors the engine on a new canonical chain head, holding the
+// head lock throughout.
+func (c *Clique) setChainHead(chain consensus.ChainHeaderReader, head *types.Header) {
+	c.headLock.Lock()
+	defer c.headLock.Unlock()
+
+	prev := c.head
+	c.head = head
+	if prev == nil || prev.Hash() == head.Hash() {
+		return
+	}
+	if prev.Hash() == head.ParentHash {
+		c.adoptHeaders(chain, head, prev, []*types.Header{head})
+		c.checkAlerts(chain, head)
+		c.recoverStall(chain, prev, head)
+		return
+	}
+	// If a batch of blocks was imported on top of the previous head, adopt all of
+	// them, however many, so no resolved proposal is missed
+	if number := prev.Number.Uint64(); number < head.Number.Uint64() {
+		if canon := chain.GetHeaderByNumber(number); canon != nil && canon.Hash() == prev.Hash() {
+			var adopted []*types.Header
+			for header := head; header != nil && header.Number.Uint64() > number; header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+				adopted = append(adopted, header)
+			}
+			if last := adopted[len(adopted)-1]; last.ParentHash == prev.Hash() {
+				c.adoptHeaders(chain, head, prev, adopted)
+				return
+			}
+		}
+	}
+	// The head was reorged (or rewound), gather the two branches down to the
+	// common ancestor, but only as deep as the tracked seal window
+	var (
+		abandoned []*types.Header
+		adopted   []*types.Header
+		oldHeader = prev
+		newHeader = head
+	)
+	for oldHeader != nil && newHeader != nil && oldHeader.Hash() != newHeader.Hash() {
+		if len(abandoned) >= sealWindow || len(adopted) >= sealWindow {
+			c.resetChainHead(chain, head, adopted, newHeader)
+			return
+		}
+		if oldNum, newNum := oldHeader.Number.Uint64(), newHeader.Number.Uint64(); oldNum >= newNum {
+			abandoned = append(abandoned, oldHeader)
+			oldHeader = chain.GetHeader(oldHeader.ParentHash, oldNum-1)
+		} else {
+			adopted = append(adopted, newHeader)
+			newHeader = chain.GetHeader(newHeader.ParentHash, newNum-1)
+		}
+	}
+	if oldHeader == nil || newHeader == nil {
+		log.Warn("Failed to find clique reorg ancestor", "old", prev.Number, "new", head.Number)
+		c.recents.Purge()
+		c.forks.purge()
+		c.seals.truncate(head.Number.Uint64())
+		if err := pruneResolutions(c.db, chain, 0); err != nil {
+			log.Warn("Failed to prune reorged clique resolutions", "err", err)
+		}
+		return
+	}
+	// Drop any snapshots cached for the abandoned branch, any resolutions recorded
+	// on it and any seals tracked above the new head. The snapshots of micro reorgs
+	// are retained aside, as the chain may well flip back to the abandoned blocks.
+	for _, header := range abandoned {
+		if len(abandoned) <= forkRetainDepth {
+			if snap, ok := c.recents.Peek(header.Hash()); ok {
+				c.forks.add(header.ParentHash, snap, head.Number.Uint64())
+			}
+		}
+		c.recents.Remove(header.Hash())
+	}
+	if err := deleteResolutions(c.db, abandoned); err != nil {
+		log.Warn("Failed to drop reorged clique resolutions", "err", err)
+	}
+	c.seals.truncate(head.Number.Uint64())
+
+	c.adoptHeaders(chain, head, oldHeader, adopted)
+	log.Debug("Invalidated reorged clique snapshots", "ancestor", oldHeader.Number, "dropped", len(abandoned), "added", len(adopted))
+}
+
+// resetChainHead drops all the cached snapshots and tracked seals if the common
+// ancestor of a reorg lies deeper than the tracked seal window, along with all
+// the resolutions recorded off the new canonical chain. Only the most recent
+// window of the new chain is adopted. The headers already gathered along the
+// new branch are reused, the rest up to the window loaded.
+func (c *Clique) resetChainHead(chain consensus.ChainHeaderReader, head *types.Header, adopted []*types.Header, next *types.Header) {
+	log.Warn("Resetting clique state on deep reorg", "new", head.Number, "window", sealWindow)
+	c.recents.Purge()
+	c.forks.purge()
+	c.seals.truncate(0)
+	if err := pruneResolutions(c.db, chain, 0); err != nil {
+		log.Warn("Failed to prune reorged clique resolutions", "err", err)
+	}
+	for next != nil && next.Number.Uint64() > 0 && len(adopted) < sealWindow {
+		adopted = append(adopted, next)
+		next = chain.GetHeader(next.ParentHash, next.Number.Uint64()-1)
+	}
+	if next == nil {
+		log.Warn("Failed to find clique reorg window", "new", head.Number)
+		return
+	}
+	c.adoptHeaders(chain, head, next, adopted)
+}
+
+// adoptHeaders replays the headers adopted into the canonical chain (ordered
+// newest first) one by one, recording the proposals they resolved in the audit
+// store and the blocks they sealed in the participation tracker, and publishing
+// their events. Each header is applied on top of the cached snapshot of its
+// parent if available (reusing any retained side fork), or the one replayed
+// before it. The snapshot cache is re-anchored on the new head afterwards.
+func (c *Clique) adoptHeaders(chain consensus.ChainHeaderReader, head *types.Header, parent *types.Header, adopted []*types.Header) {
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		log.Warn("Failed to retrieve adopted clique ancestor", "number", parent.Number, "hash", parent.Hash(), "err", err)
+		return
+	}
+	var (
+		records     = make([]sealRecord, 0, len(adopted))
+		resolutions []*Resolution
+	)
+	for i := len(adopted) - 1; i >= 0; i-- {
+		header := adopted[i]
+		number := header.Number.Uint64()
+
+		if cached := c.cachedSnapshot(chain, number-1, header.ParentHash); cached != nil {
+			snap = cached
+		}
+		if snap, err = snap.apply([]*types.Header{header}); err != nil {
+			log.Warn("Failed to replay adopted clique header", "number", number, "hash", header.Hash(), "err", err)
+			break
+		}
+		records = append(records, snap.seals...)
+		resolutions = append(resolutions, snap.resolutions...)
+		c.publishEvents(snap)
+	}
+	c.seals.add(records)
+	if err := storeResolutions(c.db, resolutions); err != nil {
+		log.Warn("Failed to record adopted clique resolutions", "number", head.Number, "hash", head.Hash(), "err", err)
+	}
+	if _, err := c.snapshot(chain, head.Number.Uint64(), head.Hash(), nil); err != nil {
+		log.Warn("Failed to re-anchor clique snapshot", "number", head.Number, "hash", head.Hash(), "err", err)
+	}
+}
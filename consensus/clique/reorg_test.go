@@ -0,0 +1,273 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a chain reorg evicts the snapshots cached for the abandoned branch
+// and recounts the seals along the adopted one.
+func TestReorgInvalidation(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		engine = New(params.AllCliqueProtocolChanges.Clique, db)
+	)
+	genspec := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	copy(genspec.ExtraData[extraVanity:], addr[:])
+	genesis := genspec.MustCommit(db)
+
+	// Generate two competing branches, differing in their vanity
+	makeBranch := func(n int, vanity byte) []*types.Block {
+		blocks, _ := core.GenerateChain(params.AllCliqueProtocolChanges, genesis, engine, db, n, func(i int, block *core.BlockGen) {
+			block.SetDifficulty(diffInTurn)
+		})
+		for i, block := range blocks {
+			header := block.Header()
+			if i > 0 {
+				header.ParentHash = blocks[i-1].Hash()
+			}
+			header.Extra = make([]byte, extraVanity+extraSeal)
+			header.Extra[0] = vanity
+			header.Difficulty = diffInTurn
+
+			sig, _ := crypto.Sign(SealHash(header).Bytes(), key)
+			copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+			blocks[i] = block.WithSeal(header)
+		}
+		return blocks
+	}
+	abandoned := makeBranch(3, 0x01)
+	adopted := makeBranch(4, 0x02)
+
+	chain, _ := core.NewBlockChain(db, nil, params.AllCliqueProtocolChanges, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	engine.NewChainHead(chain, chain.CurrentHeader())
+	if _, err := chain.InsertChain(abandoned); err != nil {
+		t.Fatalf("failed to insert initial branch: %v", err)
+	}
+	engine.NewChainHead(chain, chain.CurrentHeader())
+	for _, block := range abandoned {
+		if !engine.recents.Contains(block.Hash()) {
+			t.Fatalf("snapshot %d missing before reorg", block.NumberU64())
+		}
+	}
+	if _, err := chain.InsertChain(adopted); err != nil {
+		t.Fatalf("failed to insert competing branch: %v", err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != adopted[len(adopted)-1].Hash() {
+		t.Fatalf("chain head mismatch: have %x, want %x", head, adopted[len(adopted)-1].Hash())
+	}
+	// Track an abandoned seal to simulate a late side chain verification, then
+	// reorg and ensure everything is anchored on the new branch
+	engine.seals.add([]sealRecord{{Number: 2, Hash: abandoned[1].Hash(), Signer: addr, InTurn: true, Turn: addr}})
+
+	engine.NewChainHead(chain, chain.CurrentHeader())
+	for _, block := range abandoned {
+		if engine.recents.Contains(block.Hash()) {
+			t.Errorf("snapshot %d of abandoned branch still cached", block.NumberU64())
+		}
+	}
+	if !engine.recents.Contains(adopted[len(adopted)-1].Hash()) {
+		t.Errorf("snapshot of new head not cached")
+	}
+	for _, block := range adopted {
+		if have := engine.seals.records[block.NumberU64()].Hash; have != block.Hash() {
+			t.Errorf("seal %d mismatch: have %x, want %x", block.NumberU64(), have, block.Hash())
+		}
+	}
+	if have := engine.seals.Stats()[addr]; have.InTurn != uint64(len(adopted)) {
+		t.Errorf("seal count mismatch: have %d, want %d", have.InTurn, len(adopted))
+	}
+}
+
+// countingHeaderChain is a header chain counting the headers loaded by number.
+type countingHeaderChain struct {
+	*testerHeaderChain
+	loaded int
+}
+
+func (c *countingHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	c.loaded++
+	return c.testerHeaderChain.GetHeader(hash, number)
+}
+
+// Tests that a reorg whose common ancestor lies deeper than the tracked seal
+// window doesn't load the entire abandoned range, but resets the engine state.
+func TestDeepReorgReset(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = &countingHeaderChain{testerHeaderChain: newTesterSignedChain(accounts, config, []string{"A", "B"}, 3*sealWindow)}
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	prev := chain.CurrentHeader()
+	if _, err := engine.snapshot(chain, prev.Number.Uint64(), prev.Hash(), nil); err != nil {
+		t.Fatalf("failed to create head snapshot: %v", err)
+	}
+	engine.NewChainHead(chain, prev)
+	engine.seals.add([]sealRecord{{Number: prev.Number.Uint64(), Hash: prev.Hash()}})
+
+	// Rewind the chain far below the tracked window and check the walk is bounded
+	head := chain.headers[10]
+	chain.loaded = 0
+	engine.NewChainHead(chain, head)
+
+	if chain.loaded > 2*sealWindow {
+		t.Errorf("too many headers loaded: have %d, want at most %d", chain.loaded, 2*sealWindow)
+	}
+	if engine.recents.Contains(prev.Hash()) {
+		t.Errorf("snapshot of abandoned head still cached")
+	}
+	if !engine.recents.Contains(head.Hash()) {
+		t.Errorf("snapshot of new head not cached")
+	}
+	for number := range engine.seals.records {
+		if number > head.Number.Uint64() {
+			t.Errorf("seal %d above the new head still tracked", number)
+		}
+	}
+	for number := uint64(1); number <= head.Number.Uint64(); number++ {
+		if have := engine.seals.records[number].Hash; have != chain.headers[number].Hash() {
+			t.Errorf("seal %d mismatch: have %x, want %x", number, have, chain.headers[number].Hash())
+		}
+	}
+}
+
+// Tests that the seals and resolved proposals are only recorded for the blocks of
+// the canonical chain, not for side chains or historical replays, and that the
+// resolutions of reorged out blocks are dropped.
+func TestCanonicalRecording(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		engine = New(params.AllCliqueProtocolChanges.Clique, db)
+	)
+	genspec := &core.Genesis{
+		ExtraData: make([]byte, extraVanity+common.AddressLength+extraSeal),
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	copy(genspec.ExtraData[extraVanity:], addr[:])
+	genesis := genspec.MustCommit(db)
+
+	// Generate competing branches, differing in their vanity and voting on an
+	// account in their last block
+	makeBranch := func(n int, vanity byte, vote common.Address) []*types.Block {
+		blocks, _ := core.GenerateChain(params.AllCliqueProtocolChanges, genesis, engine, db, n, func(i int, block *core.BlockGen) {
+			block.SetDifficulty(diffInTurn)
+			if i == n-1 {
+				block.SetCoinbase(vote)
+			}
+		})
+		for i, block := range blocks {
+			header := block.Header()
+			if i > 0 {
+				header.ParentHash = blocks[i-1].Hash()
+			}
+			if i == n-1 {
+				copy(header.Nonce[:], nonceAuthVote)
+			}
+			header.Extra = make([]byte, extraVanity+extraSeal)
+			header.Extra[0] = vanity
+			header.Difficulty = diffInTurn
+
+			sig, _ := crypto.Sign(SealHash(header).Bytes(), key)
+			copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+			blocks[i] = block.WithSeal(header)
+		}
+		return blocks
+	}
+	canonical := makeBranch(3, 0x01, common.Address{0x01})
+	side := makeBranch(2, 0x02, common.Address{0x02})
+	adopted := makeBranch(4, 0x03, common.Address{0x03})
+
+	chain, _ := core.NewBlockChain(db, nil, params.AllCliqueProtocolChanges, engine, vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	engine.NewChainHead(chain, chain.CurrentHeader())
+	if _, err := chain.InsertChain(canonical); err != nil {
+		t.Fatalf("failed to insert canonical branch: %v", err)
+	}
+	engine.NewChainHead(chain, chain.CurrentHeader())
+
+	// Import a side chain and replay the history, neither should be recorded
+	if _, err := chain.InsertChain(side); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	engine.recents.Purge()
+	if _, err := engine.snapshot(chain, 2, side[1].Hash(), nil); err != nil {
+		t.Fatalf("failed to replay side chain snapshot: %v", err)
+	}
+	if _, err := engine.snapshot(chain, 1, canonical[0].Hash(), nil); err != nil {
+		t.Fatalf("failed to replay historical snapshot: %v", err)
+	}
+	check := func(blocks []*types.Block, dropped ...*types.Block) {
+		t.Helper()
+		for number := range engine.seals.records {
+			if number == 0 || number > uint64(len(blocks)) {
+				t.Errorf("seal %d beyond the canonical chain tracked", number)
+			}
+		}
+		for _, block := range blocks {
+			if have := engine.seals.records[block.NumberU64()].Hash; have != block.Hash() {
+				t.Errorf("seal %d mismatch: have %x, want %x", block.NumberU64(), have, block.Hash())
+			}
+		}
+		last := blocks[len(blocks)-1]
+		if res, err := loadResolution(db, last.NumberU64(), last.Hash()); err != nil || res.Address != last.Coinbase() {
+			t.Errorf("canonical resolution missing: %v", err)
+		}
+		for _, block := range dropped {
+			if _, err := loadResolution(db, block.NumberU64(), block.Hash()); err == nil {
+				t.Errorf("resolution of non-canonical block %d recorded", block.NumberU64())
+			}
+		}
+	}
+	check(canonical, side[1])
+
+	// Reorg onto a longer branch, dropping the resolutions of the abandoned one
+	if _, err := chain.InsertChain(adopted); err != nil {
+		t.Fatalf("failed to insert competing branch: %v", err)
+	}
+	engine.NewChainHead(chain, chain.CurrentHeader())
+	check(adopted, side[1], canonical[2])
+
+	api := &API{chain: chain, clique: engine}
+	resolutions, err := api.GetResolutionsBySigner(addr, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to query resolutions: %v", err)
+	}
+	if len(resolutions) != 1 || resolutions[0].Hash != adopted[3].Hash() {
+		t.Errorf("resolutions mismatch: have %v", resolutions)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errCorruptSnapshot is returned if a persisted snapshot or snapshot delta, or
+// any snapshot it descends from, can't be decoded.
+var errCorruptSnapshot = errors.New("corrupt voting snapshot")
+
+// deleteSnapshot drops the snapshot of the given block from the database, both
+// in full and as a delta.
+func deleteSnapshot(db ethdb.KeyValueWriter, hash common.Hash) error {
+	if err := db.Delete(append(append([]byte{}, snapshotPrefix...), hash[:]...)); err != nil {
+		return err
+	}
+	return db.Delete(append(append([]byte{}, deltaPrefix...), hash[:]...))
+}
+
+// RepairedSnapshot is a corrupted snapshot dropped from the database, along with
+// whether it could be rebuilt from the chain.
+type RepairedSnapshot struct {
+	Hash    common.Hash `json:"hash"`    // Block hash of the corrupted snapshot
+	Number  uint64      `json:"number"`  // Block number of the corrupted snapshot (if rebuilt)
+	Rebuilt bool        `json:"rebuilt"` // Whether the block is still known and the snapshot was rebuilt
+}
+
+// RepairSnapshots checks that every persisted snapshot of the chain decodes,
+// dropping the corrupted ones and rebuilding those of blocks still known to the
+// chain from the headers. Snapshots of blocks no longer known are only dropped.
+func (c *Clique) RepairSnapshots(chain consensus.ChainHeaderReader) ([]*RepairedSnapshot, error) {
+	db, err := c.snapshotDB(chain)
+	if err != nil {
+		return nil, err
+	}
+	// Collect the corrupted snapshots first, the database may not be modified
+	// while iterating it
+	var corrupted []common.Hash
+	for _, prefix := range [][]byte{snapshotPrefix, deltaPrefix} {
+		it := db.NewIterator(prefix, nil)
+		for it.Next() {
+			if len(it.Key()) != len(prefix)+common.HashLength {
+				continue // Other engine data sharing the prefix
+			}
+			hash := common.BytesToHash(it.Key()[len(prefix):])
+			if _, err := loadSnapshot(c.config, c.signatures, db, hash); err == errCorruptSnapshot {
+				corrupted = append(corrupted, hash)
+			}
+		}
+		it.Release()
+	}
+	// Drop all of them before rebuilding, as the rebuilt snapshots may descend
+	// from one another
+	for _, hash := range corrupted {
+		if err := deleteSnapshot(db, hash); err != nil {
+			return nil, err
+		}
+		c.recents.Remove(hash)
+	}
+	repaired := make([]*RepairedSnapshot, 0, len(corrupted))
+	for _, hash := range corrupted {
+		report := &RepairedSnapshot{Hash: hash}
+		if header := chain.GetHeaderByHash(hash); header != nil {
+			snap, err := c.snapshot(chain, header.Number.Uint64(), hash, nil)
+			if err != nil {
+				return nil, err
+			}
+			if err := snap.store(db); err != nil {
+				return nil, err
+			}
+			report.Number, report.Rebuilt = snap.Number, true
+		}
+		log.Info("Repaired corrupt voting snapshot", "number", report.Number, "hash", hash, "rebuilt", report.Rebuilt)
+		repaired = append(repaired, report)
+	}
+	return repaired, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that corrupted snapshots are dropped and rebuilt from the chain, both
+// transparently when loaded and explicitly when repairing the database.
+func TestSnapshotRepair(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B"}, 12)
+		database = rawdb.NewMemoryDatabase()
+	)
+	key := func(hash common.Hash) []byte {
+		return append(append([]byte{}, snapshotPrefix...), hash[:]...)
+	}
+	// Persist a snapshot and create the expected state from a separate engine
+	engine := New(config, database)
+	db, err := engine.snapshotDB(chain)
+	if err != nil {
+		t.Fatalf("failed to open snapshot database: %v", err)
+	}
+	want, err := New(config, rawdb.NewMemoryDatabase()).snapshot(chain, 8, chain.headers[8].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	// A corrupted snapshot must be rebuilt transparently when loaded
+	hash := chain.headers[8].Hash()
+	if err := db.Put(key(hash), []byte("corrupt")); err != nil {
+		t.Fatalf("failed to corrupt snapshot: %v", err)
+	}
+	if _, err := loadSnapshot(config, nil, db, hash); err != errCorruptSnapshot {
+		t.Fatalf("corrupt snapshot error mismatch: have %v, want %v", err, errCorruptSnapshot)
+	}
+	engine.flushed = hash
+	snap, err := engine.snapshot(chain, 8, hash, nil)
+	if err != nil {
+		t.Fatalf("failed to rebuild corrupt snapshot: %v", err)
+	}
+	if fields, _ := snapshotDiff(snap, want); len(fields) != 0 {
+		t.Errorf("rebuilt snapshot differs in %v", fields)
+	}
+	if ok, _ := db.Has(key(hash)); ok {
+		t.Errorf("corrupt snapshot not dropped")
+	}
+	// Corrupted snapshots must be rebuilt when repairing, unknown ones dropped
+	stale := common.Hash{0xff}
+	for _, hash := range []common.Hash{hash, stale} {
+		if err := db.Put(key(hash), []byte("corrupt")); err != nil {
+			t.Fatalf("failed to corrupt snapshot: %v", err)
+		}
+	}
+	engine.Close()
+
+	engine = New(config, database)
+	if db, err = engine.snapshotDB(chain); err != nil {
+		t.Fatalf("failed to open snapshot database: %v", err)
+	}
+	repaired, err := engine.RepairSnapshots(chain)
+	if err != nil {
+		t.Fatalf("failed to repair snapshots: %v", err)
+	}
+	if len(repaired) != 2 {
+		t.Fatalf("repaired snapshot count mismatch: have %d, want %d", len(repaired), 2)
+	}
+	for _, report := range repaired {
+		if report.Rebuilt != (report.Hash == hash) {
+			t.Errorf("snapshot %x rebuilt mismatch: have %v, want %v", report.Hash, report.Rebuilt, report.Hash == hash)
+		}
+	}
+	if ok, _ := db.Has(key(stale)); ok {
+		t.Errorf("unknown corrupt snapshot not dropped")
+	}
+	stored, err := loadSnapshot(config, nil, db, hash)
+	if err != nil {
+		t.Fatalf("failed to load repaired snapshot: %v", err)
+	}
+	if fields, _ := snapshotDiff(stored, want); len(fields) != 0 {
+		t.Errorf("repaired snapshot differs in %v", fields)
+	}
+}
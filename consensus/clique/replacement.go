@@ -0,0 +1,177 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// nonceReplaceVote is the magic nonce number to vote on replacing the signer in
+// the mix digest (left padded) with the account in the coinbase, e.g. to rotate
+// the key of a signer without going through separate add and remove proposals.
+var nonceReplaceVote = hexutil.MustDecode("0xfffffff800000000")
+
+var (
+	// errReplacementDisabled is returned if a block votes on a signer replacement
+	// before the replacement fork.
+	errReplacementDisabled = errors.New("signer replacements not enabled")
+
+	// errInvalidReplacement is returned if a block votes on replacing anything but
+	// an authorized signer, or on replacing it with an authorized one.
+	errInvalidReplacement = errors.New("invalid signer replacement")
+)
+
+// ReplaceVote is a vote of an authorized signer to swap a signer for another
+// account in a single step.
+type ReplaceVote struct {
+	Signer common.Address `json:"signer"` // Authorized signer that cast this vote
+	Block  uint64         `json:"block"`  // Block number the vote was cast in
+	Old    common.Address `json:"old"`    // Signer to remove from the signer set
+	New    common.Address `json:"new"`    // Account to authorize in its place
+}
+
+// isReplaceVote returns whether the header votes on a signer replacement.
+func isReplaceVote(header *types.Header) bool {
+	return bytes.Equal(header.Nonce[:], nonceReplaceVote)
+}
+
+// encodeReplaceVote sets the fields of a header to vote on replacing a signer.
+func encodeReplaceVote(header *types.Header, old, new common.Address) {
+	header.Coinbase = new
+	header.MixDigest = common.BytesToHash(old[:])
+	copy(header.Nonce[:], nonceReplaceVote)
+}
+
+// decodeReplaceVote extracts the signer a header votes on replacing, returning
+// false if the mix digest doesn't hold a left padded address.
+func decodeReplaceVote(header *types.Header) (common.Address, bool) {
+	if !bytes.Equal(header.MixDigest[:common.HashLength-common.AddressLength], make([]byte, common.HashLength-common.AddressLength)) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(header.MixDigest[common.HashLength-common.AddressLength:]), true
+}
+
+// validReplaceVote returns whether it makes sense to cast the specified signer
+// replacement vote in the given snapshot context.
+func (s *Snapshot) validReplaceVote(old, new common.Address) bool {
+	if _, ok := s.Signers[old]; !ok {
+		return false
+	}
+	if _, ok := s.Signers[new]; ok {
+		return false
+	}
+	return !s.changesCapped()
+}
+
+// verifyReplaceVote checks that a header voting on a signer replacement swaps an
+// authorized signer for an account that isn't one.
+func (s *Snapshot) verifyReplaceVote(header *types.Header) error {
+	old, ok := decodeReplaceVote(header)
+	if !ok {
+		return errInvalidReplacement
+	}
+	if _, ok := s.Signers[old]; !ok {
+		return errInvalidReplacement
+	}
+	if _, ok := s.Signers[header.Coinbase]; ok {
+		return errInvalidReplacement
+	}
+	return nil
+}
+
+// applyReplaceVote tallies a signer replacement vote of an authorized signer,
+// swapping the signers in a single step if the vote made the proposal pass. As
+// the signer set never changes size, the quorum stays the same throughout.
+func (s *Snapshot) applyReplaceVote(number uint64, hash common.Hash, signer, old, new common.Address) {
+	// Discard any previous replacement vote from the signer on the same signer.
+	// The votes are shared between snapshot copies, never modify in place
+	votes := make([]*ReplaceVote, 0, len(s.Replacements)+1)
+	for _, vote := range s.Replacements {
+		if vote.Signer != signer || vote.Old != old {
+			votes = append(votes, vote)
+		}
+	}
+	s.Replacements = votes
+
+	if !s.validReplaceVote(old, new) {
+		return
+	}
+	s.Replacements = append(s.Replacements, &ReplaceVote{
+		Signer: signer,
+		Block:  number,
+		Old:    old,
+		New:    new,
+	})
+	var trail []AuditVote
+	for _, vote := range s.Replacements {
+		if vote.Old == old && vote.New == new {
+			trail = append(trail, AuditVote{Signer: vote.Signer, Block: vote.Block})
+		}
+	}
+	passed := len(trail) >= int(s.signerLimit())
+
+	s.observed = append(s.observed, &VoteEvent{
+		Block:    number,
+		Hash:     hash,
+		Signer:   signer,
+		Kind:     ProposalReplacement,
+		Address:  new,
+		Replaced: old,
+		Votes:    len(trail),
+		Passed:   passed,
+	})
+	if !passed {
+		return
+	}
+	s.resolutions = append(s.resolutions, &Resolution{
+		Kind:     ProposalReplacement,
+		Block:    number,
+		Hash:     hash,
+		Address:  new,
+		Replaced: old,
+		Votes:    trail,
+	})
+	s.EpochChanges++
+
+	s.addSigner(new)
+	s.startProbation(new, number)
+	s.deauthorize(number, old)
+
+	s.discardProposal(old)
+	s.discardProposal(new)
+	s.dropReplacements(new)
+}
+
+// dropReplacements discards every replacement vote cast by or involving an
+// account whose authorization changed.
+func (s *Snapshot) dropReplacements(address common.Address) {
+	votes := make([]*ReplaceVote, 0, len(s.Replacements))
+	for _, vote := range s.Replacements {
+		if vote.Signer != address && vote.Old != address && vote.New != address {
+			votes = append(votes, vote)
+		}
+	}
+	s.Replacements = votes
+	if len(s.Replacements) == 0 {
+		s.Replacements = nil
+	}
+}
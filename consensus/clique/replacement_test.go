@@ -0,0 +1,74 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a signer replacement swaps both accounts in the block the vote passes
+// in, keeping the signer count unchanged, and that only authorized signers may be
+// replaced by accounts not signing yet.
+func TestSignerReplacement(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.CliqueConfig{Epoch: 30000, ReplacementBlock: big.NewInt(0)}
+	snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, []common.Address{
+		accounts.address("A"), accounts.address("B"), accounts.address("C"),
+	})
+	vote := func(number int64, signer, old, new string) *types.Header {
+		header := &types.Header{
+			Number: big.NewInt(number),
+			Extra:  make([]byte, extraVanity+extraSeal),
+		}
+		encodeReplaceVote(header, accounts.address(old), accounts.address(new))
+		accounts.sign(header, signer)
+		return header
+	}
+	// Replacing a non-signer or with a signer must be rejected
+	if _, err := snap.apply([]*types.Header{vote(1, "A", "D", "E")}); err != errInvalidReplacement {
+		t.Errorf("non-signer replacement error mismatch: have %v, want %v", err, errInvalidReplacement)
+	}
+	if _, err := snap.apply([]*types.Header{vote(1, "A", "C", "B")}); err != errInvalidReplacement {
+		t.Errorf("replacement with signer error mismatch: have %v, want %v", err, errInvalidReplacement)
+	}
+	// A single vote shouldn't change anything yet
+	snap, err := snap.apply([]*types.Header{vote(1, "A", "C", "D")})
+	if err != nil {
+		t.Fatalf("failed to apply first replacement vote: %v", err)
+	}
+	if !snap.IsSigner(accounts.address("C")) || snap.IsSigner(accounts.address("D")) || len(snap.Replacements) != 1 {
+		t.Fatalf("replacement applied prematurely: %v", snap.SignerList())
+	}
+	// The passing vote should swap the accounts in one go
+	if snap, err = snap.apply([]*types.Header{vote(2, "B", "C", "D")}); err != nil {
+		t.Fatalf("failed to apply passing replacement vote: %v", err)
+	}
+	if snap.IsSigner(accounts.address("C")) || !snap.IsSigner(accounts.address("D")) || len(snap.Signers) != 3 {
+		t.Errorf("signers not swapped: %v", snap.SignerList())
+	}
+	if len(snap.Replacements) != 0 {
+		t.Errorf("replacement votes kept: %d", len(snap.Replacements))
+	}
+	if len(snap.resolutions) != 1 || snap.resolutions[0].Kind != ProposalReplacement || snap.resolutions[0].Replaced != accounts.address("C") {
+		t.Errorf("replacement not resolved: %v", snap.resolutions)
+	}
+}
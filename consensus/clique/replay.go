@@ -0,0 +1,131 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// replayVolatile are the snapshot fields changing with every block, which are
+// not governance state transitions on their own.
+var replayVolatile = map[string]bool{"number": true, "hash": true, "recents": true}
+
+// Transition is a governance state transition of a replayed block: the snapshot
+// fields the block changed, along with the votes and proposals causing it.
+type Transition struct {
+	Number   uint64              `json:"number"`             // Block number of the transition
+	Hash     common.Hash         `json:"hash"`               // Block hash of the transition
+	Time     uint64              `json:"time"`               // Timestamp of the block
+	Signer   common.Address      `json:"signer"`             // Signer that sealed the block
+	Fields   []string            `json:"fields,omitempty"`   // Governance fields of the snapshot changed by the block
+	Records  []*GovernanceRecord `json:"records,omitempty"`  // Votes counted and proposals passed in the block
+	Signers  []common.Address    `json:"signers"`            // Authorized signers after the block
+	Limit    uint                `json:"limit"`              // Signer limit after the block
+	Stored   bool                `json:"stored,omitempty"`   // Whether a persisted snapshot of the block was compared
+	Mismatch []string            `json:"mismatch,omitempty"` // Fields the persisted snapshot differs from the replayed one in
+}
+
+// ReplayGovernance re-applies the canonical headers between the given blocks (both
+// inclusive) on top of the snapshot of the block preceding them, and reports every
+// block changing the governance state. If compare is set, the replayed state of
+// every block with a persisted snapshot is checked against it, and the block is
+// reported even if it changed nothing, listing the fields they differ in.
+//
+// The callback may abort the replay by returning an error, which is passed on to
+// the caller.
+func (c *Clique) ReplayGovernance(chain consensus.ChainHeaderReader, from, to uint64, compare bool, fn func(*Transition) error) error {
+	if from == 0 {
+		from = 1 // The genesis doesn't carry any votes
+	}
+	parent := chain.GetHeaderByNumber(from - 1)
+	if parent == nil {
+		return errUnknownBlock
+	}
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return err
+	}
+	db, err := c.snapshotDB(chain)
+	if err != nil {
+		return err
+	}
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for number := from; number <= to; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil || header.ParentHash != parent.Hash() {
+			return errUnknownBlock
+		}
+		next, err := snap.apply([]*types.Header{header})
+		if err != nil {
+			return fmt.Errorf("failed to apply block %d: %v", number, err)
+		}
+		signer, err := ecrecover(header, c.signatures)
+		if err != nil {
+			return err
+		}
+		hash := header.Hash()
+		transition := &Transition{
+			Number:  number,
+			Hash:    hash,
+			Time:    header.Time,
+			Signer:  signer,
+			Records: governanceRecords(next, header),
+			Signers: next.signers(),
+			Limit:   next.SignerLimit,
+		}
+		changed, err := snapshotDiff(snap, next)
+		if err != nil {
+			return err
+		}
+		for _, field := range changed {
+			if !replayVolatile[field] {
+				transition.Fields = append(transition.Fields, field)
+			}
+		}
+		if compare && hasSnapshot(db, hash) {
+			stored, err := loadSnapshot(c.config, c.signatures, db, hash)
+			if err != nil {
+				return fmt.Errorf("failed to load snapshot %d: %v", number, err)
+			}
+			if transition.Mismatch, err = snapshotDiff(stored, next); err != nil {
+				return err
+			}
+			transition.Stored = true
+		}
+		if len(transition.Fields) > 0 || len(transition.Records) > 0 || transition.Stored {
+			if err := fn(transition); err != nil {
+				return err
+			}
+		}
+		snap, parent = next, header
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Replaying voting history", "number", number, "to", to, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that replaying the voting history reports the blocks changing the
+// governance state, and the persisted snapshots diverging from the replay.
+func TestReplayGovernance(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B"}, 8)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	// Vote in a new signer in the second and third blocks, resealing the chain
+	for i, header := range chain.headers[1:] {
+		header.ParentHash = chain.headers[i].Hash()
+		if number := i + 1; number == 2 || number == 3 {
+			header.Coinbase = accounts.address("C")
+			copy(header.Nonce[:], nonceAuthVote)
+		}
+		accounts.sign(header, []string{"A", "B"}[(i+1)%2])
+	}
+	var transitions []*Transition
+	collect := func(t *Transition) error {
+		transitions = append(transitions, t)
+		return nil
+	}
+	if err := engine.ReplayGovernance(chain, 1, 8, false, collect); err != nil {
+		t.Fatalf("failed to replay voting history: %v", err)
+	}
+	if len(transitions) != 2 {
+		t.Fatalf("transition count mismatch: have %d, want 2", len(transitions))
+	}
+	if tr := transitions[0]; tr.Number != 2 || !reflect.DeepEqual(tr.Fields, []string{"tally", "votes"}) || len(tr.Records) != 1 {
+		t.Errorf("vote transition mismatch: have %+v", tr)
+	}
+	if tr := transitions[1]; tr.Number != 3 || len(tr.Signers) != 3 || len(tr.Records) != 2 {
+		t.Errorf("authorization transition mismatch: have %+v", tr)
+	}
+	// Persist a tampered snapshot and ensure it's reported when comparing
+	db, err := engine.snapshotDB(chain)
+	if err != nil {
+		t.Fatalf("failed to open snapshot database: %v", err)
+	}
+	snap, err := engine.snapshot(chain, 6, chain.headers[6].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	tampered := snap.copy()
+	tampered.SignerLimit++
+	if err := tampered.store(db); err != nil {
+		t.Fatalf("failed to store tampered snapshot: %v", err)
+	}
+	transitions = nil
+	if err := engine.ReplayGovernance(chain, 4, 8, true, collect); err != nil {
+		t.Fatalf("failed to replay voting history: %v", err)
+	}
+	if len(transitions) != 1 {
+		t.Fatalf("transition count mismatch: have %d, want 1", len(transitions))
+	}
+	if tr := transitions[0]; tr.Number != 6 || !tr.Stored || !reflect.DeepEqual(tr.Mismatch, []string{"limit"}) {
+		t.Errorf("mismatch transition mismatch: have %+v", tr)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// nonceResignation is the magic nonce number for a signer to resign from the
+// signer set in the block it seals, naming itself in the coinbase.
+var nonceResignation = hexutil.MustDecode("0xfffffff700000000")
+
+var (
+	// errResignationDisabled is returned if a block carries a resignation before
+	// the resignation fork.
+	errResignationDisabled = errors.New("signer resignations not enabled")
+
+	// errInvalidResignation is returned if a resignation block doesn't name its
+	// own sealer in the coinbase.
+	errInvalidResignation = errors.New("resignation of another signer")
+)
+
+// isResignation returns whether the header carries the resignation of its sealer.
+func isResignation(header *types.Header) bool {
+	return bytes.Equal(header.Nonce[:], nonceResignation)
+}
+
+// verifyResignation checks that a resignation block sealed by the given signer
+// names the signer itself in the coinbase.
+func verifyResignation(header *types.Header, signer common.Address) error {
+	if header.Coinbase != signer {
+		return errInvalidResignation
+	}
+	return nil
+}
+
+// canResign returns whether it makes sense for the signer to resign in the block
+// with the given number.
+func (s *Snapshot) canResign(signer common.Address, number uint64) bool {
+	if number%s.config.Epoch == 0 {
+		return false
+	}
+	if _, ok := s.Signers[signer]; !ok {
+		return false
+	}
+	return s.resizable(false)
+}
+
+// resign removes a signer from the signer set right away on its own request,
+// without waiting for the other signers to vote it out.
+func (s *Snapshot) resign(number uint64, hash common.Hash, signer common.Address) {
+	s.resolutions = append(s.resolutions, &Resolution{
+		Kind:    ProposalResignation,
+		Block:   number,
+		Hash:    hash,
+		Address: signer,
+		Votes:   []AuditVote{{Signer: signer, Block: number}},
+	})
+	s.deauthorize(number, signer)
+	s.discardProposal(signer)
+}
@@ -0,0 +1,72 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a signer resigning is removed right away along with the votes it
+// cast, but that it may neither resign another signer nor shrink the set below
+// its floor.
+func TestSignerResignation(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.CliqueConfig{Epoch: 30000, ResignationBlock: big.NewInt(0), MinSigners: 2}
+	snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, []common.Address{
+		accounts.address("A"), accounts.address("B"), accounts.address("C"),
+	})
+	block := func(number int64, signer, coinbase string, nonce []byte) *types.Header {
+		header := &types.Header{
+			Number:   big.NewInt(number),
+			Coinbase: accounts.address(coinbase),
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], nonce)
+		accounts.sign(header, signer)
+		return header
+	}
+	// A signer may not resign on behalf of another one
+	if _, err := snap.apply([]*types.Header{block(1, "A", "B", nonceResignation)}); err != errInvalidResignation {
+		t.Errorf("foreign resignation error mismatch: have %v, want %v", err, errInvalidResignation)
+	}
+	// A resigning signer should leave at once, taking its votes along
+	snap, err := snap.apply([]*types.Header{block(1, "C", "D", nonceAuthVote), block(2, "A", "", nonceDropVote), block(3, "C", "C", nonceResignation)})
+	if err != nil {
+		t.Fatalf("failed to apply resignation: %v", err)
+	}
+	if snap.IsSigner(accounts.address("C")) || len(snap.Signers) != 2 {
+		t.Errorf("resigned signer still authorized: %v", snap.SignerList())
+	}
+	if len(snap.Votes) != 0 || len(snap.Tally) != 0 {
+		t.Errorf("votes of resigned signer kept: %d votes, %d tallies", len(snap.Votes), len(snap.Tally))
+	}
+	if len(snap.resolutions) != 1 || snap.resolutions[0].Kind != ProposalResignation {
+		t.Errorf("resignation not resolved: %v", snap.resolutions)
+	}
+	// Resigning at the floor must be refused
+	if snap.canResign(accounts.address("A"), 4) {
+		t.Errorf("resignation at the floor deemed valid")
+	}
+	if _, err := snap.apply([]*types.Header{block(4, "A", "A", nonceResignation)}); err != errSignerFloor {
+		t.Errorf("resignation at the floor error mismatch: have %v, want %v", err, errSignerFloor)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// schemaVersionKey tracks the layout version of the clique database of a chain,
+// stored within the namespace of the chain.
+var schemaVersionKey = []byte("clique-schema") // schemaVersionKey -> version (uint64 big endian)
+
+// errSchemaTooNew is returned if the clique database was written by a newer
+// version of the engine, using a layout this one doesn't know.
+var errSchemaTooNew = errors.New("clique database schema newer than supported")
+
+// schemaMigration upgrades the clique database of a chain from one layout version
+// to the next.
+type schemaMigration struct {
+	name    string                                                                             // Description of the layout change
+	migrate func(chain consensus.ChainHeaderReader, db ethdb.Database, namespace string) error // Upgrade from the previous version
+}
+
+// schemaMigrations lists the upgrades from every layout version to the next, the
+// current version being the number of them. Append a migration for each layout
+// change (prefixes, indices, delta storage), never modify or remove one.
+var schemaMigrations = []schemaMigration{
+	{name: "namespace snapshots by genesis", migrate: migrateSnapshots},
+	{name: "index snapshots by block number", migrate: migrateSnapshotIndex},
+}
+
+// readSchemaVersion retrieves the layout version of the clique database of the
+// chain with the given namespace, zero if it predates versioning.
+func readSchemaVersion(db ethdb.KeyValueReader, namespace string) uint64 {
+	blob, err := db.Get(append([]byte(namespace), schemaVersionKey...))
+	if err != nil || len(blob) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(blob)
+}
+
+// writeSchemaVersion stores the layout version of the clique database of the
+// chain with the given namespace.
+func writeSchemaVersion(db ethdb.KeyValueWriter, namespace string, version uint64) error {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], version)
+	return db.Put(append([]byte(namespace), schemaVersionKey...), blob[:])
+}
+
+// migrateSchema upgrades the clique database of a chain to the current layout,
+// running every pending migration in order and recording the version reached
+// after each, so an interrupted upgrade resumes where it stopped.
+func migrateSchema(chain consensus.ChainHeaderReader, db ethdb.Database, namespace string) error {
+	version := readSchemaVersion(db, namespace)
+	if version > uint64(len(schemaMigrations)) {
+		return fmt.Errorf("%w: have %d, want %d", errSchemaTooNew, version, len(schemaMigrations))
+	}
+	for ; version < uint64(len(schemaMigrations)); version++ {
+		migration := schemaMigrations[version]
+		log.Debug("Migrating clique database", "from", version, "to", version+1, "migration", migration.name)
+
+		if err := migration.migrate(chain, db, namespace); err != nil {
+			return fmt.Errorf("clique database migration %q failed: %v", migration.name, err)
+		}
+		if err := writeSchemaVersion(db, namespace, version+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateSchema upgrades the clique database of the chain to the current layout,
+// if not done yet. It's meant to be run on startup, so a failure aborts it rather
+// than orphaning the entries of the old layout; the engine would otherwise run
+// it on first access to the snapshots.
+func (c *Clique) MigrateSchema(chain consensus.ChainHeaderReader) error {
+	_, err := c.snapshotDB(chain)
+	return err
+}
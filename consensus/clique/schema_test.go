@@ -0,0 +1,75 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the clique database of a chain is upgraded to the current schema
+// version once, and that databases written with a newer schema are refused.
+func TestSchemaMigration(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000}
+		db       = rawdb.NewMemoryDatabase()
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, []string{"A"})
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+	namespace := snapshotNamespace(genesis.Hash())
+
+	// A fresh database should be stamped with the current version
+	if err := New(config, db).MigrateSchema(chain); err != nil {
+		t.Fatalf("failed to migrate fresh database: %v", err)
+	}
+	if version := readSchemaVersion(db, namespace); version != uint64(len(schemaMigrations)) {
+		t.Fatalf("schema version mismatch: have %d, want %d", version, len(schemaMigrations))
+	}
+	// Legacy entries written after the upgrade must not be touched any more
+	snap := newSnapshot(config, nil, 0, genesis.Hash(), []common.Address{accounts.address("A")})
+	if err := snap.store(db); err != nil {
+		t.Fatalf("failed to store legacy snapshot: %v", err)
+	}
+	if err := New(config, db).MigrateSchema(chain); err != nil {
+		t.Fatalf("failed to reopen migrated database: %v", err)
+	}
+	if has, _ := db.Has(append(append([]byte{}, snapshotPrefix...), snap.Hash[:]...)); !has {
+		t.Errorf("migration rerun on up-to-date database")
+	}
+	// A database from the future must be refused
+	if err := writeSchemaVersion(db, namespace, uint64(len(schemaMigrations))+1); err != nil {
+		t.Fatalf("failed to store schema version: %v", err)
+	}
+	engine := New(config, db)
+	if err := engine.MigrateSchema(chain); !errors.Is(err, errSchemaTooNew) {
+		t.Errorf("newer schema error mismatch: have %v, want %v", err, errSchemaTooNew)
+	}
+	if _, err := engine.snapshot(chain, 0, genesis.Hash(), nil); !errors.Is(err, errSchemaTooNew) {
+		t.Errorf("snapshot on newer schema error mismatch: have %v, want %v", err, errSchemaTooNew)
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	sealSignFailMeter     = metrics.NewRegisteredMeter("clique/seal/signfail", nil)
+	sealRetryMeter        = metrics.NewRegisteredMeter("clique/seal/retry", nil)
+	sealMissedMeter       = metrics.NewRegisteredMeter("clique/seal/missed", nil)
+	sealMissedInTurnMeter = metrics.NewRegisteredMeter("clique/seal/missedinturn", nil)
+)
+
+// SealRetryPolicy configures how the engine retries signing a block after the
+// signer failed to authorize it (e.g. a locked key or a clef timeout). Retries
+// never outlast the slot of the block: once the next block is due, the slot is
+// considered lost.
+type SealRetryPolicy struct {
+	MaxAttempts int           // Maximum signing attempts per block (0 = retry until the slot is lost)
+	Backoff     time.Duration // Delay before the first retry, doubled after every failure
+	MaxBackoff  time.Duration // Upper bound of the delay between two retries
+}
+
+// DefaultSealRetryPolicy is the retry policy of newly created engines.
+var DefaultSealRetryPolicy = SealRetryPolicy{
+	Backoff:    100 * time.Millisecond,
+	MaxBackoff: 2 * time.Second,
+}
+
+// SealFailureEvent is posted whenever the local signer misses its in-turn slot
+// because its signer failed to authorize the block until the slot was lost.
+type SealFailureEvent struct {
+	Block    uint64         `json:"block"`    // Block number the local signer was in-turn for
+	Signer   common.Address `json:"signer"`   // Local signer that missed its slot
+	Attempts int            `json:"attempts"` // Number of signing attempts made
+	Error    string         `json:"error"`    // Error of the last signing attempt
+}
+
+// SetSealRetryPolicy replaces the policy used to retry failed block signatures.
+func (c *Clique) SetSealRetryPolicy(policy SealRetryPolicy) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.retry = policy
+}
+
+// SubscribeSealFailures registers a subscription for the in-turn slots the local
+// signer missed due to signing failures.
+func (c *Clique) SubscribeSealFailures(ch chan<- *SealFailureEvent) event.Subscription {
+	return c.scope.Track(c.failureFeed.Subscribe(ch))
+}
+
+// slotDeadline returns the time after which sealing the header is pointless, as
+// the next block is already due.
+func (c *Clique) slotDeadline(header *types.Header) time.Time {
+	period := c.config.Period
+	if period == 0 {
+		period = 1
+	}
+	return time.Unix(int64(header.Time+period), 0)
+}
+
+// retrySeal keeps retrying to sign a block in the background according to the
+// retry policy, delivering it at the due time if the signer recovers in time and
+// reporting the missed slot otherwise.
+func (c *Clique) retrySeal(chain consensus.ChainHeaderReader, block *types.Block, header *types.Header, signer common.Address, signFn SignerFn, inturn bool, due time.Time, results chan<- *types.Block, stop <-chan struct{}, err error) {
+	defer c.wg.Done()
+
+	c.lock.RLock()
+	policy := c.retry
+	c.lock.RUnlock()
+
+	var (
+		number   = header.Number.Uint64()
+		deadline = c.slotDeadline(header)
+		backoff  = policy.Backoff
+		attempts = 1
+	)
+	for {
+		if policy.MaxAttempts > 0 && attempts >= policy.MaxAttempts {
+			break
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			break
+		}
+		log.Warn("Failed to sign block, retrying", "number", number, "attempt", attempts, "backoff", common.PrettyDuration(backoff), "err", err)
+		select {
+		case <-stop:
+			return
+		case <-c.quit:
+			return
+		case <-time.After(backoff):
+		}
+		sealRetryMeter.Mark(1)
+		attempts++
+
+		var sighash []byte
+		if sighash, err = signFn(accounts.Account{Address: signer}, accounts.MimetypeClique, CliqueRLP(header)); err == nil {
+			copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+			log.Info("Signed block after retrying", "number", number, "attempts", attempts)
+
+			select {
+			case <-stop:
+				return
+			case <-c.quit:
+				return
+			case <-time.After(time.Until(due)):
+			}
+			select {
+			case results <- c.withReceipt(chain, block.WithSeal(header)):
+			default:
+				log.Warn("Sealing result is not read by miner", "sealhash", SealHash(header))
+			}
+			return
+		}
+		sealSignFailMeter.Mark(1)
+
+		if backoff *= 2; policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+	c.missedSlot(number, signer, inturn, attempts, err)
+}
+
+// missedSlot records a slot the local signer lost due to signing failures,
+// alerting the subscribers if it was entitled to seal it in-turn.
+func (c *Clique) missedSlot(number uint64, signer common.Address, inturn bool, attempts int, err error) {
+	sealMissedMeter.Mark(1)
+	if !inturn {
+		log.Warn("Failed to sign out-of-turn block", "number", number, "attempts", attempts, "err", err)
+		return
+	}
+	sealMissedInTurnMeter.Mark(1)
+	log.Error("Missed in-turn slot due to signing failures", "number", number, "signer", signer, "attempts", attempts, "err", err)
+
+	c.failureFeed.Send(&SealFailureEvent{
+		Block:    number,
+		Signer:   signer,
+		Attempts: attempts,
+		Error:    err.Error(),
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that failed block signatures are retried within the slot, and that the
+// subscribers are alerted if the local signer misses its in-turn slot.
+func TestSealRetry(t *testing.T) {
+	var (
+		pool    = newTesterAccountPool()
+		config  = &params.CliqueConfig{Period: 3, Epoch: 30000}
+		signers = []string{"A"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Time:       uint64(time.Now().Unix()) - 3,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	pool.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.SetSealRetryPolicy(SealRetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Millisecond})
+
+	failures := make(chan *SealFailureEvent, 1)
+	sub := engine.SubscribeSealFailures(failures)
+	defer sub.Unsubscribe()
+
+	// Create a signer failing a given number of times before recovering
+	signer := func(fails int) SignerFn {
+		return func(account accounts.Account, mimeType string, message []byte) ([]byte, error) {
+			if fails > 0 {
+				fails--
+				return nil, errors.New("signer locked")
+			}
+			return crypto.Sign(crypto.Keccak256(message), pool.accounts["A"])
+		}
+	}
+	seal := func(fails int) (*types.Block, error) {
+		engine.Authorize(pool.address("A"), signer(fails))
+
+		header := &types.Header{
+			ParentHash: genesis.Hash(),
+			Number:     common.Big1,
+			Time:       genesis.Time + config.Period,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		results := make(chan *types.Block, 1)
+		if err := engine.Seal(chain, types.NewBlockWithHeader(header), results, make(chan struct{})); err != nil {
+			return nil, err
+		}
+		select {
+		case block := <-results:
+			return block, nil
+		case failure := <-failures:
+			return nil, errors.New(failure.Error)
+		case <-time.After(time.Second):
+			return nil, errors.New("timeout")
+		}
+	}
+	// A signer recovering within the allowed attempts should still seal the block
+	block, err := seal(2)
+	if err != nil {
+		t.Fatalf("failed to seal with recovering signer: %v", err)
+	}
+	if author, err := engine.Author(block.Header()); err != nil || author != pool.address("A") {
+		t.Errorf("sealer mismatch: have %x (%v), want %x", author, err, pool.address("A"))
+	}
+	// A signer failing all attempts should report the missed in-turn slot
+	if _, err := seal(3); err == nil || err.Error() != "signer locked" {
+		t.Errorf("missed slot error mismatch: have %v, want %v", err, "signer locked")
+	}
+}
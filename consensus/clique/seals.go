@@ -0,0 +1,218 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// sealWindow is the number of most recent blocks over which the participation
+// of the individual signers is tracked.
+const sealWindow = 1024
+
+// sealRecord is the participation data of a single sealed block.
+type sealRecord struct {
+	Number uint64         // Number of the sealed block
+	Hash   common.Hash    // Hash of the sealed block
+	Signer common.Address // Signer that sealed the block
+	InTurn bool           // Whether the signer sealed in its own turn
+	Turn   common.Address // Signer whose turn it was to seal the block
+}
+
+// missed returns whether the in-turn signer failed to seal the block.
+func (r sealRecord) missed() bool {
+	return !r.InTurn && r.Turn != r.Signer
+}
+
+// seal creates the participation record of a header sealed by the given signer
+// on top of the snapshot.
+func (s *Snapshot) seal(header *types.Header, signer common.Address) sealRecord {
+	number := header.Number.Uint64()
+	return sealRecord{
+		Number: number,
+		Hash:   header.Hash(),
+		Signer: signer,
+		InTurn: s.inturn(number, signer),
+		Turn:   s.sorted[number%uint64(len(s.sorted))],
+	}
+}
+
+// SealStats is the number of blocks a signer sealed or failed to seal in its turn
+// within the tracked window.
+type SealStats struct {
+	InTurn    uint64 `json:"inturn"`    // Number of blocks sealed in-turn
+	OutOfTurn uint64 `json:"outOfTurn"` // Number of blocks sealed out-of-turn
+	Missed    uint64 `json:"missed"`    // Number of in-turn slots another signer sealed instead
+}
+
+// MissedSlot is a block which its in-turn signer failed to seal.
+type MissedSlot struct {
+	Number uint64         `json:"number"` // Number of the block sealed out-of-turn
+	Hash   common.Hash    `json:"hash"`   // Hash of the block sealed out-of-turn
+	Sealer common.Address `json:"sealer"` // Signer that sealed the block instead
+}
+
+// sealTracker aggregates the blocks sealed by each signer within a sliding window
+// of the most recent block heights. Each height is attributed to the last block
+// observed there, so reorged-out seals are replaced by the new canonical ones.
+type sealTracker struct {
+	window  uint64                        // Number of block heights tracked
+	head    uint64                        // Highest block number observed
+	records map[uint64]sealRecord         // Last seal observed at each tracked height
+	stats   map[common.Address]*SealStats // Aggregated participation of the signers
+
+	lock sync.RWMutex
+}
+
+// newSealTracker creates a participation tracker over the given window.
+func newSealTracker(window uint64) *sealTracker {
+	return &sealTracker{
+		window:  window,
+		records: make(map[uint64]sealRecord),
+		stats:   make(map[common.Address]*SealStats),
+	}
+}
+
+// add accounts a batch of freshly sealed blocks, evicting any that fall out of
+// the tracked window.
+func (t *sealTracker) add(records []sealRecord) {
+	if len(records) == 0 {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	touched := make(map[common.Address]struct{})
+	for _, rec := range records {
+		if rec.Number+t.window <= t.head {
+			continue
+		}
+		if old, ok := t.records[rec.Number]; ok {
+			t.count(old, false)
+			touched[old.Signer], touched[old.Turn] = struct{}{}, struct{}{}
+		}
+		t.records[rec.Number] = rec
+		t.count(rec, true)
+		touched[rec.Signer], touched[rec.Turn] = struct{}{}, struct{}{}
+
+		if rec.Number > t.head {
+			t.head = rec.Number
+		}
+	}
+	for number, rec := range t.records {
+		if number+t.window <= t.head {
+			delete(t.records, number)
+			t.count(rec, false)
+			touched[rec.Signer], touched[rec.Turn] = struct{}{}, struct{}{}
+		}
+	}
+	for signer := range touched {
+		t.updateMetrics(signer)
+	}
+}
+
+// truncate drops all the seals tracked above the given block number, e.g. after
+// the chain was rewound.
+func (t *sealTracker) truncate(number uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for n, rec := range t.records {
+		if n > number {
+			delete(t.records, n)
+			t.count(rec, false)
+			t.updateMetrics(rec.Signer)
+			t.updateMetrics(rec.Turn)
+		}
+	}
+	if t.head > number {
+		t.head = number
+	}
+}
+
+// updateMetrics publishes the aggregated participation of a signer.
+func (t *sealTracker) updateMetrics(signer common.Address) {
+	stats := t.signerStats(signer)
+	metrics.GetOrRegisterGauge("clique/seals/"+signer.Hex()+"/inturn", nil).Update(int64(stats.InTurn))
+	metrics.GetOrRegisterGauge("clique/seals/"+signer.Hex()+"/outofturn", nil).Update(int64(stats.OutOfTurn))
+	metrics.GetOrRegisterGauge("clique/seals/"+signer.Hex()+"/missed", nil).Update(int64(stats.Missed))
+}
+
+// signerStats retrieves the aggregated participation of a signer, creating an
+// empty one if it doesn't exist yet.
+func (t *sealTracker) signerStats(signer common.Address) *SealStats {
+	stats := t.stats[signer]
+	if stats == nil {
+		stats = new(SealStats)
+		t.stats[signer] = stats
+	}
+	return stats
+}
+
+// count adds or removes a sealed block to/from the aggregated statistics.
+func (t *sealTracker) count(rec sealRecord, add bool) {
+	stats := t.signerStats(rec.Signer)
+	counters := []*uint64{&stats.OutOfTurn}
+	if rec.InTurn {
+		counters[0] = &stats.InTurn
+	}
+	if rec.missed() {
+		counters = append(counters, &t.signerStats(rec.Turn).Missed)
+	}
+	for _, counter := range counters {
+		if add {
+			*counter++
+		} else {
+			*counter--
+		}
+	}
+}
+
+// Stats returns the participation of every signer seen within the window.
+func (t *sealTracker) Stats() map[common.Address]SealStats {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	stats := make(map[common.Address]SealStats, len(t.stats))
+	for signer, s := range t.stats {
+		if s.InTurn > 0 || s.OutOfTurn > 0 || s.Missed > 0 {
+			stats[signer] = *s
+		}
+	}
+	return stats
+}
+
+// Missed returns the in-turn slots the given signer failed to seal within the
+// window, in ascending block order.
+func (t *sealTracker) Missed(signer common.Address) []MissedSlot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	missed := []MissedSlot{}
+	for _, rec := range t.records {
+		if rec.missed() && rec.Turn == signer {
+			missed = append(missed, MissedSlot{Number: rec.Number, Hash: rec.Hash, Sealer: rec.Signer})
+		}
+	}
+	sort.Slice(missed, func(i, j int) bool { return missed[i].Number < missed[j].Number })
+	return missed
+}
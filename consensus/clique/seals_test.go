@@ -0,0 +1,84 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that the seal tracker aggregates the participation and missed slots of
+// the signers over a sliding window, replacing seals at reorged heights.
+func TestSealTracker(t *testing.T) {
+	var (
+		a = common.Address{0x0a}
+		b = common.Address{0x0b}
+	)
+	tracker := newSealTracker(4)
+
+	// Fill up the window and check the aggregates
+	tracker.add([]sealRecord{
+		{Number: 1, Signer: a, InTurn: true, Turn: a},
+		{Number: 2, Signer: b, InTurn: true, Turn: b},
+		{Number: 3, Signer: a, InTurn: false, Turn: b},
+		{Number: 4, Signer: b, InTurn: true, Turn: b},
+	})
+	want := map[common.Address]SealStats{
+		a: {InTurn: 1, OutOfTurn: 1},
+		b: {InTurn: 2, Missed: 1},
+	}
+	if have := tracker.Stats(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("stats mismatch: have %v, want %v", have, want)
+	}
+	// Replace a height via a reorg and check the old seal is discounted
+	tracker.add([]sealRecord{{Number: 4, Hash: common.Hash{0x01}, Signer: a, InTurn: false, Turn: b}})
+	want = map[common.Address]SealStats{
+		a: {InTurn: 1, OutOfTurn: 2},
+		b: {InTurn: 1, Missed: 2},
+	}
+	if have := tracker.Stats(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("stats mismatch after reorg: have %v, want %v", have, want)
+	}
+	wantMissed := []MissedSlot{{Number: 3, Sealer: a}, {Number: 4, Hash: common.Hash{0x01}, Sealer: a}}
+	if have := tracker.Missed(b); !reflect.DeepEqual(have, wantMissed) {
+		t.Fatalf("missed slots mismatch: have %v, want %v", have, wantMissed)
+	}
+	// Slide the window and check the old seals are evicted
+	tracker.add([]sealRecord{
+		{Number: 5, Signer: b, InTurn: false, Turn: a},
+		{Number: 6, Signer: b, InTurn: true, Turn: b},
+		{Number: 7, Signer: a, InTurn: true, Turn: a},
+	})
+	want = map[common.Address]SealStats{
+		a: {InTurn: 1, OutOfTurn: 1, Missed: 1},
+		b: {InTurn: 1, OutOfTurn: 1, Missed: 1},
+	}
+	if have := tracker.Stats(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("stats mismatch after sliding: have %v, want %v", have, want)
+	}
+	// Seals below the window should be ignored
+	tracker.add([]sealRecord{{Number: 1, Signer: b, InTurn: true, Turn: b}})
+	if have := tracker.Stats(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("stats mismatch after stale seal: have %v, want %v", have, want)
+	}
+	wantMissed = []MissedSlot{{Number: 4, Hash: common.Hash{0x01}, Sealer: a}}
+	if have := tracker.Missed(b); !reflect.DeepEqual(have, wantMissed) {
+		t.Fatalf("missed slots mismatch after sliding: have %v, want %v", have, wantMissed)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// flushedSnapshotKey tracks the hash of the snapshot flushed to disk when the
+// engine was last closed, letting it resume from there instead of replaying all
+// the headers since the last checkpoint.
+var flushedSnapshotKey = []byte("clique-flushed")
+
+// errEngineClosed is returned if work is requested from an engine that is
+// already closed.
+var errEngineClosed = errors.New("clique engine closed")
+
+// track registers a background task of the engine to wait for on shutdown. It
+// returns false if the engine is already closed, in which case the task must
+// not be started.
+func (c *Clique) track() bool {
+	c.closeLock.Lock()
+	defer c.closeLock.Unlock()
+
+	select {
+	case <-c.quit:
+		return false
+	default:
+		c.wg.Add(1)
+		return true
+	}
+}
+
+// loadFlushed retrieves the hash of the snapshot flushed on the last shutdown.
+func (c *Clique) loadFlushed(db ethdb.Database) {
+	if blob, err := db.Get(flushedSnapshotKey); err == nil && len(blob) == common.HashLength {
+		c.flushed = common.BytesToHash(blob)
+	}
+}
+
+// flushSnapshot writes the most recent snapshot of the canonical chain head to
+// disk, so a node stopped between checkpoints doesn't lose the votes cast since
+// the last one. Only the snapshots of the head and its parent are considered, one
+// of which is cached whenever the node is following the chain.
+func (c *Clique) flushSnapshot() {
+	c.headLock.Lock()
+	head := c.head
+	c.headLock.Unlock()
+
+	if head == nil || c.db == nil {
+		return
+	}
+	db, err := c.snapshotDB(nil)
+	if err != nil {
+		return
+	}
+	for _, hash := range []common.Hash{head.Hash(), head.ParentHash} {
+		snap, ok := c.recents.Get(hash)
+		if !ok {
+			continue
+		}
+		if snap.Number%checkpointInterval == 0 {
+			return // Already persisted when created
+		}
+		if err := snap.store(db); err != nil {
+			log.Warn("Failed to flush voting snapshot", "number", snap.Number, "hash", snap.Hash, "err", err)
+			return
+		}
+		if err := db.Put(flushedSnapshotKey, snap.Hash[:]); err != nil {
+			log.Warn("Failed to track flushed voting snapshot", "number", snap.Number, "hash", snap.Hash, "err", err)
+			return
+		}
+		log.Info("Flushed voting snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+		return
+	}
+	log.Debug("No voting snapshot to flush", "number", head.Number, "hash", head.Hash())
+}
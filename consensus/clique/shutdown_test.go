@@ -0,0 +1,110 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that closing the engine cancels in-flight sealing, rejects new work and
+// flushes the head snapshot so it survives a restart between checkpoints.
+func TestGracefulShutdown(t *testing.T) {
+	var (
+		pool    = newTesterAccountPool()
+		config  = &params.CliqueConfig{Period: 5, Epoch: 30000}
+		signers = []string{"A", "B"}
+		db      = rawdb.NewMemoryDatabase()
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	pool.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	engine := New(config, db)
+	for i, signer := range []string{"A", "B", "A"} {
+		header := &types.Header{
+			ParentHash: chain.headers[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Difficulty: common.Big1,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i == 1 {
+			header.Coinbase, header.Nonce = pool.address("C"), types.BlockNonce{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		}
+		pool.sign(header, signer)
+		chain.headers = append(chain.headers, header)
+	}
+	head := chain.CurrentHeader()
+	if _, err := engine.snapshot(chain, head.Number.Uint64(), head.Hash(), nil); err != nil {
+		t.Fatalf("failed to create head snapshot: %v", err)
+	}
+	engine.NewChainHead(chain, head)
+
+	// Start sealing a block far in the future and ensure closing cancels it
+	engine.Authorize(pool.address("B"), func(_ accounts.Account, _ string, data []byte) ([]byte, error) {
+		return make([]byte, extraSeal), nil
+	})
+	pending := &types.Header{
+		ParentHash: head.Hash(),
+		Number:     big.NewInt(4),
+		Time:       uint64(time.Now().Unix()) + 3600,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	results := make(chan *types.Block, 1)
+	if err := engine.Seal(chain, types.NewBlockWithHeader(pending), results, make(chan struct{})); err != nil {
+		t.Fatalf("failed to start sealing: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		engine.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("close didn't cancel in-flight sealing")
+	}
+	if err := engine.Seal(chain, types.NewBlockWithHeader(pending), results, make(chan struct{})); err != errEngineClosed {
+		t.Errorf("seal after close error mismatch: have %v, want %v", err, errEngineClosed)
+	}
+	if _, errc := engine.VerifyHeaders(chain, []*types.Header{head}, []bool{true}); <-errc != errEngineClosed {
+		t.Errorf("verification after close didn't fail")
+	}
+	// Restart the engine on a chain missing the history and ensure the votes survived
+	restarted := New(config, db)
+	pruned := &testerHeaderChain{config: chain.config, headers: []*types.Header{genesis}}
+
+	snap, err := restarted.snapshot(pruned, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to load flushed snapshot: %v", err)
+	}
+	if tally := snap.Tally[pool.address("C")]; tally.Votes != 1 {
+		t.Errorf("flushed vote tally mismatch: have %d, want %d", tally.Votes, 1)
+	}
+}
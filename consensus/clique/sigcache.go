@@ -0,0 +1,196 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	sigcachePrefix    = []byte("clique-signer-")    // sigcachePrefix + hash -> signer
+	sigcacheSeqPrefix = []byte("clique-signerseq-") // sigcacheSeqPrefix + seq (uint64 big endian) -> hash
+	sigcacheSeqKey    = []byte("clique-signerseq")  // sigcacheSeqKey -> next seq (uint64 big endian)
+)
+
+const (
+	// persistedSignatures is the default number of recovered signers persisted,
+	// beyond which the oldest ones are pruned.
+	persistedSignatures = 1 << 20
+
+	// sigcachePruneSlack is the fraction of the persisted signer limit allowed to
+	// pile up above it before pruning, so pruning runs in batches.
+	sigcachePruneSlack = 16
+)
+
+var (
+	sigcacheHitMeter   = metrics.NewRegisteredMeter("clique/sigcache/hit", nil)
+	sigcacheMissMeter  = metrics.NewRegisteredMeter("clique/sigcache/miss", nil)
+	sigcacheEvictMeter = metrics.NewRegisteredMeter("clique/sigcache/evict", nil)
+)
+
+// SigCache is a cache of the signers recovered from block seals. A single cache
+// may be shared by any number of engine instances, and optionally backed by a
+// database to retain the signers beyond the in-memory capacity.
+type SigCache struct {
+	cache *lru.Cache[common.Hash, common.Address] // Recently recovered signers keyed by header hash
+	db    ethdb.KeyValueStore                     // Database to persist the signers into (optional)
+
+	limit uint64     // Maximum number of signers persisted, the oldest ones pruned beyond
+	head  uint64     // Sequence number of the next signer to persist
+	tail  uint64     // Sequence number of the oldest signer persisted
+	lock  sync.Mutex // Protects the persisted signer bookkeeping
+}
+
+// NewSigCache creates a signature cache holding up to size signers in memory. If
+// a database is given, recovered signers are also persisted into it and looked up
+// on in-memory misses, so restarts reuse them. The persisted signers are bounded,
+// the oldest ones being pruned once the limit is exceeded.
+func NewSigCache(size int, db ethdb.KeyValueStore) *SigCache {
+	if size <= 0 {
+		size = inmemorySignatures
+	}
+	sc := &SigCache{
+		cache: lru.New[common.Hash, common.Address](size, lru.ARC, ""),
+		db:    db,
+		limit: persistedSignatures,
+	}
+	if db != nil {
+		if blob, err := db.Get(sigcacheSeqKey); err == nil && len(blob) == 8 {
+			sc.head = binary.BigEndian.Uint64(blob)
+		}
+		it := db.NewIterator(sigcacheSeqPrefix, nil)
+		if it.Next() && len(it.Key()) == len(sigcacheSeqPrefix)+8 {
+			sc.tail = binary.BigEndian.Uint64(it.Key()[len(sigcacheSeqPrefix):])
+		} else {
+			sc.tail = sc.head
+		}
+		it.Release()
+	}
+	return sc
+}
+
+// SetPersistLimit sets the maximum number of recovered signers persisted into
+// the database, pruning the oldest ones beyond it.
+func (sc *SigCache) SetPersistLimit(limit int) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	if limit > 0 {
+		sc.limit = uint64(limit)
+	}
+}
+
+// Get retrieves the signer of the header with the given hash, if known.
+func (sc *SigCache) Get(hash common.Hash) (common.Address, bool) {
+	if signer, ok := sc.cache.Get(hash); ok {
+		sigcacheHitMeter.Mark(1)
+		return signer, true
+	}
+	if sc.db != nil {
+		if blob, err := sc.db.Get(append(sigcachePrefix, hash[:]...)); err == nil && len(blob) == common.AddressLength {
+			signer := common.BytesToAddress(blob)
+			sc.add(hash, signer)
+
+			sigcacheHitMeter.Mark(1)
+			return signer, true
+		}
+	}
+	sigcacheMissMeter.Mark(1)
+	return common.Address{}, false
+}
+
+// Add inserts the signer of the header with the given hash into the cache and,
+// if configured, into the database.
+func (sc *SigCache) Add(hash common.Hash, signer common.Address) {
+	sc.add(hash, signer)
+	if sc.db != nil {
+		if err := sc.persist(hash, signer); err != nil {
+			log.Warn("Failed to persist clique signer", "hash", hash, "err", err)
+		}
+	}
+}
+
+// persist writes a recovered signer into the database, journaling it in the
+// order of persistence, and prunes the oldest signers if too many piled up.
+func (sc *SigCache) persist(hash common.Hash, signer common.Address) error {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], sc.head)
+
+	batch := sc.db.NewBatch()
+	batch.Put(append(append([]byte{}, sigcachePrefix...), hash[:]...), signer[:])
+	batch.Put(append(append([]byte{}, sigcacheSeqPrefix...), seq[:]...), hash[:])
+
+	binary.BigEndian.PutUint64(seq[:], sc.head+1)
+	batch.Put(sigcacheSeqKey, seq[:])
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	sc.head++
+
+	if sc.head-sc.tail > sc.limit+sc.limit/sigcachePruneSlack {
+		return sc.prune()
+	}
+	return nil
+}
+
+// prune drops the oldest persisted signers above the limit. The caller must hold
+// the persistence lock.
+func (sc *SigCache) prune() error {
+	var (
+		batch  = sc.db.NewBatch()
+		it     = sc.db.NewIterator(sigcacheSeqPrefix, nil)
+		pruned uint64
+	)
+	defer it.Release()
+
+	for sc.head-sc.tail-pruned > sc.limit && it.Next() {
+		if len(it.Key()) != len(sigcacheSeqPrefix)+8 {
+			continue
+		}
+		batch.Delete(append(append([]byte{}, sigcachePrefix...), it.Value()...))
+		batch.Delete(common.CopyBytes(it.Key()))
+		pruned++
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	sc.tail += pruned
+	log.Debug("Pruned persisted clique signers", "pruned", pruned, "kept", sc.head-sc.tail)
+	return nil
+}
+
+// add inserts a signer into the in-memory cache, tracking evictions.
+func (sc *SigCache) add(hash common.Hash, signer common.Address) {
+	if sc.cache.Add(hash, signer) {
+		sigcacheEvictMeter.Mark(1)
+	}
+}
+
+// Len returns the number of signers held in memory.
+func (sc *SigCache) Len() int {
+	return sc.cache.Len()
+}
@@ -0,0 +1,105 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that engines sharing a signature cache reuse each other's recoveries and
+// that a persistent cache survives being recreated.
+func TestSharedSigCache(t *testing.T) {
+	accounts := newTesterAccountPool()
+
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Difficulty: diffInTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	accounts.sign(header, "A")
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		sigs   = NewSigCache(1, db)
+		config = &params.CliqueConfig{Epoch: 30000}
+		first  = NewWithSigCache(config, rawdb.NewMemoryDatabase(), sigs)
+		second = NewWithSigCache(config, rawdb.NewMemoryDatabase(), sigs)
+	)
+	if signer, err := first.Author(header); err != nil || signer != accounts.address("A") {
+		t.Fatalf("signer mismatch: have %x (%v), want %x", signer, err, accounts.address("A"))
+	}
+	if signer, ok := second.signatures.Get(header.Hash()); !ok || signer != accounts.address("A") {
+		t.Fatalf("shared signer mismatch: have %x (%v), want %x", signer, ok, accounts.address("A"))
+	}
+	// Evict the signer from memory and ensure it's served from the database
+	sigs.Add(common.Hash{0x01}, common.Address{0x01})
+	if sigs.Len() != 1 {
+		t.Fatalf("cache size mismatch: have %d, want %d", sigs.Len(), 1)
+	}
+	if signer, ok := NewSigCache(1, db).Get(header.Hash()); !ok || signer != accounts.address("A") {
+		t.Fatalf("persisted signer mismatch: have %x (%v), want %x", signer, ok, accounts.address("A"))
+	}
+	if _, ok := NewSigCache(1, nil).Get(header.Hash()); ok {
+		t.Fatalf("ephemeral cache returned unknown signer")
+	}
+}
+
+// Tests that the persisted signers are bounded, pruning the oldest ones, and that
+// the bookkeeping survives recreating the cache.
+func TestSigCachePruning(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	persisted := func() (hashes []common.Hash) {
+		for i := 0; i < 64; i++ {
+			if _, ok := NewSigCache(1, db).Get(common.Hash{byte(i)}); ok {
+				hashes = append(hashes, common.Hash{byte(i)})
+			}
+		}
+		return hashes
+	}
+	sigs := NewSigCache(1, db)
+	sigs.SetPersistLimit(16)
+	for i := 0; i < 40; i++ {
+		sigs.Add(common.Hash{byte(i)}, common.Address{byte(i)})
+	}
+	hashes := persisted()
+	if len(hashes) < 16 || len(hashes) > 16+16/sigcachePruneSlack {
+		t.Fatalf("persisted signer count mismatch: have %d, want 16-%d", len(hashes), 16+16/sigcachePruneSlack)
+	}
+	if hashes[len(hashes)-1] != (common.Hash{39}) {
+		t.Errorf("newest signer pruned: have %x", hashes[len(hashes)-1])
+	}
+	// Recreate the cache and ensure pruning continues from the oldest signers
+	sigs = NewSigCache(1, db)
+	sigs.SetPersistLimit(16)
+	for i := 40; i < 64; i++ {
+		sigs.Add(common.Hash{byte(i)}, common.Address{byte(i)})
+	}
+	hashes = persisted()
+	if len(hashes) < 16 || len(hashes) > 16+16/sigcachePruneSlack {
+		t.Fatalf("persisted signer count mismatch after restart: have %d, want 16-%d", len(hashes), 16+16/sigcachePruneSlack)
+	}
+	if hashes[0][0] < 40 {
+		t.Errorf("signer persisted before the restart not pruned: %x", hashes[0])
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// errSignerFloor is returned if a block votes on deauthorizing a signer, or a
+	// checkpoint overrides the signer set, although that would shrink the set below
+	// its configured minimum size.
+	errSignerFloor = errors.New("signer set at minimum size")
+
+	// errSignerCeiling is returned if a block votes on authorizing a new signer, or
+	// a checkpoint overrides the signer set, although that would grow the set above
+	// its configured maximum size.
+	errSignerCeiling = errors.New("signer set at maximum size")
+)
+
+// atSignerFloor returns whether the signer set may not shrink any further.
+func (s *Snapshot) atSignerFloor() bool {
+	return uint(len(s.Signers)) <= s.config.MinSigners
+}
+
+// atSignerCeiling returns whether the signer set may not grow any further.
+func (s *Snapshot) atSignerCeiling() bool {
+	return s.config.MaxSigners > 0 && uint(len(s.Signers)) >= s.config.MaxSigners
+}
+
+// resizable returns whether the signer set may grow by one signer if authorize
+// is set, or shrink by one otherwise, without leaving its size bounds.
+func (s *Snapshot) resizable(authorize bool) bool {
+	if authorize {
+		return !s.atSignerCeiling()
+	}
+	return !s.atSignerFloor()
+}
+
+// verifySignerCount checks that a header doesn't vote on deauthorizing a signer
+// while the signer set is at its minimum size, nor on authorizing a new one while
+// it is at its maximum size, either openly or by revealing a committed vote.
+func (s *Snapshot) verifySignerCount(header *types.Header) error {
+	var (
+		nonces    = s.voteNonces()
+		vote      = header.Nonce == nonces.auth || header.Nonce == nonces.drop
+		authorize = header.Nonce == nonces.auth
+	)
+	if isRevealVote(header) {
+		vote = true
+		authorize, _ = decodeReveal(header)
+	}
+	if isResignation(header) && s.atSignerFloor() {
+		return errSignerFloor
+	}
+	if !vote {
+		return nil
+	}
+	if _, signer := s.Signers[header.Coinbase]; signer == authorize || s.resizable(authorize) {
+		return nil
+	}
+	if authorize {
+		return errSignerCeiling
+	}
+	return errSignerFloor
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that deauthorization votes may shrink the signer set down to its floor,
+// but are rejected once it's reached.
+func TestSignerFloor(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.CliqueConfig{Epoch: 30000, MinSigners: 2}
+	snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, []common.Address{
+		accounts.address("A"), accounts.address("B"), accounts.address("C"),
+	})
+	vote := func(number int64, signer, drop string) *types.Header {
+		header := &types.Header{
+			Number:   big.NewInt(number),
+			Coinbase: accounts.address(drop),
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], nonceDropVote)
+		accounts.sign(header, signer)
+		return header
+	}
+	// Dropping C is fine, leaving exactly the minimum number of signers
+	snap, err := snap.apply([]*types.Header{vote(1, "A", "C"), vote(2, "B", "C")})
+	if err != nil {
+		t.Fatalf("failed to drop signer above the floor: %v", err)
+	}
+	if len(snap.Signers) != 2 {
+		t.Fatalf("signer count mismatch: have %d, want %d", len(snap.Signers), 2)
+	}
+	// Any further deauthorization must be refused, both to cast and to verify
+	if snap.validVote(accounts.address("B"), false) {
+		t.Errorf("deauthorization at the floor deemed valid")
+	}
+	if _, err := snap.apply([]*types.Header{vote(3, "A", "B")}); err != errSignerFloor {
+		t.Errorf("deauthorization at the floor error mismatch: have %v, want %v", err, errSignerFloor)
+	}
+	// Blocks not voting on a signer must still be accepted
+	if _, err := snap.apply([]*types.Header{vote(3, "A", "D")}); err != nil {
+		t.Errorf("failed to apply non-signer vote at the floor: %v", err)
+	}
+	// Overrides may not shrink the set below the floor either
+	if _, err := snap.verifyOverride(4, &signerOverride{Signers: []common.Address{accounts.address("A")}}); err != errSignerFloor {
+		t.Errorf("override below the floor error mismatch: have %v, want %v", err, errSignerFloor)
+	}
+}
+
+// Tests that authorization votes may grow the signer set up to its ceiling, but
+// are rejected once it's reached.
+func TestSignerCeiling(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.CliqueConfig{Epoch: 30000, MaxSigners: 3}
+	snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, []common.Address{
+		accounts.address("A"), accounts.address("B"),
+	})
+	vote := func(number int64, signer, add string) *types.Header {
+		header := &types.Header{
+			Number:   big.NewInt(number),
+			Coinbase: accounts.address(add),
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], nonceAuthVote)
+		accounts.sign(header, signer)
+		return header
+	}
+	// Adding C is fine, reaching exactly the maximum number of signers
+	snap, err := snap.apply([]*types.Header{vote(1, "A", "C"), vote(2, "B", "C")})
+	if err != nil {
+		t.Fatalf("failed to add signer below the ceiling: %v", err)
+	}
+	if len(snap.Signers) != 3 {
+		t.Fatalf("signer count mismatch: have %d, want %d", len(snap.Signers), 3)
+	}
+	// Any further authorization must be refused, both to cast and to verify
+	if snap.validVote(accounts.address("D"), true) {
+		t.Errorf("authorization at the ceiling deemed valid")
+	}
+	if _, err := snap.apply([]*types.Header{vote(3, "C", "D")}); err != errSignerCeiling {
+		t.Errorf("authorization at the ceiling error mismatch: have %v, want %v", err, errSignerCeiling)
+	}
+	// Overrides may not grow the set above the ceiling either
+	override := &signerOverride{Signers: []common.Address{{0x01}, {0x02}, {0x03}, {0x04}}}
+	if _, err := snap.verifyOverride(4, override); err != errSignerCeiling {
+		t.Errorf("override above the ceiling error mismatch: have %v, want %v", err, errSignerCeiling)
+	}
+}
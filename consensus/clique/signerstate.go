@@ -0,0 +1,105 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// SignerStateAddress is the system account the signer set is committed to at every
+// checkpoint. Its storage follows the Solidity layout of a contract declaring:
+//
+//	address[] signers;                   // slot 0, in ascending order
+//	uint256 signerLimit;                 // slot 1, percentage unless absolute
+//	uint256 threshold;                   // slot 2, votes needed to pass a proposal
+//	mapping(address => bool) isSigner;   // slot 3
+//
+// The signer set is exposed to contracts by the consensus state precompile.
+var SignerStateAddress = params.CliqueSignerStateAddress
+
+var (
+	signersSlot   = common.BigToHash(big.NewInt(0)) // Length of the signer array
+	limitSlot     = common.BigToHash(big.NewInt(1)) // Signer limit in force
+	thresholdSlot = common.BigToHash(big.NewInt(2)) // Votes needed to pass a proposal
+	isSignerSlot  = common.BigToHash(big.NewInt(3)) // Base slot of the signer mapping
+
+	signersBase = new(big.Int).SetBytes(crypto.Keccak256(signersSlot[:])) // First slot of the signer array items
+)
+
+// signerItemSlot returns the storage slot of the i-th item of the signer array.
+func signerItemSlot(i int) common.Hash {
+	return common.BigToHash(new(big.Int).Add(signersBase, big.NewInt(int64(i))))
+}
+
+// isSignerItemSlot returns the storage slot of a signer in the signer mapping.
+func isSignerItemSlot(signer common.Address) common.Hash {
+	return crypto.Keccak256Hash(common.LeftPadBytes(signer[:], 32), isSignerSlot[:])
+}
+
+// commitSignerState writes the signer set and signer limit in force after a
+// checkpoint block into the storage of the signer state system account, so that
+// contracts can read them.
+func (c *Clique) commitSignerState(chain consensus.ChainHeaderReader, header *types.Header, statedb *state.StateDB) error {
+	number := header.Number.Uint64()
+	if !c.config.IsSignerState(header.Number) || number%c.config.Epoch != 0 {
+		return nil
+	}
+	snap, err := c.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	if snap, err = snap.overridden(header); err != nil {
+		return err
+	}
+	epoch := snap.copy()
+	epoch.SignerLimit, _ = snap.epochLimit(number)
+
+	writeSignerState(statedb, epoch.signers(), epoch.SignerLimit, epoch.signerLimit())
+	return nil
+}
+
+// writeSignerState replaces the signer state stored in the system account.
+func writeSignerState(statedb *state.StateDB, signers []common.Address, limit uint, threshold uint) {
+	// Keep the system account from being deleted as empty
+	if statedb.GetNonce(SignerStateAddress) == 0 {
+		statedb.SetNonce(SignerStateAddress, 1)
+	}
+	// Clear the previous signer set
+	stale := statedb.GetState(SignerStateAddress, signersSlot).Big().Uint64()
+	for i := 0; i < int(stale); i++ {
+		slot := signerItemSlot(i)
+		signer := common.BytesToAddress(statedb.GetState(SignerStateAddress, slot).Bytes())
+
+		statedb.SetState(SignerStateAddress, slot, common.Hash{})
+		statedb.SetState(SignerStateAddress, isSignerItemSlot(signer), common.Hash{})
+	}
+	// Write the new signer set and limit
+	statedb.SetState(SignerStateAddress, signersSlot, common.BigToHash(big.NewInt(int64(len(signers)))))
+	for i, signer := range signers {
+		statedb.SetState(SignerStateAddress, signerItemSlot(i), common.BytesToHash(signer[:]))
+		statedb.SetState(SignerStateAddress, isSignerItemSlot(signer), common.BigToHash(common.Big1))
+	}
+	statedb.SetState(SignerStateAddress, limitSlot, common.BigToHash(new(big.Int).SetUint64(uint64(limit))))
+	statedb.SetState(SignerStateAddress, thresholdSlot, common.BigToHash(new(big.Int).SetUint64(uint64(threshold))))
+}
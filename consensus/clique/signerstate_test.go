@@ -0,0 +1,172 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that checkpoints commit the signer set into the system account storage
+// and that a shrinking signer set leaves no stale entries behind.
+func TestSignerStateCommit(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 2, SignerLimit: 50, SignerStateBlock: common.Big0}
+		signers  = []string{"A", "B", "C"}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	header := &types.Header{
+		ParentHash: genesis.Hash(),
+		Number:     common.Big1,
+		Difficulty: diffNoTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	accounts.sign(header, "A")
+	chain.headers = append(chain.headers, header)
+
+	checkpoint := &types.Header{
+		ParentHash: header.Hash(),
+		Number:     common.Big2,
+		Difficulty: diffNoTurn,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(checkpoint, signers)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.Finalize(chain, checkpoint, statedb, nil, nil)
+
+	snap, err := engine.snapshot(chain, 1, header.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	verifySignerState(t, statedb, snap.signers(), 50, snap.signerLimit())
+
+	// Shrink the signer set and ensure the removed signer is cleared
+	writeSignerState(statedb, snap.signers()[:1], 60, 1)
+	verifySignerState(t, statedb, snap.signers()[:1], 60, 1)
+	for _, signer := range snap.signers()[1:] {
+		if statedb.GetState(SignerStateAddress, isSignerItemSlot(signer)) != (common.Hash{}) {
+			t.Errorf("removed signer %x still flagged", signer)
+		}
+	}
+	if statedb.GetState(SignerStateAddress, signerItemSlot(1)) != (common.Hash{}) {
+		t.Errorf("stale signer array item left behind")
+	}
+	// Ensure the system account survives empty account deletion
+	statedb.IntermediateRoot(true)
+	if !statedb.Exist(SignerStateAddress) {
+		t.Errorf("signer state account deleted")
+	}
+}
+
+// verifySignerState checks the signer state stored in the system account.
+func verifySignerState(t *testing.T, statedb *state.StateDB, signers []common.Address, limit uint, threshold uint) {
+	t.Helper()
+
+	if have := statedb.GetState(SignerStateAddress, signersSlot).Big(); have.Cmp(big.NewInt(int64(len(signers)))) != 0 {
+		t.Errorf("signer count mismatch: have %v, want %d", have, len(signers))
+	}
+	for i, signer := range signers {
+		if have := common.BytesToAddress(statedb.GetState(SignerStateAddress, signerItemSlot(i)).Bytes()); have != signer {
+			t.Errorf("signer %d mismatch: have %x, want %x", i, have, signer)
+		}
+		if have := statedb.GetState(SignerStateAddress, isSignerItemSlot(signer)).Big(); have.Cmp(common.Big1) != 0 {
+			t.Errorf("signer %x not flagged", signer)
+		}
+	}
+	if have := statedb.GetState(SignerStateAddress, limitSlot).Big(); have.Uint64() != uint64(limit) {
+		t.Errorf("signer limit mismatch: have %v, want %d", have, limit)
+	}
+	if have := statedb.GetState(SignerStateAddress, thresholdSlot).Big(); have.Uint64() != uint64(threshold) {
+		t.Errorf("threshold mismatch: have %v, want %d", have, threshold)
+	}
+}
+
+// Tests that the consensus state precompile exposes the committed signer state to
+// contracts once the signer state fork is active.
+func TestSignerStatePrecompile(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		signers  = []common.Address{accounts.address("A"), accounts.address("B")}
+		config   = &params.ChainConfig{ChainID: common.Big1, Clique: &params.CliqueConfig{Epoch: 30000, SignerStateBlock: common.Big2}}
+	)
+	sort.Sort(signersAscending(signers))
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	writeSignerState(statedb, signers, 50, 2)
+
+	call := func(number int64, method string, args ...[]byte) ([]byte, error) {
+		evm := vm.NewEVM(vm.BlockContext{BlockNumber: big.NewInt(number), CanTransfer: core.CanTransfer, Transfer: core.Transfer}, vm.TxContext{}, statedb, config, vm.Config{})
+		input := crypto.Keccak256([]byte(method))[:4]
+		for _, arg := range args {
+			input = append(input, arg...)
+		}
+		ret, _, err := evm.StaticCall(vm.AccountRef(common.Address{}), params.CliqueStatePrecompileAddress, input, 100000)
+		return ret, err
+	}
+	// Before the fork, the address is a plain empty account
+	if ret, err := call(1, "signerLimit()"); err != nil || len(ret) != 0 {
+		t.Fatalf("precompile active before fork: %x (%v)", ret, err)
+	}
+	ret, err := call(2, "signers()")
+	if err != nil {
+		t.Fatalf("failed to retrieve signers: %v", err)
+	}
+	want := append(common.BigToHash(big.NewInt(32)).Bytes(), common.BigToHash(big.NewInt(2)).Bytes()...)
+	for _, signer := range signers {
+		want = append(want, common.LeftPadBytes(signer[:], 32)...)
+	}
+	if !bytes.Equal(ret, want) {
+		t.Errorf("signers mismatch: have %x, want %x", ret, want)
+	}
+	if ret, err := call(2, "signerLimit()"); err != nil || new(big.Int).SetBytes(ret).Uint64() != 50 {
+		t.Errorf("signer limit mismatch: have %x (%v), want 50", ret, err)
+	}
+	if ret, err := call(2, "threshold()"); err != nil || new(big.Int).SetBytes(ret).Uint64() != 2 {
+		t.Errorf("threshold mismatch: have %x (%v), want 2", ret, err)
+	}
+	for _, account := range []string{"A", "B", "C"} {
+		address := accounts.address(account)
+		ret, err := call(2, "isSigner(address)", common.LeftPadBytes(address[:], 32))
+		if want := account != "C"; err != nil || (new(big.Int).SetBytes(ret).Sign() != 0) != want {
+			t.Errorf("signer %s: flag mismatch: have %x (%v), want %v", account, ret, err, want)
+		}
+	}
+	if _, err := call(2, "unknown()"); err == nil {
+		t.Errorf("unknown method succeeded")
+	}
+}
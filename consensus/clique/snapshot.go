@@ -19,16 +19,17 @@ package clique
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"math/big"
 	"sort"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
-	lru "github.com/hashicorp/golang-lru"
 )
 
 // Vote represents a single vote that an authorized signer made to modify the
@@ -66,9 +67,13 @@ type WaitTally struct {
 }
 
 // Snapshot is the state of the authorization voting at a given point in time.
+//
+// Once handed out by the engine, a snapshot is immutable and may be read from
+// multiple goroutines concurrently. Code outside of the engine should use the
+// accessor methods, which return private copies of the snapshot contents.
 type Snapshot struct {
 	config   *params.CliqueConfig // Consensus engine parameters to fine tune behavior
-	sigcache *lru.ARCCache        // Cache of recent block signatures to speed up ecrecover
+	sigcache *SigCache            // Cache of recent block signatures to speed up ecrecover
 
 	Number  uint64                      `json:"number"`  // Block number where the snapshot was created
 	Hash    common.Hash                 `json:"hash"`    // Block hash where the snapshot was created
@@ -81,8 +86,65 @@ type Snapshot struct {
 	SignerLimitVotes []*LimitVote       `json:"signerLimitVotes"` // List of votes cast in chronological order
 	SignerLimitTally map[uint]LimitTally `json:"signerLimitTally"`
 	SignerLimitWait  map[uint64]WaitTally `json:"waitTally"`
+
+	SignerLimitAffirmed uint64 `json:"limitAffirmed,omitempty"` // Block number where the signer limit was last set or reaffirmed
+	EpochChanges        uint   `json:"epochChanges,omitempty"`  // Number of signer set changes within the current epoch
+
+	Permitted   map[common.Address]struct{} `json:"permitted,omitempty"`   // Set of non-signer accounts permitted to send transactions
+	PermitVotes []*Vote                     `json:"permitVotes,omitempty"` // List of sender permission votes in chronological order
+	PermitTally map[common.Address]Tally    `json:"permitTally,omitempty"` // Current sender permission vote tally
+
+	JustifyTarget   uint64           `json:"justifyTarget,omitempty"`   // Number of the ancestor currently being justified for finality
+	JustifyHash     common.Hash      `json:"justifyHash,omitempty"`     // Hash of the ancestor currently being justified for finality
+	Justifiers      []common.Address `json:"justifiers,omitempty"`      // Signers that justified the current target (replaced, never modified)
+	FinalizedNumber uint64           `json:"finalizedNumber,omitempty"` // Number of the latest finalized block
+	FinalizedHash   common.Hash      `json:"finalizedHash,omitempty"`   // Hash of the latest finalized block
+
+	Openings []*Opening    `json:"openings,omitempty"` // Membership proposals awaiting confirmation (replaced, never modified)
+	Commits  []*Commitment `json:"commits,omitempty"`  // Membership votes committed to but not yet revealed (replaced, never modified)
+
+	Periods     []*SignerPeriod `json:"periods,omitempty"`     // Minimum block periods granted to specific signers (replaced, never modified)
+	PeriodVotes []*PeriodVote   `json:"periodVotes,omitempty"` // Signer period votes in chronological order (replaced, never modified)
+
+	Probations []*Probation `json:"probations,omitempty"` // Newly authorized signers not allowed to vote yet (replaced, never modified)
+
+	Replacements []*ReplaceVote `json:"replacements,omitempty"` // Signer replacement votes in chronological order (replaced, never modified)
+
+	AttestTarget    uint64           `json:"attestTarget,omitempty"`    // Number of the checkpoint currently being attested
+	AttestHash      common.Hash      `json:"attestHash,omitempty"`      // Hash of the checkpoint currently being attested
+	AttestSigners   []common.Address `json:"attestSigners,omitempty"`   // Signers of the checkpoint being attested, in ascending order (replaced, never modified)
+	Attestations    []*Attestation   `json:"attestations,omitempty"`    // Signers that attested the checkpoint with their keys (replaced, never modified)
+	AttestSignature hexutil.Bytes    `json:"attestSignature,omitempty"` // Aggregated signature of the attestations (replaced, never modified)
+
+	sorted      []common.Address // Authorized signers in ascending order, rebuilt only when the set changes
+	resolutions []*Resolution    // Proposals resolved by the last apply, pending persistence in the audit store
+	seals       []sealRecord     // Blocks sealed in the last apply, pending participation tracking
+	observed    []*VoteEvent     // Votes counted by the last apply, pending notification
+	owned       uint16           // Bitmask of the copy-on-write fields this snapshot holds a private instance of
+
+	base   *Snapshot // Last persisted snapshot this one descends from (itself if persisted)
+	deltas int       // Number of deltas between this persisted snapshot and the last full one
+	frozen bool      // Whether the snapshot was published and may not be modified any more
 }
 
+// Snapshot fields shared copy-on-write between a snapshot and its copies. A copy
+// starts out referencing all the containers of its origin and only clones the
+// ones it actually modifies, so copying stays cheap even for large signer sets.
+const (
+	cowSigners     uint16 = 1 << iota // Signers map
+	cowRecents                        // Recents map
+	cowVotes                          // Votes slice
+	cowTally                          // Tally map
+	cowLimitVotes                     // SignerLimitVotes slice
+	cowLimitTally                     // SignerLimitTally map
+	cowLimitWait                      // SignerLimitWait map
+	cowPermitted                      // Permitted map
+	cowPermitVotes                    // PermitVotes slice
+	cowPermitTally                    // PermitTally map
+
+	cowAll = cowSigners | cowRecents | cowVotes | cowTally | cowLimitVotes | cowLimitTally | cowLimitWait | cowPermitted | cowPermitVotes | cowPermitTally
+)
+
 // signersAscending implements the sort interface to allow sorting a list of addresses
 type signersAscending []common.Address
 
@@ -93,7 +155,11 @@ func (s signersAscending) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 // newSnapshot creates a new snapshot with the specified startup parameters. This
 // method does not initialize the set of recent signers, so only ever use if for
 // the genesis block.
-func newSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, number uint64, hash common.Hash, signers []common.Address) *Snapshot {
+func newSnapshot(config *params.CliqueConfig, sigcache *SigCache, number uint64, hash common.Hash, signers []common.Address) *Snapshot {
+	limit := config.SignerLimit
+	if limit == 0 {
+		limit = defaultSignerLimit
+	}
 	snap := &Snapshot{
 		config:           config,
 		sigcache:         sigcache,
@@ -102,90 +168,267 @@ func newSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, number uin
 		Signers:          make(map[common.Address]struct{}),
 		Recents:          make(map[uint64]common.Address),
 		Tally:            make(map[common.Address]Tally),
-		SignerLimit:      50,
+		SignerLimit:      limit,
 		SignerLimitTally: make(map[uint]LimitTally),
 		SignerLimitWait:  make(map[uint64]WaitTally),
+		Permitted:        make(map[common.Address]struct{}),
+		PermitTally:      make(map[common.Address]Tally),
+		owned:            cowAll,
 	}
 	for _, signer := range signers {
 		snap.Signers[signer] = struct{}{}
 	}
+	snap.sortSigners()
 	return snap
 }
 
-// loadSnapshot loads an existing snapshot from the database.
-func loadSnapshot(config *params.CliqueConfig, sigcache *lru.ARCCache, db ethdb.Database, hash common.Hash) (*Snapshot, error) {
-	blob, err := db.Get(append([]byte("clique-"), hash[:]...))
+// loadSnapshot loads an existing snapshot from the database, either stored in
+// full or as a delta on top of an earlier persisted snapshot.
+func loadSnapshot(config *params.CliqueConfig, sigcache *SigCache, db ethdb.Database, hash common.Hash) (*Snapshot, error) {
+	blob, err := db.Get(append(append([]byte{}, snapshotPrefix...), hash[:]...))
 	if err != nil {
-		return nil, err
+		delta, derr := loadDelta(db, hash)
+		if derr == errCorruptSnapshot {
+			return nil, derr
+		}
+		if derr != nil {
+			return nil, err
+		}
+		base, err := loadSnapshot(config, sigcache, db, delta.Parent)
+		if err != nil {
+			return nil, err
+		}
+		return base.patch(delta), nil
 	}
 	snap := new(Snapshot)
 	if err := json.Unmarshal(blob, snap); err != nil {
-		return nil, err
+		log.Warn("Failed to decode voting snapshot", "hash", hash, "err", err)
+		return nil, errCorruptSnapshot
 	}
 	snap.config = config
 	snap.sigcache = sigcache
+	snap.ensureContainers()
+	snap.owned = cowAll
+	snap.sortSigners()
+	snap.base = snap
 
 	return snap, nil
 }
 
-// store inserts the snapshot into the database.
+// ensureContainers allocates the maps of a decoded snapshot that its encoding
+// left out or encoded as null.
+func (s *Snapshot) ensureContainers() {
+	if s.Signers == nil {
+		s.Signers = make(map[common.Address]struct{})
+	}
+	if s.Recents == nil {
+		s.Recents = make(map[uint64]common.Address)
+	}
+	if s.Tally == nil {
+		s.Tally = make(map[common.Address]Tally)
+	}
+	if s.SignerLimitTally == nil {
+		s.SignerLimitTally = make(map[uint]LimitTally)
+	}
+	if s.SignerLimitWait == nil {
+		s.SignerLimitWait = make(map[uint64]WaitTally)
+	}
+	if s.Permitted == nil {
+		s.Permitted = make(map[common.Address]struct{})
+	}
+	if s.PermitTally == nil {
+		s.PermitTally = make(map[common.Address]Tally)
+	}
+}
+
+// store inserts the snapshot into the database in its canonical encoding.
 func (s *Snapshot) store(db ethdb.Database) error {
-	blob, err := json.Marshal(s)
+	blob, err := s.MarshalCanonical()
 	if err != nil {
 		return err
 	}
-	return db.Put(append([]byte("clique-"), s.Hash[:]...), blob)
+	if err := db.Put(append(append([]byte{}, snapshotPrefix...), s.Hash[:]...), blob); err != nil {
+		return err
+	}
+	return indexSnapshot(db, s.Number, s.Hash)
 }
 
-// copy creates a deep copy of the snapshot, though not the individual votes.
+// copy creates a copy-on-write copy of the snapshot, sharing all the vote and
+// signer containers with the original until they are first modified. As such,
+// a snapshot must not be modified any more after it has been copied.
 func (s *Snapshot) copy() *Snapshot {
-	cpy := &Snapshot{
+	return &Snapshot{
 		config:           s.config,
 		sigcache:         s.sigcache,
 		Number:           s.Number,
 		Hash:             s.Hash,
-		Signers:          make(map[common.Address]struct{}),
-		Recents:          make(map[uint64]common.Address),
-		Votes:            make([]*Vote, len(s.Votes)),
-		SignerLimitVotes: make([]*LimitVote, len(s.SignerLimitVotes)),
-		Tally:            make(map[common.Address]Tally),
-		SignerLimitTally: make(map[uint]LimitTally),
-		SignerLimitWait:  make(map[uint64]WaitTally),
+		Signers:          s.Signers,
+		Recents:          s.Recents,
+		Votes:            s.Votes,
+		Tally:            s.Tally,
+		SignerLimit:      s.SignerLimit,
+		SignerLimitVotes: s.SignerLimitVotes,
+		SignerLimitTally: s.SignerLimitTally,
+		SignerLimitWait:  s.SignerLimitWait,
+		Permitted:        s.Permitted,
+		PermitVotes:      s.PermitVotes,
+		PermitTally:      s.PermitTally,
+		JustifyTarget:    s.JustifyTarget,
+		JustifyHash:      s.JustifyHash,
+		Justifiers:       s.Justifiers,
+		FinalizedNumber:  s.FinalizedNumber,
+		FinalizedHash:    s.FinalizedHash,
+		Openings:         s.Openings,
+		Commits:          s.Commits,
+		Periods:          s.Periods,
+		PeriodVotes:      s.PeriodVotes,
+		Probations:       s.Probations,
+		Replacements:     s.Replacements,
+		AttestTarget:     s.AttestTarget,
+		AttestHash:       s.AttestHash,
+		AttestSigners:    s.AttestSigners,
+		Attestations:     s.Attestations,
+		AttestSignature:  s.AttestSignature,
+		sorted:           s.sorted,
+		base:             s.base,
+
+		SignerLimitAffirmed: s.SignerLimitAffirmed,
+		EpochChanges:        s.EpochChanges,
 	}
-	for signer := range s.Signers {
-		cpy.Signers[signer] = struct{}{}
-	}
-	for block, signer := range s.Recents {
-		cpy.Recents[block] = signer
+}
+
+// writable ensures that the snapshot holds a private instance of the requested
+// copy-on-write field, cloning it away from any shared origin if need be. It
+// must be called before any modification of the field.
+func (s *Snapshot) writable(field uint16) {
+	if s.frozen {
+		panic("modifying published clique snapshot")
 	}
-	for address, tally := range s.Tally {
-		cpy.Tally[address] = tally
+	if s.owned&field != 0 {
+		return
 	}
-	copy(cpy.Votes, s.Votes)
+	switch field {
+	case cowSigners:
+		signers := make(map[common.Address]struct{}, len(s.Signers))
+		for signer := range s.Signers {
+			signers[signer] = struct{}{}
+		}
+		s.Signers = signers
 
-	cpy.SignerLimit = s.SignerLimit
+	case cowRecents:
+		recents := make(map[uint64]common.Address, len(s.Recents))
+		for block, signer := range s.Recents {
+			recents[block] = signer
+		}
+		s.Recents = recents
 
-	for address, tally := range s.SignerLimitTally {
-		cpy.SignerLimitTally[address] = tally
-	}
+	case cowVotes:
+		s.Votes = append(make([]*Vote, 0, len(s.Votes)), s.Votes...)
+
+	case cowTally:
+		tally := make(map[common.Address]Tally, len(s.Tally))
+		for address, t := range s.Tally {
+			tally[address] = t
+		}
+		s.Tally = tally
+
+	case cowLimitVotes:
+		s.SignerLimitVotes = append(make([]*LimitVote, 0, len(s.SignerLimitVotes)), s.SignerLimitVotes...)
 
-	copy(cpy.SignerLimitVotes, s.SignerLimitVotes)
+	case cowLimitTally:
+		tally := make(map[uint]LimitTally, len(s.SignerLimitTally))
+		for limit, t := range s.SignerLimitTally {
+			tally[limit] = t
+		}
+		s.SignerLimitTally = tally
+
+	case cowLimitWait:
+		wait := make(map[uint64]WaitTally, len(s.SignerLimitWait))
+		for limit, t := range s.SignerLimitWait {
+			wait[limit] = t
+		}
+		s.SignerLimitWait = wait
+
+	case cowPermitted:
+		permitted := make(map[common.Address]struct{}, len(s.Permitted))
+		for address := range s.Permitted {
+			permitted[address] = struct{}{}
+		}
+		s.Permitted = permitted
+
+	case cowPermitVotes:
+		s.PermitVotes = append(make([]*Vote, 0, len(s.PermitVotes)), s.PermitVotes...)
+
+	case cowPermitTally:
+		tally := make(map[common.Address]Tally, len(s.PermitTally))
+		for address, t := range s.PermitTally {
+			tally[address] = t
+		}
+		s.PermitTally = tally
 
-	for number, tally := range s.SignerLimitWait {
-		cpy.SignerLimitWait[number] = tally
+	default:
+		panic(fmt.Sprintf("unknown snapshot field %d", field))
 	}
-	return cpy
+	s.owned |= field
 }
 
 // validVote returns whether it makes sense to cast the specified vote in the
 // given snapshot context (e.g. don't try to add an already authorized signer).
 func (s *Snapshot) validVote(address common.Address, authorize bool) bool {
 	_, signer := s.Signers[address]
-	return (signer && !authorize) || (!signer && authorize)
+	return ((signer && !authorize) || (!signer && authorize)) && s.resizable(authorize)
 }
 
-func (s *Snapshot) validSignerLimitVote(signerLimit uint, authorize bool) bool {
-	return authorize && s.SignerLimit != signerLimit
+// signerLimitBounds returns the range of signer limits that can be voted on,
+// percentages normally or vote counts if the signer limit is absolute.
+func (s *Snapshot) signerLimitBounds() (uint, uint) {
+	if s.config.AbsoluteSignerLimit {
+		return 1, uint(len(s.Signers))
+	}
+	return 1, 100
+}
+
+// decodeSignerLimit extracts the signer limit voted on from a header coinbase,
+// also reporting whether it's within the permitted bounds.
+func (s *Snapshot) decodeSignerLimit(coinbase common.Address) (uint, bool) {
+	limit := new(big.Int).SetBytes(coinbase.Bytes())
+	if !limit.IsUint64() {
+		return 0, false
+	}
+	min, max := s.signerLimitBounds()
+	if limit.Uint64() < uint64(min) || limit.Uint64() > uint64(max) {
+		return 0, false
+	}
+	return uint(limit.Uint64()), true
+}
+
+// validSignerLimitVote returns whether it makes sense to cast the specified
+// signer limit vote in the given block. Limits out of the permitted bounds are
+// never valid, and voting on the limit already in force is only meaningful to
+// reaffirm it ahead of an epoch reset.
+func (s *Snapshot) validSignerLimitVote(signerLimit uint, authorize bool, number uint64) bool {
+	if !authorize {
+		return false
+	}
+	if min, max := s.signerLimitBounds(); signerLimit < min || signerLimit > max {
+		return false
+	}
+	if s.SignerLimit != signerLimit {
+		return true
+	}
+	if !s.config.SignerLimitReset || signerLimit == s.initialSignerLimit() {
+		return false
+	}
+	return s.SignerLimitAffirmed < number-number%s.config.Epoch
+}
+
+// initialSignerLimit returns the chain configured signer limit, which is the
+// one in force at genesis and the one reverted to on epoch resets.
+func (s *Snapshot) initialSignerLimit() uint {
+	if s.config.SignerLimit == 0 {
+		return defaultSignerLimit
+	}
+	return s.config.SignerLimit
 }
 
 // cast adds a new vote into the tally.
@@ -196,6 +439,7 @@ func (s *Snapshot) cast(address common.Address, authorize bool) bool {
 	}
 
 	// Cast the vote into an existing or new tally
+	s.writable(cowTally)
 	if old, ok := s.Tally[address]; ok {
 		old.Votes++
 		s.Tally[address] = old
@@ -205,10 +449,11 @@ func (s *Snapshot) cast(address common.Address, authorize bool) bool {
 	return true
 }
 
-func (s *Snapshot) castSignerLimit(address common.Address, signerLimit uint) bool {
-	if !s.validSignerLimitVote(signerLimit, true) {
+func (s *Snapshot) castSignerLimit(address common.Address, signerLimit uint, number uint64) bool {
+	if !s.validSignerLimitVote(signerLimit, true, number) {
 		return false
 	}
+	s.writable(cowLimitTally)
 
 	if old, ok := s.SignerLimitTally[signerLimit]; ok {
 		old.Votes++
@@ -232,6 +477,7 @@ func (s *Snapshot) uncast(address common.Address, authorize bool) bool {
 	}
 
 	// Otherwise revert the vote
+	s.writable(cowTally)
 	if tally.Votes > 1 {
 		tally.Votes--
 		s.Tally[address] = tally
@@ -253,6 +499,7 @@ func (s *Snapshot) uncastSignerLimit(signerLimit uint, authorize bool) bool {
 	}
 
 	// Otherwise revert the vote
+	s.writable(cowLimitTally)
 	if tally.Votes > 1 {
 		tally.Votes--
 		s.SignerLimitTally[signerLimit] = tally
@@ -262,44 +509,232 @@ func (s *Snapshot) uncastSignerLimit(signerLimit uint, authorize bool) bool {
 	return true
 }
 
+// applyLimitVote tallies a signer limit vote of an authorized signer, changing
+// the signer limit if the vote made the proposal pass. The limit voted on must
+// already be checked against the permitted bounds.
+func (s *Snapshot) applyLimitVote(number uint64, hash common.Hash, signer, address common.Address) {
+	limit := uint(new(big.Int).SetBytes(address.Bytes()).Uint64())
 
-func (s *Snapshot, ) applySignerLimitVotes(signer common.Address, snap *Snapshot, header *types.Header) {
-	number := header.Number.Uint64()
-	limit := uint(new(big.Int).SetBytes(header.Coinbase.Bytes()).Uint64())
+	// Any new limit vote lifts the wait imposed by the previous limit change
+	s.deleteLimitWait()
 
-	snap.deleteLimitWait()
-
-	if snap.castSignerLimit(signer, limit) {
-		snap.SignerLimitVotes = append(snap.SignerLimitVotes, &LimitVote{
+	if s.castSignerLimit(signer, limit, number) {
+		s.writable(cowLimitVotes)
+		s.SignerLimitVotes = append(s.SignerLimitVotes, &LimitVote{
 			Signer:    signer,
 			Block:     number,
-			Address:   header.Coinbase,
+			Address:   address,
 			Limit:     limit,
 			Authorize: true,
 		})
+		tally := s.SignerLimitTally[limit]
+		s.observed = append(s.observed, &VoteEvent{
+			Block:  number,
+			Hash:   hash,
+			Signer: signer,
+			Kind:   ProposalSignerLimit,
+			Limit:  limit,
+			Votes:  tally.Votes,
+			Passed: tally.Votes >= int(s.signerLimit()),
+		})
+	}
+	// If the vote passed, update the signer limit
+	tally, ok := s.SignerLimitTally[limit]
+	if !ok || tally.Votes < int(s.signerLimit()) {
+		return
+	}
+	res := &Resolution{
+		Kind:      ProposalSignerLimit,
+		Block:     number,
+		Hash:      hash,
+		Address:   address,
+		Limit:     limit,
+		PrevLimit: s.SignerLimit,
+	}
+	for _, vote := range s.SignerLimitVotes {
+		if vote.Limit == limit {
+			res.Votes = append(res.Votes, AuditVote{Signer: vote.Signer, Block: vote.Block})
+		}
+	}
+	s.resolutions = append(s.resolutions, res)
+
+	s.SignerLimit = limit
+	s.SignerLimitAffirmed = number
+
+	// Discard any previous votes on the just passed limit
+	s.writable(cowLimitVotes)
+	for i := 0; i < len(s.SignerLimitVotes); i++ {
+		if s.SignerLimitVotes[i].Address == address {
+			s.SignerLimitVotes = append(s.SignerLimitVotes[:i], s.SignerLimitVotes[i+1:]...)
+			i--
+		}
+	}
+	s.writable(cowLimitTally)
+	delete(s.SignerLimitTally, limit)
+
+	// Bar voting on the same limit again until every signer had a chance to seal
+	s.writable(cowLimitWait)
+	s.SignerLimitWait[uint64(limit)] = WaitTally{Block: number + uint64(len(s.Signers))}
+}
+
+// applyVote tallies a membership vote of an authorized signer on an account,
+// updating the signer set if the vote made the proposal pass.
+func (s *Snapshot) applyVote(number uint64, hash common.Hash, signer, address common.Address, authorize bool) {
+	// Discard any previous votes from the signer, unless the tallies are frozen
+	// for the rest of the epoch
+	frozen := s.changesCapped()
+
+	// Under two-phase voting, the first vote on a proposal only opens it, and it
+	// may only be confirmed in the blocks after
+	if s.twoPhase() && !frozen && s.validVote(address, authorize) {
+		open := s.opening(address)
+		if open == nil || open.Authorize != authorize {
+			s.openProposal(number, signer, address, authorize)
+			return
+		}
+		if open.Block == number {
+			return
+		}
+	}
+	for i, vote := range s.Votes {
+		if frozen {
+			break
+		}
+		if vote.Signer == signer && vote.Address == address {
+			// Uncast the vote from the cached tally
+			s.uncast(vote.Address, vote.Authorize)
+
+			// Uncast the vote from the chronological list
+			s.writable(cowVotes)
+			s.Votes = append(s.Votes[:i], s.Votes[i+1:]...)
+			break // only one vote allowed
+		}
+	}
+
+	if !frozen && s.cast(address, authorize) {
+		s.writable(cowVotes)
+		s.Votes = append(s.Votes, &Vote{
+			Signer:    signer,
+			Block:     number,
+			Address:   address,
+			Authorize: authorize,
+		})
+		vote := &VoteEvent{
+			Block:   number,
+			Hash:    hash,
+			Signer:  signer,
+			Kind:    ProposalDeauthorize,
+			Address: address,
+			Votes:   s.Tally[address].Votes,
+		}
+		if authorize {
+			vote.Kind = ProposalAuthorize
+		}
+		vote.Passed = vote.Votes >= int(s.voteThreshold(authorize))
+		s.observed = append(s.observed, vote)
 	}
 
 	// If the vote passed, update the list of signers
-	if tally := snap.SignerLimitTally[limit]; tally.Votes >= int(snap.signerLimit()) {
-		snap.SignerLimit = limit
-		
-		// Discard any previous votes around the just changed account
-		for i := 0; i < len(snap.SignerLimitVotes); i++ {
-			if snap.SignerLimitVotes[i].Address == header.Coinbase {
-				snap.SignerLimitVotes = append(snap.SignerLimitVotes[:i], snap.SignerLimitVotes[i+1:]...)
-				i--
+	if tally := s.Tally[address]; !frozen && tally.Votes >= int(s.voteThreshold(tally.Authorize)) && s.resizable(tally.Authorize) {
+		res := &Resolution{
+			Kind:    ProposalDeauthorize,
+			Block:   number,
+			Hash:    hash,
+			Address: address,
+		}
+		if tally.Authorize {
+			res.Kind = ProposalAuthorize
+		}
+		for _, vote := range s.Votes {
+			if vote.Address == address && vote.Authorize == tally.Authorize {
+				res.Votes = append(res.Votes, AuditVote{Signer: vote.Signer, Block: vote.Block})
 			}
 		}
-		delete(snap.SignerLimitTally, limit)
-		
-		blockWait := number + uint64(len(s.Signers))
-		snap.SignerLimitWait[uint64(limit)] = WaitTally{Block: blockWait}
+		s.resolutions = append(s.resolutions, res)
+		s.EpochChanges++
+
+		if tally.Authorize {
+			s.addSigner(address)
+			s.startProbation(address, number)
+		} else {
+			s.deauthorize(number, address)
+		}
+		s.discardProposal(address)
+	}
+}
+
+// deauthorize removes a signer from the signer set, along with everything it was
+// granted and every vote it cast.
+func (s *Snapshot) deauthorize(number uint64, address common.Address) {
+	s.removeSigner(address)
+
+	// Signer list shrunk, delete any leftover recent caches
+	s.shrunkRecents(number)
+
+	// Discard the period granted to the deauthorized signer and its votes
+	s.dropPeriod(address, true)
+	s.dropReplacements(address)
+	s.endProbation(address)
+
+	// Discard any previous votes the deauthorized signer cast
+	s.writable(cowVotes)
+	for i := 0; i < len(s.Votes); i++ {
+		if s.Votes[i].Signer == address {
+			// Uncast the vote from the cached tally
+			s.uncast(s.Votes[i].Address, s.Votes[i].Authorize)
+
+			// Uncast the vote from the chronological list
+			s.Votes = append(s.Votes[:i], s.Votes[i+1:]...)
+
+			i--
+		}
+	}
+}
+
+// discardProposal drops every vote on the membership of an account whose
+// authorization just changed, closing its proposal.
+func (s *Snapshot) discardProposal(address common.Address) {
+	s.writable(cowVotes)
+	for i := 0; i < len(s.Votes); i++ {
+		if s.Votes[i].Address == address {
+
+			s.Votes = append(s.Votes[:i], s.Votes[i+1:]...)
+			i--
+		}
+	}
+
+	s.writable(cowTally)
+	delete(s.Tally, address)
+
+	s.closeProposal(address)
+}
+
+// uncastLimitVote discards any previous vote of a signer on the signer limit
+// encoded in the given address.
+func (s *Snapshot) uncastLimitVote(signer, address common.Address) {
+	limit := uint(new(big.Int).SetBytes(address.Bytes()).Uint64())
+
+	for i, vote := range s.SignerLimitVotes {
+		if vote.Signer == signer && vote.Address == address && vote.Limit == limit {
+			s.uncastSignerLimit(limit, true)
+
+			// Uncast the vote from the chronological list
+			s.writable(cowLimitVotes)
+			s.SignerLimitVotes = append(s.SignerLimitVotes[:i], s.SignerLimitVotes[i+1:]...)
+			break // only one vote allowed
+		}
 	}
 }
 
 // apply creates a new authorization snapshot by applying the given headers to
 // the original one.
 func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
+	return s.applyWith(headers, nil)
+}
+
+// applyWith is apply, tallying the signer recoveries into the given statistics
+// if any.
+func (s *Snapshot) applyWith(headers []*types.Header, stats *applyStats) (*Snapshot, error) {
 
 	
 	// Allow passing in no headers for cleaner code
@@ -333,13 +768,53 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 
 			snap.SignerLimitVotes = nil
 			snap.SignerLimitTally = make(map[uint]LimitTally)
+
+			snap.PermitVotes = nil
+			snap.PermitTally = make(map[common.Address]Tally)
+
+			snap.owned |= cowVotes | cowTally | cowLimitVotes | cowLimitTally | cowPermitVotes | cowPermitTally
+			snap.EpochChanges = 0
+			snap.Openings = nil
+			snap.Commits = nil
+			snap.PeriodVotes = nil
+			snap.Replacements = nil
+
+			// Revert the signer limit to the initial one unless reaffirmed recently
+			if limit, affirmed := snap.epochLimit(number); limit != snap.SignerLimit {
+				snap.resolutions = append(snap.resolutions, &Resolution{
+					Kind:      ProposalSignerLimit,
+					Block:     number,
+					Hash:      header.Hash(),
+					Limit:     limit,
+					PrevLimit: snap.SignerLimit,
+				})
+				snap.SignerLimit, snap.SignerLimitAffirmed = limit, affirmed
+			}
+			// Replace the signer set if a supermajority of the signers overrode it
+			if isOverride(header) {
+				if err := snap.applyOverride(header); err != nil {
+					return nil, err
+				}
+			}
+			// Start collecting the attestations of the new checkpoint
+			if s.config.IsAttestation(header.Number) {
+				snap.resetAttestations(header)
+			}
 		}
 
+		// Drop any votes not reaffirmed within the decay period, any proposals not
+		// confirmed within the confirmation window and any commitments not revealed
+		// within the reveal window
+		snap.expireVotes(number)
+		snap.expireOpenings(number)
+		snap.expireCommits(number)
+		snap.expireProbations(number)
+
 		// Delete the oldest signer from the recent list to allow it signing again
 		snap.shrunkRecents(number)
 
 		// Resolve the authorization key and check against signers
-		signer, err := ecrecover(header, s.sigcache)
+		signer, err := stats.recover(header, s.sigcache)
 		if err != nil {
 			return nil, err
 		}
@@ -347,94 +822,103 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 			return nil, errUnauthorizedSigner
 		}
 
-		for _, recent := range snap.Recents {
-			if recent == signer {
-				return nil, errRecentlySigned
+		// Enforce the spam protection, unless the network is still bootstrapping
+		if !snap.bootstrapping() {
+			for _, recent := range snap.Recents {
+				if recent == signer {
+					return nil, errRecentlySigned
+				}
 			}
+			snap.writable(cowRecents)
+			snap.Recents[number] = signer
 		}
-		snap.Recents[number] = signer
-
-		limit := uint(new(big.Int).SetBytes(header.Coinbase.Bytes()).Uint64())
+		snap.seals = append(snap.seals, snap.seal(header, signer))
 
-		//discard previous votes for limit
-		for i, vote := range snap.SignerLimitVotes{
-			if vote.Signer == signer && vote.Address == header.Coinbase && vote.Limit == limit {
-				snap.uncastSignerLimit(limit, true)
+		// Discard any previous limit vote from the signer on the same limit
+		snap.uncastLimitVote(signer, header.Coinbase)
 
-				// Uncast the vote from the chronological list
-				snap.SignerLimitVotes = append(snap.SignerLimitVotes[:i], snap.SignerLimitVotes[i+1:]...)
-				break // only one vote allowed
-			}
-		
+		// Reject membership votes that would take the set out of its size bounds,
+		// and any votes of signers still on probation
+		if err := snap.verifySignerCount(header); err != nil {
+			return nil, err
 		}
-
-		// Header authorized, discard any previous votes from the signer
-		for i, vote := range snap.Votes {
-			if vote.Signer == signer && vote.Address == header.Coinbase {
-				// Uncast the vote from the cached tally
-				snap.uncast(vote.Address, vote.Authorize)
-
-				// Uncast the vote from the chronological list
-				snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
-				break // only one vote allowed
-			}
+		if err := snap.verifyProbation(header, signer); err != nil {
+			return nil, err
 		}
-
-		// Tally up the new vote from the signer
-		var authorize bool
+		if err := snap.verifyPayloadLimit(header); err != nil {
+			return nil, err
+		}
+		// Tally up the new vote from the signer. Under commit-reveal voting, only
+		// revealed deauthorization votes are counted
+		var (
+			authorize bool
+			resigned  bool
+			counted   = !isPermitVote(header) && !isPeriodVote(header) && !isReplaceVote(header)
+		)
+		nonces := snap.voteNonces()
 		switch {
-		case bytes.Equal(header.Nonce[:], nonceAuthVote):
+		case header.Nonce == nonces.auth:
 			authorize = true
-		case bytes.Equal(header.Nonce[:], nonceDropVote):
+		case header.Nonce == nonces.drop:
+			authorize, counted = false, !snap.commitReveal()
+		case isCommitVote(header) && snap.commitReveal():
+			snap.commitVote(number, signer, header.Coinbase)
+			counted = false
+		case isRevealVote(header) && snap.commitReveal():
+			var salt revealSalt
+			authorize, salt = decodeReveal(header)
+			if err := snap.revealVote(number, signer, header.Coinbase, authorize, salt); err != nil {
+				return nil, err
+			}
+		case header.Nonce == nonces.limit:
+			if _, ok := snap.decodeSignerLimit(header.Coinbase); !ok {
+				return nil, errInvalidSignerLimit
+			}
+			snap.applyLimitVote(number, header.Hash(), signer, header.Coinbase)
+		case isPermitVote(header):
+			snap.applyPermitVote(number, header.Hash(), signer, header.Coinbase, bytes.Equal(header.Nonce[:], noncePermitVote))
+		case isPeriodVote(header):
+			period, ok := snap.decodePeriodVote(header)
+			if !ok {
+				return nil, errInvalidSignerPeriod
+			}
+			snap.applyPeriodVote(number, header.Hash(), signer, header.Coinbase, period)
+		case isReplaceVote(header):
+			if err := snap.verifyReplaceVote(header); err != nil {
+				return nil, err
+			}
+			old, _ := decodeReplaceVote(header)
+			snap.applyReplaceVote(number, header.Hash(), signer, old, header.Coinbase)
+		case isResignation(header):
+			if err := verifyResignation(header, signer); err != nil {
+				return nil, err
+			}
+			resigned, counted = true, false
+		case isOverride(header) && number%s.config.Epoch == 0:
 			authorize = false
-		case bytes.Equal(header.Nonce[:], nonceSignerLimitAuthVote):
-			s.applySignerLimitVotes(signer, snap, header)
 		default:
 			return nil, errInvalidVote
 		}
-
-		if snap.cast(header.Coinbase, authorize) {
-			snap.Votes = append(snap.Votes, &Vote{
-				Signer:    signer,
-				Block:     number,
-				Address:   header.Coinbase,
-				Authorize: authorize,
-			})
+		if counted {
+			snap.applyVote(number, header.Hash(), signer, header.Coinbase, authorize)
 		}
+		// Tally up the signer limit vote cast alongside in the payload
+		snap.applyPayloadLimit(header, signer)
 
-		// If the vote passed, update the list of signers
-		if tally := snap.Tally[header.Coinbase]; tally.Votes >= int(snap.signerLimit()) {
-			if tally.Authorize {
-				snap.Signers[header.Coinbase] = struct{}{}
-			} else {
-				delete(snap.Signers, header.Coinbase)
-
-				// Signer list shrunk, delete any leftover recent caches
-				snap.shrunkRecents(number)
-
-				// Discard any previous votes the deauthorized signer cast
-				for i := 0; i < len(snap.Votes); i++ {
-					if snap.Votes[i].Signer == header.Coinbase {
-						// Uncast the vote from the cached tally
-						snap.uncast(snap.Votes[i].Address, snap.Votes[i].Authorize)
-
-						// Uncast the vote from the chronological list
-						snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
-
-						i--
-					}
-				}
-			}
-			// Discard any previous votes around the just changed account
-			for i := 0; i < len(snap.Votes); i++ {
-				if snap.Votes[i].Address == header.Coinbase {
-
-					snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
-					i--
-				}
-			}
-
-			delete(snap.Tally, header.Coinbase)
+		// Tally up the votes cast through governance transactions
+		if err := snap.applyGovernanceVotes(header); err != nil {
+			return nil, err
+		}
+		// Count the finality justification and checkpoint attestation of the signer
+		if err := snap.applyJustification(header, signer); err != nil {
+			return nil, err
+		}
+		if err := snap.applyAttestation(header, signer); err != nil {
+			return nil, err
+		}
+		// Remove the signer only after everything else it sealed into the block
+		if resigned {
+			snap.resign(number, header.Hash(), signer)
 		}
 		// If we're taking too much time (ecrecover), notify the user once a while
 		if time.Since(logged) > 8*time.Second {
@@ -451,44 +935,306 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 	return snap, nil
 }
 
-// signers retrieves the list of authorized signers in ascending order.
+// SignerList retrieves the list of authorized signers in ascending order.
+func (s *Snapshot) SignerList() []common.Address {
+	return append([]common.Address{}, s.sorted...)
+}
+
+// IsSigner reports whether the given account is an authorized signer.
+func (s *Snapshot) IsSigner(address common.Address) bool {
+	_, ok := s.Signers[address]
+	return ok
+}
+
+// RecentSigners retrieves the recent signers for spam protection, keyed by the
+// block number they signed.
+func (s *Snapshot) RecentSigners() map[uint64]common.Address {
+	recents := make(map[uint64]common.Address, len(s.Recents))
+	for number, signer := range s.Recents {
+		recents[number] = signer
+	}
+	return recents
+}
+
+// EligibleBlocks retrieves the first block each recent signer may seal again,
+// once the block it last sealed shifts out of the spam protection window.
+func (s *Snapshot) EligibleBlocks() map[common.Address]uint64 {
+	var (
+		window   = s.recentsWindow(s.Number + 1)
+		eligible = make(map[common.Address]uint64, len(s.Recents))
+	)
+	for seen, signer := range s.Recents {
+		next := seen + window
+		if s.bootstrapping() || next <= s.Number {
+			next = s.Number + 1
+		}
+		if next > eligible[signer] {
+			eligible[signer] = next
+		}
+	}
+	return eligible
+}
+
+// PendingVotes retrieves the authorization votes cast since the last checkpoint,
+// in chronological order.
+func (s *Snapshot) PendingVotes() []Vote {
+	votes := make([]Vote, len(s.Votes))
+	for i, vote := range s.Votes {
+		votes[i] = *vote
+	}
+	return votes
+}
+
+// Tallies retrieves the current authorization vote tallies.
+func (s *Snapshot) Tallies() map[common.Address]Tally {
+	tally := make(map[common.Address]Tally, len(s.Tally))
+	for address, t := range s.Tally {
+		tally[address] = t
+	}
+	return tally
+}
+
+// Limit retrieves the signer limit percentage currently in force.
+func (s *Snapshot) Limit() uint {
+	return s.SignerLimit
+}
+
+// Threshold retrieves the number of votes needed for a proposal to pass.
+func (s *Snapshot) Threshold() uint {
+	return s.signerLimit()
+}
+
+// PendingLimitVotes retrieves the signer limit votes cast since the last
+// checkpoint, in chronological order.
+func (s *Snapshot) PendingLimitVotes() []LimitVote {
+	votes := make([]LimitVote, len(s.SignerLimitVotes))
+	for i, vote := range s.SignerLimitVotes {
+		votes[i] = *vote
+	}
+	return votes
+}
+
+// LimitTallies retrieves the current signer limit vote tallies.
+func (s *Snapshot) LimitTallies() map[uint]LimitTally {
+	tally := make(map[uint]LimitTally, len(s.SignerLimitTally))
+	for limit, t := range s.SignerLimitTally {
+		tally[limit] = t
+	}
+	return tally
+}
+
+// LimitWaits retrieves the cooldowns of the recently passed signer limits.
+func (s *Snapshot) LimitWaits() map[uint64]WaitTally {
+	wait := make(map[uint64]WaitTally, len(s.SignerLimitWait))
+	for limit, t := range s.SignerLimitWait {
+		wait[limit] = t
+	}
+	return wait
+}
+
+// signers retrieves the list of authorized signers in ascending order. The list
+// is shared between all users of the snapshot, so it must not be modified.
 func (s *Snapshot) signers() []common.Address {
+	return s.sorted
+}
+
+// sortSigners rebuilds the sorted signer list from scratch out of the signer set.
+func (s *Snapshot) sortSigners() {
 	sigs := make([]common.Address, 0, len(s.Signers))
 	for sig := range s.Signers {
 		sigs = append(sigs, sig)
 	}
 	sort.Sort(signersAscending(sigs))
-	return sigs
+	s.sorted = sigs
+}
+
+// signerIndex returns the position of the signer within the sorted signer list,
+// or the position it would be inserted at if it's not an authorized signer.
+func (s *Snapshot) signerIndex(signer common.Address) int {
+	return sort.Search(len(s.sorted), func(i int) bool {
+		return bytes.Compare(s.sorted[i][:], signer[:]) >= 0
+	})
+}
+
+// addSigner inserts a new signer into the authorized set, updating the sorted
+// signer list without having to re-sort it.
+func (s *Snapshot) addSigner(signer common.Address) {
+	if _, ok := s.Signers[signer]; ok {
+		return
+	}
+	s.writable(cowSigners)
+	s.Signers[signer] = struct{}{}
+
+	// The sorted list may be shared with other snapshots, never modify in place
+	i := s.signerIndex(signer)
+	sorted := make([]common.Address, 0, len(s.sorted)+1)
+	sorted = append(sorted, s.sorted[:i]...)
+	sorted = append(sorted, signer)
+	s.sorted = append(sorted, s.sorted[i:]...)
+}
+
+// removeSigner drops a signer from the authorized set, updating the sorted
+// signer list without having to re-sort it.
+func (s *Snapshot) removeSigner(signer common.Address) {
+	if _, ok := s.Signers[signer]; !ok {
+		return
+	}
+	s.writable(cowSigners)
+	delete(s.Signers, signer)
+
+	// The sorted list may be shared with other snapshots, never modify in place
+	i := s.signerIndex(signer)
+	sorted := make([]common.Address, 0, len(s.sorted)-1)
+	sorted = append(sorted, s.sorted[:i]...)
+	s.sorted = append(sorted, s.sorted[i+1:]...)
 }
 
 // inturn returns if a signer at a given block height is in-turn or not.
 func (s *Snapshot) inturn(number uint64, signer common.Address) bool {
-	signers, offset := s.signers(), 0
-	for offset < len(signers) && signers[offset] != signer {
-		offset++
+	signers := s.signers()
+	if len(signers) == 0 {
+		return false
+	}
+	offset := s.signerIndex(signer)
+	if offset < len(signers) && signers[offset] != signer {
+		offset = len(signers)
 	}
 	return (number % uint64(len(signers))) == uint64(offset)
 }
 
+// nextInturn returns the first block at or after the given one the signer is
+// in-turn for, assuming the signer set doesn't change until then. It returns
+// false if the signer is not authorized.
+func (s *Snapshot) nextInturn(number uint64, signer common.Address) (uint64, bool) {
+	signers := s.signers()
+	offset := s.signerIndex(signer)
+	if offset >= len(signers) || signers[offset] != signer {
+		return 0, false
+	}
+	count := uint64(len(signers))
+	return number + (uint64(offset)+count-number%count)%count, true
+}
+
+// signerLimit returns the number of votes needed for a proposal to pass, derived
+// either from the signer limit percentage or, in absolute mode, capped by the
+// number of signers to keep governance alive should the signer set shrink.
 func (s *Snapshot) signerLimit() uint {
-	return uint(len(s.Signers))*s.SignerLimit/100 + 1
+	signers := uint(len(s.Signers))
+	if s.config.AbsoluteSignerLimit {
+		if s.SignerLimit > signers && signers > 0 {
+			return signers
+		}
+		return s.SignerLimit
+	}
+	return signers*s.SignerLimit/100 + 1
+}
+
+// bootstrapping returns whether the signer set is still below the configured
+// bootstrap size, during which the spam protection is suspended and a single
+// vote suffices to authorize a new signer.
+func (s *Snapshot) bootstrapping() bool {
+	return uint(len(s.Signers)) < s.config.BootstrapSigners
+}
+
+// expireVotes drops the votes cast more than the configured decay period ago, so
+// stale half-finished campaigns fade out unless the signers recast their votes.
+func (s *Snapshot) expireVotes(number uint64) {
+	decay := s.config.TallyDecay
+	if decay == 0 {
+		return
+	}
+	// Votes are kept in chronological order, so expired ones are at the front
+	for len(s.Votes) > 0 && s.Votes[0].Block+decay <= number {
+		s.uncast(s.Votes[0].Address, s.Votes[0].Authorize)
+
+		s.writable(cowVotes)
+		s.Votes = s.Votes[1:]
+	}
+	for len(s.SignerLimitVotes) > 0 && s.SignerLimitVotes[0].Block+decay <= number {
+		s.uncastSignerLimit(s.SignerLimitVotes[0].Limit, s.SignerLimitVotes[0].Authorize)
+
+		s.writable(cowLimitVotes)
+		s.SignerLimitVotes = s.SignerLimitVotes[1:]
+	}
+}
+
+// changesCapped returns whether the signer set changed as many times within the
+// current epoch as allowed, freezing the membership tallies until the next one.
+func (s *Snapshot) changesCapped() bool {
+	return s.config.MaxEpochChanges > 0 && s.EpochChanges >= s.config.MaxEpochChanges
 }
 
-func (s *Snapshot) deleteLimitWait(){
-	for i := range s.SignerLimitWait {
-		delete(s.SignerLimitWait, i)
+// voteThreshold returns the number of votes needed for a membership proposal to
+// pass, relaxing authorizations to a single vote while bootstrapping.
+func (s *Snapshot) voteThreshold(authorize bool) uint {
+	if authorize && s.bootstrapping() {
+		return 1
 	}
+	return s.signerLimit()
 }
 
+// epochLimit returns the signer limit and its affirmation block in force after
+// the checkpoint block with the given number, reverting the limit to the initial
+// one if resets are enabled and it wasn't reaffirmed within the last epoch.
+func (s *Snapshot) epochLimit(number uint64) (uint, uint64) {
+	if s.config.SignerLimitReset && s.SignerLimit != s.initialSignerLimit() && s.SignerLimitAffirmed+s.config.Epoch <= number {
+		return s.initialSignerLimit(), number
+	}
+	return s.SignerLimit, s.SignerLimitAffirmed
+}
+
+func (s *Snapshot) deleteLimitWait() {
+	if len(s.SignerLimitWait) == 0 {
+		return
+	}
+	s.SignerLimitWait = make(map[uint64]WaitTally)
+	s.owned |= cowLimitWait
+}
+
+// recentsWindow returns the number of consecutive blocks ending at the given one
+// within which a signer may only seal once. Before the recents window fork it's
+// the signer limit. From the fork on, it defaults to a simple majority of the
+// signers, independent of the voting threshold so that governance changes don't
+// alter the liveness of the network, and a configured window is capped to the
+// signer count.
+func (s *Snapshot) recentsWindow(number uint64) uint64 {
+	if !s.config.IsRecentsWindow(new(big.Int).SetUint64(number)) {
+		return uint64(s.signerLimit())
+	}
+	signers := uint64(len(s.Signers))
+	if window := uint64(s.config.RecentsWindow); window > 0 {
+		if window > signers && signers > 0 {
+			return signers
+		}
+		return window
+	}
+	return signers/2 + 1
+}
+
+// shrunkRecents deletes any recent signers that fell out of the spam protection
+// window ending at the given block, allowing them to sign again.
 func (s *Snapshot) shrunkRecents(number uint64) {
-	if limit := uint64(s.signerLimit()); number >= limit {
-		recentsSize := uint64(len(s.Recents))
-		if recentsSize >= limit {
-			deleteAmount := recentsSize - limit + 1
-			var i uint64
-			for i = 0; i < deleteAmount; i++ {
-				delete(s.Recents, number-limit-i)
+	if !s.config.IsRecentsWindow(new(big.Int).SetUint64(number)) {
+		// Before the fork, only the oldest recents beyond the signer limit are
+		// dropped, keyed by their expected positions
+		if limit := uint64(s.signerLimit()); number >= limit {
+			recentsSize := uint64(len(s.Recents))
+			if recentsSize >= limit {
+				deleteAmount := recentsSize - limit + 1
+				s.writable(cowRecents)
+				var i uint64
+				for i = 0; i < deleteAmount; i++ {
+					delete(s.Recents, number-limit-i)
+				}
 			}
 		}
+		return
+	}
+	window := s.recentsWindow(number)
+	for seen := range s.Recents {
+		if seen+window <= number {
+			s.writable(cowRecents)
+			delete(s.Recents, seen)
+		}
 	}
 }
\ No newline at end of file
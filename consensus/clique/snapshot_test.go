@@ -19,8 +19,11 @@ package clique
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"fmt"
 	"math/big"
+	"reflect"
 	"sort"
+	"sync"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -91,6 +94,7 @@ type testerVote struct {
 	signer     string
 	voted      string
 	auth       bool
+	limit      uint
 	checkpoint []string
 	newbatch   bool
 }
@@ -100,11 +104,19 @@ type testerVote struct {
 func TestClique(t *testing.T) {
 	// Define the various voting scenarios to test
 	tests := []struct {
-		epoch   uint64
-		signers []string
-		votes   []testerVote
-		results []string
-		failure error
+		epoch      uint64
+		limitReset bool
+		bootstrap  uint
+		window     uint
+		recents    bool
+		changes    uint
+		decay      uint64
+		confirm    uint64
+		signers    []string
+		votes      []testerVote
+		results    []string
+		limit      uint
+		failure    error
 	}{
 		{
 			// Single signer, no votes cast
@@ -375,6 +387,230 @@ func TestClique(t *testing.T) {
 				{signer: "A", newbatch: true},
 			},
 			failure: errRecentlySigned,
+		}, {
+			// Two signers, changing the signer limit by mutual consent
+			signers: []string{"A", "B"},
+			votes: []testerVote{
+				{signer: "A", limit: 66},
+				{signer: "B", limit: 66},
+			},
+			results: []string{"A", "B"},
+			limit:   66,
+		}, {
+			// Signer limit votes above 100 percent are rejected
+			signers: []string{"A", "B"},
+			votes: []testerVote{
+				{signer: "A", limit: 150},
+			},
+			failure: errInvalidSignerLimit,
+		}, {
+			// Signer limit votes with garbage in the upper coinbase bytes are rejected
+			signers: []string{"A", "B"},
+			votes: []testerVote{
+				{signer: "A", voted: "C", limit: 50},
+			},
+			failure: errInvalidSignerLimit,
+		}, {
+			// Signer limits not reaffirmed within an epoch revert to the initial one
+			epoch:      3,
+			limitReset: true,
+			signers:    []string{"A", "B"},
+			votes: []testerVote{
+				{signer: "A", limit: 66},
+				{signer: "B", limit: 66},
+				{signer: "A", checkpoint: []string{"A", "B"}},
+				{signer: "B"},
+				{signer: "A"},
+				{signer: "B", checkpoint: []string{"A", "B"}},
+			},
+			results: []string{"A", "B"},
+			limit:   50,
+		}, {
+			// Signer limits reaffirmed within an epoch survive the epoch reset
+			epoch:      3,
+			limitReset: true,
+			signers:    []string{"A", "B"},
+			votes: []testerVote{
+				{signer: "A", limit: 66},
+				{signer: "B", limit: 66},
+				{signer: "A", checkpoint: []string{"A", "B"}},
+				{signer: "B", limit: 66},
+				{signer: "A", limit: 66},
+				{signer: "B", checkpoint: []string{"A", "B"}},
+			},
+			results: []string{"A", "B"},
+			limit:   66,
+		}, {
+			// Bootstrapping signers may seal consecutively and authorize alone
+			bootstrap: 3,
+			signers:   []string{"A"},
+			votes: []testerVote{
+				{signer: "A", voted: "B", auth: true},
+				{signer: "A"},
+				{signer: "A", voted: "C", auth: true},
+			},
+			results: []string{"A", "B", "C"},
+		}, {
+			// Spam protection kicks in once the bootstrap size is reached
+			bootstrap: 2,
+			signers:   []string{"A"},
+			votes: []testerVote{
+				{signer: "A", voted: "B", auth: true},
+				{signer: "A"},
+				{signer: "A"},
+			},
+			failure: errRecentlySigned,
+		}, {
+			// Deauthorizations need the full threshold even while bootstrapping
+			bootstrap: 3,
+			signers:   []string{"A", "B"},
+			votes: []testerVote{
+				{signer: "A", voted: "B", auth: false},
+			},
+			results: []string{"A", "B"},
+		}, {
+			// Raising the signer limit doesn't widen the recent signer window
+			recents: true,
+			signers: []string{"A", "B", "C", "D", "E"},
+			votes: []testerVote{
+				{signer: "A", limit: 80},
+				{signer: "B", limit: 80},
+				{signer: "C", limit: 80},
+				{signer: "D", limit: 80},
+				{signer: "E", limit: 80},
+				{signer: "A"},
+				{signer: "B"},
+				{signer: "C"},
+				{signer: "A"},
+			},
+			results: []string{"A", "B", "C", "D", "E"},
+			limit:   80,
+		}, {
+			// Before the recents window fork the window still follows the signer limit
+			signers: []string{"A", "B", "C", "D", "E"},
+			votes: []testerVote{
+				{signer: "A", limit: 80},
+				{signer: "B", limit: 80},
+				{signer: "C", limit: 80},
+				{signer: "D", limit: 80},
+				{signer: "E", limit: 80},
+				{signer: "A"},
+				{signer: "B"},
+				{signer: "C"},
+				{signer: "A"},
+			},
+			failure: errRecentlySigned,
+		}, {
+			// A configured recent signer window is respected
+			recents: true,
+			window:  1,
+			signers: []string{"A", "B"},
+			votes: []testerVote{
+				{signer: "A"},
+				{signer: "A"},
+				{signer: "B"},
+				{signer: "B"},
+			},
+			results: []string{"A", "B"},
+		}, {
+			// A configured recent signer window is enforced
+			recents: true,
+			window:  3,
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A"},
+				{signer: "B"},
+				{signer: "A"},
+			},
+			failure: errRecentlySigned,
+		}, {
+			// Shrinking the signer set shrinks the recent signer window too
+			recents: true,
+			signers: []string{"A", "B", "C", "D"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: false},
+				{signer: "B", voted: "D", auth: false},
+				{signer: "C", voted: "D", auth: false},
+				{signer: "B"},
+				{signer: "A"},
+				{signer: "B"},
+			},
+			results: []string{"A", "B", "C"},
+		}, {
+			// Reaching the per-epoch change cap freezes the tallies
+			changes: 1,
+			signers: []string{"A"},
+			votes: []testerVote{
+				{signer: "A", voted: "B", auth: true},
+				{signer: "B", voted: "C", auth: true},
+				{signer: "A", voted: "C", auth: true},
+			},
+			results: []string{"A", "B"},
+		}, {
+			// The per-epoch change cap resets at the next epoch
+			epoch:   3,
+			changes: 1,
+			signers: []string{"A"},
+			votes: []testerVote{
+				{signer: "A", voted: "B", auth: true},
+				{signer: "B", voted: "C", auth: true},
+				{signer: "A", checkpoint: []string{"A", "B"}},
+				{signer: "B", voted: "C", auth: true},
+				{signer: "A", voted: "C", auth: true},
+			},
+			results: []string{"A", "B", "C"},
+		}, {
+			// Votes not reaffirmed within the decay period expire
+			decay:   2,
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B"},
+				{signer: "C"},
+				{signer: "B", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C"},
+		}, {
+			// Recasting a vote refreshes it against the decay
+			decay:   3,
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B"},
+				{signer: "A", voted: "D", auth: true},
+				{signer: "C", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C", "D"},
+		}, {
+			// Under two-phase voting, the first vote only opens the proposal
+			confirm: 2,
+			signers: []string{"A"},
+			votes: []testerVote{
+				{signer: "A", voted: "B", auth: true},
+			},
+			results: []string{"A"},
+		}, {
+			// Under two-phase voting, confirmations within the window pass the proposal
+			confirm: 2,
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B", voted: "D", auth: true},
+				{signer: "C", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C", "D"},
+		}, {
+			// Under two-phase voting, proposals not confirmed in time must be reopened
+			confirm: 2,
+			signers: []string{"A", "B", "C"},
+			votes: []testerVote{
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B"},
+				{signer: "C"},
+				{signer: "A", voted: "D", auth: true},
+				{signer: "B", voted: "D", auth: true},
+			},
+			results: []string{"A", "B", "C"},
 		},
 	}
 	// Run through the scenarios and test them
@@ -408,14 +644,36 @@ func TestClique(t *testing.T) {
 		// Assemble a chain of headers from the cast votes
 		config := *params.TestChainConfig
 		config.Clique = &params.CliqueConfig{
-			Period: 1,
-			Epoch:  tt.epoch,
+			Period:           1,
+			Epoch:            tt.epoch,
+			SignerLimitReset: tt.limitReset,
+			BootstrapSigners: tt.bootstrap,
+			RecentsWindow:    tt.window,
+			MaxEpochChanges:  tt.changes,
+			TallyDecay:       tt.decay,
+			ConfirmWindow:    tt.confirm,
+		}
+		if tt.recents {
+			config.Clique.RecentsWindowBlock = common.Big0
 		}
 		engine := New(config.Clique, db)
 		engine.fakeDiff = true
 
 		blocks, _ := core.GenerateChain(&config, genesis.ToBlock(db), engine, db, len(tt.votes), func(j int, gen *core.BlockGen) {
 			// Cast the vote contained in this block
+			if limit := tt.votes[j].limit; limit != 0 {
+				// Limit votes may be polluted with an address to test garbage rejection
+				coinbase := common.BigToAddress(new(big.Int).SetUint64(uint64(limit)))
+				if tt.votes[j].voted != "" {
+					copy(coinbase[:common.AddressLength-8], accounts.address(tt.votes[j].voted).Bytes())
+				}
+				gen.SetCoinbase(coinbase)
+
+				var nonce types.BlockNonce
+				copy(nonce[:], nonceSignerLimitAuthVote)
+				gen.SetNonce(nonce)
+				return
+			}
 			gen.SetCoinbase(accounts.address(tt.votes[j].voted))
 			if tt.votes[j].auth {
 				var nonce types.BlockNonce
@@ -502,5 +760,388 @@ func TestClique(t *testing.T) {
 				t.Errorf("test %d, signer %d: signer mismatch: have %x, want %x", i, j, result[j], signers[j])
 			}
 		}
+		if tt.limit != 0 && snap.SignerLimit != tt.limit {
+			t.Errorf("test %d: signer limit mismatch: have %d, want %d", i, snap.SignerLimit, tt.limit)
+		}
+	}
+}
+
+// Tests that modifying a copy of a snapshot never leaks back into the original
+// or into sibling copies, even though the containers are shared copy-on-write.
+func TestSnapshotCopyOnWrite(t *testing.T) {
+	var (
+		a = common.Address{0x0a}
+		b = common.Address{0x0b}
+		c = common.Address{0x0c}
+	)
+	snap := newSnapshot(&params.CliqueConfig{Epoch: 30000}, nil, 0, common.Hash{}, []common.Address{a, b})
+	snap.cast(c, true)
+	snap.Votes = append(snap.Votes, &Vote{Signer: a, Address: c, Authorize: true}) // Leave spare capacity behind
+
+	first, second := snap.copy(), snap.copy()
+
+	first.cast(c, true)
+	first.writable(cowVotes)
+	first.Votes = append(first.Votes, &Vote{Signer: b, Address: c, Authorize: true})
+	first.addSigner(c)
+	first.writable(cowRecents)
+	first.Recents[1] = a
+
+	second.writable(cowVotes)
+	second.Votes = append(second.Votes, &Vote{Signer: a, Address: b, Authorize: false})
+
+	if len(snap.Signers) != 2 || len(snap.Recents) != 0 || len(snap.Votes) != 1 || snap.Tally[c].Votes != 1 {
+		t.Errorf("original snapshot modified: signers %d, recents %d, votes %d, tally %d", len(snap.Signers), len(snap.Recents), len(snap.Votes), snap.Tally[c].Votes)
+	}
+	if len(second.Signers) != 2 || second.Tally[c].Votes != 1 || second.Votes[1].Address != b {
+		t.Errorf("sibling snapshot modified: signers %d, tally %d, vote %x", len(second.Signers), second.Tally[c].Votes, second.Votes[1].Address)
+	}
+	if len(first.Signers) != 3 || first.Tally[c].Votes != 2 || first.Votes[1].Address != c {
+		t.Errorf("copy not modified: signers %d, tally %d, vote %x", len(first.Signers), first.Tally[c].Votes, first.Votes[1].Address)
+	}
+}
+
+// Tests that recent signers are reported eligible again from the first block
+// their last seal shifts out of the spam protection window.
+func TestSnapshotEligibleBlocks(t *testing.T) {
+	var (
+		a = common.Address{0x0a}
+		b = common.Address{0x0b}
+		c = common.Address{0x0c}
+		d = common.Address{0x0d}
+	)
+	snap := newSnapshot(&params.CliqueConfig{Epoch: 30000}, nil, 10, common.Hash{}, []common.Address{a, b, c, d})
+	snap.Recents = map[uint64]common.Address{8: a, 9: b, 10: c}
+
+	// With four signers, the window is three blocks
+	want := map[common.Address]uint64{a: 11, b: 12, c: 13}
+	if have := snap.EligibleBlocks(); !reflect.DeepEqual(have, want) {
+		t.Errorf("eligible blocks mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that the next in-turn slot of a signer is projected from the signer order.
+func TestSnapshotNextInturn(t *testing.T) {
+	var (
+		a = common.Address{0x0a}
+		b = common.Address{0x0b}
+		c = common.Address{0x0c}
+	)
+	snap := newSnapshot(&params.CliqueConfig{Epoch: 30000}, nil, 10, common.Hash{}, []common.Address{a, b, c})
+
+	tests := []struct {
+		signer common.Address
+		number uint64
+		next   uint64
+		ok     bool
+	}{
+		{signer: a, number: 12, next: 12, ok: true},
+		{signer: a, number: 13, next: 15, ok: true},
+		{signer: b, number: 11, next: 13, ok: true},
+		{signer: c, number: 11, next: 11, ok: true},
+		{signer: common.Address{0x0d}, number: 11},
+	}
+	for i, tt := range tests {
+		next, ok := snap.nextInturn(tt.number, tt.signer)
+		if next != tt.next || ok != tt.ok {
+			t.Errorf("test %d: next in-turn mismatch: have %d (%v), want %d (%v)", i, next, ok, tt.next, tt.ok)
+		}
+		if ok && !snap.inturn(next, tt.signer) {
+			t.Errorf("test %d: projected block %d not in-turn", i, next)
+		}
+	}
+}
+
+// Tests that the cached sorted signer list is kept in sync with the signer set
+// as signers are added and removed, without leaking into snapshot copies.
+func TestSnapshotSortedSigners(t *testing.T) {
+	snap := newSnapshot(&params.CliqueConfig{Epoch: 30000}, nil, 0, common.Hash{}, nil)
+
+	var shared, history [][]common.Address
+	for i := 0; i < 256; i++ {
+		signer := common.Address{byte(i * 7919 % 251), byte(i)}
+		if i%3 == 2 {
+			snap.removeSigner(snap.signers()[len(snap.signers())/2])
+		} else {
+			snap.addSigner(signer)
+		}
+		want := make([]common.Address, 0, len(snap.Signers))
+		for signer := range snap.Signers {
+			want = append(want, signer)
+		}
+		sort.Sort(signersAscending(want))
+
+		if have := snap.signers(); !reflect.DeepEqual(have, want) {
+			t.Fatalf("iteration %d: sorted signers mismatch: have %x, want %x", i, have, want)
+		}
+		shared, history = append(shared, snap.signers()), append(history, want)
+		snap = snap.copy()
+	}
+	// Ensure none of the earlier lists got modified by later operations
+	for i := range history {
+		if !reflect.DeepEqual(shared[i], history[i]) {
+			t.Fatalf("iteration %d: historical signers modified: have %x, want %x", i, shared[i], history[i])
+		}
+	}
+}
+
+// newBenchmarkSnapshot creates a snapshot with a large signer set and a number
+// of pending votes, along with a signed header extending it.
+func newBenchmarkSnapshot(signers int) (*Snapshot, *types.Header) {
+	accounts := newTesterAccountPool()
+
+	auths := make([]common.Address, signers)
+	for i := range auths {
+		auths[i] = accounts.address(fmt.Sprintf("signer-%d", i))
+	}
+	sigcache := NewSigCache(inmemorySignatures, nil)
+	snap := newSnapshot(&params.CliqueConfig{Period: 1, Epoch: 30000}, sigcache, 0, common.Hash{}, auths)
+	for i := 0; i < signers/2; i++ {
+		snap.Recents[uint64(i)] = auths[i]
+		snap.cast(common.Address{byte(i)}, true)
+		snap.Votes = append(snap.Votes, &Vote{Signer: auths[i], Address: common.Address{byte(i)}, Authorize: true})
+	}
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Difficulty: diffNoTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+	}
+	accounts.sign(header, fmt.Sprintf("signer-%d", signers-1))
+	return snap, header
+}
+
+func BenchmarkSnapshotCopy(b *testing.B) {
+	snap, _ := newBenchmarkSnapshot(128)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		snap.copy()
+	}
+}
+
+func BenchmarkSnapshotApply(b *testing.B) {
+	snap, header := newBenchmarkSnapshot(128)
+	if _, err := snap.apply([]*types.Header{header}); err != nil {
+		b.Fatalf("failed to apply header: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		snap.apply([]*types.Header{header})
+	}
+}
+
+// Tests that published snapshots can be read through the accessors while other
+// goroutines derive new snapshots from them, and that they refuse modification.
+func TestSnapshotConcurrentAccess(t *testing.T) {
+	snap, header := newBenchmarkSnapshot(16)
+	snap.frozen = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 16; j++ {
+				if _, err := snap.apply([]*types.Header{header}); err != nil {
+					t.Errorf("failed to apply header: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 16; j++ {
+				if len(snap.SignerList()) != 16 || len(snap.RecentSigners()) != 8 || len(snap.Tallies()) != 8 || len(snap.PendingVotes()) != 8 {
+					t.Errorf("snapshot contents changed")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("published snapshot modification didn't panic")
+		}
+	}()
+	snap.cast(common.Address{0xff}, true)
+}
+
+// Tests that signer limit votes out of the permitted bounds are rejected when
+// cast, so they can't push the vote threshold above the signer count.
+func TestSignerLimitVoteBounds(t *testing.T) {
+	accounts := newTesterAccountPool()
+	signers := []common.Address{accounts.address("A"), accounts.address("B")}
+
+	tests := []struct {
+		absolute bool
+		limit    uint
+		valid    bool
+	}{
+		{false, 0, false},
+		{false, 75, true},
+		{false, 100, true},
+		{false, 101, false},
+		{false, 300, false},
+		{true, 2, true},
+		{true, 3, false},
+	}
+	for i, tt := range tests {
+		config := &params.CliqueConfig{Epoch: 30000, SignerLimit: 1, AbsoluteSignerLimit: tt.absolute}
+		snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, signers)
+
+		if cast := snap.castSignerLimit(signers[0], tt.limit, 1); cast != tt.valid {
+			t.Errorf("test %d: limit vote cast mismatch: have %v, want %v", i, cast, tt.valid)
+		}
+		if !tt.valid && len(snap.SignerLimitTally) != 0 {
+			t.Errorf("test %d: rejected limit vote tallied", i)
+		}
+		// Ensure replaying a header carrying the vote enforces the same bounds
+		header := &types.Header{
+			Number:   common.Big1,
+			Coinbase: common.BigToAddress(new(big.Int).SetUint64(uint64(tt.limit))),
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		copy(header.Nonce[:], nonceSignerLimitAuthVote)
+		accounts.sign(header, "A")
+
+		if _, err := snap.apply([]*types.Header{header}); (err == nil) != tt.valid {
+			t.Errorf("test %d: limit vote replay mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+// Tests that signer limit votes are tallied on the working snapshot, pass once
+// the threshold is reached, and bar the passed limit for a full signer round.
+func TestApplyLimitVote(t *testing.T) {
+	var (
+		a = common.Address{0x0a}
+		b = common.Address{0x0b}
+		c = common.Address{0x0c}
+
+		limit75 = common.BigToAddress(big.NewInt(75))
+		limit60 = common.BigToAddress(big.NewInt(60))
+	)
+	snap := newSnapshot(&params.CliqueConfig{Epoch: 30000}, nil, 0, common.Hash{}, []common.Address{a, b, c})
+
+	// A single vote out of the two needed should only be tallied
+	snap.applyLimitVote(1, common.Hash{0x01}, a, limit75)
+	if tally := snap.SignerLimitTally[75]; tally.Votes != 1 || tally.Signer != a {
+		t.Errorf("limit tally mismatch after first vote: have %+v", tally)
+	}
+	if len(snap.SignerLimitVotes) != 1 || snap.SignerLimit != defaultSignerLimit {
+		t.Errorf("limit state mismatch after first vote: votes %d, limit %d", len(snap.SignerLimitVotes), snap.SignerLimit)
+	}
+	if len(snap.observed) != 1 || snap.observed[0].Passed {
+		t.Errorf("observed vote mismatch after first vote: have %d events", len(snap.observed))
+	}
+	// A repeated vote from the same signer on the limit should not count twice
+	snap.uncastLimitVote(a, limit75)
+	snap.applyLimitVote(2, common.Hash{0x02}, a, limit75)
+	if tally := snap.SignerLimitTally[75]; tally.Votes != 1 {
+		t.Errorf("limit tally mismatch after repeated vote: have %d, want %d", tally.Votes, 1)
+	}
+	// The second signer's vote should pass the limit and start the wait
+	snap.applyLimitVote(3, common.Hash{0x03}, b, limit75)
+	if snap.SignerLimit != 75 || snap.SignerLimitAffirmed != 3 {
+		t.Errorf("signer limit mismatch after passing: have %d at %d, want %d at %d", snap.SignerLimit, snap.SignerLimitAffirmed, 75, 3)
+	}
+	if len(snap.SignerLimitVotes) != 0 || len(snap.SignerLimitTally) != 0 {
+		t.Errorf("passed limit votes not discarded: votes %d, tallies %d", len(snap.SignerLimitVotes), len(snap.SignerLimitTally))
+	}
+	if len(snap.resolutions) != 1 || len(snap.resolutions[0].Votes) != 2 || snap.resolutions[0].PrevLimit != defaultSignerLimit {
+		t.Errorf("limit resolution mismatch: have %d resolutions", len(snap.resolutions))
+	}
+	if wait := snap.SignerLimitWait[75]; wait.Block != 3+3 {
+		t.Errorf("limit wait mismatch: have %d, want %d", wait.Block, 6)
+	}
+	// Voting on the limit in force is pointless, but any new vote lifts the wait
+	snap.applyLimitVote(4, common.Hash{0x04}, c, limit75)
+	if len(snap.SignerLimitTally) != 0 {
+		t.Errorf("vote on the limit in force tallied")
+	}
+	if len(snap.SignerLimitWait) != 0 {
+		t.Errorf("limit wait not lifted by a new vote")
+	}
+	snap.applyLimitVote(5, common.Hash{0x05}, c, limit60)
+	if tally := snap.SignerLimitTally[60]; tally.Votes != 1 {
+		t.Errorf("limit tally mismatch after new vote: have %d, want %d", tally.Votes, 1)
+	}
+}
+
+// Tests that pending signer limit votes are discarded at epoch boundaries, and
+// that a passed limit reverts to the initial one unless reaffirmed in time.
+func TestLimitVoteEpochReset(t *testing.T) {
+	accounts := newTesterAccountPool()
+	signers := []string{"A", "B", "C"}
+
+	addrs := make([]common.Address, len(signers))
+	for i, signer := range signers {
+		addrs[i] = accounts.address(signer)
+	}
+	config := &params.CliqueConfig{Epoch: 4, SignerLimitReset: true}
+	snap := newSnapshot(config, NewSigCache(16, nil), 0, common.Hash{}, addrs)
+
+	votes := []uint{75, 75, 60, 0, 0, 0, 0, 0}
+	headers := make([]*types.Header, len(votes))
+	for i, limit := range votes {
+		headers[i] = &types.Header{
+			Number: big.NewInt(int64(i + 1)),
+			Extra:  make([]byte, extraVanity+extraSeal),
+		}
+		if limit != 0 {
+			headers[i].Coinbase = common.BigToAddress(new(big.Int).SetUint64(uint64(limit)))
+			copy(headers[i].Nonce[:], nonceSignerLimitAuthVote)
+		}
+		accounts.sign(headers[i], signers[i%len(signers)])
+	}
+	// Ensure the limit passes and the pending vote is dropped at the checkpoint
+	epoch, err := snap.apply(headers[:4])
+	if err != nil {
+		t.Fatalf("failed to apply first epoch: %v", err)
+	}
+	if epoch.SignerLimit != 75 || len(epoch.SignerLimitTally) != 0 || len(epoch.SignerLimitVotes) != 0 {
+		t.Errorf("first epoch mismatch: limit %d, tallies %d, votes %d", epoch.SignerLimit, len(epoch.SignerLimitTally), len(epoch.SignerLimitVotes))
+	}
+	// Ensure the limit not reaffirmed within an epoch reverts at the next one
+	if epoch, err = epoch.apply(headers[4:]); err != nil {
+		t.Fatalf("failed to apply second epoch: %v", err)
+	}
+	if epoch.SignerLimit != defaultSignerLimit || epoch.SignerLimitAffirmed != 8 {
+		t.Errorf("second epoch mismatch: limit %d at %d, want %d at %d", epoch.SignerLimit, epoch.SignerLimitAffirmed, defaultSignerLimit, 8)
+	}
+}
+
+// Tests that the number of votes needed to pass a proposal is derived correctly
+// from the signer limit in both percentage and absolute modes.
+func TestSignerLimitModes(t *testing.T) {
+	tests := []struct {
+		absolute bool
+		limit    uint
+		signers  int
+		want     uint
+	}{
+		{false, 50, 1, 1},
+		{false, 50, 4, 3},
+		{false, 50, 5, 3},
+		{false, 66, 5, 4},
+		{false, 66, 100, 67},
+		{true, 3, 5, 3},
+		{true, 3, 100, 3},
+		{true, 3, 2, 2},
+		{true, 1, 7, 1},
+	}
+	for i, tt := range tests {
+		signers := make([]common.Address, tt.signers)
+		for j := range signers {
+			signers[j] = common.Address{byte(j + 1)}
+		}
+		config := &params.CliqueConfig{Epoch: 30000, SignerLimit: tt.limit, AbsoluteSignerLimit: tt.absolute}
+		if have := newSnapshot(config, nil, 0, common.Hash{}, signers).signerLimit(); have != tt.want {
+			t.Errorf("test %d: vote threshold mismatch: have %d, want %d", i, have, tt.want)
+		}
 	}
 }
@@ -0,0 +1,110 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var snapshotIndexPrefix = []byte("clique-index-") // snapshotIndexPrefix + num (uint64 big endian) + hash -> nil
+
+// snapshotIndexKey = snapshotIndexPrefix + num (uint64 big endian) + hash
+func snapshotIndexKey(number uint64, hash common.Hash) []byte {
+	key := append(append([]byte{}, snapshotIndexPrefix...), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(key[len(snapshotIndexPrefix):], number)
+	return append(key, hash[:]...)
+}
+
+// indexSnapshot records a persisted snapshot in the block number ordered index,
+// through which the snapshots of ancestors are found regardless of their height.
+func indexSnapshot(db ethdb.KeyValueWriter, number uint64, hash common.Hash) error {
+	return db.Put(snapshotIndexKey(number, hash), nil)
+}
+
+// unindexSnapshot drops a snapshot from the block number ordered index.
+func unindexSnapshot(db ethdb.KeyValueWriter, number uint64, hash common.Hash) error {
+	return db.Delete(snapshotIndexKey(number, hash))
+}
+
+// indexedSnapshot checks whether the snapshot of a block was persisted according
+// to the index. The nearest indexed ancestor of a block is the one to replay the
+// headers on top of when the block's own snapshot isn't available, so ancestors
+// are looked up one by one while walking back, never scanning the whole index.
+func indexedSnapshot(db ethdb.KeyValueReader, number uint64, hash common.Hash) bool {
+	has, err := db.Has(snapshotIndexKey(number, hash))
+	return err == nil && has
+}
+
+// migrateSnapshotIndex adds the full and delta voting snapshots stored before
+// the block number ordered index into it. Snapshots of blocks unknown to the
+// chain are left out, they can't be the ancestor of any block to verify.
+func migrateSnapshotIndex(chain consensus.ChainHeaderReader, db ethdb.Database, namespace string) error {
+	var (
+		table   = rawdb.NewTable(db, namespace)
+		batch   = table.NewBatch()
+		indexed int
+	)
+	it := table.NewIterator(snapshotPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		var (
+			key  = it.Key()
+			hash common.Hash
+		)
+		switch {
+		case len(key) == len(snapshotPrefix)+common.HashLength:
+			hash = common.BytesToHash(key[len(snapshotPrefix):])
+		case bytes.HasPrefix(key, deltaPrefix) && len(key) == len(deltaPrefix)+common.HashLength:
+			hash = common.BytesToHash(key[len(deltaPrefix):])
+		default:
+			continue
+		}
+		header := chain.GetHeaderByHash(hash)
+		if header == nil {
+			continue
+		}
+		if err := indexSnapshot(batch, header.Number.Uint64(), hash); err != nil {
+			return err
+		}
+		indexed++
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if indexed > 0 {
+		log.Info("Indexed voting snapshots by block number", "snapshots", indexed)
+	}
+	return nil
+}
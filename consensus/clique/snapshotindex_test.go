@@ -0,0 +1,146 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the snapshot of a block is replayed from the nearest indexed
+// ancestor snapshot, even if it's not at a checkpoint height and the headers
+// before it are gone.
+func TestSnapshotIndexFallback(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 20)
+		db       = rawdb.NewMemoryDatabase()
+	)
+	// Persist the snapshot of a block off the checkpoint interval
+	engine := New(config, db)
+	snap, err := engine.snapshot(chain, 7, chain.headers[7].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	snapdb, err := engine.snapshotDB(chain)
+	if err != nil {
+		t.Fatalf("failed to open snapshot database: %v", err)
+	}
+	if err := snap.copy().store(snapdb); err != nil {
+		t.Fatalf("failed to store snapshot: %v", err)
+	}
+	want, err := engine.snapshot(chain, 20, chain.headers[20].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create head snapshot: %v", err)
+	}
+	engine.Close()
+
+	// Prune the headers preceding the persisted snapshot, leaving the genesis
+	pruned := &testerHeaderChain{config: chain.config, headers: append([]*types.Header{}, chain.headers...)}
+	for i := 1; i < 7; i++ {
+		pruned.headers[i] = &types.Header{Number: big.NewInt(int64(i))}
+	}
+	have, err := New(config, db).snapshot(pruned, 20, chain.headers[20].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to replay from the indexed snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(have.signers(), want.signers()) || !reflect.DeepEqual(have.Recents, want.Recents) || have.Hash != want.Hash {
+		t.Errorf("replayed snapshot mismatch: have %+v, want %+v", have, want)
+	}
+	// Without the index entry, the replay runs into the pruned headers
+	if err := unindexSnapshot(snapdb, 7, chain.headers[7].Hash()); err != nil {
+		t.Fatalf("failed to unindex snapshot: %v", err)
+	}
+	if _, err := New(config, db).snapshot(pruned, 20, chain.headers[20].Hash(), nil); err != consensus.ErrUnknownAncestor {
+		t.Errorf("unindexed replay error mismatch: have %v, want %v", err, consensus.ErrUnknownAncestor)
+	}
+}
+
+// Tests that the schema migration indexes the snapshots persisted before the
+// index existed, leaving out those of blocks unknown to the chain.
+func TestSnapshotIndexMigration(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B"}, 4)
+		db       = rawdb.NewMemoryDatabase()
+	)
+	namespace := snapshotNamespace(chain.headers[0].Hash())
+	table := rawdb.NewTable(db, namespace)
+
+	known := newSnapshot(config, nil, 3, chain.headers[3].Hash(), nil)
+	unknown := newSnapshot(config, nil, 3, types.EmptyRootHash, nil)
+	for _, snap := range []*Snapshot{known, unknown} {
+		if err := snap.store(table); err != nil {
+			t.Fatalf("failed to store snapshot: %v", err)
+		}
+		if err := unindexSnapshot(table, snap.Number, snap.Hash); err != nil {
+			t.Fatalf("failed to unindex snapshot: %v", err)
+		}
+	}
+	if err := migrateSnapshotIndex(chain, db, namespace); err != nil {
+		t.Fatalf("failed to migrate snapshot index: %v", err)
+	}
+	if !indexedSnapshot(table, 3, known.Hash) {
+		t.Errorf("known snapshot not indexed")
+	}
+	if indexedSnapshot(table, 3, unknown.Hash) {
+		t.Errorf("unknown snapshot indexed")
+	}
+}
+
+// indexScanningDB is a database counting the iterations over the snapshot index.
+type indexScanningDB struct {
+	ethdb.Database
+	scans int
+}
+
+func (db *indexScanningDB) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	if bytes.Contains(prefix, snapshotIndexPrefix) {
+		db.scans++
+	}
+	return db.Database.NewIterator(prefix, start)
+}
+
+// Tests that looking up the snapshots of ancestors doesn't scan the index, whose
+// size grows with the number of persisted snapshots.
+func TestSnapshotIndexLookup(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 20)
+		db       = &indexScanningDB{Database: rawdb.NewMemoryDatabase()}
+	)
+	engine := New(config, db)
+	defer engine.Close()
+
+	if _, err := engine.snapshot(chain, 20, chain.headers[20].Hash(), nil); err != nil {
+		t.Fatalf("failed to create snapshot: %v", err)
+	}
+	if db.scans != 0 {
+		t.Errorf("snapshot index scanned %d times", db.scans)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	snapshotRootVersion   = 1 // Version of the snapshot fact encoding committed to by checkpoints
+	governanceRootVersion = 2 // Version of the fact encoding also covering the signer limit cooldowns
+
+	// snapshotRootLength is the length of the snapshot root embedded at the end of
+	// the checkpoint payload: the version of the fact encoding, followed by the
+	// root. Its length differs from the signer list and aggregate attestation ones
+	// modulo the address length, so all sections can be told apart.
+	snapshotRootLength = 1 + common.HashLength
+)
+
+var (
+	// errMismatchingSnapshotRoot is returned if a checkpoint block commits to
+	// anything else than the snapshot root derived from its parent's state.
+	errMismatchingSnapshotRoot = errors.New("mismatching snapshot root on checkpoint block")
+
+	// errUnknownFact is returned if a proof is requested for a fact that doesn't
+	// hold in the snapshot, e.g. a signer that isn't authorized.
+	errUnknownFact = errors.New("fact not in snapshot")
+)
+
+// NumberLeaf returns the Merkle leaf stating the number of the checkpoint a
+// snapshot root was committed at.
+func NumberLeaf(number uint64) common.Hash {
+	var blob [8]byte
+	binary.BigEndian.PutUint64(blob[:], number)
+	return crypto.Keccak256Hash([]byte{0x00}, []byte("number"), blob[:])
+}
+
+// SignerLimitLeaf returns the Merkle leaf stating the signer limit in force after
+// a checkpoint, and the block it was last set or reaffirmed in.
+func SignerLimitLeaf(limit uint, affirmed uint64) common.Hash {
+	var blob [16]byte
+	binary.BigEndian.PutUint64(blob[:8], uint64(limit))
+	binary.BigEndian.PutUint64(blob[8:], affirmed)
+	return crypto.Keccak256Hash([]byte{0x00}, []byte("limit"), blob[:])
+}
+
+// SignerLeaf returns the Merkle leaf stating that an address is an authorized
+// signer after a checkpoint.
+func SignerLeaf(signer common.Address) common.Hash {
+	return crypto.Keccak256Hash([]byte{0x00}, []byte("signer"), signer[:])
+}
+
+// LimitWaitLeaf returns the Merkle leaf stating that voting on a signer limit is
+// barred until the given block after a checkpoint, following its recent change.
+func LimitWaitLeaf(limit uint64, until uint64) common.Hash {
+	var blob [16]byte
+	binary.BigEndian.PutUint64(blob[:8], limit)
+	binary.BigEndian.PutUint64(blob[8:], until)
+	return crypto.Keccak256Hash([]byte{0x00}, []byte("wait"), blob[:])
+}
+
+// merkleNode hashes two sibling nodes into their parent. Leaves and inner nodes
+// are domain separated to prevent second preimage attacks.
+func merkleNode(left, right common.Hash) common.Hash {
+	return crypto.Keccak256Hash([]byte{0x01}, left[:], right[:])
+}
+
+// snapshotFacts returns the Merkle leaves of the state after a checkpoint: its
+// number, its signer limit, its signers in ascending order and the signer limit
+// cooldowns in force in ascending limit order.
+func snapshotFacts(number uint64, limit uint, affirmed uint64, signers []common.Address, waits map[uint64]WaitTally) []common.Hash {
+	leaves := make([]common.Hash, 0, 2+len(signers)+len(waits))
+	leaves = append(leaves, NumberLeaf(number), SignerLimitLeaf(limit, affirmed))
+	for _, signer := range signers {
+		leaves = append(leaves, SignerLeaf(signer))
+	}
+	limits := make([]uint64, 0, len(waits))
+	for limit := range waits {
+		limits = append(limits, limit)
+	}
+	sort.Slice(limits, func(i, j int) bool { return limits[i] < limits[j] })
+	for _, limit := range limits {
+		leaves = append(leaves, LimitWaitLeaf(limit, waits[limit].Block))
+	}
+	return leaves
+}
+
+// rootWaits returns the signer limit cooldowns the root committed to by the
+// checkpoint with the given number covers: the ones still barring votes after it
+// from the governance root fork on, none before.
+func (s *Snapshot) rootWaits(number uint64) map[uint64]WaitTally {
+	if !s.config.IsGovernanceRoot(new(big.Int).SetUint64(number)) {
+		return nil
+	}
+	waits := make(map[uint64]WaitTally)
+	for limit, wait := range s.SignerLimitWait {
+		if wait.Block > number {
+			waits[limit] = wait
+		}
+	}
+	return waits
+}
+
+// merkleLayers builds the Merkle tree over the leaves, padded with empty leaves
+// to a power of two, returning all its layers from the leaves up to the root.
+func merkleLayers(leaves []common.Hash) [][]common.Hash {
+	size := 1
+	for size < len(leaves) {
+		size *= 2
+	}
+	layer := make([]common.Hash, size)
+	copy(layer, leaves)
+
+	layers := [][]common.Hash{layer}
+	for len(layer) > 1 {
+		next := make([]common.Hash, len(layer)/2)
+		for i := range next {
+			next[i] = merkleNode(layer[2*i], layer[2*i+1])
+		}
+		layers, layer = append(layers, next), next
+	}
+	return layers
+}
+
+// snapshotRoot calculates the Merkle root of the state after a checkpoint.
+func snapshotRoot(number uint64, limit uint, affirmed uint64, signers []common.Address, waits map[uint64]WaitTally) common.Hash {
+	layers := merkleLayers(snapshotFacts(number, limit, affirmed, signers, waits))
+	return layers[len(layers)-1][0]
+}
+
+// Root returns the Merkle root of the facts of the snapshot, as committed to by
+// the checkpoint it was taken at.
+func (s *Snapshot) Root() common.Hash {
+	return snapshotRoot(s.Number, s.SignerLimit, s.SignerLimitAffirmed, s.signers(), s.rootWaits(s.Number))
+}
+
+// checkpointRoot assembles the snapshot root section the checkpoint with the
+// given number must commit to, derived from the snapshot of its parent.
+func (s *Snapshot) checkpointRoot(number uint64) []byte {
+	limit, affirmed := s.epochLimit(number)
+	root := snapshotRoot(number, limit, affirmed, s.signers(), s.rootWaits(number))
+
+	if s.config.IsGovernanceRoot(new(big.Int).SetUint64(number)) {
+		return encodeSnapshotRoot(governanceRootVersion, root)
+	}
+	return encodeSnapshotRoot(snapshotRootVersion, root)
+}
+
+// SnapshotProof is a Merkle proof of a single fact of the snapshot taken at a
+// checkpoint, verifiable against the root committed to by the checkpoint header.
+type SnapshotProof struct {
+	Number   uint64        `json:"number"`   // Number of the checkpoint committing to the root
+	Hash     common.Hash   `json:"hash"`     // Hash of the checkpoint committing to the root
+	Root     common.Hash   `json:"root"`     // Merkle root of the snapshot facts
+	Leaf     common.Hash   `json:"leaf"`     // Leaf of the proven fact
+	Index    uint64        `json:"index"`    // Position of the leaf in the tree
+	Siblings []common.Hash `json:"siblings"` // Sibling nodes from the leaf up to the root
+}
+
+// Prove creates a Merkle proof of the given fact leaf of the snapshot.
+func (s *Snapshot) Prove(leaf common.Hash) (*SnapshotProof, error) {
+	leaves := snapshotFacts(s.Number, s.SignerLimit, s.SignerLimitAffirmed, s.signers(), s.rootWaits(s.Number))
+	index := -1
+	for i, fact := range leaves {
+		if fact == leaf {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errUnknownFact
+	}
+	layers := merkleLayers(leaves)
+	proof := &SnapshotProof{
+		Number: s.Number,
+		Hash:   s.Hash,
+		Root:   layers[len(layers)-1][0],
+		Leaf:   leaf,
+		Index:  uint64(index),
+	}
+	for _, layer := range layers[:len(layers)-1] {
+		proof.Siblings = append(proof.Siblings, layer[index^1])
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifySnapshotProof checks a Merkle proof of a snapshot fact against the root
+// committed to by a checkpoint header.
+func VerifySnapshotProof(root common.Hash, proof *SnapshotProof) bool {
+	if proof.Index>>uint(len(proof.Siblings)) != 0 {
+		return false
+	}
+	node, index := proof.Leaf, proof.Index
+	for _, sibling := range proof.Siblings {
+		if index%2 == 0 {
+			node = merkleNode(node, sibling)
+		} else {
+			node = merkleNode(sibling, node)
+		}
+		index /= 2
+	}
+	return node == root
+}
+
+// CheckpointRoot extracts the snapshot root committed to by a checkpoint header,
+// or returns false if the checkpoint doesn't commit to one.
+func CheckpointRoot(config *params.CliqueConfig, header *types.Header) (common.Hash, bool) {
+	if len(header.Extra) < extraVanity+extraSeal {
+		return common.Hash{}, false
+	}
+	_, _, blob := splitCheckpoint(config, header)
+	if blob == nil || (blob[0] != snapshotRootVersion && blob[0] != governanceRootVersion) {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(blob[1:]), true
+}
+
+// encodeSnapshotRoot assembles the snapshot root section of a checkpoint payload.
+func encodeSnapshotRoot(version byte, root common.Hash) []byte {
+	return append([]byte{version}, root[:]...)
+}
+
+// splitCheckpoint separates the extra-data payload of a checkpoint header into its
+// signer list, aggregate attestation and snapshot root.
+func splitCheckpoint(config *params.CliqueConfig, header *types.Header) ([]byte, []byte, []byte) {
+	return splitCheckpointPayload(headerPayload(config, header))
+}
+
+// splitCheckpointPayload separates the positional extra-data payload of a
+// checkpoint header into its signer list, aggregate attestation and snapshot
+// root, embedded in this order. The optional sections are told apart by the
+// payload length modulo the address length, which differs for every combination.
+func splitCheckpointPayload(payload []byte) ([]byte, []byte, []byte) {
+	var (
+		aggregate []byte
+		root      []byte
+	)
+	if rem := len(payload) % common.AddressLength; len(payload) >= snapshotRootLength && (rem == snapshotRootLength%common.AddressLength || rem == (snapshotRootLength+aggregateLength)%common.AddressLength) {
+		root = payload[len(payload)-snapshotRootLength:]
+		payload = payload[:len(payload)-snapshotRootLength]
+	}
+	if len(payload) >= aggregateLength && len(payload)%common.AddressLength == aggregateLength%common.AddressLength {
+		aggregate = payload[len(payload)-aggregateLength:]
+		payload = payload[:len(payload)-aggregateLength]
+	}
+	return payload, aggregate, root
+}
+
+// verifySnapshotRoot checks that a checkpoint commits to exactly the root of the
+// state derived from the snapshot of its parent, if snapshot roots are enabled.
+// Signer set overrides don't commit to any.
+func verifySnapshotRoot(snap *Snapshot, header *types.Header) error {
+	if isOverride(header) {
+		return nil
+	}
+	var want []byte
+	if snap.config.IsSnapshotRoot(header.Number) {
+		want = snap.checkpointRoot(header.Number.Uint64())
+	}
+	if _, _, root := splitCheckpoint(snap.config, header); !bytes.Equal(root, want) {
+		return errMismatchingSnapshotRoot
+	}
+	return nil
+}
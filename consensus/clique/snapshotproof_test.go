@@ -0,0 +1,146 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that every fact of a snapshot can be proven against its root, and that
+// tampered proofs or facts not holding are rejected.
+func TestSnapshotProofs(t *testing.T) {
+	for signers := 1; signers <= 9; signers++ {
+		addrs := make([]common.Address, signers)
+		for i := range addrs {
+			addrs[i] = common.Address{byte(i + 1)}
+		}
+		snap := newSnapshot(&params.CliqueConfig{Epoch: 30000}, nil, 0, common.Hash{}, addrs)
+		root := snap.Root()
+
+		facts := []common.Hash{NumberLeaf(0), SignerLimitLeaf(snap.SignerLimit, snap.SignerLimitAffirmed)}
+		for _, addr := range addrs {
+			facts = append(facts, SignerLeaf(addr))
+		}
+		for _, fact := range facts {
+			proof, err := snap.Prove(fact)
+			if err != nil {
+				t.Fatalf("signers %d: failed to prove fact %x: %v", signers, fact, err)
+			}
+			if proof.Root != root || !VerifySnapshotProof(root, proof) {
+				t.Errorf("signers %d: proof of fact %x rejected", signers, fact)
+			}
+			proof.Index ^= 1
+			if VerifySnapshotProof(root, proof) {
+				t.Errorf("signers %d: tampered proof of fact %x accepted", signers, fact)
+			}
+		}
+		if _, err := snap.Prove(SignerLeaf(common.Address{0xff})); err != errUnknownFact {
+			t.Errorf("signers %d: unauthorized signer proof error mismatch: have %v, want %v", signers, err, errUnknownFact)
+		}
+	}
+}
+
+// Tests that checkpoints commit to the snapshot root of the state following them
+// after the fork, and that the root is told apart from the other sections.
+func TestCheckpointSnapshotRoot(t *testing.T) {
+	addrs := []common.Address{{0x01}, {0x02}, {0x03}}
+	config := &params.CliqueConfig{Epoch: 4, SnapshotRootBlock: big.NewInt(4)}
+	snap := newSnapshot(config, nil, 3, common.Hash{}, addrs)
+
+	list := make([]byte, 0, len(addrs)*common.AddressLength)
+	for _, addr := range addrs {
+		list = append(list, addr[:]...)
+	}
+	checkpoint := func(sections ...[]byte) *types.Header {
+		extra := append(make([]byte, extraVanity), list...)
+		for _, section := range sections {
+			extra = append(extra, section...)
+		}
+		return &types.Header{Number: big.NewInt(4), Extra: append(extra, make([]byte, extraSeal)...)}
+	}
+	limit, affirmed := snap.epochLimit(4)
+	root := snapshotRoot(4, limit, affirmed, snap.signers(), nil)
+
+	if err := verifySnapshotRoot(snap, checkpoint(encodeSnapshotRoot(snapshotRootVersion, root))); err != nil {
+		t.Errorf("failed to verify committed root: %v", err)
+	}
+	if err := verifySnapshotRoot(snap, checkpoint()); err != errMismatchingSnapshotRoot {
+		t.Errorf("missing root error mismatch: have %v, want %v", err, errMismatchingSnapshotRoot)
+	}
+	if err := verifySnapshotRoot(snap, checkpoint(encodeSnapshotRoot(snapshotRootVersion, common.Hash{0x01}))); err != errMismatchingSnapshotRoot {
+		t.Errorf("wrong root error mismatch: have %v, want %v", err, errMismatchingSnapshotRoot)
+	}
+	// The root must be extractable alongside an aggregate attestation
+	aggregate := bytes.Repeat([]byte{0xaa}, aggregateLength)
+	header := checkpoint(aggregate, encodeSnapshotRoot(snapshotRootVersion, root))
+	if have, ok := CheckpointRoot(snap.config, header); !ok || have != root {
+		t.Errorf("root mismatch: have %x, want %x", have, root)
+	}
+	if signers, blob, _ := splitCheckpoint(snap.config, header); !bytes.Equal(signers, list) || !bytes.Equal(blob, aggregate) {
+		t.Errorf("checkpoint sections mismatch")
+	}
+	// The root of the checkpoint snapshot must match the committed one
+	applied := newSnapshot(config, nil, 4, common.Hash{}, addrs)
+	applied.SignerLimit, applied.SignerLimitAffirmed = limit, affirmed
+	if applied.Root() != root {
+		t.Errorf("snapshot root mismatch: have %x, want %x", applied.Root(), root)
+	}
+}
+
+// Tests that from the governance root fork on, checkpoint roots commit to the
+// signer limit cooldowns in force after the checkpoint, and only to those.
+func TestGovernanceRoot(t *testing.T) {
+	config := &params.CliqueConfig{Epoch: 4, SnapshotRootBlock: common.Big0, GovernanceRootBlock: common.Big0}
+	snap := newSnapshot(config, nil, 3, common.Hash{}, []common.Address{{0x01}, {0x02}})
+	snap.SignerLimitWait[66] = WaitTally{Block: 10}
+	snap.SignerLimitWait[75] = WaitTally{Block: 2}
+
+	section := snap.checkpointRoot(4)
+	if section[0] != governanceRootVersion {
+		t.Fatalf("root version mismatch: have %d, want %d", section[0], governanceRootVersion)
+	}
+	bare := snapshotRoot(4, snap.SignerLimit, snap.SignerLimitAffirmed, snap.signers(), nil)
+	if common.BytesToHash(section[1:]) == bare {
+		t.Errorf("root doesn't commit to the cooldowns in force")
+	}
+	extra := append(make([]byte, extraVanity), common.Address{0x01}.Bytes()...)
+	extra = append(extra, common.Address{0x02}.Bytes()...)
+	extra = append(extra, encodeSnapshotRoot(snapshotRootVersion, bare)...)
+
+	header := &types.Header{Number: big.NewInt(4), Extra: append(extra, make([]byte, extraSeal)...)}
+	if err := verifySnapshotRoot(snap, header); err != errMismatchingSnapshotRoot {
+		t.Errorf("legacy root error mismatch: have %v, want %v", err, errMismatchingSnapshotRoot)
+	}
+	// The snapshot taken at the checkpoint proves the cooldown still in force
+	snap.Number = 4
+	if root := snap.Root(); root != common.BytesToHash(section[1:]) {
+		t.Fatalf("checkpoint snapshot root mismatch: have %x, want %x", root, section[1:])
+	}
+	proof, err := snap.Prove(LimitWaitLeaf(66, 10))
+	if err != nil || !VerifySnapshotProof(snap.Root(), proof) {
+		t.Errorf("failed to prove cooldown in force: %v", err)
+	}
+	if _, err := snap.Prove(LimitWaitLeaf(75, 2)); err != errUnknownFact {
+		t.Errorf("lapsed cooldown proof error mismatch: have %v, want %v", err, errUnknownFact)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// State is a self-contained view of the consensus state at a block, combining the
+// voting snapshot with the proposals the local signer is pushing. It's meant for
+// external monitoring and governance services, hence it shares no memory with
+// the engine.
+type State struct {
+	Number         uint64                       `json:"number"`
+	Hash           common.Hash                  `json:"hash"`
+	Signers        []common.Address             `json:"signers"`
+	Recents        map[uint64]common.Address    `json:"recents"`
+	Tally          map[common.Address]Tally     `json:"tally"`
+	SignerLimit    uint                         `json:"signerLimit"`
+	LimitTally     map[uint]LimitTally          `json:"limitTally"`
+	Proposals      map[common.Address]bool      `json:"proposals"`
+	LimitProposals map[uint]bool                `json:"limitProposals"`
+	Seals          map[common.Address]SealStats `json:"seals"`
+}
+
+// State retrieves the consensus state at the given header.
+func (c *Clique) State(chain consensus.ChainHeaderReader, header *types.Header) (*State, error) {
+	snap, err := c.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{
+		Number:         snap.Number,
+		Hash:           snap.Hash,
+		Signers:        snap.SignerList(),
+		Recents:        make(map[uint64]common.Address, len(snap.Recents)),
+		Tally:          make(map[common.Address]Tally, len(snap.Tally)),
+		SignerLimit:    snap.Limit(),
+		LimitTally:     snap.LimitTallies(),
+		Proposals:      make(map[common.Address]bool),
+		LimitProposals: make(map[uint]bool),
+		Seals:          c.seals.Stats(),
+	}
+	for number, signer := range snap.Recents {
+		state.Recents[number] = signer
+	}
+	for address, tally := range snap.Tally {
+		state.Tally[address] = tally
+	}
+	c.lock.RLock()
+	for address, auth := range c.proposals {
+		state.Proposals[address] = auth
+	}
+	for limit, auth := range c.signerLimitProposals {
+		state.LimitProposals[limit] = auth
+	}
+	c.lock.RUnlock()
+
+	return state, nil
+}
+
+// SubscribeState registers a subscription for the consensus state of every new
+// canonical chain head the engine is anchored on.
+func (c *Clique) SubscribeState(ch chan<- *State) event.Subscription {
+	return c.scope.Track(c.stateFeed.Subscribe(ch))
+}
+
+// publishState sends the consensus state of a new chain head to all subscribers,
+// skipping the state assembly if nobody is listening.
+func (c *Clique) publishState(chain consensus.ChainHeaderReader, head *types.Header) {
+	if c.scope.Count() == 0 {
+		return
+	}
+	state, err := c.State(chain, head)
+	if err != nil {
+		log.Warn("Failed to assemble clique state", "number", head.Number, "hash", head.Hash(), "err", err)
+		return
+	}
+	c.stateFeed.Send(state)
+}
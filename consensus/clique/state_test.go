@@ -0,0 +1,157 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the consensus state of every new chain head is streamed to the
+// subscribers and doesn't alias the engine's internal state.
+func TestStateSubscription(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000}
+	)
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, []string{"A"})
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	engine := New(config, rawdb.NewMemoryDatabase())
+	engine.proposals[accounts.address("B")] = true
+
+	states := make(chan *State, 1)
+	sub := engine.SubscribeState(states)
+	defer sub.Unsubscribe()
+
+	for i := 1; i <= 2; i++ {
+		header := &types.Header{
+			ParentHash: chain.headers[i-1].Hash(),
+			Number:     big.NewInt(int64(i)),
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		// Authorize B in the first block, then let it seal the next one
+		sealer := "B"
+		if i == 1 {
+			sealer, header.Coinbase = "A", accounts.address("B")
+			copy(header.Nonce[:], nonceAuthVote)
+		}
+		accounts.sign(header, sealer)
+		chain.headers = append(chain.headers, header)
+
+		engine.NewChainHead(chain, header)
+		select {
+		case state := <-states:
+			if state.Hash != header.Hash() {
+				t.Fatalf("block %d: state hash mismatch: have %x, want %x", i, state.Hash, header.Hash())
+			}
+			if len(state.Signers) != 2 || !state.Proposals[accounts.address("B")] {
+				t.Fatalf("block %d: state mismatch: %+v", i, state)
+			}
+			// Mutate the received state and ensure the engine is unaffected
+			state.Recents[0] = common.Address{}
+			state.Proposals[accounts.address("C")] = true
+		case <-time.After(time.Second):
+			t.Fatalf("block %d: state not published", i)
+		}
+	}
+	if _, ok := engine.proposals[accounts.address("C")]; ok {
+		t.Errorf("streamed proposals alias the engine's")
+	}
+	snap, _ := engine.snapshot(chain, 2, chain.headers[2].Hash(), nil)
+	if _, ok := snap.Recents[0]; ok {
+		t.Errorf("streamed recents alias the snapshot's")
+	}
+}
+
+// Tests that the consensus state is streamed over the clique_subscribe RPC
+// endpoint, and that a stalled subscriber doesn't hold up the head lock.
+func TestStateRPCSubscription(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000}
+		chain    = newTesterSignedChain(accounts, config, []string{"A"}, 2)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	server := rpc.NewServer()
+	defer server.Stop()
+
+	if err := server.RegisterName("clique", &API{chain: chain, clique: engine}); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	states := make(chan *State)
+	sub, err := client.Subscribe(context.Background(), "clique", states, "newState")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Wait for the subscription to be registered with the engine
+	for engine.scope.Count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	head := chain.headers[1]
+	go engine.NewChainHead(chain, head)
+
+	select {
+	case state := <-states:
+		if state.Hash != head.Hash() || len(state.Signers) != 1 {
+			t.Fatalf("state mismatch: %+v", state)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("state not streamed")
+	}
+	// Subscribe without ever reading and ensure new heads are still processed
+	stalled := make(chan *State)
+	stalledSub := engine.SubscribeState(stalled)
+	defer stalledSub.Unsubscribe()
+
+	go engine.NewChainHead(chain, chain.headers[2])
+	<-states
+
+	locked := make(chan struct{})
+	go func() {
+		engine.headLock.Lock()
+		engine.headLock.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatalf("stalled subscriber holds up the head lock")
+	}
+	<-stalled
+}
@@ -0,0 +1,107 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// applyTrigger names the consensus path a snapshot reconstruction is done for,
+// labelling its timing metrics.
+type applyTrigger string
+
+const (
+	triggerSync    applyTrigger = "sync"    // Header verification and chain maintenance
+	triggerSealing applyTrigger = "sealing" // Block preparation and sealing
+	triggerRPC     applyTrigger = "rpc"     // API and health queries
+)
+
+// applyMetrics are the timing metrics of the snapshot reconstructions done for
+// a single trigger.
+type applyMetrics struct {
+	time      metrics.Timer     // Duration of the reconstructions
+	headers   metrics.Histogram // Number of headers applied per reconstruction
+	ecrecover metrics.Timer     // Latency of the signer recoveries missing the sigcache
+	hitratio  metrics.Histogram // Percentage of the signers found in the sigcache per reconstruction
+}
+
+var triggerMetrics = map[applyTrigger]*applyMetrics{
+	triggerSync:    newApplyMetrics(triggerSync),
+	triggerSealing: newApplyMetrics(triggerSealing),
+	triggerRPC:     newApplyMetrics(triggerRPC),
+}
+
+// newApplyMetrics registers the timing metrics of a trigger.
+func newApplyMetrics(trigger applyTrigger) *applyMetrics {
+	return &applyMetrics{
+		time:      metrics.NewRegisteredTimer("clique/apply/"+string(trigger)+"/time", nil),
+		headers:   metrics.NewRegisteredHistogram("clique/apply/"+string(trigger)+"/headers", nil, metrics.NewExpDecaySample(1028, 0.015)),
+		ecrecover: metrics.NewRegisteredTimer("clique/ecrecover/"+string(trigger)+"/time", nil),
+		hitratio:  metrics.NewRegisteredHistogram("clique/sigcache/"+string(trigger)+"/hitratio", nil, metrics.NewExpDecaySample(1028, 0.015)),
+	}
+}
+
+// applyStats tallies the signer recoveries of a single snapshot reconstruction.
+// A nil tally recovers the signers without recording anything.
+type applyStats struct {
+	metrics *applyMetrics
+	hits    int // Signers found in the sigcache
+	misses  int // Signers recovered from the seals
+}
+
+// newApplyStats creates the tally of a snapshot reconstruction done for the
+// given trigger.
+func newApplyStats(trigger applyTrigger) *applyStats {
+	return &applyStats{metrics: triggerMetrics[trigger]}
+}
+
+// recover resolves the signer of a header through the sigcache, timing the seal
+// recoveries on misses.
+func (st *applyStats) recover(header *types.Header, sigcache *SigCache) (common.Address, error) {
+	if st == nil {
+		return ecrecover(header, sigcache)
+	}
+	hash := header.Hash()
+	if signer, known := sigcache.Get(hash); known {
+		st.hits++
+		return signer, nil
+	}
+	st.misses++
+
+	start := time.Now()
+	signer, err := recoverSeal(header)
+	st.metrics.ecrecover.UpdateSince(start)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sigcache.Add(hash, signer)
+	return signer, nil
+}
+
+// done records the duration and size of the finished reconstruction, along
+// with the share of its signers found in the sigcache.
+func (st *applyStats) done(start time.Time, headers int) {
+	st.metrics.time.UpdateSince(start)
+	st.metrics.headers.Update(int64(headers))
+	if total := st.hits + st.misses; total > 0 {
+		st.metrics.hitratio.Update(int64(100 * st.hits / total))
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the signer recoveries of a snapshot reconstruction are tallied into
+// sigcache hits and seal recoveries.
+func TestApplyStats(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 10)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	defer engine.Close()
+
+	genesis, err := engine.snapshot(chain, 0, chain.headers[0].Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to create genesis snapshot: %v", err)
+	}
+	cold := newApplyStats(triggerSync)
+	if _, err := genesis.applyWith(chain.headers[1:], cold); err != nil {
+		t.Fatalf("failed to apply headers: %v", err)
+	}
+	if cold.hits != 0 || cold.misses != 10 {
+		t.Errorf("cold tally mismatch: have %d hits, %d misses, want 0, 10", cold.hits, cold.misses)
+	}
+	warm := newApplyStats(triggerRPC)
+	if _, err := genesis.applyWith(chain.headers[1:], warm); err != nil {
+		t.Fatalf("failed to reapply headers: %v", err)
+	}
+	if warm.hits != 10 || warm.misses != 0 {
+		t.Errorf("warm tally mismatch: have %d hits, %d misses, want 10, 0", warm.hits, warm.misses)
+	}
+	warm.done(time.Now(), 10)
+}
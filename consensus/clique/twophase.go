@@ -0,0 +1,103 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Opening is a membership proposal opened by a first vote under two-phase voting,
+// awaiting confirmation by the signers within the confirmation window.
+type Opening struct {
+	Signer    common.Address `json:"signer"`    // Signer that opened the proposal
+	Block     uint64         `json:"block"`     // Block number the proposal was opened in
+	Address   common.Address `json:"address"`   // Account the proposal is about
+	Authorize bool           `json:"authorize"` // Whether the proposal is to authorize or deauthorize the account
+}
+
+// twoPhase returns whether membership proposals must be opened before they can
+// be confirmed by the signers.
+func (s *Snapshot) twoPhase() bool {
+	return s.config.ConfirmWindow > 0
+}
+
+// opening retrieves the open proposal on the given account, if any.
+func (s *Snapshot) opening(address common.Address) *Opening {
+	for _, open := range s.Openings {
+		if open.Address == address {
+			return open
+		}
+	}
+	return nil
+}
+
+// openProposal opens a membership proposal, replacing any stale opening on the
+// same account.
+func (s *Snapshot) openProposal(number uint64, signer, address common.Address, authorize bool) {
+	s.closeProposal(address)
+
+	// Openings are shared between snapshot copies, never modify in place
+	s.Openings = append(append(make([]*Opening, 0, len(s.Openings)+1), s.Openings...), &Opening{
+		Signer:    signer,
+		Block:     number,
+		Address:   address,
+		Authorize: authorize,
+	})
+}
+
+// closeProposal removes the opening on the given account, if any.
+func (s *Snapshot) closeProposal(address common.Address) {
+	for i, open := range s.Openings {
+		if open.Address == address {
+			openings := make([]*Opening, 0, len(s.Openings)-1)
+			s.Openings = append(append(openings, s.Openings[:i]...), s.Openings[i+1:]...)
+			return
+		}
+	}
+}
+
+// expireOpenings closes the proposals not confirmed within the confirmation
+// window ending at the given block, discarding the confirmations cast on them.
+func (s *Snapshot) expireOpenings(number uint64) {
+	for i := 0; i < len(s.Openings); i++ {
+		open := s.Openings[i]
+		if open.Block+s.config.ConfirmWindow >= number {
+			continue
+		}
+		for j := 0; j < len(s.Votes); j++ {
+			if vote := s.Votes[j]; vote.Address == open.Address && vote.Authorize == open.Authorize {
+				s.uncast(vote.Address, vote.Authorize)
+
+				s.writable(cowVotes)
+				s.Votes = append(s.Votes[:j], s.Votes[j+1:]...)
+				j--
+			}
+		}
+		s.closeProposal(open.Address)
+		i--
+	}
+}
+
+// OpenProposals retrieves the membership proposals awaiting confirmation under
+// two-phase voting.
+func (s *Snapshot) OpenProposals() []Opening {
+	openings := make([]Opening, len(s.Openings))
+	for i, open := range s.Openings {
+		openings[i] = *open
+	}
+	return openings
+}
@@ -0,0 +1,353 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// upstreamSnapshot is the voting snapshot of the stock go-ethereum clique engine,
+// kept verbatim as a reference to check that the extensions of this engine don't
+// alter the baseline semantics when they are disabled.
+type upstreamSnapshot struct {
+	config   *params.CliqueConfig
+	sigcache *SigCache
+
+	Number  uint64
+	Hash    common.Hash
+	Signers map[common.Address]struct{}
+	Recents map[uint64]common.Address
+	Votes   []*Vote
+	Tally   map[common.Address]Tally
+}
+
+// newUpstreamSnapshot creates a new stock snapshot with the specified startup
+// parameters.
+func newUpstreamSnapshot(config *params.CliqueConfig, sigcache *SigCache, number uint64, hash common.Hash, signers []common.Address) *upstreamSnapshot {
+	snap := &upstreamSnapshot{
+		config:   config,
+		sigcache: sigcache,
+		Number:   number,
+		Hash:     hash,
+		Signers:  make(map[common.Address]struct{}),
+		Recents:  make(map[uint64]common.Address),
+		Tally:    make(map[common.Address]Tally),
+	}
+	for _, signer := range signers {
+		snap.Signers[signer] = struct{}{}
+	}
+	return snap
+}
+
+// copy creates a deep copy of the stock snapshot.
+func (s *upstreamSnapshot) copy() *upstreamSnapshot {
+	cpy := &upstreamSnapshot{
+		config:   s.config,
+		sigcache: s.sigcache,
+		Number:   s.Number,
+		Hash:     s.Hash,
+		Signers:  make(map[common.Address]struct{}),
+		Recents:  make(map[uint64]common.Address),
+		Votes:    make([]*Vote, len(s.Votes)),
+		Tally:    make(map[common.Address]Tally),
+	}
+	for signer := range s.Signers {
+		cpy.Signers[signer] = struct{}{}
+	}
+	for block, signer := range s.Recents {
+		cpy.Recents[block] = signer
+	}
+	for address, tally := range s.Tally {
+		cpy.Tally[address] = tally
+	}
+	copy(cpy.Votes, s.Votes)
+
+	return cpy
+}
+
+// validVote returns whether it makes sense to cast the specified vote in the
+// given stock snapshot context.
+func (s *upstreamSnapshot) validVote(address common.Address, authorize bool) bool {
+	_, signer := s.Signers[address]
+	return (signer && !authorize) || (!signer && authorize)
+}
+
+// cast adds a new vote into the tally.
+func (s *upstreamSnapshot) cast(address common.Address, authorize bool) bool {
+	if !s.validVote(address, authorize) {
+		return false
+	}
+	if old, ok := s.Tally[address]; ok {
+		old.Votes++
+		s.Tally[address] = old
+	} else {
+		s.Tally[address] = Tally{Authorize: authorize, Votes: 1}
+	}
+	return true
+}
+
+// uncast removes a previously cast vote from the tally.
+func (s *upstreamSnapshot) uncast(address common.Address, authorize bool) bool {
+	tally, ok := s.Tally[address]
+	if !ok {
+		return false
+	}
+	if tally.Authorize != authorize {
+		return false
+	}
+	if tally.Votes > 1 {
+		tally.Votes--
+		s.Tally[address] = tally
+	} else {
+		delete(s.Tally, address)
+	}
+	return true
+}
+
+// apply creates a new stock snapshot by applying the given headers to the
+// original one.
+func (s *upstreamSnapshot) apply(headers []*types.Header) (*upstreamSnapshot, error) {
+	if len(headers) == 0 {
+		return s, nil
+	}
+	for i := 0; i < len(headers)-1; i++ {
+		if headers[i+1].Number.Uint64() != headers[i].Number.Uint64()+1 {
+			return nil, errInvalidVotingChain
+		}
+	}
+	if headers[0].Number.Uint64() != s.Number+1 {
+		return nil, errInvalidVotingChain
+	}
+	snap := s.copy()
+
+	for _, header := range headers {
+		// Remove any votes on checkpoint blocks
+		number := header.Number.Uint64()
+		if number%s.config.Epoch == 0 {
+			snap.Votes = nil
+			snap.Tally = make(map[common.Address]Tally)
+		}
+		// Delete the oldest signer from the recent list to allow it signing again
+		if limit := uint64(len(snap.Signers)/2 + 1); number >= limit {
+			delete(snap.Recents, number-limit)
+		}
+		// Resolve the authorization key and check against signers
+		signer, err := ecrecover(header, s.sigcache)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := snap.Signers[signer]; !ok {
+			return nil, errUnauthorizedSigner
+		}
+		for _, recent := range snap.Recents {
+			if recent == signer {
+				return nil, errRecentlySigned
+			}
+		}
+		snap.Recents[number] = signer
+
+		// Header authorized, discard any previous votes from the signer
+		for i, vote := range snap.Votes {
+			if vote.Signer == signer && vote.Address == header.Coinbase {
+				snap.uncast(vote.Address, vote.Authorize)
+				snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+				break
+			}
+		}
+		// Tally up the new vote from the signer
+		var authorize bool
+		switch {
+		case bytes.Equal(header.Nonce[:], nonceAuthVote):
+			authorize = true
+		case bytes.Equal(header.Nonce[:], nonceDropVote):
+			authorize = false
+		default:
+			return nil, errInvalidVote
+		}
+		if snap.cast(header.Coinbase, authorize) {
+			snap.Votes = append(snap.Votes, &Vote{
+				Signer:    signer,
+				Block:     number,
+				Address:   header.Coinbase,
+				Authorize: authorize,
+			})
+		}
+		// If the vote passed, update the list of signers
+		if tally := snap.Tally[header.Coinbase]; tally.Votes > len(snap.Signers)/2 {
+			if tally.Authorize {
+				snap.Signers[header.Coinbase] = struct{}{}
+			} else {
+				delete(snap.Signers, header.Coinbase)
+
+				// Signer list shrunk, delete any leftover recent caches
+				if limit := uint64(len(snap.Signers)/2 + 1); number >= limit {
+					delete(snap.Recents, number-limit)
+				}
+				// Discard any previous votes the deauthorized signer cast
+				for i := 0; i < len(snap.Votes); i++ {
+					if snap.Votes[i].Signer == header.Coinbase {
+						snap.uncast(snap.Votes[i].Address, snap.Votes[i].Authorize)
+						snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+						i--
+					}
+				}
+			}
+			// Discard any previous votes around the just changed account
+			for i := 0; i < len(snap.Votes); i++ {
+				if snap.Votes[i].Address == header.Coinbase {
+					snap.Votes = append(snap.Votes[:i], snap.Votes[i+1:]...)
+					i--
+				}
+			}
+			delete(snap.Tally, header.Coinbase)
+		}
+	}
+	snap.Number += uint64(len(headers))
+	snap.Hash = headers[len(headers)-1].Hash()
+
+	return snap, nil
+}
+
+// newUpstreamHeaderStream generates a random stream of valid membership votes,
+// only sealed by signers allowed to seal by the stock spam protection.
+func newUpstreamHeaderStream(rng *rand.Rand, accounts *testerAccountPool, names []string, signers []string, epoch uint64, length int) []*types.Header {
+	genesis := make([]common.Address, len(signers))
+	for i, signer := range signers {
+		genesis[i] = accounts.address(signer)
+	}
+	snap := newUpstreamSnapshot(&params.CliqueConfig{Epoch: epoch}, NewSigCache(inmemorySignatures, nil), 0, common.Hash{}, genesis)
+
+	var headers []*types.Header
+	for i := 1; i <= length; i++ {
+		number := uint64(i)
+
+		// Pick a random signer not excluded by the recents window
+		var sealers []string
+		for _, name := range names {
+			if _, ok := snap.Signers[accounts.address(name)]; !ok {
+				continue
+			}
+			limit := uint64(len(snap.Signers)/2 + 1)
+			recent := false
+			for seen, signer := range snap.Recents {
+				if signer == accounts.address(name) && (number < limit || seen > number-limit) {
+					recent = true
+				}
+			}
+			if !recent {
+				sealers = append(sealers, name)
+			}
+		}
+		header := &types.Header{
+			Number:     big.NewInt(int64(i)),
+			Time:       number,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i > 1 {
+			header.ParentHash = headers[i-2].Hash()
+		}
+		if number%epoch == 0 {
+			var checkpoint []string
+			for _, name := range names {
+				if _, ok := snap.Signers[accounts.address(name)]; ok {
+					checkpoint = append(checkpoint, name)
+				}
+			}
+			header.Extra = make([]byte, extraVanity+len(checkpoint)*common.AddressLength+extraSeal)
+			accounts.checkpoint(header, checkpoint)
+		} else if rng.Intn(3) > 0 {
+			// Vote on a random account, keeping at least two signers around
+			header.Coinbase = accounts.address(names[rng.Intn(len(names))])
+			if _, ok := snap.Signers[header.Coinbase]; !ok || len(snap.Signers) <= 2 || rng.Intn(4) == 0 {
+				copy(header.Nonce[:], nonceAuthVote)
+			}
+		}
+		accounts.sign(header, sealers[rng.Intn(len(sealers))])
+
+		var err error
+		if snap, err = snap.apply([]*types.Header{header}); err != nil {
+			panic(fmt.Sprintf("failed to generate header %d: %v", i, err))
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// Tests that with all its extensions disabled, the engine tallies membership
+// votes identically to the stock go-ethereum clique engine over random streams
+// of headers.
+func TestUpstreamEquivalence(t *testing.T) {
+	var (
+		names   = []string{"A", "B", "C", "D", "E", "F", "G"}
+		signers = []string{"A", "B", "C"}
+	)
+	for seed := int64(0); seed < 16; seed++ {
+		var (
+			rng      = rand.New(rand.NewSource(seed))
+			accounts = newTesterAccountPool()
+			config   = &params.CliqueConfig{Epoch: 10}
+			headers  = newUpstreamHeaderStream(rng, accounts, names, signers, config.Epoch, 300)
+		)
+		genesis := make([]common.Address, len(signers))
+		for i, signer := range signers {
+			genesis[i] = accounts.address(signer)
+		}
+		var (
+			want = newUpstreamSnapshot(config, NewSigCache(inmemorySignatures, nil), 0, common.Hash{}, genesis)
+			have = newSnapshot(config, NewSigCache(inmemorySignatures, nil), 0, common.Hash{}, genesis)
+		)
+		for i, header := range headers {
+			var err error
+			if want, err = want.apply([]*types.Header{header}); err != nil {
+				t.Fatalf("seed %d, block %d: failed to apply stock header: %v", seed, i+1, err)
+			}
+			if have, err = have.apply([]*types.Header{header}); err != nil {
+				t.Fatalf("seed %d, block %d: failed to apply header: %v", seed, i+1, err)
+			}
+			if !reflect.DeepEqual(have.Signers, want.Signers) {
+				t.Fatalf("seed %d, block %d: signers mismatch: have %v, want %v", seed, i+1, have.signers(), want.Signers)
+			}
+			if !reflect.DeepEqual(have.Recents, want.Recents) {
+				t.Fatalf("seed %d, block %d: recents mismatch: have %v, want %v", seed, i+1, have.Recents, want.Recents)
+			}
+			if !reflect.DeepEqual(have.Tally, want.Tally) {
+				t.Fatalf("seed %d, block %d: tally mismatch: have %v, want %v", seed, i+1, have.Tally, want.Tally)
+			}
+			if len(have.Votes) != len(want.Votes) {
+				t.Fatalf("seed %d, block %d: vote count mismatch: have %d, want %d", seed, i+1, len(have.Votes), len(want.Votes))
+			}
+			for j := range want.Votes {
+				if *have.Votes[j] != *want.Votes[j] {
+					t.Fatalf("seed %d, block %d: vote %d mismatch: have %+v, want %+v", seed, i+1, j, have.Votes[j], want.Votes[j])
+				}
+			}
+			if have.Hash != want.Hash || have.Number != want.Number {
+				t.Fatalf("seed %d, block %d: head mismatch: have #%d [%x], want #%d [%x]", seed, i+1, have.Number, have.Hash, want.Number, want.Hash)
+			}
+		}
+	}
+}
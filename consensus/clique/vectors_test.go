@@ -0,0 +1,238 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// updateVectors regenerates the snapshot test vectors instead of checking them:
+//
+//	go test ./consensus/clique -run TestSnapshotVectors -update-vectors
+var updateVectors = flag.Bool("update-vectors", false, "regenerate the snapshot test vectors")
+
+// vectorsDir is the directory the snapshot test vectors are committed to.
+var vectorsDir = filepath.Join("testdata", "vectors")
+
+// snapshotVector is a golden test vector of the voting snapshot resulting from
+// applying a sequence of headers on top of a genesis signer set.
+type snapshotVector struct {
+	Config   *params.CliqueConfig `json:"config"`   // Consensus parameters the headers are applied with
+	Signers  []common.Address     `json:"signers"`  // Signers authorized in the genesis
+	Genesis  common.Hash          `json:"genesis"`  // Hash of the genesis block the headers build on
+	Headers  []*types.Header      `json:"headers"`  // Headers applied on top of the genesis
+	Snapshot json.RawMessage      `json:"snapshot"` // Canonical encoding of the resulting snapshot
+}
+
+// vectorScenario is a voting scenario the snapshot test vectors are generated from.
+type vectorScenario struct {
+	name    string
+	config  params.CliqueConfig
+	signers []string
+	votes   []testerVote
+}
+
+// vectorScenarios are the voting scenarios covered by the snapshot test vectors.
+var vectorScenarios = []vectorScenario{
+	{
+		// Two out of three signers pass a signer limit vote
+		name:    "limit-votes",
+		config:  params.CliqueConfig{Epoch: 30000, SignerLimit: 50, RecentsWindowBlock: common.Big0},
+		signers: []string{"A", "B", "C"},
+		votes:   []testerVote{{signer: "A", limit: 75}, {signer: "B", limit: 75}, {signer: "C"}},
+	}, {
+		// A passed limit starts a wait tally while another limit is voted on
+		name:    "limit-wait",
+		config:  params.CliqueConfig{Epoch: 30000, SignerLimit: 50, RecentsWindowBlock: common.Big0},
+		signers: []string{"A", "B", "C"},
+		votes: []testerVote{
+			{signer: "A", limit: 75}, {signer: "B", limit: 75}, {signer: "C", limit: 60},
+			{signer: "A"}, {signer: "B", limit: 60},
+		},
+	}, {
+		// A pending limit vote is dropped at the epoch, the passed limit reverts
+		// at the next one as it's not reaffirmed
+		name:    "epoch-reset",
+		config:  params.CliqueConfig{Epoch: 4, SignerLimitReset: true},
+		signers: []string{"A", "B", "C"},
+		votes: []testerVote{
+			{signer: "A", limit: 75}, {signer: "B", limit: 75}, {signer: "C", limit: 60},
+			{signer: "A", checkpoint: []string{"A", "B", "C"}},
+			{signer: "B"}, {signer: "C"}, {signer: "A"},
+			{signer: "B", checkpoint: []string{"A", "B", "C"}},
+		},
+	}, {
+		// Three out of four signers deauthorize the fourth one
+		name:    "deauthorization",
+		config:  params.CliqueConfig{Epoch: 30000},
+		signers: []string{"A", "B", "C", "D"},
+		votes: []testerVote{
+			{signer: "A", voted: "D"}, {signer: "B", voted: "D"}, {signer: "C", voted: "D"},
+			{signer: "A"}, {signer: "B"},
+		},
+	}, {
+		// Two out of two signers authorize a third one, which starts voting
+		name:    "authorization",
+		config:  params.CliqueConfig{Epoch: 30000},
+		signers: []string{"A", "B"},
+		votes: []testerVote{
+			{signer: "A", voted: "C", auth: true}, {signer: "B", voted: "C", auth: true},
+			{signer: "C", voted: "D", auth: true}, {signer: "A"},
+		},
+	},
+}
+
+// newVectorAccountPool creates a tester account pool deriving the keys from the
+// account names, so the generated vectors are reproducible.
+func newVectorAccountPool() *testerAccountPool {
+	accounts := newTesterAccountPool()
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		accounts.accounts[name], _ = crypto.ToECDSA(crypto.Keccak256([]byte(name)))
+	}
+	return accounts
+}
+
+// generate creates the headers of the scenario and the snapshot they result in.
+func (s *vectorScenario) generate() (*snapshotVector, error) {
+	accounts := newVectorAccountPool()
+
+	vector := &snapshotVector{Config: &s.config}
+	for _, signer := range s.signers {
+		vector.Signers = append(vector.Signers, accounts.address(signer))
+	}
+	sort.Sort(signersAscending(vector.Signers))
+
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(s.signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, s.signers)
+	vector.Genesis = genesis.Hash()
+
+	parent := genesis
+	for i, vote := range s.votes {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Time:       parent.Time + 1,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		switch {
+		case vote.limit != 0:
+			header.Coinbase = common.BigToAddress(new(big.Int).SetUint64(uint64(vote.limit)))
+			copy(header.Nonce[:], nonceSignerLimitAuthVote)
+		case vote.voted != "":
+			header.Coinbase = accounts.address(vote.voted)
+			if vote.auth {
+				copy(header.Nonce[:], nonceAuthVote)
+			}
+		}
+		if vote.checkpoint != nil {
+			header.Extra = make([]byte, extraVanity+len(vote.checkpoint)*common.AddressLength+extraSeal)
+			accounts.checkpoint(header, vote.checkpoint)
+		}
+		accounts.sign(header, vote.signer)
+		vector.Headers = append(vector.Headers, header)
+		parent = header
+	}
+	blob, err := vector.replay()
+	if err != nil {
+		return nil, err
+	}
+	vector.Snapshot = blob
+	return vector, nil
+}
+
+// replay applies the headers of the vector on top of its genesis signers and
+// returns the canonical encoding of the resulting snapshot.
+func (v *snapshotVector) replay() ([]byte, error) {
+	snap := newSnapshot(v.Config, NewSigCache(inmemorySignatures, nil), 0, v.Genesis, v.Signers)
+	snap, err := snap.apply(v.Headers)
+	if err != nil {
+		return nil, err
+	}
+	return snap.MarshalCanonical()
+}
+
+// Tests that applying the headers of the committed snapshot test vectors results
+// in the committed snapshots, catching any change in the voting semantics.
+func TestSnapshotVectors(t *testing.T) {
+	if *updateVectors {
+		if err := os.MkdirAll(vectorsDir, 0755); err != nil {
+			t.Fatalf("failed to create vectors directory: %v", err)
+		}
+		for _, scenario := range vectorScenarios {
+			vector, err := scenario.generate()
+			if err != nil {
+				t.Fatalf("%s: failed to generate vector: %v", scenario.name, err)
+			}
+			blob, err := json.MarshalIndent(vector, "", "  ")
+			if err != nil {
+				t.Fatalf("%s: failed to encode vector: %v", scenario.name, err)
+			}
+			if err := os.WriteFile(filepath.Join(vectorsDir, scenario.name+".json"), append(blob, '\n'), 0644); err != nil {
+				t.Fatalf("%s: failed to write vector: %v", scenario.name, err)
+			}
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(vectorsDir, "*.json"))
+	if err != nil {
+		t.Fatalf("failed to list vectors: %v", err)
+	}
+	if len(files) != len(vectorScenarios) {
+		t.Errorf("vector count mismatch: have %d, want %d (regenerate with -update-vectors)", len(files), len(vectorScenarios))
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+
+		blob, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("%s: failed to read vector: %v", name, err)
+		}
+		var vector snapshotVector
+		if err := json.Unmarshal(blob, &vector); err != nil {
+			t.Fatalf("%s: failed to decode vector: %v", name, err)
+		}
+		want := new(bytes.Buffer)
+		if err := json.Compact(want, vector.Snapshot); err != nil {
+			t.Fatalf("%s: failed to compact snapshot: %v", name, err)
+		}
+		have, err := vector.replay()
+		if err != nil {
+			t.Errorf("%s: failed to replay headers: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(have, want.Bytes()) {
+			t.Errorf("%s: snapshot mismatch:\nhave %s\nwant %s", name, have, want)
+		}
+	}
+}
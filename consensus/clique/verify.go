@@ -0,0 +1,151 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// SnapshotMismatch describes a persisted snapshot differing from the one derived
+// by replaying the chain up to its block.
+type SnapshotMismatch struct {
+	Number   uint64      `json:"number"`   // Block number of the divergent snapshot
+	Hash     common.Hash `json:"hash"`     // Block hash of the divergent snapshot
+	Verified uint64      `json:"verified"` // Last block with a persisted snapshot found consistent (or the checkpoint)
+	Fields   []string    `json:"fields"`   // Snapshot fields the persisted and replayed states differ in
+}
+
+// VerifySnapshots replays the canonical headers from the last epoch checkpoint up
+// to the head and compares the resulting states against every snapshot persisted
+// in that range. The replay starts from the snapshot of the checkpoint, verified
+// against the signers and limit committed to by the checkpoint header.
+//
+// It returns the number of persisted snapshots checked and the first one found
+// differing from the replayed state, or nil if all of them are consistent. As
+// snapshots are only persisted periodically, the state first diverged somewhere
+// after the last consistent block reported along with the mismatch.
+func (c *Clique) VerifySnapshots(chain consensus.ChainHeaderReader) (int, *SnapshotMismatch, error) {
+	head := chain.CurrentHeader()
+	if head == nil {
+		return 0, nil, errUnknownBlock
+	}
+	number := head.Number.Uint64()
+	number -= number % c.config.Epoch
+
+	checkpoint := chain.GetHeaderByNumber(number)
+	if checkpoint == nil {
+		return 0, nil, errUnknownBlock
+	}
+	db, err := c.snapshotDB(chain)
+	if err != nil {
+		return 0, nil, err
+	}
+	snap, err := c.snapshot(chain, number, checkpoint.Hash(), nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := c.verifyCheckpointSnapshot(chain, checkpoint, snap); err != nil {
+		return 0, nil, fmt.Errorf("checkpoint %d snapshot inconsistent with its header: %v", number, err)
+	}
+	var (
+		checked  int
+		verified = number
+		start    = time.Now()
+		logged   = time.Now()
+	)
+	for n := number + 1; n <= head.Number.Uint64(); n++ {
+		header := chain.GetHeaderByNumber(n)
+		if header == nil {
+			return checked, nil, errUnknownBlock
+		}
+		if snap, err = snap.apply([]*types.Header{header}); err != nil {
+			return checked, nil, err
+		}
+		hash := header.Hash()
+		if !hasSnapshot(db, hash) {
+			continue
+		}
+		stored, err := loadSnapshot(c.config, c.signatures, db, hash)
+		if err != nil {
+			return checked, nil, fmt.Errorf("failed to load snapshot %d: %v", n, err)
+		}
+		checked++
+
+		fields, err := snapshotDiff(stored, snap)
+		if err != nil {
+			return checked, nil, err
+		}
+		if len(fields) > 0 {
+			return checked, &SnapshotMismatch{Number: n, Hash: hash, Verified: verified, Fields: fields}, nil
+		}
+		verified = n
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying voting snapshots", "number", n, "head", head.Number, "checked", checked, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	return checked, nil, nil
+}
+
+// hasSnapshot checks whether a snapshot is persisted for the given block, either
+// in full or as a delta.
+func hasSnapshot(db ethdb.KeyValueReader, hash common.Hash) bool {
+	if ok, _ := db.Has(append(append([]byte{}, snapshotPrefix...), hash[:]...)); ok {
+		return true
+	}
+	ok, _ := db.Has(append(append([]byte{}, deltaPrefix...), hash[:]...))
+	return ok
+}
+
+// snapshotDiff compares the canonical encodings of two snapshots and returns the
+// names of the fields they differ in, in ascending order.
+func snapshotDiff(a, b *Snapshot) ([]string, error) {
+	var fields [2]map[string]json.RawMessage
+	for i, snap := range []*Snapshot{a, b} {
+		blob, err := snap.MarshalCanonical()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(blob, &fields[i]); err != nil {
+			return nil, err
+		}
+	}
+	var diff []string
+	for name, value := range fields[0] {
+		if !bytes.Equal(value, fields[1][name]) {
+			diff = append(diff, name)
+		}
+	}
+	for name := range fields[1] {
+		if _, ok := fields[0][name]; !ok {
+			diff = append(diff, name)
+		}
+	}
+	sort.Strings(diff)
+	return diff, nil
+}
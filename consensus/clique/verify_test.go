@@ -0,0 +1,99 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newTesterSignedChain creates a chain of the given length sealed in turn by the
+// given signers, all of them authorized in the genesis.
+func newTesterSignedChain(accounts *testerAccountPool, config *params.CliqueConfig, signers []string, length int) *testerHeaderChain {
+	genesis := &types.Header{
+		Number:     common.Big0,
+		Difficulty: common.Big1,
+		Extra:      make([]byte, extraVanity+len(signers)*common.AddressLength+extraSeal),
+	}
+	accounts.checkpoint(genesis, signers)
+	chain := &testerHeaderChain{config: &params.ChainConfig{Clique: config}, headers: []*types.Header{genesis}}
+
+	for i := 1; i <= length; i++ {
+		parent := chain.headers[i-1]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Time:       parent.Time + 1,
+			Difficulty: diffInTurn,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, signers[i%len(signers)])
+		chain.headers = append(chain.headers, header)
+	}
+	return chain
+}
+
+// Tests that persisted snapshots are checked against the replayed chain, and the
+// first one differing is reported along with the fields it differs in.
+func TestVerifySnapshots(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B"}, 12)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	db, err := engine.snapshotDB(chain)
+	if err != nil {
+		t.Fatalf("failed to open snapshot database: %v", err)
+	}
+	for _, number := range []uint64{4, 8} {
+		snap, err := engine.snapshot(chain, number, chain.headers[number].Hash(), nil)
+		if err != nil {
+			t.Fatalf("failed to create snapshot %d: %v", number, err)
+		}
+		if err := snap.store(db); err != nil {
+			t.Fatalf("failed to store snapshot %d: %v", number, err)
+		}
+	}
+	checked, mismatch, err := engine.VerifySnapshots(chain)
+	if err != nil || mismatch != nil || checked != 2 {
+		t.Fatalf("consistent snapshots verification mismatch: checked %d, mismatch %v, err %v", checked, mismatch, err)
+	}
+	// Tamper with the latter snapshot and ensure it's reported
+	tampered, err := loadSnapshot(config, nil, db, chain.headers[8].Hash())
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	tampered.SignerLimit++
+	if err := tampered.store(db); err != nil {
+		t.Fatalf("failed to store tampered snapshot: %v", err)
+	}
+	checked, mismatch, err = engine.VerifySnapshots(chain)
+	if err != nil {
+		t.Fatalf("failed to verify snapshots: %v", err)
+	}
+	want := &SnapshotMismatch{Number: 8, Hash: chain.headers[8].Hash(), Verified: 4, Fields: []string{"limit"}}
+	if checked != 2 || !reflect.DeepEqual(mismatch, want) {
+		t.Errorf("mismatch report mismatch: checked %d, have %+v, want %+v", checked, mismatch, want)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// verifyBatchPerWorker is the number of headers of a batch each signature
+	// recovery worker is added for. Smaller batches are verified sequentially,
+	// as the workers would cost more to spin up than they save.
+	verifyBatchPerWorker = 64
+
+	// liveVerifyPeriods is the number of block periods within which the last
+	// header of a batch must have been sealed for the batch to be considered
+	// live traffic rather than a sync.
+	liveVerifyPeriods = 8
+
+	// liveVerifyShare is the divisor of the available cores usable by the
+	// workers verifying live traffic, leaving the rest to block processing.
+	liveVerifyShare = 4
+)
+
+var verifyWorkersGauge = metrics.NewRegisteredGauge("clique/verify/workers", nil)
+
+// SetVerifyWorkers sets the hard cap of the signature recovery workers header
+// batches are verified with, on top of the adaptive scaling (0 = the number of
+// available cores).
+func (c *Clique) SetVerifyWorkers(limit int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.verifyWorkers = limit
+}
+
+// verifyWorkerCount returns the number of workers to recover the signers of a
+// header batch with. It scales with the batch size up to the available cores
+// while syncing, but only uses a share of them for live traffic, which competes
+// with the block processing for the cores.
+func (c *Clique) verifyWorkerCount(headers []*types.Header, now time.Time) int {
+	c.lock.RLock()
+	limit := c.verifyWorkers
+	c.lock.RUnlock()
+
+	workers := len(headers) / verifyBatchPerWorker
+	if cores := runtime.GOMAXPROCS(0); workers > cores {
+		workers = cores
+	}
+	if len(headers) > 0 {
+		period := c.config.Period
+		if period == 0 {
+			period = 1
+		}
+		last := time.Unix(int64(headers[len(headers)-1].Time), 0)
+		if now.Sub(last) < time.Duration(liveVerifyPeriods*period)*time.Second {
+			if share := runtime.GOMAXPROCS(0) / liveVerifyShare; workers > share {
+				workers = share
+			}
+		}
+	}
+	if limit > 0 && workers > limit {
+		workers = limit
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+// prefetchSigners recovers the signers of a header batch into the signature
+// cache on the given number of workers, ahead of the sequential verification
+// consuming them. The returned function stops the workers and waits for them.
+func (c *Clique) prefetchSigners(headers []*types.Header, workers int) func() {
+	if workers < 2 {
+		return func() {}
+	}
+	verifyWorkersGauge.Update(int64(workers))
+
+	var (
+		wg    sync.WaitGroup
+		stop  = make(chan struct{})
+		tasks = make(chan *types.Header)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for header := range tasks {
+				ecrecover(header, c.signatures)
+			}
+		}()
+	}
+	go func() {
+		defer close(tasks)
+		for _, header := range headers {
+			select {
+			case tasks <- header:
+			case <-stop:
+				return
+			case <-c.quit:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		wg.Wait()
+		verifyWorkersGauge.Update(0)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"math/big"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the verification workers scale with the batch size up to the
+// available cores, are throttled for live traffic and never exceed the cap.
+func TestVerifyWorkerCount(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+
+	now := time.Unix(1000000, 0)
+	batch := func(size int, age time.Duration) []*types.Header {
+		headers := make([]*types.Header, size)
+		for i := range headers {
+			headers[i] = &types.Header{Number: big.NewInt(int64(i + 1)), Time: uint64(now.Add(-age).Unix())}
+		}
+		return headers
+	}
+	tests := []struct {
+		headers []*types.Header
+		limit   int
+		want    int
+	}{
+		{batch(1, time.Hour), 0, 1},
+		{batch(verifyBatchPerWorker*3, time.Hour), 0, 3},
+		{batch(verifyBatchPerWorker*100, time.Hour), 0, 8},
+		{batch(verifyBatchPerWorker*100, time.Hour), 5, 5},
+		{batch(verifyBatchPerWorker*100, time.Second), 0, 2},
+		{batch(verifyBatchPerWorker*100, time.Second), 1, 1},
+	}
+	for i, tt := range tests {
+		engine := New(&params.CliqueConfig{Period: 15, Epoch: 30000}, rawdb.NewMemoryDatabase())
+		engine.SetVerifyWorkers(tt.limit)
+
+		if have := engine.verifyWorkerCount(tt.headers, now); have != tt.want {
+			t.Errorf("test %d: worker count mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the prefetching workers recover the signers of a whole batch into
+// the signature cache.
+func TestPrefetchSigners(t *testing.T) {
+	var (
+		accounts = newTesterAccountPool()
+		config   = &params.CliqueConfig{Epoch: 30000, SignerLimit: 50}
+		chain    = newTesterSignedChain(accounts, config, []string{"A", "B", "C"}, 3*verifyBatchPerWorker)
+		engine   = New(config, rawdb.NewMemoryDatabase())
+	)
+	headers := chain.headers[1:]
+
+	stop := engine.prefetchSigners(headers, 3)
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		recovered := 0
+		for _, header := range headers {
+			if _, ok := engine.signatures.Get(header.Hash()); ok {
+				recovered++
+			}
+		}
+		if recovered == len(headers) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("signers not prefetched: have %d, want %d", recovered, len(headers))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+}
@@ -128,3 +128,56 @@ type PoW interface {
 	// Hashrate returns the current mining hashrate of a PoW consensus engine.
 	Hashrate() float64
 }
+
+// FeeRecipientResolver is a consensus engine that may credit the transaction
+// fees of a block to an account other than its author.
+type FeeRecipientResolver interface {
+	// FeeRecipient returns the account the transaction fees of a block are
+	// credited to.
+	FeeRecipient(header *types.Header) (common.Address, error)
+}
+
+// SealPrewarmer is a consensus engine that can precompute the seal verification
+// of a header before it's imported, e.g. when the block is announced by a peer.
+type SealPrewarmer interface {
+	// PrewarmSeal precomputes and caches the seal verification of a header.
+	PrewarmSeal(header *types.Header)
+}
+
+// SenderPermitter is a consensus engine that restricts which accounts may send
+// transactions.
+type SenderPermitter interface {
+	// PermitsSender returns whether an account may send transactions included in
+	// a child block of the given parent.
+	PermitsSender(chain ChainHeaderReader, parent *types.Header, sender common.Address) bool
+}
+
+// FeeExempter is a consensus engine that lets some transactions through regardless
+// of their gas price, e.g. the governance transactions of the authorities.
+type FeeExempter interface {
+	// ExemptsFee returns whether a transaction of the given sender, included in a
+	// child block of the given parent, is exempt from the gas price rules.
+	ExemptsFee(chain ChainHeaderReader, parent *types.Header, sender common.Address, tx *types.Transaction) bool
+}
+
+// StateConsumer is a consensus engine that needs to read the chain state, e.g. to
+// skip voting on candidates without on-chain deposits while sealing.
+type StateConsumer interface {
+	// SetStateReader sets the function retrieving the state after a given block.
+	SetStateReader(fn func(hash common.Hash) (*state.StateDB, error))
+}
+
+// StateVerifier is a consensus engine with rules depending on the chain state,
+// e.g. the deposits locked on-chain by candidates voted on.
+type StateVerifier interface {
+	// VerifyState checks a block against the state of its parent, before any of
+	// its transactions are applied.
+	VerifyState(chain ChainHeaderReader, block *types.Block, state *state.StateDB) error
+}
+
+// SnapshotServer is a consensus engine able to serve the voting snapshots of its
+// checkpoint blocks to remote nodes.
+type SnapshotServer interface {
+	// CheckpointSnapshot retrieves the encoded voting snapshot of a checkpoint.
+	CheckpointSnapshot(hash common.Hash) ([]byte, error)
+}
@@ -83,6 +83,7 @@ var (
 
 	errInsertionInterrupted = errors.New("insertion is interrupted")
 	errChainStopped         = errors.New("blockchain is stopped")
+	errFinalizedReorg       = errors.New("reorg past finalized block")
 )
 
 const (
@@ -193,6 +194,8 @@ type BlockChain struct {
 	currentBlock     atomic.Value // Current head of the block chain
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
+	currentFinalizedBlock atomic.Value // Latest block finalized by the consensus engine (nil if none)
+
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
 	bodyCache     *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache  *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
@@ -270,6 +273,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	var nilBlock *types.Block
 	bc.currentBlock.Store(nilBlock)
 	bc.currentFastBlock.Store(nilBlock)
+	bc.currentFinalizedBlock.Store(nilBlock)
 
 	// Initialize the chain with ancient data if it isn't empty.
 	var txIndexBlock uint64
@@ -584,6 +588,12 @@ func (bc *BlockChain) loadLastState() error {
 			headFastBlockGauge.Update(int64(block.NumberU64()))
 		}
 	}
+	// Restore the last block finalized by the consensus engine
+	if final := rawdb.ReadFinalizedBlockHash(bc.db); final != (common.Hash{}) {
+		if block := bc.GetBlockByHash(final); block != nil {
+			bc.currentFinalizedBlock.Store(block)
+		}
+	}
 	// Issue a status log for the user
 	currentFastBlock := bc.CurrentFastBlock()
 
@@ -600,6 +610,16 @@ func (bc *BlockChain) loadLastState() error {
 	return nil
 }
 
+// SetFinalized marks a block as finalized by the consensus engine, after which
+// the chain refuses to reorg any of the blocks up to and including it.
+func (bc *BlockChain) SetFinalized(block *types.Block) {
+	if final := bc.CurrentFinalizedBlock(); final != nil && final.NumberU64() >= block.NumberU64() {
+		return
+	}
+	bc.currentFinalizedBlock.Store(block)
+	rawdb.WriteFinalizedBlockHash(bc.db, block.Hash())
+}
+
 // SetHead rewinds the local chain to a new head. Depending on whether the node
 // was fast synced or full synced and in which state, the method will try to
 // delete minimal data from disk whilst retaining chain consistency.
@@ -2139,6 +2159,10 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			return fmt.Errorf("invalid new chain")
 		}
 	}
+	// Never replace blocks finalized by the consensus engine
+	if final := bc.CurrentFinalizedBlock(); final != nil && commonBlock.NumberU64() < final.NumberU64() {
+		return errFinalizedReorg
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Info
@@ -585,6 +585,36 @@ func testReorg(t *testing.T, first, second []int64, td int64, full bool) {
 	}
 }
 
+// Tests that blocks finalized by the consensus engine are never reorged out, not
+// even by a heavier chain.
+func TestFinalizedReorg(t *testing.T) {
+	db, blockchain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	easyBlocks, _ := GenerateChain(params.TestChainConfig, blockchain.CurrentBlock(), ethash.NewFaker(), db, 4, func(i int, b *BlockGen) {
+		b.OffsetTime(60)
+	})
+	diffBlocks, _ := GenerateChain(params.TestChainConfig, blockchain.CurrentBlock(), ethash.NewFaker(), db, 4, func(i int, b *BlockGen) {
+		b.OffsetTime(-9)
+	})
+	if _, err := blockchain.InsertChain(easyBlocks); err != nil {
+		t.Fatalf("failed to insert easy chain: %v", err)
+	}
+	blockchain.SetFinalized(easyBlocks[1])
+	if final := blockchain.CurrentFinalizedBlock(); final == nil || final.Hash() != easyBlocks[1].Hash() {
+		t.Fatalf("finalized block mismatch: have %v, want %x", final, easyBlocks[1].Hash())
+	}
+	if _, err := blockchain.InsertChain(diffBlocks); !errors.Is(err, errFinalizedReorg) {
+		t.Fatalf("reorg past finalized block error mismatch: have %v, want %v", err, errFinalizedReorg)
+	}
+	if head := blockchain.CurrentBlock(); head.Hash() != easyBlocks[3].Hash() {
+		t.Errorf("head block mismatch: have %x, want %x", head.Hash(), easyBlocks[3].Hash())
+	}
+}
+
 // Tests that the insertion functions detect banned hashes.
 func TestBadHeaderHashes(t *testing.T) { testBadHashes(t, false) }
 func TestBadBlockHashes(t *testing.T)  { testBadHashes(t, true) }
@@ -45,7 +45,11 @@ func NewEVMBlockContext(header *types.Header, chain ChainContext, author *common
 
 	// If we don't have an explicit author (i.e. not mining), extract from the header
 	if author == nil {
-		beneficiary, _ = chain.Engine().Author(header) // Ignore error, we're past header validation
+		if resolver, ok := chain.Engine().(consensus.FeeRecipientResolver); ok {
+			beneficiary, _ = resolver.FeeRecipient(header) // Ignore error, we're past header validation
+		} else {
+			beneficiary, _ = chain.Engine().Author(header) // Ignore error, we're past header validation
+		}
 	} else {
 		beneficiary = *author
 	}
@@ -213,25 +213,11 @@ func checksumToBytes(hash uint32) [4]byte {
 
 // gatherForks gathers all the known forks and creates a sorted list out of them.
 func gatherForks(config *params.ChainConfig) []uint64 {
-	// Gather all the fork block numbers via reflection
-	kind := reflect.TypeOf(params.ChainConfig{})
-	conf := reflect.ValueOf(config).Elem()
-
-	var forks []uint64
-	for i := 0; i < kind.NumField(); i++ {
-		// Fetch the next field and skip non-fork rules
-		field := kind.Field(i)
-		if !strings.HasSuffix(field.Name, "Block") {
-			continue
-		}
-		if field.Type != reflect.TypeOf(new(big.Int)) {
-			continue
-		}
-		// Extract the fork rule block number and aggregate it
-		rule := conf.Field(i).Interface().(*big.Int)
-		if rule != nil {
-			forks = append(forks, rule.Uint64())
-		}
+	// Gather all the fork block numbers via reflection, including the ones of the
+	// extended clique rules as they change consensus all the same
+	forks := gatherBlocks(reflect.ValueOf(config).Elem())
+	if config.Clique != nil {
+		forks = append(forks, gatherBlocks(reflect.ValueOf(config.Clique).Elem())...)
 	}
 	// Sort the fork block numbers to permit chronological XOR
 	for i := 0; i < len(forks); i++ {
@@ -254,3 +240,27 @@ func gatherForks(config *params.ChainConfig) []uint64 {
 	}
 	return forks
 }
+
+// gatherBlocks returns the block numbers of all the fork rules set in the given
+// config struct, i.e. its big integer fields named *Block.
+func gatherBlocks(conf reflect.Value) []uint64 {
+	kind := conf.Type()
+
+	var forks []uint64
+	for i := 0; i < kind.NumField(); i++ {
+		// Fetch the next field and skip non-fork rules
+		field := kind.Field(i)
+		if !strings.HasSuffix(field.Name, "Block") {
+			continue
+		}
+		if field.Type != reflect.TypeOf(new(big.Int)) {
+			continue
+		}
+		// Extract the fork rule block number and aggregate it
+		rule := conf.Field(i).Interface().(*big.Int)
+		if rule != nil {
+			forks = append(forks, rule.Uint64())
+		}
+	}
+	return forks
+}
@@ -282,3 +282,22 @@ func TestEncoding(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the fork blocks of the extended clique rules are part of the fork
+// ID, so nodes unaware of a scheduled rule change are rejected once it passes.
+func TestCliqueForks(t *testing.T) {
+	config := *params.RinkebyChainConfig
+	clique := *config.Clique
+	clique.GovernanceBlock = big.NewInt(10_000_000)
+	clique.ReceiptBlock = big.NewInt(10_000_000)
+	clique.DepositBlock = big.NewInt(12_000_000)
+	config.Clique = &clique
+
+	forks, base := gatherForks(&config), gatherForks(params.RinkebyChainConfig)
+	if len(forks) != len(base)+2 {
+		t.Fatalf("fork count mismatch: have %d, want %d", len(forks), len(base)+2)
+	}
+	if have, want := NewID(&config, params.RinkebyGenesisHash, 11_000_000), NewID(params.RinkebyChainConfig, params.RinkebyGenesisHash, 11_000_000); have == want {
+		t.Errorf("fork ID unaffected by clique forks: %v", have)
+	}
+}
@@ -23,7 +23,9 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -481,6 +483,57 @@ func DefaultGoerliGenesisBlock() *Genesis {
 	}
 }
 
+// CliqueGenesisBlock assembles the genesis block of a clique network with the
+// given initial signers, validating them against the signer limit configured.
+func CliqueGenesisBlock(config *params.ChainConfig, signers []common.Address, gasLimit uint64) (*Genesis, error) {
+	if config.Clique == nil {
+		return nil, errors.New("chain configuration is not clique")
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("no initial signers")
+	}
+	seen := make(map[common.Address]bool)
+	for _, signer := range signers {
+		if signer == (common.Address{}) {
+			return nil, errors.New("zero address signer")
+		}
+		if seen[signer] {
+			return nil, fmt.Errorf("duplicate signer %x", signer)
+		}
+		seen[signer] = true
+	}
+	limit := config.Clique.SignerLimit
+	if config.Clique.AbsoluteSignerLimit {
+		if limit == 0 || limit > uint(len(signers)) {
+			return nil, fmt.Errorf("absolute signer limit %d out of range [1, %d]", limit, len(signers))
+		}
+	} else if limit >= 100 {
+		return nil, fmt.Errorf("signer limit percentage %d out of range [0, 99]", limit)
+	}
+	return &Genesis{
+		Config:     config,
+		Timestamp:  uint64(time.Now().Unix()),
+		ExtraData:  cliqueExtraData(signers),
+		GasLimit:   gasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      GenesisAlloc{},
+	}, nil
+}
+
+// cliqueExtraData creates the clique genesis extra-data, embedding the initial
+// signers in ascending order between the vanity and the (empty) seal.
+func cliqueExtraData(signers []common.Address) []byte {
+	sorted := append([]common.Address{}, signers...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	extra := make([]byte, 32, 32+len(sorted)*common.AddressLength+crypto.SignatureLength)
+	for _, signer := range sorted {
+		extra = append(extra, signer[:]...)
+	}
+	return append(extra, make([]byte, crypto.SignatureLength)...)
+}
+
 // DefaultSepoliaGenesisBlock returns the Sepolia network genesis block.
 func DefaultSepoliaGenesisBlock() *Genesis {
 	return &Genesis{
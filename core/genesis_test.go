@@ -243,3 +243,42 @@ func TestReadWriteGenesisAlloc(t *testing.T) {
 		}
 	}
 }
+
+// Tests that clique genesis blocks are only assembled for valid signer sets and
+// signer limits.
+func TestCliqueGenesisBlock(t *testing.T) {
+	var (
+		first  = common.HexToAddress("0x01")
+		second = common.HexToAddress("0x02")
+	)
+	tests := []struct {
+		signers  []common.Address
+		limit    uint
+		absolute bool
+		fail     bool
+	}{
+		{signers: []common.Address{second, first}, limit: 50},
+		{signers: []common.Address{first, second}, limit: 2, absolute: true},
+		{signers: nil, limit: 50, fail: true},
+		{signers: []common.Address{first, first}, limit: 50, fail: true},
+		{signers: []common.Address{{}}, limit: 50, fail: true},
+		{signers: []common.Address{first}, limit: 100, fail: true},
+		{signers: []common.Address{first}, limit: 2, absolute: true, fail: true},
+		{signers: []common.Address{first}, limit: 0, absolute: true, fail: true},
+	}
+	for i, tt := range tests {
+		config := *params.AllCliqueProtocolChanges
+		config.Clique = &params.CliqueConfig{Period: 15, Epoch: 30000, SignerLimit: tt.limit, AbsoluteSignerLimit: tt.absolute}
+
+		genesis, err := CliqueGenesisBlock(&config, tt.signers, params.GenesisGasLimit)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: failure mismatch: have %v, want failure %v", i, err, tt.fail)
+			continue
+		}
+		if err == nil {
+			if _, _, err := SetupGenesisBlock(rawdb.NewMemoryDatabase(), genesis); err != nil {
+				t.Errorf("test %d: failed to set up genesis: %v", i, err)
+			}
+		}
+	}
+}
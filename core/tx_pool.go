@@ -81,6 +81,10 @@ var (
 	// transaction with a negative value.
 	ErrNegativeValue = errors.New("negative value")
 
+	// ErrSenderNotPermitted is returned if the sender of a transaction is not
+	// permitted to transact by the consensus engine.
+	ErrSenderNotPermitted = errors.New("sender not permitted")
+
 	// ErrOversizedData is returned if the input data of a transaction is greater
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
@@ -248,6 +252,10 @@ type TxPool struct {
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
 
+	permits func(common.Address) bool                     // Filter of the accounts permitted to send transactions (nil = all)
+	admit   func() error                                  // Gate rejecting all new transactions while it errors (nil = admit all)
+	exempt  func(common.Address, *types.Transaction) bool // Filter of the transactions exempt from the price rules (nil = none)
+
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
@@ -544,7 +552,7 @@ func (pool *TxPool) Pending(enforceTips bool) map[common.Address]types.Transacti
 		// If the miner requests tip enforcement, cap the lists now
 		if enforceTips && !pool.locals.contains(addr) {
 			for i, tx := range txs {
-				if tx.EffectiveGasTipIntCmp(pool.gasPrice, pool.priced.urgent.baseFee) < 0 {
+				if tx.EffectiveGasTipIntCmp(pool.gasPrice, pool.priced.urgent.baseFee) < 0 && !pool.feeExempt(addr, tx) {
 					txs = txs[:i]
 					break
 				}
@@ -581,9 +589,84 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// SetSenderFilter sets the filter of the accounts permitted to send transactions,
+// dropping all the transactions of the accounts not permitted any more. The filter
+// is reevaluated against every new chain head.
+func (pool *TxPool) SetSenderFilter(permits func(common.Address) bool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.permits = permits
+	pool.dropUnpermitted()
+}
+
+// SetAdmissionGate sets a gate checked before accepting any new transaction, all
+// of them being rejected with the error of the gate while it returns one, e.g.
+// while the chain is stalled and can't include them.
+func (pool *TxPool) SetAdmissionGate(admit func() error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.admit = admit
+}
+
+// SetFeeExemption sets the filter of the transactions exempt from the minimum tip
+// of the pool, e.g. the governance transactions of the consensus authorities. They
+// must still cover the base fee to be accepted, as the block rules require.
+func (pool *TxPool) SetFeeExemption(exempt func(common.Address, *types.Transaction) bool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.exempt = exempt
+}
+
+// feeExempt returns whether a transaction of the given sender is exempt from the
+// minimum tip of the pool. Transactions not covering the pending base fee can't be
+// included in a block and are never exempt.
+func (pool *TxPool) feeExempt(from common.Address, tx *types.Transaction) bool {
+	if pool.exempt == nil {
+		return false
+	}
+	if baseFee := pool.priced.urgent.baseFee; baseFee != nil && tx.GasFeeCapIntCmp(baseFee) < 0 {
+		return false
+	}
+	return pool.exempt(from, tx)
+}
+
+// dropUnpermitted removes all the transactions of the accounts the sender filter
+// doesn't permit to transact.
+func (pool *TxPool) dropUnpermitted() {
+	if pool.permits == nil {
+		return
+	}
+	var drop []common.Hash
+	for _, txs := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range txs {
+			if pool.permits(addr) {
+				continue
+			}
+			for _, tx := range list.Flatten() {
+				drop = append(drop, tx.Hash())
+			}
+		}
+	}
+	for _, hash := range drop {
+		pool.removeTx(hash, true)
+	}
+	if len(drop) > 0 {
+		log.Debug("Dropped unpermitted transactions", "count", len(drop))
+	}
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Reject all transactions while the admission gate is closed
+	if pool.admit != nil {
+		if err := pool.admit(); err != nil {
+			return err
+		}
+	}
 	// Accept only legacy transactions until EIP-2718/2930 activates.
 	if !pool.eip2718 && tx.Type() != types.LegacyTxType {
 		return ErrTxTypeNotSupported
@@ -621,6 +704,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// Drop transactions of senders not permitted to transact
+	if pool.permits != nil && !pool.permits(from) {
+		return ErrSenderNotPermitted
+	}
 	// Drop non-local transactions under our own minimal accepted gas price or tip
 	if !local && tx.GasTipCapIntCmp(pool.gasPrice) < 0 {
 		return ErrUnderpriced
@@ -664,8 +751,16 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	// the sender is marked as local previously, treat it as the local transaction.
 	isLocal := local || pool.locals.containsTx(tx)
 
+	// Transactions exempt from the minimum tip are validated like local ones, but
+	// are otherwise kept and evicted like the remote ones
+	exempt := false
+	if !isLocal {
+		if from, err := types.Sender(pool.signer, tx); err == nil {
+			exempt = pool.feeExempt(from, tx)
+		}
+	}
 	// If the transaction fails basic validation, discard it
-	if err := pool.validateTx(tx, isLocal); err != nil {
+	if err := pool.validateTx(tx, isLocal || exempt); err != nil {
 		log.Trace("Discarding invalid transaction", "hash", hash, "err", err)
 		invalidTxMeter.Mark(1)
 		return false, err
@@ -673,7 +768,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
-		if !isLocal && pool.priced.Underpriced(tx) {
+		if !isLocal && !exempt && pool.priced.Underpriced(tx) {
 			log.Trace("Discarding underpriced transaction", "hash", hash, "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
 			return false, ErrUnderpriced
@@ -1157,6 +1252,9 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		// Reset from the old head to the new, rescheduling any reorged transactions
 		pool.reset(reset.oldHead, reset.newHead)
 
+		// Sender permissions might have changed, drop the revoked accounts
+		pool.dropUnpermitted()
+
 		// Nonces were reset, discard any events that became stale
 		for addr := range events {
 			events[addr].Forward(pool.pendingNonces.get(addr))
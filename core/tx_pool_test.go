@@ -310,6 +310,104 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that the sender filter rejects transactions of unpermitted accounts and
+// drops the pooled transactions of accounts revoked later.
+func TestTransactionSenderFilter(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	tx := transaction(0, 100000, key)
+	from, _ := deriveSender(tx)
+	testAddBalance(pool, from, big.NewInt(1000000))
+
+	permitted := false
+	pool.SetSenderFilter(func(addr common.Address) bool { return permitted || addr != from })
+
+	if err := pool.AddRemote(tx); !errors.Is(err, ErrSenderNotPermitted) {
+		t.Fatalf("unpermitted sender error mismatch: have %v, want %v", err, ErrSenderNotPermitted)
+	}
+	permitted = true
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatalf("failed to add permitted transaction: %v", err)
+	}
+	permitted = false
+	pool.SetSenderFilter(pool.permits)
+
+	if pending, queued := pool.Stats(); pending+queued != 0 {
+		t.Errorf("revoked transactions mismatch: have %d pending, %d queued, want none", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+func TestTransactionAdmissionGate(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	tx := transaction(0, 100000, key)
+	from, _ := deriveSender(tx)
+	testAddBalance(pool, from, big.NewInt(1000000))
+
+	errClosed := errors.New("gate closed")
+	pool.SetAdmissionGate(func() error { return errClosed })
+
+	if err := pool.AddLocal(tx); !errors.Is(err, errClosed) {
+		t.Fatalf("closed gate error mismatch: have %v, want %v", err, errClosed)
+	}
+	pool.SetAdmissionGate(nil)
+	if err := pool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add transaction through open gate: %v", err)
+	}
+}
+
+func TestTransactionFeeExemption(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	pool.mu.Lock()
+	pool.priced.SetBaseFee(big.NewInt(100))
+	pool.mu.Unlock()
+
+	var (
+		tx       = dynamicFeeTx(0, 100000, big.NewInt(100), new(big.Int), key)
+		capped   = dynamicFeeTx(0, 100000, big.NewInt(99), new(big.Int), key)
+		from, _  = types.Sender(pool.signer, tx)
+		exempted = func(addr common.Address, tx *types.Transaction) bool { return addr == from }
+	)
+	testAddBalance(pool, from, big.NewInt(100000000))
+
+	if err := pool.AddRemote(tx); !errors.Is(err, ErrUnderpriced) {
+		t.Fatalf("underpriced transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	pool.SetFeeExemption(exempted)
+
+	// Exempt transactions must still cover the base fee to ever be included
+	if err := pool.AddRemote(capped); !errors.Is(err, ErrUnderpriced) {
+		t.Fatalf("fee capped transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatalf("failed to add exempt transaction: %v", err)
+	}
+	<-pool.requestPromoteExecutables(newAccountSet(pool.signer, from))
+	if pending := pool.Pending(true); len(pending[from]) != 1 {
+		t.Errorf("exempt transaction not pending for the miner: have %d, want 1", len(pending[from]))
+	}
+	// Exempt transactions aren't local, they're evicted like the remote ones
+	if pool.locals.contains(from) || pool.all.LocalCount() != 0 {
+		t.Errorf("exempt transaction tracked as local")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
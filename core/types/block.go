@@ -160,6 +160,7 @@ func (h *Header) EmptyReceipts() bool {
 type Body struct {
 	Transactions []*Transaction
 	Uncles       []*Header
+	Consensus    []byte `rlp:"optional"`
 }
 
 // Block represents an entire block in the Ethereum blockchain.
@@ -167,6 +168,7 @@ type Block struct {
 	header       *Header
 	uncles       []*Header
 	transactions Transactions
+	consensus    []byte // Optional engine-specific data not committed to by the header
 
 	// caches
 	hash atomic.Value
@@ -184,9 +186,10 @@ type Block struct {
 
 // "external" block encoding. used for eth protocol, etc.
 type extblock struct {
-	Header *Header
-	Txs    []*Transaction
-	Uncles []*Header
+	Header    *Header
+	Txs       []*Transaction
+	Uncles    []*Header
+	Consensus []byte `rlp:"optional"`
 }
 
 // NewBlock creates a new block. The input data is copied,
@@ -262,7 +265,7 @@ func (b *Block) DecodeRLP(s *rlp.Stream) error {
 	if err := s.Decode(&eb); err != nil {
 		return err
 	}
-	b.header, b.uncles, b.transactions = eb.Header, eb.Uncles, eb.Txs
+	b.header, b.uncles, b.transactions, b.consensus = eb.Header, eb.Uncles, eb.Txs, eb.Consensus
 	b.size.Store(common.StorageSize(rlp.ListSize(size)))
 	return nil
 }
@@ -270,9 +273,10 @@ func (b *Block) DecodeRLP(s *rlp.Stream) error {
 // EncodeRLP serializes b into the Ethereum RLP block format.
 func (b *Block) EncodeRLP(w io.Writer) error {
 	return rlp.Encode(w, extblock{
-		Header: b.header,
-		Txs:    b.transactions,
-		Uncles: b.uncles,
+		Header:    b.header,
+		Txs:       b.transactions,
+		Uncles:    b.uncles,
+		Consensus: b.consensus,
 	})
 }
 
@@ -280,6 +284,7 @@ func (b *Block) EncodeRLP(w io.Writer) error {
 
 func (b *Block) Uncles() []*Header          { return b.uncles }
 func (b *Block) Transactions() Transactions { return b.transactions }
+func (b *Block) ConsensusData() []byte      { return b.consensus }
 
 func (b *Block) Transaction(hash common.Hash) *Transaction {
 	for _, transaction := range b.transactions {
@@ -318,7 +323,7 @@ func (b *Block) BaseFee() *big.Int {
 func (b *Block) Header() *Header { return CopyHeader(b.header) }
 
 // Body returns the non-header content of the block.
-func (b *Block) Body() *Body { return &Body{b.transactions, b.uncles} }
+func (b *Block) Body() *Body { return &Body{b.transactions, b.uncles, b.consensus} }
 
 // Size returns the true RLP encoded storage size of the block, either by encoding
 // and returning it, or returning a previsouly cached value.
@@ -361,6 +366,7 @@ func (b *Block) WithSeal(header *Header) *Block {
 		header:       &cpy,
 		transactions: b.transactions,
 		uncles:       b.uncles,
+		consensus:    b.consensus,
 	}
 }
 
@@ -378,6 +384,17 @@ func (b *Block) WithBody(transactions []*Transaction, uncles []*Header) *Block {
 	return block
 }
 
+// WithConsensusData returns a new block with the data from b but the consensus
+// engine specific data replaced with the given one.
+func (b *Block) WithConsensusData(data []byte) *Block {
+	return &Block{
+		header:       b.header,
+		transactions: b.transactions,
+		uncles:       b.uncles,
+		consensus:    common.CopyBytes(data),
+	}
+}
+
 // Hash returns the keccak256 hash of b's header.
 // The hash is computed on the first call and cached thereafter.
 func (b *Block) Hash() common.Hash {
@@ -194,6 +194,43 @@ func TestEIP2718BlockEncoding(t *testing.T) {
 	}
 }
 
+// Tests that the consensus data of a block survives encoding, and that blocks and
+// bodies without any keep their legacy encoding.
+func TestConsensusDataEncoding(t *testing.T) {
+	block := NewBlockWithHeader(&Header{Number: big.NewInt(1), Difficulty: big.NewInt(2)})
+	legacy, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	extended, err := rlp.EncodeToBytes(block.WithConsensusData([]byte{0x01, 0x02}))
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	if bytes.Equal(legacy, extended) {
+		t.Fatalf("consensus data not encoded")
+	}
+	var decoded Block
+	if err := rlp.DecodeBytes(extended, &decoded); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if !bytes.Equal(decoded.ConsensusData(), []byte{0x01, 0x02}) || decoded.Hash() != block.Hash() {
+		t.Errorf("decoded block mismatch: consensus data %x, hash %x", decoded.ConsensusData(), decoded.Hash())
+	}
+	if err := rlp.DecodeBytes(legacy, &decoded); err != nil {
+		t.Fatal("decode error: ", err)
+	}
+	if decoded.ConsensusData() != nil {
+		t.Errorf("legacy block decoded with consensus data %x", decoded.ConsensusData())
+	}
+	body, err := rlp.EncodeToBytes(&Body{})
+	if err != nil {
+		t.Fatal("encode error: ", err)
+	}
+	if !bytes.Equal(body, []byte{0xc2, 0xc0, 0xc0}) {
+		t.Errorf("empty body encoding mismatch: have %x, want c2c0c0", body)
+	}
+}
+
 func TestUncleHash(t *testing.T) {
 	uncles := make([]*Header, 0)
 	h := CalcUncleHash(uncles)
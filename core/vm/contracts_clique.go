@@ -0,0 +1,135 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// StatefulPrecompiledContract is a precompiled contract needing read access to
+// the state of the executing EVM, charging gas for the state it reads.
+type StatefulPrecompiledContract interface {
+	PrecompiledContract
+
+	// RunWithState runs the precompiled contract against the given state.
+	RunWithState(state StateDB, input []byte, suppliedGas uint64) (ret []byte, remainingGas uint64, err error)
+}
+
+var (
+	// errCliqueStateUnknownMethod is returned if the consensus state precompile is
+	// called with an unknown selector.
+	errCliqueStateUnknownMethod = errors.New("unknown consensus state method")
+
+	// errStatefulPrecompile is returned if a stateful precompiled contract is run
+	// without state access.
+	errStatefulPrecompile = errors.New("precompiled contract needs state access")
+)
+
+var (
+	cliqueSignersSelector     = crypto.Keccak256([]byte("signers()"))[:4]
+	cliqueSignerLimitSelector = crypto.Keccak256([]byte("signerLimit()"))[:4]
+	cliqueThresholdSelector   = crypto.Keccak256([]byte("threshold()"))[:4]
+	cliqueIsSignerSelector    = crypto.Keccak256([]byte("isSigner(address)"))[:4]
+)
+
+// cliqueState implemented as a native contract, exposing the clique signer set
+// committed to the signer state system account at the last checkpoint. Its
+// methods are ABI encoded:
+//
+//	function signers() returns (address[])
+//	function signerLimit() returns (uint256)
+//	function threshold() returns (uint256)
+//	function isSigner(address) returns (bool)
+type cliqueState struct{}
+
+// RequiredGas returns the gas of the cheapest method, the state reads are charged
+// by RunWithState.
+func (c *cliqueState) RequiredGas(input []byte) uint64 {
+	return params.CliqueStateBaseGas
+}
+
+func (c *cliqueState) Run(input []byte) ([]byte, error) {
+	return nil, errStatefulPrecompile
+}
+
+func (c *cliqueState) RunWithState(state StateDB, input []byte, suppliedGas uint64) ([]byte, uint64, error) {
+	// Charge for every storage slot read, aborting if the gas runs out
+	read := func(slot common.Hash) (common.Hash, bool) {
+		if suppliedGas < params.CliqueStateReadGas {
+			return common.Hash{}, false
+		}
+		suppliedGas -= params.CliqueStateReadGas
+		return state.GetState(params.CliqueSignerStateAddress, slot), true
+	}
+	if suppliedGas < params.CliqueStateBaseGas {
+		return nil, 0, ErrOutOfGas
+	}
+	suppliedGas -= params.CliqueStateBaseGas
+
+	if len(input) < 4 {
+		return nil, suppliedGas, errCliqueStateUnknownMethod
+	}
+	switch selector := input[:4]; {
+	case bytes.Equal(selector, cliqueSignersSelector):
+		count, ok := read(common.BigToHash(big.NewInt(0)))
+		if !ok {
+			return nil, 0, ErrOutOfGas
+		}
+		n := count.Big().Uint64()
+		out := make([]byte, 0, 64+32*n)
+		out = append(out, common.BigToHash(big.NewInt(32)).Bytes()...)
+		out = append(out, count.Bytes()...)
+
+		base := new(big.Int).SetBytes(crypto.Keccak256(common.Hash{}.Bytes()))
+		for i := uint64(0); i < n; i++ {
+			signer, ok := read(common.BigToHash(new(big.Int).Add(base, new(big.Int).SetUint64(i))))
+			if !ok {
+				return nil, 0, ErrOutOfGas
+			}
+			out = append(out, signer.Bytes()...)
+		}
+		return out, suppliedGas, nil
+
+	case bytes.Equal(selector, cliqueSignerLimitSelector), bytes.Equal(selector, cliqueThresholdSelector):
+		slot := common.BigToHash(big.NewInt(1))
+		if bytes.Equal(selector, cliqueThresholdSelector) {
+			slot = common.BigToHash(big.NewInt(2))
+		}
+		value, ok := read(slot)
+		if !ok {
+			return nil, 0, ErrOutOfGas
+		}
+		return value.Bytes(), suppliedGas, nil
+
+	case bytes.Equal(selector, cliqueIsSignerSelector):
+		if len(input) != 4+32 {
+			return nil, suppliedGas, errCliqueStateUnknownMethod
+		}
+		value, ok := read(crypto.Keccak256Hash(input[4:], common.BigToHash(big.NewInt(3)).Bytes()))
+		if !ok {
+			return nil, 0, ErrOutOfGas
+		}
+		return value.Bytes(), suppliedGas, nil
+	}
+	return nil, suppliedGas, errCliqueStateUnknownMethod
+}
@@ -72,6 +72,13 @@ func (b *EthAPIBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumb
 	if number == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock().Header(), nil
 	}
+	if number == rpc.FinalizedBlockNumber {
+		block := b.eth.blockchain.CurrentFinalizedBlock()
+		if block == nil {
+			return nil, errors.New("finalized block not found")
+		}
+		return block.Header(), nil
+	}
 	return b.eth.blockchain.GetHeaderByNumber(uint64(number)), nil
 }
 
@@ -106,6 +113,13 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 	if number == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
+	if number == rpc.FinalizedBlockNumber {
+		block := b.eth.blockchain.CurrentFinalizedBlock()
+		if block == nil {
+			return nil, errors.New("finalized block not found")
+		}
+		return block, nil
+	}
 	return b.eth.blockchain.GetBlockByNumber(uint64(number)), nil
 }
 
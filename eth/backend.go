@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/big"
 	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -35,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
@@ -141,6 +143,11 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
+	if chainConfig.Clique != nil {
+		if err := clique.CheckVoteNonces(chainConfig.Clique); err != nil {
+			return nil, err
+		}
+	}
 
 	if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal)); err != nil {
 		log.Error("Failed to recover state", "error", err)
@@ -152,7 +159,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		chainDb:           chainDb,
 		eventMux:          stack.EventMux(),
 		accountManager:    stack.AccountManager(),
-		engine:            ethconfig.CreateConsensusEngine(stack, chainConfig, &ethashConfig, config.Miner.Notify, config.Miner.Noverify, chainDb),
+		engine:            ethconfig.CreateConsensusEngine(stack, chainConfig, &ethashConfig, config.Miner.Notify, config.Miner.Noverify, chainDb, ethconfig.CreateCliqueSigCache(config, chainDb)),
 		closeBloomHandler: make(chan struct{}),
 		networkID:         config.NetworkId,
 		gasPrice:          config.Miner.GasPrice,
@@ -180,6 +187,63 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
 		}
 	}
+	// Bootstrap the clique checkpoint snapshots from a trusted node if requested
+	if config.CliqueBootstrap != "" {
+		if cli := eth.cliqueEngine(); cli != nil {
+			client, err := rpc.Dial(config.CliqueBootstrap)
+			if err != nil {
+				return nil, fmt.Errorf("failed to dial clique bootstrap node: %v", err)
+			}
+			cli.SetSnapshotSource(clique.RPCSnapshotSource(client), true)
+		}
+	}
+	// Anchor the clique snapshots at the operator-supplied checkpoint if requested
+	if config.CliqueCheckpoint != nil {
+		if cli := eth.cliqueEngine(); cli != nil {
+			if err := cli.SetTrustedCheckpoint(config.CliqueCheckpoint); err != nil {
+				return nil, fmt.Errorf("failed to set clique checkpoint: %v", err)
+			}
+		}
+	}
+	// Drop the offline clique signers after a chain halt if requested
+	if config.CliqueStallRecovery {
+		if cli := eth.cliqueEngine(); cli != nil {
+			cli.SetStallRecovery(true)
+		}
+	}
+	// Order the local clique votes by the requested strategy
+	if config.CliqueProposalStrategy != "" {
+		if cli := eth.cliqueEngine(); cli != nil {
+			if err := cli.SetProposalStrategy(config.CliqueProposalStrategy); err != nil {
+				return nil, fmt.Errorf("failed to set clique proposal strategy: %v", err)
+			}
+		}
+	}
+	// Cap the clique header verification workers if requested
+	if config.CliqueVerifyWorkers > 0 {
+		if cli := eth.cliqueEngine(); cli != nil {
+			cli.SetVerifyWorkers(config.CliqueVerifyWorkers)
+		}
+	}
+	// Thin out the clique snapshots kept in memory if requested
+	if config.CliqueSnapshotMemoryInterval > 1 {
+		if cli := eth.cliqueEngine(); cli != nil {
+			cli.SetSnapshotMemoryInterval(config.CliqueSnapshotMemoryInterval)
+		}
+	}
+	// Page the operators on clique consensus anomalies if requested
+	if config.CliqueAlertWebhook != "" || config.CliqueAlertExec != "" {
+		if cli := eth.cliqueEngine(); cli != nil {
+			var hooks clique.AlertHooks
+			if config.CliqueAlertWebhook != "" {
+				hooks = append(hooks, clique.WebhookAlertHook(config.CliqueAlertWebhook))
+			}
+			if command := strings.Fields(config.CliqueAlertExec); len(command) > 0 {
+				hooks = append(hooks, clique.ExecAlertHook(command[0], command[1:]...))
+			}
+			cli.SetAlertHook(hooks)
+		}
+	}
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
@@ -202,6 +266,12 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Upgrade the clique database layout before the snapshots are first accessed
+	if cli := eth.cliqueEngine(); cli != nil {
+		if err := cli.MigrateSchema(eth.blockchain); err != nil {
+			return nil, fmt.Errorf("failed to migrate clique database: %v", err)
+		}
+	}
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -210,10 +280,41 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	if consumer, ok := eth.engine.(consensus.StateConsumer); ok {
+		consumer.SetStateReader(func(hash common.Hash) (*state.StateDB, error) {
+			header := eth.blockchain.GetHeaderByHash(hash)
+			if header == nil {
+				return nil, fmt.Errorf("unknown block %x", hash)
+			}
+			return eth.blockchain.StateAt(header.Root)
+		})
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
+	if permitter, ok := eth.engine.(consensus.SenderPermitter); ok {
+		eth.txPool.SetSenderFilter(func(sender common.Address) bool {
+			return permitter.PermitsSender(eth.blockchain, eth.blockchain.CurrentHeader(), sender)
+		})
+	}
+	if exempter, ok := eth.engine.(consensus.FeeExempter); ok {
+		eth.txPool.SetFeeExemption(func(sender common.Address, tx *types.Transaction) bool {
+			return exempter.ExemptsFee(eth.blockchain, eth.blockchain.CurrentHeader(), sender, tx)
+		})
+	}
+
+	// Report the clique quorum status, rejecting transactions while it's lost if requested
+	if cli := eth.cliqueEngine(); cli != nil {
+		stack.RegisterHandler("Clique health", "/clique/health", cli.HealthHandler(eth.blockchain))
+		stack.RegisterHandler("Consensus readiness", "/health/consensus", cli.ReadinessHandler(eth.blockchain, eth.Synced))
+		if config.CliqueRejectTxsDegraded {
+			eth.txPool.SetAdmissionGate(func() error {
+				return cli.CheckQuorum(eth.blockchain)
+			})
+		}
+	}
 
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
@@ -237,6 +338,12 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}); err != nil {
 		return nil, err
 	}
+	// Bootstrap the clique checkpoint snapshots from the peers if requested
+	if config.CliqueBootstrap == "" && config.CliquePeerBootstrap {
+		if cli := eth.cliqueEngine(); cli != nil {
+			cli.SetSnapshotSource(eth.handler.cliqueSnapshotSource(), false)
+		}
+	}
 
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
@@ -469,15 +576,7 @@ func (s *Ethereum) StartMining(threads int) error {
 			log.Error("Cannot start mining without etherbase", "err", err)
 			return fmt.Errorf("etherbase missing: %v", err)
 		}
-		var cli *clique.Clique
-		if c, ok := s.engine.(*clique.Clique); ok {
-			cli = c
-		} else if cl, ok := s.engine.(*beacon.Beacon); ok {
-			if c, ok := cl.InnerEngine().(*clique.Clique); ok {
-				cli = c
-			}
-		}
-		if cli != nil {
+		if cli := s.cliqueEngine(); cli != nil {
 			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
 			if wallet == nil || err != nil {
 				log.Error("Etherbase account unavailable locally", "err", err)
@@ -494,6 +593,47 @@ func (s *Ethereum) StartMining(threads int) error {
 	return nil
 }
 
+// cliqueEngine returns the clique consensus engine, if the node is running one
+// (directly or wrapped into the beacon engine).
+func (s *Ethereum) cliqueEngine() *clique.Clique {
+	if c, ok := s.engine.(*clique.Clique); ok {
+		return c
+	}
+	if cl, ok := s.engine.(*beacon.Beacon); ok {
+		if c, ok := cl.InnerEngine().(*clique.Clique); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// cliqueHeadLoop feeds the canonical chain heads into the clique engine to keep
+// its snapshot cache anchored on the canonical chain across reorgs, and marks the
+// blocks finalized by the signers in the chain.
+func (s *Ethereum) cliqueHeadLoop(engine *clique.Clique) {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.blockchain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	newHead := func(head *types.Header) {
+		engine.NewChainHead(s.blockchain, head)
+		if final := engine.Finalized(s.blockchain, head); final != nil {
+			if block := s.blockchain.GetBlock(final.Hash(), final.Number.Uint64()); block != nil {
+				s.blockchain.SetFinalized(block)
+			}
+		}
+	}
+	newHead(s.blockchain.CurrentHeader())
+	for {
+		select {
+		case ev := <-heads:
+			newHead(ev.Block.Header())
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
 // StopMining terminates the miner, both at the consensus engine level as well as
 // at the block creation level.
 func (s *Ethereum) StopMining() {
@@ -550,6 +690,11 @@ func (s *Ethereum) Start() error {
 	// Regularly update shutdown marker
 	s.shutdownTracker.Start()
 
+	// Keep the clique snapshots consistent with the canonical chain
+	if cli := s.cliqueEngine(); cli != nil {
+		go s.cliqueHeadLoop(cli)
+	}
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
 	if s.config.LightServ > 0 {
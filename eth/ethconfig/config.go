@@ -83,6 +83,7 @@ var Defaults = Config{
 	TrieDirtyCache:          256,
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
+	CliqueSigCache:          4096,
 	Miner: miner.Config{
 		GasCeil:  8000000,
 		GasPrice: big.NewInt(params.GWei),
@@ -143,6 +144,56 @@ type Config struct {
 	// presence of these blocks for every new peer connection.
 	PeerRequiredBlocks map[uint64]common.Hash `toml:"-"`
 
+	// CliqueBootstrap is the RPC endpoint of a trusted node to retrieve clique
+	// checkpoint snapshots from, instead of replaying the headers preceding them.
+	CliqueBootstrap string `toml:",omitempty"`
+
+	// CliquePeerBootstrap enables retrieving clique checkpoint snapshots from the
+	// connected peers if no bootstrap endpoint is configured. Being untrusted, peer
+	// snapshots are only used at checkpoints committing to their whole state.
+	CliquePeerBootstrap bool `toml:",omitempty"`
+
+	// CliqueSigCache is the number of recovered clique block signers to keep in
+	// memory, shared by all the clique engines of the node.
+	CliqueSigCache int `toml:",omitempty"`
+
+	// CliqueSigCachePersist is the number of recovered clique block signers to
+	// persist into the database, so restarts don't recover them again (0 = none).
+	CliqueSigCachePersist int `toml:",omitempty"`
+
+	// CliqueCheckpoint is an operator-supplied clique checkpoint to start validating
+	// from, overriding the one published in the chain configuration.
+	CliqueCheckpoint *params.CliqueCheckpoint `toml:",omitempty"`
+
+	// CliqueAlertWebhook is the URL to post the JSON encoded clique consensus
+	// anomalies to, e.g. to page the operators.
+	CliqueAlertWebhook string `toml:",omitempty"`
+
+	// CliqueAlertExec is a command to run for every clique consensus anomaly, fed
+	// the JSON encoded anomaly on its standard input.
+	CliqueAlertExec string `toml:",omitempty"`
+
+	// CliqueRejectTxsDegraded rejects all new transactions while the clique chain
+	// is stalled because too many signers went offline.
+	CliqueRejectTxsDegraded bool `toml:",omitempty"`
+
+	// CliqueStallRecovery makes the local clique signer propose dropping the
+	// signers that went offline once the chain resumes after a prolonged halt.
+	CliqueStallRecovery bool `toml:",omitempty"`
+
+	// CliqueProposalStrategy is the order the local clique signer votes on its
+	// queued authorization proposals in (random, fifo, priority, roundrobin).
+	CliqueProposalStrategy string `toml:",omitempty"`
+
+	// CliqueVerifyWorkers is the hard cap of the workers recovering the signers
+	// of clique header batches in parallel (0 = number of cores).
+	CliqueVerifyWorkers int `toml:",omitempty"`
+
+	// CliqueSnapshotMemoryInterval is the block interval of the clique voting
+	// snapshots kept in memory, the ones in between being recomputed on demand
+	// (0 = every block).
+	CliqueSnapshotMemoryInterval uint64 `toml:",omitempty"`
+
 	// Light client options
 	LightServ          int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightIngress       int  `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -215,12 +266,26 @@ type Config struct {
 	DoBurnTxFee            bool
 }
 
+// CreateCliqueSigCache creates the clique signature cache of the node, persisting
+// the recovered signers into the given database if configured to.
+func CreateCliqueSigCache(config *Config, db ethdb.Database) *clique.SigCache {
+	if config.CliqueSigCachePersist <= 0 {
+		return clique.NewSigCache(config.CliqueSigCache, nil)
+	}
+	sigcache := clique.NewSigCache(config.CliqueSigCache, db)
+	sigcache.SetPersistLimit(config.CliqueSigCachePersist)
+	return sigcache
+}
+
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
-func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, config *ethash.Config, notify []string, noverify bool, db ethdb.Database) consensus.Engine {
+func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, config *ethash.Config, notify []string, noverify bool, db ethdb.Database, sigcache *clique.SigCache) consensus.Engine {
 	// If proof-of-authority is requested, set it up
 	var engine consensus.Engine
 	if chainConfig.Clique != nil {
-		engine = clique.New(chainConfig.Clique, db)
+		if sigcache == nil {
+			sigcache = clique.NewSigCache(0, nil)
+		}
+		engine = clique.NewWithSigCache(chainConfig.Clique, db, sigcache)
 	} else {
 		switch config.PowMode {
 		case ethash.ModeFake:
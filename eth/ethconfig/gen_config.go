@@ -25,20 +25,32 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SnapDiscoveryURLs               []string
 		NoPruning                       bool
 		NoPrefetch                      bool
-		TxLookupLimit                   uint64                 `toml:",omitempty"`
-		PeerRequiredBlocks              map[uint64]common.Hash `toml:"-"`
-		LightServ                       int                    `toml:",omitempty"`
-		LightIngress                    int                    `toml:",omitempty"`
-		LightEgress                     int                    `toml:",omitempty"`
-		LightPeers                      int                    `toml:",omitempty"`
-		LightNoPrune                    bool                   `toml:",omitempty"`
-		LightNoSyncServe                bool                   `toml:",omitempty"`
-		SyncFromCheckpoint              bool                   `toml:",omitempty"`
-		UltraLightServers               []string               `toml:",omitempty"`
-		UltraLightFraction              int                    `toml:",omitempty"`
-		UltraLightOnlyAnnounce          bool                   `toml:",omitempty"`
-		SkipBcVersionCheck              bool                   `toml:"-"`
-		DatabaseHandles                 int                    `toml:"-"`
+		TxLookupLimit                   uint64                   `toml:",omitempty"`
+		PeerRequiredBlocks              map[uint64]common.Hash   `toml:"-"`
+		CliqueBootstrap                 string                   `toml:",omitempty"`
+		CliquePeerBootstrap             bool                     `toml:",omitempty"`
+		CliqueSigCache                  int                      `toml:",omitempty"`
+		CliqueSigCachePersist           int                      `toml:",omitempty"`
+		CliqueCheckpoint                *params.CliqueCheckpoint `toml:",omitempty"`
+		CliqueAlertWebhook              string                   `toml:",omitempty"`
+		CliqueAlertExec                 string                   `toml:",omitempty"`
+		CliqueRejectTxsDegraded         bool                     `toml:",omitempty"`
+		CliqueStallRecovery             bool                     `toml:",omitempty"`
+		CliqueProposalStrategy          string                   `toml:",omitempty"`
+		CliqueVerifyWorkers             int                      `toml:",omitempty"`
+		CliqueSnapshotMemoryInterval    uint64                   `toml:",omitempty"`
+		LightServ                       int                      `toml:",omitempty"`
+		LightIngress                    int                      `toml:",omitempty"`
+		LightEgress                     int                      `toml:",omitempty"`
+		LightPeers                      int                      `toml:",omitempty"`
+		LightNoPrune                    bool                     `toml:",omitempty"`
+		LightNoSyncServe                bool                     `toml:",omitempty"`
+		SyncFromCheckpoint              bool                     `toml:",omitempty"`
+		UltraLightServers               []string                 `toml:",omitempty"`
+		UltraLightFraction              int                      `toml:",omitempty"`
+		UltraLightOnlyAnnounce          bool                     `toml:",omitempty"`
+		SkipBcVersionCheck              bool                     `toml:"-"`
+		DatabaseHandles                 int                      `toml:"-"`
 		DatabaseCache                   int
 		DatabaseFreezer                 string
 		TrieCleanCache                  int
@@ -72,6 +84,18 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NoPrefetch = c.NoPrefetch
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.PeerRequiredBlocks = c.PeerRequiredBlocks
+	enc.CliqueBootstrap = c.CliqueBootstrap
+	enc.CliquePeerBootstrap = c.CliquePeerBootstrap
+	enc.CliqueSigCache = c.CliqueSigCache
+	enc.CliqueSigCachePersist = c.CliqueSigCachePersist
+	enc.CliqueCheckpoint = c.CliqueCheckpoint
+	enc.CliqueAlertWebhook = c.CliqueAlertWebhook
+	enc.CliqueAlertExec = c.CliqueAlertExec
+	enc.CliqueRejectTxsDegraded = c.CliqueRejectTxsDegraded
+	enc.CliqueStallRecovery = c.CliqueStallRecovery
+	enc.CliqueProposalStrategy = c.CliqueProposalStrategy
+	enc.CliqueVerifyWorkers = c.CliqueVerifyWorkers
+	enc.CliqueSnapshotMemoryInterval = c.CliqueSnapshotMemoryInterval
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
 	enc.LightEgress = c.LightEgress
@@ -119,20 +143,32 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SnapDiscoveryURLs               []string
 		NoPruning                       *bool
 		NoPrefetch                      *bool
-		TxLookupLimit                   *uint64                `toml:",omitempty"`
-		PeerRequiredBlocks              map[uint64]common.Hash `toml:"-"`
-		LightServ                       *int                   `toml:",omitempty"`
-		LightIngress                    *int                   `toml:",omitempty"`
-		LightEgress                     *int                   `toml:",omitempty"`
-		LightPeers                      *int                   `toml:",omitempty"`
-		LightNoPrune                    *bool                  `toml:",omitempty"`
-		LightNoSyncServe                *bool                  `toml:",omitempty"`
-		SyncFromCheckpoint              *bool                  `toml:",omitempty"`
-		UltraLightServers               []string               `toml:",omitempty"`
-		UltraLightFraction              *int                   `toml:",omitempty"`
-		UltraLightOnlyAnnounce          *bool                  `toml:",omitempty"`
-		SkipBcVersionCheck              *bool                  `toml:"-"`
-		DatabaseHandles                 *int                   `toml:"-"`
+		TxLookupLimit                   *uint64                  `toml:",omitempty"`
+		PeerRequiredBlocks              map[uint64]common.Hash   `toml:"-"`
+		CliqueBootstrap                 *string                  `toml:",omitempty"`
+		CliquePeerBootstrap             *bool                    `toml:",omitempty"`
+		CliqueSigCache                  *int                     `toml:",omitempty"`
+		CliqueSigCachePersist           *int                     `toml:",omitempty"`
+		CliqueCheckpoint                *params.CliqueCheckpoint `toml:",omitempty"`
+		CliqueAlertWebhook              *string                  `toml:",omitempty"`
+		CliqueAlertExec                 *string                  `toml:",omitempty"`
+		CliqueRejectTxsDegraded         *bool                    `toml:",omitempty"`
+		CliqueStallRecovery             *bool                    `toml:",omitempty"`
+		CliqueProposalStrategy          *string                  `toml:",omitempty"`
+		CliqueVerifyWorkers             *int                     `toml:",omitempty"`
+		CliqueSnapshotMemoryInterval    *uint64                  `toml:",omitempty"`
+		LightServ                       *int                     `toml:",omitempty"`
+		LightIngress                    *int                     `toml:",omitempty"`
+		LightEgress                     *int                     `toml:",omitempty"`
+		LightPeers                      *int                     `toml:",omitempty"`
+		LightNoPrune                    *bool                    `toml:",omitempty"`
+		LightNoSyncServe                *bool                    `toml:",omitempty"`
+		SyncFromCheckpoint              *bool                    `toml:",omitempty"`
+		UltraLightServers               []string                 `toml:",omitempty"`
+		UltraLightFraction              *int                     `toml:",omitempty"`
+		UltraLightOnlyAnnounce          *bool                    `toml:",omitempty"`
+		SkipBcVersionCheck              *bool                    `toml:"-"`
+		DatabaseHandles                 *int                     `toml:"-"`
 		DatabaseCache                   *int
 		DatabaseFreezer                 *string
 		TrieCleanCache                  *int
@@ -187,6 +223,42 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.PeerRequiredBlocks != nil {
 		c.PeerRequiredBlocks = dec.PeerRequiredBlocks
 	}
+	if dec.CliqueBootstrap != nil {
+		c.CliqueBootstrap = *dec.CliqueBootstrap
+	}
+	if dec.CliquePeerBootstrap != nil {
+		c.CliquePeerBootstrap = *dec.CliquePeerBootstrap
+	}
+	if dec.CliqueSigCache != nil {
+		c.CliqueSigCache = *dec.CliqueSigCache
+	}
+	if dec.CliqueSigCachePersist != nil {
+		c.CliqueSigCachePersist = *dec.CliqueSigCachePersist
+	}
+	if dec.CliqueCheckpoint != nil {
+		c.CliqueCheckpoint = dec.CliqueCheckpoint
+	}
+	if dec.CliqueAlertWebhook != nil {
+		c.CliqueAlertWebhook = *dec.CliqueAlertWebhook
+	}
+	if dec.CliqueAlertExec != nil {
+		c.CliqueAlertExec = *dec.CliqueAlertExec
+	}
+	if dec.CliqueRejectTxsDegraded != nil {
+		c.CliqueRejectTxsDegraded = *dec.CliqueRejectTxsDegraded
+	}
+	if dec.CliqueStallRecovery != nil {
+		c.CliqueStallRecovery = *dec.CliqueStallRecovery
+	}
+	if dec.CliqueProposalStrategy != nil {
+		c.CliqueProposalStrategy = *dec.CliqueProposalStrategy
+	}
+	if dec.CliqueVerifyWorkers != nil {
+		c.CliqueVerifyWorkers = *dec.CliqueVerifyWorkers
+	}
+	if dec.CliqueSnapshotMemoryInterval != nil {
+		c.CliqueSnapshotMemoryInterval = *dec.CliqueSnapshotMemoryInterval
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
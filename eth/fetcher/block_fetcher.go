@@ -98,6 +98,10 @@ type chainInsertFn func(types.Blocks) (int, error)
 // peerDropFn is a callback type for dropping a peer detected as malicious.
 type peerDropFn func(id string)
 
+// headerPrewarmFn is a callback type for precomputing the expensive parts of a
+// header's verification (e.g. recovering its seal) ahead of the import.
+type headerPrewarmFn func(header *types.Header)
+
 // blockAnnounce is the hash notification of the availability of a new block in the
 // network.
 type blockAnnounce struct {
@@ -188,6 +192,7 @@ type BlockFetcher struct {
 	insertHeaders  headersInsertFn    // Injects a batch of headers into the chain
 	insertChain    chainInsertFn      // Injects a batch of blocks into the chain
 	dropPeer       peerDropFn         // Drops a peer for misbehaving
+	prewarmHeader  headerPrewarmFn    // Precomputes the seal verification of a queued header (optional)
 
 	// Testing hooks
 	announceChangeHook func(common.Hash, bool)           // Method to call upon adding or deleting a hash from the blockAnnounce list
@@ -226,6 +231,13 @@ func NewBlockFetcher(light bool, getHeader HeaderRetrievalFn, getBlock blockRetr
 	}
 }
 
+// SetHeaderPrewarmer installs a callback run in the background for every newly
+// queued header or block, allowing the consensus engine to warm its caches before
+// the import verifies the block. It must be called before Start.
+func (f *BlockFetcher) SetHeaderPrewarmer(prewarm headerPrewarmFn) {
+	f.prewarmHeader = prewarm
+}
+
 // Start boots up the announcement based synchroniser, accepting and processing
 // hash notifications and block fetches until termination requested.
 func (f *BlockFetcher) Start() {
@@ -774,6 +786,12 @@ func (f *BlockFetcher) enqueue(peer string, header *types.Header, block *types.B
 		if f.queueChangeHook != nil {
 			f.queueChangeHook(hash, true)
 		}
+		if f.prewarmHeader != nil {
+			if header == nil {
+				header = block.Header()
+			}
+			go f.prewarmHeader(header)
+		}
 		log.Debug("Queued delivered header or block", "peer", peer, "number", number, "hash", hash, "queued", f.queue.Size())
 	}
 }
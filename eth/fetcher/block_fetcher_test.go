@@ -655,6 +655,43 @@ func TestImportDeduplication(t *testing.T) {
 	}
 }
 
+// Tests that queued blocks are handed to the header prewarmer exactly once, ahead
+// of their import.
+func TestQueuePrewarming(t *testing.T) {
+	hashes, blocks := makeChain(2, 0, genesis)
+
+	tester := newTester(false)
+	prewarmed := make(chan common.Hash, len(hashes))
+	tester.fetcher.SetHeaderPrewarmer(func(header *types.Header) { prewarmed <- header.Hash() })
+
+	imported := make(chan interface{}, len(hashes)-1)
+	tester.fetcher.importedHook = func(header *types.Header, block *types.Block) { imported <- block }
+
+	// Propagate the blocks out of order, duplicating one of them
+	tester.fetcher.Enqueue("valid", blocks[hashes[0]])
+	tester.fetcher.Enqueue("valid", blocks[hashes[0]])
+	tester.fetcher.Enqueue("valid", blocks[hashes[1]])
+	verifyImportCount(t, imported, 2)
+
+	seen := make(map[common.Hash]bool)
+	for i := 0; i < len(hashes)-1; i++ {
+		select {
+		case hash := <-prewarmed:
+			if seen[hash] {
+				t.Fatalf("block %x prewarmed multiple times", hash)
+			}
+			seen[hash] = true
+		case <-time.After(time.Second):
+			t.Fatalf("block prewarm %d missing", i)
+		}
+	}
+	select {
+	case hash := <-prewarmed:
+		t.Fatalf("unexpected prewarm of %x", hash)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
 // Tests that blocks with numbers much lower or higher than out current head get
 // discarded to prevent wasting resources on useless blocks from faulty peers.
 func TestDistantPropagationDiscarding(t *testing.T) {
@@ -0,0 +1,76 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+)
+
+// cliqueSnapshotTimeout is the maximum time to wait for a peer to serve a clique
+// checkpoint snapshot.
+const cliqueSnapshotTimeout = 5 * time.Second
+
+var (
+	errNoSnapshotPeer  = errors.New("no peer served the snapshot")
+	errSnapshotTimeout = errors.New("snapshot request timed out")
+)
+
+// cliqueSnapshotSource creates a clique snapshot source retrieving checkpoint
+// snapshots from the connected peers, asking them one after the other until one
+// serves it. The engine verifies the snapshot against the checkpoint header.
+func (h *handler) cliqueSnapshotSource() clique.SnapshotSource {
+	return func(number uint64, hash common.Hash) ([]byte, error) {
+		for _, peer := range h.peers.allPeers() {
+			blob, err := requestCliqueSnapshot(peer.Peer, hash)
+			if err != nil {
+				peer.Log().Debug("Failed to retrieve clique snapshot", "number", number, "hash", hash, "err", err)
+				continue
+			}
+			if len(blob) > 0 {
+				return blob, nil
+			}
+		}
+		return nil, errNoSnapshotPeer
+	}
+}
+
+// requestCliqueSnapshot retrieves the clique snapshot of a checkpoint from a peer,
+// returning an empty blob if the peer doesn't have it.
+func requestCliqueSnapshot(peer *eth.Peer, hash common.Hash) ([]byte, error) {
+	resCh := make(chan *eth.Response)
+	req, err := peer.RequestCliqueSnapshot(hash, resCh)
+	if err != nil {
+		return nil, err
+	}
+	defer req.Close()
+
+	timeout := time.NewTimer(cliqueSnapshotTimeout)
+	defer timeout.Stop()
+
+	select {
+	case res := <-resCh:
+		res.Done <- nil
+		return *res.Res.(*eth.CliqueSnapshotPacket), nil
+	case <-timeout.C:
+		return nil, errSnapshotTimeout
+	}
+}
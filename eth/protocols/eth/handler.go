@@ -190,6 +190,8 @@ var eth66 = map[uint64]msgHandler{
 	BridgeMsg:                     handleBridgeMsg,
 	GetHealthCheckMsg:             handleGetHealthCheck,
 	HealthCheckMsg:                handleHealthCheck,
+	GetCliqueSnapshotMsg:          handleGetCliqueSnapshot,
+	CliqueSnapshotMsg:             handleCliqueSnapshot,
 }
 
 // handleMessage is invoked whenever an inbound message is received from a remote
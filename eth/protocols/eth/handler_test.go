@@ -566,3 +566,28 @@ func testGetBlockReceipts(t *testing.T, protocol uint) {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
+
+// Tests that clique snapshot queries are answered with an empty response if the
+// consensus engine can't serve them.
+func TestGetCliqueSnapshot66(t *testing.T) { testGetCliqueSnapshot(t, ETH66) }
+
+func testGetCliqueSnapshot(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	backend := newTestBackend(4)
+	defer backend.close()
+
+	peer, _ := newTestPeer("peer", protocol, backend)
+	defer peer.close()
+
+	p2p.Send(peer.app, GetCliqueSnapshotMsg, &GetCliqueSnapshotPacket66{
+		RequestId:               123,
+		GetCliqueSnapshotPacket: GetCliqueSnapshotPacket{Hash: backend.chain.CurrentBlock().Hash()},
+	})
+	if err := p2p.ExpectMsg(peer.app, CliqueSnapshotMsg, &CliqueSnapshotPacket66{
+		RequestId:            123,
+		CliqueSnapshotPacket: CliqueSnapshotPacket{},
+	}); err != nil {
+		t.Errorf("snapshot mismatch: %v", err)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
@@ -571,6 +572,43 @@ func handleHealthCheck(backend Backend, msg Decoder, peer *Peer) error {
 	}, nil)
 }
 
+func handleGetCliqueSnapshot(backend Backend, msg Decoder, peer *Peer) error {
+	// Decode the clique snapshot retrieval message
+	var query GetCliqueSnapshotPacket66
+	if err := msg.Decode(&query); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	response := ServiceGetCliqueSnapshotQuery(backend.Chain(), query.Hash)
+	return peer.ReplyCliqueSnapshot(query.RequestId, response)
+}
+
+// ServiceGetCliqueSnapshotQuery assembles the response to a clique checkpoint
+// snapshot query, leaving it empty if the consensus engine can't serve it.
+func ServiceGetCliqueSnapshotQuery(chain *core.BlockChain, hash common.Hash) CliqueSnapshotPacket {
+	server, ok := chain.Engine().(consensus.SnapshotServer)
+	if !ok {
+		return nil
+	}
+	blob, err := server.CheckpointSnapshot(hash)
+	if err != nil || len(blob) > softResponseLimit {
+		return nil
+	}
+	return blob
+}
+
+func handleCliqueSnapshot(backend Backend, msg Decoder, peer *Peer) error {
+	// A clique snapshot arrived to one of our previous requests
+	res := new(CliqueSnapshotPacket66)
+	if err := msg.Decode(res); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	return peer.dispatchResponse(&Response{
+		id:   res.RequestId,
+		code: CliqueSnapshotMsg,
+		Res:  &res.CliqueSnapshotPacket,
+	}, nil)
+}
+
 func handleBridgeMsg(backend Backend, msg Decoder, peer *Peer) error {
 	res := new(BridgeMsgPacket66)
 	if err := msg.Decode(res); err != nil {
@@ -33,8 +33,8 @@ const (
 )
 
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter) error {
+// network IDs, difficulties, head and genesis blocks and consensus rule versions.
+func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter, consensus uint64) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 
@@ -48,10 +48,11 @@ func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			Head:            head,
 			Genesis:         genesis,
 			ForkID:          forkID,
+			Consensus:       consensus,
 		})
 	}()
 	go func() {
-		errc <- p.readStatus(network, &status, genesis, forkFilter)
+		errc <- p.readStatus(network, &status, genesis, forkFilter, consensus)
 	}()
 	timeout := time.NewTimer(handshakeTimeout)
 	defer timeout.Stop()
@@ -76,7 +77,7 @@ func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 }
 
 // readStatus reads the remote handshake message.
-func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.Hash, forkFilter forkid.Filter) error {
+func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.Hash, forkFilter forkid.Filter, consensus uint64) error {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -103,5 +104,10 @@ func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.H
 	if err := forkFilter(status.ForkID); err != nil {
 		return fmt.Errorf("%w: %v", errForkIDRejected, err)
 	}
+	// Peers predating the version negotiation omit it, they're left to the fork
+	// ID checks instead of being dropped mid rolling upgrade
+	if status.Consensus != 0 && status.Consensus != consensus {
+		return fmt.Errorf("%w: %d (!= %d)", errConsensusMismatch, status.Consensus, consensus)
+	}
 	return nil
 }
@@ -41,6 +41,7 @@ func testHandshake(t *testing.T, protocol uint) {
 		head    = backend.chain.CurrentBlock()
 		td      = backend.chain.GetTd(head.Hash(), head.NumberU64())
 		forkID  = forkid.NewID(backend.chain.Config(), backend.chain.Genesis().Hash(), backend.chain.CurrentHeader().Number.Uint64())
+		version = backend.chain.Config().ConsensusVersion()
 	)
 	tests := []struct {
 		code uint64
@@ -52,21 +53,25 @@ func testHandshake(t *testing.T, protocol uint) {
 			want: errNoStatusMsg,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{10, 1, td, head.Hash(), genesis.Hash(), forkID},
+			code: StatusMsg, data: StatusPacket{10, 1, td, head.Hash(), genesis.Hash(), forkID, version},
 			want: errProtocolVersionMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), forkID},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 999, td, head.Hash(), genesis.Hash(), forkID, version},
 			want: errNetworkIDMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), common.Hash{3}, forkID},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), common.Hash{3}, forkID, version},
 			want: errGenesisMismatch,
 		},
 		{
-			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}},
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}, version},
 			want: errForkIDRejected,
 		},
+		{
+			code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkID, version + 1},
+			want: errConsensusMismatch,
+		},
 	}
 	for i, test := range tests {
 		// Create the two peers to shake with each other
@@ -80,7 +85,7 @@ func testHandshake(t *testing.T, protocol uint) {
 		// Send the junk test with one peer, check the handshake failure
 		go p2p.Send(app, test.code, test.data)
 
-		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain))
+		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), version)
 		if err == nil {
 			t.Errorf("test %d: protocol returned nil error, want %q", i, test.want)
 		} else if !errors.Is(err, test.want) {
@@ -88,3 +93,37 @@ func testHandshake(t *testing.T, protocol uint) {
 		}
 	}
 }
+
+// Tests that peers omitting the consensus rules version, i.e. ones predating its
+// negotiation, are not dropped by upgraded nodes.
+func TestHandshakeLegacyConsensus66(t *testing.T) { testHandshakeLegacyConsensus(t, ETH66) }
+
+func testHandshakeLegacyConsensus(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	backend := newTestBackend(3)
+	defer backend.close()
+
+	var (
+		genesis = backend.chain.Genesis()
+		head    = backend.chain.CurrentBlock()
+		td      = backend.chain.GetTd(head.Hash(), head.NumberU64())
+		forkID  = forkid.NewID(backend.chain.Config(), backend.chain.Genesis().Hash(), backend.chain.CurrentHeader().Number.Uint64())
+	)
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	peer := NewPeer(protocol, p2p.NewPeer(enode.ID{}, "peer", nil), net, nil)
+	defer peer.Close()
+
+	go p2p.Send(app, StatusMsg, StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkID, 0})
+	go func() {
+		if msg, err := app.ReadMsg(); err == nil {
+			msg.Discard()
+		}
+	}()
+	if err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), 1); err != nil {
+		t.Fatalf("handshake with legacy peer failed: %v", err)
+	}
+}
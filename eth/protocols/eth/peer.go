@@ -475,6 +475,28 @@ func (p *Peer) RequestReceipts(hashes []common.Hash, sink chan *Response) (*Requ
 	return req, nil
 }
 
+// RequestCliqueSnapshot fetches the clique voting snapshot of a checkpoint block
+// from a remote node.
+func (p *Peer) RequestCliqueSnapshot(hash common.Hash, sink chan *Response) (*Request, error) {
+	p.Log().Debug("Fetching clique snapshot", "hash", hash)
+	id := rand.Uint64()
+
+	req := &Request{
+		id:   id,
+		sink: sink,
+		code: GetCliqueSnapshotMsg,
+		want: CliqueSnapshotMsg,
+		data: &GetCliqueSnapshotPacket66{
+			RequestId:               id,
+			GetCliqueSnapshotPacket: GetCliqueSnapshotPacket{Hash: hash},
+		},
+	}
+	if err := p.dispatchRequest(req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
 // RequestTxs fetches a batch of transactions from a remote node.
 func (p *Peer) RequestTxs(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of transactions", "count", len(hashes))
@@ -535,3 +557,11 @@ func (p *Peer) SendBrBridgeMsg(id uint64, msg *BridgeMsgPacket) error {
 		BridgeMsgPacket: msg,
 	})
 }
+
+// ReplyCliqueSnapshot is the eth/66 response to GetCliqueSnapshot.
+func (p *Peer) ReplyCliqueSnapshot(id uint64, snapshot CliqueSnapshotPacket) error {
+	return p2p.Send(p.rw, CliqueSnapshotMsg, &CliqueSnapshotPacket66{
+		RequestId:            id,
+		CliqueSnapshotPacket: snapshot,
+	})
+}
@@ -50,7 +50,7 @@ var ProtocolVersions = []uint{ETH66}
 
 // protocolLengths are the number of implemented message corresponding to
 // different protocol versions.
-var protocolLengths = map[uint]uint64{ETH66: 22}
+var protocolLengths = map[uint]uint64{ETH66: 24}
 
 // maxMessageSize is the maximum cap on the size of a protocol message.
 const maxMessageSize = 10 * 1024 * 1024
@@ -74,6 +74,8 @@ const (
 	BridgeMsg                     = 0x13
 	GetHealthCheckMsg             = 0x14
 	HealthCheckMsg                = 0x15
+	GetCliqueSnapshotMsg          = 0x16
+	CliqueSnapshotMsg             = 0x17
 )
 
 const (
@@ -89,6 +91,7 @@ var (
 	errNetworkIDMismatch       = errors.New("network ID mismatch")
 	errGenesisMismatch         = errors.New("genesis mismatch")
 	errForkIDRejected          = errors.New("fork ID rejected")
+	errConsensusMismatch       = errors.New("consensus rules version mismatch")
 )
 
 // Packet represents a p2p message in the `eth` protocol.
@@ -105,6 +108,7 @@ type StatusPacket struct {
 	Head            common.Hash
 	Genesis         common.Hash
 	ForkID          forkid.ID
+	Consensus       uint64 `rlp:"optional"` // Version of the consensus rules, omitted by vanilla nodes
 }
 
 // NewBlockHashesPacket is the network packet for the block announcements.
@@ -538,6 +542,27 @@ func (*BlockBodiesPacket) Kind() byte   { return BlockBodiesMsg }
 func (*NewBlockPacket) Name() string { return "NewBlock" }
 func (*NewBlockPacket) Kind() byte   { return NewBlockMsg }
 
+// GetCliqueSnapshotPacket represents a clique checkpoint snapshot query.
+type GetCliqueSnapshotPacket struct {
+	Hash common.Hash // Hash of the checkpoint block to retrieve the snapshot of
+}
+
+// GetCliqueSnapshotPacket66 represents a clique checkpoint snapshot query over eth/66.
+type GetCliqueSnapshotPacket66 struct {
+	RequestId uint64
+	GetCliqueSnapshotPacket
+}
+
+// CliqueSnapshotPacket is the JSON encoded clique snapshot of a checkpoint, or
+// empty if the remote node can't serve it.
+type CliqueSnapshotPacket []byte
+
+// CliqueSnapshotPacket66 represents a clique checkpoint snapshot response over eth/66.
+type CliqueSnapshotPacket66 struct {
+	RequestId uint64
+	CliqueSnapshotPacket
+}
+
 func (*GetNodeDataPacket) Name() string { return "GetNodeData" }
 func (*GetNodeDataPacket) Kind() byte   { return GetNodeDataMsg }
 
@@ -561,3 +586,9 @@ func (*PooledTransactionsPacket) Kind() byte   { return PooledTransactionsMsg }
 
 func (*HealthCheckPacket) Name() string { return "HealthCheck" }
 func (*HealthCheckPacket) Kind() byte   { return HealthCheckMsg }
+
+func (*GetCliqueSnapshotPacket) Name() string { return "GetCliqueSnapshot" }
+func (*GetCliqueSnapshotPacket) Kind() byte   { return GetCliqueSnapshotMsg }
+
+func (*CliqueSnapshotPacket) Name() string { return "CliqueSnapshot" }
+func (*CliqueSnapshotPacket) Kind() byte   { return CliqueSnapshotMsg }
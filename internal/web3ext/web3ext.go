@@ -47,6 +47,12 @@ web3._extend({
 			call: 'clique_getSnapshotAtHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'snapshotHash',
+			call: 'clique_snapshotHash',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getSigners',
 			call: 'clique_getSigners',
@@ -58,11 +64,26 @@ web3._extend({
 			call: 'clique_getSignersAtHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getSignersInfoAtHash',
+			call: 'clique_getSignersInfoAtHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getGovernanceReceipt',
+			call: 'clique_getGovernanceReceipt',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'propose',
 			call: 'clique_propose',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'proposeWithMemo',
+			call: 'clique_propose',
+			params: 3
+		}),
 		new web3._extend.Method({
 			name: 'votingPercentage',
 			call: 'clique_votingpercentage',
@@ -73,17 +94,196 @@ web3._extend({
 			call: 'clique_discard',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'proposeWithPriority',
+			call: 'clique_proposeWithPriority',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'proposePermit',
+			call: 'clique_proposePermit',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'discardPermit',
+			call: 'clique_discardPermit',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'proposePeriod',
+			call: 'clique_proposePeriod',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'discardPeriod',
+			call: 'clique_discardPeriod',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'discardAll',
+			call: 'clique_discardAll'
+		}),
+		new web3._extend.Method({
+			name: 'proposeReplacement',
+			call: 'clique_proposeReplacement',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'discardReplacement',
+			call: 'clique_discardReplacement',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'resign',
+			call: 'clique_resign'
+		}),
+		new web3._extend.Method({
+			name: 'discardResignation',
+			call: 'clique_discardResignation'
+		}),
+		new web3._extend.Method({
+			name: 'setFeeRecipient',
+			call: 'clique_setFeeRecipient',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'status',
 			call: 'clique_status',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'dashboard',
+			call: 'clique_dashboard',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'getSigner',
 			call: 'clique_getSigner',
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'getResolutions',
+			call: 'clique_getResolutions',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getResolutionsBySigner',
+			call: 'clique_getResolutionsBySigner',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'signerHistory',
+			call: 'clique_signerHistory',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getResolutionsByKind',
+			call: 'clique_getResolutionsByKind',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorsAtEpoch',
+			call: 'clique_getValidatorsAtEpoch',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSealStats',
+			call: 'clique_getSealStats',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getRecents',
+			call: 'clique_getRecents',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'inTurn',
+			call: 'clique_inTurn',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getAttestation',
+			call: 'clique_getAttestation',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getSignerProof',
+			call: 'clique_getSignerProof',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getSignerLimitProof',
+			call: 'clique_getSignerLimitProof',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getOfflineSigners',
+			call: 'clique_getOfflineSigners',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getQuorum',
+			call: 'clique_getQuorum',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getRejections',
+			call: 'clique_getRejections',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getMissedSlots',
+			call: 'clique_getMissedSlots',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'importSnapshot',
+			call: 'clique_importSnapshot',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'overrideHash',
+			call: 'clique_overrideHash',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'proposeOverride',
+			call: 'clique_proposeOverride',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'discardOverride',
+			call: 'clique_discardOverride',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'metadataHash',
+			call: 'clique_metadataHash',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'registerMetadata',
+			call: 'clique_registerMetadata',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getRegistry',
+			call: 'clique_getRegistry',
+			params: 0
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -70,6 +70,10 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumb
 	if number == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentHeader(), nil
 	}
+	// Finality is tracked by the consensus engine of full nodes only
+	if number == rpc.FinalizedBlockNumber {
+		return nil, errors.New("finalized block not tracked by light clients")
+	}
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(number))
 }
 
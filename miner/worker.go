@@ -1020,6 +1020,12 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 		log.Error("Failed to prepare header for sealing", "err", err)
 		return nil, err
 	}
+	// Credit the fees to the recipient declared by the consensus engine, if any
+	if resolver, ok := w.engine.(consensus.FeeRecipientResolver); ok {
+		if recipient, err := resolver.FeeRecipient(header); err == nil {
+			genParams.coinbase = recipient
+		}
+	}
 	// Could potentially happen if starting to mine in an odd state.
 	// Note genParams.coinbase can be different with header.Coinbase
 	// since clique algorithm can modify the coinbase field in header.
@@ -1056,6 +1062,18 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) {
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
 	pending := w.eth.TxPool().Pending(true)
+
+	// Skip the senders the consensus engine doesn't permit on top of the parent,
+	// the pool might lag behind a permission revocation
+	if permitter, ok := w.engine.(consensus.SenderPermitter); ok {
+		if parent := w.chain.GetHeader(env.header.ParentHash, env.header.Number.Uint64()-1); parent != nil {
+			for account := range pending {
+				if !permitter.PermitsSender(w.chain, parent, account) {
+					delete(pending, account)
+				}
+			}
+		}
+	}
 	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
 	for _, account := range w.eth.TxPool().Locals() {
 		if txs := remoteTxs[account]; len(txs) > 0 {
@@ -1063,6 +1081,28 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) {
 			localTxs[account] = txs
 		}
 	}
+	// Commit the transactions exempt from fees along the local ones, so they
+	// don't compete with the paying ones for block space. Only the leading exempt
+	// transactions of an account are moved, the rest must wait for them by nonce.
+	if exempter, ok := w.engine.(consensus.FeeExempter); ok {
+		if parent := w.chain.GetHeader(env.header.ParentHash, env.header.Number.Uint64()-1); parent != nil {
+			for account, txs := range remoteTxs {
+				exempt := 0
+				for exempt < len(txs) && exempter.ExemptsFee(w.chain, parent, account, txs[exempt]) {
+					exempt++
+				}
+				if exempt == 0 {
+					continue
+				}
+				localTxs[account] = txs[:exempt]
+				if exempt == len(txs) {
+					delete(remoteTxs, account)
+				} else {
+					remoteTxs[account] = txs[exempt:]
+				}
+			}
+		}
+	}
 	if len(localTxs) > 0 {
 		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
 		if w.commitTransactions(env, txs, interrupt) {
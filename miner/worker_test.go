@@ -270,6 +270,62 @@ func testGenerateBlockAndImport(t *testing.T, isClique bool) {
 	}
 }
 
+// Tests that governance votes exempt from the tip rules are admitted by the pool
+// as remote transactions and still make it into the sealed blocks.
+func TestFeeExemptGovernanceClique(t *testing.T) {
+	var (
+		db          = rawdb.NewMemoryDatabase()
+		chainConfig = *params.AllCliqueProtocolChanges
+	)
+	chainConfig.LondonBlock = big.NewInt(0)
+	chainConfig.Clique = &params.CliqueConfig{Period: 1, Epoch: 30000, GovernanceBlock: big.NewInt(0)}
+	engine := clique.New(chainConfig.Clique, db)
+	defer engine.Close()
+
+	b := newTestWorkerBackend(t, &chainConfig, engine, db, 0)
+	b.txPool.SetFeeExemption(func(sender common.Address, tx *types.Transaction) bool {
+		return engine.ExemptsFee(b.chain, b.chain.CurrentHeader(), sender, tx)
+	})
+	w := newWorker(testConfig, &chainConfig, engine, b, new(event.TypeMux), nil, false)
+	w.setEtherbase(testBankAddress)
+	defer w.close()
+
+	// Ignore empty commit here for less noise.
+	w.skipSealHook = func(task *task) bool {
+		return len(task.receipts) == 0
+	}
+	sub := w.mux.Subscribe(core.NewMinedBlockEvent{})
+	defer sub.Unsubscribe()
+
+	// Submit a tipless vote, which would be underpriced without the exemption
+	vote := types.MustSignNewTx(testBankKey, types.LatestSigner(&chainConfig), &types.DynamicFeeTx{
+		ChainID:   chainConfig.ChainID,
+		Nonce:     0,
+		To:        &clique.GovernanceAddress,
+		Gas:       50000,
+		GasFeeCap: big.NewInt(params.InitialBaseFee),
+		GasTipCap: new(big.Int),
+		Data:      clique.GovernanceTxData(clique.ProposalAuthorize, testUserAddress),
+	})
+	if err := b.txPool.AddRemote(vote); err != nil {
+		t.Fatalf("failed to add exempt vote: %v", err)
+	}
+	if locals := b.txPool.Locals(); len(locals) != 0 {
+		t.Fatalf("exempt vote made its sender local: %v", locals)
+	}
+	w.start()
+
+	select {
+	case ev := <-sub.Chan():
+		block := ev.Data.(core.NewMinedBlockEvent).Block
+		if block.Transaction(vote.Hash()) == nil {
+			t.Fatalf("exempt vote missing from mined block %d", block.NumberU64())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timeout")
+	}
+}
+
 func TestEmptyWorkEthash(t *testing.T) {
 	testEmptyWork(t, ethashChainConfig, ethash.NewFaker())
 }
@@ -205,6 +205,12 @@ func (api *privateAdminAPI) StartHTTP(host *string, port *int, cors *string, api
 		}
 	}
 
+	scopes, err := api.node.obtainScopeConfig()
+	if err != nil {
+		return false, err
+	}
+	config.scopes = scopes
+
 	if err := api.node.http.setListenAddr(*host, *port); err != nil {
 		return false, err
 	}
@@ -273,6 +279,12 @@ func (api *privateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 		}
 	}
 
+	scopes, err := api.node.obtainScopeConfig()
+	if err != nil {
+		return false, err
+	}
+	config.scopes = scopes
+
 	// Enable WebSocket on the server.
 	server := api.node.wsServerForPort(*port, false)
 	if err := server.setListenAddr(*host, *port); err != nil {
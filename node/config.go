@@ -205,6 +205,17 @@ type Config struct {
 	// JWTSecret is the hex-encoded jwt secret.
 	JWTSecret string `toml:",omitempty"`
 
+	// RPCScopeSecret is the path to the hex-encoded secret verifying the scoped
+	// JWT tokens accepted on the HTTP and WebSocket RPC endpoints. The scopes a
+	// token grants are listed in its space separated "scope" claim.
+	RPCScopeSecret string `toml:",omitempty"`
+
+	// RPCAPITokens maps the static API tokens accepted on the HTTP and WebSocket
+	// RPC endpoints to the authorization scopes they grant. If either tokens or
+	// a scope secret are configured, requests without a token are granted no
+	// scopes at all.
+	RPCAPITokens map[string][]string `toml:",omitempty"`
+
     CensorshipAdminAddress common.Address
 }
 
@@ -381,6 +381,28 @@ func (n *Node) obtainJWTSecret(cliParam string) ([]byte, error) {
 	return jwtSecret, nil
 }
 
+// obtainScopeConfig loads the configuration of the authorization scopes granted
+// to the clients of the HTTP and WebSocket endpoints, or nil if the endpoints
+// don't restrict access by scope.
+func (n *Node) obtainScopeConfig() (*scopeConfig, error) {
+	if n.config.RPCScopeSecret == "" && len(n.config.RPCAPITokens) == 0 {
+		return nil, nil
+	}
+	config := &scopeConfig{tokens: n.config.RPCAPITokens}
+	if n.config.RPCScopeSecret != "" {
+		data, err := os.ReadFile(n.config.RPCScopeSecret)
+		if err != nil {
+			return nil, err
+		}
+		config.secret = common.FromHex(strings.TrimSpace(string(data)))
+		if len(config.secret) != 32 {
+			log.Error("Invalid RPC scope secret", "path", n.config.RPCScopeSecret, "length", len(config.secret))
+			return nil, errors.New("invalid RPC scope secret")
+		}
+	}
+	return config, nil
+}
+
 // startRPC is a helper method to configure all the various RPC endpoints during node
 // startup. It's not meant to be called at any time afterwards as it makes certain
 // assumptions about the state of the node.
@@ -399,6 +421,10 @@ func (n *Node) startRPC() error {
 		servers   []*httpServer
 		open, all = n.GetAPIs()
 	)
+	scopes, err := n.obtainScopeConfig()
+	if err != nil {
+		return err
+	}
 
 	initHttp := func(server *httpServer, apis []rpc.API, port int) error {
 		if err := server.setListenAddr(n.config.HTTPHost, port); err != nil {
@@ -409,6 +435,7 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			scopes:             scopes,
 		}); err != nil {
 			return err
 		}
@@ -425,6 +452,7 @@ func (n *Node) startRPC() error {
 			Modules: n.config.WSModules,
 			Origins: n.config.WSOrigins,
 			prefix:  n.config.WSPathPrefix,
+			scopes:  scopes,
 		}); err != nil {
 			return err
 		}
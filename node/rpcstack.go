@@ -39,16 +39,18 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
-	prefix             string // path prefix on which to mount http handler
-	jwtSecret          []byte // optional JWT secret
+	prefix             string       // path prefix on which to mount http handler
+	jwtSecret          []byte       // optional JWT secret
+	scopes             *scopeConfig // optional authorization scopes of clients
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
 	Origins   []string
 	Modules   []string
-	prefix    string // path prefix on which to mount ws handler
-	jwtSecret []byte // optional JWT secret
+	prefix    string       // path prefix on which to mount ws handler
+	jwtSecret []byte       // optional JWT secret
+	scopes    *scopeConfig // optional authorization scopes of clients
 }
 
 type rpcHandler struct {
@@ -286,8 +288,13 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 		return err
 	}
 	h.httpConfig = config
+
+	var handler http.Handler = srv
+	if config.scopes != nil {
+		handler = newScopeHandler(config.scopes, handler)
+	}
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
+		Handler: NewHTTPHandlerStack(handler, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
 		server:  srv,
 	})
 	return nil
@@ -317,8 +324,13 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 		return err
 	}
 	h.wsConfig = config
+
+	handler := srv.WebsocketHandler(config.Origins)
+	if config.scopes != nil {
+		handler = newScopeHandler(config.scopes, handler)
+	}
 	h.wsHandler.Store(&rpcHandler{
-		Handler: NewWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtSecret),
+		Handler: NewWSHandlerStack(handler, config.jwtSecret),
 		server:  srv,
 	})
 	return nil
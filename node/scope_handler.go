@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// scopeConfig is the configuration of the authorization scopes granted to the
+// clients of an RPC endpoint.
+type scopeConfig struct {
+	secret []byte              // secret verifying scoped JWT tokens, nil if none accepted
+	tokens map[string][]string // static API tokens and the scopes they grant
+}
+
+// lookup retrieves the scopes granted by a static API token. All the tokens are
+// compared in constant time, so the lookup doesn't leak how much of a token was
+// guessed right.
+func (config *scopeConfig) lookup(token string) ([]string, bool) {
+	var (
+		scopes []string
+		found  bool
+	)
+	for candidate, granted := range config.tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			scopes, found = granted, true
+		}
+	}
+	return scopes, found
+}
+
+// scopeClaims are the claims of a scoped JWT token.
+type scopeClaims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope"` // Space separated list of granted scopes
+}
+
+type scopeHandler struct {
+	config *scopeConfig
+	next   http.Handler
+}
+
+// newScopeHandler creates a http.Handler granting requests the authorization
+// scopes of the bearer token they carry. Requests without a token are served
+// without any scopes, requests with an unknown or invalid token are rejected.
+func newScopeHandler(config *scopeConfig, next http.Handler) http.Handler {
+	return &scopeHandler{config: config, next: next}
+}
+
+// ServeHTTP implements http.Handler
+func (handler *scopeHandler) ServeHTTP(out http.ResponseWriter, r *http.Request) {
+	var strToken string
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		strToken = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if len(strToken) == 0 {
+		handler.next.ServeHTTP(out, r.WithContext(rpc.WithScopes(r.Context(), nil)))
+		return
+	}
+	if scopes, ok := handler.config.lookup(strToken); ok {
+		handler.next.ServeHTTP(out, r.WithContext(rpc.WithScopes(r.Context(), scopes)))
+		return
+	}
+	if len(handler.config.secret) == 0 {
+		http.Error(out, "unknown token", http.StatusForbidden)
+		return
+	}
+	// Scoped tokens are long lived, only check the expiry if present
+	var claims scopeClaims
+	token, err := jwt.ParseWithClaims(strToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		return handler.config.secret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithoutClaimsValidation())
+
+	switch {
+	case err != nil:
+		http.Error(out, err.Error(), http.StatusForbidden)
+	case !token.Valid:
+		http.Error(out, "invalid token", http.StatusForbidden)
+	case !claims.VerifyExpiresAt(time.Now(), false):
+		http.Error(out, "token is expired", http.StatusForbidden)
+	default:
+		handler.next.ServeHTTP(out, r.WithContext(rpc.WithScopes(r.Context(), strings.Fields(claims.Scope))))
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -366,10 +367,90 @@ func (c *EthashConfig) String() string {
 	return "ethash"
 }
 
+var (
+	// CliqueSignerStateAddress is the system account clique checkpoints commit the
+	// signer set to, from the signer state fork on.
+	CliqueSignerStateAddress = common.HexToAddress("0x000000000000000000000000000000000000c11a")
+
+	// CliqueStatePrecompileAddress is the precompiled contract exposing the signer
+	// set committed to the signer state system account, from the same fork on.
+	CliqueStatePrecompileAddress = common.HexToAddress("0x000000000000000000000000000000000000c11b")
+)
+
+// CliqueRulesVersion is the version of the extended clique consensus rules this
+// node enforces, advertised in the eth handshake so nodes running vanilla clique
+// on the same network disconnect instead of forking at the first extended vote.
+// Rule changes gated by a CliqueConfig fork block are already covered by the fork
+// ID, the version must only be bumped for incompatible changes without a fork.
+const CliqueRulesVersion = 1
+
 // CliqueConfig is the consensus engine configs for proof-of-authority based sealing.
 type CliqueConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+
+	SignerLimit         uint   `json:"signerLimit,omitempty"`         // Initial signer limit, a percentage of the signers unless absolute (default = 50%)
+	AbsoluteSignerLimit bool   `json:"absoluteSignerLimit,omitempty"` // Whether the signer limit is an absolute number of votes instead of a percentage
+	SignerLimitReset    bool   `json:"signerLimitReset,omitempty"`    // Whether to reset the signer limit to the initial one at epochs unless reaffirmed
+	EmergencyOverride   bool   `json:"emergencyOverride,omitempty"`   // Whether a supermajority of signers may replace the signer set at a checkpoint
+	BootstrapSigners    uint   `json:"bootstrapSigners,omitempty"`    // Signer count below which spam protection is suspended and single votes authorize signers
+	RecentsWindow       uint   `json:"recentsWindow,omitempty"`       // Number of blocks within which a signer may only seal once from the recents window fork on (default = half the signers + 1)
+	CheckpointLimit     bool   `json:"checkpointLimit,omitempty"`     // Whether checkpoints commit the signer limit state into their mix digest
+	MaxEpochChanges     uint   `json:"maxEpochChanges,omitempty"`     // Maximum signer set changes per epoch, after which tallies freeze until the next one (0 = unlimited)
+	TallyDecay          uint64 `json:"tallyDecay,omitempty"`          // Number of blocks after which a vote expires unless recast (0 = votes last until the epoch ends)
+	OutOfTurnSealDelay  uint64 `json:"outOfTurnSealDelay,omitempty"`  // Extra seconds an out-of-turn block must be timestamped after the in-turn slot
+	OutOfTurnWiggle     uint64 `json:"outOfTurnWiggle,omitempty"`     // Maximum random broadcast delay of out-of-turn blocks per recent signer, in milliseconds (default = 500)
+	MinSigners          uint   `json:"minSigners,omitempty"`          // Signer count below which the signer set may not be voted or overridden (0 = no floor)
+	MaxSigners          uint   `json:"maxSigners,omitempty"`          // Signer count above which the signer set may not be voted or overridden (0 = no ceiling)
+	ProbationPeriod     uint64 `json:"probationPeriod,omitempty"`     // Number of blocks after its authorization during which a new signer may seal but not vote (0 = none)
+
+	AuthVoteNonce  *hexutil.Uint64 `json:"authVoteNonce,omitempty"`  // Nonce of the votes authorizing a signer (nil = 0xffffffffffffffff)
+	DropVoteNonce  *hexutil.Uint64 `json:"dropVoteNonce,omitempty"`  // Nonce of the votes deauthorizing a signer and of the blocks not voting (nil = 0x0)
+	LimitVoteNonce *hexutil.Uint64 `json:"limitVoteNonce,omitempty"` // Nonce of the votes on the signer limit (nil = 0xfffffff100000000)
+
+	ConfirmWindow uint64 `json:"confirmWindow,omitempty"` // Number of blocks after a membership proposal is opened within which the signers must confirm it (0 = single-phase voting)
+	RevealWindow  uint64 `json:"revealWindow,omitempty"`  // Number of blocks after a deauthorization vote is committed to within which it must be revealed (0 = open voting)
+
+	RewardBlock *big.Int `json:"rewardBlock,omitempty"` // Block number from which sealers are rewarded (nil = no rewards)
+	BlockReward *big.Int `json:"blockReward,omitempty"` // Wei credited to the sealer of every block from the reward fork on
+
+	FeeRecipientBlock *big.Int `json:"feeRecipientBlock,omitempty"` // Block number from which sealers may declare the recipient of their fees (nil = never)
+	GovernanceBlock   *big.Int `json:"governanceBlock,omitempty"`   // Block number from which signers may vote through governance transactions (nil = never)
+	SignerStateBlock  *big.Int `json:"signerStateBlock,omitempty"`  // Block number from which checkpoints commit the signer set to state (nil = never)
+	PermissionBlock   *big.Int `json:"permissionBlock,omitempty"`   // Block number from which only signer-approved senders may transact (nil = never)
+
+	FinalityBlock    *big.Int `json:"finalityBlock,omitempty"`    // Block number from which signers justify ancestors to finalize them (nil = never)
+	FinalityInterval uint64   `json:"finalityInterval,omitempty"` // Number of blocks between the ancestors justified for finality (default = 64)
+
+	AttestationBlock    *big.Int `json:"attestationBlock,omitempty"`    // Block number from which signers attest checkpoints with aggregated BLS signatures (nil = never)
+	SnapshotRootBlock   *big.Int `json:"snapshotRootBlock,omitempty"`   // Block number from which checkpoints commit to the Merkle root of the signer state (nil = never)
+	GovernanceRootBlock *big.Int `json:"governanceRootBlock,omitempty"` // Block number from which the committed root also covers the signer limit cooldowns in force (nil = never)
+	ReceiptBlock        *big.Int `json:"receiptBlock,omitempty"`        // Block number from which sealed blocks carry a receipt of their governance effects in their body (nil = never)
+	SignerPeriodBlock   *big.Int `json:"signerPeriodBlock,omitempty"`   // Block number from which signers may vote specific signers a longer block period (nil = never)
+
+	ResignationBlock *big.Int `json:"resignationBlock,omitempty"` // Block number from which signers may resign from the signer set on their own (nil = never)
+	ReplacementBlock *big.Int `json:"replacementBlock,omitempty"` // Block number from which signers may vote on swapping a signer for another account in one step (nil = never)
+
+	DepositBlock    *big.Int       `json:"depositBlock,omitempty"`    // Block number from which signer candidates must have locked a deposit to be voted in (nil = never)
+	DepositContract common.Address `json:"depositContract,omitempty"` // Contract holding the deposits of the signer candidates
+	MinDeposit      *big.Int       `json:"minDeposit,omitempty"`      // Wei a candidate must have locked in the deposit contract
+
+	RecentsWindowBlock *big.Int `json:"recentsWindowBlock,omitempty"` // Block number from which the recent signer window is decoupled from the signer limit (nil = never)
+
+	StructuredExtraBlock *big.Int `json:"structuredExtraBlock,omitempty"` // Block number from which headers embed an RLP payload in their extra-data instead of positional sections (nil = never, genesis is always positional)
+
+	TrustedCheckpoint *CliqueCheckpoint `json:"trustedCheckpoint,omitempty"` // Governance-published checkpoint to start validating from (nil = replay from genesis)
+}
+
+// CliqueCheckpoint is the consensus state of a clique checkpoint block, trusted
+// to let nodes start validating from it without replaying the voting history
+// preceding it.
+type CliqueCheckpoint struct {
+	Number              uint64           `json:"number"`                  // Number of the checkpoint block, must be an epoch boundary
+	Hash                common.Hash      `json:"hash"`                    // Hash of the checkpoint block
+	Signers             []common.Address `json:"signers"`                 // Authorized signers at the checkpoint
+	SignerLimit         uint             `json:"signerLimit,omitempty"`   // Signer limit at the checkpoint (0 = initial signer limit)
+	SignerLimitAffirmed uint64           `json:"limitAffirmed,omitempty"` // Block number where the signer limit was last set or reaffirmed
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -377,6 +458,86 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// IsReward returns whether num is either equal to the sealer reward fork block or greater.
+func (c *CliqueConfig) IsReward(num *big.Int) bool {
+	return isForked(c.RewardBlock, num)
+}
+
+// IsFeeRecipient returns whether num is either equal to the fee recipient fork block or greater.
+func (c *CliqueConfig) IsFeeRecipient(num *big.Int) bool {
+	return isForked(c.FeeRecipientBlock, num)
+}
+
+// IsGovernance returns whether num is either equal to the governance transaction fork block or greater.
+func (c *CliqueConfig) IsGovernance(num *big.Int) bool {
+	return isForked(c.GovernanceBlock, num)
+}
+
+// IsSignerState returns whether num is either equal to the signer state fork block or greater.
+func (c *CliqueConfig) IsSignerState(num *big.Int) bool {
+	return isForked(c.SignerStateBlock, num)
+}
+
+// IsPermissioned returns whether num is either equal to the sender permission fork block or greater.
+func (c *CliqueConfig) IsPermissioned(num *big.Int) bool {
+	return isForked(c.PermissionBlock, num)
+}
+
+// IsFinality returns whether num is either equal to the finality vote fork block or greater.
+func (c *CliqueConfig) IsFinality(num *big.Int) bool {
+	return isForked(c.FinalityBlock, num)
+}
+
+// IsAttestation returns whether num is either equal to the checkpoint attestation fork block or greater.
+func (c *CliqueConfig) IsAttestation(num *big.Int) bool {
+	return isForked(c.AttestationBlock, num)
+}
+
+// IsSnapshotRoot returns whether num is either equal to the snapshot root fork block or greater.
+func (c *CliqueConfig) IsSnapshotRoot(num *big.Int) bool {
+	return isForked(c.SnapshotRootBlock, num)
+}
+
+// IsGovernanceRoot returns whether num is either equal to the governance root fork block or greater.
+func (c *CliqueConfig) IsGovernanceRoot(num *big.Int) bool {
+	return isForked(c.GovernanceRootBlock, num)
+}
+
+// IsReceipt returns whether num is either equal to the governance receipt fork block or greater.
+func (c *CliqueConfig) IsReceipt(num *big.Int) bool {
+	return isForked(c.ReceiptBlock, num)
+}
+
+// IsSignerPeriod returns whether num is either equal to the signer period fork block or greater.
+func (c *CliqueConfig) IsSignerPeriod(num *big.Int) bool {
+	return isForked(c.SignerPeriodBlock, num)
+}
+
+// IsResignation returns whether num is either equal to the signer resignation fork block or greater.
+func (c *CliqueConfig) IsResignation(num *big.Int) bool {
+	return isForked(c.ResignationBlock, num)
+}
+
+// IsReplacement returns whether num is either equal to the signer replacement fork block or greater.
+func (c *CliqueConfig) IsReplacement(num *big.Int) bool {
+	return isForked(c.ReplacementBlock, num)
+}
+
+// IsDeposit returns whether num is either equal to the candidate deposit fork block or greater.
+func (c *CliqueConfig) IsDeposit(num *big.Int) bool {
+	return isForked(c.DepositBlock, num)
+}
+
+// IsRecentsWindow returns whether num is either equal to the recents window fork block or greater.
+func (c *CliqueConfig) IsRecentsWindow(num *big.Int) bool {
+	return isForked(c.RecentsWindowBlock, num)
+}
+
+// IsStructuredExtra returns whether num is either equal to the structured extra-data fork block or greater.
+func (c *CliqueConfig) IsStructuredExtra(num *big.Int) bool {
+	return isForked(c.StructuredExtraBlock, num)
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -477,6 +638,15 @@ func (c *ChainConfig) IsArrowGlacier(num *big.Int) bool {
 	return isForked(c.ArrowGlacierBlock, num)
 }
 
+// ConsensusVersion returns the version of the consensus rules the chain runs
+// under, zero if they are the vanilla ones of the configured engine.
+func (c *ChainConfig) ConsensusVersion() uint64 {
+	if c.Clique != nil {
+		return CliqueRulesVersion
+	}
+	return 0
+}
+
 // IsTerminalPoWBlock returns whether the given block is the last block of PoW stage.
 func (c *ChainConfig) IsTerminalPoWBlock(parentTotalDiff *big.Int, totalDiff *big.Int) bool {
 	if c.TerminalTotalDifficulty == nil {
@@ -153,6 +153,9 @@ const (
 	Bls12381MapG1Gas          uint64 = 5500   // Gas price for BLS12-381 mapping field element to G1 operation
 	Bls12381MapG2Gas          uint64 = 110000 // Gas price for BLS12-381 mapping field element to G2 operation
 
+	CliqueStateBaseGas uint64 = 100 // Base price of a call to the clique consensus state precompile
+	CliqueStateReadGas uint64 = 800 // Price of every storage slot read by the clique consensus state precompile
+
 	// The Refund Quotient is the cap on how much of the used gas can be refunded. Before EIP-3529,
 	// up to half the consumed gas could be refunded. Redefined as 1/5th in EIP-3529
 	RefundQuotient        uint64 = 2
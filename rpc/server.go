@@ -169,10 +169,48 @@ type PeerInfo struct {
 		Origin    string
 		Host      string
 	}
+
+	// Authorization scopes granted to the client by the endpoint, nil if the
+	// endpoint doesn't restrict access by scope.
+	Scopes []string
+}
+
+// HasScope reports whether the client is granted the given authorization scope.
+// Clients of endpoints not restricting access by scope, e.g. IPC or in-process
+// ones, are granted every scope.
+func (info PeerInfo) HasScope(scope string) bool {
+	if info.Scopes == nil {
+		return true
+	}
+	for _, granted := range info.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
 }
 
 type peerInfoContextKey struct{}
 
+type scopesContextKey struct{}
+
+// WithScopes returns a copy of the HTTP request context carrying the authorization
+// scopes granted to the client, to be reported in its PeerInfo. It's meant to be
+// used by HTTP middleware authenticating requests before they reach the server.
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return context.WithValue(ctx, scopesContextKey{}, scopes)
+}
+
+// scopesFromContext returns the authorization scopes attached to a request
+// context, or nil if none were.
+func scopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(scopesContextKey{}).([]string)
+	return scopes
+}
+
 // PeerInfoFromContext returns information about the client's network connection.
 // Use this with the context passed to RPC method handler functions.
 //
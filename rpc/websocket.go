@@ -61,6 +61,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn, r.Host, r.Header)
+		codec.(*websocketCodec).info.Scopes = scopesFromContext(r.Context())
 		s.ServeCodec(codec, 0)
 	})
 }
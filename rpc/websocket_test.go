@@ -152,6 +152,35 @@ func TestWebsocketPeerInfo(t *testing.T) {
 	}
 }
 
+// This test checks that the authorization scopes attached to the upgrade request
+// are reported for the whole WebSocket connection.
+func TestWebsocketScopes(t *testing.T) {
+	var (
+		s       = newTestServer()
+		handler = s.WebsocketHandler([]string{"*"})
+		ts      = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(WithScopes(r.Context(), []string{"admin"})))
+		}))
+		tsurl = "ws:" + strings.TrimPrefix(ts.URL, "http:")
+	)
+	defer s.Stop()
+	defer ts.Close()
+
+	c, err := DialWebsocket(context.Background(), tsurl, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var connInfo PeerInfo
+	if err := c.Call(&connInfo, "test_peerInfo"); err != nil {
+		t.Fatal(err)
+	}
+	if !connInfo.HasScope("admin") || connInfo.HasScope("other") {
+		t.Errorf("wrong Scopes %v", connInfo.Scopes)
+	}
+}
+
 // This test checks that client handles WebSocket ping frames correctly.
 func TestClientWebsocketPing(t *testing.T) {
 	t.Parallel()